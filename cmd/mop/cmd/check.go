@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	optionNameVerbose   = "verbose"
+	optionNameMinHealth = "min-health"
+)
+
+type checkChunkStatus struct {
+	Address       string `json:"address"`
+	Retrievable   bool   `json:"retrievable"`
+	Latency       string `json:"latency"`
+	Neighbourhood uint8  `json:"neighbourhood"`
+	Responded     int    `json:"responded"`
+	Replicas      int    `json:"replicas"`
+	Error         string `json:"error"`
+}
+
+type checkReport struct {
+	Reference   string             `json:"reference"`
+	Chunks      []checkChunkStatus `json:"chunks"`
+	Total       int                `json:"total"`
+	Retrievable int                `json:"retrievable"`
+	Complete    bool               `json:"complete"`
+	Health      float64            `json:"health"`
+}
+
+func (c *command) initCheckCmd() error {
+	cmd := &cobra.Command{
+		Use:   "check reference",
+		Short: "check the availability of the content in the network",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			verbose, err := cmd.Flags().GetBool(optionNameVerbose)
+			if err != nil {
+				return err
+			}
+			minHealth, err := cmd.Flags().GetFloat64(optionNameMinHealth)
+			if err != nil {
+				return err
+			}
+
+			response, err := http.Get(fmt.Sprintf("http://localhost:1683/check/%s", args[0]))
+			if err != nil {
+				return err
+			}
+			defer response.Body.Close()
+
+			if response.StatusCode != http.StatusOK {
+				return fmt.Errorf("check: %s", response.Status)
+			}
+
+			var report checkReport
+			if err := json.NewDecoder(response.Body).Decode(&report); err != nil {
+				return fmt.Errorf("decode check report: %w", err)
+			}
+
+			out := cmd.OutOrStdout()
+			if verbose {
+				for _, ch := range report.Chunks {
+					fmt.Fprintf(out, "%s retrievable: %t, latency: %s, neighbourhood: %d, replicas: %d/%d", ch.Address, ch.Retrievable, ch.Latency, ch.Neighbourhood, ch.Replicas, ch.Responded)
+					if ch.Error != "" {
+						fmt.Fprintf(out, ", error: %s", ch.Error)
+					}
+					fmt.Fprintln(out)
+				}
+			}
+
+			fmt.Fprintf(out, "reference: %s\n", report.Reference)
+			fmt.Fprintf(out, "retrievable chunks: %d/%d\n", report.Retrievable, report.Total)
+			if !report.Complete {
+				fmt.Fprintln(out, "not all chunks could be found, as some intermediate chunks are not retrievable")
+			}
+			fmt.Fprintf(out, "health: %.2f\n", report.Health)
+
+			if report.Health < minHealth {
+				return fmt.Errorf("health %.2f is below %.2f", report.Health, minHealth)
+			}
+			return nil
+		},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return c.config.BindPFlags(cmd.Flags())
+		},
+	}
+
+	c.setAllFlags(cmd)
+	cmd.Flags().Bool(optionNameVerbose, false, "print the status of every chunk")
+	cmd.Flags().Float64(optionNameMinHealth, 0, "fail if the health of the content is below the value between 0 and 1")
+	c.root.AddCommand(cmd)
+
+	return nil
+}
@@ -14,6 +14,8 @@ import (
 	"github.com/redesblock/mop/core/cluster"
 	"github.com/redesblock/mop/core/log"
 	"github.com/redesblock/mop/core/node"
+	"github.com/redesblock/mop/core/storer/kv"
+	"github.com/redesblock/mop/core/usage"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -26,12 +28,16 @@ const (
 	optionNameDBBlockCacheCapacity       = "db-block-cache-capacity"
 	optionNameDBWriteBufferSize          = "db-write-buffer-size"
 	optionNameDBDisableSeeksCompaction   = "db-disable-seeks-compaction"
+	optionNameDBBackend                  = "db-backend"
+	optionNameDBVerifyInterval           = "db-verify-interval"
+	optionNameDBVerifyRate               = "db-verify-rate"
 	optionNamePassword                   = "password"
 	optionNamePasswordFile               = "password-file"
 	optionNameAPIAddr                    = "api-addr"
 	optionNameP2PAddr                    = "p2p-addr"
 	optionNameNATAddr                    = "nat-addr"
 	optionNameP2PWSEnable                = "p2p-ws-enable"
+	optionNameP2PQUICEnable              = "p2p-quic-enable"
 	optionNameDebugAPIEnable             = "debug-api-enable"
 	optionNameDebugAPIAddr               = "debug-api-addr"
 	optionNameBootnodes                  = "bootnode"
@@ -86,6 +92,37 @@ const (
 	optionTrustNode                      = "trust-node"
 	optionTLSCertPath                    = "tls-certificate-path"
 	optionTLSKeyPath                     = "tls-key-path"
+	optionNameDNSLinkGateway             = "dnslink-gateway"
+	optionNameAddressBookTTL             = "addressbook-ttl"
+	optionNameReseedInterval             = "reseed-interval"
+	optionNameReseedRate                 = "reseed-rate"
+	optionNameReseedBudget               = "reseed-budget"
+	optionNameConnectionsLowWatermark    = "connections-low-watermark"
+	optionNameConnectionsHighWatermark   = "connections-high-watermark"
+	optionNameP2PRelayService            = "p2p-relay-service"
+	optionNameP2PStaticRelays            = "p2p-static-relays"
+	optionNameP2PHolePunching            = "p2p-hole-punching"
+	optionNameGatewayAddr                = "gateway-addr"
+	optionNameGatewayAllowedOrigins      = "gateway-allowed-origins"
+	optionNameRoutingLatencyTradeoff     = "routing-latency-tradeoff"
+	optionNameColdStoreDir               = "cold-store-dir"
+	optionNameColdStoreS3Endpoint        = "cold-store-s3-endpoint"
+	optionNameColdStoreS3Region          = "cold-store-s3-region"
+	optionNameColdStoreS3Bucket          = "cold-store-s3-bucket"
+	optionNameColdStoreS3AccessKey       = "cold-store-s3-access-key"
+	optionNameColdStoreS3SecretKey       = "cold-store-s3-secret-key"
+	optionNameColdStoreS3Prefix          = "cold-store-s3-prefix"
+	optionNameColdAfter                  = "cold-after"
+	optionNameUsageAccounting            = "usage-accounting"
+	optionNameUsageRetention             = "usage-retention"
+	optionNameTrafficExportURL           = "traffic-export-url"
+	optionNameTrafficExportCAFiles       = "traffic-export-ca-files"
+	optionNameAPIUploadConcurrency       = "api-upload-concurrency"
+	optionNameAPIUploadQueue             = "api-upload-queue"
+	optionNameAPIDownloadConcurrency     = "api-download-concurrency"
+	optionNameAPIDownloadQueue           = "api-download-queue"
+	optionNameAPIQueueTimeout            = "api-queue-timeout"
+	optionNameAPIRequestDeadline         = "api-request-deadline"
 )
 
 func init() {
@@ -169,6 +206,14 @@ func newCommand(opts ...option) (c *command, err error) {
 		return nil, err
 	}
 
+	if err := c.initCheckCmd(); err != nil {
+		return nil, err
+	}
+
+	if err := c.initPublishNameCmd(); err != nil {
+		return nil, err
+	}
+
 	if err := c.initExportPrivateCmd(); err != nil {
 		return nil, err
 	}
@@ -253,12 +298,16 @@ func (c *command) setAllFlags(cmd *cobra.Command) {
 	cmd.Flags().Uint64(optionNameDBBlockCacheCapacity, 32*1024*1024, "size of block cache of the database in bytes")
 	cmd.Flags().Uint64(optionNameDBWriteBufferSize, 32*1024*1024, "size of the database write buffer in bytes")
 	cmd.Flags().Bool(optionNameDBDisableSeeksCompaction, false, "disables db compactions triggered by seeks")
+	cmd.Flags().String(optionNameDBBackend, "", fmt.Sprintf("key-value database backend of a new data directory, one of %s, %s if empty; existing databases keep their backend, use db migrate-backend to convert them", strings.Join(kv.Backends, ", "), kv.DefaultBackend))
+	cmd.Flags().Duration(optionNameDBVerifyInterval, 0, "interval of the background verification and repair of the chunk data and the localstore indexes, 0 disables it")
+	cmd.Flags().Int(optionNameDBVerifyRate, 100, "maximum number of chunks verified per second by the background verification")
 	cmd.Flags().String(optionNamePassword, "", "password for decrypting keys")
 	cmd.Flags().String(optionNamePasswordFile, "", "path to a file that contains password for decrypting keys")
 	cmd.Flags().StringSlice(optionNameAPIAddr, []string{":1683"}, "HTTP API listen address")
 	cmd.Flags().String(optionNameP2PAddr, ":1684", "P2P listen address")
 	cmd.Flags().String(optionNameNATAddr, "", "NAT exposed address")
 	cmd.Flags().Bool(optionNameP2PWSEnable, false, "enable P2P WebSocket transport")
+	cmd.Flags().Bool(optionNameP2PQUICEnable, false, "enable P2P QUIC transport")
 	cmd.Flags().StringSlice(optionNameBootnodes, []string{"/ip4/202.83.246.155/tcp/1684/p2p/16Uiu2HAmPqr2vmnwZi6HhTmWoCEVx2pD37m3p9G5dfNYCMrormLf"}, "initial nodes to connect to")
 	cmd.Flags().Bool(optionNameDebugAPIEnable, false, "enable debug HTTP API")
 	cmd.Flags().String(optionNameDebugAPIAddr, ":1685", "debug HTTP API listen address")
@@ -274,7 +323,7 @@ func (c *command) setAllFlags(cmd *cobra.Command) {
 	cmd.Flags().String(optionNamePaymentThreshold, "13500000", "threshold in MOP where you expect to get paid from your peers")
 	cmd.Flags().Int64(optionNamePaymentTolerance, 25, "excess debt above payment threshold in percentages where you disconnect from your peer")
 	cmd.Flags().Int64(optionNamePaymentEarly, 50, "percentage below the peers payment threshold when we initiate settlement")
-	cmd.Flags().StringSlice(optionNameResolverEndpoints, []string{}, "ENS compatible API endpoint for a TLD and with contract address, can be repeated, format [tld:][contract-addr@]url")
+	cmd.Flags().StringSlice(optionNameResolverEndpoints, []string{}, "ENS compatible API endpoint for a TLD and with contract address, a local name registry with its owner address or DNSLink TXT records, can be repeated, format [tld:][contract-addr@]url, [tld:]owner-addr@registry[?ttl=duration] or [tld:]dnslink[?ttl=duration]")
 	cmd.Flags().Bool(optionNameBootnodeMode, false, "cause the node to always accept incoming connections")
 	cmd.Flags().Bool(optionNameClefSignerEnable, false, "enable clef signer")
 	cmd.Flags().String(optionNameClefSignerEndpoint, "", "clef signer endpoint")
@@ -308,9 +357,41 @@ func (c *command) setAllFlags(cmd *cobra.Command) {
 	cmd.Flags().Bool(optionNameUseVoucherSnapshot, false, "bootstrap node using voucher snapshot from the network")
 	cmd.Flags().String(optionNameRemoteEndpoint, "", "push remote server")
 	cmd.Flags().Int(optionNameMaxWorker, 0, "number of workers")
+	_ = cmd.Flags().MarkDeprecated(optionNameMaxWorker, fmt.Sprintf("it has no effect, use --%s and --%s instead", optionNameAPIUploadConcurrency, optionNameAPIDownloadConcurrency))
 	cmd.Flags().Bool(optionTrustNode, false, "ensure the locally chunk is valid")
 	cmd.Flags().String(optionTLSCertPath, "", "the cert.pem file path for the server TLS configuration")
 	cmd.Flags().String(optionTLSKeyPath, "", "the key.pem file path for the server TLS configuration")
+	cmd.Flags().Bool(optionNameDNSLinkGateway, false, "serve the content of custom domains resolved by the name resolver from the request host, the API is then only reachable by IP address or localhost")
+	cmd.Flags().Duration(optionNameAddressBookTTL, 7*24*time.Hour, "time after which addresses of peers that were not seen or advertised again are pruned from the address book, 0 disables pruning")
+	cmd.Flags().Duration(optionNameReseedInterval, 0, "interval of checking the replication of a sample of pinned content and pushing again the chunks with too few replicas, 0 disables it")
+	cmd.Flags().Float64(optionNameReseedRate, 10, "maximal number of pinned chunks pushed again per second")
+	cmd.Flags().Uint64(optionNameReseedBudget, 100000000, "maximal amount in bookkeeper units spent on pushing again pinned chunks in an interval, 0 means no limit")
+	cmd.Flags().Int(optionNameConnectionsLowWatermark, 150, "number of connections the connections are trimmed down to once they exceed the high watermark")
+	cmd.Flags().Int(optionNameConnectionsHighWatermark, 200, "number of connections above which the connections to peers that are not protected are trimmed, 0 disables the connection manager")
+	cmd.Flags().Bool(optionNameP2PRelayService, false, "act as a circuit relay with limited duration and data for peers that are not publicly reachable when the node is publicly reachable, only in full node mode")
+	cmd.Flags().StringSlice(optionNameP2PStaticRelays, nil, "underlay addresses of circuit relays used to be reachable when the node is not publicly reachable")
+	cmd.Flags().Bool(optionNameP2PHolePunching, true, "upgrade relayed connections to direct ones by hole punching")
+	cmd.Flags().String(optionNameGatewayAddr, "", "browser light-client gateway listen address, served over secure WebSocket when the TLS certificate and key are set, empty disables it")
+	cmd.Flags().StringSlice(optionNameGatewayAllowedOrigins, []string{}, "origins of the browser clients allowed to connect to the gateway, all origins are allowed if empty")
+	cmd.Flags().Uint8(optionNameRoutingLatencyTradeoff, 0, "number of proximity orders a peer may be farther from a chunk than the closest peer and still be chosen for lower latency, with 0 latency only breaks ties between peers in the same proximity order")
+	cmd.Flags().String(optionNameColdStoreDir, "", "directory of the cold storage tier where rarely accessed reserve and pinned chunks are moved to, disabled if empty")
+	cmd.Flags().String(optionNameColdStoreS3Endpoint, "", "endpoint URL of an S3-compatible object store used as the cold storage tier, disabled if empty")
+	cmd.Flags().String(optionNameColdStoreS3Region, "us-east-1", "region of the cold store S3 bucket")
+	cmd.Flags().String(optionNameColdStoreS3Bucket, "", "bucket of the cold store objects")
+	cmd.Flags().String(optionNameColdStoreS3AccessKey, "", "access key of the cold store S3 bucket")
+	cmd.Flags().String(optionNameColdStoreS3SecretKey, "", "secret key of the cold store S3 bucket")
+	cmd.Flags().String(optionNameColdStoreS3Prefix, "", "prefix of the cold store object keys")
+	cmd.Flags().Duration(optionNameColdAfter, 7*24*time.Hour, "time since the last access after which a reserve or pinned chunk is moved to the cold storage tier")
+	cmd.Flags().Bool(optionNameUsageAccounting, false, "record the bytes served and stored and the retrieval cost of the API requests per root reference and tenant, queryable on the debug API")
+	cmd.Flags().Duration(optionNameUsageRetention, usage.DefaultRetention, "time for which the usage records are kept")
+	cmd.Flags().String(optionNameTrafficExportURL, "", "endpoint of the billing service the signed hourly API traffic reports are delivered to, defaults to the /api/v1/traffic path of the MOP_TRAFFIC_HOST environment variable, disabled if both are empty")
+	cmd.Flags().StringSlice(optionNameTrafficExportCAFiles, nil, "PEM files of the certificate authorities trusted in addition to the system ones to verify the billing service")
+	cmd.Flags().Int(optionNameAPIUploadConcurrency, 0, "maximal number of upload API requests served at the same time, 0 means no limit")
+	cmd.Flags().Int(optionNameAPIUploadQueue, 100, "maximal number of upload API requests waiting to be served, further ones are refused with 503 Service Unavailable")
+	cmd.Flags().Int(optionNameAPIDownloadConcurrency, 0, "maximal number of download API requests served at the same time, 0 means no limit")
+	cmd.Flags().Int(optionNameAPIDownloadQueue, 100, "maximal number of download API requests waiting to be served, further ones are refused with 503 Service Unavailable")
+	cmd.Flags().Duration(optionNameAPIQueueTimeout, 10*time.Second, "maximal time an upload or download API request waits to be served before it is refused with 503 Service Unavailable")
+	cmd.Flags().Duration(optionNameAPIRequestDeadline, 0, "maximal time of serving an upload or download API request, 0 means no limit")
 }
 
 func newLogger(cmd *cobra.Command, verbosity string) (log.Logger, error) {
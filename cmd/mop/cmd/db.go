@@ -1,22 +1,37 @@
 package cmd
 
 import (
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math/big"
 	"os"
 	"path/filepath"
 	"strings"
+	"text/tabwriter"
 	"time"
 
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/incentives/voucher"
+	"github.com/redesblock/mop/core/incentives/voucher/batchstore"
+	"github.com/redesblock/mop/core/node"
+	"github.com/redesblock/mop/core/storer/kv"
 	"github.com/redesblock/mop/core/storer/localstore"
 	"github.com/redesblock/mop/core/storer/statestore/leveldb"
+	"github.com/redesblock/mop/core/storer/storage"
 	"github.com/spf13/cobra"
 )
 
 const (
 	optionNameForgetOverlay = "forget-overlay"
 	optionNameForgetStamps  = "forget-stamps"
+	optionNameRepair        = "repair"
+	optionNameRate          = "rate"
+	optionNameRadius        = "radius"
+	optionNameCapacity      = "capacity"
+	optionNameJSON          = "json"
 )
 
 func (c *command) initDBCmd() {
@@ -29,6 +44,9 @@ func (c *command) initDBCmd() {
 	dbImportCmd(cmd)
 	dbNukeCmd(cmd)
 	dbIndicesCmd(cmd)
+	dbMigrateBackendCmd(cmd)
+	dbCheckCmd(cmd)
+	dbReserveReportCmd(cmd)
 
 	c.root.AddCommand(cmd)
 }
@@ -289,6 +307,346 @@ func dbNukeCmd(cmd *cobra.Command) {
 	cmd.AddCommand(c)
 }
 
+func dbMigrateBackendCmd(cmd *cobra.Command) {
+	c := &cobra.Command{
+		Use:   "migrate-backend <backend>",
+		Short: fmt.Sprintf("Convert the localstore and the statestore to another key-value database backend, one of %s", strings.Join(kv.Backends, ", ")),
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if (len(args)) != 1 {
+				return cmd.Help()
+			}
+			backend := args[0]
+			if !kv.ValidBackend(backend) {
+				return fmt.Errorf("unknown backend %q, want one of %s", backend, strings.Join(kv.Backends, ", "))
+			}
+
+			start := time.Now()
+			v, err := cmd.Flags().GetString(optionNameVerbosity)
+			if err != nil {
+				return fmt.Errorf("get verbosity: %w", err)
+			}
+			v = strings.ToLower(v)
+			logger, err := newLogger(cmd, v)
+			if err != nil {
+				return fmt.Errorf("new logger: %w", err)
+			}
+
+			dataDir, err := cmd.Flags().GetString(optionNameDataDir)
+			if err != nil {
+				return fmt.Errorf("get data-dir: %w", err)
+			}
+			if dataDir == "" {
+				return errors.New("no data-dir provided")
+			}
+
+			logger.Info("migrating databases with data-dir", "path", dataDir, "backend", backend)
+			logger.Info("the node must not be running and the disk needs room for a copy of the databases")
+
+			for _, name := range []string{"statestore", "localstore"} {
+				path := filepath.Join(dataDir, name)
+				from, err := kv.ReadBackend(path)
+				if err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+				if from == "" || from == backend {
+					logger.Info("skipping database", "name", name, "backend", from)
+					continue
+				}
+
+				logger.Info("migrating database", "name", name, "from", from, "to", backend)
+				count, backup, err := kv.Migrate(path, backend, nil)
+				if err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+				logger.Info("database migrated; remove the backup once the node runs fine", "name", name, "keys", count, "backup", backup)
+			}
+
+			logger.Info("done", "elapsed", time.Since(start))
+
+			return nil
+		},
+	}
+	c.Flags().String(optionNameDataDir, "", "data directory")
+	c.Flags().String(optionNameVerbosity, "info", "verbosity level")
+	cmd.AddCommand(c)
+}
+
+func dbCheckCmd(cmd *cobra.Command) {
+	c := &cobra.Command{
+		Use:   "check",
+		Short: "Verify the chunk data and the consistency of the localstore indexes",
+		Long: `Verify the data of all chunks against their addresses and the consistency of the localstore indexes.
+With --repair the invalid chunks and the dangling index entries are removed and the missing index entries are restored.
+Chunks moved to a cold store are not verified.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			start := time.Now()
+			v, err := cmd.Flags().GetString(optionNameVerbosity)
+			if err != nil {
+				return fmt.Errorf("get verbosity: %w", err)
+			}
+			v = strings.ToLower(v)
+			logger, err := newLogger(cmd, v)
+			if err != nil {
+				return fmt.Errorf("new logger: %w", err)
+			}
+
+			dataDir, err := cmd.Flags().GetString(optionNameDataDir)
+			if err != nil {
+				return fmt.Errorf("get data-dir: %w", err)
+			}
+			if dataDir == "" {
+				return errors.New("no data-dir provided")
+			}
+			repair, err := cmd.Flags().GetBool(optionNameRepair)
+			if err != nil {
+				return fmt.Errorf("get repair: %w", err)
+			}
+			rate, err := cmd.Flags().GetInt(optionNameRate)
+			if err != nil {
+				return fmt.Errorf("get rate: %w", err)
+			}
+
+			logger.Info("checking localstore with data-dir", "path", dataDir, "repair", repair)
+			logger.Info("the node must not be running")
+
+			path := filepath.Join(dataDir, "localstore")
+
+			storer, err := localstore.New(path, nil, nil, nil, logger)
+			if err != nil {
+				return fmt.Errorf("localstore: %w", err)
+			}
+			defer storer.Close()
+
+			r, err := storer.Check(cmd.Context(), localstore.CheckOptions{Repair: repair, Rate: rate})
+			if err != nil {
+				return fmt.Errorf("check: %w", err)
+			}
+
+			for _, addr := range r.Invalid {
+				logger.Info("invalid chunk", "address", addr)
+			}
+			for _, addr := range r.Unreadable {
+				logger.Info("unreadable chunk", "address", addr)
+			}
+			for name, n := range r.Missing {
+				logger.Info("missing index entries", "index", name, "count", n)
+			}
+			for name, n := range r.Dangling {
+				logger.Info("dangling index entries", "index", name, "count", n)
+			}
+			logger.Info("done", "chunks", r.Chunks, "skipped", r.Skipped, "invalid", len(r.Invalid), "unreadable", len(r.Unreadable), "repaired", r.Repaired, "elapsed", time.Since(start))
+
+			if !r.Consistent() && !repair {
+				return errors.New("database is not consistent, run the check with --repair to fix it")
+			}
+			return nil
+		},
+	}
+	c.Flags().String(optionNameDataDir, "", "data directory")
+	c.Flags().String(optionNameVerbosity, "info", "verbosity level")
+	c.Flags().Bool(optionNameRepair, false, "remove invalid chunks and dangling index entries and restore missing index entries")
+	c.Flags().Int(optionNameRate, 0, "maximum number of chunks verified per second, unlimited if 0")
+	cmd.AddCommand(c)
+}
+
+func dbReserveReportCmd(cmd *cobra.Command) {
+	c := &cobra.Command{
+		Use:   "reserve-report",
+		Short: "Report the reserve usage by proximity order and batch and project the evictions for radius and capacity values",
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			v, err := cmd.Flags().GetString(optionNameVerbosity)
+			if err != nil {
+				return fmt.Errorf("get verbosity: %w", err)
+			}
+			v = strings.ToLower(v)
+			logger, err := newLogger(cmd, v)
+			if err != nil {
+				return fmt.Errorf("new logger: %w", err)
+			}
+
+			dataDir, err := cmd.Flags().GetString(optionNameDataDir)
+			if err != nil {
+				return fmt.Errorf("get data-dir: %w", err)
+			}
+			if dataDir == "" {
+				return errors.New("no data-dir provided")
+			}
+			radiuses, err := cmd.Flags().GetUintSlice(optionNameRadius)
+			if err != nil {
+				return fmt.Errorf("get radius: %w", err)
+			}
+			capacities, err := cmd.Flags().GetInt64Slice(optionNameCapacity)
+			if err != nil {
+				return fmt.Errorf("get capacity: %w", err)
+			}
+			asJSON, err := cmd.Flags().GetBool(optionNameJSON)
+			if err != nil {
+				return fmt.Errorf("get json: %w", err)
+			}
+
+			stateStore, err := leveldb.NewStateStore(filepath.Join(dataDir, "statestore"), logger)
+			if err != nil {
+				return fmt.Errorf("statestore: %w", err)
+			}
+			defer stateStore.Close()
+
+			overlay, err := node.GetExistingOverlay(stateStore, node.NoncedOverlayKey)
+			if err != nil {
+				return fmt.Errorf("get overlay: %w", err)
+			}
+			batchStore, err := batchstore.New(stateStore, nil, logger)
+			if err != nil {
+				return fmt.Errorf("batchstore: %w", err)
+			}
+
+			storer, err := localstore.New(filepath.Join(dataDir, "localstore"), overlay.Bytes(), stateStore, nil, logger)
+			if err != nil {
+				return fmt.Errorf("localstore: %w", err)
+			}
+			defer storer.Close()
+
+			usage, err := storer.ReserveUsage()
+			if err != nil {
+				return fmt.Errorf("reserve usage: %w", err)
+			}
+
+			rs := batchStore.GetReserveState()
+			r, err := newReserveReport(batchStore, usage, rs)
+			if err != nil {
+				return err
+			}
+
+			if len(radiuses) == 0 {
+				for r := uint(rs.StorageRadius); r <= uint(rs.Radius)+2 && r <= uint(cluster.MaxPO); r++ {
+					radiuses = append(radiuses, r)
+				}
+			}
+			if len(capacities) == 0 {
+				capacities = []int64{batchstore.Capacity}
+			}
+			for _, radius := range radiuses {
+				if radius > uint(cluster.MaxPO) {
+					return fmt.Errorf("radius %d exceeds the maximum proximity order %d", radius, cluster.MaxPO)
+				}
+				for _, capacity := range capacities {
+					r.Projections = append(r.Projections, batchstore.Simulate(usage, rs.StorageRadius, uint8(radius), capacity))
+				}
+			}
+
+			if asJSON {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(r)
+			}
+			return r.write(cmd.OutOrStdout())
+		},
+	}
+	c.Flags().String(optionNameDataDir, "", "data directory")
+	c.Flags().String(optionNameVerbosity, "info", "verbosity level")
+	c.Flags().UintSlice(optionNameRadius, nil, "reserve radius values of the projections, which cap the storage radius, from the storage radius to two above the reserve radius if empty")
+	c.Flags().Int64Slice(optionNameCapacity, nil, fmt.Sprintf("reserve capacities in chunks of the projections, %d if empty", batchstore.Capacity))
+	c.Flags().Bool(optionNameJSON, false, "print the report in JSON")
+	cmd.AddCommand(c)
+}
+
+// reserveReport is the output of the db reserve-report command.
+type reserveReport struct {
+	Radius        uint8                   `json:"radius"`
+	StorageRadius uint8                   `json:"storageRadius"`
+	Bins          []reserveReportBin      `json:"bins"`
+	Batches       []reserveReportBatch    `json:"batches"`
+	Projections   []batchstore.Projection `json:"projections"`
+}
+
+type reserveReportBin struct {
+	PO       uint8  `json:"po"`
+	Chunks   uint64 `json:"chunks"`
+	Reserved uint64 `json:"reserved"`
+}
+
+type reserveReportBatch struct {
+	ID       string   `json:"id"`
+	Value    *big.Int `json:"value"`
+	Depth    uint8    `json:"depth"`
+	Expired  bool     `json:"expired"`
+	Radius   uint8    `json:"radius"`
+	Chunks   uint64   `json:"chunks"`
+	Reserved uint64   `json:"reserved"`
+}
+
+// newReserveReport sets the values of the batches in the usage and returns
+// the report of the current reserve usage. The batches which are no longer
+// in the batch store have zero value.
+func newReserveReport(batchStore voucher.Storer, usage []*batchstore.BatchUsage, rs *voucher.ReserveState) (*reserveReport, error) {
+	r := &reserveReport{Radius: rs.Radius, StorageRadius: rs.StorageRadius}
+
+	var bins [cluster.MaxBins]reserveReportBin
+	for i := range bins {
+		bins[i].PO = uint8(i)
+	}
+	for _, u := range usage {
+		b, err := batchStore.Get(u.ID)
+		switch {
+		case err == nil:
+			u.Value = b.Value
+		case errors.Is(err, storage.ErrNotFound):
+			u.Value = big.NewInt(0)
+		default:
+			return nil, fmt.Errorf("get batch %x: %w", u.ID, err)
+		}
+
+		rb := reserveReportBatch{
+			ID:       hex.EncodeToString(u.ID),
+			Value:    u.Value,
+			Expired:  b == nil,
+			Radius:   u.Radius,
+			Reserved: u.Reserved(0),
+		}
+		if b != nil {
+			rb.Depth = b.Depth
+		}
+		for po, n := range u.Bins {
+			rb.Chunks += n
+			bins[po].Chunks += n
+			if uint8(po) >= u.Radius {
+				bins[po].Reserved += n
+			}
+		}
+		r.Batches = append(r.Batches, rb)
+	}
+	for _, b := range bins {
+		if b.Chunks > 0 {
+			r.Bins = append(r.Bins, b)
+		}
+	}
+	return r, nil
+}
+
+func (r *reserveReport) write(out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "radius %d, storage radius %d\n\n", r.Radius, r.StorageRadius)
+	fmt.Fprintln(w, "PO\tCHUNKS\tRESERVED")
+	for _, b := range r.Bins {
+		fmt.Fprintf(w, "%d\t%d\t%d\n", b.PO, b.Chunks, b.Reserved)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "BATCH\tVALUE\tDEPTH\tRADIUS\tCHUNKS\tRESERVED")
+	for _, b := range r.Batches {
+		depth := fmt.Sprint(b.Depth)
+		if b.Expired {
+			depth = "expired"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\n", b.ID, b.Value, depth, b.Radius, b.Chunks, b.Reserved)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "RADIUS\tCAPACITY\tSIZE\tEVICTED\tEVICTED BATCHES\tSTORAGE RADIUS\tFITS")
+	for _, p := range r.Projections {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%d\t%d\t%t\n", p.Radius, p.Capacity, p.Size, p.Evicted, p.EvictedBatches, p.StorageRadius, p.Fits)
+	}
+	return w.Flush()
+}
+
 func removeContent(path string) error {
 	dir, err := os.Open(path)
 	if err != nil {
@@ -35,7 +35,7 @@ func (c *command) initDeployCmd() error {
 			deployGasPrice := c.config.GetString(optionNameSwapDeploymentGasPrice)
 			networkID := c.config.GetUint64(optionNameNetworkID)
 
-			stateStore, err := node.InitStateStore(logger, dataDir)
+			stateStore, err := node.InitStateStore(logger, dataDir, c.config.GetString(optionNameDBBackend))
 			if err != nil {
 				return err
 			}
@@ -1,11 +1,21 @@
 package cmd
 
 import (
+	"archive/tar"
+	"errors"
 	"fmt"
-	"github.com/spf13/cobra"
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	optionNameRecursive = "recursive"
+	optionNameOutput    = "output"
 )
 
 func (c *command) initDownloadCmd() error {
@@ -14,9 +24,19 @@ func (c *command) initDownloadCmd() error {
 		Short: "get file or index document from a collection of files",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			recursive, err := cmd.Flags().GetBool(optionNameRecursive)
+			if err != nil {
+				return err
+			}
+
+			url := fmt.Sprintf("http://localhost:1683/mop/%s", args[0])
+			if recursive {
+				url += "?format=tar"
+			}
+
 			client := &http.Client{}
-			fmt.Println(fmt.Sprintf("http://localhost:1683/mop/%s", args[0]))
-			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://localhost:1683/mop/%s", args[0]), nil)
+			fmt.Println(url)
+			req, err := http.NewRequest(http.MethodGet, url, nil)
 			if err != nil {
 				return err
 			}
@@ -24,6 +44,21 @@ func (c *command) initDownloadCmd() error {
 			if err != nil {
 				return err
 			}
+			defer response.Body.Close()
+
+			if recursive {
+				if response.StatusCode != http.StatusOK {
+					return fmt.Errorf("download collection: %s", response.Status)
+				}
+				output, err := cmd.Flags().GetString(optionNameOutput)
+				if err != nil {
+					return err
+				}
+				if output == "" {
+					output = args[0]
+				}
+				return untar(response.Body, output)
+			}
 
 			stdout := os.Stdout
 			_, err = io.Copy(stdout, response.Body)
@@ -36,7 +71,90 @@ func (c *command) initDownloadCmd() error {
 	}
 
 	c.setAllFlags(cmd)
+	cmd.Flags().Bool(optionNameRecursive, false, "download all the files of the collection")
+	cmd.Flags().String(optionNameOutput, "", "output directory of the recursive download, defaults to the reference")
 	c.root.AddCommand(cmd)
 
 	return nil
 }
+
+// tarBlockSize is the size of the blocks of a tar stream. The end of the
+// archive is marked by two zero blocks.
+const tarBlockSize = 512
+
+var errTarTruncated = errors.New("tar archive is truncated")
+
+// tailReader keeps the last bytes read from the reader.
+type tailReader struct {
+	r    io.Reader
+	tail []byte
+}
+
+func (t *tailReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	t.tail = append(t.tail, p[:n]...)
+	if len(t.tail) > 2*tarBlockSize {
+		t.tail = t.tail[len(t.tail)-2*tarBlockSize:]
+	}
+	return n, err
+}
+
+// ended reports whether the read bytes end with the end-of-archive marker.
+func (t *tailReader) ended() bool {
+	if len(t.tail) < 2*tarBlockSize {
+		return false
+	}
+	for _, b := range t.tail {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// untar extracts the files of the tar stream into the dir directory. An
+// error is returned if the stream ends without the end-of-archive marker.
+func untar(r io.Reader, dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	tailr := &tailReader{r: r}
+	tr := tar.NewReader(tailr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			if !tailr.ended() {
+				return errTarTruncated
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(hdr.Name))
+		if !strings.HasPrefix(target, dir+string(filepath.Separator)) {
+			return fmt.Errorf("invalid file path %q", hdr.Name)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, tr); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Println(target)
+	}
+}
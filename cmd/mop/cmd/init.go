@@ -28,13 +28,18 @@ func (c *command) initInitCmd() (err error) {
 			}
 
 			dataDir := c.config.GetString(optionNameDataDir)
-			stateStore, err := node.InitStateStore(logger, dataDir)
+			dbBackend := c.config.GetString(optionNameDBBackend)
+			stateStore, err := node.InitStateStore(logger, dataDir, dbBackend)
 			if err != nil {
 				return err
 			}
 
 			defer stateStore.Close()
 
+			if err := node.InitLocalStore(dataDir, dbBackend); err != nil {
+				return fmt.Errorf("localstore: %w", err)
+			}
+
 			return nil
 		},
 		PreRunE: func(cmd *cobra.Command, args []string) error {
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/redesblock/mop/core/api"
+	"github.com/spf13/cobra"
+)
+
+type publishNameRequest struct {
+	Reference string `json:"reference"`
+}
+
+type publishNameResponse struct {
+	Name      string `json:"name"`
+	Owner     string `json:"owner"`
+	Reference string `json:"reference"`
+}
+
+func (c *command) initPublishNameCmd() error {
+	cmd := &cobra.Command{
+		Use:   "publish-name id name reference",
+		Short: "publish a name of the local name registry pointing to the reference",
+		Long: `Publish a name of the local name registry pointing to the reference.
+
+The record is signed by the node key, so the name is resolved by the nodes
+which have the node address configured as the owner of the registry.`,
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			body, err := json.Marshal(publishNameRequest{Reference: args[2]})
+			if err != nil {
+				return err
+			}
+
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://localhost:1683/names/%s", args[1]), bytes.NewReader(body))
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(api.ClusterVoucherBatchIdHeader, args[0])
+			response, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return err
+			}
+			defer response.Body.Close()
+
+			if response.StatusCode != http.StatusCreated {
+				return fmt.Errorf("publish name: %s", response.Status)
+			}
+
+			var res publishNameResponse
+			if err := json.NewDecoder(response.Body).Decode(&res); err != nil {
+				return fmt.Errorf("decode publish name response: %w", err)
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "name: %s\n", res.Name)
+			fmt.Fprintf(out, "reference: %s\n", res.Reference)
+			fmt.Fprintf(out, "registry owner: %s\n", res.Owner)
+			return nil
+		},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return c.config.BindPFlags(cmd.Flags())
+		},
+	}
+
+	c.setAllFlags(cmd)
+	c.root.AddCommand(cmd)
+
+	return nil
+}
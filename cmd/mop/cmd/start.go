@@ -21,7 +21,6 @@ import (
 	"github.com/redesblock/mop/core/cluster"
 	"github.com/redesblock/mop/core/crypto"
 	"github.com/redesblock/mop/core/crypto/clef"
-	"github.com/redesblock/mop/core/dispatcher"
 	"github.com/redesblock/mop/core/keystore"
 	filekeystore "github.com/redesblock/mop/core/keystore/file"
 	memkeystore "github.com/redesblock/mop/core/keystore/mem"
@@ -60,10 +59,6 @@ func (c *command) initStartCmd() (err error) {
 				return fmt.Errorf("new logger: %w", err)
 			}
 
-			if workers := c.config.GetInt(optionNameMaxWorker); workers > 0 {
-				dispatcher := dispatcher.NewDispatcher(workers)
-				dispatcher.Run()
-			}
 			go startTimeBomb(logger)
 
 			isWindowsService, err := isWindowsService()
@@ -181,11 +176,23 @@ func (c *command) initStartCmd() (err error) {
 				DBBlockCacheCapacity:       c.config.GetUint64(optionNameDBBlockCacheCapacity),
 				DBWriteBufferSize:          c.config.GetUint64(optionNameDBWriteBufferSize),
 				DBDisableSeeksCompaction:   c.config.GetBool(optionNameDBDisableSeeksCompaction),
+				DBBackend:                  c.config.GetString(optionNameDBBackend),
+				DBVerifyInterval:           c.config.GetDuration(optionNameDBVerifyInterval),
+				DBVerifyRate:               c.config.GetInt(optionNameDBVerifyRate),
+				ColdStoreDir:               c.config.GetString(optionNameColdStoreDir),
+				ColdStoreS3Endpoint:        c.config.GetString(optionNameColdStoreS3Endpoint),
+				ColdStoreS3Region:          c.config.GetString(optionNameColdStoreS3Region),
+				ColdStoreS3Bucket:          c.config.GetString(optionNameColdStoreS3Bucket),
+				ColdStoreS3AccessKey:       c.config.GetString(optionNameColdStoreS3AccessKey),
+				ColdStoreS3SecretKey:       c.config.GetString(optionNameColdStoreS3SecretKey),
+				ColdStoreS3Prefix:          c.config.GetString(optionNameColdStoreS3Prefix),
+				ColdAfter:                  c.config.GetDuration(optionNameColdAfter),
 				APIAddr:                    c.config.GetStringSlice(optionNameAPIAddr),
 				DebugAPIAddr:               debugAPIAddr,
 				Addr:                       c.config.GetString(optionNameP2PAddr),
 				NATAddr:                    c.config.GetString(optionNameNATAddr),
 				EnableWS:                   c.config.GetBool(optionNameP2PWSEnable),
+				EnableQUIC:                 c.config.GetBool(optionNameP2PQUICEnable),
 				WelcomeMessage:             c.config.GetString(optionWelcomeMessage),
 				Bootnodes:                  networkConfig.bootNodes,
 				CORSAllowedOrigins:         c.config.GetStringSlice(optionCORSAllowedOrigins),
@@ -229,6 +236,29 @@ func (c *command) initStartCmd() (err error) {
 				TrustNode:                  c.config.GetBool(optionTrustNode),
 				TLSCertFile:                c.config.GetString(optionTLSCertPath),
 				TLSKeyFile:                 c.config.GetString(optionTLSKeyPath),
+				DNSLinkGateway:             c.config.GetBool(optionNameDNSLinkGateway),
+				AddressBookTTL:             c.config.GetDuration(optionNameAddressBookTTL),
+				ReseedInterval:             c.config.GetDuration(optionNameReseedInterval),
+				ReseedRate:                 c.config.GetFloat64(optionNameReseedRate),
+				ReseedBudget:               c.config.GetUint64(optionNameReseedBudget),
+				ConnectionsLowWatermark:    c.config.GetInt(optionNameConnectionsLowWatermark),
+				ConnectionsHighWatermark:   c.config.GetInt(optionNameConnectionsHighWatermark),
+				RelayService:               c.config.GetBool(optionNameP2PRelayService),
+				StaticRelays:               c.config.GetStringSlice(optionNameP2PStaticRelays),
+				HolePunching:               c.config.GetBool(optionNameP2PHolePunching),
+				GatewayAddr:                c.config.GetString(optionNameGatewayAddr),
+				GatewayAllowedOrigins:      c.config.GetStringSlice(optionNameGatewayAllowedOrigins),
+				RoutingLatencyTradeoff:     uint8(c.config.GetUint(optionNameRoutingLatencyTradeoff)),
+				UsageAccounting:            c.config.GetBool(optionNameUsageAccounting),
+				UsageRetention:             c.config.GetDuration(optionNameUsageRetention),
+				TrafficExportURL:           c.config.GetString(optionNameTrafficExportURL),
+				TrafficExportCAFiles:       c.config.GetStringSlice(optionNameTrafficExportCAFiles),
+				APIUploadConcurrency:       c.config.GetInt(optionNameAPIUploadConcurrency),
+				APIUploadQueue:             c.config.GetInt(optionNameAPIUploadQueue),
+				APIDownloadConcurrency:     c.config.GetInt(optionNameAPIDownloadConcurrency),
+				APIDownloadQueue:           c.config.GetInt(optionNameAPIDownloadQueue),
+				APIQueueTimeout:            c.config.GetDuration(optionNameAPIQueueTimeout),
+				APIRequestDeadline:         c.config.GetDuration(optionNameAPIRequestDeadline),
 			})
 			if err != nil {
 				return fmt.Errorf("new node %v", err)
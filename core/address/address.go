@@ -10,6 +10,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/redesblock/mop/core/cluster"
@@ -18,24 +19,35 @@ import (
 	ma "github.com/multiformats/go-multiaddr"
 )
 
-var ErrInvalidAddress = errors.New("invalid address")
+var (
+	ErrInvalidAddress = errors.New("invalid address")
+	// ErrInvalidRecord is returned when the address record signature is
+	// missing or is not made by the owner of the address.
+	ErrInvalidRecord = errors.New("invalid address record")
+)
 
 // Address represents the mop address in cluster.
 // It consists of a peers underlay (physical) address, overlay (topology) address and signature.
 // Signature is used to verify the `Overlay/Underlay` pair, as it is based on `underlay|networkID`, signed with the public key of Overlay address
+// Timestamp and RecordSignature form an optional signed record which tells
+// when the peer advertised the address, so that newer records replace older ones.
 type Address struct {
-	Underlay    ma.Multiaddr
-	Overlay     cluster.Address
-	Signature   []byte
-	Transaction []byte
-	BSCAddress  []byte
+	Underlay        ma.Multiaddr
+	Overlay         cluster.Address
+	Signature       []byte
+	Transaction     []byte
+	BSCAddress      []byte
+	Timestamp       int64
+	RecordSignature []byte
 }
 
 type addressJSON struct {
-	Overlay     string `json:"overlay"`
-	Underlay    string `json:"underlay"`
-	Signature   string `json:"signature"`
-	Transaction string `json:"transaction"`
+	Overlay         string `json:"overlay"`
+	Underlay        string `json:"underlay"`
+	Signature       string `json:"signature"`
+	Transaction     string `json:"transaction"`
+	Timestamp       int64  `json:"timestamp,omitempty"`
+	RecordSignature string `json:"recordSignature,omitempty"`
 }
 
 func NewAddress(signer crypto.Signer, underlay ma.Multiaddr, overlay cluster.Address, networkID uint64, trx []byte) (*Address, error) {
@@ -100,16 +112,80 @@ func generateSignData(underlay, overlay []byte, networkID uint64) []byte {
 	return append(signData, networkIDBytes...)
 }
 
+func generateRecordSignData(underlay, overlay []byte, networkID uint64, timestamp int64) []byte {
+	networkIDBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(networkIDBytes, networkID)
+	timestampBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(timestampBytes, uint64(timestamp))
+	signData := append([]byte("mop-record-"), underlay...)
+	signData = append(signData, overlay...)
+	signData = append(signData, networkIDBytes...)
+	return append(signData, timestampBytes...)
+}
+
+// SignRecord signs the address record with the time t, which must be done
+// by the same key that signed the address.
+func (a *Address) SignRecord(signer crypto.Signer, networkID uint64, t time.Time) error {
+	underlayBinary, err := a.Underlay.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	timestamp := t.UnixNano()
+	signature, err := signer.Sign(generateRecordSignData(underlayBinary, a.Overlay.Bytes(), networkID, timestamp))
+	if err != nil {
+		return err
+	}
+
+	a.Timestamp = timestamp
+	a.RecordSignature = signature
+	return nil
+}
+
+// VerifyRecord checks that the address record is signed by the owner of the
+// address signature.
+func (a *Address) VerifyRecord(networkID uint64) error {
+	if len(a.RecordSignature) == 0 {
+		return ErrInvalidRecord
+	}
+
+	underlayBinary, err := a.Underlay.MarshalBinary()
+	if err != nil {
+		return ErrInvalidRecord
+	}
+
+	addressPK, err := crypto.Recover(a.Signature, generateSignData(underlayBinary, a.Overlay.Bytes(), networkID))
+	if err != nil {
+		return ErrInvalidRecord
+	}
+	recordPK, err := crypto.Recover(a.RecordSignature, generateRecordSignData(underlayBinary, a.Overlay.Bytes(), networkID, a.Timestamp))
+	if err != nil {
+		return ErrInvalidRecord
+	}
+	if !addressPK.Equal(recordPK) {
+		return ErrInvalidRecord
+	}
+	return nil
+}
+
+// Newer reports whether the address carries a signed record which is more
+// recent than the record of b.
+func (a *Address) Newer(b *Address) bool {
+	return len(a.RecordSignature) > 0 && a.Timestamp > b.Timestamp
+}
+
 func (a *Address) Equal(b *Address) bool {
 	return a.Overlay.Equal(b.Overlay) && a.Underlay.Equal(b.Underlay) && bytes.Equal(a.Signature, b.Signature) && bytes.Equal(a.Transaction, b.Transaction)
 }
 
 func (a *Address) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&addressJSON{
-		Overlay:     a.Overlay.String(),
-		Underlay:    a.Underlay.String(),
-		Signature:   base64.StdEncoding.EncodeToString(a.Signature),
-		Transaction: common.Bytes2Hex(a.Transaction),
+		Overlay:         a.Overlay.String(),
+		Underlay:        a.Underlay.String(),
+		Signature:       base64.StdEncoding.EncodeToString(a.Signature),
+		Transaction:     common.Bytes2Hex(a.Transaction),
+		Timestamp:       a.Timestamp,
+		RecordSignature: base64.StdEncoding.EncodeToString(a.RecordSignature),
 	})
 }
 
@@ -134,7 +210,14 @@ func (a *Address) UnmarshalJSON(b []byte) error {
 
 	a.Underlay = m
 	a.Signature, err = base64.StdEncoding.DecodeString(v.Signature)
+	if err != nil {
+		return err
+	}
 	a.Transaction = common.Hex2Bytes(v.Transaction)
+	a.Timestamp = v.Timestamp
+	if v.RecordSignature != "" {
+		a.RecordSignature, err = base64.StdEncoding.DecodeString(v.RecordSignature)
+	}
 	return err
 }
 
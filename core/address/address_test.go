@@ -1,7 +1,9 @@
 package address_test
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	mop "github.com/redesblock/mop/core/address"
@@ -54,6 +56,89 @@ func TestMopAddress(t *testing.T) {
 	}
 
 	if !newmop.Equal(mopAddress) {
-		t.Fatalf("got %s expected %s", newmop, mopAddress)
+		t.Fatalf("got %s expected %s", &newmop, mopAddress)
+	}
+}
+
+func TestMopAddressRecord(t *testing.T) {
+	node1ma, err := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/1684/p2p/16Uiu2HAkx8ULY8cTXhdVAcMmLcH9AsTKz6uBQ7DPLKRjMLgBVYkA")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce := common.HexToHash("0x2").Bytes()
+
+	privateKey1, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey2, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	overlay, err := crypto.NewOverlayAddress(privateKey1.PublicKey, 3, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer1 := crypto.NewDefaultSigner(privateKey1)
+
+	mopAddress, err := mop.NewAddress(signer1, node1ma, overlay, 3, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mopAddress.VerifyRecord(3); !errors.Is(err, mop.ErrInvalidRecord) {
+		t.Fatalf("got error %v, want %v", err, mop.ErrInvalidRecord)
+	}
+
+	older := *mopAddress
+	if err := older.SignRecord(signer1, 3, time.Unix(1, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := mopAddress.SignRecord(signer1, 3, time.Unix(2, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := mopAddress.VerifyRecord(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := mopAddress.VerifyRecord(4); !errors.Is(err, mop.ErrInvalidRecord) {
+		t.Fatalf("got error %v, want %v", err, mop.ErrInvalidRecord)
+	}
+	if !mopAddress.Newer(&older) {
+		t.Fatal("expected the record to be newer")
+	}
+	if older.Newer(mopAddress) {
+		t.Fatal("expected the record to be older")
+	}
+
+	bytes, err := mopAddress.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var newmop mop.Address
+	if err := newmop.UnmarshalJSON(bytes); err != nil {
+		t.Fatal(err)
+	}
+	if newmop.Timestamp != mopAddress.Timestamp {
+		t.Fatalf("got timestamp %d, want %d", newmop.Timestamp, mopAddress.Timestamp)
+	}
+	if err := newmop.VerifyRecord(3); err != nil {
+		t.Fatal(err)
+	}
+
+	forged := *mopAddress
+	if err := forged.SignRecord(crypto.NewDefaultSigner(privateKey2), 3, time.Unix(3, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := forged.VerifyRecord(3); !errors.Is(err, mop.ErrInvalidRecord) {
+		t.Fatalf("got error %v, want %v", err, mop.ErrInvalidRecord)
+	}
+
+	forged = *mopAddress
+	forged.Timestamp++
+	if err := forged.VerifyRecord(3); !errors.Is(err, mop.ErrInvalidRecord) {
+		t.Fatalf("got error %v, want %v", err, mop.ErrInvalidRecord)
 	}
 }
@@ -4,12 +4,20 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/redesblock/mop/core/cluster"
 	"github.com/redesblock/mop/core/storer/storage"
 )
 
-const keyPrefix = "addressbook_entry_"
+const (
+	keyPrefix     = "addressbook_entry_"
+	seenKeyPrefix = "addressbook_seen_"
+)
+
+// DefaultTTL is the default duration after which an address of a peer that
+// has not been seen or re-advertised expires.
+const DefaultTTL = 7 * 24 * time.Hour
 
 var _ Interface = (*store)(nil)
 
@@ -25,6 +33,9 @@ type Interface interface {
 	IterateOverlays(func(cluster.Address) (bool, error)) error
 	// Addresses returns a list of all Address-es saved in address.
 	Addresses() ([]Address, error)
+	// Prune removes the addresses which have expired, except the ones for
+	// which keep returns true, and returns the removed overlay addresses.
+	Prune(keep func(cluster.Address) bool) ([]cluster.Address, error)
 }
 
 type GetPutter interface {
@@ -49,13 +60,32 @@ type Remover interface {
 
 type store struct {
 	store storage.StateStorer
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+// Option is a function that configures the address book.
+type Option func(*store)
+
+// WithTTL sets the duration after which an address that has not been seen
+// expires. Zero or negative value disables the expiry.
+func WithTTL(ttl time.Duration) Option {
+	return func(s *store) {
+		s.ttl = ttl
+	}
 }
 
 // New creates new address for state storer.
-func New(storer storage.StateStorer) Interface {
-	return &store{
+func New(storer storage.StateStorer, opts ...Option) Interface {
+	s := &store{
 		store: storer,
+		ttl:   DefaultTTL,
+		now:   time.Now,
 	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
 }
 
 func (s *store) Get(overlay cluster.Address) (*Address, error) {
@@ -74,11 +104,75 @@ func (s *store) Get(overlay cluster.Address) (*Address, error) {
 
 func (s *store) Put(overlay cluster.Address, addr Address) (err error) {
 	key := keyPrefix + overlay.String()
-	return s.store.Put(key, &addr)
+	if err := s.store.Put(key, &addr); err != nil {
+		return err
+	}
+	return s.store.Put(seenKeyPrefix+overlay.String(), s.now().UnixNano())
 }
 
 func (s *store) Remove(overlay cluster.Address) error {
-	return s.store.Delete(keyPrefix + overlay.String())
+	if err := s.store.Delete(keyPrefix + overlay.String()); err != nil {
+		return err
+	}
+	return s.store.Delete(seenKeyPrefix + overlay.String())
+}
+
+// lastSeen returns the time of the last put of the address. The time of the
+// signed address record is only used for addresses put before the time of
+// the put was stored. It is set by the peer, so it is capped at the current
+// time and stored, otherwise a peer could make its address unprunable.
+// Addresses put before the upgrade without a record are seen now, so that
+// they are not all pruned on the first start after the upgrade.
+func (s *store) lastSeen(addr *Address) (time.Time, error) {
+	key := seenKeyPrefix + addr.Overlay.String()
+	var seen int64
+	err := s.store.Get(key, &seen)
+	if err == nil {
+		return time.Unix(0, seen), nil
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return time.Time{}, err
+	}
+	seen = addr.Timestamp
+	if now := s.now().UnixNano(); seen <= 0 || seen > now {
+		seen = now
+		if err := s.store.Put(key, seen); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Unix(0, seen), nil
+}
+
+func (s *store) Prune(keep func(cluster.Address) bool) (pruned []cluster.Address, err error) {
+	if s.ttl <= 0 {
+		return nil, nil
+	}
+
+	addresses, err := s.Addresses()
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := s.now().Add(-s.ttl)
+	for i := range addresses {
+		addr := &addresses[i]
+		if keep != nil && keep(addr.Overlay) {
+			continue
+		}
+		seen, err := s.lastSeen(addr)
+		if err != nil {
+			return pruned, err
+		}
+		if !seen.Before(deadline) {
+			continue
+		}
+		if err := s.Remove(addr.Overlay); err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, addr.Overlay)
+	}
+
+	return pruned, nil
 }
 
 func (s *store) IterateOverlays(cb func(cluster.Address) (bool, error)) error {
@@ -2,6 +2,7 @@ package address_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/redesblock/mop/core/address"
@@ -84,3 +85,135 @@ func run(t *testing.T, f bookFunc) {
 		t.Fatalf("expected addresses len %v, got %v", 1, len(addresses))
 	}
 }
+
+func TestPrune(t *testing.T) {
+	var (
+		now   = time.Unix(1000, 0)
+		ttl   = time.Hour
+		store = mock.NewStateStore()
+		book  = address.New(store, address.WithTTL(ttl), address.WithNowFunc(func() time.Time { return now }))
+	)
+
+	multiaddr, err := ma.NewMultiaddr("/ip4/1.1.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := crypto.NewDefaultSigner(pk)
+
+	put := func(overlay cluster.Address, record time.Time) {
+		t.Helper()
+		mopAddress, err := mop.NewAddress(signer, multiaddr, overlay, 1, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !record.IsZero() {
+			if err := mopAddress.SignRecord(signer, 1, record); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := book.Put(overlay, *mopAddress); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var (
+		stale     = cluster.NewAddress([]byte{0, 1, 2, 1})
+		kept      = cluster.NewAddress([]byte{0, 1, 2, 2})
+		fresh     = cluster.NewAddress([]byte{0, 1, 2, 3})
+		advertise = cluster.NewAddress([]byte{0, 1, 2, 4})
+		legacy    = cluster.NewAddress([]byte{0, 1, 2, 5})
+		upgraded  = cluster.NewAddress([]byte{0, 1, 2, 6})
+	)
+	put(stale, time.Time{})
+	put(kept, time.Time{})
+	// addresses put before the upgrade without a record are seen from the
+	// first prune
+	put(upgraded, time.Time{})
+	if err := store.Delete("addressbook_seen_" + upgraded.String()); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(ttl)
+	put(fresh, time.Time{})
+	// the record of the peer in the future is not trusted beyond the put
+	put(advertise, now.Add(10*ttl))
+	// nor beyond the current time for addresses put without the time of
+	// the put
+	put(legacy, now.Add(10*ttl))
+	if err := store.Delete("addressbook_seen_" + legacy.String()); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(ttl / 2)
+	pruned, err := book.Prune(func(a cluster.Address) bool { return a.Equal(kept) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pruned) != 1 || !pruned[0].Equal(stale) {
+		t.Fatalf("got pruned %v, want %v", pruned, stale)
+	}
+	if _, err := book.Get(stale); err != address.ErrNotFound {
+		t.Fatalf("got error %v, want %v", err, address.ErrNotFound)
+	}
+
+	now = now.Add(ttl)
+	pruned, err = book.Prune(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pruned) != 3 {
+		t.Fatalf("got %d pruned, want %d", len(pruned), 3)
+	}
+
+	overlays, err := book.Overlays()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalAddresses(overlays, legacy, upgraded) {
+		t.Fatalf("got overlays %v, want %v", overlays, []cluster.Address{legacy, upgraded})
+	}
+
+	seen := 0
+	if err := store.Iterate("addressbook_seen_", func(_, _ []byte) (bool, error) {
+		seen++
+		return false, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if seen != 2 {
+		t.Fatalf("got %d last seen entries, want %d", seen, 2)
+	}
+
+	now = now.Add(ttl / 2)
+	pruned, err = book.Prune(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalAddresses(pruned, legacy, upgraded) {
+		t.Fatalf("got pruned %v, want %v", pruned, []cluster.Address{legacy, upgraded})
+	}
+}
+
+// equalAddresses reports whether got holds the addresses of want in any order.
+func equalAddresses(got []cluster.Address, want ...cluster.Address) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for _, w := range want {
+		found := false
+		for _, g := range got {
+			if g.Equal(w) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,10 @@
+package address
+
+import "time"
+
+// WithNowFunc sets the function that returns the current time.
+func WithNowFunc(now func() time.Time) Option {
+	return func(s *store) {
+		s.now = now
+	}
+}
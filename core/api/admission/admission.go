@@ -0,0 +1,172 @@
+// Package admission provides the admission control of the HTTP requests.
+// The requests of a route group are served by a bounded number of
+// concurrent handlers, wait in a bounded queue when all are busy and are
+// refused with 503 Service Unavailable when the queue is full or the wait
+// is too long, so that a burst in one group does not starve the others.
+package admission
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redesblock/mop/core/api/jsonhttp"
+)
+
+// DefaultQueueTimeout is the default maximal time a request waits in the queue.
+const DefaultQueueTimeout = 10 * time.Second
+
+// Limits are the admission limits of a route group.
+type Limits struct {
+	// Concurrency is the maximal number of requests served at the same
+	// time, unlimited if zero.
+	Concurrency int
+	// Queue is the maximal number of requests waiting to be served.
+	Queue int
+	// QueueTimeout is the maximal time a request waits to be served.
+	QueueTimeout time.Duration
+	// Deadline is the maximal time of serving a request, after which its
+	// context is canceled, unlimited if zero.
+	Deadline time.Duration
+}
+
+// Controller admits the requests of the route groups within their limits.
+type Controller struct {
+	groups  map[string]*group
+	metrics metrics
+}
+
+type group struct {
+	name   string
+	limits Limits
+	slots  chan struct{}
+
+	mu      sync.Mutex
+	waiting int
+}
+
+// New returns a new controller with the limits of the route groups.
+func New(limits map[string]Limits) *Controller {
+	c := &Controller{
+		groups:  make(map[string]*group, len(limits)),
+		metrics: newMetrics(),
+	}
+	for name, l := range limits {
+		if l.QueueTimeout <= 0 {
+			l.QueueTimeout = DefaultQueueTimeout
+		}
+		g := &group{name: name, limits: l}
+		if l.Concurrency > 0 {
+			g.slots = make(chan struct{}, l.Concurrency)
+		}
+		c.groups[name] = g
+	}
+	return c
+}
+
+// Handler returns a middleware admitting the requests of the route group.
+// The requests of groups without limits are served directly.
+func (c *Controller) Handler(name string) func(h http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		g, ok := c.groups[name]
+		if !ok {
+			return h
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			release, ok := c.Admit(w, r, name)
+			if !ok {
+				return
+			}
+			defer release()
+
+			if g.limits.Deadline > 0 {
+				ctx, cancel := context.WithTimeout(r.Context(), g.limits.Deadline)
+				defer cancel()
+				r = r.WithContext(ctx)
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Admit admits the request of the route group and returns the function
+// releasing its slot, for the requests which are served beyond their
+// handler, like the upgraded streams. If the request is not admitted, the
+// response is written and false is returned.
+func (c *Controller) Admit(w http.ResponseWriter, r *http.Request, name string) (release func(), ok bool) {
+	g, ok := c.groups[name]
+	if !ok {
+		return func() {}, true
+	}
+
+	free, ok := c.admit(r.Context(), g)
+	if !ok {
+		c.metrics.Rejected.WithLabelValues(g.name).Inc()
+		w.Header().Set("Retry-After", retryAfter(g.limits.QueueTimeout))
+		jsonhttp.ServiceUnavailable(w, "too many requests, try again later")
+		return nil, false
+	}
+
+	c.metrics.InFlight.WithLabelValues(g.name).Inc()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.metrics.InFlight.WithLabelValues(g.name).Dec()
+			free()
+		})
+	}, true
+}
+
+// admit waits for a free slot of the group and returns the function
+// releasing it, or false if the request is not admitted.
+func (c *Controller) admit(ctx context.Context, g *group) (release func(), ok bool) {
+	if g.slots == nil {
+		return func() {}, true
+	}
+	release = func() { <-g.slots }
+
+	select {
+	case g.slots <- struct{}{}:
+		c.metrics.QueueWait.WithLabelValues(g.name).Observe(0)
+		return release, true
+	default:
+	}
+
+	g.mu.Lock()
+	if g.waiting >= g.limits.Queue {
+		g.mu.Unlock()
+		return nil, false
+	}
+	g.waiting++
+	g.mu.Unlock()
+	c.metrics.Queued.WithLabelValues(g.name).Inc()
+
+	defer func() {
+		g.mu.Lock()
+		g.waiting--
+		g.mu.Unlock()
+		c.metrics.Queued.WithLabelValues(g.name).Dec()
+	}()
+
+	start := time.Now()
+	timer := time.NewTimer(g.limits.QueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case g.slots <- struct{}{}:
+		c.metrics.QueueWait.WithLabelValues(g.name).Observe(time.Since(start).Seconds())
+		return release, true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	c.metrics.QueueWait.WithLabelValues(g.name).Observe(time.Since(start).Seconds())
+	return nil, false
+}
+
+// retryAfter returns the value of the Retry-After header in seconds.
+func retryAfter(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
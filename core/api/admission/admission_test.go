@@ -0,0 +1,175 @@
+package admission_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/redesblock/mop/core/api/admission"
+)
+
+func TestController(t *testing.T) {
+	c := admission.New(map[string]admission.Limits{
+		"upload": {Concurrency: 1, QueueTimeout: 2 * time.Second},
+	})
+
+	var (
+		started = make(chan struct{})
+		unblock = make(chan struct{})
+	)
+	upload := c.Handler("upload")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-unblock
+	}))
+	download := c.Handler("download")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	served := make(chan int)
+	go func() {
+		served <- serve(upload).Code
+	}()
+	<-started
+
+	rec := serve(upload)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Fatalf("got Retry-After %q, want %q", got, "2")
+	}
+
+	// other groups are not affected
+	if rec := serve(download); rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	close(unblock)
+	if code := <-served; code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestControllerQueue(t *testing.T) {
+	c := admission.New(map[string]admission.Limits{
+		"upload": {Concurrency: 1, Queue: 1, QueueTimeout: time.Minute},
+	})
+
+	var (
+		started = make(chan struct{}, 2)
+		unblock = make(chan struct{})
+	)
+	h := c.Handler("upload")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-unblock
+	}))
+
+	served := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			served <- serve(h).Code
+		}()
+	}
+	<-started
+
+	select {
+	case <-started:
+		t.Fatal("request over the concurrency limit served")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// the queued request is served once the first one is done
+	close(unblock)
+	for i := 0; i < 2; i++ {
+		if code := <-served; code != http.StatusOK {
+			t.Fatalf("got status %d, want %d", code, http.StatusOK)
+		}
+	}
+}
+
+func TestControllerQueueTimeout(t *testing.T) {
+	c := admission.New(map[string]admission.Limits{
+		"upload": {Concurrency: 1, Queue: 1, QueueTimeout: 50 * time.Millisecond},
+	})
+
+	var (
+		started = make(chan struct{})
+		unblock = make(chan struct{})
+	)
+	h := c.Handler("upload")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-unblock
+	}))
+	go serve(h)
+	<-started
+	defer close(unblock)
+
+	rec := serve(h)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Fatalf("got Retry-After %q, want %q", got, "1")
+	}
+}
+
+func TestControllerDeadline(t *testing.T) {
+	c := admission.New(map[string]admission.Limits{
+		"download": {Deadline: 10 * time.Millisecond},
+	})
+
+	var err error
+	h := c.Handler("download")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		err = r.Context().Err()
+	}))
+	serve(h)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestControllerAdmit(t *testing.T) {
+	c := admission.New(map[string]admission.Limits{
+		"upload": {Concurrency: 1, QueueTimeout: time.Second},
+	})
+
+	// the slot is held beyond the handler until it is released
+	admit := func() (func(), *httptest.ResponseRecorder) {
+		rec := httptest.NewRecorder()
+		release, ok := c.Admit(rec, httptest.NewRequest(http.MethodGet, "/", nil), "upload")
+		if !ok {
+			return nil, rec
+		}
+		return release, rec
+	}
+
+	release, _ := admit()
+	if release == nil {
+		t.Fatal("not admitted")
+	}
+	if _, rec := admit(); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	release()
+	release()
+	next, _ := admit()
+	if next == nil {
+		t.Fatal("not admitted after release")
+	}
+	next()
+
+	// groups without limits are always admitted
+	rec := httptest.NewRecorder()
+	if _, ok := c.Admit(rec, httptest.NewRequest(http.MethodGet, "/", nil), "download"); !ok {
+		t.Fatal("not admitted")
+	}
+}
+
+func serve(h http.Handler) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	return rec
+}
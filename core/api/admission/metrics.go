@@ -0,0 +1,52 @@
+package admission
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	m "github.com/redesblock/mop/core/metrics"
+)
+
+type metrics struct {
+	// all metrics fields must be exported
+	// to be able to return them by Metrics()
+	// using reflection
+	QueueWait *prometheus.HistogramVec
+	Queued    *prometheus.GaugeVec
+	InFlight  *prometheus.GaugeVec
+	Rejected  *prometheus.CounterVec
+}
+
+func newMetrics() metrics {
+	subsystem := "api_admission"
+
+	return metrics{
+		QueueWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "queue_wait_seconds",
+			Help:      "Histogram of the time requests wait to be served.",
+			Buckets:   []float64{0, 0.01, 0.05, 0.1, 0.5, 1, 2.5, 5, 10, 30},
+		}, []string{"group"}),
+		Queued: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "queued",
+			Help:      "Number of requests waiting to be served.",
+		}, []string{"group"}),
+		InFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "in_flight",
+			Help:      "Number of requests being served.",
+		}, []string{"group"}),
+		Rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "rejected_count",
+			Help:      "Number of requests refused as the limits are reached.",
+		}, []string{"group"}),
+	}
+}
+
+func (c *Controller) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(c.metrics)
+}
@@ -26,8 +26,10 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redesblock/mop/core/api/admission"
 	"github.com/redesblock/mop/core/api/auth"
 	"github.com/redesblock/mop/core/api/jsonhttp"
+	"github.com/redesblock/mop/core/availability"
 	"github.com/redesblock/mop/core/chain/transaction"
 	"github.com/redesblock/mop/core/cluster"
 	"github.com/redesblock/mop/core/crypto"
@@ -52,8 +54,11 @@ import (
 	"github.com/redesblock/mop/core/resolver"
 	"github.com/redesblock/mop/core/storer/storage"
 	"github.com/redesblock/mop/core/tags"
+	"github.com/redesblock/mop/core/tenant"
 	"github.com/redesblock/mop/core/tracer"
+	"github.com/redesblock/mop/core/traffic"
 	"github.com/redesblock/mop/core/traverser"
+	"github.com/redesblock/mop/core/usage"
 	"github.com/redesblock/mop/core/warden"
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/sync/semaphore"
@@ -73,6 +78,14 @@ const (
 	ClusterCollectionHeader     = "Cluster-Collection"
 	ClusterVoucherBatchIdHeader = "Cluster-Voucher-Batch-Id"
 	ClusterDeferredUploadHeader = "Cluster-Deferred-Upload"
+	ClusterStreamVersionHeader  = "Cluster-Stream-Version"
+	ClusterStreamSyncHeader     = "Cluster-Stream-Sync"
+)
+
+// Route groups with separate admission limits.
+const (
+	RouteGroupUpload   = "upload"
+	RouteGroupDownload = "download"
 )
 
 // The size of buffer used for prefetching content with Langos.
@@ -108,10 +121,11 @@ var (
 
 type authenticator interface {
 	Authorize(string) bool
-	GenerateKey(string, int) (string, error)
+	GenerateKey(string, string, bool, int) (string, error)
 	RefreshKey(string, int) (string, error)
 	Enforce(string, string, string) (bool, error)
 	SecretKey(string) (string, error)
+	Tenant(string) (string, bool, error)
 }
 
 type Service struct {
@@ -122,7 +136,13 @@ type Service struct {
 	pss             psser.Interface
 	traversal       traverser.Traverser
 	pinning         pins.Interface
+	pinCollections  *pins.Collections
 	warden          warden.Interface
+	availability    availability.Interface
+	usage           *usage.Service
+	trafficExporter *traffic.Exporter
+	tenants         *tenant.Registry
+	admission       *admission.Controller
 	logger          log.Logger
 	loggerV1        log.Logger
 	tracer          *tracer.Tracer
@@ -197,6 +217,12 @@ type Options struct {
 	WsPingPeriod       time.Duration
 	Restricted         bool
 	NATAddr            string
+	// DNSLinkGateway enables serving the content of custom domains, which
+	// are resolved by the name resolver from the request host.
+	DNSLinkGateway bool
+	// Admission are the admission limits of the route groups, keyed by
+	// RouteGroupUpload and RouteGroupDownload.
+	Admission map[string]admission.Limits
 }
 
 type ExtraOptions struct {
@@ -214,12 +240,17 @@ type ExtraOptions struct {
 	Pss              psser.Interface
 	TraversalService traverser.Traverser
 	Pinning          pins.Interface
+	PinCollections   *pins.Collections
 	FeedFactory      feeds.Factory
 	Post             voucher.Service
 	VoucherContract  vouchercontract.Interface
 	PledgeContract   pledge.Service
 	RewardContract   reward.Service
 	Warden           warden.Interface
+	Availability     availability.Interface
+	Usage            *usage.Service
+	TrafficExporter  *traffic.Exporter
+	Tenants          *tenant.Registry
 	SyncStatus       func() (bool, error)
 	StoreDirectory   func() string
 }
@@ -252,6 +283,7 @@ func (s *Service) Configure(signer crypto.Signer, auth authenticator, tracer *tr
 	s.Options = o
 	s.tracer = tracer
 	s.metrics = newMetrics()
+	s.admission = admission.New(o.Admission)
 
 	s.quit = make(chan struct{})
 
@@ -261,12 +293,17 @@ func (s *Service) Configure(signer crypto.Signer, auth authenticator, tracer *tr
 	s.pss = e.Pss
 	s.traversal = e.TraversalService
 	s.pinning = e.Pinning
+	s.pinCollections = e.PinCollections
 	s.feedFactory = e.FeedFactory
 	s.post = e.Post
 	s.voucherContract = e.VoucherContract
 	s.pledgeContract = e.PledgeContract
 	s.rewardContract = e.RewardContract
 	s.warden = e.Warden
+	s.availability = e.Availability
+	s.usage = e.Usage
+	s.trafficExporter = e.TrafficExporter
+	s.tenants = e.Tenants
 
 	s.pingpong = e.Pingpong
 	s.topologyDriver = e.TopologyDriver
@@ -292,7 +329,8 @@ func (s *Service) SetProbe(probe *Probe) {
 	s.probe = probe
 }
 
-// Close hangs up running websockets on shutdown.
+// Close hangs up running websockets on shutdown and exports the traffic
+// collected in memory.
 func (s *Service) Close() error {
 	s.logger.Info("api shutting down")
 	close(s.quit)
@@ -303,36 +341,43 @@ func (s *Service) Close() error {
 		s.wsWg.Wait()
 	}()
 
+	var err error
 	select {
 	case <-done:
 	case <-time.After(1 * time.Second):
-		return errors.New("api shutting down with open websockets")
+		err = errors.New("api shutting down with open websockets")
 	}
 
-	return nil
+	// the traffic of the hours which are not evicted yet is exported, so
+	// that it is spooled by the exporter and not lost
+	if s.lru != nil {
+		s.lru.Purge()
+	}
+
+	return err
 }
 
 // getOrCreateTag attempts to get the tag if an id is supplied, and returns an error if it does not exist.
 // If no id is supplied, it will attempt to create a new tag with a generated name and return it.
-func (s *Service) getOrCreateTag(tagUid string) (*tags.Tag, bool, error) {
+func (s *Service) getOrCreateTag(ctx context.Context, tagUid string) (*tags.Tag, bool, error) {
 	// if tag ID is not supplied, create a new tag
 	if tagUid == "" {
-		tag, err := s.tags.Create(0)
+		tag, err := s.createTag(ctx)
 		if err != nil {
 			return nil, false, fmt.Errorf("cannot create tag: %w", err)
 		}
 		return tag, true, nil
 	}
-	t, err := s.getTag(tagUid)
+	t, err := s.getTag(ctx, tagUid)
 	return t, false, err
 }
 
-func (s *Service) getTag(tagUid string) (*tags.Tag, error) {
+func (s *Service) getTag(ctx context.Context, tagUid string) (*tags.Tag, error) {
 	uid, err := strconv.Atoi(tagUid)
 	if err != nil {
 		return nil, fmt.Errorf("cannot parse taguid: %w", err)
 	}
-	return s.tags.Get(uint32(uid))
+	return s.ownedTag(ctx, uint32(uid))
 }
 
 func (s *Service) resolveNameOrAddress(str string) (cluster.Address, error) {
@@ -402,8 +447,12 @@ type securityTokenReq struct {
 	Expiry int    `json:"expiry"`
 }
 
+// authHandler issues a security token for the credential of the basic
+// authentication. The password of the node issues the tokens of the
+// administrators, while the name and the password of a tenant issue the
+// tokens bound to the tenant.
 func (s *Service) authHandler(w http.ResponseWriter, r *http.Request) {
-	_, pass, ok := r.BasicAuth()
+	user, pass, ok := r.BasicAuth()
 
 	if !ok {
 		s.logger.Error(nil, "auth handler: missing basic auth")
@@ -412,7 +461,23 @@ func (s *Service) authHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !s.auth.Authorize(pass) {
+	var (
+		name  string
+		admin = s.auth.Authorize(pass)
+	)
+	if !admin && s.tenants != nil {
+		authorized, err := s.tenants.Authorize(user, pass)
+		if err != nil {
+			s.logger.Debug("auth handler: authorize tenant failed", "tenant", user, "error", err)
+			s.logger.Error(nil, "auth handler: authorize tenant failed")
+			jsonhttp.InternalServerError(w, "Error authorizing tenant")
+			return
+		}
+		if authorized {
+			name = user
+		}
+	}
+	if !admin && name == "" {
 		s.logger.Error(nil, "auth handler: unauthorized")
 		w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
 		jsonhttp.Unauthorized(w, "Unauthorized")
@@ -435,7 +500,7 @@ func (s *Service) authHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	key, err := s.auth.GenerateKey(payload.Role, payload.Expiry)
+	key, err := s.auth.GenerateKey(payload.Role, name, admin, payload.Expiry)
 	if errors.Is(err, auth.ErrExpiry) {
 		s.logger.Debug("auth handler: generate key failed", "error", err)
 		s.logger.Error(nil, "auth handler: generate key failed")
@@ -647,7 +712,7 @@ func (s *Service) newStamperPutter(r *http.Request) (storage.Storer, func() erro
 		return nil, noopWaitFn, fmt.Errorf("batch exists: %w", err)
 	}
 
-	issuer, err := s.post.GetStampIssuer(batch)
+	issuer, err := s.stampIssuer(r.Context(), batch)
 	if err != nil {
 		return nil, noopWaitFn, fmt.Errorf("stamp issuer: %w", err)
 	}
@@ -670,6 +735,7 @@ type pushStamperPutter struct {
 	eg      errgroup.Group
 	c       chan *pusher.Op
 	sem     chan struct{}
+	synced  func(context.Context, cluster.Address)
 }
 
 func newPushStamperPutter(s storage.Storer, post voucher.Service, signer crypto.Signer, batch []byte, cc chan *pusher.Op) (*pushStamperPutter, error) {
@@ -695,9 +761,12 @@ func (p *pushStamperPutter) Put(ctx context.Context, mode storage.ModePut, chs .
 			exists[i] = true
 			continue
 		}
-		stamp, err := p.stamper.Stamp(c.Address())
-		if err != nil {
-			return nil, err
+		if c.Stamp() == nil {
+			stamp, err := p.stamper.Stamp(c.Address())
+			if err != nil {
+				return nil, err
+			}
+			c = c.WithStamp(stamp)
 		}
 
 		func(ch cluster.Chunk) {
@@ -719,9 +788,11 @@ func (p *pushStamperPutter) Put(ctx context.Context, mode storage.ModePut, chs .
 						if _, err := p.Storer.Put(ctx, storage.ModePutSync, ch); err != nil {
 							return err
 						}
+						p.notifySynced(ctx, ch.Address())
 						return nil
 					}
 					if err == nil {
+						p.notifySynced(ctx, ch.Address())
 						return nil
 					}
 					goto PUSH
@@ -729,11 +800,19 @@ func (p *pushStamperPutter) Put(ctx context.Context, mode storage.ModePut, chs .
 					return ctx.Err()
 				}
 			})
-		}(c.WithStamp(stamp))
+		}(c)
 	}
 	return exists, nil
 }
 
+// notifySynced calls the synced callback, if set, with the context of the
+// put once the chunk is pushed to the network.
+func (p *pushStamperPutter) notifySynced(ctx context.Context, addr cluster.Address) {
+	if p.synced != nil {
+		p.synced(ctx, addr)
+	}
+}
+
 type stamperPutter struct {
 	storage.Storer
 	stamper voucher.Stamper
@@ -765,11 +844,13 @@ func (p *stamperPutter) Put(ctx context.Context, mode storage.ModePut, chs ...cl
 			exists[i] = true
 			continue
 		}
-		stamp, err := p.stamper.Stamp(c.Address())
-		if err != nil {
-			return nil, err
+		if c.Stamp() == nil {
+			stamp, err := p.stamper.Stamp(c.Address())
+			if err != nil {
+				return nil, err
+			}
+			chs[i] = c.WithStamp(stamp)
 		}
-		chs[i] = c.WithStamp(stamp)
 		ctp = append(ctp, chs[i])
 		idx = append(idx, i)
 	}
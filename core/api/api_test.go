@@ -20,8 +20,10 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/gorilla/websocket"
 	"github.com/redesblock/mop/core/api"
+	"github.com/redesblock/mop/core/api/admission"
 	mockauth "github.com/redesblock/mop/core/api/auth/mock"
 	"github.com/redesblock/mop/core/api/jsonhttp/jsonhttptest"
+	"github.com/redesblock/mop/core/availability"
 	"github.com/redesblock/mop/core/chain/transaction/backendmock"
 	transactionmock "github.com/redesblock/mop/core/chain/transaction/mock"
 	"github.com/redesblock/mop/core/cluster"
@@ -53,8 +55,11 @@ import (
 	"github.com/redesblock/mop/core/storer/storage/mock"
 	testingc "github.com/redesblock/mop/core/storer/storage/testing"
 	"github.com/redesblock/mop/core/tags"
+	"github.com/redesblock/mop/core/tenant"
 	"github.com/redesblock/mop/core/tracer"
+	"github.com/redesblock/mop/core/traffic"
 	"github.com/redesblock/mop/core/traverser"
+	"github.com/redesblock/mop/core/usage"
 	"github.com/redesblock/mop/core/warden"
 	"resenje.org/web"
 )
@@ -88,11 +93,19 @@ type testServerOptions struct {
 	VoucherContract    vouchercontract.Interface
 	Post               voucher.Service
 	Steward            warden.Interface
+	Availability       availability.Interface
+	Usage              *usage.Service
+	TrafficExporter    *traffic.Exporter
+	Tenants            *tenant.Registry
+	PinCollections     *pins.Collections
 	WsHeaders          http.Header
 	Authenticator      *mockauth.Auth
 	DebugAPI           bool
 	Restricted         bool
 	DirectUpload       bool
+	PushGate           chan struct{}
+	DNSLinkGateway     bool
+	Admission          map[string]admission.Limits
 
 	Overlay         cluster.Address
 	PublicKey       ecdsa.PublicKey
@@ -137,6 +150,9 @@ func newTestServer(t *testing.T, o testServerOptions) (*http.Client, *websocket.
 	if o.BatchStore == nil {
 		o.BatchStore = mockbatchstore.New(mockbatchstore.WithAcceptAllExistsFunc()) // default is with accept-all Exists() func
 	}
+	if o.Tenants == nil {
+		o.Tenants = tenant.New(statestore.NewStateStore())
+	}
 	if o.SyncStatus == nil {
 		o.SyncStatus = func() (bool, error) { return true, nil }
 	}
@@ -177,10 +193,15 @@ func newTestServer(t *testing.T, o testServerOptions) (*http.Client, *websocket.
 		Pss:              o.Pss,
 		TraversalService: o.Traversal,
 		Pinning:          o.Pinning,
+		PinCollections:   o.PinCollections,
 		FeedFactory:      o.Feeds,
 		Post:             o.Post,
 		VoucherContract:  o.VoucherContract,
 		Warden:           o.Steward,
+		Availability:     o.Availability,
+		Usage:            o.Usage,
+		TrafficExporter:  o.TrafficExporter,
+		Tenants:          o.Tenants,
 		SyncStatus:       o.SyncStatus,
 	}
 
@@ -199,17 +220,19 @@ func newTestServer(t *testing.T, o testServerOptions) (*http.Client, *websocket.
 		CORSAllowedOrigins: o.CORSAllowedOrigins,
 		WsPingPeriod:       o.WsPingPeriod,
 		Restricted:         o.Restricted,
+		DNSLinkGateway:     o.DNSLinkGateway,
+		Admission:          o.Admission,
 	}, extraOpts, 1, erc20)
 
 	if o.DebugAPI {
 		s.MountTechnicalDebug()
-		s.MountDebug(false)
+		s.MountDebug(o.Restricted)
 	} else {
 		s.MountAPI()
 	}
 
 	if o.DirectUpload {
-		chanStore = newChanStore(chC)
+		chanStore = newChanStore(chC, o.PushGate)
 		t.Cleanup(chanStore.stop)
 	}
 
@@ -579,12 +602,14 @@ func TestVoucherDirectAndDeferred(t *testing.T) {
 
 type chanStorer struct {
 	chunks map[string]struct{}
+	gate   chan struct{} // if set, each push waits for a value on it
 	quit   chan struct{}
 }
 
-func newChanStore(cc <-chan *pusher.Op) *chanStorer {
+func newChanStore(cc <-chan *pusher.Op, gate chan struct{}) *chanStorer {
 	c := &chanStorer{
 		chunks: make(map[string]struct{}),
+		gate:   gate,
 		quit:   make(chan struct{}),
 	}
 	go c.drain(cc)
@@ -595,6 +620,13 @@ func (c *chanStorer) drain(cc <-chan *pusher.Op) {
 	for {
 		select {
 		case op := <-cc:
+			if c.gate != nil {
+				select {
+				case <-c.gate:
+				case <-c.quit:
+					return
+				}
+			}
 			c.chunks[op.Chunk.Address().ByteString()] = struct{}{}
 			op.Err <- nil
 		case <-c.quit:
@@ -0,0 +1,293 @@
+package api
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/redesblock/mop/core/api/jsonhttp"
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/file/joiner"
+	"github.com/redesblock/mop/core/langos"
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/manifest"
+	"github.com/redesblock/mop/core/tracer"
+)
+
+const (
+	archiveFormatTar = "tar"
+	archiveFormatZip = "zip"
+
+	contentTypeZip = "application/zip"
+
+	// archivePrefetchFiles is the number of files which are opened
+	// and prefetched ahead of the one currently written to the archive.
+	archivePrefetchFiles = 4
+	// archivePrefetchMaxSize is the size up to which the whole file is
+	// prefetched. Larger files are streamed with a lookahead buffer.
+	archivePrefetchMaxSize = smallFileBufferSize
+
+	// paxRecordPrefix is the prefix of the pax records holding the
+	// manifest entry reference and metadata in the tar archive.
+	paxRecordPrefix = "MOP."
+	// zipExtraFieldID is the header id of the zip extra field holding the
+	// manifest entry reference and metadata in the zip archive.
+	zipExtraFieldID = 0x4f4d
+)
+
+// archiveWriter writes manifest files into an archive stream.
+type archiveWriter interface {
+	writeFile(path string, size int64, reference cluster.Address, metadata map[string]string, r io.Reader) error
+	Close() error
+}
+
+type tarArchiveWriter struct {
+	w       *tar.Writer
+	modTime time.Time
+}
+
+func (a *tarArchiveWriter) writeFile(path string, size int64, reference cluster.Address, metadata map[string]string, r io.Reader) error {
+	records := map[string]string{
+		paxRecordPrefix + "reference": reference.String(),
+	}
+	for k, v := range metadata {
+		records[paxRecordPrefix+k] = v
+	}
+	if err := a.w.WriteHeader(&tar.Header{
+		Typeflag:   tar.TypeReg,
+		Name:       path,
+		Size:       size,
+		Mode:       0644,
+		ModTime:    a.modTime,
+		PAXRecords: records,
+		Format:     tar.FormatPAX,
+	}); err != nil {
+		return err
+	}
+	_, err := io.Copy(a.w, r)
+	return err
+}
+
+func (a *tarArchiveWriter) Close() error {
+	return a.w.Close()
+}
+
+type zipArchiveWriter struct {
+	w       *zip.Writer
+	modTime time.Time
+}
+
+// zipExtraField is the content of the zip extra field with the
+// zipExtraFieldID header id.
+type zipExtraField struct {
+	Reference cluster.Address   `json:"reference"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+func (a *zipArchiveWriter) writeFile(path string, size int64, reference cluster.Address, metadata map[string]string, r io.Reader) error {
+	data, err := json.Marshal(zipExtraField{Reference: reference, Metadata: metadata})
+	if err != nil {
+		return err
+	}
+	if len(data) > 1<<16-1 {
+		return fmt.Errorf("zip extra field of %d bytes too large", len(data))
+	}
+	extra := make([]byte, 4, 4+len(data))
+	binary.LittleEndian.PutUint16(extra[0:2], zipExtraFieldID)
+	binary.LittleEndian.PutUint16(extra[2:4], uint16(len(data)))
+	extra = append(extra, data...)
+
+	h := &zip.FileHeader{
+		Name:               path,
+		Method:             zip.Store,
+		Modified:           a.modTime,
+		Comment:            reference.String(),
+		Extra:              extra,
+		UncompressedSize64: uint64(size),
+	}
+	h.SetMode(0644)
+	fw, err := a.w.CreateHeader(h)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, r)
+	return err
+}
+
+func (a *zipArchiveWriter) Close() error {
+	return a.w.Close()
+}
+
+type archiveFile struct {
+	path   string
+	entry  manifest.Entry
+	size   int64
+	reader io.Reader
+	err    error
+}
+
+// serveManifestArchive streams all the files of the manifest as a tar or
+// zip archive. Up to archivePrefetchFiles files ahead of the archive writer
+// are opened concurrently, so that the chunks of the following files are
+// retrieved while the memory usage stays bounded.
+func (s *Service) serveManifestArchive(
+	w http.ResponseWriter,
+	r *http.Request,
+	address cluster.Address,
+	m manifest.Interface,
+	format string,
+	etag bool,
+) {
+	logger := tracer.NewLoggerWithTraceID(r.Context(), s.logger)
+
+	var contentType string
+	switch format {
+	case archiveFormatTar:
+		contentType = contentTypeTar
+	case archiveFormatZip:
+		contentType = contentTypeZip
+	default:
+		jsonhttp.BadRequest(w, "bad archive format")
+		return
+	}
+
+	emptyAddr := cluster.NewAddress(make([]byte, cluster.HashSize))
+	var entries []archiveFile
+	err := m.IterateEntries(r.Context(), func(path string, entry manifest.Entry) error {
+		if path == manifest.RootPath {
+			return nil
+		}
+		if ref := entry.Reference(); ref.IsZero() || ref.Equal(emptyAddr) {
+			return nil
+		}
+		entries = append(entries, archiveFile{path: path, entry: entry})
+		return nil
+	})
+	if err != nil {
+		logger.Debug("mop archive: iterate manifest failed", "address", address, "error", err)
+		logger.Error(nil, "mop archive: iterate manifest failed")
+		jsonhttp.NotFound(w, nil)
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].path < entries[j].path
+	})
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	files := make(chan chan archiveFile, archivePrefetchFiles)
+	go s.prefetchArchiveFiles(ctx, entries, files)
+
+	w.Header().Set(contentTypeHeader, contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.%s\"", address, format))
+	w.Header().Set("Access-Control-Expose-Headers", "Content-Disposition")
+	if etag {
+		w.Header().Set("ETag", fmt.Sprintf("%q", address))
+	}
+
+	var aw archiveWriter
+	modTime := time.Now()
+	switch format {
+	case archiveFormatTar:
+		aw = &tarArchiveWriter{w: tar.NewWriter(w), modTime: modTime}
+	case archiveFormatZip:
+		aw = &zipArchiveWriter{w: zip.NewWriter(w), modTime: modTime}
+	}
+
+	for c := range files {
+		f := <-c
+		if f.err != nil {
+			logger.Debug("mop archive: open file failed", "address", address, "path", f.path, "error", f.err)
+			logger.Error(nil, "mop archive: open file failed")
+			abortArchive(w, logger)
+			return
+		}
+		err := aw.writeFile(f.path, f.size, f.entry.Reference(), f.entry.Metadata(), f.reader)
+		if err != nil {
+			logger.Debug("mop archive: write file failed", "address", address, "path", f.path, "error", err)
+			logger.Error(nil, "mop archive: write file failed")
+			abortArchive(w, logger)
+			return
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		logger.Debug("mop archive: canceled", "address", address, "error", err)
+		abortArchive(w, logger)
+		return
+	}
+
+	if err := aw.Close(); err != nil {
+		logger.Debug("mop archive: close archive failed", "address", address, "error", err)
+		logger.Error(nil, "mop archive: close archive failed")
+		abortArchive(w, logger)
+	}
+}
+
+// abortArchive aborts the archive response. The status has already been
+// sent, so the connection is closed without the end of the response body
+// and the client gets an unexpected EOF instead of a truncated archive.
+func abortArchive(w http.ResponseWriter, logger log.Logger) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		logger.Debug("mop archive: abort failed", "error", errors.New("response writer does not support hijacking"))
+		return
+	}
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		logger.Debug("mop archive: abort failed", "error", err)
+		return
+	}
+	if err := conn.Close(); err != nil {
+		logger.Debug("mop archive: abort failed", "error", err)
+	}
+}
+
+// prefetchArchiveFiles opens the files concurrently and sends them in order
+// to the files channel, each one over its own channel which receives the
+// file once it is opened. The capacity of the files channel bounds the
+// number of files opened ahead of the one written to the archive.
+func (s *Service) prefetchArchiveFiles(ctx context.Context, entries []archiveFile, files chan<- chan archiveFile) {
+	defer close(files)
+
+	for _, f := range entries {
+		c := make(chan archiveFile, 1)
+		select {
+		case files <- c:
+		case <-ctx.Done():
+			return
+		}
+		go func(f archiveFile) {
+			c <- s.openArchiveFile(ctx, f)
+		}(f)
+	}
+}
+
+// openArchiveFile opens the file of the manifest entry. Small files are
+// read in full, large ones are wrapped in langos.
+func (s *Service) openArchiveFile(ctx context.Context, f archiveFile) archiveFile {
+	reader, size, err := joiner.New(ctx, s.storer, f.entry.Reference())
+	if err != nil {
+		f.err = err
+		return f
+	}
+	if size <= archivePrefetchMaxSize {
+		buf := bytes.NewBuffer(make([]byte, 0, size))
+		if _, err := io.Copy(buf, reader); err != nil {
+			f.err = err
+		}
+		f.size, f.reader = size, buf
+		return f
+	}
+	f.size, f.reader = size, langos.NewBufferedLangos(reader, lookaheadBufferSize(size))
+	return f
+}
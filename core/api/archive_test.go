@@ -0,0 +1,279 @@
+package api_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/redesblock/mop/core/api"
+	"github.com/redesblock/mop/core/api/auth"
+	"github.com/redesblock/mop/core/api/auth/mock"
+	"github.com/redesblock/mop/core/api/jsonhttp"
+	"github.com/redesblock/mop/core/api/jsonhttp/jsonhttptest"
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/file/loadsave"
+	"github.com/redesblock/mop/core/file/pipeline/builder"
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/manifest"
+	"github.com/redesblock/mop/core/storer/storage"
+	smock "github.com/redesblock/mop/core/storer/storage/mock"
+	testingc "github.com/redesblock/mop/core/storer/storage/testing"
+)
+
+func TestMopArchive(t *testing.T) {
+	var (
+		storerMock      = smock.NewStorer()
+		client, _, _, _ = newTestServer(t, testServerOptions{
+			Storer: storerMock,
+			Logger: log.Noop,
+		})
+		ctx     = context.Background()
+		factory = pipelineFactory(storerMock, storage.ModePutUpload, false)
+		files   = map[string]string{
+			"index.html":      "<h1>index</h1>",
+			"docs/a.txt":      "a",
+			"docs/sub/c.json": "{}",
+			"large.bin":       strings.Repeat("0123456789", 40000),
+		}
+	)
+
+	m, err := manifest.NewDefaultManifest(loadsave.New(storerMock, factory), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := make(map[string]cluster.Address)
+	for p, data := range files {
+		ref, err := builder.FeedPipeline(ctx, factory(), strings.NewReader(data), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		refs[p] = ref
+		if err := m.Add(ctx, p, manifest.NewEntry(ref, map[string]string{
+			manifest.EntryMetadataFilenameKey:    p,
+			manifest.EntryMetadataContentTypeKey: "text/plain",
+		})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Add(ctx, manifest.RootPath, manifest.NewEntry(cluster.ZeroAddress, map[string]string{
+		manifest.WebsiteIndexDocumentSuffixKey: "index.html",
+	})); err != nil {
+		t.Fatal(err)
+	}
+	root, err := m.Store(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPaths := []string{"docs/a.txt", "docs/sub/c.json", "index.html", "large.bin"}
+
+	t.Run("tar", func(t *testing.T) {
+		resp := request(t, client, http.MethodGet, "/mop/"+root.String()+"?format=tar", nil, http.StatusOK)
+		defer resp.Body.Close()
+		if ct := resp.Header.Get(api.ContentTypeHeader); ct != "application/x-tar" {
+			t.Fatalf("got content type %q", ct)
+		}
+
+		tr := tar.NewReader(resp.Body)
+		var paths []string
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			paths = append(paths, hdr.Name)
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != files[hdr.Name] {
+				t.Fatalf("%s: unexpected content of length %d", hdr.Name, len(data))
+			}
+			if got := hdr.PAXRecords["MOP.reference"]; got != refs[hdr.Name].String() {
+				t.Fatalf("%s: got reference %q, want %q", hdr.Name, got, refs[hdr.Name])
+			}
+			if got := hdr.PAXRecords["MOP."+manifest.EntryMetadataContentTypeKey]; got != "text/plain" {
+				t.Fatalf("%s: got content type record %q", hdr.Name, got)
+			}
+		}
+		assertArchivePaths(t, paths, wantPaths)
+	})
+
+	t.Run("zip", func(t *testing.T) {
+		resp := request(t, client, http.MethodGet, "/mop/"+root.String()+"?format=zip", nil, http.StatusOK)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var paths []string
+		for _, f := range zr.File {
+			paths = append(paths, f.Name)
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != files[f.Name] {
+				t.Fatalf("%s: unexpected content of length %d", f.Name, len(data))
+			}
+			field := zipExtraField(t, f.Extra, 0x4f4d)
+			if !field.Reference.Equal(refs[f.Name]) {
+				t.Fatalf("%s: got reference %s, want %s", f.Name, field.Reference, refs[f.Name])
+			}
+			if got := field.Metadata[manifest.EntryMetadataContentTypeKey]; got != "text/plain" {
+				t.Fatalf("%s: got content type metadata %q", f.Name, got)
+			}
+		}
+		assertArchivePaths(t, paths, wantPaths)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		m, err := manifest.NewDefaultManifest(loadsave.New(storerMock, factory), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for p, ref := range map[string]cluster.Address{
+			"a.bin": refs["large.bin"],
+			"b.txt": testingc.GenerateTestRandomChunk().Address(),
+		} {
+			if err := m.Add(ctx, p, manifest.NewEntry(ref, nil)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		root, err := m.Store(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/mop/"+root.String()+"?format=tar", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			// the response was aborted before the status was sent
+			return
+		}
+		defer resp.Body.Close()
+
+		tr := tar.NewReader(resp.Body)
+		for {
+			_, err := tr.Next()
+			if err == io.EOF {
+				t.Fatal("got a complete archive, want an aborted response")
+			}
+			if err != nil {
+				return
+			}
+			if _, err := io.Copy(io.Discard, tr); err != nil {
+				return
+			}
+		}
+	})
+
+	t.Run("bad format", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodGet, "/mop/"+root.String()+"?format=rar", http.StatusBadRequest)
+	})
+
+	t.Run("restricted", func(t *testing.T) {
+		client, _, _, _ := newTestServer(t, testServerOptions{
+			Storer:     storerMock,
+			Logger:     log.Noop,
+			Restricted: true,
+			Authenticator: &mock.Auth{
+				EnforceFunc: func(key, _, _ string) (bool, error) {
+					if key == "expired" {
+						return false, auth.ErrTokenExpired
+					}
+					return key == "allowed", nil
+				},
+			},
+		})
+		resource := "/mop/" + root.String() + "?format=tar"
+
+		jsonhttptest.Request(t, client, http.MethodGet, resource, http.StatusForbidden,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "Missing bearer token",
+				Code:    http.StatusForbidden,
+			}),
+		)
+		jsonhttptest.Request(t, client, http.MethodGet, resource, http.StatusUnauthorized,
+			jsonhttptest.WithRequestHeader("Authorization", "Bearer expired"),
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "Token expired",
+				Code:    http.StatusUnauthorized,
+			}),
+		)
+		jsonhttptest.Request(t, client, http.MethodGet, resource, http.StatusForbidden,
+			jsonhttptest.WithRequestHeader("Authorization", "Bearer denied"),
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "Provided security token does not grant access to the resource",
+				Code:    http.StatusForbidden,
+			}),
+		)
+		// the url of the archive has to be signed as other downloads
+		jsonhttptest.Request(t, client, http.MethodGet, resource, http.StatusForbidden,
+			jsonhttptest.WithRequestHeader("Authorization", "Bearer allowed"),
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "Provided sign does not grant access to the resource",
+				Code:    http.StatusForbidden,
+			}),
+		)
+	})
+}
+
+func assertArchivePaths(t *testing.T, got, want []string) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got paths %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got paths %v, want %v", got, want)
+		}
+	}
+}
+
+// zipExtraField decodes the zip extra field with the given header id.
+func zipExtraField(t *testing.T, extra []byte, id uint16) (field struct {
+	Reference cluster.Address   `json:"reference"`
+	Metadata  map[string]string `json:"metadata"`
+}) {
+	t.Helper()
+
+	for len(extra) >= 4 {
+		size := int(binary.LittleEndian.Uint16(extra[2:4]))
+		if len(extra) < 4+size {
+			break
+		}
+		if binary.LittleEndian.Uint16(extra[0:2]) == id {
+			if err := json.Unmarshal(extra[4:4+size], &field); err != nil {
+				t.Fatal(err)
+			}
+			return field
+		}
+		extra = extra[4+size:]
+	}
+	t.Fatalf("zip extra field %#x not found", id)
+	return field
+}
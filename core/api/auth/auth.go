@@ -25,6 +25,8 @@ const loggerName = "auth"
 type authRecord struct {
 	Role   string    `json:"r"`
 	Secret string    `json:"s"`
+	Tenant string    `json:"t,omitempty"`
+	Admin  bool      `json:"a,omitempty"`
 	Expiry time.Time `json:"e"`
 }
 
@@ -86,7 +88,11 @@ func (a *Authenticator) Authorize(password string) bool {
 
 var ErrExpiry = errors.New("expiry duration must be a positive number")
 
-func (a *Authenticator) GenerateKey(role string, expiryDuration int) (string, error) {
+// GenerateKey returns a new security token of the role bound to the tenant,
+// which is not bound to any tenant if empty. The admin flag marks the tokens
+// issued for the credential of the node, which reach the objects of all the
+// tenants.
+func (a *Authenticator) GenerateKey(role, tenant string, admin bool, expiryDuration int) (string, error) {
 	if expiryDuration == 0 {
 		return "", ErrExpiry
 	}
@@ -101,6 +107,8 @@ func (a *Authenticator) GenerateKey(role string, expiryDuration int) (string, er
 	ar := authRecord{
 		Role:   role,
 		Secret: secret,
+		Tenant: tenant,
+		Admin:  admin && tenant == "",
 		Expiry: time.Now().Add(time.Second * time.Duration(expiryDuration)),
 	}
 
@@ -217,6 +225,32 @@ func (a *Authenticator) SecretKey(apiKey string) (string, error) {
 	return ar.Secret, nil
 }
 
+// Tenant returns the tenant the security token is bound to, empty if none,
+// and whether the token was issued to an administrator. Tokens which can
+// not be decoded are only logged at the debug level as every request may
+// bear one.
+func (a *Authenticator) Tenant(apiKey string) (tenant string, admin bool, err error) {
+	decoded, err := base64.StdEncoding.DecodeString(apiKey)
+	if err != nil {
+		a.log.Debug("decode token failed", "error", err)
+		return "", false, err
+	}
+
+	decryptedBytes, err := a.ciph.decrypt(decoded)
+	if err != nil {
+		a.log.Debug("decrypt token failed", "error", err)
+		return "", false, err
+	}
+
+	var ar authRecord
+	if err := json.Unmarshal(decryptedBytes, &ar); err != nil {
+		a.log.Debug("unmarshal token failed", "error", err)
+		return "", false, err
+	}
+
+	return ar.Tenant, ar.Admin, nil
+}
+
 type encrypter struct {
 	gcm cipher.AEAD
 }
@@ -283,6 +317,7 @@ func applyPolicies(e *casbin.Enforcer) error {
 		{"creator", "/soc/*/*", "POST"},
 		{"creator", "/feeds/*/*", "POST"},
 		{"consumer", "/feeds/*/*", "GET"},
+		{"maintainer", "/names/*", "POST"},
 		{"maintainer", "/stamps", "GET"},
 		{"maintainer", "/stamps/*", "GET"},
 		{"maintainer", "/stamps/*/*", "POST"},
@@ -294,6 +329,7 @@ func applyPolicies(e *casbin.Enforcer) error {
 		{"maintainer", "/peers", "GET"},
 		{"maintainer", "/peers/*", "DELETE"},
 		{"maintainer", "/pingpong/*", "POST"},
+		{"maintainer", "/reachability", "GET"},
 		{"maintainer", "/topology", "GET"},
 		{"maintainer", "/welcome-message", "(GET)|(POST)"},
 		{"maintainer", "/balances", "GET"},
@@ -316,6 +352,7 @@ func applyPolicies(e *casbin.Enforcer) error {
 		{"maintainer", "/settlements/*", "GET"},
 		{"maintainer", "/settlements", "GET"},
 		{"maintainer", "/transactions", "GET"},
+		{"maintainer", "/tenants/*", "(PUT)|(DELETE)"},
 		{"consumer", "/transactions/*", "GET"},
 		{"accountant", "/transactions/*", "(POST)|(DELETE)"},
 		{"consumer", "/consumed", "GET"},
@@ -323,6 +360,7 @@ func applyPolicies(e *casbin.Enforcer) error {
 		{"consumer", "/chunks/stream", "GET"},
 		{"creator", "/wardenship/*", "GET"},
 		{"consumer", "/wardenship/*", "PUT"},
+		{"creator", "/check/*", "GET"},
 	})
 
 	if err != nil {
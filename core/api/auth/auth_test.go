@@ -51,7 +51,7 @@ func TestExpiry(t *testing.T) {
 		t.Error(err)
 	}
 
-	key, err := a.GenerateKey("consumer", 1)
+	key, err := a.GenerateKey("consumer", "", false, 1)
 	if err != nil {
 		t.Errorf("expected no error, got: %v", err)
 	}
@@ -110,11 +110,23 @@ func TestEnforce(t *testing.T) {
 			resource: "/pingpong/someone",
 			action:   "DELETE",
 		},
+		{
+			desc:     "list pins",
+			role:     "creator",
+			resource: "/pins",
+			action:   "GET",
+		},
+		{
+			desc:     "list batches",
+			role:     "creator",
+			resource: "/stamps",
+			action:   "GET",
+		},
 	}
 
 	for _, tC := range tt {
 		t.Run(tC.desc, func(t *testing.T) {
-			apiKey, err := a.GenerateKey(tC.role, 1)
+			apiKey, err := a.GenerateKey(tC.role, "", false, 1)
 
 			if err != nil {
 				t.Errorf("expected no error, got: %v", err)
@@ -132,3 +144,58 @@ func TestEnforce(t *testing.T) {
 		})
 	}
 }
+
+func TestTenant(t *testing.T) {
+	a, err := auth.New(encryptionKey, passwordHash, log.Noop)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		desc, role, tenant string
+		admin, wantAdmin   bool
+	}{
+		{
+			desc:      "administrator",
+			role:      "creator",
+			admin:     true,
+			wantAdmin: true,
+		},
+		{
+			desc: "maintainer",
+			role: "maintainer",
+		},
+		{
+			desc:   "maintainer of tenant",
+			role:   "maintainer",
+			tenant: "a",
+		},
+		{
+			desc:   "administrator bound to tenant",
+			role:   "maintainer",
+			tenant: "a",
+			admin:  true,
+		},
+	}
+
+	for _, tC := range tt {
+		t.Run(tC.desc, func(t *testing.T) {
+			apiKey, err := a.GenerateKey(tC.role, tC.tenant, tC.admin, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tenant, admin, err := a.Tenant(apiKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tenant != tC.tenant || admin != tC.wantAdmin {
+				t.Errorf("got tenant %q and admin %v, want %q and %v", tenant, admin, tC.tenant, tC.wantAdmin)
+			}
+		})
+	}
+
+	if _, _, err := a.Tenant("invalid"); err == nil {
+		t.Error("expected error for an invalid token")
+	}
+}
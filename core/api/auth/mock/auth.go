@@ -3,7 +3,9 @@ package mock
 type Auth struct {
 	AuthorizeFunc   func(string) bool
 	GenerateKeyFunc func(string) (string, error)
+	TenantKeyFunc   func(role, tenant string, admin bool) (string, error)
 	EnforceFunc     func(string, string, string) (bool, error)
+	TenantFunc      func(string) (string, bool, error)
 }
 
 func (ma *Auth) Authorize(u string) bool {
@@ -12,7 +14,10 @@ func (ma *Auth) Authorize(u string) bool {
 	}
 	return ma.AuthorizeFunc(u)
 }
-func (ma *Auth) GenerateKey(k string, _ int) (string, error) {
+func (ma *Auth) GenerateKey(k, tenant string, admin bool, _ int) (string, error) {
+	if ma.TenantKeyFunc != nil {
+		return ma.TenantKeyFunc(k, tenant, admin)
+	}
 	if ma.GenerateKeyFunc == nil {
 		return "", nil
 	}
@@ -30,3 +35,9 @@ func (ma *Auth) Enforce(a1 string, a2 string, a3 string) (bool, error) {
 func (ma *Auth) SecretKey(a1 string) (string, error) {
 	return "", nil
 }
+func (ma *Auth) Tenant(a1 string) (string, bool, error) {
+	if ma.TenantFunc == nil {
+		return "", false, nil
+	}
+	return ma.TenantFunc(a1)
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/redesblock/mop/core/storer/storage"
 	"github.com/redesblock/mop/core/tags"
 	"github.com/redesblock/mop/core/tracer"
+	"github.com/redesblock/mop/core/usage"
 	"github.com/redesblock/mop/core/util/ioutil"
 )
 
@@ -44,7 +45,7 @@ func (s *Service) bytesUploadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tag, created, err := s.getOrCreateTag(r.Header.Get(ClusterTagHeader))
+	tag, created, err := s.getOrCreateTag(r.Context(), r.Header.Get(ClusterTagHeader))
 	if err != nil {
 		logger.Debug("bytes upload: get or create tag failed", "error", err)
 		logger.Error(nil, "bytes upload: get or create tag failed")
@@ -105,7 +106,7 @@ func (s *Service) bytesUploadHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if strings.ToLower(r.Header.Get(ClusterPinHeader)) == "true" {
-		if err := s.pinning.CreatePin(ctx, address, false); err != nil {
+		if err := s.createPin(ctx, address, false); err != nil {
 			logger.Debug("bytes upload: pins creation failed", "address", address, "error", err)
 			logger.Error(nil, "bytes upload: pins creation failed")
 			jsonhttp.InternalServerError(w, "bytes upload: create ping failed")
@@ -115,6 +116,7 @@ func (s *Service) bytesUploadHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set(ClusterTagHeader, fmt.Sprint(tag.Uid))
 	w.Header().Set("Access-Control-Expose-Headers", ClusterTagHeader)
+	usage.SetReference(r.Context(), address.String())
 	jsonhttp.Created(w, bytesPostResponse{
 		Reference: address,
 	})
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/redesblock/mop/core/api/jsonhttp"
+	"github.com/redesblock/mop/core/availability"
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/resolver"
+)
+
+type chunkStatusResponse struct {
+	Address       cluster.Address `json:"address"`
+	Retrievable   bool            `json:"retrievable"`
+	Latency       string          `json:"latency"`
+	Neighbourhood uint8           `json:"neighbourhood"`
+	Responded     int             `json:"responded"`
+	Replicas      int             `json:"replicas"`
+	Error         string          `json:"error,omitempty"`
+}
+
+type checkResponse struct {
+	Reference   cluster.Address       `json:"reference"`
+	Chunks      []chunkStatusResponse `json:"chunks"`
+	Total       int                   `json:"total"`
+	Retrievable int                   `json:"retrievable"`
+	Complete    bool                  `json:"complete"`
+	Health      float64               `json:"health"`
+}
+
+// checkHandler reports the availability of every chunk of the content on
+// the given address in the network.
+func (s *Service) checkHandler(w http.ResponseWriter, r *http.Request) {
+	nameOrHex := mux.Vars(r)["address"]
+	address, err := s.resolveNameOrAddress(nameOrHex)
+	switch {
+	case errors.Is(err, resolver.ErrParse), errors.Is(err, resolver.ErrInvalidContentHash):
+		s.logger.Debug("check: parse address string failed", "string", nameOrHex, "error", err)
+		s.logger.Error(nil, "check: invalid address")
+		jsonhttp.BadRequest(w, "invalid address")
+		return
+	case err != nil:
+		s.logger.Debug("check: resolve address or name string failed", "string", nameOrHex, "error", err)
+		s.logger.Error(nil, "check: resolve address or name string failed")
+		jsonhttp.NotFound(w, nil)
+		return
+	}
+
+	report, err := s.availability.Probe(r.Context(), address)
+	if errors.Is(err, availability.ErrTooManyChunks) {
+		s.logger.Debug("check: content too large", "chunk_address", address, "error", err)
+		s.logger.Error(nil, "check: content too large")
+		jsonhttp.BadRequest(w, "content has too many chunks to check")
+		return
+	}
+	if err != nil {
+		s.logger.Debug("check: availability probe failed", "chunk_address", address, "error", err)
+		s.logger.Error(nil, "check: availability probe failed")
+		jsonhttp.InternalServerError(w, "check: availability probe failed")
+		return
+	}
+
+	chunks := make([]chunkStatusResponse, 0, len(report.Chunks))
+	for _, st := range report.Chunks {
+		chunks = append(chunks, chunkStatusResponse{
+			Address:       st.Address,
+			Retrievable:   st.Retrievable,
+			Latency:       st.Latency.String(),
+			Neighbourhood: st.Neighbourhood,
+			Responded:     st.Responded,
+			Replicas:      st.Replicas,
+			Error:         st.Error,
+		})
+	}
+	jsonhttp.OK(w, checkResponse{
+		Reference:   report.Root,
+		Chunks:      chunks,
+		Total:       len(chunks),
+		Retrievable: report.Retrievable,
+		Complete:    report.Complete,
+		Health:      report.Health,
+	})
+}
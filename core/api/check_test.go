@@ -0,0 +1,100 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/redesblock/mop/core/api"
+	"github.com/redesblock/mop/core/api/jsonhttp"
+	"github.com/redesblock/mop/core/api/jsonhttp/jsonhttptest"
+	"github.com/redesblock/mop/core/availability"
+	"github.com/redesblock/mop/core/availability/mock"
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/resolver"
+	resolverMock "github.com/redesblock/mop/core/resolver/mock"
+)
+
+func TestCheck(t *testing.T) {
+	var (
+		addr    = cluster.NewAddress([]byte{31: 128})
+		missing = cluster.NewAddress([]byte{31: 129})
+		report  = &availability.Report{
+			Root: addr,
+			Chunks: []availability.ChunkStatus{
+				{Address: addr, Retrievable: true, Latency: time.Second, Neighbourhood: 8, Responded: 4, Replicas: 3},
+				{Address: missing, Error: "not found", Neighbourhood: 7, Responded: 2},
+			},
+			Retrievable: 1,
+			Complete:    true,
+			Health:      0.5,
+		}
+		large = cluster.NewAddress([]byte{31: 130})
+		probe = mock.New(func(_ context.Context, root cluster.Address) (*availability.Report, error) {
+			if root.Equal(large) {
+				return nil, availability.ErrTooManyChunks
+			}
+			if !root.Equal(addr) {
+				return nil, errors.New("probe failed")
+			}
+			return report, nil
+		})
+	)
+	client, _, _, _ := newTestServer(t, testServerOptions{
+		Logger:       log.Noop,
+		Availability: probe,
+		Resolver: resolverMock.NewResolver(
+			resolverMock.WithResolveFunc(
+				func(string) (cluster.Address, error) {
+					return cluster.Address{}, resolver.ErrParse
+				},
+			),
+		),
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodGet, "/v1/check/"+addr.String(), http.StatusOK,
+			jsonhttptest.WithExpectedJSONResponse(api.CheckResponse{
+				Reference: addr,
+				Chunks: []api.ChunkStatusResponse{
+					{Address: addr, Retrievable: true, Latency: "1s", Neighbourhood: 8, Responded: 4, Replicas: 3},
+					{Address: missing, Latency: "0s", Error: "not found", Neighbourhood: 7, Responded: 2},
+				},
+				Total:       2,
+				Retrievable: 1,
+				Complete:    true,
+				Health:      0.5,
+			}),
+		)
+	})
+
+	t.Run("invalid address", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodGet, "/v1/check/invalid", http.StatusBadRequest,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusBadRequest,
+				Message: "invalid address",
+			}),
+		)
+	})
+
+	t.Run("probe error", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodGet, "/v1/check/"+missing.String(), http.StatusInternalServerError,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "check: availability probe failed",
+			}),
+		)
+	})
+
+	t.Run("too many chunks", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodGet, "/v1/check/"+large.String(), http.StatusBadRequest,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusBadRequest,
+				Message: "content has too many chunks to check",
+			}),
+		)
+	})
+}
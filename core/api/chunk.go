@@ -18,6 +18,7 @@ import (
 	"github.com/redesblock/mop/core/mctx"
 	"github.com/redesblock/mop/core/storer/storage"
 	"github.com/redesblock/mop/core/tags"
+	"github.com/redesblock/mop/core/usage"
 )
 
 type chunkAddressResponse struct {
@@ -29,7 +30,7 @@ func (s *Service) processUploadRequest(
 ) (ctx context.Context, tag *tags.Tag, putter storage.Putter, waitFn func() error, err error) {
 
 	if h := r.Header.Get(ClusterTagHeader); h != "" {
-		tag, err = s.getTag(h)
+		tag, err = s.getTag(r.Context(), h)
 		if err != nil {
 			s.logger.Debug("chunk upload: get tag failed", "error", err)
 			s.logger.Error(nil, "chunk upload: get tag failed")
@@ -135,7 +136,7 @@ func (s *Service) chunkUploadHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if strings.ToLower(r.Header.Get(ClusterPinHeader)) == "true" {
-		if err := s.pinning.CreatePin(ctx, chunk.Address(), false); err != nil {
+		if err := s.createPin(ctx, chunk.Address(), false); err != nil {
 			s.logger.Debug("chunk upload: pins creation failed", "chunk_address", chunk.Address(), "error", err)
 			s.logger.Error(nil, "chunk upload: pins creation failed")
 			err = s.storer.Set(ctx, storage.ModeSetUnpin, chunk.Address())
@@ -156,6 +157,7 @@ func (s *Service) chunkUploadHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Access-Control-Expose-Headers", ClusterTagHeader)
+	usage.SetReference(r.Context(), chunk.Address().String())
 	jsonhttp.Created(w, chunkAddressResponse{Reference: chunk.Address()})
 }
 
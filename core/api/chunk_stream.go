@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/redesblock/mop/core/mctx"
 	"github.com/redesblock/mop/core/storer/storage"
 	"github.com/redesblock/mop/core/tags"
+	"github.com/redesblock/mop/core/tenant"
 )
 
 const streamReadTimeout = 15 * time.Minute
@@ -22,6 +24,39 @@ const streamReadTimeout = 15 * time.Minute
 var successWsMsg = []byte{}
 
 func (s *Service) chunkUploadStreamHandler(w http.ResponseWriter, r *http.Request) {
+	// the admission slot is held by the stream until it ends
+	release, ok := s.admission.Admit(w, r, RouteGroupUpload)
+	if !ok {
+		return
+	}
+	defer func() {
+		if release != nil {
+			release()
+		}
+	}()
+
+	version := r.Header.Get(ClusterStreamVersionHeader)
+	if version != "" && version != "1" && version != "2" {
+		jsonhttp.BadRequest(w, "unsupported stream version")
+		return
+	}
+
+	var sync bool
+	if h := r.Header.Get(ClusterStreamSyncHeader); h != "" {
+		var err error
+		if sync, err = strconv.ParseBool(h); err != nil {
+			jsonhttp.BadRequest(w, "invalid stream sync header")
+			return
+		}
+	}
+	if sync {
+		// chunks of deferred uploads are pushed later by the pusher
+		deferred, err := requestDeferred(r)
+		if err != nil || deferred || version != "2" {
+			jsonhttp.BadRequest(w, "sync confirmations require a direct upload with stream version 2")
+			return
+		}
+	}
 
 	_, tag, putter, wait, err := s.processUploadRequest(r)
 	if err != nil {
@@ -35,7 +70,12 @@ func (s *Service) chunkUploadStreamHandler(w http.ResponseWriter, r *http.Reques
 		CheckOrigin:     s.checkOrigin,
 	}
 
-	c, err := upgrader.Upgrade(w, r, nil)
+	var header http.Header
+	if version == "2" {
+		header = http.Header{ClusterStreamVersionHeader: []string{version}}
+	}
+
+	c, err := upgrader.Upgrade(w, r, header)
 	if err != nil {
 		s.logger.Debug("chunk upload: upgrade failed", "error", err)
 		s.logger.Error(nil, "chunk upload: upgrade failed")
@@ -47,17 +87,26 @@ func (s *Service) chunkUploadStreamHandler(w http.ResponseWriter, r *http.Reques
 	if tag != nil {
 		cctx = mctx.SetTag(cctx, tag)
 	}
+	if t, ok := tenant.FromContext(r.Context()); ok {
+		cctx = tenant.WithContext(cctx, t)
+	}
 
-	s.wsWg.Add(1)
-	go s.handleUploadStream(
-		cctx,
-		c,
-		tag,
-		putter,
-		requestModePut(r),
-		strings.ToLower(r.Header.Get(ClusterPinHeader)) == "true",
-		wait,
+	var (
+		mode = requestModePut(r)
+		pin  = strings.ToLower(r.Header.Get(ClusterPinHeader)) == "true"
+		done = release
 	)
+	release = nil
+	s.wsWg.Add(1)
+	go func() {
+		defer done()
+
+		if version == "2" {
+			s.handleUploadStreamV2(cctx, c, tag, putter, mode, pin, wait, sync)
+			return
+		}
+		s.handleUploadStream(cctx, c, tag, putter, mode, pin, wait)
+	}()
 }
 
 func (s *Service) handleUploadStream(
@@ -203,7 +252,7 @@ func (s *Service) handleUploadStream(
 		}
 
 		if pin {
-			if err := s.pinning.CreatePin(ctx, chunk.Address(), false); err != nil {
+			if err := s.createPin(ctx, chunk.Address(), false); err != nil {
 				s.logger.Debug("chunk upload stream: pins creation failed", "chunk_address", chunk.Address(), "error", err)
 				s.logger.Error(nil, "chunk upload stream: pins creation failed")
 				// since we already increment the pins counter because of the ModePut, we need
@@ -218,6 +267,8 @@ func (s *Service) handleUploadStream(
 			}
 		}
 
+		s.addStreamUsage(ctx, chunk)
+
 		err = sendMsg(websocket.BinaryMessage, successWsMsg)
 		if err != nil {
 			s.logger.Debug("chunk upload stream: sending success message failed", "error", err)
@@ -226,3 +277,14 @@ func (s *Service) handleUploadStream(
 		}
 	}
 }
+
+// addStreamUsage records a chunk uploaded on a stream as stored by the
+// tenant of the stream. The chunks are recorded one by one, as the stream
+// outlives the request which opened it.
+func (s *Service) addStreamUsage(ctx context.Context, ch cluster.Chunk) {
+	if s.usage == nil {
+		return
+	}
+	t, _ := tenant.FromContext(ctx)
+	s.usage.Add(time.Now(), ch.Address().String(), t, 0, uint64(len(ch.Data())), 0)
+}
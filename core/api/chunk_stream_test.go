@@ -3,14 +3,23 @@ package api_test
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
+	"errors"
+	"math/big"
 	"net/http"
+	"net/url"
 	"testing"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/redesblock/mop/core/api"
+	"github.com/redesblock/mop/core/api/admission"
 	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/crypto"
+	"github.com/redesblock/mop/core/incentives/voucher"
+	mockbatchstore "github.com/redesblock/mop/core/incentives/voucher/batchstore/mock"
 	mockpost "github.com/redesblock/mop/core/incentives/voucher/mock"
+	postagetesting "github.com/redesblock/mop/core/incentives/voucher/testing"
 	"github.com/redesblock/mop/core/log"
 	pinning "github.com/redesblock/mop/core/pins/mock"
 	statestore "github.com/redesblock/mop/core/storer/statestore/mock"
@@ -111,4 +120,387 @@ func TestChunkUploadStream(t *testing.T) {
 			t.Fatalf("incorrect response on error, exp: (invalid message) got (%s)", cerr.Text)
 		}
 	})
+
+	t.Run("admission slot held by the stream", func(t *testing.T) {
+		_, wsConn, addr, _ := newTestServer(t, testServerOptions{
+			Storer:    mock.NewStorer(),
+			Tags:      tags.NewTags(statestore.NewStateStore(), log.Noop),
+			Post:      mockpost.New(mockpost.WithAcceptAll()),
+			WsPath:    "/chunks/stream",
+			WsHeaders: wsHeaders,
+			Admission: map[string]admission.Limits{
+				api.RouteGroupUpload: {Concurrency: 1, QueueTimeout: time.Second},
+			},
+		})
+		u := url.URL{Scheme: "ws", Host: addr, Path: "/chunks/stream"}
+
+		_, resp, err := websocket.DefaultDialer.Dial(u.String(), wsHeaders)
+		if !errors.Is(err, websocket.ErrBadHandshake) {
+			t.Fatalf("got error %v, want %v", err, websocket.ErrBadHandshake)
+		}
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("got status %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+		}
+
+		// the slot is released when the stream ends
+		if err := wsConn.Close(); err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			conn, _, err := websocket.DefaultDialer.Dial(u.String(), wsHeaders)
+			if err == nil {
+				_ = conn.Close()
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("stream not admitted after the previous one ended: %v", err)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	})
+}
+
+func TestChunkUploadStreamV2(t *testing.T) {
+	wsHeaders := func(deferred, sync bool) http.Header {
+		h := http.Header{}
+		h.Set(api.ClusterStreamVersionHeader, "2")
+		h.Set(api.ClusterVoucherBatchIdHeader, batchOkStr)
+		if !deferred {
+			h.Set(api.ClusterDeferredUploadHeader, "false")
+		}
+		if sync {
+			h.Set(api.ClusterStreamSyncHeader, "true")
+		}
+		return h
+	}
+
+	t.Run("upload and verify", func(t *testing.T) {
+		key, err := crypto.GenerateSecp256k1Key()
+		if err != nil {
+			t.Fatal(err)
+		}
+		signer := crypto.NewDefaultSigner(key)
+		owner, err := signer.BSCAddress()
+		if err != nil {
+			t.Fatal(err)
+		}
+		batch := postagetesting.MustNewBatch(postagetesting.WithOwner(owner.Bytes()))
+		issuer := voucher.NewStampIssuer("", "", batch.ID, batch.Value, batch.Depth, batch.BucketDepth, 1000, true)
+
+		storerMock := mock.NewStorer()
+		_, wsConn, _, _ := newTestServer(t, testServerOptions{
+			Storer:     storerMock,
+			Tags:       tags.NewTags(statestore.NewStateStore(), log.Noop),
+			Post:       mockpost.New(mockpost.WithAcceptAll()),
+			BatchStore: mockbatchstore.New(mockbatchstore.WithBatch(batch), mockbatchstore.WithAcceptAllExistsFunc()),
+			WsPath:     "/chunks/stream",
+			WsHeaders:  wsHeaders(true, false),
+		})
+		expectStreamWindow(t, wsConn)
+
+		chs := []cluster.Chunk{
+			testingc.GenerateTestRandomChunk(),
+			testingc.GenerateTestRandomChunk(),
+			testingc.GenerateTestRandomChunk(),
+		}
+		stamp, err := voucher.NewStamper(issuer, signer).Stamp(chs[2].Address())
+		if err != nil {
+			t.Fatal(err)
+		}
+		clientStamp, err := stamp.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		invalidStamp, err := postagetesting.MustNewStamp().MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sendStreamChunks(t, wsConn, []streamTestChunk{
+			{id: 1, data: chs[0].Data()},
+			{id: 2, data: chs[1].Data()},
+			{id: 3, data: chs[2].Data(), stamp: clientStamp},
+			{id: 4, data: []byte{1, 2, 3}},
+			{id: 5, data: testingc.GenerateTestRandomChunk().Data(), stamp: invalidStamp},
+			{id: 6, data: chs[0].Data()},
+		})
+		expectStreamAck(t, wsConn, map[uint64]byte{
+			1: api.StreamChunkStored,
+			2: api.StreamChunkStored,
+			3: api.StreamChunkStored,
+			4: api.StreamChunkInvalid,
+			5: api.StreamChunkInvalidStamp,
+			6: api.StreamChunkExists,
+		})
+
+		for _, c := range chs {
+			ch, err := storerMock.Get(context.Background(), storage.ModeGetRequest, c.Address())
+			if err != nil {
+				t.Fatal("failed to get chunk after upload", err)
+			}
+			if !ch.Equal(c) {
+				t.Fatal("invalid chunk read")
+			}
+		}
+		ch, err := storerMock.Get(context.Background(), storage.ModeGetRequest, chs[2].Address())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ch.Stamp().MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, clientStamp) {
+			t.Fatal("chunk not stored with the client stamp")
+		}
+	})
+
+	t.Run("bucket full", func(t *testing.T) {
+		// a single stamp per collision bucket and two buckets
+		issuer := voucher.NewStampIssuer("", "", batchOk, big.NewInt(3), 1, 1, 1000, true)
+		_, wsConn, _, _ := newTestServer(t, testServerOptions{
+			Storer:    mock.NewStorer(),
+			Post:      mockpost.New(mockpost.WithIssuer(issuer)),
+			WsPath:    "/chunks/stream",
+			WsHeaders: wsHeaders(true, false),
+		})
+		expectStreamWindow(t, wsConn)
+
+		sendStreamChunks(t, wsConn, []streamTestChunk{
+			{id: 1, data: testingc.GenerateTestRandomChunk().Data()},
+			{id: 2, data: testingc.GenerateTestRandomChunk().Data()},
+			{id: 3, data: testingc.GenerateTestRandomChunk().Data()},
+		})
+		statuses := readStreamAck(t, wsConn)
+		var full int
+		for _, status := range statuses {
+			if status == api.StreamChunkBucketFull {
+				full++
+			}
+		}
+		if full == 0 {
+			t.Fatalf("got statuses %v, want a full bucket", statuses)
+		}
+	})
+
+	t.Run("window exceeded", func(t *testing.T) {
+		_, wsConn, _, _ := newTestServer(t, testServerOptions{
+			Storer:    mock.NewStorer(),
+			Post:      mockpost.New(mockpost.WithAcceptAll()),
+			WsPath:    "/chunks/stream",
+			WsHeaders: wsHeaders(true, false),
+		})
+		expectStreamWindow(t, wsConn)
+
+		chs := make([]streamTestChunk, api.StreamWindow+1)
+		for i := range chs {
+			chs[i] = streamTestChunk{id: uint64(i), data: []byte{1}}
+		}
+		sendStreamChunks(t, wsConn, chs)
+
+		err := wsConn.SetReadDeadline(time.Now().Add(time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = wsConn.ReadMessage()
+		var cerr *websocket.CloseError
+		if !errors.As(err, &cerr) || cerr.Code != websocket.ClosePolicyViolation {
+			t.Fatalf("got error %v, want close with policy violation", err)
+		}
+	})
+
+	t.Run("sync confirmations", func(t *testing.T) {
+		_, wsConn, _, _ := newTestServer(t, testServerOptions{
+			Storer:       mock.NewStorer(),
+			Post:         mockpost.New(mockpost.WithAcceptAll()),
+			DirectUpload: true,
+			WsPath:       "/chunks/stream",
+			WsHeaders:    wsHeaders(false, true),
+		})
+		expectStreamWindow(t, wsConn)
+
+		sendStreamChunks(t, wsConn, []streamTestChunk{
+			{id: 1, data: testingc.GenerateTestRandomChunk().Data()},
+			{id: 2, data: testingc.GenerateTestRandomChunk().Data()},
+			{id: 3, data: testingc.GenerateTestRandomChunk().Data()},
+		})
+
+		var (
+			acked  bool
+			synced = make(map[uint64]bool)
+		)
+		for !acked || len(synced) < 3 {
+			msg := readStreamFrame(t, wsConn)
+			switch msg[0] {
+			case api.StreamFrameAck:
+				acked = true
+			case api.StreamFrameSynced:
+				count := int(binary.BigEndian.Uint16(msg[1:]))
+				for i := 0; i < count; i++ {
+					synced[binary.BigEndian.Uint64(msg[3+8*i:])] = true
+				}
+			default:
+				t.Fatalf("unexpected frame type %d", msg[0])
+			}
+		}
+		for id := uint64(1); id <= 3; id++ {
+			if !synced[id] {
+				t.Fatalf("chunk %d not confirmed synced", id)
+			}
+		}
+	})
+
+	t.Run("sync confirmations of duplicate chunks", func(t *testing.T) {
+		gate := make(chan struct{})
+		_, wsConn, _, _ := newTestServer(t, testServerOptions{
+			Storer:       mock.NewStorer(),
+			Post:         mockpost.New(mockpost.WithAcceptAll()),
+			DirectUpload: true,
+			PushGate:     gate,
+			WsPath:       "/chunks/stream",
+			WsHeaders:    wsHeaders(false, true),
+		})
+		expectStreamWindow(t, wsConn)
+
+		// the same chunk under two ids is pushed twice, each push confirms
+		// a single id
+		data := testingc.GenerateTestRandomChunk().Data()
+		sendStreamChunks(t, wsConn, []streamTestChunk{
+			{id: 1, data: data},
+			{id: 2, data: data},
+		})
+		expectStreamAck(t, wsConn, map[uint64]byte{
+			1: api.StreamChunkStored,
+			2: api.StreamChunkStored,
+		})
+
+		synced := make(map[uint64]bool)
+		for i := 0; i < 2; i++ {
+			gate <- struct{}{}
+			msg := readStreamFrame(t, wsConn)
+			if msg[0] != api.StreamFrameSynced {
+				t.Fatalf("unexpected frame type %d", msg[0])
+			}
+			if count := int(binary.BigEndian.Uint16(msg[1:])); count != 1 {
+				t.Fatalf("push confirmed %d chunks, want %d", count, 1)
+			}
+			synced[binary.BigEndian.Uint64(msg[3:])] = true
+		}
+		if !synced[1] || !synced[2] {
+			t.Fatalf("got confirmations %v, want chunks 1 and 2", synced)
+		}
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		_, _, addr, _ := newTestServer(t, testServerOptions{
+			Storer: mock.NewStorer(),
+			Post:   mockpost.New(mockpost.WithAcceptAll()),
+		})
+
+		unsupported := wsHeaders(true, false)
+		unsupported.Set(api.ClusterStreamVersionHeader, "3")
+		for _, h := range []http.Header{unsupported, wsHeaders(true, true)} {
+			u := url.URL{Scheme: "ws", Host: addr, Path: "/chunks/stream"}
+			conn, resp, err := websocket.DefaultDialer.Dial(u.String(), h)
+			if err == nil {
+				_ = conn.Close()
+				t.Fatal("expected bad handshake")
+			}
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Fatalf("got status %d, want %d", resp.StatusCode, http.StatusBadRequest)
+			}
+		}
+	})
+}
+
+type streamTestChunk struct {
+	id    uint64
+	stamp []byte
+	data  []byte
+}
+
+func sendStreamChunks(t *testing.T, conn *websocket.Conn, chs []streamTestChunk) {
+	t.Helper()
+
+	msg := []byte{api.StreamFrameChunks, 0, 0}
+	binary.BigEndian.PutUint16(msg[1:], uint16(len(chs)))
+	for _, ch := range chs {
+		hdr := make([]byte, 13)
+		binary.BigEndian.PutUint64(hdr, ch.id)
+		if ch.stamp != nil {
+			hdr[8] = api.StreamChunkStamped
+		}
+		binary.BigEndian.PutUint32(hdr[9:], uint32(len(ch.data)))
+		msg = append(msg, hdr...)
+		msg = append(msg, ch.stamp...)
+		msg = append(msg, ch.data...)
+	}
+
+	if err := conn.SetWriteDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteMessage(websocket.BinaryMessage, msg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readStreamFrame(t *testing.T, conn *websocket.Conn) []byte {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	mt, msg, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mt != websocket.BinaryMessage || len(msg) == 0 {
+		t.Fatal("invalid response", mt, msg)
+	}
+	return msg
+}
+
+func expectStreamWindow(t *testing.T, conn *websocket.Conn) {
+	t.Helper()
+
+	msg := readStreamFrame(t, conn)
+	if msg[0] != api.StreamFrameWindow || len(msg) != 5 {
+		t.Fatalf("got frame %v, want window", msg)
+	}
+	if got := binary.BigEndian.Uint32(msg[1:]); got != api.StreamWindow {
+		t.Fatalf("got window %d, want %d", got, api.StreamWindow)
+	}
+}
+
+func readStreamAck(t *testing.T, conn *websocket.Conn) map[uint64]byte {
+	t.Helper()
+
+	msg := readStreamFrame(t, conn)
+	if msg[0] != api.StreamFrameAck || len(msg) < 3 {
+		t.Fatalf("got frame %v, want ack", msg)
+	}
+	count := int(binary.BigEndian.Uint16(msg[1:]))
+	if len(msg) != 3+9*count {
+		t.Fatalf("got ack of %d bytes for %d chunks", len(msg), count)
+	}
+	statuses := make(map[uint64]byte, count)
+	for i := 0; i < count; i++ {
+		statuses[binary.BigEndian.Uint64(msg[3+9*i:])] = msg[3+9*i+8]
+	}
+	return statuses
+}
+
+func expectStreamAck(t *testing.T, conn *websocket.Conn, want map[uint64]byte) {
+	t.Helper()
+
+	got := readStreamAck(t, conn)
+	if len(got) != len(want) {
+		t.Fatalf("got %d acknowledged chunks, want %d", len(got), len(want))
+	}
+	for id, status := range want {
+		if got[id] != status {
+			t.Fatalf("got status %d of chunk %d, want %d", got[id], id, status)
+		}
+	}
 }
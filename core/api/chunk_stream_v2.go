@@ -0,0 +1,470 @@
+package api
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/redesblock/mop/core/chunk/cac"
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/incentives/voucher"
+	"github.com/redesblock/mop/core/storer/storage"
+	"github.com/redesblock/mop/core/tags"
+)
+
+// Version 2 of the chunk upload stream exchanges binary frames, all integers
+// being big-endian. The first byte of a frame is its type:
+//
+//	chunks (client): type, count uint16, count times:
+//	                 id uint64, flags uint8, size uint32, [stamp], chunk data
+//	window (server): type, credits uint32
+//	ack    (server): type, count uint16, count times: id uint64, status uint8
+//	synced (server): type, count uint16, count times: id uint64
+//
+// The ids are chosen by the client to match the acknowledgements with the
+// chunks. The server grants a window of credits when the stream is opened,
+// each chunk sent takes one credit and each acknowledged chunk gives it back,
+// so that the client never has more than the window of chunks unacknowledged.
+// Chunks with the streamChunkStamped flag carry a voucher stamp issued by the
+// client, the others are stamped with the batch of the upload. When push sync
+// confirmations are requested, a synced frame is sent once a stored chunk is
+// pushed to the network.
+const (
+	streamFrameChunks byte = iota + 1
+	streamFrameWindow
+	streamFrameAck
+	streamFrameSynced
+)
+
+// streamChunkStamped flags the chunks of a chunks frame carrying a stamp.
+const streamChunkStamped byte = 1
+
+// Statuses of the chunks acknowledged in an ack frame.
+const (
+	streamChunkStored byte = iota
+	// streamChunkExists is the status of chunks already known by the node,
+	// no sync confirmation is sent for them.
+	streamChunkExists
+	streamChunkInvalid
+	streamChunkInvalidStamp
+	streamChunkBucketFull
+	streamChunkFailed
+)
+
+// streamWindow is the number of unacknowledged chunks a client may send.
+const streamWindow = 128
+
+// streamChunkIDKey is the context key of the id of the chunk being put, so
+// that its sync confirmation is matched with the id even if the stream sends
+// the same chunk under several ids.
+type streamChunkIDKey struct{}
+
+// syncKey identifies a chunk waiting for a sync confirmation.
+type syncKey struct {
+	addr string
+	id   uint64
+}
+
+var errInvalidStreamFrame = errors.New("invalid stream frame")
+
+type streamChunk struct {
+	id    uint64
+	stamp []byte
+	data  []byte
+}
+
+// decodeStreamChunks decodes the chunks of a chunks frame.
+func decodeStreamChunks(msg []byte) ([]streamChunk, error) {
+	if len(msg) < 3 || msg[0] != streamFrameChunks {
+		return nil, errInvalidStreamFrame
+	}
+	count := int(binary.BigEndian.Uint16(msg[1:]))
+	if count == 0 {
+		return nil, errInvalidStreamFrame
+	}
+	chs := make([]streamChunk, count)
+	msg = msg[3:]
+	for i := range chs {
+		if len(msg) < 13 {
+			return nil, errInvalidStreamFrame
+		}
+		chs[i].id = binary.BigEndian.Uint64(msg)
+		flags := msg[8]
+		size := int(binary.BigEndian.Uint32(msg[9:]))
+		msg = msg[13:]
+		if flags&streamChunkStamped != 0 {
+			if len(msg) < voucher.StampSize {
+				return nil, errInvalidStreamFrame
+			}
+			chs[i].stamp, msg = msg[:voucher.StampSize], msg[voucher.StampSize:]
+		}
+		if len(msg) < size {
+			return nil, errInvalidStreamFrame
+		}
+		chs[i].data, msg = msg[:size], msg[size:]
+	}
+	if len(msg) != 0 {
+		return nil, errInvalidStreamFrame
+	}
+	return chs, nil
+}
+
+// chunkStream is a version 2 chunk upload stream.
+type chunkStream struct {
+	s          *Service
+	conn       *websocket.Conn
+	tag        *tags.Tag
+	putter     storage.Putter
+	mode       storage.ModePut
+	pin        bool
+	validStamp voucher.ValidStampFn
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	credits int
+	pending map[syncKey]struct{} // chunks waiting for a sync confirmation
+}
+
+func (s *Service) handleUploadStreamV2(
+	ctx context.Context,
+	conn *websocket.Conn,
+	tag *tags.Tag,
+	putter storage.Putter,
+	mode storage.ModePut,
+	pin bool,
+	wait func() error,
+	confirmSync bool,
+) {
+	defer s.wsWg.Done()
+
+	cs := &chunkStream{
+		s:          s,
+		conn:       conn,
+		tag:        tag,
+		putter:     putter,
+		mode:       mode,
+		pin:        pin,
+		validStamp: voucher.ValidStamp(s.batchStore),
+		credits:    streamWindow,
+	}
+
+	var (
+		synced     chan syncKey
+		syncedDone = make(chan struct{})
+	)
+	if p, ok := putter.(*pushStamperPutter); ok && confirmSync {
+		synced = make(chan syncKey, streamWindow)
+		cs.pending = make(map[syncKey]struct{})
+		p.synced = func(ctx context.Context, addr cluster.Address) {
+			if id, ok := ctx.Value(streamChunkIDKey{}).(uint64); ok {
+				synced <- syncKey{addr: addr.ByteString(), id: id}
+			}
+		}
+		go func() {
+			defer close(syncedDone)
+			cs.confirmSynced(synced)
+		}()
+	} else {
+		close(syncedDone)
+	}
+
+	batches := make(chan []streamChunk, streamWindow)
+	processed := make(chan struct{})
+	go func() {
+		defer close(processed)
+		cs.process(ctx, batches)
+	}()
+
+	conn.SetReadLimit(3 + streamWindow*(13+voucher.StampSize+cluster.ChunkWithSpanSize))
+
+	window := make([]byte, 5)
+	window[0] = streamFrameWindow
+	binary.BigEndian.PutUint32(window[1:], streamWindow)
+	if err := cs.send(window); err != nil {
+		s.logger.Debug("chunk upload stream: sending window failed", "error", err)
+		s.logger.Error(nil, "chunk upload stream: sending window failed")
+	} else {
+		// the connection is closed on shutdown, so that idle streams do not
+		// hold it until their read times out
+		readDone := make(chan struct{})
+		go func() {
+			select {
+			case <-s.quit:
+				cs.sendErrorClose(websocket.CloseGoingAway, "node shutting down")
+				_ = conn.Close()
+			case <-readDone:
+			}
+		}()
+		cs.read(batches)
+		close(readDone)
+	}
+	close(batches)
+	<-processed
+
+	if err := wait(); err != nil {
+		s.logger.Error(err, "chunk upload stream: syncing chunks failed")
+	}
+	if synced != nil {
+		close(synced)
+	}
+	<-syncedDone
+	_ = conn.Close()
+}
+
+// read reads the chunks frames and passes their chunks to the processing
+// until the stream is closed.
+func (cs *chunkStream) read(batches chan<- []streamChunk) {
+	for {
+		err := cs.conn.SetReadDeadline(time.Now().Add(streamReadTimeout))
+		if err != nil {
+			cs.s.logger.Debug("chunk upload stream: set read deadline failed", "error", err)
+			cs.s.logger.Error(nil, "chunk upload stream: set read deadline failed")
+			return
+		}
+
+		mt, msg, err := cs.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				cs.s.logger.Debug("chunk upload stream: read message failed", "error", err)
+				cs.s.logger.Error(nil, "chunk upload stream: read message failed")
+			}
+			return
+		}
+
+		if mt != websocket.BinaryMessage {
+			cs.s.logger.Debug("chunk upload stream: unexpected message received from client", "message_type", mt)
+			cs.s.logger.Error(nil, "chunk upload stream: unexpected message received from client")
+			cs.sendErrorClose(websocket.CloseUnsupportedData, "invalid message")
+			return
+		}
+
+		chs, err := decodeStreamChunks(msg)
+		if err != nil {
+			cs.s.logger.Debug("chunk upload stream: decode chunks failed", "error", err)
+			cs.s.logger.Error(nil, "chunk upload stream: decode chunks failed")
+			cs.sendErrorClose(websocket.CloseUnsupportedData, "invalid message")
+			return
+		}
+
+		cs.mu.Lock()
+		exceeded := len(chs) > cs.credits
+		if !exceeded {
+			cs.credits -= len(chs)
+		}
+		cs.mu.Unlock()
+		if exceeded {
+			cs.s.logger.Debug("chunk upload stream: window exceeded", "chunks", len(chs))
+			cs.s.logger.Error(nil, "chunk upload stream: window exceeded")
+			cs.sendErrorClose(websocket.ClosePolicyViolation, "window exceeded")
+			return
+		}
+
+		batches <- chs
+	}
+}
+
+// process stores the chunks and acknowledges them with their statuses.
+func (cs *chunkStream) process(ctx context.Context, batches <-chan []streamChunk) {
+	var failed bool
+	for chs := range batches {
+		if failed {
+			// drain the batches read before the stream is closed
+			continue
+		}
+
+		msg := make([]byte, 3+9*len(chs))
+		msg[0] = streamFrameAck
+		binary.BigEndian.PutUint16(msg[1:], uint16(len(chs)))
+		for i, ch := range chs {
+			binary.BigEndian.PutUint64(msg[3+9*i:], ch.id)
+			msg[3+9*i+8] = cs.put(ctx, ch)
+		}
+
+		cs.mu.Lock()
+		cs.credits += len(chs)
+		cs.mu.Unlock()
+
+		if err := cs.send(msg); err != nil {
+			cs.s.logger.Debug("chunk upload stream: sending ack failed", "error", err)
+			cs.s.logger.Error(nil, "chunk upload stream: sending ack failed")
+			failed = true
+			_ = cs.conn.Close()
+		}
+	}
+}
+
+// put stores the chunk and returns its status.
+func (cs *chunkStream) put(ctx context.Context, sc streamChunk) byte {
+	if cs.tag != nil {
+		if err := cs.tag.Inc(tags.StateSplit); err != nil {
+			cs.s.logger.Debug("chunk upload stream: incrementing tag failed", "error", err)
+			cs.s.logger.Error(nil, "chunk upload stream: incrementing tag failed")
+			return streamChunkFailed
+		}
+	}
+
+	if len(sc.data) < cluster.SpanSize {
+		return streamChunkInvalid
+	}
+	chunk, err := cac.NewWithDataSpan(sc.data)
+	if err != nil {
+		cs.s.logger.Debug("chunk upload stream: create chunk failed", "error", err)
+		return streamChunkInvalid
+	}
+
+	if sc.stamp != nil {
+		stamped, err := cs.validStamp(chunk, sc.stamp)
+		if err != nil {
+			cs.s.logger.Debug("chunk upload stream: invalid stamp", "chunk_address", chunk.Address(), "error", err)
+			return streamChunkInvalidStamp
+		}
+		chunk = stamped
+	}
+
+	cs.awaitSync(chunk.Address(), sc.id)
+	seen, err := cs.putter.Put(context.WithValue(ctx, streamChunkIDKey{}, sc.id), cs.mode, chunk)
+	if err != nil || (len(seen) > 0 && seen[0]) {
+		cs.cancelSync(chunk.Address(), sc.id)
+	}
+	if err != nil {
+		cs.s.logger.Debug("chunk upload stream: write chunk failed", "chunk_address", chunk.Address(), "error", err)
+		cs.s.logger.Error(nil, "chunk upload stream: write chunk failed")
+		if errors.Is(err, voucher.ErrBucketFull) {
+			return streamChunkBucketFull
+		}
+		return streamChunkFailed
+	}
+
+	status := streamChunkStored
+	if len(seen) > 0 && seen[0] {
+		status = streamChunkExists
+		if cs.tag != nil {
+			if err := cs.tag.Inc(tags.StateSeen); err != nil {
+				cs.s.logger.Debug("chunk upload stream: increment tag failed", "error", err)
+				cs.s.logger.Error(nil, "chunk upload stream: increment tag failed")
+				return streamChunkFailed
+			}
+		}
+	}
+
+	if cs.tag != nil {
+		// indicate that the chunk is stored
+		if err := cs.tag.Inc(tags.StateStored); err != nil {
+			cs.s.logger.Debug("chunk upload stream: increment tag failed", "error", err)
+			cs.s.logger.Error(nil, "chunk upload stream: increment tag failed")
+			return streamChunkFailed
+		}
+	}
+
+	if cs.pin {
+		if err := cs.s.createPin(ctx, chunk.Address(), false); err != nil {
+			cs.s.logger.Debug("chunk upload stream: pins creation failed", "chunk_address", chunk.Address(), "error", err)
+			cs.s.logger.Error(nil, "chunk upload stream: pins creation failed")
+			// since we already increment the pins counter because of the ModePut, we need
+			// to delete the pins here to prevent the pins counter from never going to 0
+			if err := cs.s.storer.Set(ctx, storage.ModeSetUnpin, chunk.Address()); err != nil {
+				cs.s.logger.Debug("chunk upload stream: pins deletion failed", "chunk_address", chunk.Address(), "error", err)
+				cs.s.logger.Error(nil, "chunk upload stream: pins deletion failed")
+			}
+			return streamChunkFailed
+		}
+	}
+
+	cs.s.addStreamUsage(ctx, chunk)
+	return status
+}
+
+// awaitSync registers a chunk waiting for a sync confirmation. It is
+// registered before the chunk is put, as the chunk may be pushed before the
+// put returns.
+func (cs *chunkStream) awaitSync(addr cluster.Address, id uint64) {
+	if cs.pending == nil {
+		return
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.pending[syncKey{addr: addr.ByteString(), id: id}] = struct{}{}
+}
+
+// cancelSync removes a chunk that is not going to be pushed.
+func (cs *chunkStream) cancelSync(addr cluster.Address, id uint64) {
+	if cs.pending == nil {
+		return
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	delete(cs.pending, syncKey{addr: addr.ByteString(), id: id})
+}
+
+// confirmSynced sends the sync confirmations of the pushed chunks, batching
+// the ones pushed while the previous confirmation is sent.
+func (cs *chunkStream) confirmSynced(synced <-chan syncKey) {
+	var failed bool
+	for key := range synced {
+		ids := cs.syncedIDs(nil, key)
+	batch:
+		for len(ids) < streamWindow {
+			select {
+			case key, ok := <-synced:
+				if !ok {
+					break batch
+				}
+				ids = cs.syncedIDs(ids, key)
+			default:
+				break batch
+			}
+		}
+		if failed || len(ids) == 0 {
+			continue
+		}
+
+		msg := make([]byte, 3+8*len(ids))
+		msg[0] = streamFrameSynced
+		binary.BigEndian.PutUint16(msg[1:], uint16(len(ids)))
+		for i, id := range ids {
+			binary.BigEndian.PutUint64(msg[3+8*i:], id)
+		}
+		if err := cs.send(msg); err != nil {
+			cs.s.logger.Debug("chunk upload stream: sending sync confirmation failed", "error", err)
+			cs.s.logger.Error(nil, "chunk upload stream: sending sync confirmation failed")
+			failed = true
+		}
+	}
+}
+
+// syncedIDs appends the id of the pushed chunk to ids if it is waiting for
+// a sync confirmation.
+func (cs *chunkStream) syncedIDs(ids []uint64, key syncKey) []uint64 {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if _, ok := cs.pending[key]; !ok {
+		return ids
+	}
+	delete(cs.pending, key)
+	return append(ids, key.id)
+}
+
+func (cs *chunkStream) send(msg []byte) error {
+	cs.writeMu.Lock()
+	defer cs.writeMu.Unlock()
+
+	if err := cs.conn.SetWriteDeadline(time.Now().Add(writeDeadline)); err != nil {
+		return err
+	}
+	return cs.conn.WriteMessage(websocket.BinaryMessage, msg)
+}
+
+func (cs *chunkStream) sendErrorClose(code int, errmsg string) {
+	err := cs.conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, errmsg),
+		time.Now().Add(writeDeadline),
+	)
+	if err != nil {
+		cs.s.logger.Error(err, "chunk upload stream: failed sending close message")
+	}
+}
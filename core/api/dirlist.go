@@ -0,0 +1,189 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/redesblock/mop/core/api/jsonhttp"
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/file/joiner"
+	"github.com/redesblock/mop/core/manifest"
+	"github.com/redesblock/mop/core/tracer"
+)
+
+const (
+	dirListFormatJSON = "json"
+	dirListFormatHTML = "html"
+
+	dirListDefaultLimit = 100
+	dirListMaxLimit     = 1000
+
+	dirListTypeFile      = "file"
+	dirListTypeDirectory = "directory"
+)
+
+type dirListEntry struct {
+	Name        string           `json:"name"`
+	Path        string           `json:"path"`
+	Type        string           `json:"type"`
+	Reference   *cluster.Address `json:"reference,omitempty"`
+	Size        int64            `json:"size,omitempty"`
+	ContentType string           `json:"contentType,omitempty"`
+}
+
+type dirListResponse struct {
+	Reference cluster.Address `json:"reference"`
+	Path      string          `json:"path"`
+	After     string          `json:"after,omitempty"`
+	Limit     int             `json:"limit"`
+	Next      string          `json:"next,omitempty"`
+	Entries   []dirListEntry  `json:"entries"`
+}
+
+var dirListTemplate = template.Must(template.New("dirlist").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Index of /{{.Path}}</title></head>
+<body>
+<h1>Index of /{{.Path}}</h1>
+<table>
+<tr><th>Name</th><th>Size</th><th>Content type</th><th>Reference</th></tr>
+{{if .Path}}<tr><td><a href="../?list=html">../</a></td><td></td><td></td><td></td></tr>
+{{end}}{{range .Entries}}<tr><td><a href="{{.Name}}{{if eq .Type "directory"}}?list=html{{end}}">{{.Name}}</a></td><td>{{if .Size}}{{.Size}}{{end}}</td><td>{{.ContentType}}</td><td>{{if .Reference}}{{.Reference}}{{end}}</td></tr>
+{{end}}</table>
+{{if .Next}}<p><a href="?list=html&amp;after={{.Next}}&amp;limit={{.Limit}}">Next</a></p>
+{{end}}</body>
+</html>
+`))
+
+// serveManifestDirList lists the files and directories of the manifest
+// directory with the given path, either as JSON or as an HTML page.
+func (s *Service) serveManifestDirList(
+	w http.ResponseWriter,
+	r *http.Request,
+	address cluster.Address,
+	m manifest.Interface,
+	dir, format string,
+	etag bool,
+) {
+	logger := tracer.NewLoggerWithTraceID(r.Context(), s.logger)
+
+	after, limit := r.URL.Query().Get("after"), dirListDefaultLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 1 || l > dirListMaxLimit {
+			logger.Debug("mop dir list: parse limit string failed", "string", v, "error", err)
+			logger.Error(nil, "mop dir list: parse limit string failed")
+			jsonhttp.BadRequest(w, "bad limit")
+			return
+		}
+		limit = l
+	}
+
+	withSize := false
+	if v := r.URL.Query().Get("size"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			logger.Debug("mop dir list: parse size string failed", "string", v, "error", err)
+			logger.Error(nil, "mop dir list: parse size string failed")
+			jsonhttp.BadRequest(w, "bad size")
+			return
+		}
+		withSize = b
+	}
+
+	// one more entry is listed to know if there is a next page
+	entries, err := manifest.List(r.Context(), m, dir, after, limit+1)
+	if err != nil {
+		logger.Debug("mop dir list: list failed", "address", address, "path", dir, "error", err)
+		logger.Error(nil, "mop dir list: list failed")
+		if errors.Is(err, manifest.ErrNotFound) {
+			jsonhttp.NotFound(w, "path address not found")
+			return
+		}
+		jsonhttp.InternalServerError(w, "mop dir list: list failed")
+		return
+	}
+
+	resp := dirListResponse{
+		Reference: address,
+		Path:      dir,
+		After:     after,
+		Limit:     limit,
+		Entries:   []dirListEntry{},
+	}
+
+	if len(entries) > limit {
+		entries = entries[:limit]
+		resp.Next = entries[limit-1].Name
+	}
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, s.newDirListEntry(r, e, withSize))
+	}
+
+	if etag {
+		w.Header().Set("ETag", fmt.Sprintf("%q", address))
+	}
+
+	if format == dirListFormatHTML {
+		w.Header().Set(contentTypeHeader, "text/html; charset=utf-8")
+		if err := dirListTemplate.Execute(w, resp); err != nil {
+			logger.Debug("mop dir list: render html failed", "error", err)
+			logger.Error(nil, "mop dir list: render html failed")
+		}
+		return
+	}
+
+	jsonhttp.OK(w, resp)
+}
+
+// newDirListEntry creates the listing item for the given directory entry.
+// If requested, the file size is read from the root chunk of the file, it
+// is omitted if the root chunk can not be retrieved.
+func (s *Service) newDirListEntry(r *http.Request, e manifest.DirEntry, withSize bool) dirListEntry {
+	if e.IsDir() {
+		return dirListEntry{
+			Name: e.Name,
+			Path: e.Path,
+			Type: dirListTypeDirectory,
+		}
+	}
+
+	ref := e.Entry.Reference()
+	item := dirListEntry{
+		Name:        e.Name,
+		Path:        e.Path,
+		Type:        dirListTypeFile,
+		Reference:   &ref,
+		ContentType: e.Entry.Metadata()[manifest.EntryMetadataContentTypeKey],
+	}
+	if !withSize {
+		return item
+	}
+
+	_, size, err := joiner.New(r.Context(), s.storer, ref)
+	if err != nil {
+		s.logger.Debug("mop dir list: file size unavailable", "address", ref, "error", err)
+		return item
+	}
+	item.Size = size
+	return item
+}
+
+// requestDirListFormat returns the requested directory listing format, or
+// an empty string if the listing was not requested.
+func requestDirListFormat(r *http.Request) (string, bool) {
+	switch v := strings.ToLower(r.URL.Query().Get("list")); v {
+	case "":
+		return "", true
+	case "true", dirListFormatJSON:
+		return dirListFormatJSON, true
+	case dirListFormatHTML:
+		return dirListFormatHTML, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,136 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/redesblock/mop/core/api"
+	"github.com/redesblock/mop/core/api/jsonhttp/jsonhttptest"
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/file/loadsave"
+	"github.com/redesblock/mop/core/file/pipeline/builder"
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/manifest"
+	"github.com/redesblock/mop/core/storer/storage"
+	smock "github.com/redesblock/mop/core/storer/storage/mock"
+)
+
+func TestMopDirList(t *testing.T) {
+	var (
+		storerMock      = smock.NewStorer()
+		client, _, _, _ = newTestServer(t, testServerOptions{
+			Storer: storerMock,
+			Logger: log.Noop,
+		})
+		ctx     = context.Background()
+		factory = pipelineFactory(storerMock, storage.ModePutUpload, false)
+		files   = map[string]string{
+			"index.html":      "<h1>index</h1>",
+			"docs/a.txt":      "a",
+			"docs/b.txt":      "bb",
+			"docs/sub/c.json": "{}",
+		}
+	)
+
+	m, err := manifest.NewDefaultManifest(loadsave.New(storerMock, factory), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := make(map[string]cluster.Address)
+	for p, data := range files {
+		ref, err := builder.FeedPipeline(ctx, factory(), strings.NewReader(data), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		refs[p] = ref
+		if err := m.Add(ctx, p, manifest.NewEntry(ref, map[string]string{
+			manifest.EntryMetadataFilenameKey:    p,
+			manifest.EntryMetadataContentTypeKey: "text/plain",
+		})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root, err := m.Store(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resource := func(p string) string { return "/mop/" + root.String() + "/" + p }
+
+	t.Run("root", func(t *testing.T) {
+		var resp api.DirListResponse
+		jsonhttptest.Request(t, client, http.MethodGet, resource("?list=json&size=true"), http.StatusOK,
+			jsonhttptest.WithUnmarshalJSONResponse(&resp),
+		)
+		if len(resp.Entries) != 2 || resp.Next != "" {
+			t.Fatalf("got %d entries and next %q, want 2 and no next", len(resp.Entries), resp.Next)
+		}
+		if e := resp.Entries[0]; e.Name != "docs/" || e.Type != "directory" || e.Reference != nil {
+			t.Fatalf("unexpected directory entry %+v", e)
+		}
+		e := resp.Entries[1]
+		if e.Name != "index.html" || e.Type != "file" || !e.Reference.Equal(refs["index.html"]) {
+			t.Fatalf("unexpected file entry %+v", e)
+		}
+		if e.Size != int64(len(files["index.html"])) || e.ContentType != "text/plain" {
+			t.Fatalf("got size %d and content type %q", e.Size, e.ContentType)
+		}
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		var resp api.DirListResponse
+		jsonhttptest.Request(t, client, http.MethodGet, resource("docs/?list=true&after=a.txt&limit=1"), http.StatusOK,
+			jsonhttptest.WithUnmarshalJSONResponse(&resp),
+		)
+		if len(resp.Entries) != 1 || resp.Entries[0].Path != "docs/b.txt" || resp.Next != "b.txt" {
+			t.Fatalf("unexpected page %+v", resp)
+		}
+		if resp.Entries[0].Size != 0 {
+			t.Fatalf("got size %d, want it omitted", resp.Entries[0].Size)
+		}
+
+		var last api.DirListResponse
+		jsonhttptest.Request(t, client, http.MethodGet, resource("docs/?list=true&after="+resp.Next+"&limit=1"), http.StatusOK,
+			jsonhttptest.WithUnmarshalJSONResponse(&last),
+		)
+		if len(last.Entries) != 1 || last.Entries[0].Path != "docs/sub/" || last.Next != "" {
+			t.Fatalf("unexpected last page %+v", last)
+		}
+	})
+
+	t.Run("html", func(t *testing.T) {
+		resp := request(t, client, http.MethodGet, resource("docs/?list=html"), nil, http.StatusOK)
+		defer resp.Body.Close()
+		if ct := resp.Header.Get(api.ContentTypeHeader); !strings.HasPrefix(ct, "text/html") {
+			t.Fatalf("got content type %q", ct)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{`href="a.txt"`, `href="sub/?list=html"`, refs["docs/b.txt"].String()} {
+			if !bytes.Contains(body, []byte(want)) {
+				t.Fatalf("html listing does not contain %q", want)
+			}
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodGet, resource("missing/?list=json"), http.StatusNotFound)
+	})
+
+	t.Run("bad params", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodGet, resource("?list=xml"), http.StatusBadRequest)
+		jsonhttptest.Request(t, client, http.MethodGet, resource("?list=json&limit=0"), http.StatusBadRequest)
+		jsonhttptest.Request(t, client, http.MethodGet, resource("?list=json&size=maybe"), http.StatusBadRequest)
+	})
+
+	t.Run("file download unaffected", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodGet, resource("docs/a.txt?list=json"), http.StatusOK,
+			jsonhttptest.WithExpectedResponse([]byte(files["docs/a.txt"])),
+		)
+	})
+}
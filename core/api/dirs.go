@@ -26,6 +26,7 @@ import (
 	"github.com/redesblock/mop/core/storer/storage"
 	"github.com/redesblock/mop/core/tags"
 	"github.com/redesblock/mop/core/tracer"
+	"github.com/redesblock/mop/core/usage"
 )
 
 var errEmptyDir = errors.New("no files in root directory")
@@ -60,7 +61,7 @@ func (s *Service) dirUploadHandler(w http.ResponseWriter, r *http.Request, store
 	}
 	defer r.Body.Close()
 
-	tag, created, err := s.getOrCreateTag(r.Header.Get(ClusterTagHeader))
+	tag, created, err := s.getOrCreateTag(r.Context(), r.Header.Get(ClusterTagHeader))
 	if err != nil {
 		logger.Debug("mop upload dir: get or create tag failed", "error", err)
 		logger.Error(nil, "mop upload dir: get or create tag failed")
@@ -112,7 +113,7 @@ func (s *Service) dirUploadHandler(w http.ResponseWriter, r *http.Request, store
 	}
 
 	if strings.ToLower(r.Header.Get(ClusterPinHeader)) == "true" {
-		if err := s.pinning.CreatePin(r.Context(), reference, false); err != nil {
+		if err := s.createPin(r.Context(), reference, false); err != nil {
 			logger.Debug("mop upload dir: pins creation failed", "address", reference, "error", err)
 			logger.Error(nil, "mop upload dir: pins creation failed")
 			jsonhttp.InternalServerError(w, "mop upload dir: create pins failed")
@@ -129,6 +130,7 @@ func (s *Service) dirUploadHandler(w http.ResponseWriter, r *http.Request, store
 
 	w.Header().Set("Access-Control-Expose-Headers", ClusterTagHeader)
 	w.Header().Set(ClusterTagHeader, fmt.Sprint(tag.Uid))
+	usage.SetReference(r.Context(), reference.String())
 	jsonhttp.Created(w, mopUploadResponse{
 		Reference: reference,
 	})
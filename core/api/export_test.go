@@ -1,22 +1,44 @@
 package api
 
 import (
+	"time"
+
 	"github.com/redesblock/mop/core/cluster"
 	"github.com/redesblock/mop/core/log"
 )
 
 type (
-	BytesPostResponse     = bytesPostResponse
-	ChunkAddressResponse  = chunkAddressResponse
-	SocPostResponse       = socPostResponse
-	FeedReferenceResponse = feedReferenceResponse
-	MopUploadResponse     = mopUploadResponse
-	DebugTagResponse      = debugTagResponse
-	TagRequest            = tagRequest
-	ListTagsResponse      = listTagsResponse
-	IsRetrievableResponse = isRetrievableResponse
-	SecurityTokenResponse = securityTokenRsp
-	SecurityTokenRequest  = securityTokenReq
+	BytesPostResponse       = bytesPostResponse
+	ChunkAddressResponse    = chunkAddressResponse
+	SocPostResponse         = socPostResponse
+	FeedReferenceResponse   = feedReferenceResponse
+	NamePublishRequest      = namePublishRequest
+	NamePublishResponse     = namePublishResponse
+	MopUploadResponse       = mopUploadResponse
+	DebugTagResponse        = debugTagResponse
+	TagRequest              = tagRequest
+	ListTagsResponse        = listTagsResponse
+	IsRetrievableResponse   = isRetrievableResponse
+	CheckResponse           = checkResponse
+	ChunkStatusResponse     = chunkStatusResponse
+	UsageResponse           = usageResponse
+	UsageRecordResponse     = usageRecordResponse
+	DirListResponse         = dirListResponse
+	DirListEntry            = dirListEntry
+	SecurityTokenResponse   = securityTokenRsp
+	SecurityTokenRequest    = securityTokenReq
+	TenantCredentialRequest = tenantCredentialRequest
+	ManifestPatchRequest    = manifestPatchRequest
+	ManifestPatchResponse   = manifestPatchResponse
+	ManifestOperation       = manifestOperation
+	ManifestDiffResponse    = manifestDiffResponse
+	ManifestChange          = manifestChange
+	PinCollectionRequest    = pinCollectionRequest
+	PinCollections          = pinCollectionsResponse
+	PinCollection           = pinCollectionResponse
+	PinReferencesRequest    = pinReferencesRequest
+	PinReferencesResponse   = pinReferencesResponse
+	PinReferenceResult      = pinReferenceResult
 )
 
 var (
@@ -44,6 +66,22 @@ var (
 	SuccessWsMsg = successWsMsg
 )
 
+const (
+	StreamFrameChunks       = streamFrameChunks
+	StreamFrameWindow       = streamFrameWindow
+	StreamFrameAck          = streamFrameAck
+	StreamFrameSynced       = streamFrameSynced
+	StreamChunkStamped      = streamChunkStamped
+	StreamChunkStored       = streamChunkStored
+	StreamChunkExists       = streamChunkExists
+	StreamChunkInvalid      = streamChunkInvalid
+	StreamChunkInvalidStamp = streamChunkInvalidStamp
+	StreamChunkBucketFull   = streamChunkBucketFull
+	StreamWindow            = streamWindow
+
+	MaxPinCollectionReferences = maxPinCollectionReferences
+)
+
 var (
 	FileSizeBucketsKBytes = fileSizeBucketsKBytes
 	ToFileSizeBucket      = toFileSizeBucket
@@ -64,6 +102,7 @@ type (
 	PeerConnectResponse               = peerConnectResponse
 	PeersResponse                     = peersResponse
 	AddressesResponse                 = addressesResponse
+	ReachabilityResponse              = reachabilityResponse
 	WelcomeMessageRequest             = welcomeMessageRequest
 	WelcomeMessageResponse            = welcomeMessageResponse
 	BalancesResponse                  = balancesResponse
@@ -123,3 +162,8 @@ var (
 
 func ReplaceLogRegistryIterateFn(fn LogRegistryIterateFn)   { logRegistryIterate = fn }
 func ReplaceLogSetVerbosityByExp(fn LogSetVerbosityByExpFn) { logSetVerbosityByExp = fn }
+
+// AddTraffic records the traffic of a request as the access log does.
+func (s *Service) AddTraffic(t time.Time, key string, upload bool, size int64) {
+	s.trafficHandler(t, key, upload, size)
+}
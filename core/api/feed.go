@@ -19,6 +19,7 @@ import (
 	"github.com/redesblock/mop/core/file/loadsave"
 	"github.com/redesblock/mop/core/incentives/voucher"
 	"github.com/redesblock/mop/core/manifest"
+	"github.com/redesblock/mop/core/usage"
 )
 
 const (
@@ -197,7 +198,7 @@ func (s *Service) feedPostHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if strings.ToLower(r.Header.Get(ClusterPinHeader)) == "true" {
-		if err := s.pinning.CreatePin(r.Context(), ref, false); err != nil {
+		if err := s.createPin(r.Context(), ref, false); err != nil {
 			s.logger.Debug("feed post: pins creation failed: %v", "address", ref, "error", err)
 			s.logger.Error(nil, "feed post: pins creation failed")
 			jsonhttp.InternalServerError(w, "feed post: creation of pins failed")
@@ -212,6 +213,7 @@ func (s *Service) feedPostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	usage.SetReference(r.Context(), ref.String())
 	jsonhttp.Created(w, feedReferenceResponse{Reference: ref})
 }
 
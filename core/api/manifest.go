@@ -0,0 +1,344 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/redesblock/mop/core/api/jsonhttp"
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/file"
+	"github.com/redesblock/mop/core/file/loadsave"
+	"github.com/redesblock/mop/core/incentives/voucher"
+	"github.com/redesblock/mop/core/manifest"
+	"github.com/redesblock/mop/core/storer/storage"
+	"github.com/redesblock/mop/core/tracer"
+	"github.com/redesblock/mop/core/usage"
+)
+
+// manifestPatchMaxRequestSize limits the size of the list of
+// operations sent in a single manifest patch request.
+const manifestPatchMaxRequestSize = 1024 * 1024
+
+const (
+	manifestOpAdd     = "add"
+	manifestOpReplace = "replace"
+	manifestOpMove    = "move"
+	manifestOpRemove  = "remove"
+	manifestOpMerge   = "merge"
+)
+
+var (
+	errManifestPathExists  = errors.New("path already exists")
+	errManifestInvalidOp   = errors.New("invalid operation")
+	errManifestInvalidPath = errors.New("invalid path")
+)
+
+type manifestOperation struct {
+	Op        string            `json:"op"`
+	Path      string            `json:"path"`
+	From      string            `json:"from,omitempty"`
+	Reference cluster.Address   `json:"reference,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+type manifestPatchRequest struct {
+	Operations []manifestOperation `json:"operations"`
+}
+
+type manifestPatchResponse struct {
+	Reference cluster.Address `json:"reference"`
+}
+
+type manifestChange struct {
+	Path         string              `json:"path"`
+	Type         manifest.ChangeType `json:"type"`
+	OldReference *cluster.Address    `json:"oldReference,omitempty"`
+	NewReference *cluster.Address    `json:"newReference,omitempty"`
+	OldMetadata  map[string]string   `json:"oldMetadata,omitempty"`
+	NewMetadata  map[string]string   `json:"newMetadata,omitempty"`
+}
+
+type manifestDiffResponse struct {
+	Changes []manifestChange `json:"changes"`
+}
+
+// manifestPatchHandler applies a list of operations on an existing
+// manifest and stores the result as a new manifest.
+func (s *Service) manifestPatchHandler(w http.ResponseWriter, r *http.Request) {
+	logger := tracer.NewLoggerWithTraceID(r.Context(), s.logger)
+
+	nameOrHex := mux.Vars(r)["address"]
+	address, err := s.resolveNameOrAddress(nameOrHex)
+	if err != nil {
+		logger.Debug("manifest patch: parse address string failed", "string", nameOrHex, "error", err)
+		logger.Error(nil, "manifest patch: parse address string failed")
+		jsonhttp.NotFound(w, nil)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		if jsonhttp.HandleBodyReadError(err, w) {
+			return
+		}
+		logger.Debug("manifest patch: read request body failed", "error", err)
+		logger.Error(nil, "manifest patch: read request body failed")
+		jsonhttp.InternalServerError(w, "cannot read request")
+		return
+	}
+
+	var req manifestPatchRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		logger.Debug("manifest patch: unmarshal request body failed", "error", err)
+		logger.Error(nil, "manifest patch: unmarshal request body failed")
+		jsonhttp.BadRequest(w, "invalid request body")
+		return
+	}
+	if len(req.Operations) == 0 {
+		jsonhttp.BadRequest(w, "no operations")
+		return
+	}
+
+	putter, wait, err := s.newStamperPutter(r)
+	if err != nil {
+		logger.Debug("manifest patch: putter failed", "error", err)
+		logger.Error(nil, "manifest patch: putter failed")
+		switch {
+		case errors.Is(err, voucher.ErrNotFound):
+			jsonhttp.BadRequest(w, "batch not found")
+		case errors.Is(err, voucher.ErrNotUsable):
+			jsonhttp.BadRequest(w, "batch not usable yet")
+		case errors.Is(err, errInvalidVoucherBatch):
+			jsonhttp.BadRequest(w, "invalid voucher batch id")
+		default:
+			jsonhttp.BadRequest(w, nil)
+		}
+		return
+	}
+
+	ctx := r.Context()
+	ls := loadsave.New(putter, requestPipelineFactory(ctx, putter, r))
+
+	m, err := manifest.NewDefaultManifestReference(address, ls)
+	if err != nil {
+		logger.Debug("manifest patch: not manifest", "address", address, "error", err)
+		logger.Error(nil, "manifest patch: not manifest")
+		jsonhttp.NotFound(w, nil)
+		return
+	}
+
+	for i, op := range req.Operations {
+		if err := applyManifestOperation(ctx, m, ls, op); err != nil {
+			logger.Debug("manifest patch: operation failed", "address", address, "index", i, "op", op.Op, "path", op.Path, "error", err)
+			logger.Error(nil, "manifest patch: operation failed")
+			msg := fmt.Sprintf("operation %d: %v", i, err)
+			switch {
+			case errors.Is(err, manifest.ErrNotFound), errors.Is(err, storage.ErrNotFound):
+				jsonhttp.NotFound(w, msg)
+			case errors.Is(err, errManifestPathExists):
+				jsonhttp.Conflict(w, msg)
+			case errors.Is(err, errManifestInvalidOp), errors.Is(err, errManifestInvalidPath):
+				jsonhttp.BadRequest(w, msg)
+			default:
+				jsonhttp.InternalServerError(w, "manifest patch: operation failed")
+			}
+			return
+		}
+	}
+
+	reference, err := m.Store(ctx)
+	if err != nil {
+		logger.Debug("manifest patch: manifest store failed", "address", address, "error", err)
+		logger.Error(nil, "manifest patch: manifest store failed")
+		switch {
+		case errors.Is(err, voucher.ErrBucketFull):
+			jsonhttp.PaymentRequired(w, "batch is overissued")
+		default:
+			jsonhttp.InternalServerError(w, "manifest patch: manifest store failed")
+		}
+		return
+	}
+	logger.Info("manifest patch: store", "address", address, "manifest_reference", reference)
+
+	if strings.ToLower(r.Header.Get(ClusterPinHeader)) == "true" {
+		if err := s.createPin(ctx, reference, false); err != nil {
+			logger.Debug("manifest patch: pins creation failed", "manifest_reference", reference, "error", err)
+			logger.Error(nil, "manifest patch: pins creation failed")
+			jsonhttp.InternalServerError(w, "manifest patch: create pins failed")
+			return
+		}
+	}
+
+	if err = wait(); err != nil {
+		logger.Debug("manifest patch: chainsync chunks failed", "error", err)
+		logger.Error(nil, "manifest patch: chainsync chunks failed")
+		jsonhttp.InternalServerError(w, "manifest patch: chainsync chunks failed")
+		return
+	}
+
+	w.Header().Set("ETag", fmt.Sprintf("%q", reference.String()))
+	usage.SetReference(r.Context(), reference.String())
+	jsonhttp.Created(w, manifestPatchResponse{
+		Reference: reference,
+	})
+}
+
+// applyManifestOperation applies a single patch operation on the manifest.
+// Paths ending with a slash denote all the entries under that prefix for
+// the move and remove operations and the destination prefix for merge.
+func applyManifestOperation(ctx context.Context, m manifest.Interface, ls file.LoadSaver, op manifestOperation) error {
+	if strings.HasPrefix(op.Path, "/") || strings.HasPrefix(op.From, "/") {
+		return fmt.Errorf("%w: / in prefix not allowed", errManifestInvalidPath)
+	}
+
+	switch op.Op {
+	case manifestOpAdd:
+		if op.Path == "" || strings.HasSuffix(op.Path, "/") || op.Reference.IsZero() {
+			return fmt.Errorf("%w: add requires a file path and a reference", errManifestInvalidOp)
+		}
+		if _, err := m.Lookup(ctx, op.Path); err == nil {
+			return fmt.Errorf("%w: %s", errManifestPathExists, op.Path)
+		} else if !errors.Is(err, manifest.ErrNotFound) {
+			return err
+		}
+		metadata := map[string]string{
+			manifest.EntryMetadataFilenameKey: path.Base(op.Path),
+		}
+		if contentType := mime.TypeByExtension(path.Ext(op.Path)); contentType != "" {
+			metadata[manifest.EntryMetadataContentTypeKey] = contentType
+		}
+		for k, v := range op.Metadata {
+			metadata[k] = v
+		}
+		return m.Add(ctx, op.Path, manifest.NewEntry(op.Reference, metadata))
+
+	case manifestOpReplace:
+		if op.Path == "" || strings.HasSuffix(op.Path, "/") {
+			return fmt.Errorf("%w: replace requires a file path", errManifestInvalidOp)
+		}
+		entry, err := m.Lookup(ctx, op.Path)
+		if err != nil {
+			return err
+		}
+		reference := entry.Reference()
+		if !op.Reference.IsZero() {
+			reference = op.Reference
+		}
+		metadata := make(map[string]string, len(entry.Metadata())+len(op.Metadata))
+		for k, v := range entry.Metadata() {
+			metadata[k] = v
+		}
+		for k, v := range op.Metadata {
+			metadata[k] = v
+		}
+		return m.Add(ctx, op.Path, manifest.NewEntry(reference, metadata))
+
+	case manifestOpMove:
+		if op.From == "" || op.Path == "" || strings.HasSuffix(op.From, "/") != strings.HasSuffix(op.Path, "/") {
+			return fmt.Errorf("%w: move requires from and path of the same kind", errManifestInvalidOp)
+		}
+		if err := manifest.Move(ctx, m, op.From, op.Path); err != nil {
+			if errors.Is(err, manifest.ErrPathExists) {
+				return fmt.Errorf("%w: %s", errManifestPathExists, op.Path)
+			}
+			return err
+		}
+		return nil
+
+	case manifestOpRemove:
+		if op.Path == "" {
+			return fmt.Errorf("%w: remove requires a path", errManifestInvalidOp)
+		}
+		if !strings.HasSuffix(op.Path, "/") {
+			return m.Remove(ctx, op.Path)
+		}
+		n, err := manifest.RemovePrefix(ctx, m, op.Path)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return manifest.ErrNotFound
+		}
+		return nil
+
+	case manifestOpMerge:
+		if op.Reference.IsZero() || (op.Path != "" && !strings.HasSuffix(op.Path, "/")) {
+			return fmt.Errorf("%w: merge requires a reference and a directory path", errManifestInvalidOp)
+		}
+		src, err := manifest.NewDefaultManifestReference(op.Reference, ls)
+		if err != nil {
+			return err
+		}
+		return manifest.Merge(ctx, m, src, op.Path)
+	}
+
+	return fmt.Errorf("%w: %q", errManifestInvalidOp, op.Op)
+}
+
+// manifestDiffHandler lists the path-level changes between two manifests.
+func (s *Service) manifestDiffHandler(w http.ResponseWriter, r *http.Request) {
+	logger := tracer.NewLoggerWithTraceID(r.Context(), s.logger)
+	ls := loadsave.NewReadonly(s.storer)
+
+	var manifests [2]manifest.Interface
+	for i, name := range []string{"address", "other"} {
+		nameOrHex := mux.Vars(r)[name]
+		address, err := s.resolveNameOrAddress(nameOrHex)
+		if err != nil {
+			logger.Debug("manifest diff: parse address string failed", "string", nameOrHex, "error", err)
+			logger.Error(nil, "manifest diff: parse address string failed")
+			jsonhttp.NotFound(w, nil)
+			return
+		}
+		manifests[i], err = manifest.NewDefaultManifestReference(address, ls)
+		if err != nil {
+			logger.Debug("manifest diff: not manifest", "address", address, "error", err)
+			logger.Error(nil, "manifest diff: not manifest")
+			jsonhttp.NotFound(w, nil)
+			return
+		}
+	}
+
+	changes, err := manifest.Diff(r.Context(), manifests[0], manifests[1])
+	if err != nil {
+		logger.Debug("manifest diff: diff failed", "error", err)
+		logger.Error(nil, "manifest diff: diff failed")
+		if errors.Is(err, storage.ErrNotFound) {
+			jsonhttp.NotFound(w, nil)
+			return
+		}
+		jsonhttp.InternalServerError(w, "manifest diff: diff failed")
+		return
+	}
+
+	resp := manifestDiffResponse{
+		Changes: make([]manifestChange, 0, len(changes)),
+	}
+	for _, c := range changes {
+		mc := manifestChange{
+			Path: c.Path,
+			Type: c.Type,
+		}
+		if c.Old != nil {
+			ref := c.Old.Reference()
+			mc.OldReference = &ref
+			mc.OldMetadata = c.Old.Metadata()
+		}
+		if c.New != nil {
+			ref := c.New.Reference()
+			mc.NewReference = &ref
+			mc.NewMetadata = c.New.Metadata()
+		}
+		resp.Changes = append(resp.Changes, mc)
+	}
+
+	jsonhttp.OK(w, resp)
+}
@@ -0,0 +1,207 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/redesblock/mop/core/api"
+	"github.com/redesblock/mop/core/api/jsonhttp"
+	"github.com/redesblock/mop/core/api/jsonhttp/jsonhttptest"
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/file/loadsave"
+	mockpost "github.com/redesblock/mop/core/incentives/voucher/mock"
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/manifest"
+	statestore "github.com/redesblock/mop/core/storer/statestore/mock"
+	"github.com/redesblock/mop/core/storer/storage"
+	smock "github.com/redesblock/mop/core/storer/storage/mock"
+	testingc "github.com/redesblock/mop/core/storer/storage/testing"
+	"github.com/redesblock/mop/core/tags"
+)
+
+func TestManifestPatchAndDiff(t *testing.T) {
+	var (
+		storerMock      = smock.NewStorer()
+		logger          = log.Noop
+		client, _, _, _ = newTestServer(t, testServerOptions{
+			Storer: storerMock,
+			Tags:   tags.NewTags(statestore.NewStateStore(), logger),
+			Logger: logger,
+			Post:   mockpost.New(mockpost.WithAcceptAll()),
+		})
+		patchResource = func(addr cluster.Address) string { return "/manifests/" + addr.String() }
+		diffResource  = func(a, b cluster.Address) string { return "/manifests/" + a.String() + "/diff/" + b.String() }
+
+		ref1 = testingc.GenerateTestRandomChunk().Address()
+		ref2 = testingc.GenerateTestRandomChunk().Address()
+		ref3 = testingc.GenerateTestRandomChunk().Address()
+	)
+
+	ctx := context.Background()
+	ls := loadsave.New(storerMock, pipelineFactory(storerMock, storage.ModePutUpload, false))
+	m, err := manifest.NewDefaultManifest(ls, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p, ref := range map[string]cluster.Address{
+		"index.html":     ref1,
+		"docs/a.html":    ref2,
+		"docs/b.html":    ref2,
+		"img/logo.png":   ref3,
+		"img/banner.png": ref3,
+	} {
+		if err := m.Add(ctx, p, manifest.NewEntry(ref, map[string]string{
+			manifest.EntryMetadataFilenameKey: p,
+		})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root, err := m.Store(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var patched api.ManifestPatchResponse
+	jsonhttptest.Request(t, client, http.MethodPatch, patchResource(root), http.StatusCreated,
+		jsonhttptest.WithRequestHeader(api.ClusterVoucherBatchIdHeader, batchOkStr),
+		jsonhttptest.WithJSONRequestBody(api.ManifestPatchRequest{
+			Operations: []api.ManifestOperation{
+				{Op: "add", Path: "new.txt", Reference: ref1},
+				{Op: "replace", Path: "index.html", Reference: ref2},
+				{Op: "move", From: "docs/", Path: "guide/"},
+				{Op: "remove", Path: "img/banner.png"},
+			},
+		}),
+		jsonhttptest.WithUnmarshalJSONResponse(&patched),
+	)
+
+	var diff api.ManifestDiffResponse
+	jsonhttptest.Request(t, client, http.MethodGet, diffResource(root, patched.Reference), http.StatusOK,
+		jsonhttptest.WithUnmarshalJSONResponse(&diff),
+	)
+
+	want := []struct {
+		path string
+		typ  manifest.ChangeType
+	}{
+		{path: "docs/a.html", typ: manifest.ChangeRemoved},
+		{path: "docs/b.html", typ: manifest.ChangeRemoved},
+		{path: "guide/a.html", typ: manifest.ChangeAdded},
+		{path: "guide/b.html", typ: manifest.ChangeAdded},
+		{path: "img/banner.png", typ: manifest.ChangeRemoved},
+		{path: "index.html", typ: manifest.ChangeModified},
+		{path: "new.txt", typ: manifest.ChangeAdded},
+	}
+	if len(diff.Changes) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(diff.Changes), len(want), diff.Changes)
+	}
+	for i, c := range diff.Changes {
+		if c.Path != want[i].path || c.Type != want[i].typ {
+			t.Fatalf("change %d: got %s %s, want %s %s", i, c.Type, c.Path, want[i].typ, want[i].path)
+		}
+	}
+	if c := diff.Changes[5]; !c.OldReference.Equal(ref1) || !c.NewReference.Equal(ref2) {
+		t.Fatalf("modified references: got %s -> %s, want %s -> %s", c.OldReference, c.NewReference, ref1, ref2)
+	}
+	if got := diff.Changes[6].NewMetadata[manifest.EntryMetadataContentTypeKey]; got != "text/plain; charset=utf-8" {
+		t.Fatalf("added content type: got %q", got)
+	}
+
+	t.Run("add existing path", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodPatch, patchResource(root), http.StatusConflict,
+			jsonhttptest.WithRequestHeader(api.ClusterVoucherBatchIdHeader, batchOkStr),
+			jsonhttptest.WithJSONRequestBody(api.ManifestPatchRequest{
+				Operations: []api.ManifestOperation{
+					{Op: "add", Path: "index.html", Reference: ref1},
+				},
+			}),
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "operation 0: path already exists: index.html",
+				Code:    http.StatusConflict,
+			}),
+		)
+	})
+
+	t.Run("move to existing path", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodPatch, patchResource(root), http.StatusConflict,
+			jsonhttptest.WithRequestHeader(api.ClusterVoucherBatchIdHeader, batchOkStr),
+			jsonhttptest.WithJSONRequestBody(api.ManifestPatchRequest{
+				Operations: []api.ManifestOperation{
+					{Op: "move", From: "docs/a.html", Path: "index.html"},
+				},
+			}),
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "operation 0: path already exists: index.html",
+				Code:    http.StatusConflict,
+			}),
+		)
+	})
+
+	t.Run("remove missing path", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodPatch, patchResource(root), http.StatusNotFound,
+			jsonhttptest.WithRequestHeader(api.ClusterVoucherBatchIdHeader, batchOkStr),
+			jsonhttptest.WithJSONRequestBody(api.ManifestPatchRequest{
+				Operations: []api.ManifestOperation{
+					{Op: "remove", Path: "missing/"},
+				},
+			}),
+		)
+	})
+
+	t.Run("invalid operation", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodPatch, patchResource(root), http.StatusBadRequest,
+			jsonhttptest.WithRequestHeader(api.ClusterVoucherBatchIdHeader, batchOkStr),
+			jsonhttptest.WithJSONRequestBody(api.ManifestPatchRequest{
+				Operations: []api.ManifestOperation{
+					{Op: "copy", Path: "index.html"},
+				},
+			}),
+		)
+	})
+
+	t.Run("same manifest", func(t *testing.T) {
+		var diff api.ManifestDiffResponse
+		jsonhttptest.Request(t, client, http.MethodGet, diffResource(root, root), http.StatusOK,
+			jsonhttptest.WithUnmarshalJSONResponse(&diff),
+		)
+		if len(diff.Changes) != 0 {
+			t.Fatalf("got %d changes, want none", len(diff.Changes))
+		}
+	})
+
+	t.Run("remove path with descendants", func(t *testing.T) {
+		m, err := manifest.NewDefaultManifest(ls, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range []string{"a", "a.txt", "a/b.txt"} {
+			if err := m.Add(ctx, p, manifest.NewEntry(ref1, nil)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		root, err := m.Store(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var patched api.ManifestPatchResponse
+		jsonhttptest.Request(t, client, http.MethodPatch, patchResource(root), http.StatusCreated,
+			jsonhttptest.WithRequestHeader(api.ClusterVoucherBatchIdHeader, batchOkStr),
+			jsonhttptest.WithJSONRequestBody(api.ManifestPatchRequest{
+				Operations: []api.ManifestOperation{
+					{Op: "remove", Path: "a"},
+				},
+			}),
+			jsonhttptest.WithUnmarshalJSONResponse(&patched),
+		)
+
+		var diff api.ManifestDiffResponse
+		jsonhttptest.Request(t, client, http.MethodGet, diffResource(root, patched.Reference), http.StatusOK,
+			jsonhttptest.WithUnmarshalJSONResponse(&diff),
+		)
+		if len(diff.Changes) != 1 || diff.Changes[0].Path != "a" || diff.Changes[0].Type != manifest.ChangeRemoved {
+			t.Fatalf("got changes %+v, want only a removed", diff.Changes)
+		}
+	})
+}
@@ -75,7 +75,11 @@ func toFileSizeBucket(bytes int64) int64 {
 }
 
 func (s *Service) Metrics() []prometheus.Collector {
-	return m.PrometheusCollectorsFromFields(s.metrics)
+	collectors := m.PrometheusCollectorsFromFields(s.metrics)
+	if s.admission != nil {
+		collectors = append(collectors, s.admission.Metrics()...)
+	}
+	return collectors
 }
 
 func (s *Service) pageviewMetricsHandler(h http.Handler) http.Handler {
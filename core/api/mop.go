@@ -29,6 +29,7 @@ import (
 	"github.com/redesblock/mop/core/storer/storage"
 	"github.com/redesblock/mop/core/tags"
 	"github.com/redesblock/mop/core/tracer"
+	"github.com/redesblock/mop/core/usage"
 )
 
 func (s *Service) mopUploadHandler(w http.ResponseWriter, r *http.Request) {
@@ -83,7 +84,7 @@ func (s *Service) fileUploadHandler(w http.ResponseWriter, r *http.Request, stor
 	// Content-Type has already been validated by this time
 	contentType := r.Header.Get(contentTypeHeader)
 
-	tag, created, err := s.getOrCreateTag(r.Header.Get(ClusterTagHeader))
+	tag, created, err := s.getOrCreateTag(r.Context(), r.Header.Get(ClusterTagHeader))
 	if err != nil {
 		logger.Debug("mop upload file: get or create tag failed", "error", err)
 		logger.Error(nil, "mop upload file: get or create tag failed")
@@ -218,7 +219,7 @@ func (s *Service) fileUploadHandler(w http.ResponseWriter, r *http.Request, stor
 	}
 
 	if strings.ToLower(r.Header.Get(ClusterPinHeader)) == "true" {
-		if err := s.pinning.CreatePin(ctx, manifestReference, false); err != nil {
+		if err := s.createPin(ctx, manifestReference, false); err != nil {
 			logger.Debug("mop upload file: pins creation failed", "manifest_reference", manifestReference, "error", err)
 			logger.Error(nil, "mop upload file: pins creation failed")
 			jsonhttp.InternalServerError(w, "mop upload file: create pins failed")
@@ -236,6 +237,7 @@ func (s *Service) fileUploadHandler(w http.ResponseWriter, r *http.Request, stor
 	w.Header().Set("ETag", fmt.Sprintf("%q", manifestReference.String()))
 	w.Header().Set(ClusterTagHeader, fmt.Sprint(tag.Uid))
 	w.Header().Set("Access-Control-Expose-Headers", ClusterTagHeader)
+	usage.SetReference(r.Context(), manifestReference.String())
 	jsonhttp.Created(w, mopUploadResponse{
 		Reference: manifestReference,
 	})
@@ -338,6 +340,23 @@ FETCH:
 		return
 	}
 
+	if format := r.URL.Query().Get("format"); format != "" && pathVar == "" {
+		s.serveManifestArchive(w, r, address, m, strings.ToLower(format), !feedDereferenced)
+		return
+	}
+
+	if pathVar == "" || strings.HasSuffix(pathVar, "/") {
+		format, ok := requestDirListFormat(r)
+		if !ok {
+			jsonhttp.BadRequest(w, "bad list format")
+			return
+		}
+		if format != "" {
+			s.serveManifestDirList(w, r, address, m, pathVar, format, !feedDereferenced)
+			return
+		}
+	}
+
 	if pathVar == "" {
 		logger.Debug("mop download: handle empty path", "address", address)
 
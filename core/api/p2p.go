@@ -41,3 +41,29 @@ func (s *Service) addressesHandler(w http.ResponseWriter, r *http.Request) {
 		PSSPublicKey: hex.EncodeToString(crypto.EncodeSecp256k1PublicKey(&s.pssPublicKey)),
 	})
 }
+
+type reachabilityResponse struct {
+	Reachability       string                `json:"reachability"`
+	RelayService       bool                  `json:"relayService"`
+	HolePunching       bool                  `json:"holePunching"`
+	RelayAddresses     []multiaddr.Multiaddr `json:"relayAddresses"`
+	DirectConnections  int                   `json:"directConnections"`
+	RelayedConnections int                   `json:"relayedConnections"`
+}
+
+func (s *Service) reachabilityHandler(w http.ResponseWriter, r *http.Request) {
+	reachability := s.p2p.Reachability()
+
+	relayAddresses := reachability.RelayAddresses
+	if relayAddresses == nil {
+		relayAddresses = make([]multiaddr.Multiaddr, 0)
+	}
+	jsonhttp.OK(w, reachabilityResponse{
+		Reachability:       reachability.Status.String(),
+		RelayService:       reachability.RelayService,
+		HolePunching:       reachability.HolePunching,
+		RelayAddresses:     relayAddresses,
+		DirectConnections:  reachability.DirectConnections,
+		RelayedConnections: reachability.RelayedConnections,
+	})
+}
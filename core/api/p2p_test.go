@@ -13,6 +13,7 @@ import (
 	"github.com/redesblock/mop/core/api/jsonhttp/jsonhttptest"
 	"github.com/redesblock/mop/core/cluster"
 	"github.com/redesblock/mop/core/crypto"
+	"github.com/redesblock/mop/core/p2p"
 	"github.com/redesblock/mop/core/p2p/mock"
 )
 
@@ -101,3 +102,30 @@ func mustMultiaddr(t *testing.T, s string) multiaddr.Multiaddr {
 	}
 	return a
 }
+
+func TestReachability(t *testing.T) {
+	relayAddress := mustMultiaddr(t, "/ip4/10.0.0.1/tcp/1634/p2p/16Uiu2HAmTBuJT9LvNmBiQiNoTsxE5mtNy6YG3paw79m94CRa9sRb/p2p-circuit/p2p/16Uiu2HAm5QVy3Uz8KjP1vgeUDVj6JTQhjRSt1LNqR2N7X6QVTZVG")
+
+	testServer, _, _, _ := newTestServer(t, testServerOptions{
+		DebugAPI: true,
+		P2P: mock.New(mock.WithReachabilityFunc(func() p2p.Reachability {
+			return p2p.Reachability{
+				Status:             p2p.ReachabilityStatusPrivate,
+				HolePunching:       true,
+				RelayAddresses:     []multiaddr.Multiaddr{relayAddress},
+				DirectConnections:  3,
+				RelayedConnections: 1,
+			}
+		})),
+	})
+
+	jsonhttptest.Request(t, testServer, http.MethodGet, "/reachability", http.StatusOK,
+		jsonhttptest.WithExpectedJSONResponse(api.ReachabilityResponse{
+			Reachability:       "Private",
+			HolePunching:       true,
+			RelayAddresses:     []multiaddr.Multiaddr{relayAddress},
+			DirectConnections:  3,
+			RelayedConnections: 1,
+		}),
+	)
+}
@@ -70,8 +70,9 @@ func (s *Service) peerDisconnectHandler(w http.ResponseWriter, r *http.Request)
 
 // Peer holds information about a Peer.
 type Peer struct {
-	Address  cluster.Address `json:"address"`
-	FullNode bool            `json:"fullNode"`
+	Address   cluster.Address   `json:"address"`
+	FullNode  bool              `json:"fullNode"`
+	Protocols map[string]string `json:"protocols,omitempty"`
 }
 
 type peersResponse struct {
@@ -79,8 +80,13 @@ type peersResponse struct {
 }
 
 func (s *Service) peersHandler(w http.ResponseWriter, r *http.Request) {
+	peers := mapPeers(s.p2p.Peers())
+	for i := range peers {
+		peers[i].Protocols = s.p2p.PeerProtocols(peers[i].Address)
+	}
+
 	jsonhttp.OK(w, peersResponse{
-		Peers: mapPeers(s.p2p.Peers()),
+		Peers: peers,
 	})
 }
 
@@ -168,13 +168,15 @@ func TestPeer(t *testing.T) {
 		DebugAPI: true,
 		P2P: mock.New(mock.WithPeersFunc(func() []p2p.Peer {
 			return []p2p.Peer{{Address: overlay}}
+		}), mock.WithPeerProtocolsFunc(func(cluster.Address) map[string]string {
+			return map[string]string{"pullsync": "2.0.0"}
 		})),
 	})
 
 	t.Run("ok", func(t *testing.T) {
 		jsonhttptest.Request(t, testServer, http.MethodGet, "/peers", http.StatusOK,
 			jsonhttptest.WithExpectedJSONResponse(api.PeersResponse{
-				Peers: []api.Peer{{Address: overlay}},
+				Peers: []api.Peer{{Address: overlay, Protocols: map[string]string{"pullsync": "2.0.0"}}},
 			}),
 		)
 	})
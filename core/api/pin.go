@@ -8,6 +8,7 @@ import (
 	"github.com/redesblock/mop/core/api/jsonhttp"
 	"github.com/redesblock/mop/core/cluster"
 	"github.com/redesblock/mop/core/storer/storage"
+	"github.com/redesblock/mop/core/tenant"
 )
 
 // pinRootHash pins root hash of given reference. This method is idempotent.
@@ -28,11 +29,23 @@ func (s *Service) pinRootHash(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if has {
+		if err := s.addPinOwner(r.Context(), ref); err != nil {
+			s.logger.Debug("pins root hash: add pins owner failed", "chunk_address", ref, "error", err)
+			s.logger.Error(nil, "pins root hash: add pins owner failed")
+			jsonhttp.InternalServerError(w, "pins root hash: creation of tracking pins failed")
+			return
+		}
+		if err := s.keepPin(ref); err != nil {
+			s.logger.Debug("pins root hash: keep pins failed", "chunk_address", ref, "error", err)
+			s.logger.Error(nil, "pins root hash: keep pins failed")
+			jsonhttp.InternalServerError(w, "pins root hash: creation of tracking pins failed")
+			return
+		}
 		jsonhttp.OK(w, nil)
 		return
 	}
 
-	switch err = s.pinning.CreatePin(r.Context(), ref, true); {
+	switch err = s.createPin(r.Context(), ref, true); {
 	case errors.Is(err, storage.ErrNotFound):
 		jsonhttp.NotFound(w, nil)
 		return
@@ -63,18 +76,34 @@ func (s *Service) unpinRootHash(w http.ResponseWriter, r *http.Request) {
 		jsonhttp.InternalServerError(w, "pins root hash: checking of tracking pins")
 		return
 	}
+	if has {
+		has, err = s.owns(r.Context(), tenant.KindPin, ref.String())
+		if err != nil {
+			s.logger.Debug("unpin root hash: pins owner check failed", "chunk_address", ref, "error", err)
+			s.logger.Error(nil, "unpin root hash: pins owner check failed")
+			jsonhttp.InternalServerError(w, "pins root hash: checking of tracking pins")
+			return
+		}
+	}
 	if !has {
 		jsonhttp.NotFound(w, nil)
 		return
 	}
 
-	if err := s.pinning.DeletePin(r.Context(), ref); err != nil {
+	if err := s.deletePin(r.Context(), ref); err != nil {
 		s.logger.Debug("unpin root hash: delete pins failed", "chunk_address", ref, "error", err)
 		s.logger.Error(nil, "unpin root hash: delete pins failed")
 		jsonhttp.InternalServerError(w, "unpin root hash: deletion of pins failed")
 		return
 	}
 
+	if err := s.uncollectUnpinned(ref); err != nil {
+		s.logger.Debug("unpin root hash: remove from collections failed", "chunk_address", ref, "error", err)
+		s.logger.Error(nil, "unpin root hash: remove from collections failed")
+		jsonhttp.InternalServerError(w, "unpin root hash: deletion of pins failed")
+		return
+	}
+
 	jsonhttp.OK(w, nil)
 }
 
@@ -95,6 +124,15 @@ func (s *Service) getPinnedRootHash(w http.ResponseWriter, r *http.Request) {
 		jsonhttp.InternalServerError(w, "pinned root hash: check reference failed")
 		return
 	}
+	if has {
+		has, err = s.owns(r.Context(), tenant.KindPin, ref.String())
+		if err != nil {
+			s.logger.Debug("pinned root hash: pins owner check failed", "chunk_address", ref, "error", err)
+			s.logger.Error(nil, "pinned root hash: pins owner check failed")
+			jsonhttp.InternalServerError(w, "pinned root hash: check reference failed")
+			return
+		}
+	}
 
 	if !has {
 		jsonhttp.NotFound(w, nil)
@@ -118,6 +156,39 @@ func (s *Service) listPinnedRootHashes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, ok := s.requestTenant(r.Context()); ok {
+		owned := make([]cluster.Address, 0, len(pinned))
+		for _, ref := range pinned {
+			owns, err := s.owns(r.Context(), tenant.KindPin, ref.String())
+			if err != nil {
+				s.logger.Debug("list pinned root references: pins owner check failed", "chunk_address", ref, "error", err)
+				s.logger.Error(nil, "list pinned root references: pins owner check failed")
+				jsonhttp.InternalServerError(w, "list pinned root references failed")
+				return
+			}
+			if owns {
+				owned = append(owned, ref)
+			}
+		}
+		pinned = owned
+	}
+
+	if s.pinCollections != nil {
+		pinned, err = s.filterPinnedRootHashes(r, pinned)
+		if errors.Is(err, errPinCollectionLabel) {
+			s.logger.Debug("list pinned root references: parse labels failed", "error", err)
+			s.logger.Error(nil, "list pinned root references: parse labels failed")
+			jsonhttp.BadRequest(w, "invalid label")
+			return
+		}
+		if err != nil {
+			s.logger.Debug("list pinned root references: filter references failed", "error", err)
+			s.logger.Error(nil, "list pinned root references: filter references failed")
+			jsonhttp.InternalServerError(w, "list pinned root references failed")
+			return
+		}
+	}
+
 	jsonhttp.OK(w, struct {
 		References []cluster.Address `json:"references"`
 	}{
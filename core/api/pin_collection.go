@@ -0,0 +1,499 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/redesblock/mop/core/api/jsonhttp"
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/pins"
+	"github.com/redesblock/mop/core/storer/storage"
+)
+
+// maxPinCollectionReferences is the maximal number of references of bulk
+// pin and unpin requests. The references are pinned within the request,
+// which traverses each of them.
+const maxPinCollectionReferences = 100
+
+var errPinCollectionLabel = errors.New("invalid label")
+
+type pinCollectionRequest struct {
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type pinCollectionsResponse struct {
+	Collections []pins.Collection `json:"collections"`
+}
+
+type pinCollectionResponse struct {
+	pins.Collection
+	References []pins.Member `json:"references"`
+}
+
+type pinReferencesRequest struct {
+	References []cluster.Address `json:"references"`
+}
+
+type pinReferenceResult struct {
+	Reference cluster.Address `json:"reference"`
+	Error     string          `json:"error,omitempty"`
+}
+
+type pinReferencesResponse struct {
+	References []pinReferenceResult `json:"references"`
+}
+
+// requestLabels returns the labels of the label query parameters, which
+// are formatted as key:value.
+func requestLabels(r *http.Request) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, l := range r.URL.Query()["label"] {
+		kv := strings.SplitN(l, ":", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, errPinCollectionLabel
+		}
+		labels[kv[0]] = kv[1]
+	}
+	return labels, nil
+}
+
+// pinCollectionOwner returns the owner of the collections of the request,
+// which is its tenant. The requests not bound to a tenant select the
+// collections of a tenant with the owner query parameter.
+func (s *Service) pinCollectionOwner(r *http.Request) string {
+	if t, ok := s.requestTenant(r.Context()); ok {
+		return t
+	}
+	return r.URL.Query().Get("owner")
+}
+
+// pinCollection returns the collection with the name of the owner of the
+// request.
+func (s *Service) pinCollection(r *http.Request, name string) (pins.Collection, error) {
+	return s.pinCollections.Get(pins.CollectionID(s.pinCollectionOwner(r), name))
+}
+
+// listPinCollections lists the collections of the tenant of the request
+// with all the labels.
+func (s *Service) listPinCollections(ctx context.Context, labels map[string]string) ([]pins.Collection, error) {
+	all, err := s.pinCollections.List()
+	if err != nil {
+		return nil, err
+	}
+	t, scoped := s.requestTenant(ctx)
+	cols := make([]pins.Collection, 0, len(all))
+	for _, col := range all {
+		if scoped && col.Owner != t {
+			continue
+		}
+		if col.HasLabels(labels) {
+			cols = append(cols, col)
+		}
+	}
+	return cols, nil
+}
+
+// filterPinnedRootHashes returns the pinned references of the collections
+// selected by the collection and label query parameters.
+func (s *Service) filterPinnedRootHashes(r *http.Request, pinned []cluster.Address) ([]cluster.Address, error) {
+	name := r.URL.Query().Get("collection")
+	labels, err := requestLabels(r)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" && len(labels) == 0 {
+		return pinned, nil
+	}
+
+	cols, err := s.listPinCollections(r.Context(), labels)
+	if err != nil {
+		return nil, err
+	}
+	selected := make(map[string]struct{})
+	for _, col := range cols {
+		if name != "" && col.Name != name {
+			continue
+		}
+		members, err := s.pinCollections.Members(col.ID())
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range members {
+			selected[m.Reference.String()] = struct{}{}
+		}
+	}
+
+	filtered := make([]cluster.Address, 0, len(selected))
+	for _, ref := range pinned {
+		if _, ok := selected[ref.String()]; ok {
+			filtered = append(filtered, ref)
+		}
+	}
+	return filtered, nil
+}
+
+// unpinUncollected unpins the reference once it is in no collection if
+// its pin was created for a collection. References pinned directly stay
+// pinned.
+func (s *Service) unpinUncollected(ctx context.Context, ref cluster.Address) error {
+	release, err := s.pinCollections.Release(ref)
+	if err != nil || !release {
+		return err
+	}
+	has, err := s.pinning.HasPin(ref)
+	if err != nil || !has {
+		return err
+	}
+	return s.deletePin(ctx, ref)
+}
+
+// uncollectUnpinned removes the reference from all the collections once
+// it is no longer pinned.
+func (s *Service) uncollectUnpinned(ref cluster.Address) error {
+	if s.pinCollections == nil {
+		return nil
+	}
+	has, err := s.pinning.HasPin(ref)
+	if err != nil || has {
+		return err
+	}
+	ids, err := s.pinCollections.IDs(ref)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, err := s.pinCollections.Remove(id, ref); err != nil {
+			return err
+		}
+	}
+	_, err = s.pinCollections.Release(ref)
+	return err
+}
+
+// keepPin records that the reference is pinned directly, so that it stays
+// pinned once it is in no collection.
+func (s *Service) keepPin(ref cluster.Address) error {
+	if s.pinCollections == nil {
+		return nil
+	}
+	return s.pinCollections.KeepPin(ref)
+}
+
+func (s *Service) listPinCollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	if s.pinCollections == nil {
+		jsonhttp.NotImplemented(w, "pin collections disabled")
+		return
+	}
+
+	labels, err := requestLabels(r)
+	if err != nil {
+		s.logger.Debug("list pin collections: parse labels failed", "error", err)
+		s.logger.Error(nil, "list pin collections: parse labels failed")
+		jsonhttp.BadRequest(w, "invalid label")
+		return
+	}
+
+	cols, err := s.listPinCollections(r.Context(), labels)
+	if err != nil {
+		s.logger.Debug("list pin collections: listing failed", "error", err)
+		s.logger.Error(nil, "list pin collections: listing failed")
+		jsonhttp.InternalServerError(w, "list pin collections failed")
+		return
+	}
+
+	jsonhttp.OK(w, pinCollectionsResponse{
+		Collections: cols,
+	})
+}
+
+func (s *Service) createPinCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	if s.pinCollections == nil {
+		jsonhttp.NotImplemented(w, "pin collections disabled")
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		if jsonhttp.HandleBodyReadError(err, w) {
+			return
+		}
+		s.logger.Debug("create pin collection: read request body failed", "error", err)
+		s.logger.Error(nil, "create pin collection: read request body failed")
+		jsonhttp.InternalServerError(w, "cannot read request")
+		return
+	}
+
+	var req pinCollectionRequest
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			s.logger.Debug("create pin collection: unmarshal request body failed", "error", err)
+			s.logger.Error(nil, "create pin collection: unmarshal request body failed")
+			jsonhttp.BadRequest(w, "invalid request body")
+			return
+		}
+	}
+
+	col, err := s.pinCollections.Create(name, s.pinCollectionOwner(r), req.Labels)
+	switch {
+	case errors.Is(err, pins.ErrInvalidCollectionName):
+		s.logger.Debug("create pin collection: invalid name", "name", name, "error", err)
+		s.logger.Error(nil, "create pin collection: invalid name")
+		jsonhttp.BadRequest(w, "invalid collection name")
+		return
+	case errors.Is(err, pins.ErrCollectionExists):
+		s.logger.Debug("create pin collection: collection exists", "name", name)
+		s.logger.Error(nil, "create pin collection: collection exists")
+		jsonhttp.Conflict(w, "collection already exists")
+		return
+	case err != nil:
+		s.logger.Debug("create pin collection: create failed", "name", name, "error", err)
+		s.logger.Error(nil, "create pin collection: create failed")
+		jsonhttp.InternalServerError(w, "create pin collection failed")
+		return
+	}
+
+	jsonhttp.Created(w, col)
+}
+
+func (s *Service) getPinCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	if s.pinCollections == nil {
+		jsonhttp.NotImplemented(w, "pin collections disabled")
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+
+	col, err := s.pinCollection(r, name)
+	switch {
+	case errors.Is(err, pins.ErrCollectionNotFound):
+		jsonhttp.NotFound(w, "collection not found")
+		return
+	case err != nil:
+		s.logger.Debug("get pin collection: get failed", "name", name, "error", err)
+		s.logger.Error(nil, "get pin collection: get failed")
+		jsonhttp.InternalServerError(w, "get pin collection failed")
+		return
+	}
+
+	members, err := s.pinCollections.Members(col.ID())
+	if err != nil {
+		s.logger.Debug("get pin collection: list references failed", "name", name, "error", err)
+		s.logger.Error(nil, "get pin collection: list references failed")
+		jsonhttp.InternalServerError(w, "get pin collection failed")
+		return
+	}
+
+	jsonhttp.OK(w, pinCollectionResponse{
+		Collection: col,
+		References: members,
+	})
+}
+
+// deletePinCollectionHandler deletes the collection and unpins its
+// references which are in no other collection.
+func (s *Service) deletePinCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	if s.pinCollections == nil {
+		jsonhttp.NotImplemented(w, "pin collections disabled")
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+
+	col, err := s.pinCollection(r, name)
+	switch {
+	case errors.Is(err, pins.ErrCollectionNotFound):
+		jsonhttp.NotFound(w, "collection not found")
+		return
+	case err != nil:
+		s.logger.Debug("delete pin collection: get failed", "name", name, "error", err)
+		s.logger.Error(nil, "delete pin collection: get failed")
+		jsonhttp.InternalServerError(w, "delete pin collection failed")
+		return
+	}
+
+	refs, err := s.pinCollections.Delete(col.ID())
+	if err != nil {
+		s.logger.Debug("delete pin collection: delete failed", "name", name, "error", err)
+		s.logger.Error(nil, "delete pin collection: delete failed")
+		jsonhttp.InternalServerError(w, "delete pin collection failed")
+		return
+	}
+
+	for _, ref := range refs {
+		if err := s.unpinUncollected(r.Context(), ref); err != nil {
+			s.logger.Debug("delete pin collection: unpin failed", "name", name, "chunk_address", ref, "error", err)
+			s.logger.Error(nil, "delete pin collection: unpin failed")
+			jsonhttp.InternalServerError(w, "delete pin collection: unpin failed")
+			return
+		}
+	}
+
+	jsonhttp.OK(w, nil)
+}
+
+// pinCollectionReferences returns the ID of the collection and the
+// references of the bulk pin and unpin requests. It responds to the request
+// on errors.
+func (s *Service) pinCollectionReferences(w http.ResponseWriter, r *http.Request, op string) (string, []cluster.Address, bool) {
+	if s.pinCollections == nil {
+		jsonhttp.NotImplemented(w, "pin collections disabled")
+		return "", nil, false
+	}
+
+	name := mux.Vars(r)["name"]
+
+	col, err := s.pinCollection(r, name)
+	switch {
+	case errors.Is(err, pins.ErrCollectionNotFound):
+		jsonhttp.NotFound(w, "collection not found")
+		return "", nil, false
+	case err != nil:
+		s.logger.Debug(op+": get collection failed", "name", name, "error", err)
+		s.logger.Error(nil, op+": get collection failed")
+		jsonhttp.InternalServerError(w, op+" failed")
+		return "", nil, false
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		if jsonhttp.HandleBodyReadError(err, w) {
+			return "", nil, false
+		}
+		s.logger.Debug(op+": read request body failed", "error", err)
+		s.logger.Error(nil, op+": read request body failed")
+		jsonhttp.InternalServerError(w, "cannot read request")
+		return "", nil, false
+	}
+
+	var req pinReferencesRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		s.logger.Debug(op+": unmarshal request body failed", "error", err)
+		s.logger.Error(nil, op+": unmarshal request body failed")
+		jsonhttp.BadRequest(w, "invalid request body")
+		return "", nil, false
+	}
+	if len(req.References) == 0 || len(req.References) > maxPinCollectionReferences {
+		s.logger.Error(nil, op+": invalid number of references", "count", len(req.References))
+		jsonhttp.BadRequest(w, "invalid number of references")
+		return "", nil, false
+	}
+
+	return col.ID(), req.References, true
+}
+
+// collectReference pins the reference and adds it to the collection. It
+// reports whether the reference was not in the collection before.
+func (s *Service) collectReference(ctx context.Context, id string, ref cluster.Address) (bool, error) {
+	created, err := s.pinReference(ctx, ref, true)
+	if err != nil {
+		return false, err
+	}
+	added, err := s.pinCollections.Add(id, ref, created)
+	if err != nil && created {
+		if err := s.deletePin(ctx, ref); err != nil {
+			s.logger.Debug("pin collection references: delete pins failed", "collection", id, "chunk_address", ref, "error", err)
+			s.logger.Error(nil, "pin collection references: delete pins failed")
+		}
+	}
+	return added, err
+}
+
+// uncollectReferences removes the references from the collection and
+// unpins those which are in no other collection.
+func (s *Service) uncollectReferences(ctx context.Context, id string, refs []cluster.Address) {
+	for _, ref := range refs {
+		if _, err := s.pinCollections.Remove(id, ref); err != nil {
+			s.logger.Debug("pin collection references: remove reference failed", "collection", id, "chunk_address", ref, "error", err)
+			s.logger.Error(nil, "pin collection references: remove reference failed")
+			continue
+		}
+		if err := s.unpinUncollected(ctx, ref); err != nil {
+			s.logger.Debug("pin collection references: unpin failed", "collection", id, "chunk_address", ref, "error", err)
+			s.logger.Error(nil, "pin collection references: unpin failed")
+		}
+	}
+}
+
+// pinCollectionReferencesHandler pins the references and adds them to the
+// collection. The references are pinned all or none: on the first one which
+// can not be pinned, the references added by the request are removed from
+// the collection and unpinned again.
+func (s *Service) pinCollectionReferencesHandler(w http.ResponseWriter, r *http.Request) {
+	id, refs, ok := s.pinCollectionReferences(w, r, "pin collection references")
+	if !ok {
+		return
+	}
+
+	resp := pinReferencesResponse{
+		References: make([]pinReferenceResult, 0, len(refs)),
+	}
+	added := make([]cluster.Address, 0, len(refs))
+	for _, ref := range refs {
+		collected, err := s.collectReference(r.Context(), id, ref)
+		if err != nil {
+			s.uncollectReferences(r.Context(), id, added)
+		}
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			s.logger.Debug("pin collection references: reference not found", "collection", id, "chunk_address", ref)
+			s.logger.Error(nil, "pin collection references: reference not found")
+			jsonhttp.NotFound(w, fmt.Sprintf("reference %s not found", ref))
+			return
+		case err != nil:
+			s.logger.Debug("pin collection references: pin failed", "collection", id, "chunk_address", ref, "error", err)
+			s.logger.Error(nil, "pin collection references: pin failed")
+			jsonhttp.InternalServerError(w, fmt.Sprintf("pin reference %s failed", ref))
+			return
+		}
+		if collected {
+			added = append(added, ref)
+		}
+		resp.References = append(resp.References, pinReferenceResult{Reference: ref})
+	}
+
+	jsonhttp.OK(w, resp)
+}
+
+// unpinCollectionReferencesHandler removes the references from the
+// collection and unpins those which are in no other collection.
+func (s *Service) unpinCollectionReferencesHandler(w http.ResponseWriter, r *http.Request) {
+	id, refs, ok := s.pinCollectionReferences(w, r, "unpin collection references")
+	if !ok {
+		return
+	}
+
+	resp := pinReferencesResponse{
+		References: make([]pinReferenceResult, len(refs)),
+	}
+	for i, ref := range refs {
+		resp.References[i].Reference = ref
+
+		removed, err := s.pinCollections.Remove(id, ref)
+		if err != nil {
+			s.logger.Debug("unpin collection references: remove reference failed", "collection", id, "chunk_address", ref, "error", err)
+			s.logger.Error(nil, "unpin collection references: remove reference failed")
+			resp.References[i].Error = "remove from collection failed"
+			continue
+		}
+		if !removed {
+			resp.References[i].Error = "reference not in collection"
+			continue
+		}
+
+		if err := s.unpinUncollected(r.Context(), ref); err != nil {
+			s.logger.Debug("unpin collection references: unpin failed", "collection", id, "chunk_address", ref, "error", err)
+			s.logger.Error(nil, "unpin collection references: unpin failed")
+			resp.References[i].Error = "unpin failed"
+		}
+	}
+
+	jsonhttp.OK(w, resp)
+}
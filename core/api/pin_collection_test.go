@@ -0,0 +1,297 @@
+package api_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redesblock/mop/core/api"
+	mockauth "github.com/redesblock/mop/core/api/auth/mock"
+	"github.com/redesblock/mop/core/api/jsonhttp"
+	"github.com/redesblock/mop/core/api/jsonhttp/jsonhttptest"
+	"github.com/redesblock/mop/core/cluster"
+	mockpost "github.com/redesblock/mop/core/incentives/voucher/mock"
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/pins"
+	statestore "github.com/redesblock/mop/core/storer/statestore/mock"
+	"github.com/redesblock/mop/core/storer/storage/mock"
+	"github.com/redesblock/mop/core/tags"
+	"github.com/redesblock/mop/core/tenant"
+	"github.com/redesblock/mop/core/traverser"
+)
+
+func TestPinCollections(t *testing.T) {
+	var (
+		logger          = log.Noop
+		storerMock      = mock.NewStorer()
+		stateStore      = statestore.NewStateStore()
+		collections     = pins.NewCollections(stateStore, storerMock, traverser.New(storerMock), logger)
+		client, _, _, _ = newTestServer(t, testServerOptions{
+			Storer:         storerMock,
+			Traversal:      traverser.New(storerMock),
+			Tags:           tags.NewTags(statestore.NewStateStore(), logger),
+			Pinning:        pins.NewService(storerMock, stateStore, traverser.New(storerMock)),
+			PinCollections: collections,
+			Logger:         logger,
+			Post:           mockpost.New(mockpost.WithAcceptAll()),
+		})
+		contents = []string{"this is a simple text", "this is another text", "this is a pinned text"}
+		refs     = make([]cluster.Address, len(contents))
+		size     uint64
+	)
+	t.Cleanup(func() {
+		if err := collections.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	for i, content := range contents {
+		var resp api.MopUploadResponse
+		jsonhttptest.Request(t, client, http.MethodPost, "/bytes", http.StatusCreated,
+			jsonhttptest.WithRequestHeader(api.ClusterDeferredUploadHeader, "true"),
+			jsonhttptest.WithRequestHeader(api.ClusterVoucherBatchIdHeader, batchOkStr),
+			jsonhttptest.WithRequestBody(strings.NewReader(content)),
+			jsonhttptest.WithUnmarshalJSONResponse(&resp),
+		)
+		refs[i] = resp.Reference
+		// the content fits a single chunk with the span
+		size += uint64(len(content) + cluster.SpanSize)
+	}
+	// the last reference is pinned directly before it is collected
+	jsonhttptest.Request(t, client, http.MethodPost, "/pins/"+refs[2].String(), http.StatusCreated)
+	unknown := cluster.MustParseHexAddress("838d0a193ecd1152d1bb1432d5ecc02398533b2494889e23b8bd5ace30ac2ccc")
+
+	getCollection := func(t *testing.T, name string) api.PinCollection {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); ; {
+			var col api.PinCollection
+			jsonhttptest.Request(t, client, http.MethodGet, "/pins/collections/"+name, http.StatusOK,
+				jsonhttptest.WithUnmarshalJSONResponse(&col),
+			)
+			if col.Unsized == 0 {
+				return col
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("got %d unsized references of collection %q", col.Unsized, name)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	listPins := func(t *testing.T, query string, want []cluster.Address) {
+		t.Helper()
+		var resp struct {
+			References []cluster.Address `json:"references"`
+		}
+		jsonhttptest.Request(t, client, http.MethodGet, "/pins"+query, http.StatusOK,
+			jsonhttptest.WithUnmarshalJSONResponse(&resp),
+		)
+		got := make(map[string]bool)
+		for _, ref := range resp.References {
+			got[ref.String()] = true
+		}
+		if len(got) != len(want) {
+			t.Fatalf("got references %v, want %v", resp.References, want)
+		}
+		for _, ref := range want {
+			if !got[ref.String()] {
+				t.Fatalf("got references %v, want %v", resp.References, want)
+			}
+		}
+	}
+
+	t.Run("create", func(t *testing.T) {
+		var col api.PinCollection
+		jsonhttptest.Request(t, client, http.MethodPost, "/pins/collections/backups", http.StatusCreated,
+			jsonhttptest.WithJSONRequestBody(api.PinCollectionRequest{
+				Labels: map[string]string{"team": "ops"},
+			}),
+			jsonhttptest.WithUnmarshalJSONResponse(&col),
+		)
+		if col.Name != "backups" || col.Labels["team"] != "ops" || col.Created.IsZero() {
+			t.Fatalf("got collection %+v", col)
+		}
+
+		jsonhttptest.Request(t, client, http.MethodPost, "/pins/collections/backups", http.StatusConflict,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "collection already exists",
+				Code:    http.StatusConflict,
+			}),
+		)
+		jsonhttptest.Request(t, client, http.MethodPost, "/pins/collections/site", http.StatusCreated)
+	})
+
+	t.Run("bulk pin", func(t *testing.T) {
+		// the references are pinned all or none
+		jsonhttptest.Request(t, client, http.MethodPost, "/pins/collections/backups/references", http.StatusNotFound,
+			jsonhttptest.WithJSONRequestBody(api.PinReferencesRequest{
+				References: []cluster.Address{refs[0], refs[1], refs[2], unknown},
+			}),
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "reference " + unknown.String() + " not found",
+				Code:    http.StatusNotFound,
+			}),
+		)
+		if col := getCollection(t, "backups"); col.Count != 0 || len(col.References) != 0 {
+			t.Fatalf("got collection %+v, want no references", col)
+		}
+		jsonhttptest.Request(t, client, http.MethodGet, "/pins/"+refs[0].String(), http.StatusNotFound)
+		jsonhttptest.Request(t, client, http.MethodGet, "/pins/"+refs[2].String(), http.StatusOK)
+
+		var resp api.PinReferencesResponse
+		jsonhttptest.Request(t, client, http.MethodPost, "/pins/collections/backups/references", http.StatusOK,
+			jsonhttptest.WithJSONRequestBody(api.PinReferencesRequest{
+				References: refs,
+			}),
+			jsonhttptest.WithUnmarshalJSONResponse(&resp),
+		)
+		if len(resp.References) != 3 {
+			t.Fatalf("got %d results, want 3", len(resp.References))
+		}
+		for i, res := range resp.References {
+			if !res.Reference.Equal(refs[i]) || res.Error != "" {
+				t.Fatalf("got result %+v for reference %s", res, refs[i])
+			}
+		}
+
+		jsonhttptest.Request(t, client, http.MethodPost, "/pins/collections/missing/references", http.StatusNotFound,
+			jsonhttptest.WithJSONRequestBody(api.PinReferencesRequest{
+				References: []cluster.Address{refs[0]},
+			}),
+		)
+		jsonhttptest.Request(t, client, http.MethodPost, "/pins/collections/backups/references", http.StatusBadRequest,
+			jsonhttptest.WithJSONRequestBody(api.PinReferencesRequest{}),
+		)
+		tooMany := make([]cluster.Address, api.MaxPinCollectionReferences+1)
+		for i := range tooMany {
+			tooMany[i] = refs[0]
+		}
+		jsonhttptest.Request(t, client, http.MethodPost, "/pins/collections/backups/references", http.StatusBadRequest,
+			jsonhttptest.WithJSONRequestBody(api.PinReferencesRequest{
+				References: tooMany,
+			}),
+		)
+
+		col := getCollection(t, "backups")
+		if col.Count != 3 || col.Size != size || len(col.References) != 3 {
+			t.Fatalf("got collection %+v, want 3 references of %d bytes", col, size)
+		}
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		listPins(t, "?collection=backups", refs)
+		listPins(t, "?label=team:ops", refs)
+		listPins(t, "?label=team:dev", []cluster.Address{})
+		listPins(t, "?collection=site", []cluster.Address{})
+		listPins(t, "", refs)
+
+		jsonhttptest.Request(t, client, http.MethodGet, "/pins?label=team", http.StatusBadRequest,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "invalid label",
+				Code:    http.StatusBadRequest,
+			}),
+		)
+
+		var cols api.PinCollections
+		jsonhttptest.Request(t, client, http.MethodGet, "/pins/collections?label=team:ops", http.StatusOK,
+			jsonhttptest.WithUnmarshalJSONResponse(&cols),
+		)
+		if len(cols.Collections) != 1 || cols.Collections[0].Name != "backups" {
+			t.Fatalf("got collections %+v, want backups", cols.Collections)
+		}
+	})
+
+	t.Run("bulk unpin", func(t *testing.T) {
+		var resp api.PinReferencesResponse
+		jsonhttptest.Request(t, client, http.MethodDelete, "/pins/collections/backups/references", http.StatusOK,
+			jsonhttptest.WithJSONRequestBody(api.PinReferencesRequest{
+				References: []cluster.Address{refs[0], unknown},
+			}),
+			jsonhttptest.WithUnmarshalJSONResponse(&resp),
+		)
+		if len(resp.References) != 2 || resp.References[0].Error != "" || resp.References[1].Error != "reference not in collection" {
+			t.Fatalf("got results %+v", resp.References)
+		}
+		jsonhttptest.Request(t, client, http.MethodGet, "/pins/"+refs[0].String(), http.StatusNotFound)
+		listPins(t, "?collection=backups", refs[1:])
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodDelete, "/pins/collections/backups", http.StatusOK)
+		jsonhttptest.Request(t, client, http.MethodGet, "/pins/collections/backups", http.StatusNotFound)
+		jsonhttptest.Request(t, client, http.MethodGet, "/pins/"+refs[1].String(), http.StatusNotFound)
+		// the reference pinned directly stays pinned
+		jsonhttptest.Request(t, client, http.MethodGet, "/pins/"+refs[2].String(), http.StatusOK)
+	})
+}
+
+func TestPinCollectionsTenants(t *testing.T) {
+	var (
+		logger      = log.Noop
+		storerMock  = mock.NewStorer()
+		stateStore  = statestore.NewStateStore()
+		collections = pins.NewCollections(stateStore, storerMock, traverser.New(storerMock), logger)
+		// the security tokens of the test are the tenant names
+		authenticator = &mockauth.Auth{
+			EnforceFunc: func(_, _, _ string) (bool, error) {
+				return true, nil
+			},
+			TenantFunc: func(key string) (string, bool, error) {
+				if key == "admin" {
+					return "", true, nil
+				}
+				return key, false, nil
+			},
+		}
+		client, _, _, _ = newTestServer(t, testServerOptions{
+			Storer:         storerMock,
+			Traversal:      traverser.New(storerMock),
+			Tags:           tags.NewTags(statestore.NewStateStore(), logger),
+			Pinning:        pins.NewService(storerMock, stateStore, traverser.New(storerMock)),
+			PinCollections: collections,
+			Logger:         logger,
+			Post:           mockpost.New(mockpost.WithAcceptAll()),
+			Restricted:     true,
+			Authenticator:  authenticator,
+			Tenants:        tenant.New(statestore.NewStateStore()),
+		})
+	)
+	t.Cleanup(func() {
+		if err := collections.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	// the names are scoped per tenant
+	for _, key := range []string{"a", "b"} {
+		jsonhttptest.Request(t, client, http.MethodPost, "/pins/collections/backups", http.StatusCreated,
+			jsonhttptest.WithRequestHeader("Authorization", "Bearer "+key),
+		)
+	}
+	jsonhttptest.Request(t, client, http.MethodPost, "/pins/collections/backups", http.StatusConflict,
+		jsonhttptest.WithRequestHeader("Authorization", "Bearer a"),
+	)
+	jsonhttptest.Request(t, client, http.MethodDelete, "/pins/collections/backups", http.StatusOK,
+		jsonhttptest.WithRequestHeader("Authorization", "Bearer b"),
+	)
+
+	var col api.PinCollection
+	jsonhttptest.Request(t, client, http.MethodGet, "/pins/collections/backups", http.StatusOK,
+		jsonhttptest.WithRequestHeader("Authorization", "Bearer a"),
+		jsonhttptest.WithUnmarshalJSONResponse(&col),
+	)
+	if col.Owner != "a" {
+		t.Fatalf("got collection %+v, want the collection of a", col)
+	}
+	jsonhttptest.Request(t, client, http.MethodGet, "/pins/collections/backups", http.StatusNotFound,
+		jsonhttptest.WithRequestHeader("Authorization", "Bearer b"),
+	)
+	// the owner query parameter selects the tenant of the admin requests
+	jsonhttptest.Request(t, client, http.MethodGet, "/pins/collections/backups?owner=a", http.StatusOK,
+		jsonhttptest.WithRequestHeader("Authorization", "Bearer admin"),
+	)
+	jsonhttptest.Request(t, client, http.MethodGet, "/pins/collections/backups?owner=a", http.StatusNotFound,
+		jsonhttptest.WithRequestHeader("Authorization", "Bearer b"),
+	)
+}
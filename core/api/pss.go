@@ -81,7 +81,7 @@ func (s *Service) pssPostHandler(w http.ResponseWriter, r *http.Request) {
 		jsonhttp.BadRequest(w, "invalid voucher batch id")
 		return
 	}
-	i, err := s.post.GetStampIssuer(batch)
+	i, err := s.stampIssuer(r.Context(), batch)
 	if err != nil {
 		s.logger.Debug("psser post: get voucher batch issuer failed", "batch_id", fmt.Sprintf("%x", batch), "error", err)
 		s.logger.Error(nil, "psser post: get voucher batch issuer failed")
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gorilla/mux"
+	"github.com/redesblock/mop/core/api/jsonhttp"
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/incentives/voucher"
+	"github.com/redesblock/mop/core/resolver/registry"
+	"github.com/redesblock/mop/core/tracer"
+)
+
+type namePublishRequest struct {
+	Reference cluster.Address `json:"reference"`
+}
+
+type namePublishResponse struct {
+	Name      string          `json:"name"`
+	Owner     common.Address  `json:"owner"`
+	Reference cluster.Address `json:"reference"`
+}
+
+// namePublishHandler publishes a local name registry record of the name
+// pointing to the reference. The record is signed by the node key, so it is
+// resolved by the nodes which have the node address configured as the owner
+// of the registry.
+func (s *Service) namePublishHandler(w http.ResponseWriter, r *http.Request) {
+	logger := tracer.NewLoggerWithTraceID(r.Context(), s.logger)
+
+	name := mux.Vars(r)["name"]
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		if jsonhttp.HandleBodyReadError(err, w) {
+			return
+		}
+		logger.Debug("name publish: read request body failed", "error", err)
+		logger.Error(nil, "name publish: read request body failed")
+		jsonhttp.InternalServerError(w, "cannot read request")
+		return
+	}
+
+	var req namePublishRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		logger.Debug("name publish: unmarshal request body failed", "error", err)
+		logger.Error(nil, "name publish: unmarshal request body failed")
+		jsonhttp.BadRequest(w, "invalid request body")
+		return
+	}
+	if req.Reference.IsZero() {
+		jsonhttp.BadRequest(w, "missing reference")
+		return
+	}
+
+	owner, err := s.signer.BSCAddress()
+	if err != nil {
+		logger.Debug("name publish: signer address failed", "error", err)
+		logger.Error(nil, "name publish: signer address failed")
+		jsonhttp.InternalServerError(w, "name publish: signer address failed")
+		return
+	}
+
+	putter, wait, err := s.newStamperPutter(r)
+	if err != nil {
+		logger.Debug("name publish: putter failed", "error", err)
+		logger.Error(nil, "name publish: putter failed")
+		switch {
+		case errors.Is(err, voucher.ErrNotFound):
+			jsonhttp.BadRequest(w, "batch not found")
+		case errors.Is(err, voucher.ErrNotUsable):
+			jsonhttp.BadRequest(w, "batch not usable yet")
+		case errors.Is(err, errInvalidVoucherBatch):
+			jsonhttp.BadRequest(w, "invalid voucher batch id")
+		default:
+			jsonhttp.BadRequest(w, nil)
+		}
+		return
+	}
+
+	if err := registry.Publish(r.Context(), s.storer, putter, s.signer, name, req.Reference); err != nil {
+		logger.Debug("name publish: publish record failed", "name", name, "error", err)
+		logger.Error(nil, "name publish: publish record failed")
+		switch {
+		case errors.Is(err, voucher.ErrBucketFull):
+			jsonhttp.PaymentRequired(w, "batch is overissued")
+		default:
+			jsonhttp.InternalServerError(w, "name publish: publish record failed")
+		}
+		return
+	}
+
+	if err = wait(); err != nil {
+		logger.Debug("name publish: chainsync chunks failed", "error", err)
+		logger.Error(nil, "name publish: chainsync chunks failed")
+		jsonhttp.InternalServerError(w, "name publish: chainsync failed")
+		return
+	}
+
+	jsonhttp.Created(w, namePublishResponse{
+		Name:      name,
+		Owner:     owner,
+		Reference: req.Reference,
+	})
+}
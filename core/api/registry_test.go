@@ -0,0 +1,57 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/redesblock/mop/core/api"
+	"github.com/redesblock/mop/core/api/jsonhttp/jsonhttptest"
+	mockpost "github.com/redesblock/mop/core/incentives/voucher/mock"
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/resolver/registry"
+	smock "github.com/redesblock/mop/core/storer/storage/mock"
+	testingc "github.com/redesblock/mop/core/storer/storage/testing"
+)
+
+func TestNamePublish(t *testing.T) {
+	var (
+		storerMock      = smock.NewStorer()
+		client, _, _, _ = newTestServer(t, testServerOptions{
+			Storer: storerMock,
+			Logger: log.Noop,
+			Post:   mockpost.New(mockpost.WithAcceptAll()),
+		})
+		ref = testingc.GenerateTestRandomChunk().Address()
+	)
+
+	var resp api.NamePublishResponse
+	jsonhttptest.Request(t, client, http.MethodPost, "/names/dataset.team", http.StatusCreated,
+		jsonhttptest.WithRequestHeader(api.ClusterVoucherBatchIdHeader, batchOkStr),
+		jsonhttptest.WithJSONRequestBody(api.NamePublishRequest{Reference: ref}),
+		jsonhttptest.WithUnmarshalJSONResponse(&resp),
+	)
+	if resp.Name != "dataset.team" || !resp.Reference.Equal(ref) {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	got, err := registry.New(storerMock, resp.Owner).Resolve("dataset.team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(ref) {
+		t.Fatalf("got %s, want %s", got, ref)
+	}
+
+	t.Run("missing reference", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodPost, "/names/dataset.team", http.StatusBadRequest,
+			jsonhttptest.WithRequestHeader(api.ClusterVoucherBatchIdHeader, batchOkStr),
+			jsonhttptest.WithJSONRequestBody(api.NamePublishRequest{}),
+		)
+	})
+
+	t.Run("missing batch", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodPost, "/names/dataset.team", http.StatusBadRequest,
+			jsonhttptest.WithJSONRequestBody(api.NamePublishRequest{Reference: ref}),
+		)
+	})
+}
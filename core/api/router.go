@@ -1,14 +1,10 @@
 package api
 
 import (
-	"crypto/tls"
-	"encoding/json"
 	"expvar"
 	"fmt"
 	"net/http"
 	"net/http/pprof"
-	"os"
-	"strings"
 	"sync"
 	"time"
 
@@ -22,6 +18,7 @@ import (
 	"github.com/redesblock/mop/core/api/httpaccess"
 	"github.com/redesblock/mop/core/api/jsonhttp"
 	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/traffic"
 	"resenje.org/web"
 )
 
@@ -54,6 +51,7 @@ func (s *Service) MountDebug(restricted bool) {
 		handlers.CompressHandler,
 		s.corsHandler,
 		web.NoCacheHeadersHandler,
+		s.tenantHandler(restricted),
 		web.FinalHandler(s.router),
 	)
 }
@@ -84,6 +82,7 @@ func (s *Service) MountAPI() {
 		s.responseCodeMetricsHandler,
 		s.pageviewMetricsHandler,
 		s.corsHandler,
+		s.tenantHandler(s.Restricted),
 		web.FinalHandler(s.router),
 	)
 }
@@ -166,6 +165,18 @@ func (s *Service) mountAPI() {
 		),
 	})
 
+	if s.DNSLinkGateway {
+		s.router.MatcherFunc(isCustomDomainRequest).Subrouter().Handle("/{path:.*}", jsonhttp.MethodHandler{
+			"GET": web.ChainHandlers(
+				s.admission.Handler(RouteGroupDownload),
+				s.contentLengthMetricMiddleware(),
+				s.usageMiddleware(),
+				s.newTracingHandler("custom-domain-download"),
+				web.FinalHandlerFunc(s.customDomainHandler),
+			),
+		})
+	}
+
 	s.router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintln(w, "BNB Smart Chain Cluster Mop")
 	})
@@ -182,7 +193,9 @@ func (s *Service) mountAPI() {
 
 	handle("/bytes", jsonhttp.MethodHandler{
 		"POST": web.ChainHandlers(
+			s.admission.Handler(RouteGroupUpload),
 			s.contentLengthMetricMiddleware(),
+			s.usageMiddleware(),
 			s.newTracingHandler("bytes-upload"),
 			web.FinalHandlerFunc(s.bytesUploadHandler),
 		),
@@ -190,7 +203,9 @@ func (s *Service) mountAPI() {
 
 	handle("/bytes/{address}", jsonhttp.MethodHandler{
 		"GET": web.ChainHandlers(
+			s.admission.Handler(RouteGroupDownload),
 			s.contentLengthMetricMiddleware(),
+			s.usageMiddleware(),
 			s.newTracingHandler("bytes-download"),
 			web.FinalHandlerFunc(s.bytesGetHandler),
 		),
@@ -202,25 +217,35 @@ func (s *Service) mountAPI() {
 
 	handle("/chunks", jsonhttp.MethodHandler{
 		"POST": web.ChainHandlers(
+			s.admission.Handler(RouteGroupUpload),
 			jsonhttp.NewMaxBodyBytesHandler(cluster.ChunkWithSpanSize),
+			s.usageMiddleware(),
 			web.FinalHandlerFunc(s.chunkUploadHandler),
 		),
 	})
 
+	// the upload stream holds its admission slot until it ends and records
+	// the usage of its chunks as they are stored
 	handle("/chunks/stream", web.ChainHandlers(
 		s.newTracingHandler("chunks-stream-upload"),
 		web.FinalHandlerFunc(s.chunkUploadStreamHandler),
 	))
 
 	handle("/chunks/{address}", jsonhttp.MethodHandler{
-		"GET":    http.HandlerFunc(s.chunkGetHandler),
+		"GET": web.ChainHandlers(
+			s.admission.Handler(RouteGroupDownload),
+			s.usageMiddleware(),
+			web.FinalHandlerFunc(s.chunkGetHandler),
+		),
 		"HEAD":   http.HandlerFunc(s.hasChunkHandler),
 		"DELETE": http.HandlerFunc(s.removeChunk),
 	})
 
 	handle("/soc/{owner}/{id}", jsonhttp.MethodHandler{
 		"POST": web.ChainHandlers(
+			s.admission.Handler(RouteGroupUpload),
 			jsonhttp.NewMaxBodyBytesHandler(cluster.ChunkWithSpanSize),
+			s.usageMiddleware(),
 			web.FinalHandlerFunc(s.socUploadHandler),
 		),
 	})
@@ -228,59 +253,109 @@ func (s *Service) mountAPI() {
 	handle("/feeds/{owner}/{topic}", jsonhttp.MethodHandler{
 		"GET": http.HandlerFunc(s.feedGetHandler),
 		"POST": web.ChainHandlers(
+			s.admission.Handler(RouteGroupUpload),
 			jsonhttp.NewMaxBodyBytesHandler(cluster.ChunkWithSpanSize),
+			s.usageMiddleware(),
 			web.FinalHandlerFunc(s.feedPostHandler),
 		),
 	})
 
 	handle("/mop", jsonhttp.MethodHandler{
 		"POST": web.ChainHandlers(
+			s.admission.Handler(RouteGroupUpload),
 			s.contentLengthMetricMiddleware(),
+			s.usageMiddleware(),
 			s.newTracingHandler("mop-upload"),
 			web.FinalHandlerFunc(s.mopUploadHandler),
 		),
 	})
 
+	// signURLHandler responds with a temporary signed URL of the request
+	// when it has the secret query parameter.
+	signURLHandler := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			if q.Has("secret") {
+				singer := urlSigner.New(q.Get("secret"))
+				q.Del("secret")
+				r.URL.RawQuery = q.Encode()
+
+				url := singer.SignTemporary(*r.URL, time.Now().UTC().Add(10*time.Minute))
+				jsonhttp.OK(w, url.String())
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+
+	var mopArchiveHandler, mopDownloadHandler http.Handler
+	if s.Options.Restricted {
+		mopArchiveHandler = web.ChainHandlers(
+			signURLHandler,
+			auth.PermissionCheckHandler(s.auth),
+			auth.URLSignCheckHandler(s.auth),
+			s.admission.Handler(RouteGroupDownload),
+			s.usageMiddleware(),
+			s.newTracingHandler("mop-archive-download"),
+			web.FinalHandlerFunc(s.mopDownloadHandler),
+		)
+		mopDownloadHandler = web.ChainHandlers(
+			signURLHandler,
+			auth.PermissionCheckHandler(s.auth),
+			auth.URLSignCheckHandler(s.auth),
+			s.admission.Handler(RouteGroupDownload),
+			s.contentLengthMetricMiddleware(),
+			s.usageMiddleware(),
+			s.newTracingHandler("mop-download"),
+			web.FinalHandlerFunc(s.mopDownloadHandler),
+		)
+	} else {
+		mopArchiveHandler = web.ChainHandlers(
+			s.admission.Handler(RouteGroupDownload),
+			s.usageMiddleware(),
+			s.newTracingHandler("mop-archive-download"),
+			web.FinalHandlerFunc(s.mopDownloadHandler),
+		)
+		mopDownloadHandler = web.ChainHandlers(
+			s.admission.Handler(RouteGroupDownload),
+			s.contentLengthMetricMiddleware(),
+			s.usageMiddleware(),
+			s.newTracingHandler("mop-download"),
+			web.FinalHandlerFunc(s.mopDownloadHandler),
+		)
+	}
+
 	handle("/mop/{address}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Query().Get("format") != "" {
+			mopArchiveHandler.ServeHTTP(w, r)
+			return
+		}
 		u := r.URL
 		u.Path += "/"
 		http.Redirect(w, r, u.String(), http.StatusPermanentRedirect)
 	}))
 
-	if s.Options.Restricted {
-		handle("/mop/{address}/{path:.*}", jsonhttp.MethodHandler{
-			"GET": web.ChainHandlers(
-				func(h http.Handler) http.Handler {
-					return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-						q := r.URL.Query()
-						if q.Has("secret") {
-							singer := urlSigner.New(q.Get("secret"))
-							q.Del("secret")
-							r.URL.RawQuery = q.Encode()
-
-							url := singer.SignTemporary(*r.URL, time.Now().UTC().Add(10*time.Minute))
-							jsonhttp.OK(w, url.String())
-							return
-						}
-						h.ServeHTTP(w, r)
-					})
-				},
-				auth.PermissionCheckHandler(s.auth),
-				auth.URLSignCheckHandler(s.auth),
-				s.contentLengthMetricMiddleware(),
-				s.newTracingHandler("mop-download"),
-				web.FinalHandlerFunc(s.mopDownloadHandler),
-			),
-		})
-	} else {
-		handle("/mop/{address}/{path:.*}", jsonhttp.MethodHandler{
-			"GET": web.ChainHandlers(
-				s.contentLengthMetricMiddleware(),
-				s.newTracingHandler("mop-download"),
-				web.FinalHandlerFunc(s.mopDownloadHandler),
-			),
-		})
-	}
+	handle("/mop/{address}/{path:.*}", jsonhttp.MethodHandler{
+		"GET": mopDownloadHandler,
+	})
+
+	handle("/manifests/{address}", jsonhttp.MethodHandler{
+		"PATCH": web.ChainHandlers(
+			s.admission.Handler(RouteGroupUpload),
+			jsonhttp.NewMaxBodyBytesHandler(manifestPatchMaxRequestSize),
+			s.usageMiddleware(),
+			s.newTracingHandler("manifest-patch"),
+			web.FinalHandlerFunc(s.manifestPatchHandler),
+		),
+	})
+
+	handle("/manifests/{address}/diff/{other}", jsonhttp.MethodHandler{
+		"GET": web.ChainHandlers(
+			s.admission.Handler(RouteGroupDownload),
+			s.newTracingHandler("manifest-diff"),
+			web.FinalHandlerFunc(s.manifestDiffHandler),
+		),
+	})
 
 	handle("/psser/send/{topic}/{targets}", web.ChainHandlers(
 		web.FinalHandler(jsonhttp.MethodHandler{
@@ -295,7 +370,27 @@ func (s *Service) mountAPI() {
 		web.FinalHandlerFunc(s.pssWsHandler),
 	))
 
+	// permissionCheck restricts the access to the objects of the tenants.
+	permissionCheck := func(h http.Handler) http.Handler {
+		if s.Restricted {
+			return auth.PermissionCheckHandler(s.auth)(h)
+		}
+		return h
+	}
+
+	handle("/names/{name}", web.ChainHandlers(
+		permissionCheck,
+		web.FinalHandler(jsonhttp.MethodHandler{
+			"POST": web.ChainHandlers(
+				s.admission.Handler(RouteGroupUpload),
+				jsonhttp.NewMaxBodyBytesHandler(1024),
+				web.FinalHandlerFunc(s.namePublishHandler),
+			),
+		})),
+	)
+
 	handle("/tags", web.ChainHandlers(
+		permissionCheck,
 		web.FinalHandler(jsonhttp.MethodHandler{
 			"GET": http.HandlerFunc(s.listTagsHandler),
 			"POST": web.ChainHandlers(
@@ -306,6 +401,7 @@ func (s *Service) mountAPI() {
 	)
 
 	handle("/tags/{id}", web.ChainHandlers(
+		permissionCheck,
 		web.FinalHandler(jsonhttp.MethodHandler{
 			"GET":    http.HandlerFunc(s.getTagHandler),
 			"DELETE": http.HandlerFunc(s.deleteTagHandler),
@@ -317,12 +413,47 @@ func (s *Service) mountAPI() {
 	)
 
 	handle("/pins", web.ChainHandlers(
+		permissionCheck,
 		web.FinalHandler(jsonhttp.MethodHandler{
 			"GET": http.HandlerFunc(s.listPinnedRootHashes),
 		})),
 	)
 
+	handle("/pins/collections", web.ChainHandlers(
+		permissionCheck,
+		web.FinalHandler(jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.listPinCollectionsHandler),
+		})),
+	)
+
+	handle("/pins/collections/{name}", web.ChainHandlers(
+		permissionCheck,
+		web.FinalHandler(jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.getPinCollectionHandler),
+			"POST": web.ChainHandlers(
+				jsonhttp.NewMaxBodyBytesHandler(16*1024),
+				web.FinalHandlerFunc(s.createPinCollectionHandler),
+			),
+			"DELETE": http.HandlerFunc(s.deletePinCollectionHandler),
+		})),
+	)
+
+	handle("/pins/collections/{name}/references", web.ChainHandlers(
+		permissionCheck,
+		web.FinalHandler(jsonhttp.MethodHandler{
+			"POST": web.ChainHandlers(
+				jsonhttp.NewMaxBodyBytesHandler(maxPinCollectionReferences*cluster.HashSize*4),
+				web.FinalHandlerFunc(s.pinCollectionReferencesHandler),
+			),
+			"DELETE": web.ChainHandlers(
+				jsonhttp.NewMaxBodyBytesHandler(maxPinCollectionReferences*cluster.HashSize*4),
+				web.FinalHandlerFunc(s.unpinCollectionReferencesHandler),
+			),
+		})),
+	)
+
 	handle("/pins/{reference}", web.ChainHandlers(
+		permissionCheck,
 		web.FinalHandler(jsonhttp.MethodHandler{
 			"GET":    http.HandlerFunc(s.getPinnedRootHash),
 			"POST":   http.HandlerFunc(s.pinRootHash),
@@ -339,6 +470,16 @@ func (s *Service) mountAPI() {
 		),
 	})
 
+	handle("/check/{address}", web.ChainHandlers(
+		permissionCheck,
+		web.FinalHandler(jsonhttp.MethodHandler{
+			"GET": web.ChainHandlers(
+				s.admission.Handler(RouteGroupDownload),
+				web.FinalHandlerFunc(s.checkHandler),
+			),
+		})),
+	)
+
 	handle("/readiness", web.ChainHandlers(
 		httpaccess.NewHTTPAccessSuppressLogHandler(),
 		web.FinalHandlerFunc(s.readinessHandler),
@@ -420,6 +561,10 @@ func (s *Service) mountBusinessDebug(restricted bool) {
 		"GET": http.HandlerFunc(s.topologyHandler),
 	})
 
+	handle("/reachability", jsonhttp.MethodHandler{
+		"GET": http.HandlerFunc(s.reachabilityHandler),
+	})
+
 	handle("/welcome-message", jsonhttp.MethodHandler{
 		"GET": http.HandlerFunc(s.getWelcomeMessageHandler),
 		"POST": web.ChainHandlers(
@@ -586,6 +731,22 @@ func (s *Service) mountBusinessDebug(restricted bool) {
 		"GET": http.HandlerFunc(s.accountingInfoHandler),
 	})
 
+	handle("/usage", jsonhttp.MethodHandler{
+		"GET": http.HandlerFunc(s.usageHandler),
+	})
+
+	handle("/tenants/{tenant}", jsonhttp.MethodHandler{
+		"PUT": web.ChainHandlers(
+			jsonhttp.NewMaxBodyBytesHandler(1024),
+			web.FinalHandlerFunc(s.tenantCredentialPutHandler),
+		),
+		"DELETE": http.HandlerFunc(s.tenantCredentialDeleteHandler),
+	})
+
+	handle("/traffic/exporter", jsonhttp.MethodHandler{
+		"GET": http.HandlerFunc(s.trafficExporterHandler),
+	})
+
 	handle("/readiness", web.ChainHandlers(
 		httpaccess.NewHTTPAccessSuppressLogHandler(),
 		web.FinalHandlerFunc(s.readinessHandler),
@@ -599,42 +760,38 @@ func (s *Service) mountBusinessDebug(restricted bool) {
 }
 
 type trafficObject struct {
-	Timestamp     int64            `json:"timestamp"`
-	Address       string           `json:"address"`
-	UploadedCnt   map[string]int64 `json:"uploaded_cnt"`
-	Uploaded      map[string]int64 `json:"uploaded"`
-	DownloadedCnt map[string]int64 `json:"downloaded_cnt"`
-	Downloaded    map[string]int64 `json:"downloaded"`
-	Signed        string           `json:"signed"`
-	NATAddr       string           `json:"nat_addr"`
-	mutex         sync.Mutex       `json:"-"`
+	Timestamp     int64
+	UploadedCnt   map[string]int64
+	Uploaded      map[string]int64
+	DownloadedCnt map[string]int64
+	Downloaded    map[string]int64
+	mutex         sync.Mutex
 }
 
 func (s *Service) trafficHandler(t time.Time, key string, upload bool, size int64) {
 	duration := 60 * time.Minute
 	if s.lru == nil {
 		s.lru, _ = lru.NewWithEvict(2, func(key, value interface{}) {
-			traffic := value.(*trafficObject)
-			if len(traffic.Downloaded) > 0 || len(traffic.Uploaded) > 0 {
-				traffic.NATAddr = s.NATAddr
-				traffic.Address = s.bscAddress.String()
-				bts, _ := json.Marshal(traffic)
-
-				s.logger.Debug("traffic handler", "val", string(bts))
-				trafficHost := os.Getenv("MOP_TRAFFIC_HOST")
-				if len(trafficHost) == 0 {
-					return
+			obj := value.(*trafficObject)
+			if len(obj.Downloaded) > 0 || len(obj.Uploaded) > 0 {
+				obj.mutex.Lock()
+				report := &traffic.Report{
+					Timestamp:     obj.Timestamp,
+					Address:       s.bscAddress.String(),
+					UploadedCnt:   copyTraffic(obj.UploadedCnt),
+					Uploaded:      copyTraffic(obj.Uploaded),
+					DownloadedCnt: copyTraffic(obj.DownloadedCnt),
+					Downloaded:    copyTraffic(obj.Downloaded),
+					NATAddr:       s.NATAddr,
 				}
-				client := &http.Client{
-					Transport: &http.Transport{
-						TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-					},
+				obj.mutex.Unlock()
+
+				s.logger.Debug("traffic handler", "timestamp", report.Timestamp, "uploaded", report.Uploaded, "downloaded", report.Downloaded)
+				if s.trafficExporter == nil {
+					return
 				}
-				resp, err := client.Post(trafficHost+"/api/v1/traffic", "application/json", strings.NewReader(string(bts)))
-				if err != nil {
-					s.logger.Error(err, "traffic handler", "key", key, "val", string(bts))
-				} else {
-					resp.Body.Close()
+				if err := s.trafficExporter.Export(report); err != nil {
+					s.logger.Error(err, "traffic handler: export report failed", "timestamp", report.Timestamp)
 				}
 			}
 		})
@@ -643,7 +800,11 @@ func (s *Service) trafficHandler(t time.Time, key string, upload bool, size int6
 			ticker := time.NewTicker(duration)
 			defer ticker.Stop()
 			for {
-				<-ticker.C
+				select {
+				case <-s.quit:
+					return
+				case <-ticker.C:
+				}
 				d := int64(duration / time.Second)
 				timestamp := (time.Now().Unix() / d) * d
 				if _, ok := s.lru.Get(timestamp); !ok {
@@ -683,3 +844,11 @@ func (s *Service) trafficHandler(t time.Time, key string, upload bool, size int6
 	traffic.mutex.Unlock()
 	s.lru.Add(timestamp, traffic)
 }
+
+func copyTraffic(m map[string]int64) map[string]int64 {
+	c := make(map[string]int64, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/redesblock/mop/core/chunk/soc"
 	"github.com/redesblock/mop/core/cluster"
 	"github.com/redesblock/mop/core/incentives/voucher"
+	"github.com/redesblock/mop/core/usage"
 )
 
 type socPostResponse struct {
@@ -132,7 +133,7 @@ func (s *Service) socUploadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	i, err := s.post.GetStampIssuer(batch)
+	i, err := s.stampIssuer(r.Context(), batch)
 	if err != nil {
 		s.logger.Debug("soc upload: get voucher batch issuer failed", "batch_id", fmt.Sprintf("%x", batch), "error", err)
 		s.logger.Error(nil, "soc upload: get voucher batch issue")
@@ -169,7 +170,7 @@ func (s *Service) socUploadHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if strings.ToLower(r.Header.Get(ClusterPinHeader)) == "true" {
-		if err := s.pinning.CreatePin(ctx, sch.Address(), false); err != nil {
+		if err := s.createPin(ctx, sch.Address(), false); err != nil {
 			s.logger.Debug("soc upload: create pins failed", "chunk_address", sch.Address(), "error", err)
 			s.logger.Error(nil, "soc upload: create pins failed")
 			jsonhttp.InternalServerError(w, "soc upload: creation of pins failed")
@@ -177,5 +178,6 @@ func (s *Service) socUploadHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	usage.SetReference(r.Context(), sch.Address().String())
 	jsonhttp.Created(w, chunkAddressResponse{Reference: sch.Address()})
 }
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"net"
 	"net/http"
 	"strings"
 
@@ -30,3 +31,47 @@ func (s *Service) subdomainHandler(w http.ResponseWriter, r *http.Request) {
 
 	s.serveReference(address, pathVar, w, r)
 }
+
+// customDomainHandler serves the content of the custom domain of the request
+// host, the domain is resolved by the name resolver, eg. from its DNSLink
+// TXT record.
+func (s *Service) customDomainHandler(w http.ResponseWriter, r *http.Request) {
+	logger := tracer.NewLoggerWithTraceID(r.Context(), s.logger)
+
+	host := requestHostname(r)
+	pathVar := mux.Vars(r)["path"]
+	if strings.HasSuffix(pathVar, "/") {
+		pathVar = strings.TrimRight(pathVar, "/")
+		// NOTE: leave one slash if there was some
+		pathVar += "/"
+	}
+
+	address, err := s.resolveNameOrAddress(host)
+	if err != nil {
+		logger.Debug("custom domain get: resolve host failed", "host", host, "error", err)
+		logger.Error(nil, "custom domain get: resolve host failed")
+		jsonhttp.NotFound(w, nil)
+		return
+	}
+
+	s.serveReference(address, pathVar, w, r)
+}
+
+// isCustomDomainRequest reports whether the request host is a domain name
+// which is not the local host, so that its content is served by the
+// customDomainHandler instead of the API.
+func isCustomDomainRequest(r *http.Request, _ *mux.RouteMatch) bool {
+	host := requestHostname(r)
+	if host == "" || net.ParseIP(host) != nil || !strings.Contains(host, ".") {
+		return false
+	}
+	return host != "localhost" && !strings.HasSuffix(host, ".localhost")
+}
+
+func requestHostname(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
@@ -1,18 +1,25 @@
 package api_test
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"path"
+	"strings"
 	"testing"
 
 	"github.com/redesblock/mop/core/api"
 	"github.com/redesblock/mop/core/api/jsonhttp/jsonhttptest"
 	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/file/loadsave"
+	"github.com/redesblock/mop/core/file/pipeline/builder"
 	mockpost "github.com/redesblock/mop/core/incentives/voucher/mock"
 	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/manifest"
+	"github.com/redesblock/mop/core/resolver"
 	resolverMock "github.com/redesblock/mop/core/resolver/mock"
 	statestore "github.com/redesblock/mop/core/storer/statestore/mock"
+	"github.com/redesblock/mop/core/storer/storage"
 	"github.com/redesblock/mop/core/storer/storage/mock"
 	"github.com/redesblock/mop/core/tags"
 )
@@ -170,3 +177,78 @@ func TestSubdomains(t *testing.T) {
 		})
 	}
 }
+
+func TestCustomDomain(t *testing.T) {
+	var (
+		storerMock = mock.NewStorer()
+		ctx        = context.Background()
+		factory    = pipelineFactory(storerMock, storage.ModePutUpload, false)
+	)
+
+	m, err := manifest.NewDefaultManifest(loadsave.New(storerMock, factory), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := builder.FeedPipeline(ctx, factory(), strings.NewReader("<h1>docs</h1>"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add(ctx, "index.html", manifest.NewEntry(ref, map[string]string{
+		manifest.EntryMetadataFilenameKey:    "index.html",
+		manifest.EntryMetadataContentTypeKey: "text/html; charset=utf-8",
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add(ctx, manifest.RootPath, manifest.NewEntry(cluster.ZeroAddress, map[string]string{
+		manifest.WebsiteIndexDocumentSuffixKey: "index.html",
+	})); err != nil {
+		t.Fatal(err)
+	}
+	root, err := m.Store(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := resolverMock.NewResolver(
+		resolverMock.WithResolveFunc(func(name string) (cluster.Address, error) {
+			if name != "docs.example.org" {
+				return cluster.ZeroAddress, resolver.ErrNotFound
+			}
+			return root, nil
+		}),
+	)
+
+	t.Run("enabled", func(t *testing.T) {
+		client, _, _, _ := newTestServer(t, testServerOptions{
+			Storer:         storerMock,
+			Resolver:       res,
+			Logger:         log.Noop,
+			DNSLinkGateway: true,
+		})
+
+		jsonhttptest.Request(t, client, http.MethodGet, "http://docs.example.org/", http.StatusOK,
+			jsonhttptest.WithExpectedResponse([]byte("<h1>docs</h1>")),
+		)
+		jsonhttptest.Request(t, client, http.MethodGet, "http://docs.example.org:1633/index.html", http.StatusOK,
+			jsonhttptest.WithExpectedResponse([]byte("<h1>docs</h1>")),
+		)
+		jsonhttptest.Request(t, client, http.MethodGet, "http://unknown.example.org/", http.StatusNotFound)
+
+		// the api is still served on the local host
+		jsonhttptest.Request(t, client, http.MethodGet, "http://localhost/bytes/"+ref.String(), http.StatusOK,
+			jsonhttptest.WithExpectedResponse([]byte("<h1>docs</h1>")),
+		)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		client, _, _, _ := newTestServer(t, testServerOptions{
+			Storer:   storerMock,
+			Resolver: res,
+			Logger:   log.Noop,
+		})
+
+		jsonhttptest.Request(t, client, http.MethodGet, "http://docs.example.org/bytes/"+ref.String(), http.StatusOK,
+			jsonhttptest.WithExpectedResponse([]byte("<h1>docs</h1>")),
+		)
+	})
+}
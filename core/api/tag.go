@@ -1,10 +1,13 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"strconv"
 	"time"
 
@@ -12,6 +15,7 @@ import (
 	"github.com/redesblock/mop/core/api/jsonhttp"
 	"github.com/redesblock/mop/core/cluster"
 	"github.com/redesblock/mop/core/tags"
+	"github.com/redesblock/mop/core/tenant"
 )
 
 type tagRequest struct {
@@ -63,7 +67,7 @@ func (s *Service) createTagHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	tag, err := s.tags.Create(0)
+	tag, err := s.createTag(r.Context())
 	if err != nil {
 		s.logger.Debug("create tag: create tag failed", "error", err)
 		s.logger.Error(nil, "create tag: create tag failed")
@@ -85,7 +89,7 @@ func (s *Service) getTagHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tag, err := s.tags.Get(uint32(id))
+	tag, err := s.ownedTag(r.Context(), uint32(id))
 	if err != nil {
 		if errors.Is(err, tags.ErrNotFound) {
 			s.logger.Debug("get tag: tag not found", "tag_id", id)
@@ -114,7 +118,7 @@ func (s *Service) deleteTagHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tag, err := s.tags.Get(uint32(id))
+	tag, err := s.ownedTag(r.Context(), uint32(id))
 	if err != nil {
 		if errors.Is(err, tags.ErrNotFound) {
 			s.logger.Debug("delete tag: tag not found", "tag_id", id)
@@ -128,6 +132,14 @@ func (s *Service) deleteTagHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if s.tenants != nil {
+		if err := s.tenants.RemoveAll(tenant.KindTag, fmt.Sprint(tag.Uid)); err != nil {
+			s.logger.Debug("delete tag: remove owners failed", "tag_id", id, "error", err)
+			s.logger.Error(nil, "delete tag: remove owners failed", "tag_id", id)
+			jsonhttp.InternalServerError(w, "cannot delete tag")
+			return
+		}
+	}
 	s.tags.Delete(tag.Uid)
 	jsonhttp.NoContent(w)
 }
@@ -165,7 +177,7 @@ func (s *Service) doneSplitHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	tag, err := s.tags.Get(uint32(id))
+	tag, err := s.ownedTag(r.Context(), uint32(id))
 	if err != nil {
 		if errors.Is(err, tags.ErrNotFound) {
 			s.logger.Debug("done split tag: tag not found", "tag_id", id)
@@ -212,7 +224,7 @@ func (s *Service) listTagsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	tagList, err := s.tags.ListAll(r.Context(), offset, limit)
+	tagList, err := s.listTags(r.Context(), offset, limit)
 	if err != nil {
 		s.logger.Debug("list tags: listing failed", "offset", offset, "limit", limit, "error", err)
 		s.logger.Error(nil, "list tags: listing failed")
@@ -229,3 +241,93 @@ func (s *Service) listTagsHandler(w http.ResponseWriter, r *http.Request) {
 		Tags: tags,
 	})
 }
+
+// listTags lists the tags owned by the tenant of the request.
+func (s *Service) listTags(ctx context.Context, offset, limit int) ([]*tags.Tag, error) {
+	t, ok := s.requestTenant(ctx)
+	if !ok {
+		return s.tags.ListAll(ctx, offset, limit)
+	}
+	if t == "" {
+		return s.listUnownedTags(ctx, offset, limit)
+	}
+
+	keys, err := s.tenants.Keys(tenant.KindTag, t)
+	if err != nil {
+		return nil, err
+	}
+	uids := make([]uint32, 0, len(keys))
+	for _, k := range keys {
+		uid, err := strconv.ParseUint(k, 10, 32)
+		if err != nil {
+			continue
+		}
+		uids = append(uids, uint32(uid))
+	}
+	sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+
+	var owned []*tags.Tag
+	for _, uid := range uids {
+		if limit > 0 && len(owned) == limit {
+			break
+		}
+		tag, err := s.tags.Get(uid)
+		if errors.Is(err, tags.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		owned = append(owned, tag)
+	}
+	return owned, nil
+}
+
+// listUnownedTagsPage is the number of tags read at once when listing the
+// tags without an owner.
+const listUnownedTagsPage = 100
+
+// listUnownedTags lists the tags without an owner. The objects without an
+// owner are not recorded, so all the tags are read page by page and the
+// offset and limit are applied to the ones without an owner.
+func (s *Service) listUnownedTags(ctx context.Context, offset, limit int) ([]*tags.Tag, error) {
+	var (
+		owned []*tags.Tag
+		seen  = make(map[uint32]struct{})
+	)
+	for page := 0; limit <= 0 || len(owned) < limit; page += listUnownedTagsPage {
+		all, err := s.tags.ListAll(ctx, page, listUnownedTagsPage)
+		if err != nil {
+			return nil, err
+		}
+		if len(all) == 0 {
+			break
+		}
+		for _, tag := range all {
+			if limit > 0 && len(owned) == limit {
+				break
+			}
+			if _, ok := seen[tag.Uid]; ok {
+				continue
+			}
+			seen[tag.Uid] = struct{}{}
+			owns, err := s.tenants.Owns(tenant.KindTag, "", fmt.Sprint(tag.Uid))
+			if err != nil {
+				return nil, err
+			}
+			if !owns {
+				continue
+			}
+			if offset > 0 {
+				offset--
+				continue
+			}
+			owned = append(owned, tag)
+		}
+	}
+	return owned, nil
+}
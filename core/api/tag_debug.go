@@ -49,7 +49,7 @@ func (s *Service) getDebugTagHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tag, err := s.tags.Get(uint32(id))
+	tag, err := s.ownedTag(r.Context(), uint32(id))
 	if err != nil {
 		if errors.Is(err, tags.ErrNotFound) {
 			s.logger.Debug("get tag: tag not found", "tag_id", id)
@@ -0,0 +1,270 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/redesblock/mop/core/api/jsonhttp"
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/incentives/voucher"
+	"github.com/redesblock/mop/core/tags"
+	"github.com/redesblock/mop/core/tenant"
+)
+
+// tenantHandler binds the requests of the restricted API to the tenant of
+// their security token. Requests of administrators, whose tokens were issued
+// for the credential of the node, reach the objects of all the tenants,
+// while other requests without a tenant only reach the objects without an
+// owner.
+func (s *Service) tenantHandler(restricted bool) func(h http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		if !restricted {
+			return h
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var name string
+			if key := bearerKey(r); key != "" {
+				t, admin, err := s.auth.Tenant(key)
+				if err == nil && admin {
+					h.ServeHTTP(w, r)
+					return
+				}
+				name = t
+			}
+			h.ServeHTTP(w, r.WithContext(tenant.WithContext(r.Context(), name)))
+		})
+	}
+}
+
+// bearerKey returns the API key of the request, if any.
+func bearerKey(r *http.Request) string {
+	token := r.Header.Get("Authorization")
+	if !strings.HasPrefix(token, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(token, "Bearer "))
+}
+
+// requestTenant returns the tenant of the request and false if the request
+// reaches the objects of all the tenants.
+func (s *Service) requestTenant(ctx context.Context) (string, bool) {
+	if s.tenants == nil {
+		return "", false
+	}
+	return tenant.FromContext(ctx)
+}
+
+// owns reports whether the tenant of the request owns the object.
+func (s *Service) owns(ctx context.Context, kind, key string) (bool, error) {
+	t, ok := s.requestTenant(ctx)
+	if !ok {
+		return true, nil
+	}
+	return s.tenants.Owns(kind, t, key)
+}
+
+// addOwner records the tenant of the request as an owner of the object.
+// Objects of requests not bound to a tenant, or of anonymous ones, are left
+// without an owner.
+func (s *Service) addOwner(ctx context.Context, kind, key string) error {
+	t, ok := s.requestTenant(ctx)
+	if !ok || t == "" {
+		return nil
+	}
+	return s.tenants.Add(kind, t, key)
+}
+
+// createTag creates a new tag owned by the tenant of the request.
+func (s *Service) createTag(ctx context.Context) (*tags.Tag, error) {
+	tag, err := s.tags.Create(0)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.addOwner(ctx, tenant.KindTag, fmt.Sprint(tag.Uid)); err != nil {
+		s.tags.Delete(tag.Uid)
+		return nil, fmt.Errorf("tag owner: %w", err)
+	}
+	return tag, nil
+}
+
+// ownedTag returns the tag if the tenant of the request owns it, and
+// tags.ErrNotFound otherwise.
+func (s *Service) ownedTag(ctx context.Context, uid uint32) (*tags.Tag, error) {
+	owns, err := s.owns(ctx, tenant.KindTag, fmt.Sprint(uid))
+	if err != nil {
+		return nil, fmt.Errorf("tag owner: %w", err)
+	}
+	if !owns {
+		return nil, tags.ErrNotFound
+	}
+	return s.tags.Get(uid)
+}
+
+// createPin pins the reference for the tenant of the request.
+func (s *Service) createPin(ctx context.Context, ref cluster.Address, traverse bool) error {
+	created, err := s.pinReference(ctx, ref, traverse)
+	if err != nil || created {
+		return err
+	}
+	return s.keepPin(ref)
+}
+
+// pinReference pins the reference for the tenant of the request and
+// reports whether the pin was created.
+func (s *Service) pinReference(ctx context.Context, ref cluster.Address, traverse bool) (bool, error) {
+	has, err := s.pinning.HasPin(ref)
+	if err != nil {
+		return false, err
+	}
+	if has {
+		return false, s.addPinOwner(ctx, ref)
+	}
+	if err := s.pinning.CreatePin(ctx, ref, traverse); err != nil {
+		return false, err
+	}
+	return true, s.addOwner(ctx, tenant.KindPin, ref.String())
+}
+
+// addPinOwner records the tenant of the request as an owner of the pinned
+// reference, keeping the pin for its other owners.
+func (s *Service) addPinOwner(ctx context.Context, ref cluster.Address) error {
+	if s.tenants == nil {
+		return nil
+	}
+	// requests not bound to a tenant pin for the anonymous owner
+	owner, _ := s.requestTenant(ctx)
+
+	owners, err := s.tenants.Owners(tenant.KindPin, ref.String())
+	if err != nil {
+		return err
+	}
+	if len(owners) == 0 {
+		if owner == "" {
+			return nil
+		}
+		// the pin without an owner is kept for the anonymous owner
+		if err := s.tenants.Add(tenant.KindPin, "", ref.String()); err != nil {
+			return err
+		}
+	}
+	return s.tenants.Add(tenant.KindPin, owner, ref.String())
+}
+
+// deletePin unpins the reference for the tenant of the request. The pin is
+// only deleted once no other tenant owns it.
+func (s *Service) deletePin(ctx context.Context, ref cluster.Address) error {
+	if s.tenants == nil {
+		return s.pinning.DeletePin(ctx, ref)
+	}
+	if t, ok := s.requestTenant(ctx); ok {
+		last, err := s.tenants.Remove(tenant.KindPin, t, ref.String())
+		if err != nil {
+			return err
+		}
+		if !last {
+			return nil
+		}
+	}
+	if err := s.pinning.DeletePin(ctx, ref); err != nil {
+		return err
+	}
+	return s.tenants.RemoveAll(tenant.KindPin, ref.String())
+}
+
+// stampIssuer returns the stamp issuer of the batch if the tenant of the
+// request owns it, and voucher.ErrNotFound otherwise.
+func (s *Service) stampIssuer(ctx context.Context, batchID []byte) (*voucher.StampIssuer, error) {
+	owns, err := s.owns(ctx, tenant.KindBatch, hex.EncodeToString(batchID))
+	if err != nil {
+		return nil, fmt.Errorf("batch owner: %w", err)
+	}
+	if !owns {
+		return nil, voucher.ErrNotFound
+	}
+	return s.post.GetStampIssuer(batchID)
+}
+
+type tenantCredentialRequest struct {
+	Password string `json:"password"`
+}
+
+// tenantCredentialPutHandler sets the password of the tenant, for which the
+// security tokens bound to the tenant are issued. The credentials are only
+// managed by the administrators.
+func (s *Service) tenantCredentialPutHandler(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.tenantCredentialName(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.logger.Debug("tenant credential: read request body failed", "error", err)
+		s.logger.Error(nil, "tenant credential: read request body failed")
+		jsonhttp.BadRequest(w, "read request body")
+		return
+	}
+	var payload tenantCredentialRequest
+	if err := json.Unmarshal(body, &payload); err != nil {
+		s.logger.Debug("tenant credential: unmarshal request body failed", "error", err)
+		s.logger.Error(nil, "tenant credential: unmarshal request body failed")
+		jsonhttp.BadRequest(w, "unmarshal json body")
+		return
+	}
+	if payload.Password == "" {
+		jsonhttp.BadRequest(w, "missing password")
+		return
+	}
+
+	if err := s.tenants.SetCredential(name, payload.Password); err != nil {
+		s.logger.Debug("tenant credential: set credential failed", "tenant", name, "error", err)
+		s.logger.Error(nil, "tenant credential: set credential failed")
+		jsonhttp.InternalServerError(w, "set tenant credential failed")
+		return
+	}
+	jsonhttp.OK(w, nil)
+}
+
+// tenantCredentialDeleteHandler removes the password of the tenant. The
+// security tokens issued before remain valid until they expire.
+func (s *Service) tenantCredentialDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.tenantCredentialName(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.tenants.RemoveCredential(name); err != nil {
+		s.logger.Debug("tenant credential: remove credential failed", "tenant", name, "error", err)
+		s.logger.Error(nil, "tenant credential: remove credential failed")
+		jsonhttp.InternalServerError(w, "remove tenant credential failed")
+		return
+	}
+	jsonhttp.OK(w, nil)
+}
+
+// tenantCredentialName returns the tenant of the credential request, or
+// responds with an error if the request is not the one of an administrator.
+func (s *Service) tenantCredentialName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if s.tenants == nil {
+		jsonhttp.NotImplemented(w, "tenants disabled")
+		return "", false
+	}
+	if _, ok := tenant.FromContext(r.Context()); ok {
+		jsonhttp.Forbidden(w, "tenant credentials are managed by administrators")
+		return "", false
+	}
+	name := mux.Vars(r)["tenant"]
+	if err := tenant.ValidName(name); err != nil {
+		s.logger.Debug("tenant credential: invalid tenant", "tenant", name, "error", err)
+		s.logger.Error(nil, "tenant credential: invalid tenant")
+		jsonhttp.BadRequest(w, "invalid tenant")
+		return "", false
+	}
+	return name, true
+}
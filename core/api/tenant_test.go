@@ -0,0 +1,258 @@
+package api_test
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/redesblock/mop/core/api"
+	"github.com/redesblock/mop/core/api/auth/mock"
+	"github.com/redesblock/mop/core/api/jsonhttp"
+	"github.com/redesblock/mop/core/api/jsonhttp/jsonhttptest"
+	"github.com/redesblock/mop/core/cluster"
+	mockpost "github.com/redesblock/mop/core/incentives/voucher/mock"
+	"github.com/redesblock/mop/core/log"
+	pinning "github.com/redesblock/mop/core/pins/mock"
+	statestore "github.com/redesblock/mop/core/storer/statestore/mock"
+	mockstorer "github.com/redesblock/mop/core/storer/storage/mock"
+	"github.com/redesblock/mop/core/tags"
+	"github.com/redesblock/mop/core/tenant"
+	"github.com/redesblock/mop/core/traverser"
+)
+
+func TestTenants(t *testing.T) {
+	var (
+		logger        = log.Noop
+		storer        = mockstorer.NewStorer()
+		tenants       = tenant.New(statestore.NewStateStore())
+		authenticator = &mock.Auth{
+			AuthorizeFunc: func(password string) bool { return password == "node" },
+			EnforceFunc: func(_, _, _ string) (bool, error) {
+				return true, nil
+			},
+			TenantKeyFunc: func(_, tenant string, admin bool) (string, error) {
+				if admin {
+					return "admin", nil
+				}
+				return tenant, nil
+			},
+			// the security tokens of the test are the tenant names
+			TenantFunc: func(key string) (string, bool, error) {
+				switch key {
+				case "admin":
+					return "", true, nil
+				case "creator":
+					return "", false, nil
+				}
+				return key, false, nil
+			},
+		}
+		client, _, _, _ = newTestServer(t, testServerOptions{
+			Storer:        storer,
+			Traversal:     traverser.New(storer),
+			Tags:          tags.NewTags(statestore.NewStateStore(), logger),
+			Pinning:       pinning.NewServiceMock(),
+			Logger:        logger,
+			Post:          mockpost.New(mockpost.WithAcceptAll()),
+			Restricted:    true,
+			Authenticator: authenticator,
+			Tenants:       tenants,
+		})
+	)
+
+	for _, name := range []string{"a", "b"} {
+		if err := tenants.Add(tenant.KindBatch, name, batchOkStr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	as := func(key string) jsonhttptest.Option {
+		return jsonhttptest.WithRequestHeader("Authorization", "Bearer "+key)
+	}
+
+	t.Run("tags", func(t *testing.T) {
+		var tr api.TagResponse
+		jsonhttptest.Request(t, client, http.MethodPost, "/tags", http.StatusCreated,
+			as("a"),
+			jsonhttptest.WithJSONRequestBody(api.TagRequest{}),
+			jsonhttptest.WithUnmarshalJSONResponse(&tr),
+		)
+		tagPath := "/tags/" + strconv.FormatUint(uint64(tr.Uid), 10)
+
+		jsonhttptest.Request(t, client, http.MethodGet, tagPath, http.StatusOK, as("a"))
+		jsonhttptest.Request(t, client, http.MethodGet, tagPath, http.StatusNotFound, as("b"),
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "tag not present",
+				Code:    http.StatusNotFound,
+			}),
+		)
+		jsonhttptest.Request(t, client, http.MethodDelete, tagPath, http.StatusNotFound, as("b"))
+
+		// the tag can not be used for uploads of other tenants
+		jsonhttptest.Request(t, client, http.MethodPost, "/bytes", http.StatusInternalServerError,
+			as("b"),
+			jsonhttptest.WithRequestHeader(api.ClusterTagHeader, strconv.FormatUint(uint64(tr.Uid), 10)),
+			jsonhttptest.WithRequestHeader(api.ClusterVoucherBatchIdHeader, batchOkStr),
+			jsonhttptest.WithRequestBody(strings.NewReader("tenant data")),
+		)
+
+		for _, tc := range []struct {
+			key  string
+			want []uint32
+		}{
+			{"a", []uint32{tr.Uid}},
+			{"b", nil},
+			// tokens without a tenant which are not administrators are
+			// anonymous
+			{"creator", nil},
+			{"admin", []uint32{tr.Uid}},
+		} {
+			var resp api.ListTagsResponse
+			jsonhttptest.Request(t, client, http.MethodGet, "/tags", http.StatusOK,
+				as(tc.key),
+				jsonhttptest.WithUnmarshalJSONResponse(&resp),
+			)
+			var got []uint32
+			for _, tag := range resp.Tags {
+				got = append(got, tag.Uid)
+			}
+			if len(got) != len(tc.want) || (len(got) > 0 && got[0] != tc.want[0]) {
+				t.Fatalf("tenant %q: got tags %v, want %v", tc.key, got, tc.want)
+			}
+		}
+
+		jsonhttptest.Request(t, client, http.MethodDelete, tagPath, http.StatusNoContent, as("a"))
+	})
+
+	t.Run("tag pages", func(t *testing.T) {
+		unowned := make(map[uint32]bool)
+		for i := 0; i < 3; i++ {
+			for _, key := range []string{"a", "creator"} {
+				var tr api.TagResponse
+				jsonhttptest.Request(t, client, http.MethodPost, "/tags", http.StatusCreated,
+					as(key),
+					jsonhttptest.WithJSONRequestBody(api.TagRequest{}),
+					jsonhttptest.WithUnmarshalJSONResponse(&tr),
+				)
+				if key == "creator" {
+					unowned[tr.Uid] = true
+				}
+			}
+		}
+
+		// the pages of the tags without an owner are full until the last one
+		for offset := 0; ; offset++ {
+			var resp api.ListTagsResponse
+			jsonhttptest.Request(t, client, http.MethodGet, "/tags?limit=1&offset="+strconv.Itoa(offset), http.StatusOK,
+				as("creator"),
+				jsonhttptest.WithUnmarshalJSONResponse(&resp),
+			)
+			if offset == 3 {
+				if len(resp.Tags) != 0 {
+					t.Fatalf("offset %d: got %d tags, want none", offset, len(resp.Tags))
+				}
+				break
+			}
+			if len(resp.Tags) != 1 || !unowned[resp.Tags[0].Uid] {
+				t.Fatalf("offset %d: got tags %v, want one of %v", offset, resp.Tags, unowned)
+			}
+			delete(unowned, resp.Tags[0].Uid)
+		}
+	})
+
+	t.Run("pins", func(t *testing.T) {
+		var resp api.MopUploadResponse
+		jsonhttptest.Request(t, client, http.MethodPost, "/bytes", http.StatusCreated,
+			as("a"),
+			jsonhttptest.WithRequestHeader(api.ClusterDeferredUploadHeader, "true"),
+			jsonhttptest.WithRequestHeader(api.ClusterVoucherBatchIdHeader, batchOkStr),
+			jsonhttptest.WithRequestHeader(api.ClusterPinHeader, "true"),
+			jsonhttptest.WithRequestBody(strings.NewReader("this is a simple text")),
+			jsonhttptest.WithUnmarshalJSONResponse(&resp),
+		)
+		ref := resp.Reference
+		pinPath := "/pins/" + ref.String()
+
+		listPins := func(key string, want []cluster.Address) {
+			t.Helper()
+			jsonhttptest.Request(t, client, http.MethodGet, "/pins", http.StatusOK,
+				as(key),
+				jsonhttptest.WithExpectedJSONResponse(struct {
+					References []cluster.Address `json:"references"`
+				}{
+					References: want,
+				}),
+			)
+		}
+
+		listPins("a", []cluster.Address{ref})
+		listPins("b", []cluster.Address{})
+		listPins("creator", []cluster.Address{})
+		listPins("admin", []cluster.Address{ref})
+		jsonhttptest.Request(t, client, http.MethodGet, pinPath, http.StatusNotFound, as("b"))
+		jsonhttptest.Request(t, client, http.MethodDelete, pinPath, http.StatusNotFound, as("b"))
+
+		// the pin is kept until the last tenant unpins it
+		jsonhttptest.Request(t, client, http.MethodPost, pinPath, http.StatusOK, as("b"))
+		listPins("b", []cluster.Address{ref})
+		jsonhttptest.Request(t, client, http.MethodDelete, pinPath, http.StatusOK, as("a"))
+		listPins("a", []cluster.Address{})
+		listPins("b", []cluster.Address{ref})
+		jsonhttptest.Request(t, client, http.MethodDelete, pinPath, http.StatusOK, as("b"))
+		listPins("admin", nil)
+	})
+
+	t.Run("batches", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodPost, "/bytes", http.StatusBadRequest,
+			as("c"),
+			jsonhttptest.WithRequestHeader(api.ClusterDeferredUploadHeader, "true"),
+			jsonhttptest.WithRequestHeader(api.ClusterVoucherBatchIdHeader, batchOkStr),
+			jsonhttptest.WithRequestBody(strings.NewReader("tenant data")),
+		)
+		jsonhttptest.Request(t, client, http.MethodPost, "/bytes", http.StatusCreated,
+			as("admin"),
+			jsonhttptest.WithRequestHeader(api.ClusterDeferredUploadHeader, "true"),
+			jsonhttptest.WithRequestHeader(api.ClusterVoucherBatchIdHeader, batchOkStr),
+			jsonhttptest.WithRequestBody(strings.NewReader("tenant data")),
+		)
+	})
+
+	t.Run("credentials", func(t *testing.T) {
+		debugClient, _, _, _ := newTestServer(t, testServerOptions{
+			DebugAPI:      true,
+			Restricted:    true,
+			Authenticator: authenticator,
+			Tenants:       tenants,
+		})
+		login := func(user, password string, status int, key string) {
+			t.Helper()
+
+			basic := base64.StdEncoding.EncodeToString([]byte(user + ":" + password))
+			opts := []jsonhttptest.Option{
+				jsonhttptest.WithRequestHeader("Authorization", "Basic "+basic),
+				jsonhttptest.WithJSONRequestBody(api.SecurityTokenRequest{Role: "maintainer", Expiry: 60}),
+			}
+			if key != "" {
+				opts = append(opts, jsonhttptest.WithExpectedJSONResponse(api.SecurityTokenResponse{Key: key}))
+			}
+			jsonhttptest.Request(t, client, http.MethodPost, "/auth", status, opts...)
+		}
+		credential := jsonhttptest.WithJSONRequestBody(api.TenantCredentialRequest{Password: "secret"})
+
+		jsonhttptest.Request(t, debugClient, http.MethodPut, "/tenants/d", http.StatusForbidden, as("a"), credential)
+		jsonhttptest.Request(t, debugClient, http.MethodPut, "/tenants/"+strings.Repeat("a", 65), http.StatusBadRequest, as("admin"), credential)
+		login("d", "secret", http.StatusUnauthorized, "")
+
+		jsonhttptest.Request(t, debugClient, http.MethodPut, "/tenants/d", http.StatusOK, as("admin"), credential)
+		login("d", "secret", http.StatusCreated, "d")
+		login("d", "wrong", http.StatusUnauthorized, "")
+		// the tenant and the admin flag are taken from the credential
+		login("", "node", http.StatusCreated, "admin")
+		login("d", "node", http.StatusCreated, "admin")
+
+		jsonhttptest.Request(t, debugClient, http.MethodDelete, "/tenants/d", http.StatusOK, as("admin"))
+		login("d", "secret", http.StatusUnauthorized, "")
+	})
+}
@@ -0,0 +1,24 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/redesblock/mop/core/api/jsonhttp"
+)
+
+// trafficExporterHandler returns the delivery status of the traffic reports.
+func (s *Service) trafficExporterHandler(w http.ResponseWriter, _ *http.Request) {
+	if s.trafficExporter == nil {
+		jsonhttp.NotImplemented(w, "traffic export disabled")
+		return
+	}
+
+	status, err := s.trafficExporter.Status()
+	if err != nil {
+		s.logger.Debug("traffic exporter: get status failed", "error", err)
+		s.logger.Error(nil, "traffic exporter: get status failed")
+		jsonhttp.InternalServerError(w, "traffic exporter: get status failed")
+		return
+	}
+	jsonhttp.OK(w, status)
+}
@@ -0,0 +1,84 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/redesblock/mop/core/api"
+	"github.com/redesblock/mop/core/api/jsonhttp"
+	"github.com/redesblock/mop/core/api/jsonhttp/jsonhttptest"
+	"github.com/redesblock/mop/core/crypto"
+	"github.com/redesblock/mop/core/log"
+	statestore "github.com/redesblock/mop/core/storer/statestore/mock"
+	"github.com/redesblock/mop/core/traffic"
+)
+
+func TestTrafficExporter(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		client, _, _, _ := newTestServer(t, testServerOptions{DebugAPI: true})
+		jsonhttptest.Request(t, client, http.MethodGet, "/traffic/exporter", http.StatusNotImplemented,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "traffic export disabled",
+				Code:    http.StatusNotImplemented,
+			}),
+		)
+	})
+
+	t.Run("status", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+		defer srv.Close()
+
+		key, err := crypto.GenerateSecp256k1Key()
+		if err != nil {
+			t.Fatal(err)
+		}
+		exporter, err := traffic.New(crypto.NewDefaultSigner(key), statestore.NewStateStore(), log.Noop, traffic.Options{URL: srv.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer exporter.Close()
+
+		client, _, _, _ := newTestServer(t, testServerOptions{DebugAPI: true, TrafficExporter: exporter})
+		jsonhttptest.Request(t, client, http.MethodGet, "/traffic/exporter", http.StatusOK,
+			jsonhttptest.WithExpectedJSONResponse(traffic.Status{}),
+		)
+	})
+
+	t.Run("close", func(t *testing.T) {
+		// the billing service is unavailable, so the reports stay spooled
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		key, err := crypto.GenerateSecp256k1Key()
+		if err != nil {
+			t.Fatal(err)
+		}
+		signer := crypto.NewDefaultSigner(key)
+		exporter, err := traffic.New(signer, statestore.NewStateStore(), log.Noop, traffic.Options{URL: srv.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer exporter.Close()
+
+		s := api.New(key.PublicKey, key.PublicKey, common.Address{}, log.Noop, nil, nil, api.FullMode, false, false, nil, nil)
+		s.Configure(signer, nil, nil, api.Options{}, api.ExtraOptions{TrafficExporter: exporter}, 1, nil)
+		s.AddTraffic(time.Now(), "127.0.0.1", false, 100)
+
+		// the traffic of the current hour is exported on close
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		status, err := exporter.Status()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Pending != 1 {
+			t.Fatalf("got %d pending reports, want %d", status.Pending, 1)
+		}
+	})
+}
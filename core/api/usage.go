@@ -0,0 +1,193 @@
+package api
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/redesblock/mop/core/api/jsonhttp"
+	"github.com/redesblock/mop/core/tenant"
+	"github.com/redesblock/mop/core/usage"
+)
+
+type usageRecordResponse struct {
+	Time      int64  `json:"time"`
+	Reference string `json:"reference"`
+	Tenant    string `json:"tenant"`
+	Requests  uint64 `json:"requests"`
+	Served    uint64 `json:"served"`
+	Stored    uint64 `json:"stored"`
+	Cost      uint64 `json:"cost"`
+}
+
+type usageResponse struct {
+	Records []usageRecordResponse `json:"records"`
+}
+
+// usageHandler returns the usage records selected by the from and to unix
+// timestamps and the reference and tenant query parameters, as JSON or as CSV
+// with the csv format query parameter.
+func (s *Service) usageHandler(w http.ResponseWriter, r *http.Request) {
+	if s.usage == nil {
+		jsonhttp.NotImplemented(w, "usage accounting disabled")
+		return
+	}
+
+	q := r.URL.Query()
+	f := usage.Filter{
+		Reference: q.Get("reference"),
+		Tenant:    q.Get("tenant"),
+	}
+	for name, t := range map[string]*time.Time{"from": &f.From, "to": &f.To} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		ts, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			s.logger.Debug("usage: parse timestamp string failed", "name", name, "string", v, "error", err)
+			s.logger.Error(nil, "usage: parse timestamp string failed")
+			jsonhttp.BadRequest(w, "bad "+name)
+			return
+		}
+		*t = time.Unix(ts, 0)
+	}
+
+	records, err := s.usage.Records(f)
+	if err != nil {
+		s.logger.Debug("usage: get records failed", "error", err)
+		s.logger.Error(nil, "usage: get records failed")
+		jsonhttp.InternalServerError(w, "usage: get records failed")
+		return
+	}
+
+	switch format := strings.ToLower(q.Get("format")); format {
+	case "", "json":
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="usage.csv"`)
+		if err := usage.WriteCSV(w, records); err != nil {
+			s.logger.Debug("usage: write csv failed", "error", err)
+			s.logger.Error(nil, "usage: write csv failed")
+		}
+		return
+	default:
+		s.logger.Debug("usage: unknown format", "format", format)
+		s.logger.Error(nil, "usage: unknown format")
+		jsonhttp.BadRequest(w, "bad format")
+		return
+	}
+
+	resp := usageResponse{Records: make([]usageRecordResponse, 0, len(records))}
+	for _, rec := range records {
+		resp.Records = append(resp.Records, usageRecordResponse{
+			Time:      rec.Time.Unix(),
+			Reference: rec.Reference,
+			Tenant:    rec.Tenant,
+			Requests:  rec.Requests,
+			Served:    rec.Served,
+			Stored:    rec.Stored,
+			Cost:      rec.Cost,
+		})
+	}
+	jsonhttp.OK(w, resp)
+}
+
+// usageMiddleware records the usage of the content routes. The bytes of
+// the successful downloads are attributed as served and the bytes of the
+// successful uploads as stored to the root reference and the tenant of the
+// request, together with the retrieval cost of the request. The hijacked
+// connections, like the ones of the aborted archive downloads, are not
+// recorded.
+func (s *Service) usageMiddleware() func(h http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if s.usage == nil {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			now := time.Now()
+			ctx, meter := usage.WithMeter(r.Context())
+			r = r.WithContext(ctx)
+			body := &usageBodyReader{ReadCloser: r.Body}
+			if r.Body != nil {
+				r.Body = body
+			}
+			uw := &usageResponseWriter{ResponseWriter: w, status: http.StatusOK}
+
+			h.ServeHTTP(uw, r)
+
+			if uw.hijacked || uw.status >= http.StatusBadRequest {
+				return
+			}
+			reference := meter.Reference()
+			if reference == "" {
+				reference = mux.Vars(r)["address"]
+			}
+			var served, stored uint64
+			if r.Method == http.MethodGet {
+				served = uw.size
+			} else {
+				stored = body.size
+			}
+			t, _ := tenant.FromContext(r.Context())
+			s.usage.Add(now, reference, t, served, stored, meter.Cost())
+		})
+	}
+}
+
+// usageBodyReader counts the bytes read from the request body.
+type usageBodyReader struct {
+	io.ReadCloser
+	size uint64
+}
+
+func (r *usageBodyReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.size += uint64(n)
+	return n, err
+}
+
+// usageResponseWriter counts the bytes written to the response.
+type usageResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	hijacked    bool
+	size        uint64
+}
+
+// Hijack lets the handlers take over the connection.
+func (w *usageResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	conn, rw, err := hj.Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+func (w *usageResponseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *usageResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.size += uint64(n)
+	return n, err
+}
@@ -0,0 +1,157 @@
+package api_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/redesblock/mop/core/api"
+	mockauth "github.com/redesblock/mop/core/api/auth/mock"
+	"github.com/redesblock/mop/core/api/jsonhttp"
+	"github.com/redesblock/mop/core/api/jsonhttp/jsonhttptest"
+	mockpost "github.com/redesblock/mop/core/incentives/voucher/mock"
+	"github.com/redesblock/mop/core/log"
+	statestore "github.com/redesblock/mop/core/storer/statestore/mock"
+	"github.com/redesblock/mop/core/storer/storage/mock"
+	testingc "github.com/redesblock/mop/core/storer/storage/testing"
+	"github.com/redesblock/mop/core/tags"
+	"github.com/redesblock/mop/core/tenant"
+	"github.com/redesblock/mop/core/usage"
+)
+
+func TestUsage(t *testing.T) {
+	usageService := usage.New(statestore.NewStateStore(), log.Noop, time.Hour, 0)
+	t.Cleanup(func() { _ = usageService.Close() })
+
+	// the security tokens of the test are the tenant names
+	authenticator := &mockauth.Auth{
+		EnforceFunc: func(_, _, _ string) (bool, error) {
+			return true, nil
+		},
+		TenantFunc: func(key string) (string, bool, error) {
+			return key, false, nil
+		},
+	}
+	tenants := tenant.New(statestore.NewStateStore())
+	if err := tenants.Add(tenant.KindBatch, "a", batchOkStr); err != nil {
+		t.Fatal(err)
+	}
+
+	wsHeaders := http.Header{}
+	wsHeaders.Set("Authorization", "Bearer a")
+	wsHeaders.Set(api.ClusterDeferredUploadHeader, "true")
+	wsHeaders.Set(api.ClusterVoucherBatchIdHeader, batchOkStr)
+
+	var (
+		client, wsConn, _, _ = newTestServer(t, testServerOptions{
+			Storer:        mock.NewStorer(),
+			Tags:          tags.NewTags(statestore.NewStateStore(), log.Noop),
+			Logger:        log.Noop,
+			Post:          mockpost.New(mockpost.WithAcceptAll()),
+			Usage:         usageService,
+			Restricted:    true,
+			Authenticator: authenticator,
+			Tenants:       tenants,
+			WsPath:        "/chunks/stream",
+			WsHeaders:     wsHeaders,
+		})
+		debugClient, _, _, _ = newTestServer(t, testServerOptions{
+			DebugAPI: true,
+			Usage:    usageService,
+		})
+		content = []byte("usage accounting")
+	)
+
+	var res api.BytesPostResponse
+	jsonhttptest.Request(t, client, http.MethodPost, "/bytes", http.StatusCreated,
+		jsonhttptest.WithRequestHeader("Authorization", "Bearer a"),
+		jsonhttptest.WithRequestHeader(api.ClusterDeferredUploadHeader, "true"),
+		jsonhttptest.WithRequestHeader(api.ClusterVoucherBatchIdHeader, batchOkStr),
+		jsonhttptest.WithRequestBody(bytes.NewReader(content)),
+		jsonhttptest.WithUnmarshalJSONResponse(&res),
+	)
+	reference := res.Reference.String()
+
+	for i := 0; i < 2; i++ {
+		jsonhttptest.Request(t, client, http.MethodGet, "/bytes/"+reference, http.StatusOK,
+			jsonhttptest.WithRequestHeader("Authorization", "Bearer a"),
+			jsonhttptest.WithExpectedResponse(content),
+		)
+	}
+	// failed requests are not recorded
+	jsonhttptest.Request(t, client, http.MethodGet, "/bytes/0xabcd", http.StatusNotFound)
+
+	now := time.Now().UTC().Truncate(usage.Period).Unix()
+	record := api.UsageRecordResponse{
+		Time:      now,
+		Reference: reference,
+		Tenant:    "a",
+		Requests:  3,
+		Served:    uint64(2 * len(content)),
+		Stored:    uint64(len(content)),
+	}
+
+	t.Run("json", func(t *testing.T) {
+		jsonhttptest.Request(t, debugClient, http.MethodGet, "/usage?tenant=a", http.StatusOK,
+			jsonhttptest.WithExpectedJSONResponse(api.UsageResponse{
+				Records: []api.UsageRecordResponse{record},
+			}),
+		)
+	})
+
+	t.Run("time range", func(t *testing.T) {
+		jsonhttptest.Request(t, debugClient, http.MethodGet, fmt.Sprintf("/usage?from=%d", now+1), http.StatusOK,
+			jsonhttptest.WithExpectedJSONResponse(api.UsageResponse{
+				Records: []api.UsageRecordResponse{},
+			}),
+		)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		want := fmt.Sprintf("time,reference,tenant,requests,served,stored,cost\n%s,%s,%s,3,%d,%d,0\n",
+			time.Unix(now, 0).UTC().Format(time.RFC3339), reference, record.Tenant, record.Served, record.Stored)
+		header := jsonhttptest.Request(t, debugClient, http.MethodGet, "/usage?format=csv&reference="+reference, http.StatusOK,
+			jsonhttptest.WithExpectedResponse([]byte(want)),
+		)
+		if got := header.Get("Content-Type"); got != "text/csv" {
+			t.Fatalf("got content type %q, want %q", got, "text/csv")
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		ch := testingc.GenerateTestRandomChunk()
+		if err := wsConn.WriteMessage(websocket.BinaryMessage, ch.Data()); err != nil {
+			t.Fatal(err)
+		}
+		if err := wsConn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := wsConn.ReadMessage(); err != nil {
+			t.Fatal(err)
+		}
+
+		jsonhttptest.Request(t, debugClient, http.MethodGet, "/usage?reference="+ch.Address().String(), http.StatusOK,
+			jsonhttptest.WithExpectedJSONResponse(api.UsageResponse{
+				Records: []api.UsageRecordResponse{{
+					Time:      time.Now().UTC().Truncate(usage.Period).Unix(),
+					Reference: ch.Address().String(),
+					Tenant:    "a",
+					Requests:  1,
+					Stored:    uint64(len(ch.Data())),
+				}},
+			}),
+		)
+	})
+
+	t.Run("bad from", func(t *testing.T) {
+		jsonhttptest.Request(t, debugClient, http.MethodGet, "/usage?from=yesterday", http.StatusBadRequest,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "bad from",
+				Code:    http.StatusBadRequest,
+			}),
+		)
+	})
+}
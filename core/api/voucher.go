@@ -17,6 +17,7 @@ import (
 	"github.com/redesblock/mop/core/incentives/voucher/vouchercontract"
 	"github.com/redesblock/mop/core/mctx"
 	"github.com/redesblock/mop/core/storer/storage"
+	"github.com/redesblock/mop/core/tenant"
 	"github.com/redesblock/mop/core/tracer"
 	"github.com/redesblock/mop/core/util/bigint"
 )
@@ -130,6 +131,13 @@ func (s *Service) voucherCreateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := s.addOwner(r.Context(), tenant.KindBatch, hex.EncodeToString(batchID)); err != nil {
+		s.logger.Debug("create batch: add batch owner failed", "batch_id", fmt.Sprintf("%x", batchID), "error", err)
+		s.logger.Error(nil, "create batch: add batch owner failed")
+		jsonhttp.InternalServerError(w, "cannot create batch")
+		return
+	}
+
 	jsonhttp.Created(w, &voucherCreateResponse{
 		BatchID: batchID,
 	})
@@ -183,6 +191,17 @@ func (s *Service) voucherGetStampsHandler(w http.ResponseWriter, r *http.Request
 	resp := voucherStampsResponse{}
 	resp.Stamps = make([]voucherStampResponse, 0, len(s.post.StampIssuers()))
 	for _, v := range s.post.StampIssuers() {
+		owns, err := s.owns(r.Context(), tenant.KindBatch, hex.EncodeToString(v.ID()))
+		if err != nil {
+			s.logger.Debug("get stamp issuer: check batch owner failed", "batch_id", fmt.Sprintf("%x", v.ID()), "error", err)
+			s.logger.Error(nil, "get stamp issuer: check batch owner failed")
+			jsonhttp.InternalServerError(w, "unable to check batch")
+			return
+		}
+		if !owns {
+			continue
+		}
+
 		exists, err := s.batchStore.Exists(v.ID())
 		if err != nil {
 			s.logger.Debug("get stamp issuer: check batch failed", "batch_id", fmt.Sprintf("%x", v.ID()), "error", err)
@@ -271,7 +290,7 @@ func (s *Service) voucherGetStampBucketsHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	issuer, err := s.post.GetStampIssuer(id)
+	issuer, err := s.stampIssuer(r.Context(), id)
 	if err != nil {
 		s.logger.Debug("get stamp issuer: get issuer failed", "batch_id", fmt.Sprintf("%x", id), "error", err)
 		s.logger.Error(nil, "get stamp issuer: get issuer failed")
@@ -309,7 +328,7 @@ func (s *Service) voucherGetStampHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	issuer, err := s.post.GetStampIssuer(id)
+	issuer, err := s.stampIssuer(r.Context(), id)
 	if err != nil {
 		s.logger.Debug("get stamp issuer: get issuer failed", "batch_id", fmt.Sprintf("%x", id), "error", err)
 		s.logger.Error(nil, "get stamp issuer: get issuer failed")
@@ -456,6 +475,20 @@ func (s *Service) voucherTopUpHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	owns, err := s.owns(r.Context(), tenant.KindBatch, hex.EncodeToString(id))
+	if err != nil {
+		s.logger.Debug("topup batch: check batch owner failed", "batch_id", fmt.Sprintf("%x", id), "error", err)
+		s.logger.Error(nil, "topup batch: check batch owner failed")
+		jsonhttp.InternalServerError(w, "unable to check batch")
+		return
+	}
+	if !owns {
+		s.logger.Debug("topup batch: batch not found", "batch_id", fmt.Sprintf("%x", id))
+		s.logger.Error(nil, "topup batch: batch not found")
+		jsonhttp.NotFound(w, "batch not found")
+		return
+	}
+
 	amountStr := mux.Vars(r)["amount"]
 	amount, ok := big.NewInt(0).SetString(amountStr, 10)
 	if !ok {
@@ -509,6 +542,20 @@ func (s *Service) voucherDiluteHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	owns, err := s.owns(r.Context(), tenant.KindBatch, hex.EncodeToString(id))
+	if err != nil {
+		s.logger.Debug("dilute batch: check batch owner failed", "batch_id", fmt.Sprintf("%x", id), "error", err)
+		s.logger.Error(nil, "dilute batch: check batch owner failed")
+		jsonhttp.InternalServerError(w, "unable to check batch")
+		return
+	}
+	if !owns {
+		s.logger.Debug("dilute batch: batch not found", "batch_id", fmt.Sprintf("%x", id))
+		s.logger.Error(nil, "dilute batch: batch not found")
+		jsonhttp.NotFound(w, "batch not found")
+		return
+	}
+
 	depthStr := mux.Vars(r)["depth"]
 	depth, err := strconv.ParseUint(depthStr, 10, 8)
 	if err != nil {
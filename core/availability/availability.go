@@ -0,0 +1,368 @@
+// Package availability provides the probe of content availability, which
+// reports for every chunk of a reference whether it is retrievable from the
+// network and how many peers of its neighbourhood store it.
+package availability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/p2p/topology"
+	"github.com/redesblock/mop/core/protocol/has"
+	"github.com/redesblock/mop/core/protocol/retrieval"
+	"github.com/redesblock/mop/core/storer/storage"
+	"github.com/redesblock/mop/core/traverser"
+	"golang.org/x/sync/errgroup"
+)
+
+// loggerName is the tree path name of the logger for this package.
+const loggerName = "availability"
+
+const (
+	// DefaultPeers is the default number of the closest peers asked for each chunk.
+	DefaultPeers = 4
+	// DefaultReplicas is the default number of replicas for a chunk to be considered durable.
+	DefaultReplicas = 2
+	// DefaultMaxChunks is the default maximal number of chunks probed for a content.
+	DefaultMaxChunks = 10000
+
+	parallelProbes = 8 // how many chunks are retrieved in parallel
+)
+
+// ErrTooManyChunks is returned when the content has more chunks than are probed.
+var ErrTooManyChunks = errors.New("too many chunks")
+
+// Interface is the probe of content availability.
+type Interface interface {
+	// Probe checks the availability of all chunks of the content on the
+	// given root address and reports their status.
+	Probe(ctx context.Context, root cluster.Address) (*Report, error)
+}
+
+// ChunkStatus is the availability status of a single chunk.
+type ChunkStatus struct {
+	Address cluster.Address
+	// Retrievable tells whether the chunk was retrieved from the network.
+	Retrievable bool
+	// Latency is the time it took to retrieve the chunk.
+	Latency time.Duration
+	// Neighbourhood is the proximity order of the chunk and the closest
+	// peer which responded to the has request.
+	Neighbourhood uint8
+	// Responded is the number of asked peers that responded.
+	Responded int
+	// Replicas is the number of responding peers that store the chunk.
+	Replicas int
+	// Error holds the reason why the chunk was not retrieved.
+	Error string
+}
+
+// Report is the availability report of the content.
+type Report struct {
+	Root   cluster.Address
+	Chunks []ChunkStatus
+	// Retrievable is the number of retrievable chunks.
+	Retrievable int
+	// Complete tells whether all the chunks of the content were found, which
+	// is not the case when an intermediate chunk is not retrievable.
+	Complete bool
+	// Health is the score between 0 and 1, where each chunk contributes
+	// equally with half of its score for being retrievable and half for the
+	// ratio of its replicas to the expected number of replicas.
+	Health float64
+}
+
+// Options configure the probe.
+type Options struct {
+	// Peers is the number of the closest peers asked for each chunk.
+	Peers int
+	// Replicas is the number of replicas for a chunk to be considered durable.
+	Replicas int
+	// MaxChunks is the maximal number of chunks probed for a content.
+	MaxChunks int
+}
+
+// Topology is the topology needed to find the peers closest to the chunks
+// and the neighbourhood responsible for storing them.
+type Topology interface {
+	topology.EachPeerer
+	topology.NeighborhoodDepther
+}
+
+type service struct {
+	retrieval retrieval.Interface
+	has       has.Interface
+	topology  Topology
+	logger    log.Logger
+	peers     int
+	replicas  int
+	maxChunks int
+}
+
+// New returns a new probe which retrieves the chunks with the retrieval
+// protocol and asks the connected peers closest to them with the has protocol.
+func New(r retrieval.Interface, h has.Interface, t Topology, logger log.Logger, o Options) Interface {
+	if o.Peers <= 0 {
+		o.Peers = DefaultPeers
+	}
+	if o.Replicas <= 0 {
+		o.Replicas = DefaultReplicas
+	}
+	if o.MaxChunks <= 0 {
+		o.MaxChunks = DefaultMaxChunks
+	}
+	return &service{
+		retrieval: r,
+		has:       h,
+		topology:  t,
+		logger:    logger.WithName(loggerName).Register(),
+		peers:     o.Peers,
+		replicas:  o.Replicas,
+		maxChunks: o.MaxChunks,
+	}
+}
+
+// Probe implements Interface.Probe method.
+func (s *service) Probe(ctx context.Context, root cluster.Address) (*Report, error) {
+	rg := &recordingGetter{
+		retrieval: s.retrieval,
+		statuses:  make(map[string]*ChunkStatus),
+	}
+
+	var addrs []cluster.Address
+	seen := make(map[string]struct{})
+	iterFn := func(addr cluster.Address) error {
+		if _, ok := seen[addr.ByteString()]; !ok {
+			if len(addrs) == s.maxChunks {
+				return ErrTooManyChunks
+			}
+			seen[addr.ByteString()] = struct{}{}
+			addrs = append(addrs, addr)
+		}
+		return nil
+	}
+
+	report := &Report{Root: root, Complete: true}
+	if err := traverser.New(rg).Traverse(ctx, root, iterFn); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if errors.Is(err, ErrTooManyChunks) {
+			return nil, ErrTooManyChunks
+		}
+		s.logger.Debug("probe traversal incomplete", "root_address", root, "error", err)
+		report.Complete = false
+	}
+	// the chunks which failed the traversal are reported as well
+	for _, st := range rg.failed() {
+		if _, ok := seen[st.Address.ByteString()]; !ok {
+			seen[st.Address.ByteString()] = struct{}{}
+			addrs = append(addrs, st.Address)
+		}
+	}
+	if len(addrs) > s.maxChunks {
+		return nil, ErrTooManyChunks
+	}
+
+	statuses := make([]ChunkStatus, len(addrs))
+	sem := make(chan struct{}, parallelProbes)
+	var wg sync.WaitGroup
+	for i, addr := range addrs {
+		if st, ok := rg.status(addr); ok {
+			statuses[i] = *st
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return nil, ctx.Err()
+		}
+		wg.Add(1)
+		go func(i int, addr cluster.Address) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			_, st, _ := rg.retrieve(ctx, addr)
+			statuses[i] = *st
+		}(i, addr)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	depth := s.topology.NeighborhoodDepth()
+	if err := CountReplicas(ctx, s.has, s.topology, s.logger, s.peers, depth, statuses); err != nil {
+		return nil, err
+	}
+
+	report.Chunks = statuses
+	var score float64
+	for _, st := range statuses {
+		if !st.Retrievable {
+			continue
+		}
+		report.Retrievable++
+		replicas := st.Replicas
+		if replicas > s.replicas {
+			replicas = s.replicas
+		}
+		score += 0.5 + 0.5*float64(replicas)/float64(s.replicas)
+	}
+	if len(statuses) > 0 {
+		report.Health = score / float64(len(statuses))
+	}
+	return report, nil
+}
+
+// CountReplicas asks at most n connected peers closest to each chunk whether
+// they store it and records their responses in the statuses, batching the
+// addresses per peer. Only the peers within the given depth of a chunk, which
+// are responsible for storing it, are counted as its replicas.
+func CountReplicas(ctx context.Context, h has.Interface, t topology.EachPeerer, logger log.Logger, n int, depth uint8, statuses []ChunkStatus) error {
+	var peers []cluster.Address
+	err := t.EachPeer(func(addr cluster.Address, _ uint8) (bool, bool, error) {
+		peers = append(peers, addr)
+		return false, false, nil
+	}, topology.Filter{})
+	if err != nil {
+		return fmt.Errorf("iterate peers: %w", err)
+	}
+	if len(peers) == 0 {
+		return nil
+	}
+
+	// indexes of the statuses for which the peer is one of the closest
+	requests := make(map[string][]int)
+	for i := range statuses {
+		for _, p := range closestPeers(statuses[i].Address, peers, n) {
+			requests[p.ByteString()] = append(requests[p.ByteString()], i)
+		}
+	}
+
+	var mu sync.Mutex
+	eg, ectx := errgroup.WithContext(ctx)
+	for peer, indexes := range requests {
+		peer := cluster.NewAddress([]byte(peer))
+		for len(indexes) > 0 {
+			n := len(indexes)
+			if n > has.MaxAddresses {
+				n = has.MaxAddresses
+			}
+			batch := indexes[:n]
+			indexes = indexes[n:]
+
+			eg.Go(func() error {
+				addrs := make([]cluster.Address, len(batch))
+				for j, i := range batch {
+					addrs[j] = statuses[i].Address
+				}
+				res, err := h.Has(ectx, peer, addrs...)
+				if err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					logger.Debug("has request failed", "peer_address", peer, "error", err)
+					return nil
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				for j, i := range batch {
+					st := &statuses[i]
+					po := cluster.Proximity(st.Address.Bytes(), peer.Bytes())
+					if st.Responded == 0 || po > st.Neighbourhood {
+						st.Neighbourhood = po
+					}
+					st.Responded++
+					if res[j] && po >= depth {
+						st.Replicas++
+					}
+				}
+				return nil
+			})
+		}
+	}
+	return eg.Wait()
+}
+
+// closestPeers returns at most n peers closest to the address.
+func closestPeers(addr cluster.Address, peers []cluster.Address, n int) []cluster.Address {
+	sorted := make([]cluster.Address, len(peers))
+	copy(sorted, peers)
+	sort.Slice(sorted, func(i, j int) bool {
+		closer, _ := sorted[i].Closer(addr, sorted[j])
+		return closer
+	})
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// recordingGetter retrieves the chunks only from the network and records
+// their status, so that the chunks fetched by the traversal are not
+// retrieved again.
+type recordingGetter struct {
+	retrieval retrieval.Interface
+	mu        sync.Mutex
+	statuses  map[string]*ChunkStatus
+}
+
+// retrieve retrieves the chunk from the network and records its status.
+func (g *recordingGetter) retrieve(ctx context.Context, addr cluster.Address) (cluster.Chunk, *ChunkStatus, error) {
+	start := time.Now()
+	ch, err := g.retrieval.RetrieveChunk(ctx, addr, cluster.ZeroAddress)
+	st := &ChunkStatus{
+		Address:     addr,
+		Retrievable: err == nil,
+		Latency:     time.Since(start),
+	}
+	if err != nil {
+		st.Error = err.Error()
+	}
+
+	g.mu.Lock()
+	g.statuses[addr.ByteString()] = st
+	g.mu.Unlock()
+	return ch, st, err
+}
+
+// status returns the recorded status of the chunk.
+func (g *recordingGetter) status(addr cluster.Address) (*ChunkStatus, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	st, ok := g.statuses[addr.ByteString()]
+	return st, ok
+}
+
+// failed returns the recorded statuses of the chunks which were not retrieved.
+func (g *recordingGetter) failed() (failed []*ChunkStatus) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, st := range g.statuses {
+		if !st.Retrievable {
+			failed = append(failed, st)
+		}
+	}
+	return failed
+}
+
+// Get implements the storage Getter.Get interface.
+func (g *recordingGetter) Get(ctx context.Context, _ storage.ModeGet, addr cluster.Address) (cluster.Chunk, error) {
+	ch, _, err := g.retrieve(ctx, addr)
+	return ch, err
+}
+
+// Put implements the storage Putter.Put interface.
+func (g *recordingGetter) Put(_ context.Context, _ storage.ModePut, _ ...cluster.Chunk) ([]bool, error) {
+	return nil, errors.New("operation is not supported")
+}
@@ -0,0 +1,147 @@
+package availability_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/redesblock/mop/core/availability"
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/cluster/test"
+	"github.com/redesblock/mop/core/file/pipeline/builder"
+	"github.com/redesblock/mop/core/log"
+	topmock "github.com/redesblock/mop/core/p2p/topology/mock"
+	hasmock "github.com/redesblock/mop/core/protocol/has/mock"
+	"github.com/redesblock/mop/core/storer/storage"
+	"github.com/redesblock/mop/core/storer/storage/mock"
+)
+
+func TestProbe(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		chunks    = 20
+		data      = make([]byte, chunks*4096)
+		store     = &loggingStore{Storer: mock.NewStorer(), missing: make(map[string]bool)}
+		holder    = test.RandomAddress()
+		other     = test.RandomAddress()
+		hasCalls  int32
+		hasServer = hasmock.New(func(_ context.Context, peer cluster.Address, addrs ...cluster.Address) ([]bool, error) {
+			atomic.AddInt32(&hasCalls, 1)
+			res := make([]bool, len(addrs))
+			for i, a := range addrs {
+				res[i] = peer.Equal(holder) && !store.missing[a.String()]
+			}
+			return res, nil
+		})
+		p = availability.New(store, hasServer, topmock.NewTopologyDriver(topmock.WithPeers(holder, other)), log.Noop, availability.Options{Replicas: 2})
+	)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+
+	pipe := builder.NewPipelineBuilder(ctx, store, storage.ModePutUpload, false)
+	addr, err := builder.FeedPipeline(ctx, pipe, bytes.NewReader(data), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := p.Probe(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Complete {
+		t.Fatal("expected complete report")
+	}
+	if got, want := len(report.Chunks), len(store.addrs); got != want {
+		t.Fatalf("got %d chunks, want %d", got, want)
+	}
+	if report.Retrievable != len(report.Chunks) {
+		t.Fatalf("got %d retrievable chunks, want %d", report.Retrievable, len(report.Chunks))
+	}
+	if c := atomic.LoadInt32(&hasCalls); c != 2 {
+		t.Fatalf("got %d has calls, want %d", c, 2)
+	}
+	for _, st := range report.Chunks {
+		if st.Replicas != 1 || st.Responded != 2 {
+			t.Fatalf("chunk %s: got %d replicas of %d responses, want %d of %d", st.Address, st.Replicas, st.Responded, 1, 2)
+		}
+		want := cluster.Proximity(st.Address.Bytes(), holder.Bytes())
+		if po := cluster.Proximity(st.Address.Bytes(), other.Bytes()); po > want {
+			want = po
+		}
+		if st.Neighbourhood != want {
+			t.Fatalf("chunk %s: got neighbourhood %d, want %d", st.Address, st.Neighbourhood, want)
+		}
+	}
+	if report.Health != 0.75 {
+		t.Fatalf("got health %v, want %v", report.Health, 0.75)
+	}
+
+	// the first stored chunk is a data chunk
+	missing := store.addrs[0]
+	store.missing[missing.String()] = true
+
+	report, err = p.Probe(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Retrievable != len(report.Chunks)-1 {
+		t.Fatalf("got %d retrievable chunks, want %d", report.Retrievable, len(report.Chunks)-1)
+	}
+	for _, st := range report.Chunks {
+		if !st.Address.Equal(missing) {
+			continue
+		}
+		if st.Retrievable || st.Replicas != 0 || st.Error == "" {
+			t.Fatalf("got status %+v of a missing chunk", st)
+		}
+	}
+	if want := 0.75 * float64(len(report.Chunks)-1) / float64(len(report.Chunks)); report.Health != want {
+		t.Fatalf("got health %v, want %v", report.Health, want)
+	}
+
+	delete(store.missing, missing.String())
+
+	// the holder is outside of the neighbourhood of the chunks at the maximal depth
+	deep := availability.New(store, hasServer, topmock.NewTopologyDriver(topmock.WithPeers(holder, other), topmock.WithNeighborhoodDepth(cluster.MaxPO)), log.Noop, availability.Options{Replicas: 2})
+	report, err = deep.Probe(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range report.Chunks {
+		if st.Replicas != 0 || st.Responded != 2 {
+			t.Fatalf("chunk %s: got %d replicas of %d responses, want %d of %d", st.Address, st.Replicas, st.Responded, 0, 2)
+		}
+	}
+	if report.Health != 0.5 {
+		t.Fatalf("got health %v, want %v", report.Health, 0.5)
+	}
+
+	limited := availability.New(store, hasServer, topmock.NewTopologyDriver(topmock.WithPeers(holder, other)), log.Noop, availability.Options{MaxChunks: len(store.addrs) - 1})
+	if _, err := limited.Probe(ctx, addr); !errors.Is(err, availability.ErrTooManyChunks) {
+		t.Fatalf("got error %v, want %v", err, availability.ErrTooManyChunks)
+	}
+}
+
+type loggingStore struct {
+	storage.Storer
+	addrs   []cluster.Address
+	missing map[string]bool
+}
+
+func (ls *loggingStore) Put(ctx context.Context, mode storage.ModePut, chs ...cluster.Chunk) (exist []bool, err error) {
+	for _, c := range chs {
+		ls.addrs = append(ls.addrs, c.Address())
+	}
+	return ls.Storer.Put(ctx, mode, chs...)
+}
+
+func (ls *loggingStore) RetrieveChunk(ctx context.Context, addr, sourceAddr cluster.Address) (chunk cluster.Chunk, err error) {
+	if ls.missing[addr.String()] {
+		return nil, storage.ErrNotFound
+	}
+	return ls.Get(ctx, storage.ModeGetRequest, addr)
+}
@@ -0,0 +1,23 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/redesblock/mop/core/availability"
+	"github.com/redesblock/mop/core/cluster"
+)
+
+// Probe represents availability.Interface mock.
+type Probe struct {
+	probeFunc func(context.Context, cluster.Address) (*availability.Report, error)
+}
+
+// New returns a new availability.Interface mock with the given probe function.
+func New(probeFunc func(context.Context, cluster.Address) (*availability.Report, error)) *Probe {
+	return &Probe{probeFunc: probeFunc}
+}
+
+// Probe implements availability.Interface Probe method.
+func (p *Probe) Probe(ctx context.Context, root cluster.Address) (*availability.Report, error) {
+	return p.probeFunc(ctx, root)
+}
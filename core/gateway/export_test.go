@@ -0,0 +1,6 @@
+package gateway
+
+// Balance returns the current debt of the client.
+func (s *Service) Balance(client string) (uint64, error) {
+	return s.ledger.balance(client)
+}
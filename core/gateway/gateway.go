@@ -0,0 +1,474 @@
+// Package gateway provides the browser light-client gateway. It exposes a
+// restricted subset of the protocols, the chunk retrieval, the chunk push
+// and the psser subscriptions, to browser clients over WebSocket, so that
+// they can access the network without relying on the HTTP API of a single
+// node. The clients are charged the price of the chunks within an allowance
+// which is refreshed over time, and the pushed chunks must carry a valid
+// postage stamp.
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/redesblock/mop/core/chunk/cac"
+	"github.com/redesblock/mop/core/chunk/soc"
+	"github.com/redesblock/mop/core/chunk/trojan"
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/gateway/pb"
+	"github.com/redesblock/mop/core/incentives/voucher"
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/p2p/topology"
+	"github.com/redesblock/mop/core/pricer"
+	"github.com/redesblock/mop/core/protocol/pushsync"
+	"github.com/redesblock/mop/core/protocol/retrieval"
+	"github.com/redesblock/mop/core/psser"
+	"github.com/redesblock/mop/core/storer/storage"
+)
+
+// loggerName is the tree path name of the logger for this package.
+const loggerName = "gateway"
+
+const (
+	// DefaultMaxConcurrentRequests is the default number of the requests of
+	// a single connection which are served at the same time.
+	DefaultMaxConcurrentRequests = 16
+	// DefaultPingPeriod is the default time between two pings of the client.
+	DefaultPingPeriod = time.Minute
+	// DefaultMaxSubscriptions is the default number of the psser
+	// subscriptions of a single connection.
+	DefaultMaxSubscriptions = 16
+	// DefaultRefreshRate is the default amount of the debt of a client
+	// forgiven per second, the refresh rate of the light nodes.
+	DefaultRefreshRate = 450000
+
+	requestTimeout = time.Minute
+	// maxRequestSize is the size of a push request with a chunk and a stamp,
+	// with room for the encoding of the request
+	maxRequestSize = cluster.ChunkWithSpanSize + voucher.StampSize + 1024
+	writeDeadline  = 4 * time.Second // should be smaller than the shutdown timeout of the node
+)
+
+var (
+	errMissingStamp     = errors.New("missing postage stamp")
+	errInvalidTopic     = errors.New("invalid topic")
+	errUnknownOperation = errors.New("unknown operation")
+	errSubscriptions    = errors.New("too many subscriptions")
+)
+
+// Registerer registers the handlers of the psser messages.
+type Registerer interface {
+	Register(topic trojan.Topic, handler psser.Handler) (cleanup func())
+}
+
+// Options configure the gateway. The allowance and the refresh rate apply
+// to each client, identified by the host of its network address: a client
+// keeps its debt across its connections, which are served from different
+// ports, and the clients behind the same NAT or proxy share an allowance.
+// The debts are persisted in the state store, so that restarting the node
+// does not reset them either.
+type Options struct {
+	// AllowedOrigins are the origins of the browser clients which are
+	// allowed to connect, all origins are allowed if it is empty or if it
+	// contains the "*" wildcard.
+	AllowedOrigins []string
+	// MaxConcurrentRequests is the number of the requests of a single
+	// connection which are served at the same time.
+	MaxConcurrentRequests int
+	// PingPeriod is the time between two pings of the client.
+	PingPeriod time.Duration
+	// MaxSubscriptions is the number of the psser subscriptions of a single
+	// connection.
+	MaxSubscriptions int
+	// RefreshRate is the amount of the debt of a client forgiven per second.
+	RefreshRate uint64
+	// Allowance is the maximal debt of a client, ten seconds of the refresh
+	// rate if zero.
+	Allowance uint64
+}
+
+// Service is the browser light-client gateway.
+type Service struct {
+	retrieval      retrieval.Interface
+	pushSyncer     pushsync.PushSyncer
+	pss            Registerer
+	ledger         *ledger
+	pricer         pricer.Interface
+	validStamp     voucher.ValidStampFn
+	storer         storage.Putter
+	logger         log.Logger
+	metrics        metrics
+	allowedOrigins []string
+	maxConcurrent  int
+	maxSubs        int
+	pingPeriod     time.Duration
+	wg             sync.WaitGroup
+	closeMu        sync.Mutex
+	quit           chan struct{}
+}
+
+// New constructs a new gateway. The chunks pushed by the clients for which
+// the node is the closest one are stored with the storer, the debts of the
+// clients are persisted in the state store.
+func New(r retrieval.Interface, p pushsync.PushSyncer, pss Registerer, pr pricer.Interface, validStamp voucher.ValidStampFn, storer storage.Putter, stateStore storage.StateStorer, logger log.Logger, o Options) *Service {
+	if o.MaxConcurrentRequests <= 0 {
+		o.MaxConcurrentRequests = DefaultMaxConcurrentRequests
+	}
+	if o.PingPeriod <= 0 {
+		o.PingPeriod = DefaultPingPeriod
+	}
+	if o.MaxSubscriptions <= 0 {
+		o.MaxSubscriptions = DefaultMaxSubscriptions
+	}
+	if o.RefreshRate == 0 {
+		o.RefreshRate = DefaultRefreshRate
+	}
+	if o.Allowance == 0 {
+		o.Allowance = 10 * o.RefreshRate
+	}
+
+	return &Service{
+		retrieval:      r,
+		pushSyncer:     p,
+		pss:            pss,
+		ledger:         newLedger(stateStore, o.Allowance, o.RefreshRate),
+		pricer:         pr,
+		validStamp:     validStamp,
+		storer:         storer,
+		logger:         logger.WithName(loggerName).Register(),
+		metrics:        newMetrics(),
+		allowedOrigins: o.AllowedOrigins,
+		maxConcurrent:  o.MaxConcurrentRequests,
+		maxSubs:        o.MaxSubscriptions,
+		pingPeriod:     o.PingPeriod,
+		quit:           make(chan struct{}),
+	}
+}
+
+func (s *Service) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || len(s.allowedOrigins) == 0 {
+		return true
+	}
+	for _, v := range s.allowedOrigins {
+		if v == "*" || strings.EqualFold(origin, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeHTTP upgrades the request to a WebSocket connection and serves the
+// requests of the client until the connection is closed.
+func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  cluster.ChunkWithSpanSize,
+		WriteBufferSize: cluster.ChunkWithSpanSize,
+		CheckOrigin:     s.checkOrigin,
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.logger.Debug("gateway: upgrade failed", "error", err)
+		s.logger.Error(nil, "gateway: upgrade failed")
+		return
+	}
+
+	// the clients are charged by their host, the port changes with every
+	// connection.
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	// the connection is not added once the gateway is closing, so that
+	// Close does not wait concurrently with it being added
+	s.closeMu.Lock()
+	defer s.closeMu.Unlock()
+	select {
+	case <-s.quit:
+		_ = conn.Close()
+		return
+	default:
+	}
+	s.wg.Add(1)
+	go s.serve(conn, host)
+}
+
+// client is a connection of a browser client. The id identifies the client
+// in the ledger across its connections.
+type client struct {
+	conn    *websocket.Conn
+	id      string
+	writeMu sync.Mutex
+}
+
+func (c *client) write(msg *pb.Response) error {
+	b, err := msg.Marshal()
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(writeDeadline)); err != nil {
+		return err
+	}
+	return c.conn.WriteMessage(websocket.BinaryMessage, b)
+}
+
+func (c *client) writeControl(messageType int) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	return c.conn.WriteControl(messageType, nil, time.Now().Add(writeDeadline))
+}
+
+func (s *Service) serve(conn *websocket.Conn, id string) {
+	defer s.wg.Done()
+
+	c := &client{conn: conn, id: id}
+	conn.SetReadLimit(maxRequestSize)
+
+	if err := s.ledger.prune(); err != nil {
+		s.logger.Debug("gateway: prune debts failed", "error", err)
+	}
+	s.metrics.Connections.Inc()
+	s.metrics.OpenConnections.Inc()
+
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		requests    sync.WaitGroup
+		sem         = make(chan struct{}, s.maxConcurrent)
+		cleanups    []func()
+	)
+	defer func() {
+		cancel()
+		requests.Wait()
+		for _, cleanup := range cleanups {
+			cleanup()
+		}
+		_ = conn.Close()
+		s.metrics.OpenConnections.Dec()
+	}()
+
+	go s.keepalive(ctx, c)
+
+	for {
+		_, b, err := conn.ReadMessage()
+		if err != nil {
+			s.logger.Debug("gateway: client gone", "error", err)
+			return
+		}
+
+		req := new(pb.Request)
+		if err := req.Unmarshal(b); err != nil {
+			s.logger.Debug("gateway: invalid request", "error", err)
+			return
+		}
+
+		if req.Op == pb.Op_SUBSCRIBE {
+			if len(cleanups) == s.maxSubs {
+				_ = c.write(&pb.Response{ID: req.ID, Err: errSubscriptions.Error()})
+				continue
+			}
+			cleanup, err := s.subscribe(c, req)
+			if err != nil {
+				_ = c.write(&pb.Response{ID: req.ID, Err: err.Error()})
+				continue
+			}
+			cleanups = append(cleanups, cleanup)
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+
+		requests.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				requests.Done()
+			}()
+
+			resp, err := s.handle(ctx, c, req)
+			if err != nil {
+				s.logger.Debug("gateway: request failed", "operation", req.Op, "error", err)
+				s.metrics.RequestErrors.WithLabelValues(req.Op.String()).Inc()
+				resp = &pb.Response{ID: req.ID, Err: err.Error()}
+			}
+			if err := c.write(resp); err != nil {
+				s.logger.Debug("gateway: write response failed", "error", err)
+			}
+		}()
+	}
+}
+
+// keepalive pings the client periodically and closes the connection when
+// the gateway is closed.
+func (s *Service) keepalive(ctx context.Context, c *client) {
+	ticker := time.NewTicker(s.pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.quit:
+			_ = c.writeControl(websocket.CloseMessage)
+			_ = c.conn.Close()
+			return
+		case <-ticker.C:
+			if err := c.writeControl(websocket.PingMessage); err != nil {
+				// error encountered while pinging client. client probably gone
+				_ = c.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+func (s *Service) handle(ctx context.Context, c *client, req *pb.Request) (*pb.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	s.metrics.Requests.WithLabelValues(req.Op.String()).Inc()
+
+	switch req.Op {
+	case pb.Op_RETRIEVE:
+		return s.retrieve(ctx, c, req)
+	case pb.Op_PUSH:
+		return s.push(ctx, c, req)
+	default:
+		return nil, errUnknownOperation
+	}
+}
+
+// debit charges the client with the price of the chunk once the function
+// succeeds.
+func (s *Service) debit(c *client, addr cluster.Address, f func() error) error {
+	price := s.pricer.Price(addr)
+	action, err := s.ledger.prepareDebit(c.id, price)
+	if err != nil {
+		s.metrics.Refused.Inc()
+		return err
+	}
+	defer action.Cleanup()
+
+	if err := f(); err != nil {
+		return err
+	}
+	if err := action.Apply(); err != nil {
+		return err
+	}
+	s.metrics.Debited.Add(float64(price))
+	return nil
+}
+
+func (s *Service) retrieve(ctx context.Context, c *client, req *pb.Request) (*pb.Response, error) {
+	addr := cluster.NewAddress(req.Address)
+
+	var ch cluster.Chunk
+	err := s.debit(c, addr, func() (err error) {
+		ch, err = s.retrieval.RetrieveChunk(ctx, addr, cluster.ZeroAddress)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("retrieve chunk %s: %w", addr, err)
+	}
+
+	resp := &pb.Response{ID: req.ID, Address: addr.Bytes(), Data: ch.Data()}
+	if ch.Stamp() != nil {
+		stamp, err := ch.Stamp().MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("marshal stamp: %w", err)
+		}
+		resp.Stamp = stamp
+	}
+	return resp, nil
+}
+
+func (s *Service) push(ctx context.Context, c *client, req *pb.Request) (*pb.Response, error) {
+	if len(req.Stamp) == 0 {
+		return nil, errMissingStamp
+	}
+
+	addr := cluster.NewAddress(req.Address)
+	ch := cluster.NewChunk(addr, req.Data)
+	if !cac.Valid(ch) && !soc.Valid(ch) {
+		return nil, cluster.ErrInvalidChunk
+	}
+
+	ch, err := s.validStamp(ch, req.Stamp)
+	if err != nil {
+		s.metrics.InvalidStamps.Inc()
+		return nil, fmt.Errorf("invalid stamp: %w", err)
+	}
+
+	resp := &pb.Response{ID: req.ID, Address: addr.Bytes()}
+	err = s.debit(c, addr, func() error {
+		receipt, err := s.pushSyncer.PushChunkToClosest(ctx, ch)
+		if errors.Is(err, topology.ErrWantSelf) {
+			// the node is the closest one to the chunk
+			_, err = s.storer.Put(ctx, storage.ModePutSync, ch)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		resp.Data = receipt.Signature
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("push chunk %s: %w", addr, err)
+	}
+	return resp, nil
+}
+
+// subscribe forwards the psser messages with the requested topic to the
+// client, until the returned cleanup function is called.
+func (s *Service) subscribe(c *client, req *pb.Request) (func(), error) {
+	if len(req.Topic) != len(trojan.Topic{}) {
+		return nil, errInvalidTopic
+	}
+	var topic trojan.Topic
+	copy(topic[:], req.Topic)
+
+	s.metrics.Requests.WithLabelValues(req.Op.String()).Inc()
+	return s.pss.Register(topic, func(_ context.Context, m []byte) {
+		if err := c.write(&pb.Response{ID: req.ID, Data: m}); err != nil {
+			s.logger.Debug("gateway: write message failed", "error", err)
+		}
+	}), nil
+}
+
+// Close closes the connections and waits for their requests to finish.
+func (s *Service) Close() error {
+	s.closeMu.Lock()
+	close(s.quit)
+	s.closeMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(5 * time.Second):
+		return errors.New("stopping gateway with ongoing connections")
+	}
+}
@@ -0,0 +1,317 @@
+package gateway_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/redesblock/mop/core/chunk/trojan"
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/gateway"
+	"github.com/redesblock/mop/core/gateway/pb"
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/pricer"
+	"github.com/redesblock/mop/core/protocol/pushsync"
+	pushsyncmock "github.com/redesblock/mop/core/protocol/pushsync/mock"
+	"github.com/redesblock/mop/core/psser"
+	statestore "github.com/redesblock/mop/core/storer/statestore/mock"
+	"github.com/redesblock/mop/core/storer/storage"
+	"github.com/redesblock/mop/core/storer/storage/mock"
+	testingc "github.com/redesblock/mop/core/storer/storage/testing"
+)
+
+const price = 10
+
+type retriever map[string]cluster.Chunk
+
+func (r retriever) RetrieveChunk(_ context.Context, addr, _ cluster.Address) (cluster.Chunk, error) {
+	ch, ok := r[addr.ByteString()]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return ch, nil
+}
+
+type registerer struct {
+	mu       sync.Mutex
+	handlers map[trojan.Topic]psser.Handler
+}
+
+func (r *registerer) Register(topic trojan.Topic, h psser.Handler) func() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[topic] = h
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.handlers, topic)
+	}
+}
+
+func (r *registerer) handler(topic trojan.Topic) psser.Handler {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.handlers[topic]
+}
+
+var validStamp = []byte("valid stamp")
+
+func validStampFn(ch cluster.Chunk, stamp []byte) (cluster.Chunk, error) {
+	if !bytes.Equal(stamp, validStamp) {
+		return nil, errors.New("invalid stamp")
+	}
+	return ch, nil
+}
+
+type testServer struct {
+	gateway *gateway.Service
+	pss     *registerer
+	url     string
+}
+
+func newTestServer(t *testing.T, r retriever, p pushsync.PushSyncer, o gateway.Options) *testServer {
+	t.Helper()
+
+	// the debts are not forgiven during the tests
+	if o.RefreshRate == 0 {
+		o.RefreshRate = 1
+	}
+	if o.Allowance == 0 {
+		o.Allowance = 1 << 20
+	}
+
+	pss := &registerer{handlers: make(map[trojan.Topic]psser.Handler)}
+	gw := gateway.New(r, p, pss, pricer.NewFixedPricer(cluster.ZeroAddress, price), validStampFn, mock.NewStorer(), statestore.NewStateStore(), log.Noop, o)
+	srv := httptest.NewServer(gw)
+	t.Cleanup(func() {
+		srv.Close()
+		if err := gw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	return &testServer{
+		gateway: gw,
+		pss:     pss,
+		url:     "ws" + strings.TrimPrefix(srv.URL, "http"),
+	}
+}
+
+func (s *testServer) dial(t *testing.T) *websocket.Conn {
+	t.Helper()
+
+	conn, _, err := websocket.DefaultDialer.Dial(s.url, http.Header{"Origin": {"https://app.example"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func request(t *testing.T, conn *websocket.Conn, req *pb.Request) *pb.Response {
+	t.Helper()
+
+	b, err := req.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteMessage(websocket.BinaryMessage, b); err != nil {
+		t.Fatal(err)
+	}
+	return response(t, conn)
+}
+
+func response(t *testing.T, conn *websocket.Conn) *pb.Response {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	_, b, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := new(pb.Response)
+	if err := resp.Unmarshal(b); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// debited returns the debt of the test client.
+func debited(t *testing.T, s *testServer) int64 {
+	t.Helper()
+
+	b, err := s.gateway.Balance("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return int64(b)
+}
+
+func TestRetrieve(t *testing.T) {
+	ch := testingc.GenerateTestRandomChunk()
+	s := newTestServer(t, retriever{ch.Address().ByteString(): ch}, pushsyncmock.New(nil), gateway.Options{})
+	conn := s.dial(t)
+
+	resp := request(t, conn, &pb.Request{ID: 1, Op: pb.Op_RETRIEVE, Address: ch.Address().Bytes()})
+	if resp.Err != "" {
+		t.Fatal(resp.Err)
+	}
+	if resp.ID != 1 {
+		t.Fatalf("got response id %d, want %d", resp.ID, 1)
+	}
+	if !bytes.Equal(resp.Data, ch.Data()) {
+		t.Fatal("retrieved chunk data mismatch")
+	}
+	stamp, err := ch.Stamp().MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(resp.Stamp, stamp) {
+		t.Fatal("retrieved chunk stamp mismatch")
+	}
+
+	resp = request(t, conn, &pb.Request{ID: 2, Op: pb.Op_RETRIEVE, Address: cluster.ZeroAddress.Bytes()})
+	if resp.Err == "" {
+		t.Fatal("expected error retrieving missing chunk")
+	}
+
+	if got, want := debited(t, s), int64(cluster.MaxPO-cluster.Proximity(cluster.ZeroAddress.Bytes(), ch.Address().Bytes())+1)*price; got != want {
+		t.Fatalf("got debited %d, want %d", got, want)
+	}
+}
+
+func TestPush(t *testing.T) {
+	var (
+		ch        = testingc.GenerateTestRandomChunk()
+		signature = []byte("receipt signature")
+		pushed    = make(chan cluster.Chunk, 1)
+		s         = newTestServer(t, nil, pushsyncmock.New(func(_ context.Context, ch cluster.Chunk) (*pushsync.Receipt, error) {
+			pushed <- ch
+			return &pushsync.Receipt{Address: ch.Address(), Signature: signature}, nil
+		}), gateway.Options{})
+		conn = s.dial(t)
+	)
+
+	for _, tc := range []struct {
+		name  string
+		data  []byte
+		stamp []byte
+	}{
+		{name: "missing stamp", data: ch.Data()},
+		{name: "invalid stamp", data: ch.Data(), stamp: []byte("invalid stamp")},
+		{name: "invalid chunk", data: ch.Data()[1:], stamp: validStamp},
+	} {
+		resp := request(t, conn, &pb.Request{ID: 1, Op: pb.Op_PUSH, Address: ch.Address().Bytes(), Data: tc.data, Stamp: tc.stamp})
+		if resp.Err == "" {
+			t.Fatalf("%s: expected error", tc.name)
+		}
+	}
+	if d := debited(t, s); d != 0 {
+		t.Fatalf("got debited %d for rejected chunks", d)
+	}
+
+	resp := request(t, conn, &pb.Request{ID: 2, Op: pb.Op_PUSH, Address: ch.Address().Bytes(), Data: ch.Data(), Stamp: validStamp})
+	if resp.Err != "" {
+		t.Fatal(resp.Err)
+	}
+	if !bytes.Equal(resp.Data, signature) {
+		t.Fatalf("got receipt signature %q, want %q", resp.Data, signature)
+	}
+	if got := <-pushed; !got.Address().Equal(ch.Address()) {
+		t.Fatalf("got pushed chunk %s, want %s", got.Address(), ch.Address())
+	}
+	if debited(t, s) == 0 {
+		t.Fatal("push not debited")
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	s := newTestServer(t, nil, pushsyncmock.New(nil), gateway.Options{MaxSubscriptions: 1})
+	conn := s.dial(t)
+
+	resp := request(t, conn, &pb.Request{ID: 1, Op: pb.Op_SUBSCRIBE, Topic: []byte("short")})
+	if resp.Err == "" {
+		t.Fatal("expected error subscribing to invalid topic")
+	}
+
+	topic := trojan.NewTopic("topic")
+	b, err := (&pb.Request{ID: 2, Op: pb.Op_SUBSCRIBE, Topic: topic[:]}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteMessage(websocket.BinaryMessage, b); err != nil {
+		t.Fatal(err)
+	}
+
+	var handler psser.Handler
+	for i := 0; handler == nil; i++ {
+		if i == 100 {
+			t.Fatal("subscription not registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+		handler = s.pss.handler(topic)
+	}
+	handler(context.Background(), []byte("message"))
+
+	resp = response(t, conn)
+	if resp.ID != 2 || string(resp.Data) != "message" {
+		t.Fatalf("got message %q with id %d, want %q with id %d", resp.Data, resp.ID, "message", 2)
+	}
+
+	other := trojan.NewTopic("other")
+	resp = request(t, conn, &pb.Request{ID: 3, Op: pb.Op_SUBSCRIBE, Topic: other[:]})
+	if resp.ID != 3 || resp.Err == "" {
+		t.Fatal("expected error subscribing over the limit")
+	}
+	if s.pss.handler(other) != nil {
+		t.Fatal("subscription over the limit registered")
+	}
+}
+
+func TestAllowance(t *testing.T) {
+	ch := testingc.GenerateTestRandomChunk()
+	s := newTestServer(t, retriever{ch.Address().ByteString(): ch}, pushsyncmock.New(nil), gateway.Options{
+		Allowance: 1,
+	})
+
+	resp := request(t, s.dial(t), &pb.Request{ID: 1, Op: pb.Op_RETRIEVE, Address: ch.Address().Bytes()})
+	if !strings.Contains(resp.Err, gateway.ErrAllowanceExceeded.Error()) {
+		t.Fatalf("got error %q, want %q", resp.Err, gateway.ErrAllowanceExceeded)
+	}
+	if len(resp.Data) != 0 {
+		t.Fatal("chunk delivered over the allowance")
+	}
+
+	// the allowance is kept across the connections of the client
+	resp = request(t, s.dial(t), &pb.Request{ID: 2, Op: pb.Op_RETRIEVE, Address: ch.Address().Bytes()})
+	if !strings.Contains(resp.Err, gateway.ErrAllowanceExceeded.Error()) {
+		t.Fatalf("got error %q, want %q", resp.Err, gateway.ErrAllowanceExceeded)
+	}
+	if d := debited(t, s); d != 0 {
+		t.Fatalf("got debited %d for refused requests", d)
+	}
+}
+
+func TestAllowedOrigins(t *testing.T) {
+	s := newTestServer(t, nil, pushsyncmock.New(nil), gateway.Options{
+		AllowedOrigins: []string{"https://app.example"},
+	})
+	s.dial(t)
+
+	_, resp, err := websocket.DefaultDialer.Dial(s.url, http.Header{"Origin": {"https://other.example"}})
+	if err == nil {
+		t.Fatal("expected rejected connection")
+	}
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("got status %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,207 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redesblock/mop/core/storer/storage"
+)
+
+// ErrAllowanceExceeded is returned when serving the request would exceed the
+// allowance of the client.
+var ErrAllowanceExceeded = errors.New("allowance exceeded")
+
+// debtPrefix is the prefix of the state store keys of the client debts.
+const debtPrefix = "gateway_debt_"
+
+// ledger keeps the debts of the clients. The debt of a client is forgiven at
+// the refresh rate, so that a client is served at the refresh rate on
+// average and in bursts up to the allowance. The clients are identified by
+// their network address rather than by their connections, so reconnecting
+// does not reset the debt, and the debts are persisted in the state store,
+// so neither does restarting the node.
+type ledger struct {
+	mu        sync.Mutex
+	store     storage.StateStorer
+	allowance uint64
+	rate      uint64
+	now       func() time.Time
+	debts     map[string]*debt
+}
+
+type debt struct {
+	amount   uint64
+	reserved uint64
+	updated  time.Time
+}
+
+// storedDebt is the debt of a client as persisted in the state store.
+type storedDebt struct {
+	Amount  uint64    `json:"amount"`
+	Updated time.Time `json:"updated"`
+}
+
+func newLedger(store storage.StateStorer, allowance, rate uint64) *ledger {
+	return &ledger{
+		store:     store,
+		allowance: allowance,
+		rate:      rate,
+		now:       time.Now,
+		debts:     make(map[string]*debt),
+	}
+}
+
+func debtKey(client string) string {
+	return debtPrefix + client
+}
+
+// refresh forgives the debt accrued since its last update.
+func (l *ledger) refresh(d *debt, now time.Time) {
+	forgiven := uint64(now.Sub(d.updated).Seconds() * float64(l.rate))
+	if forgiven == 0 {
+		return
+	}
+	if forgiven > d.amount {
+		forgiven = d.amount
+	}
+	d.amount -= forgiven
+	d.updated = now
+}
+
+// debt returns the debt of the client, loading it from the state store if
+// it is not kept in memory. Must be called with the mutex held.
+func (l *ledger) debt(client string, now time.Time) (*debt, error) {
+	if d, ok := l.debts[client]; ok {
+		return d, nil
+	}
+
+	d := &debt{updated: now}
+	var sd storedDebt
+	err := l.store.Get(debtKey(client), &sd)
+	switch {
+	case err == nil:
+		d.amount, d.updated = sd.Amount, sd.Updated
+	case !errors.Is(err, storage.ErrNotFound):
+		return nil, fmt.Errorf("load debt: %w", err)
+	}
+	l.debts[client] = d
+	return d, nil
+}
+
+// prepareDebit reserves the price in the allowance of the client. The
+// reservation is turned into debt when the returned action is applied.
+func (l *ledger) prepareDebit(client string, price uint64) (*debitAction, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	d, err := l.debt(client, now)
+	if err != nil {
+		return nil, err
+	}
+	l.refresh(d, now)
+
+	if d.amount+d.reserved+price > l.allowance {
+		return nil, ErrAllowanceExceeded
+	}
+	d.reserved += price
+
+	return &debitAction{ledger: l, client: client, debt: d, price: price}, nil
+}
+
+// prune removes the clients whose debt has been forgiven, including the
+// ones persisted in the state store which are not kept in memory.
+func (l *ledger) prune() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	var forgiven []string
+	err := l.store.Iterate(debtPrefix, func(key, value []byte) (bool, error) {
+		client := strings.TrimPrefix(string(key), debtPrefix)
+		if _, ok := l.debts[client]; ok {
+			return false, nil
+		}
+		var sd storedDebt
+		if err := json.Unmarshal(value, &sd); err != nil {
+			return true, err
+		}
+		d := &debt{amount: sd.Amount, updated: sd.Updated}
+		l.refresh(d, now)
+		if d.amount == 0 {
+			forgiven = append(forgiven, client)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("iterate debts: %w", err)
+	}
+
+	for client, d := range l.debts {
+		l.refresh(d, now)
+		if d.amount == 0 && d.reserved == 0 {
+			delete(l.debts, client)
+			forgiven = append(forgiven, client)
+		}
+	}
+	for _, client := range forgiven {
+		if err := l.store.Delete(debtKey(client)); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("delete debt: %w", err)
+		}
+	}
+	return nil
+}
+
+// balance returns the current debt of the client.
+func (l *ledger) balance(client string) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	d, err := l.debt(client, now)
+	if err != nil {
+		return 0, err
+	}
+	l.refresh(d, now)
+	return d.amount, nil
+}
+
+type debitAction struct {
+	ledger  *ledger
+	client  string
+	debt    *debt
+	price   uint64
+	applied bool
+}
+
+// Apply turns the reserved price into debt and persists it.
+func (a *debitAction) Apply() error {
+	a.ledger.mu.Lock()
+	defer a.ledger.mu.Unlock()
+
+	a.debt.reserved -= a.price
+	a.debt.amount += a.price
+	a.applied = true
+
+	err := a.ledger.store.Put(debtKey(a.client), storedDebt{Amount: a.debt.amount, Updated: a.debt.updated})
+	if err != nil {
+		return fmt.Errorf("store debt: %w", err)
+	}
+	return nil
+}
+
+// Cleanup releases the reserved price if the action was not applied.
+func (a *debitAction) Cleanup() {
+	if a.applied {
+		return
+	}
+
+	a.ledger.mu.Lock()
+	defer a.ledger.mu.Unlock()
+
+	a.debt.reserved -= a.price
+}
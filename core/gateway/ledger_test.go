@@ -0,0 +1,73 @@
+package gateway
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/storer/statestore/leveldb"
+)
+
+func TestLedger(t *testing.T) {
+	store, err := leveldb.NewInMemoryStateStore(log.Noop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	now := time.Unix(0, 0)
+	l := newLedger(store, 100, 10)
+	l.now = func() time.Time { return now }
+
+	balance := func(l *ledger, want uint64) {
+		t.Helper()
+		b, err := l.balance("client")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b != want {
+			t.Fatalf("got balance %d, want %d", b, want)
+		}
+	}
+
+	a, err := l.prepareDebit("client", 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.prepareDebit("client", 60); !errors.Is(err, ErrAllowanceExceeded) {
+		t.Fatalf("got error %v, want %v", err, ErrAllowanceExceeded)
+	}
+	if err := a.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	a.Cleanup()
+	balance(l, 60)
+
+	a, err = l.prepareDebit("client", 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Cleanup()
+	balance(l, 60)
+
+	now = now.Add(3 * time.Second)
+	balance(l, 30)
+
+	// the debt is kept by a new ledger, as after a restart of the node
+	restarted := newLedger(store, 100, 10)
+	restarted.now = l.now
+	balance(restarted, 30)
+
+	now = now.Add(time.Hour)
+	if err := l.prune(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(l.debts); n != 0 {
+		t.Fatalf("got %d debts after prune, want %d", n, 0)
+	}
+	var sd storedDebt
+	if err := store.Get(debtKey("client"), &sd); err == nil {
+		t.Fatalf("got stored debt %+v after prune", sd)
+	}
+}
@@ -0,0 +1,78 @@
+package gateway
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	m "github.com/redesblock/mop/core/metrics"
+)
+
+type metrics struct {
+	// all metrics fields must be exported
+	// to be able to return them by Metrics()
+	// using reflection
+	Connections     prometheus.Counter
+	OpenConnections prometheus.Gauge
+	Refused         prometheus.Counter
+	Requests        *prometheus.CounterVec
+	RequestErrors   *prometheus.CounterVec
+	InvalidStamps   prometheus.Counter
+	Debited         prometheus.Counter
+}
+
+func newMetrics() metrics {
+	subsystem := "gateway"
+
+	return metrics{
+		Connections: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "connections_total",
+			Help:      "Total number of browser client connections.",
+		}),
+		OpenConnections: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "open_connections",
+			Help:      "Number of open browser client connections.",
+		}),
+		Refused: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "refused_total",
+			Help:      "Total number of requests refused for exceeding the allowance of the client.",
+		}),
+		Requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: m.Namespace,
+				Subsystem: subsystem,
+				Name:      "requests_total",
+				Help:      "Total number of requests by operation.",
+			},
+			[]string{"operation"},
+		),
+		RequestErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: m.Namespace,
+				Subsystem: subsystem,
+				Name:      "request_errors_total",
+				Help:      "Total number of failed requests by operation.",
+			},
+			[]string{"operation"},
+		),
+		InvalidStamps: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "invalid_stamps_total",
+			Help:      "Total number of pushed chunks with invalid stamps.",
+		}),
+		Debited: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "debited_total",
+			Help:      "Total amount debited to the browser clients.",
+		}),
+	}
+}
+
+func (s *Service) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(s.metrics)
+}
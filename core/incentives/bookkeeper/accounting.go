@@ -893,6 +893,21 @@ func (a *Accounting) PeerDebt(peer cluster.Address) (*big.Int, error) {
 	return peerDebt, nil
 }
 
+// Settling reports whether a settlement with the peer is ongoing or an amount
+// is reserved for an active interaction with the peer, in which case the peer
+// should not be disconnected.
+func (a *Accounting) Settling(peer cluster.Address) bool {
+	accountingPeer := a.getAccountingPeer(peer)
+
+	accountingPeer.lock.Lock()
+	defer accountingPeer.lock.Unlock()
+
+	return accountingPeer.paymentOngoing ||
+		accountingPeer.refreshOngoing ||
+		accountingPeer.reservedBalance.Sign() != 0 ||
+		accountingPeer.shadowReservedBalance.Sign() != 0
+}
+
 // peerLatentDebt returns the sum of the positive part of the outstanding balance, shadow reserve and the ghost balance
 func (a *Accounting) peerLatentDebt(peer cluster.Address) (*big.Int, error) {
 
@@ -392,6 +392,57 @@ func TestAccountingReserve(t *testing.T) {
 	}
 }
 
+// TestAccountingSettling tests that the peers with reserved amounts are reported as settling.
+func TestAccountingSettling(t *testing.T) {
+	logger := log.Noop
+
+	store := mock.NewStateStore()
+	defer store.Close()
+
+	pricing := &pricingMock{}
+
+	acc, err := bookkeeper.NewAccounting(testPaymentThreshold, testPaymentTolerance, testPaymentEarly, logger, store, pricing, big.NewInt(testRefreshRate), testLightFactor, p2pmock.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peer1Addr, err := cluster.ParseHexAddress("00112233")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acc.Connect(peer1Addr, true)
+
+	if acc.Settling(peer1Addr) {
+		t.Fatal("expected peer not to be settling")
+	}
+
+	creditAction, err := acc.PrepareCredit(context.Background(), peer1Addr, testPrice, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acc.Settling(peer1Addr) {
+		t.Fatal("expected peer with reserved amount to be settling")
+	}
+	creditAction.Cleanup()
+
+	debitAction, err := acc.PrepareDebit(context.Background(), peer1Addr, testPrice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acc.Settling(peer1Addr) {
+		t.Fatal("expected peer with shadow reserved amount to be settling")
+	}
+	if err := debitAction.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	debitAction.Cleanup()
+
+	if acc.Settling(peer1Addr) {
+		t.Fatal("expected peer not to be settling")
+	}
+}
+
 // TestAccountingReserve tests that reserve returns an error if the payment threshold would be exceeded,
 // but extends this limit by 'n' (max 2) seconds worth of refresh rate if last refreshment was 'n' seconds ago.
 func TestAccountingReserveAheadOfTime(t *testing.T) {
@@ -0,0 +1,106 @@
+package batchstore
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/redesblock/mop/core/cluster"
+)
+
+// BatchUsage is the number of chunks of a batch stored by a node.
+type BatchUsage struct {
+	ID    []byte
+	Value *big.Int
+	// Radius is the proximity order below which the chunks
+	// of the batch are already unreserved.
+	Radius uint8
+	// Bins is the number of stored chunks by proximity order to the node.
+	Bins [cluster.MaxBins]uint64
+}
+
+// Reserved returns the number of chunks of the batch in the reserve when
+// the chunks with proximity order below the radius are unreserved.
+func (b *BatchUsage) Reserved(radius uint8) (n uint64) {
+	if b.Radius > radius {
+		radius = b.Radius
+	}
+	for po := int(radius); po < len(b.Bins); po++ {
+		n += b.Bins[po]
+	}
+	return n
+}
+
+// Projection is the predicted state of the reserve for a radius and a capacity.
+type Projection struct {
+	// Radius is the reserve radius, up to which the storage radius is increased.
+	Radius   uint8 `json:"radius"`
+	Capacity int64 `json:"capacity"`
+	// Size is the number of chunks in the reserve at the initial storage radius.
+	Size uint64 `json:"size"`
+	// Evicted is the number of chunks evicted to fit the capacity.
+	Evicted uint64 `json:"evicted"`
+	// EvictedBatches is the number of batches which chunks are evicted.
+	EvictedBatches int `json:"evictedBatches"`
+	// StorageRadius is the storage radius after the evictions.
+	StorageRadius uint8 `json:"storageRadius"`
+	// Fits reports whether the reserve fits the capacity after the evictions.
+	Fits bool `json:"fits"`
+}
+
+// Simulate predicts the evictions needed for the stored chunks of the
+// batches to fit the capacity when the chunks below the storage radius are
+// not in the reserve. The evictions follow the order of Unreserve: the
+// batches with the lowest value are unreserved first, one proximity order at
+// a time, and the storage radius is increased once no batch can be
+// unreserved, but not above the reserve radius.
+func Simulate(batches []*BatchUsage, storageRadius, radius uint8, capacity int64) Projection {
+	if storageRadius > radius {
+		storageRadius = radius
+	}
+	p := Projection{Radius: radius, Capacity: capacity, StorageRadius: storageRadius}
+
+	sorted := make([]*BatchUsage, len(batches))
+	copy(sorted, batches)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Value.Cmp(sorted[j].Value) < 0
+	})
+
+	radiuses := make([]uint8, len(sorted))
+	for i, b := range sorted {
+		radiuses[i] = storageRadius
+		if b.Radius > storageRadius {
+			radiuses[i] = b.Radius
+		}
+		p.Size += b.Reserved(storageRadius)
+	}
+
+	size := p.Size
+	evicted := make(map[int]struct{})
+	for size > uint64(capacity) {
+		unreserved := false
+		for i, b := range sorted {
+			if radiuses[i] > p.StorageRadius || int(radiuses[i]) >= len(b.Bins) {
+				continue
+			}
+			unreserved = true
+			if n := b.Bins[radiuses[i]]; n > 0 {
+				size -= n
+				p.Evicted += n
+				evicted[i] = struct{}{}
+			}
+			radiuses[i]++
+			if size <= uint64(capacity) {
+				break
+			}
+		}
+		if !unreserved {
+			if p.StorageRadius >= radius {
+				break
+			}
+			p.StorageRadius++
+		}
+	}
+	p.EvictedBatches = len(evicted)
+	p.Fits = size <= uint64(capacity)
+	return p
+}
@@ -0,0 +1,84 @@
+package batchstore_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/redesblock/mop/core/incentives/voucher/batchstore"
+)
+
+func TestSimulate(t *testing.T) {
+	cheap := &batchstore.BatchUsage{ID: []byte{1}, Value: big.NewInt(10)}
+	cheap.Bins[0], cheap.Bins[1], cheap.Bins[2] = 4, 2, 1
+	dear := &batchstore.BatchUsage{ID: []byte{2}, Value: big.NewInt(20), Radius: 1}
+	dear.Bins[0], dear.Bins[1], dear.Bins[2] = 8, 4, 2
+	batches := []*batchstore.BatchUsage{dear, cheap}
+
+	if got := dear.Reserved(0); got != 6 {
+		t.Fatalf("got %d reserved chunks, want %d", got, 6)
+	}
+
+	for _, tc := range []struct {
+		name          string
+		storageRadius uint8
+		radius        uint8
+		capacity      int64
+		want          batchstore.Projection
+	}{
+		{
+			name:          "fits",
+			storageRadius: 0,
+			radius:        0,
+			capacity:      13,
+			want:          batchstore.Projection{Radius: 0, Capacity: 13, Size: 13, Fits: true},
+		},
+		{
+			name:          "evicts cheap batch first",
+			storageRadius: 0,
+			radius:        0,
+			capacity:      9,
+			want:          batchstore.Projection{Radius: 0, Capacity: 9, Size: 13, Evicted: 4, EvictedBatches: 1, Fits: true},
+		},
+		{
+			name:          "raises storage radius",
+			storageRadius: 0,
+			radius:        2,
+			capacity:      5,
+			want:          batchstore.Projection{Radius: 2, Capacity: 5, Size: 13, Evicted: 10, EvictedBatches: 2, StorageRadius: 1, Fits: true},
+		},
+		{
+			name:          "higher storage radius",
+			storageRadius: 2,
+			radius:        2,
+			capacity:      5,
+			want:          batchstore.Projection{Radius: 2, Capacity: 5, Size: 3, StorageRadius: 2, Fits: true},
+		},
+		{
+			name:          "evicts everything",
+			storageRadius: 0,
+			radius:        3,
+			capacity:      0,
+			want:          batchstore.Projection{Radius: 3, Capacity: 0, Size: 13, Evicted: 13, EvictedBatches: 2, StorageRadius: 2, Fits: true},
+		},
+		{
+			name:          "capped at reserve radius",
+			storageRadius: 0,
+			radius:        1,
+			capacity:      0,
+			want:          batchstore.Projection{Radius: 1, Capacity: 0, Size: 13, Evicted: 10, EvictedBatches: 2, StorageRadius: 1},
+		},
+		{
+			name:          "storage radius above reserve radius",
+			storageRadius: 2,
+			radius:        1,
+			capacity:      13,
+			want:          batchstore.Projection{Radius: 1, Capacity: 13, Size: 9, StorageRadius: 1, Fits: true},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := batchstore.Simulate(batches, tc.storageRadius, tc.radius, tc.capacity); got != tc.want {
+				t.Fatalf("got projection %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package manifest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChangeType describes how a single path differs between two manifests.
+type ChangeType string
+
+const (
+	// ChangeAdded is used for paths present only in the new manifest.
+	ChangeAdded ChangeType = "added"
+	// ChangeRemoved is used for paths present only in the old manifest.
+	ChangeRemoved ChangeType = "removed"
+	// ChangeModified is used for paths present in both manifests with
+	// a different reference or metadata.
+	ChangeModified ChangeType = "modified"
+)
+
+// Change represents a path-level difference between two manifests.
+type Change struct {
+	Path string
+	Type ChangeType
+	// Old is the entry in the old manifest; nil for added paths.
+	Old Entry
+	// New is the entry in the new manifest; nil for removed paths.
+	New Entry
+}
+
+// differ is implemented by the manifests which can find the entries that
+// may differ from another manifest without iterating over all of them.
+type differ interface {
+	// diffEntries returns the entries of both manifests on the paths
+	// which may differ. It returns false if the other manifest is not
+	// of the same type.
+	diffEntries(ctx context.Context, other Interface) (oldEntries, newEntries map[string]Entry, ok bool, err error)
+}
+
+// Diff returns the path-level changes which turn the a manifest into
+// the b manifest. Changes are sorted by path.
+func Diff(ctx context.Context, a, b Interface) ([]Change, error) {
+	var (
+		oldEntries, newEntries map[string]Entry
+		ok                     bool
+		err                    error
+	)
+	if d, isDiffer := a.(differ); isDiffer {
+		oldEntries, newEntries, ok, err = d.diffEntries(ctx, b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !ok {
+		oldEntries, err = entries(ctx, a)
+		if err != nil {
+			return nil, fmt.Errorf("old manifest: %w", err)
+		}
+		newEntries, err = entries(ctx, b)
+		if err != nil {
+			return nil, fmt.Errorf("new manifest: %w", err)
+		}
+	}
+
+	var changes []Change
+	for path, o := range oldEntries {
+		n, ok := newEntries[path]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Path: path, Type: ChangeRemoved, Old: o})
+		case !entriesEqual(o, n):
+			changes = append(changes, Change{Path: path, Type: ChangeModified, Old: o, New: n})
+		}
+	}
+	for path, n := range newEntries {
+		if _, ok := oldEntries[path]; !ok {
+			changes = append(changes, Change{Path: path, Type: ChangeAdded, New: n})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes, nil
+}
+
+// Merge adds all the entries of the src manifest to the dst manifest
+// under the given path prefix, replacing the existing ones. When the
+// prefix is not empty, the root metadata entry of the src manifest is
+// skipped as the website metadata only applies to the root of dst.
+func Merge(ctx context.Context, dst, src Interface, prefix string) error {
+	return src.IterateEntries(ctx, func(path string, entry Entry) error {
+		if prefix != "" && path == RootPath {
+			return nil
+		}
+		if err := dst.Add(ctx, prefix+path, entry); err != nil {
+			return fmt.Errorf("merge %q: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// Move moves the entry on the from path to the to path. If the from
+// path ends with a slash, all the entries with that prefix are moved
+// under the to prefix. ErrNotFound is returned if nothing was moved and
+// ErrPathExists if a destination path has an entry which is not moved.
+func Move(ctx context.Context, m Interface, from, to string) error {
+	moved, err := moveEntries(ctx, m, func(path string) (string, bool) {
+		if !strings.HasSuffix(from, "/") {
+			return to, path == from
+		}
+		if !strings.HasPrefix(path, from) {
+			return "", false
+		}
+		return to + strings.TrimPrefix(path, from), true
+	})
+	if err != nil {
+		return err
+	}
+	if moved == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RemovePrefix removes all the entries with the given path prefix and
+// returns their number.
+func RemovePrefix(ctx context.Context, m Interface, prefix string) (int, error) {
+	var paths []string
+	err := m.IterateEntries(ctx, func(path string, _ Entry) error {
+		if strings.HasPrefix(path, prefix) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, path := range paths {
+		if err := m.Remove(ctx, path); err != nil {
+			return 0, fmt.Errorf("remove %q: %w", path, err)
+		}
+	}
+	return len(paths), nil
+}
+
+func moveEntries(ctx context.Context, m Interface, target func(string) (string, bool)) (int, error) {
+	type move struct {
+		from, to string
+		entry    Entry
+	}
+
+	var moves []move
+	err := m.IterateEntries(ctx, func(path string, entry Entry) error {
+		if to, ok := target(path); ok {
+			moves = append(moves, move{from: path, to: to, entry: entry})
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sources := make(map[string]struct{}, len(moves))
+	for _, mv := range moves {
+		sources[mv.from] = struct{}{}
+	}
+	for _, mv := range moves {
+		if _, ok := sources[mv.to]; ok {
+			continue
+		}
+		switch _, err := m.Lookup(ctx, mv.to); {
+		case err == nil:
+			return 0, fmt.Errorf("%w: %s", ErrPathExists, mv.to)
+		case !errors.Is(err, ErrNotFound):
+			return 0, err
+		}
+	}
+
+	// remove all the sources first so that overlapping
+	// source and destination prefixes are handled.
+	for _, mv := range moves {
+		if err := m.Remove(ctx, mv.from); err != nil {
+			return 0, fmt.Errorf("remove %q: %w", mv.from, err)
+		}
+	}
+	for _, mv := range moves {
+		if err := m.Add(ctx, mv.to, mv.entry); err != nil {
+			return 0, fmt.Errorf("add %q: %w", mv.to, err)
+		}
+	}
+	return len(moves), nil
+}
+
+func entries(ctx context.Context, m Interface) (map[string]Entry, error) {
+	res := make(map[string]Entry)
+	err := m.IterateEntries(ctx, func(path string, entry Entry) error {
+		res[path] = entry
+		return nil
+	})
+	return res, err
+}
+
+func entriesEqual(a, b Entry) bool {
+	if !a.Reference().Equal(b.Reference()) {
+		return false
+	}
+	am, bm := a.Metadata(), b.Metadata()
+	if len(am) != len(bm) {
+		return false
+	}
+	for k, v := range am {
+		if bv, ok := bm[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
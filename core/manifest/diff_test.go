@@ -0,0 +1,296 @@
+package manifest_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/file"
+	"github.com/redesblock/mop/core/file/loadsave"
+	"github.com/redesblock/mop/core/file/pipeline"
+	"github.com/redesblock/mop/core/file/pipeline/builder"
+	"github.com/redesblock/mop/core/manifest"
+	"github.com/redesblock/mop/core/storer/storage"
+	"github.com/redesblock/mop/core/storer/storage/mock"
+	testingc "github.com/redesblock/mop/core/storer/storage/testing"
+)
+
+func newLoadSaver() file.LoadSaver {
+	store := mock.NewStorer()
+	return loadsave.New(store, func() pipeline.Interface {
+		return builder.NewPipelineBuilder(context.Background(), store, storage.ModePutRequest, false)
+	})
+}
+
+func newManifest(t *testing.T, ls file.LoadSaver, entries map[string]cluster.Address) manifest.Interface {
+	t.Helper()
+
+	m, err := manifest.NewDefaultManifest(ls, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, ref := range entries {
+		if err := m.Add(context.Background(), path, manifest.NewEntry(ref, map[string]string{
+			manifest.EntryMetadataFilenameKey: path,
+		})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func storeAndLoad(t *testing.T, ls file.LoadSaver, m manifest.Interface) manifest.Interface {
+	t.Helper()
+
+	ref, err := m.Store(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err = manifest.NewDefaultManifestReference(ref, ls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func paths(t *testing.T, m manifest.Interface) map[string]cluster.Address {
+	t.Helper()
+
+	res := make(map[string]cluster.Address)
+	err := m.IterateEntries(context.Background(), func(path string, entry manifest.Entry) error {
+		res[path] = entry.Reference()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestDiff(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		ls   = newLoadSaver()
+		ref1 = testingc.GenerateTestRandomChunk().Address()
+		ref2 = testingc.GenerateTestRandomChunk().Address()
+		ref3 = testingc.GenerateTestRandomChunk().Address()
+	)
+
+	a := storeAndLoad(t, ls, newManifest(t, ls, map[string]cluster.Address{
+		"index.html":   ref1,
+		"img/logo.png": ref2,
+		"old.txt":      ref3,
+	}))
+	b := storeAndLoad(t, ls, newManifest(t, ls, map[string]cluster.Address{
+		"index.html":   ref1,
+		"img/logo.png": ref3,
+		"new.txt":      ref3,
+	}))
+
+	changes, err := manifest.Diff(ctx, a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type change struct {
+		path string
+		typ  manifest.ChangeType
+	}
+	var have []change
+	for _, c := range changes {
+		have = append(have, change{path: c.Path, typ: c.Type})
+	}
+	want := []change{
+		{path: "img/logo.png", typ: manifest.ChangeModified},
+		{path: "new.txt", typ: manifest.ChangeAdded},
+		{path: "old.txt", typ: manifest.ChangeRemoved},
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("changes mismatch: have %v; want %v", have, want)
+	}
+
+	changes, err = manifest.Diff(ctx, a, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("got %d changes for the same manifest; want none", len(changes))
+	}
+
+	// the changes which are not stored yet are found as well
+	c, err := manifest.NewDefaultManifestReference(mustStore(t, a), ls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Add(ctx, "img/new.png", manifest.NewEntry(ref1, nil)); err != nil {
+		t.Fatal(err)
+	}
+	changes, err = manifest.Diff(ctx, a, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Path != "img/new.png" || changes[0].Type != manifest.ChangeAdded {
+		t.Fatalf("got changes %v; want img/new.png added", changes)
+	}
+}
+
+func mustStore(t *testing.T, m manifest.Interface) cluster.Address {
+	t.Helper()
+
+	ref, err := m.Store(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ref
+}
+
+// countingLoadSaver counts the loads of the manifest nodes.
+type countingLoadSaver struct {
+	file.LoadSaver
+	loads int
+}
+
+func (ls *countingLoadSaver) Load(ctx context.Context, ref []byte) ([]byte, error) {
+	ls.loads++
+	return ls.LoadSaver.Load(ctx, ref)
+}
+
+// TestDiffSkipsEqualSubtrees checks that the subtrees which are the same in
+// both manifests are not loaded.
+func TestDiffSkipsEqualSubtrees(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		ls      = newLoadSaver()
+		entries = make(map[string]cluster.Address)
+		ref     = testingc.GenerateTestRandomChunk().Address()
+	)
+	for _, dir := range []string{"a/", "b/", "c/"} {
+		for _, name := range []string{"1.txt", "2.txt", "3.txt"} {
+			entries[dir+name] = testingc.GenerateTestRandomChunk().Address()
+		}
+	}
+	a := storeAndLoad(t, ls, newManifest(t, ls, entries))
+	entries["b/2.txt"] = ref
+	b := storeAndLoad(t, ls, newManifest(t, ls, entries))
+
+	load := func(m manifest.Interface, ls file.LoadSaver) manifest.Interface {
+		t.Helper()
+		ref, err := m.Store(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err = manifest.NewDefaultManifestReference(ref, ls)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	full := &countingLoadSaver{LoadSaver: ls}
+	for _, m := range []manifest.Interface{load(a, full), load(b, full)} {
+		if err := m.IterateEntries(ctx, func(string, manifest.Entry) error { return nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	counting := &countingLoadSaver{LoadSaver: ls}
+	changes, err := manifest.Diff(ctx, load(a, counting), load(b, counting))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Path != "b/2.txt" || changes[0].Type != manifest.ChangeModified {
+		t.Fatalf("got changes %v; want b/2.txt modified", changes)
+	}
+	if counting.loads >= full.loads {
+		t.Fatalf("got %d loads; want fewer than %d of a full walk", counting.loads, full.loads)
+	}
+}
+
+func TestMove(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		ls   = newLoadSaver()
+		ref1 = testingc.GenerateTestRandomChunk().Address()
+		ref2 = testingc.GenerateTestRandomChunk().Address()
+		ref3 = testingc.GenerateTestRandomChunk().Address()
+	)
+
+	m := storeAndLoad(t, ls, newManifest(t, ls, map[string]cluster.Address{
+		"index.html":     ref1,
+		"docs/a.html":    ref2,
+		"docs/sub/b.txt": ref3,
+	}))
+
+	if err := manifest.Move(ctx, m, "index.html", "home.html"); err != nil {
+		t.Fatal(err)
+	}
+	if err := manifest.Move(ctx, m, "docs/", "v2/docs/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := manifest.Move(ctx, m, "missing.html", "other.html"); !errors.Is(err, manifest.ErrNotFound) {
+		t.Fatalf("got error %v; want %v", err, manifest.ErrNotFound)
+	}
+	// an existing destination is not overwritten
+	if err := manifest.Move(ctx, m, "home.html", "v2/docs/a.html"); !errors.Is(err, manifest.ErrPathExists) {
+		t.Fatalf("got error %v; want %v", err, manifest.ErrPathExists)
+	}
+
+	m = storeAndLoad(t, ls, m)
+	want := map[string]cluster.Address{
+		"home.html":         ref1,
+		"v2/docs/a.html":    ref2,
+		"v2/docs/sub/b.txt": ref3,
+	}
+	if have := paths(t, m); !reflect.DeepEqual(have, want) {
+		t.Fatalf("entries mismatch: have %v; want %v", have, want)
+	}
+}
+
+func TestMergeAndRemovePrefix(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		ls   = newLoadSaver()
+		ref1 = testingc.GenerateTestRandomChunk().Address()
+		ref2 = testingc.GenerateTestRandomChunk().Address()
+	)
+
+	dst := storeAndLoad(t, ls, newManifest(t, ls, map[string]cluster.Address{
+		"index.html": ref1,
+	}))
+	src := storeAndLoad(t, ls, newManifest(t, ls, map[string]cluster.Address{
+		"a.txt":   ref2,
+		"b/c.txt": ref2,
+	}))
+
+	if err := manifest.Merge(ctx, dst, src, "assets/"); err != nil {
+		t.Fatal(err)
+	}
+
+	dst = storeAndLoad(t, ls, dst)
+	want := map[string]cluster.Address{
+		"index.html":     ref1,
+		"assets/a.txt":   ref2,
+		"assets/b/c.txt": ref2,
+	}
+	if have := paths(t, dst); !reflect.DeepEqual(have, want) {
+		t.Fatalf("entries mismatch: have %v; want %v", have, want)
+	}
+
+	n, err := manifest.RemovePrefix(ctx, dst, "assets/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("removed %d entries; want 2", n)
+	}
+
+	dst = storeAndLoad(t, ls, dst)
+	want = map[string]cluster.Address{
+		"index.html": ref1,
+	}
+	if have := paths(t, dst); !reflect.DeepEqual(have, want) {
+		t.Fatalf("entries mismatch: have %v; want %v", have, want)
+	}
+}
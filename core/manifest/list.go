@@ -0,0 +1,137 @@
+package manifest
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// DirEntry represents a single item of a manifest directory listing.
+type DirEntry struct {
+	// Name is the name relative to the listed directory. Directory
+	// names end with a slash.
+	Name string
+	// Path is the full path of the item in the manifest.
+	Path string
+	// Entry is the manifest entry of a file; nil for directories.
+	Entry Entry
+}
+
+// IsDir reports whether the listed item is a directory.
+func (e DirEntry) IsDir() bool {
+	return e.Entry == nil
+}
+
+// seeker is implemented by the manifests which can look up the entries
+// in path order without iterating over all of them.
+type seeker interface {
+	// seek returns the first path, with its entry, which has the given
+	// prefix and is not smaller than from.
+	seek(ctx context.Context, prefix, from string) (string, Entry, error)
+}
+
+// List returns the files and directories directly contained in the
+// directory with the given path, sorted by name. The path must be
+// empty for the root directory or end with a slash. The listing starts
+// with the first item whose name sorts after the given name, and holds
+// at most limit items, all of them if the limit is zero. ErrNotFound is
+// returned if there are no entries under a non-root path.
+func List(ctx context.Context, m Interface, dir, after string, limit int) ([]DirEntry, error) {
+	var (
+		res []DirEntry
+		err error
+	)
+	if s, ok := m.(seeker); ok {
+		res, err = seekList(ctx, s, dir, after, limit)
+	} else {
+		res, err = iterateList(ctx, m, dir, after, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 && dir != "" && after == "" {
+		return nil, ErrNotFound
+	}
+	return res, nil
+}
+
+// seekList lists the directory by looking up the items one after another,
+// skipping the paths of the listed subdirectories.
+func seekList(ctx context.Context, s seeker, dir, after string, limit int) ([]DirEntry, error) {
+	var res []DirEntry
+
+	from := dir
+	if after != "" {
+		from = pathSuccessor(dir + after)
+	}
+	for limit == 0 || len(res) < limit {
+		path, entry, err := s.seek(ctx, dir, from)
+		if errors.Is(err, ErrNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if path == RootPath || path == dir {
+			from = pathSuccessor(path)
+			continue
+		}
+		name := strings.TrimPrefix(path, dir)
+		if i := strings.IndexByte(name, '/'); i >= 0 {
+			name = name[:i+1]
+			res = append(res, DirEntry{Name: name, Path: dir + name})
+		} else {
+			res = append(res, DirEntry{Name: name, Path: path, Entry: entry})
+		}
+		from = pathSuccessor(dir + name)
+	}
+	return res, nil
+}
+
+// iterateList lists the directory by iterating over all the entries of
+// the manifest.
+func iterateList(ctx context.Context, m Interface, dir, after string, limit int) ([]DirEntry, error) {
+	var (
+		res  []DirEntry
+		dirs = make(map[string]struct{})
+	)
+	err := m.IterateEntries(ctx, func(path string, entry Entry) error {
+		if path == RootPath || !strings.HasPrefix(path, dir) || path == dir {
+			return nil
+		}
+		name := strings.TrimPrefix(path, dir)
+		if i := strings.IndexByte(name, '/'); i >= 0 {
+			name = name[:i+1]
+			if _, ok := dirs[name]; !ok && name > after {
+				dirs[name] = struct{}{}
+				res = append(res, DirEntry{Name: name, Path: dir + name})
+			}
+			return nil
+		}
+		if name > after {
+			res = append(res, DirEntry{Name: name, Path: path, Entry: entry})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+// pathSuccessor returns the smallest path which sorts after the given
+// path and, for a directory, after all the paths it contains.
+func pathSuccessor(path string) string {
+	if strings.HasSuffix(path, "/") {
+		return path[:len(path)-1] + string(rune('/'+1))
+	}
+	return path + "\x00"
+}
@@ -0,0 +1,64 @@
+package manifest_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/manifest"
+	testingc "github.com/redesblock/mop/core/storer/storage/testing"
+)
+
+func TestList(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		ls   = newLoadSaver()
+		ref1 = testingc.GenerateTestRandomChunk().Address()
+	)
+
+	m := storeAndLoad(t, ls, newManifest(t, ls, map[string]cluster.Address{
+		"index.html":        ref1,
+		"docs/a.html":       ref1,
+		"docs/b.html":       ref1,
+		"docs/sub/c.html":   ref1,
+		"docs/sub/d/e.html": ref1,
+	}))
+
+	for _, tc := range []struct {
+		dir   string
+		after string
+		limit int
+		want  []string
+	}{
+		{dir: "", want: []string{"docs/", "index.html"}},
+		{dir: "docs/", want: []string{"a.html", "b.html", "sub/"}},
+		{dir: "docs/sub/", want: []string{"c.html", "d/"}},
+		{dir: "docs/", limit: 2, want: []string{"a.html", "b.html"}},
+		{dir: "docs/", after: "a.html", limit: 1, want: []string{"b.html"}},
+		{dir: "docs/", after: "b.html", want: []string{"sub/"}},
+		{dir: "", after: "docs/", want: []string{"index.html"}},
+		{dir: "docs/", after: "sub/", want: nil},
+	} {
+		entries, err := manifest.List(ctx, m, tc.dir, tc.after, tc.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var have []string
+		for _, e := range entries {
+			have = append(have, e.Name)
+			if e.IsDir() != strings.HasSuffix(e.Name, "/") {
+				t.Fatalf("entry %q: unexpected directory flag %v", e.Path, e.IsDir())
+			}
+		}
+		if !reflect.DeepEqual(have, tc.want) {
+			t.Fatalf("list %q after %q: have %v; want %v", tc.dir, tc.after, have, tc.want)
+		}
+	}
+
+	if _, err := manifest.List(ctx, m, "missing/", "", 0); !errors.Is(err, manifest.ErrNotFound) {
+		t.Fatalf("got error %v; want %v", err, manifest.ErrNotFound)
+	}
+}
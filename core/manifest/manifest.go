@@ -31,6 +31,9 @@ var (
 	// ErrMissingReference is returned when the reference for the manifest file
 	// is missing.
 	ErrMissingReference = errors.New("manifest: missing reference")
+
+	// ErrPathExists is returned when an entry would be overwritten.
+	ErrPathExists = errors.New("manifest: path exists")
 )
 
 // StoreSizeFunc is a callback on every content size that will be stored by
@@ -39,6 +42,10 @@ type StoreSizeFunc func(int64) error
 
 type AddressIterFunc func(address cluster.Address, metadata map[string]string) error
 
+// EntryIterFunc is a callback on every entry that is visited by the
+// IterateEntries function.
+type EntryIterFunc func(path string, entry Entry) error
+
 // Interface for operations with manifest.
 type Interface interface {
 	// Type returns manifest implementation type information
@@ -56,6 +63,9 @@ type Interface interface {
 	// IterateAddresses is used to iterate over chunks addresses for
 	// the manifest.
 	IterateAddresses(context.Context, AddressIterFunc) error
+	// IterateEntries is used to iterate over all the entries of the
+	// manifest together with their paths.
+	IterateEntries(context.Context, EntryIterFunc) error
 }
 
 // Entry represents a single manifest entry.
@@ -102,6 +102,18 @@ func (m *mantarayManifest) HasPrefix(ctx context.Context, prefix string) (bool,
 	return m.trie.HasPrefix(ctx, p, m.ls)
 }
 
+func (m *mantarayManifest) seek(ctx context.Context, prefix, from string) (string, Entry, error) {
+	path, node, err := m.trie.Seek(ctx, []byte(prefix), []byte(from), m.ls)
+	if err != nil {
+		if errors.Is(err, mantaray.ErrNotFound) {
+			return "", nil, ErrNotFound
+		}
+		return "", nil, err
+	}
+
+	return string(path), NewEntry(cluster.NewAddress(node.Entry()), node.Metadata()), nil
+}
+
 func (m *mantarayManifest) Store(ctx context.Context, storeSizeFn ...StoreSizeFunc) (cluster.Address, error) {
 	var ls mantaray.LoadSaver
 	if len(storeSizeFn) > 0 {
@@ -175,6 +187,51 @@ func (m *mantarayManifest) IterateAddresses(ctx context.Context, fn AddressIterF
 	return nil
 }
 
+func (m *mantarayManifest) IterateEntries(ctx context.Context, fn EntryIterFunc) error {
+	walker := func(path []byte, node *mantaray.Node, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if node == nil || !node.IsValueType() {
+			return nil
+		}
+
+		return fn(string(path), NewEntry(cluster.NewAddress(node.Entry()), node.Metadata()))
+	}
+
+	err := m.trie.WalkNode(ctx, []byte{}, m.ls, walker)
+	if err != nil {
+		return fmt.Errorf("manifest iterate entries: %w", err)
+	}
+
+	return nil
+}
+
+func (m *mantarayManifest) diffEntries(ctx context.Context, other Interface) (oldEntries, newEntries map[string]Entry, ok bool, err error) {
+	o, ok := other.(*mantarayManifest)
+	if !ok {
+		return nil, nil, false, nil
+	}
+
+	oldEntries = make(map[string]Entry)
+	newEntries = make(map[string]Entry)
+	add := func(entries map[string]Entry, path []byte, node *mantaray.Node) {
+		if node != nil && node.IsValueType() {
+			entries[string(path)] = NewEntry(cluster.NewAddress(node.Entry()), node.Metadata())
+		}
+	}
+	err = mantaray.WalkDiff(ctx, m.trie, o.trie, m.ls, o.ls, func(path []byte, a, b *mantaray.Node) error {
+		add(oldEntries, path, a)
+		add(newEntries, path, b)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("manifest diff: %w", err)
+	}
+	return oldEntries, newEntries, true, nil
+}
+
 type mantarayLoadSaver struct {
 	ls          file.LoadSaver
 	storeSizeFn []StoreSizeFunc
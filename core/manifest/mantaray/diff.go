@@ -0,0 +1,68 @@
+package mantaray
+
+import (
+	"bytes"
+	"context"
+)
+
+// WalkDiffFunc is the type of the function called by WalkDiff for the
+// nodes which may differ. The node of a trie is nil if the path is only
+// in the other trie.
+type WalkDiffFunc func(path []byte, a, b *Node) error
+
+// WalkDiff walks the node tree structures rooted at a and b in parallel,
+// calling walkFn for the nodes on the same path. The subtrees with equal
+// references are identical, so they are skipped without being loaded.
+// The subtrees of forks which are split differently in the two tries are
+// walked separately.
+func WalkDiff(ctx context.Context, a, b *Node, la, lb Loader, walkFn WalkDiffFunc) error {
+	return walkDiff(ctx, []byte{}, a, b, la, lb, walkFn)
+}
+
+func walkDiff(ctx context.Context, path []byte, a, b *Node, la, lb Loader, walkFn WalkDiffFunc) error {
+	if a.ref != nil && bytes.Equal(a.ref, b.ref) {
+		return nil
+	}
+	if a.forks == nil {
+		if err := a.load(ctx, la); err != nil {
+			return err
+		}
+	}
+	if b.forks == nil {
+		if err := b.load(ctx, lb); err != nil {
+			return err
+		}
+	}
+
+	if err := walkFn(append(path[:0:0], path...), a, b); err != nil {
+		return err
+	}
+
+	for k, fa := range a.forks {
+		fb, ok := b.forks[k]
+		if ok && bytes.Equal(fa.prefix, fb.prefix) {
+			nextPath := append(path[:0:0], path...)
+			nextPath = append(nextPath, fa.prefix...)
+			if err := walkDiff(ctx, nextPath, fa.Node, fb.Node, la, lb, walkFn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := walkNode(ctx, append(append(path[:0:0], path...), fa.prefix...), la, fa.Node, func(p []byte, n *Node, _ error) error {
+			return walkFn(p, n, nil)
+		}); err != nil {
+			return err
+		}
+	}
+	for k, fb := range b.forks {
+		if fa, ok := a.forks[k]; ok && bytes.Equal(fa.prefix, fb.prefix) {
+			continue
+		}
+		if err := walkNode(ctx, append(append(path[:0:0], path...), fb.prefix...), lb, fb.Node, func(p []byte, n *Node, _ error) error {
+			return walkFn(p, nil, n)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
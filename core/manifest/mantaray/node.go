@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 )
 
 const (
@@ -216,8 +217,9 @@ func (n *Node) Add(ctx context.Context, path, entry []byte, metadata map[string]
 		if err := n.load(ctx, ls); err != nil {
 			return err
 		}
-		n.ref = nil
 	}
+	// the node is modified, it has to be persisted again
+	n.ref = nil
 	f := n.forks[path[0]]
 	if f == nil {
 		nn := New()
@@ -313,10 +315,38 @@ func (n *Node) Remove(ctx context.Context, path []byte, ls LoadSaver) error {
 	rest := path[len(f.prefix):]
 	if len(rest) == 0 {
 		// full path matched
-		delete(n.forks, path[0])
+		if f.Node.forks == nil {
+			if err := f.Node.load(ctx, ls); err != nil {
+				return err
+			}
+		}
+		if len(f.Node.forks) == 0 {
+			delete(n.forks, path[0])
+			n.ref = nil
+			return nil
+		}
+		// keep the paths under the node, only its value is removed
+		if !f.Node.IsValueType() {
+			return ErrNotFound
+		}
+		f.Node.entry = nil
+		f.Node.metadata = nil
+		f.Node.makeNotValue()
+		f.Node.makeNotWithMetadata()
+		f.Node.ref = nil
+		n.ref = nil
 		return nil
 	}
-	return f.Node.Remove(ctx, rest, ls)
+	if err := f.Node.Remove(ctx, rest, ls); err != nil {
+		return err
+	}
+	// drop the fork if there is nothing left under it
+	if len(f.Node.forks) == 0 && !f.Node.IsValueType() {
+		delete(n.forks, path[0])
+	}
+	// the node is modified, it has to be persisted again
+	n.ref = nil
+	return nil
 }
 
 func common(a, b []byte) (c []byte) {
@@ -354,3 +384,61 @@ func (n *Node) HasPrefix(ctx context.Context, path []byte, l Loader) (bool, erro
 	}
 	return false, nil
 }
+
+// Seek returns the first path in ascending order which has the given prefix
+// and is not smaller than from, together with its value node. Only the forks
+// which may hold such a path are loaded. ErrNotFound is returned if there is
+// no such path.
+func (n *Node) Seek(ctx context.Context, prefix, from []byte, l Loader) ([]byte, *Node, error) {
+	path, node, err := n.seek(ctx, nil, prefix, from, l)
+	if err != nil {
+		return nil, nil, err
+	}
+	if node == nil {
+		return nil, nil, notFound(prefix)
+	}
+	return path, node, nil
+}
+
+func (n *Node) seek(ctx context.Context, path, prefix, from []byte, l Loader) ([]byte, *Node, error) {
+	select {
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	default:
+	}
+	if n.forks == nil {
+		if err := n.load(ctx, l); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(path) > 0 && n.IsValueType() && bytes.HasPrefix(path, prefix) && bytes.Compare(path, from) >= 0 {
+		return path, n, nil
+	}
+
+	keys := make([]int, 0, len(n.forks))
+	for k := range n.forks {
+		keys = append(keys, int(k))
+	}
+	sort.Ints(keys)
+
+	for _, k := range keys {
+		f := n.forks[byte(k)]
+		next := append(path[:len(path):len(path)], f.prefix...)
+		// the paths of the fork do not have the prefix
+		if !bytes.HasPrefix(next, prefix) && !bytes.HasPrefix(prefix, next) {
+			continue
+		}
+		// the paths of the fork are all smaller than from
+		if bytes.Compare(next, from) < 0 && !bytes.HasPrefix(from, next) {
+			continue
+		}
+		p, node, err := f.Node.seek(ctx, next, prefix, from, l)
+		if err != nil {
+			return nil, nil, err
+		}
+		if node != nil {
+			return p, node, nil
+		}
+	}
+	return nil, nil, nil
+}
@@ -194,6 +194,7 @@ func TestRemove(t *testing.T) {
 		name     string
 		toAdd    []mantaray.NodeEntry
 		toRemove [][]byte
+		toKeep   [][]byte
 	}{
 		{
 			name: "simple",
@@ -244,6 +245,27 @@ func TestRemove(t *testing.T) {
 				[]byte("img/2/test1.png"),
 			},
 		},
+		{
+			name: "path-with-descendants",
+			toAdd: []mantaray.NodeEntry{
+				{
+					Path: []byte("a"),
+				},
+				{
+					Path: []byte("a.txt"),
+				},
+				{
+					Path: []byte("a/b.txt"),
+				},
+			},
+			toRemove: [][]byte{
+				[]byte("a"),
+			},
+			toKeep: [][]byte{
+				[]byte("a.txt"),
+				[]byte("a/b.txt"),
+			},
+		},
 	} {
 		ctx := context.Background()
 		t.Run(tc.name, func(t *testing.T) {
@@ -285,6 +307,11 @@ func TestRemove(t *testing.T) {
 				}
 			}
 
+			for _, c := range tc.toKeep {
+				if _, err := n.Lookup(ctx, c, nil); err != nil {
+					t.Fatalf("lookup %s: expected no error, got %v", c, err)
+				}
+			}
 		})
 	}
 }
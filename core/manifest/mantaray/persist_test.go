@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/sha256"
+	"errors"
 	"sync"
 	"testing"
 
@@ -56,6 +57,52 @@ func TestPersistIdempotence(t *testing.T) {
 	}
 }
 
+func TestPersistRemove(t *testing.T) {
+	ctx := context.Background()
+	var ls mantaray.LoadSaver = newMockLoadSaver()
+
+	n := mantaray.New()
+	paths := [][]byte{
+		[]byte("index.html"),
+		[]byte("img/1.png"),
+		[]byte("img/2.png"),
+	}
+	for _, c := range paths {
+		var v [32]byte
+		copy(v[:], c)
+		if err := n.Add(ctx, c, v[:], nil, ls); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if err := n.Save(ctx, ls); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// modify a manifest loaded by reference
+	n = mantaray.NewNodeRef(n.Reference())
+	for _, c := range paths[1:] {
+		if err := n.Remove(ctx, c, ls); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if err := n.Save(ctx, ls); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	n = mantaray.NewNodeRef(n.Reference())
+	if _, err := n.Lookup(ctx, paths[0], ls); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, c := range paths[1:] {
+		if _, err := n.Lookup(ctx, c, ls); !errors.Is(err, mantaray.ErrNotFound) {
+			t.Fatalf("expected not found error, got %v", err)
+		}
+	}
+	if ok, err := n.HasPrefix(ctx, []byte("img/"), ls); err != nil || ok {
+		t.Fatalf("expected no prefix, got %v, %v", ok, err)
+	}
+}
+
 type addr [32]byte
 type mockLoadSaver struct {
 	mtx   sync.Mutex
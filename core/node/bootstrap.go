@@ -102,6 +102,7 @@ func bootstrapNode(
 		PrivateKey:     libp2pPrivateKey,
 		NATAddr:        o.NATAddr,
 		EnableWS:       o.EnableWS,
+		EnableQUIC:     o.EnableQUIC,
 		WelcomeMessage: o.WelcomeMessage,
 		FullNode:       false,
 		Nonce:          nonce,
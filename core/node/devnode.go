@@ -16,6 +16,8 @@ import (
 	mop "github.com/redesblock/mop/core/address"
 	"github.com/redesblock/mop/core/api"
 	"github.com/redesblock/mop/core/api/auth"
+	"github.com/redesblock/mop/core/availability"
+	mockAvailability "github.com/redesblock/mop/core/availability/mock"
 	"github.com/redesblock/mop/core/chain/transaction"
 	"github.com/redesblock/mop/core/chain/transaction/backendmock"
 	transactionmock "github.com/redesblock/mop/core/chain/transaction/mock"
@@ -390,6 +392,23 @@ func NewDevMop(logger log.Logger, o *DevOptions) (b *DevMop, err error) {
 	mockResolver := resolverMock.NewResolver()
 	mockPinning := pinning.NewServiceMock()
 	mockSteward := new(mockSteward.Steward)
+	// the dev node has no peers, so only the root chunk in the local store is checked
+	mockAvailability := mockAvailability.New(func(ctx context.Context, root cluster.Address) (*availability.Report, error) {
+		has, err := storer.Has(ctx, root)
+		if err != nil {
+			return nil, err
+		}
+		report := &availability.Report{
+			Root:     root,
+			Chunks:   []availability.ChunkStatus{{Address: root, Retrievable: has}},
+			Complete: true,
+		}
+		if has {
+			report.Retrievable = 1
+			report.Health = 1
+		}
+		return report, nil
+	})
 
 	debugOpts := api.ExtraOptions{
 		Pingpong:         pingPong,
@@ -410,6 +429,7 @@ func NewDevMop(logger log.Logger, o *DevOptions) (b *DevMop, err error) {
 		Post:             post,
 		VoucherContract:  voucherContract,
 		Warden:           mockSteward,
+		Availability:     mockAvailability,
 		SyncStatus:       syncStatusFn,
 	}
 
@@ -34,13 +34,16 @@ import (
 	ma "github.com/multiformats/go-multiaddr"
 	"github.com/redesblock/mop/core/address"
 	"github.com/redesblock/mop/core/api"
+	"github.com/redesblock/mop/core/api/admission"
 	"github.com/redesblock/mop/core/api/auth"
+	"github.com/redesblock/mop/core/availability"
 	"github.com/redesblock/mop/core/chain/config"
 	chainsyncer "github.com/redesblock/mop/core/chain/syncer"
 	"github.com/redesblock/mop/core/chain/transaction"
 	"github.com/redesblock/mop/core/cluster"
 	"github.com/redesblock/mop/core/crypto"
 	"github.com/redesblock/mop/core/feeds/factory"
+	"github.com/redesblock/mop/core/gateway"
 	"github.com/redesblock/mop/core/incentives/bookkeeper"
 	"github.com/redesblock/mop/core/incentives/settlement/swap"
 	"github.com/redesblock/mop/core/incentives/settlement/swap/chequebook"
@@ -54,6 +57,7 @@ import (
 	"github.com/redesblock/mop/core/log"
 	"github.com/redesblock/mop/core/metrics"
 	"github.com/redesblock/mop/core/p2p"
+	"github.com/redesblock/mop/core/p2p/connmgr"
 	"github.com/redesblock/mop/core/p2p/libp2p"
 	"github.com/redesblock/mop/core/p2p/topology"
 	"github.com/redesblock/mop/core/p2p/topology/depthmonitor"
@@ -62,6 +66,7 @@ import (
 	"github.com/redesblock/mop/core/pins"
 	"github.com/redesblock/mop/core/pricer"
 	"github.com/redesblock/mop/core/protocol/chainsync"
+	"github.com/redesblock/mop/core/protocol/has"
 	"github.com/redesblock/mop/core/protocol/hive"
 	"github.com/redesblock/mop/core/protocol/pingpong"
 	"github.com/redesblock/mop/core/protocol/pricing"
@@ -73,14 +78,19 @@ import (
 	"github.com/redesblock/mop/core/psser"
 	"github.com/redesblock/mop/core/puller"
 	"github.com/redesblock/mop/core/pusher"
+	"github.com/redesblock/mop/core/reseeder"
 	"github.com/redesblock/mop/core/resolver/multiresolver"
+	"github.com/redesblock/mop/core/storer/coldstore"
 	"github.com/redesblock/mop/core/storer/localstore"
 	"github.com/redesblock/mop/core/storer/netstore"
 	"github.com/redesblock/mop/core/storer/shed"
 	"github.com/redesblock/mop/core/storer/storage"
 	"github.com/redesblock/mop/core/tags"
+	"github.com/redesblock/mop/core/tenant"
 	"github.com/redesblock/mop/core/tracer"
+	"github.com/redesblock/mop/core/traffic"
 	"github.com/redesblock/mop/core/traverser"
+	"github.com/redesblock/mop/core/usage"
 	"github.com/redesblock/mop/core/util"
 	"github.com/redesblock/mop/core/util/ioutil"
 	"github.com/redesblock/mop/core/warden"
@@ -97,6 +107,7 @@ type Mop struct {
 	p2pCancel                context.CancelFunc
 	apiCloser                []io.Closer
 	apiServer                []*http.Server
+	apiServiceCloser         io.Closer
 	debugAPIServer           *http.Server
 	resolverCloser           io.Closer
 	errorLogWriter           io.Writer
@@ -121,6 +132,13 @@ type Mop struct {
 	hiveCloser               io.Closer
 	chainSyncerCloser        io.Closer
 	depthMonitorCloser       io.Closer
+	reseederCloser           io.Closer
+	usageCloser              io.Closer
+	pinCollectionsCloser     io.Closer
+	trafficExporterCloser    io.Closer
+	gatewayServer            *http.Server
+	gatewayCloser            io.Closer
+	connManagerCloser        io.Closer
 	shutdownInProgress       bool
 	shutdownMutex            sync.Mutex
 	syncingStopped           *util.Signaler
@@ -134,11 +152,23 @@ type Options struct {
 	DBWriteBufferSize          uint64
 	DBBlockCacheCapacity       uint64
 	DBDisableSeeksCompaction   bool
+	DBBackend                  string
+	DBVerifyInterval           time.Duration
+	DBVerifyRate               int
+	ColdStoreDir               string
+	ColdStoreS3Endpoint        string
+	ColdStoreS3Region          string
+	ColdStoreS3Bucket          string
+	ColdStoreS3AccessKey       string
+	ColdStoreS3SecretKey       string
+	ColdStoreS3Prefix          string
+	ColdAfter                  time.Duration
 	APIAddr                    []string
 	DebugAPIAddr               string
 	Addr                       string
 	NATAddr                    string
 	EnableWS                   bool
+	EnableQUIC                 bool
 	WelcomeMessage             string
 	Bootnodes                  []string
 	CORSAllowedOrigins         []string
@@ -175,6 +205,7 @@ type Options struct {
 	StaticNodes                []cluster.Address
 	AllowPrivateCIDRs          bool
 	Restricted                 bool
+	DNSLinkGateway             bool
 	TokenEncryptionKey         string
 	AdminPasswordHash          string
 	UseVoucherSnapshot         bool
@@ -182,6 +213,28 @@ type Options struct {
 	TrustNode                  bool
 	TLSCertFile                string
 	TLSKeyFile                 string
+	AddressBookTTL             time.Duration
+	ReseedInterval             time.Duration
+	ReseedRate                 float64
+	ReseedBudget               uint64
+	ConnectionsLowWatermark    int
+	ConnectionsHighWatermark   int
+	RelayService               bool
+	StaticRelays               []string
+	HolePunching               bool
+	GatewayAddr                string
+	GatewayAllowedOrigins      []string
+	RoutingLatencyTradeoff     uint8
+	UsageAccounting            bool
+	UsageRetention             time.Duration
+	TrafficExportURL           string
+	TrafficExportCAFiles       []string
+	APIUploadConcurrency       int
+	APIUploadQueue             int
+	APIDownloadConcurrency     int
+	APIDownloadQueue           int
+	APIQueueTimeout            time.Duration
+	APIRequestDeadline         time.Duration
 }
 
 func (cfg Options) KeyFile() string {
@@ -271,7 +324,7 @@ func NewMop(interrupt chan struct{}, sysInterrupt chan os.Signal, addr string, p
 		}
 	}(b)
 
-	stateStore, err := InitStateStore(logger, o.DataDir)
+	stateStore, err := InitStateStore(logger, o.DataDir, o.DBBackend)
 	if err != nil {
 		return nil, err
 	}
@@ -284,7 +337,7 @@ func NewMop(interrupt chan struct{}, sysInterrupt chan os.Signal, addr string, p
 		return nil, fmt.Errorf("batchstore: exists: %w", err)
 	}
 
-	addressbook := address.New(stateStore)
+	addressbook := address.New(stateStore, address.WithTTL(o.AddressBookTTL))
 
 	var (
 		chainBackend       transaction.Backend
@@ -550,7 +603,7 @@ func NewMop(interrupt chan struct{}, sysInterrupt chan os.Signal, addr string, p
 		nonce = make([]byte, 32)
 	}
 
-	existingOverlay, err := GetExistingOverlay(stateStore)
+	existingOverlay, err := GetExistingOverlay(stateStore, SecureOverlayKey)
 	if err != nil {
 		if !errors.Is(err, storage.ErrNotFound) {
 			return nil, fmt.Errorf("get existing overlay: %w", err)
@@ -684,10 +737,14 @@ func NewMop(interrupt chan struct{}, sysInterrupt chan os.Signal, addr string, p
 		PrivateKey:      libp2pPrivateKey,
 		NATAddr:         o.NATAddr,
 		EnableWS:        o.EnableWS,
+		EnableQUIC:      o.EnableQUIC,
 		WelcomeMessage:  o.WelcomeMessage,
 		FullNode:        o.FullNodeMode,
 		Nonce:           nonce,
 		ValidateOverlay: chainEnabled,
+		RelayService:    o.RelayService && o.FullNodeMode,
+		StaticRelays:    o.StaticRelays,
+		HolePunching:    o.HolePunching,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("p2p service: %w", err)
@@ -714,6 +771,31 @@ func NewMop(interrupt chan struct{}, sysInterrupt chan os.Signal, addr string, p
 		BlockCacheCapacity:     o.DBBlockCacheCapacity,
 		WriteBufferSize:        o.DBWriteBufferSize,
 		DisableSeeksCompaction: o.DBDisableSeeksCompaction,
+		Backend:                o.DBBackend,
+		ColdAfter:              o.ColdAfter,
+		VerifyInterval:         o.DBVerifyInterval,
+		VerifyRate:             o.DBVerifyRate,
+	}
+	switch {
+	case o.ColdStoreDir != "" && o.ColdStoreS3Endpoint != "":
+		return nil, errors.New("cold store directory and S3 endpoint are mutually exclusive")
+	case o.ColdStoreDir != "":
+		lo.ColdStore, err = coldstore.NewDirStore(o.ColdStoreDir)
+		if err != nil {
+			return nil, fmt.Errorf("cold store: %w", err)
+		}
+	case o.ColdStoreS3Endpoint != "":
+		lo.ColdStore, err = coldstore.NewS3Store(coldstore.S3Options{
+			Endpoint:  o.ColdStoreS3Endpoint,
+			Region:    o.ColdStoreS3Region,
+			Bucket:    o.ColdStoreS3Bucket,
+			AccessKey: o.ColdStoreS3AccessKey,
+			SecretKey: o.ColdStoreS3SecretKey,
+			Prefix:    o.ColdStoreS3Prefix,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cold store: %w", err)
+		}
 	}
 
 	storer, err := localstore.New(path, clusterAddress.Bytes(), stateStore, lo, logger)
@@ -845,8 +927,23 @@ func NewMop(interrupt chan struct{}, sysInterrupt chan os.Signal, addr string, p
 		return nil, fmt.Errorf("unable to create metrics storage for kademlia: %w", err)
 	}
 
-	kad, err := kademlia.New(clusterAddress, addressbook, hive, p2ps, pingPong, metricsDB, logger,
-		kademlia.Options{Bootnodes: bootnodes, BootnodeMode: o.BootnodeMode, StaticNodes: o.StaticNodes, IgnoreRadius: !chainEnabled})
+	kadOptions := kademlia.Options{Bootnodes: bootnodes, BootnodeMode: o.BootnodeMode, StaticNodes: o.StaticNodes, IgnoreRadius: !chainEnabled, LatencyTradeoff: o.RoutingLatencyTradeoff}
+
+	var connManager *connmgr.Service
+	if o.ConnectionsHighWatermark > 0 && !o.BootnodeMode {
+		connManager, err = connmgr.New(p2ps, logger, connmgr.Options{
+			LowWatermark:  o.ConnectionsLowWatermark,
+			HighWatermark: o.ConnectionsHighWatermark,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("connection manager: %w", err)
+		}
+		b.connManagerCloser = connManager
+		p2ps.SetConnectionManager(connManager)
+		kadOptions.ConnectionsSaturatedFunc = connManager.Saturated
+	}
+
+	kad, err := kademlia.New(clusterAddress, addressbook, hive, p2ps, pingPong, metricsDB, logger, kadOptions)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create kademlia: %w", err)
 	}
@@ -967,6 +1064,25 @@ func NewMop(interrupt chan struct{}, sysInterrupt chan os.Signal, addr string, p
 
 	acc.SetRefreshFunc(pseudosettleService.Pay)
 
+	if connManager != nil {
+		staticNodes := make(map[string]struct{}, len(o.StaticNodes))
+		for _, addr := range o.StaticNodes {
+			staticNodes[addr.ByteString()] = struct{}{}
+		}
+		// the static peers, the neighbours, the peers we are settling with
+		// and the peers that owe us are not trimmed
+		connManager.SetProtectFunc(func(peer cluster.Address) bool {
+			if _, ok := staticNodes[peer.ByteString()]; ok {
+				return true
+			}
+			if kad.IsWithinDepth(peer) || acc.Settling(peer) {
+				return true
+			}
+			debt, err := acc.PeerDebt(peer)
+			return err == nil && debt.Sign() > 0
+		})
+	}
+
 	if o.SwapEnable && chainEnabled {
 		var priceOracle priceoracle.Service
 		swapService, priceOracle, err = InitSwap(
@@ -996,6 +1112,9 @@ func NewMop(interrupt chan struct{}, sysInterrupt chan os.Signal, addr string, p
 	pricing.SetPaymentThresholdObserver(acc)
 
 	retrieve := retrieval.New(clusterAddress, storer, p2ps, kad, logger, acc, pricer, tracer, o.RetrievalCaching, validStamp)
+	storer.SetRefetchFunc(func(ctx context.Context, addr cluster.Address) (cluster.Chunk, error) {
+		return retrieve.RetrieveChunk(ctx, addr, cluster.ZeroAddress)
+	})
 	tagService := tags.NewTags(stateStore, logger)
 	b.tagsCloser = tagService
 
@@ -1019,7 +1138,7 @@ func NewMop(interrupt chan struct{}, sysInterrupt chan os.Signal, addr string, p
 
 	pullStorage := pullstorage.New(storer)
 
-	pullSyncProtocol := pullsync.New(p2ps, pullStorage, pssService.TryUnwrap, validStamp, logger)
+	pullSyncProtocol := pullsync.New(clusterAddress, p2ps, pullStorage, pssService.TryUnwrap, validStamp, logger)
 	b.pullSyncCloser = pullSyncProtocol
 
 	var pullerService *puller.Puller
@@ -1051,6 +1170,11 @@ func NewMop(interrupt chan struct{}, sysInterrupt chan os.Signal, addr string, p
 		return nil, fmt.Errorf("pullsync protocol: %w", err)
 	}
 
+	hasService := has.New(p2ps, storer, logger)
+	if err = p2ps.AddProtocol(hasService.Protocol()); err != nil {
+		return nil, fmt.Errorf("has protocol: %w", err)
+	}
+
 	if o.FullNodeMode {
 		depthMonitor := depthmonitor.New(kad, pullSyncProtocol, storer, batchStore, logger, warmupTime, depthmonitor.DefaultWakeupInterval)
 		b.depthMonitorCloser = depthMonitor
@@ -1059,6 +1183,7 @@ func NewMop(interrupt chan struct{}, sysInterrupt chan os.Signal, addr string, p
 	multiResolver := multiresolver.NewMultiResolver(
 		multiresolver.WithConnectionConfigs(o.ResolverConnectionCfgs),
 		multiresolver.WithLogger(o.Logger),
+		multiresolver.WithChunkGetter(netStorer),
 		multiresolver.WithDefaultCIDResolver(),
 	)
 	b.resolverCloser = multiResolver
@@ -1082,6 +1207,79 @@ func NewMop(interrupt chan struct{}, sysInterrupt chan os.Signal, addr string, p
 
 	feedFactory := factory.New(netStorer)
 	warden := warden.New(storer, traversalService, retrieve, pushSyncProtocol)
+	availabilityProbe := availability.New(retrieve, hasService, kad, logger, availability.Options{})
+
+	var reseederService *reseeder.Service
+	if o.ReseedInterval > 0 {
+		reseederService = reseeder.New(storer, pinningService, hasService, kad, pushSyncProtocol, pricer, logger, reseeder.Options{
+			Interval: o.ReseedInterval,
+			Rate:     o.ReseedRate,
+			Budget:   o.ReseedBudget,
+		})
+		b.reseederCloser = reseederService
+	}
+
+	var gatewayService *gateway.Service
+	if o.GatewayAddr != "" {
+		gatewayService = gateway.New(retrieve, pushSyncProtocol, pssService, pricer, validStamp, storer, stateStore, logger, gateway.Options{
+			AllowedOrigins: o.GatewayAllowedOrigins,
+			RefreshRate:    uint64(lightRefreshRate),
+		})
+		gatewayServer := &http.Server{
+			IdleTimeout:       30 * time.Second,
+			ReadHeaderTimeout: 3 * time.Second,
+			Handler:           gatewayService,
+			ErrorLog:          stdlog.New(b.errorLogWriter, "", 0),
+		}
+
+		gatewayListener, err := net.Listen("tcp", o.GatewayAddr)
+		if err != nil {
+			return nil, fmt.Errorf("gateway listener: %w", err)
+		}
+
+		go func() {
+			logger.Info("starting gateway server", "address", gatewayListener.Addr(), "tls", o.IsTLSEnabled())
+			if o.IsTLSEnabled() {
+				if err := gatewayServer.ServeTLS(gatewayListener, o.CertFile(), o.KeyFile()); err != nil && err != http.ErrServerClosed {
+					logger.Debug("gateway server failed to start", "error", err)
+					logger.Error(nil, "gateway server failed to start")
+				}
+			} else {
+				if err := gatewayServer.Serve(gatewayListener); err != nil && err != http.ErrServerClosed {
+					logger.Debug("gateway server failed to start", "error", err)
+					logger.Error(nil, "gateway server failed to start")
+				}
+			}
+		}()
+
+		b.gatewayServer = gatewayServer
+		b.gatewayCloser = gatewayService
+	}
+
+	trafficExportURL := o.TrafficExportURL
+	if host := os.Getenv("MOP_TRAFFIC_HOST"); trafficExportURL == "" && host != "" {
+		trafficExportURL = host + "/api/v1/traffic"
+	}
+	var trafficExporter *traffic.Exporter
+	if trafficExportURL != "" {
+		trafficExporter, err = traffic.New(signer, stateStore, logger, traffic.Options{
+			URL:     trafficExportURL,
+			CAFiles: o.TrafficExportCAFiles,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("traffic exporter: %w", err)
+		}
+		b.trafficExporterCloser = trafficExporter
+	}
+
+	var usageService *usage.Service
+	if o.UsageAccounting {
+		usageService = usage.New(stateStore, logger, usage.DefaultFlushInterval, o.UsageRetention)
+		b.usageCloser = usageService
+	}
+
+	pinCollections := pins.NewCollections(stateStore, storer, traversalService, logger)
+	b.pinCollectionsCloser = pinCollections
 
 	extraOpts := api.ExtraOptions{
 		Pingpong:         pingPong,
@@ -1098,12 +1296,17 @@ func NewMop(interrupt chan struct{}, sysInterrupt chan os.Signal, addr string, p
 		Pss:              pssService,
 		TraversalService: traversalService,
 		Pinning:          pinningService,
+		PinCollections:   pinCollections,
 		FeedFactory:      feedFactory,
 		Post:             post,
 		VoucherContract:  voucherContractService,
 		PledgeContract:   pledgeContractService,
 		RewardContract:   rewardContractService,
 		Warden:           warden,
+		Availability:     availabilityProbe,
+		Usage:            usageService,
+		TrafficExporter:  trafficExporter,
+		Tenants:          tenant.New(stateStore),
 		SyncStatus:       syncStatusFn,
 		StoreDirectory: func() string {
 			return filepath.Join(o.DataDir, "uploads", uuid.New().String())
@@ -1120,12 +1323,28 @@ func NewMop(interrupt chan struct{}, sysInterrupt chan os.Signal, addr string, p
 			WsPingPeriod:       60 * time.Second,
 			Restricted:         o.Restricted,
 			NATAddr:            o.NATAddr,
+			DNSLinkGateway:     o.DNSLinkGateway,
+			Admission: map[string]admission.Limits{
+				api.RouteGroupUpload: {
+					Concurrency:  o.APIUploadConcurrency,
+					Queue:        o.APIUploadQueue,
+					QueueTimeout: o.APIQueueTimeout,
+					Deadline:     o.APIRequestDeadline,
+				},
+				api.RouteGroupDownload: {
+					Concurrency:  o.APIDownloadConcurrency,
+					Queue:        o.APIDownloadQueue,
+					QueueTimeout: o.APIQueueTimeout,
+					Deadline:     o.APIRequestDeadline,
+				},
+			},
 		}, extraOpts, chainID, erc20Service)
 
 		pusherService.AddFeed(chunkC)
 
 		apiService.MountAPI()
 		apiService.SetProbe(probe)
+		b.apiServiceCloser = apiService
 
 		if !o.Restricted {
 			for _, apiAddr := range o.APIAddr {
@@ -1205,6 +1424,16 @@ func NewMop(interrupt chan struct{}, sysInterrupt chan os.Signal, addr string, p
 		debugService.MustRegisterMetrics(pullSyncProtocol.Metrics()...)
 		debugService.MustRegisterMetrics(pullStorage.Metrics()...)
 		debugService.MustRegisterMetrics(retrieve.Metrics()...)
+		debugService.MustRegisterMetrics(hasService.Metrics()...)
+		if connManager != nil {
+			debugService.MustRegisterMetrics(connManager.Metrics()...)
+		}
+		if reseederService != nil {
+			debugService.MustRegisterMetrics(reseederService.Metrics()...)
+		}
+		if gatewayService != nil {
+			debugService.MustRegisterMetrics(gatewayService.Metrics()...)
+		}
 		debugService.MustRegisterMetrics(lightNodes.Metrics()...)
 		debugService.MustRegisterMetrics(hive.Metrics()...)
 
@@ -1232,6 +1461,9 @@ func NewMop(interrupt chan struct{}, sysInterrupt chan os.Signal, addr string, p
 			debugService.MustRegisterMetrics(nsMetrics.Metrics()...)
 		}
 		debugService.MustRegisterMetrics(pseudosettleService.Metrics()...)
+		if trafficExporter != nil {
+			debugService.MustRegisterMetrics(trafficExporter.Metrics()...)
+		}
 		if swapService != nil {
 			debugService.MustRegisterMetrics(swapService.Metrics()...)
 		}
@@ -1317,6 +1549,14 @@ func (b *Mop) Shutdown() error {
 			})
 		}
 	}
+	if b.gatewayServer != nil {
+		eg.Go(func() error {
+			if err := b.gatewayServer.Shutdown(ctx); err != nil {
+				return fmt.Errorf("gateway server: %w", err)
+			}
+			return nil
+		})
+	}
 	if b.debugAPIServer != nil {
 		eg.Go(func() error {
 			if err := b.debugAPIServer.Shutdown(ctx); err != nil {
@@ -1330,6 +1570,14 @@ func (b *Mop) Shutdown() error {
 		mErr = multierror.Append(mErr, err)
 	}
 
+	tryClose(b.apiServiceCloser, "api service")
+	tryClose(b.gatewayCloser, "gateway")
+	tryClose(b.reseederCloser, "reseeder")
+	tryClose(b.usageCloser, "usage")
+	tryClose(b.pinCollectionsCloser, "pin collections")
+	tryClose(b.trafficExporterCloser, "traffic exporter")
+	tryClose(b.connManagerCloser, "connection manager")
+
 	var wg sync.WaitGroup
 	wg.Add(7)
 	go func() {
@@ -7,27 +7,44 @@ import (
 
 	"github.com/redesblock/mop/core/cluster"
 	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/storer/kv"
 	"github.com/redesblock/mop/core/storer/statestore/leveldb"
 	"github.com/redesblock/mop/core/storer/storage"
 )
 
 // InitStateStore will initialize the stateStore with the given path to the
 // data directory. When given an empty directory path, the function will instead
-// initialize an in-memory state store that will not be persisted.
-func InitStateStore(logger log.Logger, dataDir string) (storage.StateStorer, error) {
+// initialize an in-memory state store that will not be persisted. A new state
+// store uses the given key-value store backend, the default one if empty.
+func InitStateStore(logger log.Logger, dataDir, dbBackend string) (storage.StateStorer, error) {
 	if dataDir == "" {
 		logger.Warning("using in-mem state store, no node state will be persisted")
 		return leveldb.NewInMemoryStateStore(logger)
 	}
-	return leveldb.NewStateStore(filepath.Join(dataDir, "statestore"), logger)
+	return leveldb.NewStateStoreWithBackend(filepath.Join(dataDir, "statestore"), dbBackend, logger)
 }
 
-const secureOverlayKey = "non-mineable-overlay"
-const noncedOverlayKey = "nonce-overlay"
+// InitLocalStore creates the database of the local store in the data directory
+// with the given key-value store backend, if it does not exist, so that the
+// node uses the backend when it starts.
+func InitLocalStore(dataDir, dbBackend string) error {
+	if dataDir == "" {
+		return nil
+	}
+	db, err := kv.Open(filepath.Join(dataDir, "localstore"), &kv.Options{Backend: dbBackend})
+	if err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+const SecureOverlayKey = "non-mineable-overlay"
+const NoncedOverlayKey = "nonce-overlay"
 
-func GetExistingOverlay(storer storage.StateStorer) (cluster.Address, error) {
+// GetExistingOverlay returns the overlay stored in the statestore under the key.
+func GetExistingOverlay(storer storage.StateStorer, key string) (cluster.Address, error) {
 	var storedOverlay cluster.Address
-	err := storer.Get(secureOverlayKey, &storedOverlay)
+	err := storer.Get(key, &storedOverlay)
 	if err != nil {
 		return cluster.ZeroAddress, err
 	}
@@ -39,12 +56,12 @@ func GetExistingOverlay(storer storage.StateStorer) (cluster.Address, error) {
 func CheckOverlayWithStore(overlay cluster.Address, storer storage.StateStorer) error {
 
 	var storedOverlay cluster.Address
-	err := storer.Get(noncedOverlayKey, &storedOverlay)
+	err := storer.Get(NoncedOverlayKey, &storedOverlay)
 	if err != nil {
 		if !errors.Is(err, storage.ErrNotFound) {
 			return err
 		}
-		return storer.Put(noncedOverlayKey, overlay)
+		return storer.Put(NoncedOverlayKey, overlay)
 	}
 
 	if !storedOverlay.Equal(overlay) {
@@ -56,7 +73,7 @@ func CheckOverlayWithStore(overlay cluster.Address, storer storage.StateStorer)
 
 // SetOverlayInStore sets the overlay stored in the statestore (for purpose of overlay migration)
 func SetOverlayInStore(overlay cluster.Address, storer storage.StateStorer) error {
-	return storer.Put(noncedOverlayKey, overlay)
+	return storer.Put(NoncedOverlayKey, overlay)
 }
 
 const OverlayNonce = "overlayV2_nonce"
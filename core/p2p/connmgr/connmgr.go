@@ -0,0 +1,244 @@
+// Package connmgr provides the connection manager which keeps the overall
+// number of connected peers within the connection budget of the node. When
+// the number of connections exceeds the high watermark, the connections are
+// trimmed down to the low watermark, sparing the protected peers and the
+// peers connected within the grace period.
+package connmgr
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/p2p"
+)
+
+// loggerName is the tree path name of the logger for this package.
+const loggerName = "connmgr"
+
+const (
+	// DefaultLowWatermark is the default number of connections the
+	// connections are trimmed down to.
+	DefaultLowWatermark = 150
+	// DefaultHighWatermark is the default number of connections above which
+	// the connections are trimmed.
+	DefaultHighWatermark = 200
+	// DefaultGracePeriod is the default time during which new connections
+	// are not trimmed.
+	DefaultGracePeriod = time.Minute
+	// DefaultTrimInterval is the default time between two checks of the
+	// number of connections.
+	DefaultTrimInterval = 30 * time.Second
+)
+
+// ErrInvalidWatermarks is returned when the low watermark is not lower than
+// the high watermark.
+var ErrInvalidWatermarks = errors.New("low watermark must be lower than high watermark")
+
+// ProtectFunc reports whether the peer must not be disconnected.
+type ProtectFunc func(peer cluster.Address) bool
+
+// PeerDisconnecter lists and disconnects the connected peers.
+type PeerDisconnecter interface {
+	Peers() []p2p.Peer
+	Disconnect(overlay cluster.Address, reason string) error
+}
+
+// Options configure the connection manager.
+type Options struct {
+	// LowWatermark is the number of connections the connections are
+	// trimmed down to.
+	LowWatermark int
+	// HighWatermark is the number of connections above which the
+	// connections are trimmed.
+	HighWatermark int
+	// GracePeriod is the time during which new connections are not trimmed.
+	GracePeriod time.Duration
+	// TrimInterval is the time between two checks of the number of
+	// connections.
+	TrimInterval time.Duration
+}
+
+// Service is the connection manager.
+type Service struct {
+	p2p       PeerDisconnecter
+	logger    log.Logger
+	metrics   metrics
+	low       int
+	high      int
+	grace     time.Duration
+	mu        sync.Mutex
+	connected map[string]time.Time // time of the connection by overlay
+	protect   ProtectFunc
+	now       func() time.Time
+	trimMu    sync.Mutex // only one trimming at a time
+	trimC     chan struct{}
+	quit      chan struct{}
+	stopped   chan struct{}
+}
+
+// New constructs a new connection manager and starts its trimming loop.
+func New(p PeerDisconnecter, logger log.Logger, o Options) (*Service, error) {
+	if o.LowWatermark <= 0 {
+		o.LowWatermark = DefaultLowWatermark
+	}
+	if o.HighWatermark <= 0 {
+		o.HighWatermark = DefaultHighWatermark
+	}
+	if o.LowWatermark >= o.HighWatermark {
+		return nil, ErrInvalidWatermarks
+	}
+	if o.GracePeriod <= 0 {
+		o.GracePeriod = DefaultGracePeriod
+	}
+	if o.TrimInterval <= 0 {
+		o.TrimInterval = DefaultTrimInterval
+	}
+
+	s := &Service{
+		p2p:       p,
+		logger:    logger.WithName(loggerName).Register(),
+		metrics:   newMetrics(),
+		low:       o.LowWatermark,
+		high:      o.HighWatermark,
+		grace:     o.GracePeriod,
+		connected: make(map[string]time.Time),
+		protect:   func(cluster.Address) bool { return false },
+		now:       time.Now,
+		trimC:     make(chan struct{}, 1),
+		quit:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+
+	go s.manage(o.TrimInterval)
+
+	return s, nil
+}
+
+// SetProtectFunc sets the function which tells the peers that must not be
+// disconnected, such as the static and neighbourhood peers or the peers we
+// are settling with.
+func (s *Service) SetProtectFunc(f ProtectFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.protect = f
+}
+
+// Connected implements p2p.ConnectionManager interface. It records the time
+// of the connection and triggers the trimming when the number of connections
+// exceeds the high watermark.
+func (s *Service) Connected(peer cluster.Address) {
+	s.mu.Lock()
+	s.connected[peer.ByteString()] = s.now()
+	s.mu.Unlock()
+
+	if len(s.p2p.Peers()) > s.high {
+		select {
+		case s.trimC <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Saturated reports whether the number of connections reached the high
+// watermark, so that no new connections should be established other than to
+// the neighbourhood peers.
+func (s *Service) Saturated() bool {
+	return len(s.p2p.Peers()) >= s.high
+}
+
+func (s *Service) manage(interval time.Duration) {
+	defer close(s.stopped)
+
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-s.trimC:
+		case <-time.After(interval):
+		}
+		s.Trim()
+	}
+}
+
+// Trim disconnects the peers above the low watermark if the number of
+// connections exceeds the high watermark. The light nodes are disconnected
+// first, and then the peers with the shortest connections. The protected
+// peers and the peers connected within the grace period are kept, even if
+// the low watermark is not reached then.
+func (s *Service) Trim() {
+	s.trimMu.Lock()
+	defer s.trimMu.Unlock()
+
+	peers := s.p2p.Peers()
+
+	s.mu.Lock()
+	now := s.now()
+	protect := s.protect
+	current := make(map[string]time.Time, len(peers))
+	for _, p := range peers {
+		t, ok := s.connected[p.Address.ByteString()]
+		if !ok {
+			t = now
+		}
+		current[p.Address.ByteString()] = t
+	}
+	s.connected = current
+	s.mu.Unlock()
+
+	s.metrics.Connections.Set(float64(len(peers)))
+	if len(peers) <= s.high {
+		return
+	}
+	s.metrics.Trims.Inc()
+
+	type candidate struct {
+		peer        p2p.Peer
+		connectedAt time.Time
+	}
+	var candidates []candidate
+	for _, p := range peers {
+		connectedAt := current[p.Address.ByteString()]
+		if now.Sub(connectedAt) < s.grace {
+			continue
+		}
+		if protect(p.Address) {
+			s.metrics.ProtectedPeers.Inc()
+			continue
+		}
+		candidates = append(candidates, candidate{peer: p, connectedAt: connectedAt})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].peer.FullNode != candidates[j].peer.FullNode {
+			return !candidates[i].peer.FullNode
+		}
+		return candidates[i].connectedAt.After(candidates[j].connectedAt)
+	})
+
+	excess := len(peers) - s.low
+	if excess > len(candidates) {
+		s.logger.Debug("unable to trim connections to the low watermark", "connections", len(peers), "low_watermark", s.low, "candidates", len(candidates))
+		excess = len(candidates)
+	}
+	for _, c := range candidates[:excess] {
+		if err := s.p2p.Disconnect(c.peer.Address, "trimmed by connection manager"); err != nil {
+			s.logger.Debug("trim disconnect failed", "peer_address", c.peer.Address, "error", err)
+			continue
+		}
+		s.metrics.TrimmedPeers.Inc()
+	}
+}
+
+// Close stops the trimming loop.
+func (s *Service) Close() error {
+	close(s.quit)
+	select {
+	case <-s.stopped:
+		return nil
+	case <-time.After(5 * time.Second):
+		return errors.New("stopping connection manager with ongoing worker goroutine")
+	}
+}
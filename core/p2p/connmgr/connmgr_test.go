@@ -0,0 +1,174 @@
+package connmgr_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/cluster/test"
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/p2p"
+	"github.com/redesblock/mop/core/p2p/connmgr"
+)
+
+type peerRegistry struct {
+	mu    sync.Mutex
+	peers []p2p.Peer
+}
+
+func (r *peerRegistry) add(full bool) cluster.Address {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	addr := test.RandomAddress()
+	r.peers = append(r.peers, p2p.Peer{Address: addr, FullNode: full})
+	return addr
+}
+
+func (r *peerRegistry) Peers() []p2p.Peer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]p2p.Peer(nil), r.peers...)
+}
+
+func (r *peerRegistry) Disconnect(overlay cluster.Address, _ string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, p := range r.peers {
+		if p.Address.Equal(overlay) {
+			r.peers = append(r.peers[:i], r.peers[i+1:]...)
+			return nil
+		}
+	}
+	return p2p.ErrPeerNotFound
+}
+
+func (r *peerRegistry) connected(addr cluster.Address) bool {
+	for _, p := range r.Peers() {
+		if p.Address.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clock is the current time of the connection manager.
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newService(t *testing.T, r *peerRegistry, o connmgr.Options) (*connmgr.Service, *clock) {
+	t.Helper()
+
+	if o.TrimInterval == 0 {
+		o.TrimInterval = time.Hour
+	}
+	s, err := connmgr.New(r, log.Noop, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	c := &clock{now: time.Now()}
+	s.SetNowFunc(c.Now)
+	return s, c
+}
+
+func TestTrim(t *testing.T) {
+	t.Run("below high watermark", func(t *testing.T) {
+		r := new(peerRegistry)
+		s, clock := newService(t, r, connmgr.Options{LowWatermark: 2, HighWatermark: 4})
+		for i := 0; i < 4; i++ {
+			s.Connected(r.add(true))
+		}
+		clock.advance(time.Hour)
+
+		s.Trim()
+		if got := len(r.Peers()); got != 4 {
+			t.Fatalf("got %d peers, want %d", got, 4)
+		}
+		if !s.Saturated() {
+			t.Fatal("expected saturated connections")
+		}
+	})
+
+	t.Run("trim to low watermark", func(t *testing.T) {
+		r := new(peerRegistry)
+		s, clock := newService(t, r, connmgr.Options{LowWatermark: 3, HighWatermark: 4, GracePeriod: time.Minute})
+
+		var (
+			oldest    = r.add(true)
+			protected = r.add(true)
+			light     = r.add(false)
+		)
+		s.SetProtectFunc(func(peer cluster.Address) bool {
+			return peer.Equal(protected)
+		})
+		for _, p := range []cluster.Address{oldest, protected, light} {
+			s.Connected(p)
+		}
+		clock.advance(time.Minute)
+		newer := r.add(true)
+		s.Connected(newer)
+		clock.advance(time.Minute)
+		fresh := r.add(true)
+		s.Connected(fresh)
+		clock.advance(time.Second)
+
+		s.Trim()
+
+		if got := len(r.Peers()); got != 3 {
+			t.Fatalf("got %d peers, want %d", got, 3)
+		}
+		for _, p := range []cluster.Address{light, newer} {
+			if r.connected(p) {
+				t.Fatalf("peer %s not trimmed", p)
+			}
+		}
+		for _, p := range []cluster.Address{oldest, protected, fresh} {
+			if !r.connected(p) {
+				t.Fatalf("peer %s trimmed", p)
+			}
+		}
+	})
+
+	t.Run("protected peers", func(t *testing.T) {
+		r := new(peerRegistry)
+		s, clock := newService(t, r, connmgr.Options{LowWatermark: 1, HighWatermark: 2})
+		s.SetProtectFunc(func(cluster.Address) bool { return true })
+		for i := 0; i < 3; i++ {
+			s.Connected(r.add(true))
+		}
+		clock.advance(time.Hour)
+
+		s.Trim()
+		if got := len(r.Peers()); got != 3 {
+			t.Fatalf("got %d peers, want %d", got, 3)
+		}
+	})
+
+	t.Run("invalid watermarks", func(t *testing.T) {
+		_, err := connmgr.New(new(peerRegistry), log.Noop, connmgr.Options{LowWatermark: 4, HighWatermark: 4})
+		if !errors.Is(err, connmgr.ErrInvalidWatermarks) {
+			t.Fatalf("got error %v, want %v", err, connmgr.ErrInvalidWatermarks)
+		}
+	})
+}
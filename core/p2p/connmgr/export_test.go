@@ -0,0 +1,10 @@
+package connmgr
+
+import "time"
+
+// SetNowFunc sets the function that returns the current time.
+func (s *Service) SetNowFunc(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
@@ -0,0 +1,51 @@
+package connmgr
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	m "github.com/redesblock/mop/core/metrics"
+)
+
+type metrics struct {
+	// all metrics fields must be exported
+	// to be able to return them by Metrics()
+	// using reflection
+	Connections    prometheus.Gauge
+	Trims          prometheus.Counter
+	TrimmedPeers   prometheus.Counter
+	ProtectedPeers prometheus.Counter
+}
+
+func newMetrics() metrics {
+	subsystem := "connmgr"
+
+	return metrics{
+		Connections: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "connections",
+			Help:      "Number of connected peers at the last check.",
+		}),
+		Trims: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "trims_count",
+			Help:      "Number of times the connections exceeded the high watermark and were trimmed.",
+		}),
+		TrimmedPeers: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "trimmed_peers_count",
+			Help:      "Number of peers disconnected by trimming.",
+		}),
+		ProtectedPeers: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "protected_peers_count",
+			Help:      "Number of times a protected peer was spared from trimming.",
+		}),
+	}
+}
+
+func (s *Service) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(s.metrics)
+}
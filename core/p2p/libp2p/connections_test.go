@@ -77,6 +77,145 @@ func TestConnectDisconnect(t *testing.T) {
 	expectPeersEventually(t, s1)
 }
 
+func TestReachability(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s1, overlay1 := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		FullNode:     true,
+		RelayService: true,
+		HolePunching: true,
+	}})
+	s2, _ := newService(t, 1, libp2pServiceOpts{})
+
+	if _, err := s2.Connect(ctx, serviceUnderlayAddress(t, s1)); err != nil {
+		t.Fatal(err)
+	}
+	expectPeers(t, s2, overlay1)
+
+	r := s1.Reachability()
+	if !r.RelayService || !r.HolePunching {
+		t.Fatalf("got relay service %v and hole punching %v, want both enabled", r.RelayService, r.HolePunching)
+	}
+	if len(r.RelayAddresses) != 0 {
+		t.Fatalf("got relay addresses %v, want none", r.RelayAddresses)
+	}
+
+	r = s2.Reachability()
+	if r.RelayService || r.HolePunching {
+		t.Fatalf("got relay service %v and hole punching %v, want both disabled", r.RelayService, r.HolePunching)
+	}
+	if r.DirectConnections != 1 || r.RelayedConnections != 0 {
+		t.Fatalf("got %d direct and %d relayed connections, want 1 direct", r.DirectConnections, r.RelayedConnections)
+	}
+}
+
+func TestConnectQUIC(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s1, overlay1 := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		FullNode:   true,
+		EnableQUIC: true,
+	}})
+	s2, overlay2 := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		EnableQUIC: true,
+	}})
+
+	addrs, err := s1.Addresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var addr ma.Multiaddr
+	for _, a := range addrs {
+		if _, err := a.ValueForProtocol(ma.P_QUIC); err == nil {
+			addr = a
+			break
+		}
+	}
+	if addr == nil {
+		t.Fatalf("no quic address in %v", addrs)
+	}
+
+	mopAddress, err := s2.Connect(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectPeers(t, s2, overlay1)
+	expectPeersEventually(t, s1, overlay2)
+
+	// the underlay advertised in the handshake is the observed quic address
+	if _, err := mopAddress.Underlay.ValueForProtocol(ma.P_QUIC); err != nil {
+		t.Fatalf("got underlay %s, want quic address", mopAddress.Underlay)
+	}
+	for _, c := range s2.Host().Network().Conns() {
+		if _, err := c.RemoteMultiaddr().ValueForProtocol(ma.P_QUIC); err != nil {
+			t.Fatalf("got connection over %s, want quic", c.RemoteMultiaddr())
+		}
+	}
+}
+
+func TestConnectQUICWithNATAddr(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s1, overlay1 := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		FullNode:   true,
+		EnableQUIC: true,
+		NATAddr:    ":30123",
+	}})
+	s2, _ := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		EnableQUIC: true,
+	}})
+
+	addrs, err := s1.Addresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var addr ma.Multiaddr
+	for _, a := range addrs {
+		if _, err := a.ValueForProtocol(ma.P_QUIC); err == nil {
+			addr = a
+			break
+		}
+	}
+	if addr == nil {
+		t.Fatalf("no quic address in %v", addrs)
+	}
+
+	mopAddress, err := s2.Connect(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectPeers(t, s2, overlay1)
+
+	// the advertised underlay has the nat port and keeps the quic transport
+	if port, err := mopAddress.Underlay.ValueForProtocol(ma.P_UDP); err != nil || port != "30123" {
+		t.Fatalf("got underlay %s, want udp port 30123", mopAddress.Underlay)
+	}
+	if _, err := mopAddress.Underlay.ValueForProtocol(ma.P_QUIC); err != nil {
+		t.Fatalf("got underlay %s, want quic address", mopAddress.Underlay)
+	}
+}
+
+func TestTransportName(t *testing.T) {
+	for _, tc := range []struct {
+		addr string
+		want string
+	}{
+		{addr: "/ip4/127.0.0.1/tcp/1634", want: "tcp"},
+		{addr: "/ip4/127.0.0.1/tcp/1634/ws", want: "ws"},
+		{addr: "/ip6/::1/udp/1634/quic", want: "quic"},
+		{addr: "/ip4/127.0.0.1/tcp/1634/p2p/16Uiu2HAmTBuJT9LvNmBiQiNoTsxE5mtNy6YG3paw79m94CRa9sRb/p2p-circuit", want: "relay"},
+		{addr: "/ip4/127.0.0.1/udp/1634", want: "other"},
+	} {
+		if got := libp2p.TransportName(ma.StringCast(tc.addr)); got != tc.want {
+			t.Errorf("got transport %q for %s, want %q", got, tc.addr, tc.want)
+		}
+	}
+}
+
 func TestConnectToLightPeer(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -1023,6 +1162,10 @@ func TestReachabilityUpdate(t *testing.T) {
 		t.Fatalf("test timed out")
 	}
 
+	if got := s1.Reachability().Status; got != p2p.ReachabilityStatusPublic {
+		t.Fatalf("got reachability %s, want %s", got, p2p.ReachabilityStatusPublic)
+	}
+
 	secondUpdate := make(chan struct{})
 	s1.SetPickyNotifier(mockReachabilityNotifier(func(status p2p.ReachabilityStatus) {
 		if status == p2p.ReachabilityStatusPrivate {
@@ -27,6 +27,7 @@ type StaticAddressResolver = staticAddressResolver
 var (
 	NewStaticAddressResolver = newStaticAddressResolver
 	UserAgent                = userAgent
+	TransportName            = transportName
 )
 
 func WithHostFactory(factory func(...libp2pm.Option) (host.Host, error)) Options {
@@ -167,6 +167,10 @@ func (s *Service) Handshake(ctx context.Context, stream p2p.Stream, peerMultiadd
 		return nil, err
 	}
 
+	if err := mopAddress.SignRecord(s.signer, s.networkID, time.Now()); err != nil {
+		return nil, err
+	}
+
 	advertisableUnderlayBytes, err := mopAddress.Underlay.MarshalBinary()
 	if err != nil {
 		return nil, err
@@ -185,9 +189,11 @@ func (s *Service) Handshake(ctx context.Context, stream p2p.Stream, peerMultiadd
 	welcomeMessage := s.GetWelcomeMessage()
 	msg := &pb.Ack{
 		Address: &pb.MopAddress{
-			Underlay:  advertisableUnderlayBytes,
-			Overlay:   mopAddress.Overlay.Bytes(),
-			Signature: mopAddress.Signature,
+			Underlay:        advertisableUnderlayBytes,
+			Overlay:         mopAddress.Overlay.Bytes(),
+			Signature:       mopAddress.Signature,
+			Timestamp:       mopAddress.Timestamp,
+			RecordSignature: mopAddress.RecordSignature,
 		},
 		NetworkID:      s.networkID,
 		FullNode:       s.fullNode,
@@ -250,6 +256,10 @@ func (s *Service) Handle(ctx context.Context, stream p2p.Stream, remoteMultiaddr
 		return nil, err
 	}
 
+	if err := mopAddress.SignRecord(s.signer, s.networkID, time.Now()); err != nil {
+		return nil, err
+	}
+
 	advertisableUnderlayBytes, err := mopAddress.Underlay.MarshalBinary()
 	if err != nil {
 		return nil, err
@@ -263,9 +273,11 @@ func (s *Service) Handle(ctx context.Context, stream p2p.Stream, remoteMultiaddr
 		},
 		Ack: &pb.Ack{
 			Address: &pb.MopAddress{
-				Underlay:  advertisableUnderlayBytes,
-				Overlay:   mopAddress.Overlay.Bytes(),
-				Signature: mopAddress.Signature,
+				Underlay:        advertisableUnderlayBytes,
+				Overlay:         mopAddress.Overlay.Bytes(),
+				Signature:       mopAddress.Signature,
+				Timestamp:       mopAddress.Timestamp,
+				RecordSignature: mopAddress.RecordSignature,
 			},
 			NetworkID:      s.networkID,
 			FullNode:       s.fullNode,
@@ -337,5 +349,14 @@ func (s *Service) parseCheckAck(ack *pb.Ack) (*mop.Address, error) {
 		return nil, ErrInvalidAck
 	}
 
+	// peers which do not sign their address records are still accepted
+	if len(ack.Address.RecordSignature) > 0 {
+		mopAddress.Timestamp = ack.Address.Timestamp
+		mopAddress.RecordSignature = ack.Address.RecordSignature
+		if err := mopAddress.VerifyRecord(s.networkID); err != nil {
+			return nil, ErrInvalidAck
+		}
+	}
+
 	return mopAddress, nil
 }
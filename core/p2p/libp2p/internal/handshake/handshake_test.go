@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	mop "github.com/redesblock/mop/core/address"
@@ -385,6 +386,51 @@ func TestHandshake(t *testing.T) {
 		}
 	})
 
+	t.Run("Handshake - invalid address record", func(t *testing.T) {
+		var buffer1 bytes.Buffer
+		var buffer2 bytes.Buffer
+		stream1 := mock.NewStream(&buffer1, &buffer2)
+		stream2 := mock.NewStream(&buffer2, &buffer1)
+
+		record := *node2MopAddress
+		if err := record.SignRecord(signer1, networkID, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		w := protobuf.NewWriter(stream2)
+		if err := w.WriteMsg(&pb.SynAck{
+			Syn: &pb.Syn{
+				ObservedUnderlay: node1maBinary,
+			},
+			Ack: &pb.Ack{
+				Address: &pb.MopAddress{
+					Underlay:        node2maBinary,
+					Overlay:         node2MopAddress.Overlay.Bytes(),
+					Signature:       node2MopAddress.Signature,
+					Timestamp:       record.Timestamp,
+					RecordSignature: record.RecordSignature,
+				},
+				NetworkID: networkID,
+				FullNode:  true,
+				Nonce:     nonce,
+			},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		handshakeService, err := handshake.New(signer1, aaddresser, node1Info.MopAddress.Overlay, networkID, true, nonce, testWelcomeMessage, true, node1AddrInfo.ID, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := handshakeService.Handshake(context.Background(), stream1, node2AddrInfo.Addrs[0], node2AddrInfo.ID)
+		if res != nil {
+			t.Fatal("res should be nil")
+		}
+
+		if err != handshake.ErrInvalidAck {
+			t.Fatalf("expected %s, got %s", handshake.ErrInvalidAck, err)
+		}
+	})
+
 	t.Run("Handshake - error advertisable address", func(t *testing.T) {
 		var buffer1 bytes.Buffer
 		var buffer2 bytes.Buffer
@@ -485,6 +531,68 @@ func TestHandshake(t *testing.T) {
 		})
 	})
 
+	t.Run("Handle - OK quic behind nat", func(t *testing.T) {
+		observed, err := ma.NewMultiaddr("/ip4/127.0.0.1/udp/1684/quic/p2p/16Uiu2HAkx8ULY8cTXhdVAcMmLcH9AsTKz6uBQ7DPLKRjMLgBVYkA")
+		if err != nil {
+			t.Fatal(err)
+		}
+		advertised, err := ma.NewMultiaddr("/ip4/192.168.1.34/udp/30123/quic/p2p/16Uiu2HAkx8ULY8cTXhdVAcMmLcH9AsTKz6uBQ7DPLKRjMLgBVYkA")
+		if err != nil {
+			t.Fatal(err)
+		}
+		observedBinary, err := observed.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		handshakeService, err := handshake.New(signer1, &AdvertisableAddresserMock{advertisableAddress: advertised}, node1Info.MopAddress.Overlay, networkID, true, nonce, "", true, node1AddrInfo.ID, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buffer1 bytes.Buffer
+		var buffer2 bytes.Buffer
+		stream1 := mock.NewStream(&buffer1, &buffer2)
+		stream2 := mock.NewStream(&buffer2, &buffer1)
+
+		w := protobuf.NewWriter(stream2)
+		if err := w.WriteMsg(&pb.Syn{
+			ObservedUnderlay: observedBinary,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := w.WriteMsg(&pb.Ack{
+			Address: &pb.MopAddress{
+				Underlay:  node2maBinary,
+				Overlay:   node2MopAddress.Overlay.Bytes(),
+				Signature: node2MopAddress.Signature,
+			},
+			NetworkID: networkID,
+			Nonce:     nonce,
+			FullNode:  true,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := handshakeService.Handle(context.Background(), stream1, node2AddrInfo.Addrs[0], node2AddrInfo.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		_, r := protobuf.NewWriterAndReader(stream2)
+		var got pb.SynAck
+		if err := r.ReadMsg(&got); err != nil {
+			t.Fatal(err)
+		}
+
+		mopAddress, err := mop.ParseAddress(got.Ack.Address.Underlay, got.Ack.Address.Overlay, got.Ack.Address.Signature, got.Ack.Nonce, true, got.Ack.NetworkID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !mopAddress.Underlay.Equal(advertised) {
+			t.Fatalf("got underlay %s, want %s", mopAddress.Underlay, advertised)
+		}
+	})
+
 	t.Run("Handle - read error ", func(t *testing.T) {
 		handshakeService, err := handshake.New(signer1, aaddresser, node1Info.MopAddress.Overlay, networkID, true, nil, "", true, node1AddrInfo.ID, logger)
 		if err != nil {
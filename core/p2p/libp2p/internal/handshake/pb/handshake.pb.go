@@ -195,9 +195,11 @@ func (m *SynAck) GetAck() *Ack {
 }
 
 type MopAddress struct {
-	Underlay  []byte `protobuf:"bytes,1,opt,name=Underlay,proto3" json:"Underlay,omitempty"`
-	Signature []byte `protobuf:"bytes,2,opt,name=Signature,proto3" json:"Signature,omitempty"`
-	Overlay   []byte `protobuf:"bytes,3,opt,name=Overlay,proto3" json:"Overlay,omitempty"`
+	Underlay        []byte `protobuf:"bytes,1,opt,name=Underlay,proto3" json:"Underlay,omitempty"`
+	Signature       []byte `protobuf:"bytes,2,opt,name=Signature,proto3" json:"Signature,omitempty"`
+	Overlay         []byte `protobuf:"bytes,3,opt,name=Overlay,proto3" json:"Overlay,omitempty"`
+	Timestamp       int64  `protobuf:"varint,4,opt,name=Timestamp,proto3" json:"Timestamp,omitempty"`
+	RecordSignature []byte `protobuf:"bytes,5,opt,name=RecordSignature,proto3" json:"RecordSignature,omitempty"`
 }
 
 func (m *MopAddress) Reset()         { *m = MopAddress{} }
@@ -254,6 +256,20 @@ func (m *MopAddress) GetOverlay() []byte {
 	return nil
 }
 
+func (m *MopAddress) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *MopAddress) GetRecordSignature() []byte {
+	if m != nil {
+		return m.RecordSignature
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Syn)(nil), "handshake.Syn")
 	proto.RegisterType((*Ack)(nil), "handshake.Ack")
@@ -264,27 +280,29 @@ func init() {
 func init() { proto.RegisterFile("handshake.proto", fileDescriptor_a77305914d5d202f) }
 
 var fileDescriptor_a77305914d5d202f = []byte{
-	// 317 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x64, 0x91, 0xdf, 0x4a, 0xc3, 0x30,
-	0x14, 0xc6, 0x97, 0x75, 0xee, 0xcf, 0x71, 0x4c, 0x09, 0x0a, 0x45, 0x46, 0x29, 0xbd, 0x90, 0xe2,
-	0xc5, 0x44, 0x7d, 0x82, 0x89, 0x08, 0x82, 0xdb, 0x20, 0x45, 0x04, 0xaf, 0xec, 0x9a, 0xc3, 0x26,
-	0xad, 0xc9, 0x48, 0xb6, 0x49, 0xdf, 0xc2, 0x27, 0xf1, 0x39, 0xbc, 0xdc, 0xa5, 0x97, 0xb2, 0xbd,
-	0x88, 0x34, 0xdb, 0x5a, 0x9d, 0x97, 0xdf, 0x77, 0xbe, 0x24, 0xbf, 0xef, 0x04, 0x0e, 0xc6, 0xa1,
-	0xe0, 0x7a, 0x1c, 0xc6, 0xd8, 0x99, 0x28, 0x39, 0x95, 0xb4, 0x91, 0x1b, 0xde, 0x05, 0x58, 0x41,
-	0x2a, 0xe8, 0x19, 0x1c, 0x0e, 0x86, 0x1a, 0xd5, 0x1c, 0xf9, 0x83, 0xe0, 0xa8, 0x92, 0x30, 0xb5,
-	0x89, 0x4b, 0xfc, 0x26, 0xfb, 0xe7, 0x7b, 0x1f, 0x04, 0xac, 0x6e, 0x14, 0xd3, 0x73, 0xa8, 0x75,
-	0x39, 0x57, 0xa8, 0xb5, 0x89, 0xee, 0x5f, 0x1e, 0x77, 0x8a, 0x87, 0x7a, 0x72, 0xb2, 0x19, 0xb2,
-	0x6d, 0x8a, 0xb6, 0xa1, 0xd1, 0xc7, 0xe9, 0x9b, 0x54, 0xf1, 0xdd, 0x8d, 0x5d, 0x76, 0x89, 0x5f,
-	0x61, 0x85, 0x41, 0x4f, 0xa0, 0x7e, 0x3b, 0x4b, 0x92, 0xbe, 0xe4, 0x68, 0x5b, 0x2e, 0xf1, 0xeb,
-	0x2c, 0xd7, 0xf4, 0x08, 0xf6, 0xfa, 0x52, 0x44, 0x68, 0x57, 0x0c, 0xd3, 0x5a, 0xd0, 0x53, 0x68,
-	0x3d, 0x62, 0x12, 0xc9, 0x57, 0xec, 0xa1, 0xd6, 0xe1, 0x08, 0xed, 0xc8, 0x25, 0x7e, 0x83, 0xed,
-	0xb8, 0xde, 0x3d, 0x54, 0x83, 0x54, 0x64, 0xc8, 0xae, 0x69, 0xbb, 0xc1, 0x6d, 0xfd, 0xc2, 0x0d,
-	0x52, 0xc1, 0xcc, 0x22, 0x5c, 0xd3, 0xcd, 0xd0, 0xfd, 0x4d, 0x74, 0xa3, 0x98, 0x65, 0x23, 0xef,
-	0x19, 0xa0, 0x28, 0x97, 0x51, 0xef, 0x2c, 0x2c, 0xd7, 0x59, 0xdf, 0xe0, 0x65, 0x24, 0xc2, 0xe9,
-	0x4c, 0xa1, 0xb9, 0xb1, 0xc9, 0x0a, 0x83, 0xda, 0x50, 0x1b, 0xcc, 0xd7, 0x07, 0x2d, 0x33, 0xdb,
-	0xca, 0xeb, 0xf6, 0xe7, 0xd2, 0x21, 0x8b, 0xa5, 0x43, 0xbe, 0x97, 0x0e, 0x79, 0x5f, 0x39, 0xa5,
-	0xc5, 0xca, 0x29, 0x7d, 0xad, 0x9c, 0xd2, 0x53, 0x79, 0x32, 0x1c, 0x56, 0xcd, 0x1f, 0x5e, 0xfd,
-	0x04, 0x00, 0x00, 0xff, 0xff, 0xb4, 0x91, 0xcc, 0x96, 0xd6, 0x01, 0x00, 0x00,
+	// 348 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x64, 0x92, 0xcd, 0x4a, 0xeb, 0x40,
+	0x14, 0xc7, 0x3b, 0x4d, 0x3f, 0xcf, 0x2d, 0xed, 0x65, 0xb8, 0x17, 0xc2, 0xa5, 0x84, 0x90, 0xc5,
+	0x25, 0xb8, 0xa8, 0xa8, 0x4f, 0x50, 0x11, 0x41, 0xb0, 0x2d, 0x4c, 0x14, 0xc1, 0x5d, 0x9a, 0x1c,
+	0xda, 0x92, 0x74, 0x26, 0xcc, 0xa4, 0x95, 0xbc, 0x85, 0x8f, 0xe1, 0xca, 0xe7, 0x70, 0xd9, 0xa5,
+	0x4b, 0x69, 0x5f, 0x44, 0x32, 0xfd, 0x88, 0xd6, 0xe5, 0xff, 0x77, 0x3e, 0xe6, 0xff, 0x3f, 0x0c,
+	0x74, 0xa6, 0x3e, 0x0f, 0xd5, 0xd4, 0x8f, 0xb0, 0x97, 0x48, 0x91, 0x0a, 0xda, 0x3c, 0x00, 0xe7,
+	0x0c, 0x0c, 0x2f, 0xe3, 0xf4, 0x04, 0x7e, 0x8f, 0xc6, 0x0a, 0xe5, 0x12, 0xc3, 0x7b, 0x1e, 0xa2,
+	0x8c, 0xfd, 0xcc, 0x24, 0x36, 0x71, 0x5b, 0xec, 0x07, 0x77, 0x5e, 0x09, 0x18, 0xfd, 0x20, 0xa2,
+	0xa7, 0x50, 0xef, 0x87, 0xa1, 0x44, 0xa5, 0x74, 0xeb, 0xaf, 0xf3, 0xbf, 0xbd, 0xe2, 0xa1, 0x81,
+	0x48, 0x76, 0x45, 0xb6, 0xef, 0xa2, 0x5d, 0x68, 0x0e, 0x31, 0x7d, 0x12, 0x32, 0xba, 0xb9, 0x32,
+	0xcb, 0x36, 0x71, 0x2b, 0xac, 0x00, 0xf4, 0x1f, 0x34, 0xae, 0x17, 0x71, 0x3c, 0x14, 0x21, 0x9a,
+	0x86, 0x4d, 0xdc, 0x06, 0x3b, 0x68, 0xfa, 0x07, 0xaa, 0x43, 0xc1, 0x03, 0x34, 0x2b, 0xda, 0xd3,
+	0x56, 0xd0, 0xff, 0xd0, 0x7e, 0xc0, 0x38, 0x10, 0x73, 0x1c, 0xa0, 0x52, 0xfe, 0x04, 0xcd, 0xc0,
+	0x26, 0x6e, 0x93, 0x1d, 0x51, 0xe7, 0x16, 0x6a, 0x5e, 0xc6, 0x73, 0xcb, 0xb6, 0x4e, 0xbb, 0xb3,
+	0xdb, 0xfe, 0x62, 0xd7, 0xcb, 0x38, 0xd3, 0x87, 0xb0, 0x75, 0x36, 0xed, 0xee, 0x7b, 0x47, 0x3f,
+	0x88, 0x58, 0x5e, 0x72, 0x5e, 0x08, 0x40, 0x91, 0x2e, 0xb7, 0x7d, 0x74, 0xb1, 0x83, 0xce, 0x03,
+	0x7b, 0xb3, 0x09, 0xf7, 0xd3, 0x85, 0x44, 0xbd, 0xb2, 0xc5, 0x0a, 0x40, 0x4d, 0xa8, 0x8f, 0x96,
+	0xdb, 0x41, 0x43, 0xd7, 0xf6, 0x32, 0x9f, 0xbb, 0x9b, 0xcd, 0x51, 0xa5, 0xfe, 0x3c, 0xd1, 0x91,
+	0x0d, 0x56, 0x00, 0xea, 0x42, 0x87, 0x61, 0x20, 0x64, 0x58, 0xec, 0xae, 0xea, 0xf9, 0x63, 0x7c,
+	0xd9, 0x7d, 0x5b, 0x5b, 0x64, 0xb5, 0xb6, 0xc8, 0xc7, 0xda, 0x22, 0xcf, 0x1b, 0xab, 0xb4, 0xda,
+	0x58, 0xa5, 0xf7, 0x8d, 0x55, 0x7a, 0x2c, 0x27, 0xe3, 0x71, 0x4d, 0x7f, 0x86, 0x8b, 0xcf, 0x00,
+	0x00, 0x00, 0xff, 0xff, 0x12, 0x44, 0x24, 0x65, 0x1f, 0x02, 0x00, 0x00,
 }
 
 func (m *Syn) Marshal() (dAtA []byte, err error) {
@@ -450,6 +468,18 @@ func (m *MopAddress) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.RecordSignature) > 0 {
+		i -= len(m.RecordSignature)
+		copy(dAtA[i:], m.RecordSignature)
+		i = encodeVarintHandshake(dAtA, i, uint64(len(m.RecordSignature)))
+		i--
+		dAtA[i] = 0x2a
+	}
+	if m.Timestamp != 0 {
+		i = encodeVarintHandshake(dAtA, i, uint64(m.Timestamp))
+		i--
+		dAtA[i] = 0x20
+	}
 	if len(m.Overlay) > 0 {
 		i -= len(m.Overlay)
 		copy(dAtA[i:], m.Overlay)
@@ -560,6 +590,13 @@ func (m *MopAddress) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovHandshake(uint64(l))
 	}
+	if m.Timestamp != 0 {
+		n += 1 + sovHandshake(uint64(m.Timestamp))
+	}
+	l = len(m.RecordSignature)
+	if l > 0 {
+		n += 1 + l + sovHandshake(uint64(l))
+	}
 	return n
 }
 
@@ -1097,6 +1134,59 @@ func (m *MopAddress) Unmarshal(dAtA []byte) error {
 				m.Overlay = []byte{}
 			}
 			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Timestamp", wireType)
+			}
+			m.Timestamp = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowHandshake
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Timestamp |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RecordSignature", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowHandshake
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthHandshake
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthHandshake
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.RecordSignature = append(m.RecordSignature[:0], dAtA[iNdEx:postIndex]...)
+			if m.RecordSignature == nil {
+				m.RecordSignature = []byte{}
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipHandshake(dAtA[iNdEx:])
@@ -25,6 +25,7 @@ import (
 	"github.com/libp2p/go-libp2p-core/peerstore"
 	protocol "github.com/libp2p/go-libp2p-core/protocol"
 	"github.com/libp2p/go-libp2p-peerstore/pstoremem"
+	libp2pquic "github.com/libp2p/go-libp2p-quic-transport"
 	libp2pswarm "github.com/libp2p/go-libp2p-swarm"
 	goyamux "github.com/libp2p/go-libp2p-yamux"
 	basichost "github.com/libp2p/go-libp2p/p2p/host/basic"
@@ -100,7 +101,11 @@ type Service struct {
 	lightNodeLimit    int
 	protocolsmu       sync.RWMutex
 	reacher           p2p.Reacher
+	connectionManager p2p.ConnectionManager
 	networkStatus     atomic.Int32
+	reachability      atomic.Int32
+	relayService      bool
+	holePunching      bool
 	HeadersRWTimeout  time.Duration
 }
 
@@ -116,6 +121,7 @@ type Options struct {
 	PrivateKey       *ecdsa.PrivateKey
 	NATAddr          string
 	EnableWS         bool
+	EnableQUIC       bool
 	FullNode         bool
 	LightNodeLimit   int
 	WelcomeMessage   string
@@ -123,6 +129,15 @@ type Options struct {
 	ValidateOverlay  bool
 	hostFactory      func(...libp2p.Option) (host.Host, error)
 	HeadersRWTimeout time.Duration
+	// RelayService enables acting as a circuit relay for the peers that are
+	// not publicly reachable, if the node itself is publicly reachable.
+	RelayService bool
+	// StaticRelays are the underlay addresses of the relays through which
+	// the node becomes reachable when it is not publicly reachable.
+	StaticRelays []string
+	// HolePunching enables the upgrade of the relayed connections to the
+	// direct ones.
+	HolePunching bool
 }
 
 func New(ctx context.Context, signer mopCrypto.Signer, networkID uint64, overlay cluster.Address, addr string, ab address.Putter, storer storage.StateStorer, lightNodes *lightnode.Container, logger log.Logger, tracer *tracer.Tracer, o Options) (*Service, error) {
@@ -154,6 +169,9 @@ func New(ctx context.Context, signer mopCrypto.Signer, networkID uint64, overlay
 		if o.EnableWS {
 			listenAddrs = append(listenAddrs, fmt.Sprintf("/ip4/%s/tcp/%s/ws", ip4Addr, port))
 		}
+		if o.EnableQUIC {
+			listenAddrs = append(listenAddrs, fmt.Sprintf("/ip4/%s/udp/%s/quic", ip4Addr, port))
+		}
 	}
 
 	if ip6Addr != "" {
@@ -161,6 +179,9 @@ func New(ctx context.Context, signer mopCrypto.Signer, networkID uint64, overlay
 		if o.EnableWS {
 			listenAddrs = append(listenAddrs, fmt.Sprintf("/ip6/%s/tcp/%s/ws", ip6Addr, port))
 		}
+		if o.EnableQUIC {
+			listenAddrs = append(listenAddrs, fmt.Sprintf("/ip6/%s/udp/%s/quic", ip6Addr, port))
+		}
 	}
 
 	security := libp2p.DefaultSecurity
@@ -202,8 +223,18 @@ func New(ctx context.Context, signer mopCrypto.Signer, networkID uint64, overlay
 		transports = append(transports, libp2p.Transport(ws.New))
 	}
 
+	if o.EnableQUIC {
+		transports = append(transports, libp2p.Transport(libp2pquic.NewTransport))
+	}
+
 	opts = append(opts, transports...)
 
+	relayOpts, err := relayOptions(o)
+	if err != nil {
+		return nil, fmt.Errorf("relay: %w", err)
+	}
+	opts = append(opts, relayOpts...)
+
 	if o.hostFactory == nil {
 		// Use the default libp2p host creation
 		o.hostFactory = libp2p.New
@@ -293,6 +324,8 @@ func New(ctx context.Context, signer mopCrypto.Signer, networkID uint64, overlay
 		halt:              make(chan struct{}),
 		lightNodes:        lightNodes,
 		HeadersRWTimeout:  o.HeadersRWTimeout,
+		relayService:      o.RelayService,
+		holePunching:      o.HolePunching,
 	}
 
 	peerRegistry.setDisconnecter(s)
@@ -331,6 +364,7 @@ func (s *Service) reachabilityWorker() error {
 				return
 			case e := <-sub.Out():
 				if r, ok := e.(event.EvtLocalReachabilityChanged); ok {
+					s.reachability.Store(int32(r.Reachability))
 					select {
 					case <-s.ready:
 					case <-s.halt:
@@ -491,6 +525,10 @@ func (s *Service) handleIncoming(stream network.Stream) {
 		s.reacher.Connected(overlay, i.MopAddress.Underlay)
 	}
 
+	if s.connectionManager != nil {
+		s.connectionManager.Connected(overlay)
+	}
+
 	peerUserAgent := appendSpace(s.peerUserAgent(s.ctx, peerID))
 
 	loggerV1.Debug("stream handler: successfully connected to peer (inbound)", "addresses", i.MopAddress.ShortString(), "light", i.LightString(), "user_agent", peerUserAgent)
@@ -503,11 +541,46 @@ func (s *Service) SetPickyNotifier(n p2p.PickyNotifier) {
 	s.notifier = n
 }
 
+// SetConnectionManager sets the connection manager which is notified about
+// the established connections. It must be set before the service is ready.
+func (s *Service) SetConnectionManager(m p2p.ConnectionManager) {
+	s.connectionManager = m
+}
+
 func (s *Service) AddProtocol(p p2p.ProtocolSpec) (err error) {
-	for _, ss := range p.StreamSpecs {
+	versions := p.SupportedVersions()
+	for _, v := range versions {
+		if err := s.addProtocolVersion(p.Name, v, versions); err != nil {
+			return err
+		}
+	}
+
+	s.protocolsmu.Lock()
+	s.protocols = append(s.protocols, p)
+	s.protocolsmu.Unlock()
+	return nil
+}
+
+// addProtocolVersion registers the stream handlers of a single version of
+// the protocol, next to all the versions of the protocol.
+func (s *Service) addProtocolVersion(name string, v p2p.VersionSpec, versions []p2p.VersionSpec) error {
+	for _, ss := range v.StreamSpecs {
 		ss := ss
-		id := protocol.ID(p2p.NewClusterStreamName(p.Name, p.Version, ss.Name))
-		matcher, err := s.protocolSemverMatcher(id)
+		id := protocol.ID(p2p.NewClusterStreamName(name, v.Version, ss.Name))
+		// the other versions which handle the same stream
+		var others []string
+		for _, o := range versions {
+			if o.Version == v.Version {
+				continue
+			}
+			for _, oss := range o.StreamSpecs {
+				if oss.Name == ss.Name {
+					others = append(others, o.Version)
+					break
+				}
+			}
+		}
+		matcher, err := s.protocolSemverMatcher(id, others...)
 		if err != nil {
 			return fmt.Errorf("protocol version match %s: %w", id, err)
 		}
@@ -534,11 +607,12 @@ func (s *Service) AddProtocol(p p2p.ProtocolSpec) (err error) {
 			defer cancel()
 			// exchange headers
 			if err := handleHeaders(ctx, ss.Headler, stream, overlay); err != nil {
-				s.logger.Debug("handle protocol: handle headers failed", "protocol", p.Name, "version", p.Version, "stream", ss.Name, "peer", overlay, "error", err)
+				s.logger.Debug("handle protocol: handle headers failed", "protocol", name, "version", v.Version, "stream", ss.Name, "peer", overlay, "error", err)
 				_ = stream.Reset()
 				return
 			}
 			s.metrics.HeadersExchangeDuration.Observe(time.Since(start).Seconds())
+			s.peers.setProtocolVersion(peerID, streamlibp2p.Protocol())
 
 			ctx, cancel = context.WithCancel(s.ctx)
 
@@ -549,7 +623,7 @@ func (s *Service) AddProtocol(p p2p.ProtocolSpec) (err error) {
 			// silently ignore if the peer is not providing tracer
 			ctx, err := s.tracer.WithContextFromHeaders(ctx, stream.Headers())
 			if err != nil && !errors.Is(err, tracer.ErrContextNotFound) {
-				s.logger.Debug("handle protocol: get tracer context failed", "protocol", p.Name, "version", p.Version, "stream", ss.Name, "peer", overlay, "error", err)
+				s.logger.Debug("handle protocol: get tracer context failed", "protocol", name, "version", v.Version, "stream", ss.Name, "peer", overlay, "error", err)
 				_ = stream.Reset()
 				return
 			}
@@ -561,7 +635,7 @@ func (s *Service) AddProtocol(p p2p.ProtocolSpec) (err error) {
 			if err := ss.Handler(ctx, p2p.Peer{Address: overlay, FullNode: full}, stream); err != nil {
 				var de *p2p.DisconnectError
 				if errors.As(err, &de) {
-					loggerV1.Debug("libp2p handler: disconnecting due to disconnect error", "protocol", p.Name, "address", overlay)
+					loggerV1.Debug("libp2p handler: disconnecting due to disconnect error", "protocol", name, "address", overlay)
 					_ = stream.Reset()
 					_ = s.Disconnect(overlay, de.Error())
 				}
@@ -573,7 +647,7 @@ func (s *Service) AddProtocol(p p2p.ProtocolSpec) (err error) {
 						logger.Debug("blocklist: could not blocklist peer", "peer_id", peerID, "error", err)
 						logger.Error(nil, "unable to blocklist peer", "peer_id", peerID)
 					}
-					loggerV1.Debug("handler: peer blocklisted", "protocol", p.Name, "peer_address", overlay)
+					loggerV1.Debug("handler: peer blocklisted", "protocol", name, "peer_address", overlay)
 				}
 				// count unexpected requests
 				if errors.Is(err, p2p.ErrUnexpected) {
@@ -582,15 +656,11 @@ func (s *Service) AddProtocol(p p2p.ProtocolSpec) (err error) {
 				if errors.Is(err, mux.ErrReset) {
 					s.metrics.StreamHandlerErrResetCount.Inc()
 				}
-				logger.Debug("handle protocol failed", "protocol", p.Name, "version", p.Version, "stream", ss.Name, "peer", overlay, "error", err)
+				logger.Debug("handle protocol failed", "protocol", name, "version", v.Version, "stream", ss.Name, "peer", overlay, "error", err)
 				return
 			}
 		})
 	}
-
-	s.protocolsmu.Lock()
-	s.protocols = append(s.protocols, p)
-	s.protocolsmu.Unlock()
 	return nil
 }
 
@@ -769,6 +839,10 @@ func (s *Service) Connect(ctx context.Context, addr ma.Multiaddr) (address *mop.
 		s.reacher.Connected(overlay, i.MopAddress.Underlay)
 	}
 
+	if s.connectionManager != nil {
+		s.connectionManager.Connected(overlay)
+	}
+
 	peerUserAgent := appendSpace(s.peerUserAgent(ctx, info.ID))
 
 	loggerV1.Debug("successfully connected to peer (outbound)", "addresses", i.MopAddress.ShortString(), "light", i.LightString(), "user_agent", peerUserAgent)
@@ -853,6 +927,16 @@ func (s *Service) Peers() []p2p.Peer {
 	return s.peers.peers()
 }
 
+// PeerProtocols returns the highest protocol versions negotiated with the peer, keyed
+// by the protocol name.
+func (s *Service) PeerProtocols(overlay cluster.Address) map[string]string {
+	peerID, found := s.peers.peerID(overlay)
+	if !found {
+		return nil
+	}
+	return s.peers.protocolVersions(peerID)
+}
+
 func (s *Service) Blocklisted(overlay cluster.Address) (bool, error) {
 	return s.blocklist.Exists(overlay)
 }
@@ -877,6 +961,7 @@ func (s *Service) NewStream(ctx context.Context, overlay cluster.Address, header
 	if err != nil {
 		return nil, fmt.Errorf("new stream for peerid: %w", err)
 	}
+	s.peers.setProtocolVersion(peerID, streamlibp2p.Protocol())
 
 	stream := newStream(streamlibp2p)
 
@@ -902,6 +987,9 @@ func (s *Service) NewStream(ctx context.Context, overlay cluster.Address, header
 
 func (s *Service) newStreamForPeerID(ctx context.Context, peerID libp2ppeer.ID, protocolName, protocolVersion, streamName string) (network.Stream, error) {
 	clusterStreamName := p2p.NewClusterStreamName(protocolName, protocolVersion, streamName)
+	// allow streams over the relayed connections until they are upgraded
+	// to the direct ones by hole punching
+	ctx = network.WithUseTransient(ctx, "relayed connection")
 	st, err := s.host.NewStream(ctx, peerID, protocol.ID(clusterStreamName))
 	if err != nil {
 		if st != nil {
@@ -1074,8 +1162,34 @@ type connectionNotifier struct {
 	network.Notifiee
 }
 
-func (c *connectionNotifier) Connected(_ network.Network, _ network.Conn) {
+func (c *connectionNotifier) Connected(_ network.Network, conn network.Conn) {
 	c.metrics.HandledConnectionCount.Inc()
+	transport := transportName(conn.RemoteMultiaddr())
+	c.metrics.TransportConnectionCount.WithLabelValues(transport, conn.Stat().Direction.String()).Inc()
+	c.metrics.OpenTransportConnections.WithLabelValues(transport).Inc()
+}
+
+func (c *connectionNotifier) Disconnected(_ network.Network, conn network.Conn) {
+	c.metrics.OpenTransportConnections.WithLabelValues(transportName(conn.RemoteMultiaddr())).Dec()
+}
+
+// transportName returns the name of the transport of the connection with the
+// remote address.
+func transportName(addr ma.Multiaddr) string {
+	for _, p := range []struct {
+		code int
+		name string
+	}{
+		{ma.P_CIRCUIT, "relay"},
+		{ma.P_QUIC, "quic"},
+		{ma.P_WS, "ws"},
+		{ma.P_TCP, "tcp"},
+	} {
+		if _, err := addr.ValueForProtocol(p.code); err == nil {
+			return p.name
+		}
+	}
+	return "other"
 }
 
 // isNetworkOrHostUnreachableError determines based on the
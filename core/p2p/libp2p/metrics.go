@@ -21,6 +21,8 @@ type metrics struct {
 	KickedOutPeersCount        prometheus.Counter
 	StreamHandlerErrResetCount prometheus.Counter
 	HeadersExchangeDuration    prometheus.Histogram
+	TransportConnectionCount   *prometheus.CounterVec
+	OpenTransportConnections   *prometheus.GaugeVec
 }
 
 func newMetrics() metrics {
@@ -99,6 +101,24 @@ func newMetrics() metrics {
 			Name:      "headers_exchange_duration",
 			Help:      "The duration spent exchanging the headers.",
 		}),
+		TransportConnectionCount: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: m.Namespace,
+				Subsystem: subsystem,
+				Name:      "transport_connection_count",
+				Help:      "Number of established libp2p connections by transport and direction.",
+			},
+			[]string{"transport", "direction"},
+		),
+		OpenTransportConnections: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: m.Namespace,
+				Subsystem: subsystem,
+				Name:      "open_transport_connections",
+				Help:      "Number of open libp2p connections by transport.",
+			},
+			[]string{"transport"},
+		),
 	}
 }
 
@@ -6,8 +6,10 @@ import (
 	"sort"
 	"sync"
 
+	"github.com/coreos/go-semver/semver"
 	"github.com/libp2p/go-libp2p-core/network"
 	libp2ppeer "github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
 	ma "github.com/multiformats/go-multiaddr"
 	"github.com/redesblock/mop/core/cluster"
 	"github.com/redesblock/mop/core/p2p"
@@ -19,6 +21,7 @@ type peerRegistry struct {
 	full        map[libp2ppeer.ID]bool                      // map to track whether a node is full or light node (true=full)
 	connections map[libp2ppeer.ID]map[network.Conn]struct{} // list of connections for safe removal on Disconnect notification
 	streams     map[libp2ppeer.ID]map[network.Stream]context.CancelFunc
+	protocols   map[libp2ppeer.ID]map[string]string // map of protocol names to the negotiated versions
 	mu          sync.RWMutex
 
 	//nolint:misspell
@@ -37,6 +40,7 @@ func newPeerRegistry() *peerRegistry {
 		full:        make(map[libp2ppeer.ID]bool),
 		connections: make(map[libp2ppeer.ID]map[network.Conn]struct{}),
 		streams:     make(map[libp2ppeer.ID]map[network.Stream]context.CancelFunc),
+		protocols:   make(map[libp2ppeer.ID]map[string]string),
 
 		Notifiee: new(network.NoopNotifiee),
 	}
@@ -77,6 +81,7 @@ func (r *peerRegistry) Disconnected(_ network.Network, c network.Conn) {
 	}
 	delete(r.streams, peerID)
 	delete(r.full, peerID)
+	delete(r.protocols, peerID)
 	r.mu.Unlock()
 	r.disconnecter.disconnected(overlay)
 
@@ -151,6 +156,46 @@ func (r *peerRegistry) addIfNotExists(c network.Conn, overlay cluster.Address, f
 
 }
 
+// setProtocolVersion records the version of the stream protocol id as the
+// version of the protocol negotiated with the peer, unless a higher version
+// of the same protocol has already been negotiated.
+func (r *peerRegistry) setProtocolVersion(peerID libp2ppeer.ID, id protocol.ID) {
+	name, version, ok := parseProtocolID(id)
+	if !ok {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.overlays[peerID]; !ok {
+		return
+	}
+	if _, ok := r.protocols[peerID]; !ok {
+		r.protocols[peerID] = make(map[string]string)
+	}
+	if current, ok := r.protocols[peerID][name]; ok {
+		cv, err := semver.NewVersion(current)
+		if err == nil {
+			v, err := semver.NewVersion(version)
+			if err != nil || v.LessThan(*cv) {
+				return
+			}
+		}
+	}
+	r.protocols[peerID][name] = version
+}
+
+func (r *peerRegistry) protocolVersions(peerID libp2ppeer.ID) map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions := make(map[string]string, len(r.protocols[peerID]))
+	for name, version := range r.protocols[peerID] {
+		versions[name] = version
+	}
+	return versions
+}
+
 func (r *peerRegistry) peerID(overlay cluster.Address) (peerID libp2ppeer.ID, found bool) {
 	r.mu.RLock()
 	peerID, found = r.underlays[overlay.ByteString()]
@@ -213,6 +258,7 @@ func (r *peerRegistry) remove(overlay cluster.Address) (found, full bool, peerID
 	delete(r.streams, peerID)
 	full = r.full[peerID]
 	delete(r.full, peerID)
+	delete(r.protocols, peerID)
 	r.mu.Unlock()
 
 	return found, full, peerID
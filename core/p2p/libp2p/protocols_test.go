@@ -210,6 +210,174 @@ func TestNewStream_errNotSupported(t *testing.T) {
 	expectErrNotSupported(t, err)
 }
 
+// TestNewStream_versions tests that the highest version of a protocol
+// supported by both peers is negotiated and reported per peer.
+func TestNewStream_versions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s1, overlay1 := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		FullNode: true,
+	}})
+
+	s2, overlay2 := newService(t, 1, libp2pServiceOpts{})
+
+	var h1calls, h2calls int32
+	spec := newTestProtocol(func(_ context.Context, _ p2p.Peer, s p2p.Stream) error {
+		defer s.Close()
+		_ = atomic.AddInt32(&h1calls, 1)
+		return nil
+	})
+	spec.Versions = []p2p.VersionSpec{{
+		Version: "3.0.0",
+		StreamSpecs: []p2p.StreamSpec{{
+			Name: testStreamName,
+			Handler: func(_ context.Context, _ p2p.Peer, s p2p.Stream) error {
+				defer s.Close()
+				_ = atomic.AddInt32(&h2calls, 1)
+				return nil
+			},
+		}},
+	}}
+	if err := s1.AddProtocol(spec); err != nil {
+		t.Fatal(err)
+	}
+
+	addr := serviceUnderlayAddress(t, s1)
+
+	if _, err := s2.Connect(ctx, addr); err != nil {
+		t.Fatal(err)
+	}
+
+	stream, version, err := p2p.NewVersionedStream(ctx, s2, overlay1, nil, spec, testStreamName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.FullClose(); err != nil {
+		t.Fatal(err)
+	}
+	if version != "3.0.0" {
+		t.Fatalf("got version %q, want %q", version, "3.0.0")
+	}
+	if atomic.LoadInt32(&h2calls) != 1 || atomic.LoadInt32(&h1calls) != 0 {
+		t.Fatal("highest version handler should have been called")
+	}
+
+	// a peer which supports a newer version than the remote peer falls back
+	// to the highest common version
+	newer := newTestProtocol(nil)
+	newer.Versions = []p2p.VersionSpec{{
+		Version:     "4.0.0",
+		StreamSpecs: []p2p.StreamSpec{{Name: testStreamName}},
+	}}
+	stream, version, err = p2p.NewVersionedStream(ctx, s2, overlay1, nil, newer, testStreamName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.FullClose(); err != nil {
+		t.Fatal(err)
+	}
+	if version != testProtocolVersion {
+		t.Fatalf("got version %q, want %q", version, testProtocolVersion)
+	}
+	if atomic.LoadInt32(&h1calls) != 1 {
+		t.Fatal("base version handler should have been called")
+	}
+
+	// the highest negotiated version is reported
+	if got := s2.PeerProtocols(overlay1)[testProtocolName]; got != "3.0.0" {
+		t.Fatalf("got negotiated version %q, want %q", got, "3.0.0")
+	}
+	if got := s1.PeerProtocols(overlay2)[testProtocolName]; got != "3.0.0" {
+		t.Fatalf("got negotiated version %q, want %q", got, "3.0.0")
+	}
+}
+
+func TestNewStream_minorVersions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s1, overlay1 := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		FullNode: true,
+	}})
+
+	s2, _ := newService(t, 1, libp2pServiceOpts{})
+
+	var calls sync.Map
+	handler := func(version string) p2p.HandlerFunc {
+		return func(_ context.Context, _ p2p.Peer, s p2p.Stream) error {
+			defer s.Close()
+			calls.Store(version, true)
+			return nil
+		}
+	}
+	spec := p2p.ProtocolSpec{
+		Name:    testProtocolName,
+		Version: "1.0.0",
+		StreamSpecs: []p2p.StreamSpec{{
+			Name:    testStreamName,
+			Handler: handler("1.0.0"),
+		}},
+		Versions: []p2p.VersionSpec{{
+			Version: "1.1.0",
+			StreamSpecs: []p2p.StreamSpec{{
+				Name:    testStreamName,
+				Handler: handler("1.1.0"),
+			}},
+		}},
+	}
+	if err := s1.AddProtocol(spec); err != nil {
+		t.Fatal(err)
+	}
+
+	addr := serviceUnderlayAddress(t, s1)
+
+	if _, err := s2.Connect(ctx, addr); err != nil {
+		t.Fatal(err)
+	}
+
+	// each stream is handled by the lowest compatible version
+	for _, tc := range []struct {
+		version, handler string
+	}{
+		{version: "1.0.0", handler: "1.0.0"},
+		{version: "1.0.1", handler: "1.0.0"},
+		{version: "1.1.0", handler: "1.1.0"},
+	} {
+		calls = sync.Map{}
+		stream, err := s2.NewStream(ctx, overlay1, nil, testProtocolName, tc.version, testStreamName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := stream.FullClose(); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		calls.Range(func(k, _ interface{}) bool {
+			got = append(got, k.(string))
+			return true
+		})
+		if len(got) != 1 || got[0] != tc.handler {
+			t.Fatalf("version %s: got handlers %v, want %s", tc.version, got, tc.handler)
+		}
+	}
+
+	// the highest negotiated version is reported
+	stream, err := s2.NewStream(ctx, overlay1, nil, testProtocolName, "1.0.0", testStreamName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.FullClose(); err != nil {
+		t.Fatal(err)
+	}
+	if got := s2.PeerProtocols(overlay1)[testProtocolName]; got != "1.1.0" {
+		t.Fatalf("got protocol version %q, want %q", got, "1.1.0")
+	}
+
+	_, err = s2.NewStream(ctx, overlay1, nil, testProtocolName, "1.2.0", testStreamName)
+	expectErrNotSupported(t, err)
+}
+
 func TestNewStream_semanticVersioning(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -0,0 +1,81 @@
+package libp2p
+
+import (
+	"fmt"
+
+	libp2p "github.com/libp2p/go-libp2p"
+	libp2ppeer "github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p/p2p/host/autorelay"
+	relay "github.com/libp2p/go-libp2p/p2p/protocol/circuitv2/relay"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/redesblock/mop/core/p2p"
+)
+
+// relayOptions returns the libp2p host options for the circuit relay and the
+// hole punching as configured by the options.
+func relayOptions(o Options) ([]libp2p.Option, error) {
+	var opts []libp2p.Option
+
+	if o.RelayService {
+		// The relayed connections keep the default limits of duration and
+		// data, so that the relay is only used to establish the connections
+		// which are upgraded to the direct ones by hole punching, and not to
+		// carry the traffic of the relayed peers.
+		opts = append(opts, libp2p.EnableRelayService(relay.WithLimit(relay.DefaultLimit())))
+	}
+
+	if len(o.StaticRelays) > 0 {
+		relays := make([]libp2ppeer.AddrInfo, 0, len(o.StaticRelays))
+		for _, r := range o.StaticRelays {
+			info, err := libp2ppeer.AddrInfoFromString(r)
+			if err != nil {
+				return nil, fmt.Errorf("static relay %s: %w", r, err)
+			}
+			relays = append(relays, *info)
+		}
+		opts = append(opts, libp2p.EnableAutoRelay(autorelay.WithStaticRelays(relays)))
+	}
+
+	if o.HolePunching {
+		opts = append(opts, libp2p.EnableHolePunching())
+	}
+
+	return opts, nil
+}
+
+// isRelayed reports whether the address is a circuit relay address.
+func isRelayed(addr ma.Multiaddr) bool {
+	_, err := addr.ValueForProtocol(ma.P_CIRCUIT)
+	return err == nil
+}
+
+// Reachability returns how the node is reachable by the other peers.
+func (s *Service) Reachability() p2p.Reachability {
+	r := p2p.Reachability{
+		Status:         p2p.ReachabilityStatus(s.reachability.Load()),
+		RelayService:   s.relayService,
+		HolePunching:   s.holePunching,
+		RelayAddresses: make([]ma.Multiaddr, 0),
+	}
+
+	for _, addr := range s.host.Addrs() {
+		if !isRelayed(addr) {
+			continue
+		}
+		a, err := buildUnderlayAddress(addr, s.host.ID())
+		if err != nil {
+			continue
+		}
+		r.RelayAddresses = append(r.RelayAddresses, a)
+	}
+
+	for _, c := range s.host.Network().Conns() {
+		if isRelayed(c.RemoteMultiaddr()) {
+			r.RelayedConnections++
+		} else {
+			r.DirectConnections++
+		}
+	}
+
+	return r
+}
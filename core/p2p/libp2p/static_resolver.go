@@ -65,7 +65,13 @@ func (r *staticAddressResolver) Resolve(observedAddress ma.Multiaddr) (ma.Multia
 	} else {
 		port = observedAddrSplit[4]
 	}
-	a, err := ma.NewMultiaddr(multiProto + "/" + observedAddrSplit[3] + "/" + port)
+	// keep the components following the port, like quic or ws, as they
+	// belong to the transport of the address
+	rest := strings.Join(observedAddrSplit[5:], "/")
+	if rest != "" {
+		rest = "/" + rest
+	}
+	a, err := ma.NewMultiaddr(multiProto + "/" + observedAddrSplit[3] + "/" + port + rest)
 	if err != nil {
 		return nil, err
 	}
@@ -75,6 +75,24 @@ func TestStaticAddressResolver(t *testing.T) {
 			observableAddress: "/ip4/127.0.0.1/tcp/7071/p2p/16Uiu2HAkyyGKpjBiCkVqCKoJa6RzzZw9Nr7hGogsMPcdad1KyMmd",
 			want:              "/dns/ipv4and6.com/tcp/30777/p2p/16Uiu2HAkyyGKpjBiCkVqCKoJa6RzzZw9Nr7hGogsMPcdad1KyMmd",
 		},
+		{
+			name:              "replace port of quic address",
+			natAddr:           ":30123",
+			observableAddress: "/ip4/127.0.0.1/udp/7071/quic/p2p/16Uiu2HAkyyGKpjBiCkVqCKoJa6RzzZw9Nr7hGogsMPcdad1KyMmd",
+			want:              "/ip4/127.0.0.1/udp/30123/quic/p2p/16Uiu2HAkyyGKpjBiCkVqCKoJa6RzzZw9Nr7hGogsMPcdad1KyMmd",
+		},
+		{
+			name:              "replace ip and port of quic address",
+			natAddr:           "192.168.1.34:30777",
+			observableAddress: "/ip6/2001:db8::8a2e:370:7334/udp/7071/quic/p2p/16Uiu2HAkyyGKpjBiCkVqCKoJa6RzzZw9Nr7hGogsMPcdad1KyMmd",
+			want:              "/ip4/192.168.1.34/udp/30777/quic/p2p/16Uiu2HAkyyGKpjBiCkVqCKoJa6RzzZw9Nr7hGogsMPcdad1KyMmd",
+		},
+		{
+			name:              "replace ip of ws address",
+			natAddr:           "192.168.1.34:",
+			observableAddress: "/ip4/127.0.0.1/tcp/7071/ws/p2p/16Uiu2HAkyyGKpjBiCkVqCKoJa6RzzZw9Nr7hGogsMPcdad1KyMmd",
+			want:              "/ip4/192.168.1.34/tcp/7071/ws/p2p/16Uiu2HAkyyGKpjBiCkVqCKoJa6RzzZw9Nr7hGogsMPcdad1KyMmd",
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			r, err := libp2p.NewStaticAddressResolver(tc.natAddr, func(host string) ([]net.IP, error) {
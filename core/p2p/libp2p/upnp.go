@@ -14,6 +14,7 @@ type UpnpAddressResolver struct {
 }
 
 // Resolve checks if there is a possible better advertisable underlay then the provided observed address.
+// When the node is reachable only through a relay, the relay address is advertised.
 // In some NAT situations, for example in the case when nodes are behind upnp, observer might send the observed address with a wrong port.
 // In this case, observed address is compared to addresses provided by host, and if there is a same address but with different port, that one is used as advertisable address instead of provided observed one.
 // TODO: this is a quickfix and it will be improved in the future
@@ -27,6 +28,15 @@ func (r *UpnpAddressResolver) Resolve(observedAddress ma.Multiaddr) (ma.Multiadd
 		return nil, errors.New("invalid observed address")
 	}
 
+	// the host has circuit addresses only when it is not publicly reachable
+	// and holds a reservation with a relay, so advertise the relay address
+	// as the observed one is not dialable
+	for _, a := range r.host.Addrs() {
+		if isRelayed(a) {
+			return buildUnderlayAddress(a, observableAddrInfo.ID)
+		}
+	}
+
 	observedAddrSplit := strings.Split(observableAddrInfo.Addrs[0].String(), "/")
 
 	// if address is not in a form of '/ipversion/ip/protocol/port/...` don't compare to addresses and return it
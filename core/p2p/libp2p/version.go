@@ -15,7 +15,13 @@ import (
 // matches the base protocol. A given protocol ID matches the base protocol if
 // the IDs are the same and if the semantic version of the base protocol is the
 // same or higher than that of the protocol ID provided.
-func (s *Service) protocolSemverMatcher(base protocol.ID) (func(string) bool, error) {
+//
+// The other versions are the ones of the same protocol registered side by
+// side with the base one. The protocol ID does not match the base protocol
+// if any of them is a closer match, being the same or higher than the
+// version of the protocol ID, but lower than the base version, so that the
+// streams are handled by the lowest compatible registered version.
+func (s *Service) protocolSemverMatcher(base protocol.ID, others ...string) (func(string) bool, error) {
 	parts := strings.Split(string(base), "/")
 	partsLen := len(parts)
 	if partsLen < 2 {
@@ -25,6 +31,16 @@ func (s *Service) protocolSemverMatcher(base protocol.ID) (func(string) bool, er
 	if err != nil {
 		return nil, err
 	}
+	siblings := make([]*semver.Version, 0, len(others))
+	for _, o := range others {
+		v, err := semver.NewVersion(o)
+		if err != nil {
+			return nil, err
+		}
+		if v.Major == vers.Major && v.LessThan(*vers) {
+			siblings = append(siblings, v)
+		}
+	}
 
 	return func(check string) bool {
 		chparts := strings.Split(check, "/")
@@ -48,6 +64,24 @@ func (s *Service) protocolSemverMatcher(base protocol.ID) (func(string) bool, er
 			return false
 		}
 
-		return vers.Major == chvers.Major && vers.Minor >= chvers.Minor
+		if vers.Major != chvers.Major || vers.Minor < chvers.Minor {
+			return false
+		}
+		for _, v := range siblings {
+			if v.Minor >= chvers.Minor {
+				return false
+			}
+		}
+		return true
 	}, nil
 }
+
+// parseProtocolID returns the protocol name and version of the Cluster
+// protocol ID: /cluster/{protocol}/{version}/{stream}.
+func parseProtocolID(id protocol.ID) (name, version string, ok bool) {
+	parts := strings.Split(string(id), "/")
+	if len(parts) != 5 || parts[1] != "cluster" {
+		return "", "", false
+	}
+	return parts[2], parts[3], true
+}
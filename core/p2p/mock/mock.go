@@ -23,6 +23,8 @@ type Service struct {
 	setWelcomeMessageFunc func(string) error
 	getWelcomeMessageFunc func() string
 	blocklistFunc         func(cluster.Address, time.Duration, string) error
+	peerProtocolsFunc     func(cluster.Address) map[string]string
+	reachabilityFunc      func() p2p.Reachability
 	welcomeMessage        string
 }
 
@@ -61,6 +63,20 @@ func WithBlocklistedPeersFunc(f func() ([]p2p.Peer, error)) Option {
 	})
 }
 
+// WithPeerProtocolsFunc sets the mock implementation of the PeerProtocols function
+func WithPeerProtocolsFunc(f func(cluster.Address) map[string]string) Option {
+	return optionFunc(func(s *Service) {
+		s.peerProtocolsFunc = f
+	})
+}
+
+// WithReachabilityFunc sets the mock implementation of the Reachability function
+func WithReachabilityFunc(f func() p2p.Reachability) Option {
+	return optionFunc(func(s *Service) {
+		s.reachabilityFunc = f
+	})
+}
+
 // WithAddressesFunc sets the mock implementation of the Adresses function
 func WithAddressesFunc(f func() ([]ma.Multiaddr, error)) Option {
 	return optionFunc(func(s *Service) {
@@ -137,6 +153,20 @@ func (s *Service) Peers() []p2p.Peer {
 	return s.peersFunc()
 }
 
+func (s *Service) PeerProtocols(overlay cluster.Address) map[string]string {
+	if s.peerProtocolsFunc == nil {
+		return nil
+	}
+	return s.peerProtocolsFunc(overlay)
+}
+
+func (s *Service) Reachability() p2p.Reachability {
+	if s.reachabilityFunc == nil {
+		return p2p.Reachability{}
+	}
+	return s.reachabilityFunc()
+}
+
 func (s *Service) Blocklisted(overlay cluster.Address) (bool, error) {
 	return false, nil
 }
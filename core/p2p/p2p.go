@@ -112,6 +112,12 @@ type Reacher interface {
 	Disconnected(cluster.Address)
 }
 
+// ConnectionManager is notified about the established connections so that it
+// can keep their number within the connection budget of the node.
+type ConnectionManager interface {
+	Connected(cluster.Address)
+}
+
 type ReachabilityUpdater interface {
 	UpdateReachability(ReachabilityStatus)
 }
@@ -128,6 +134,30 @@ type DebugService interface {
 	Service
 	SetWelcomeMessage(val string) error
 	GetWelcomeMessage() string
+	// PeerProtocols returns the highest protocol versions negotiated with the
+	// peer, keyed by the protocol name.
+	PeerProtocols(overlay cluster.Address) map[string]string
+	// Reachability returns how the node is reachable by the other peers.
+	Reachability() Reachability
+}
+
+// Reachability describes how the node is reachable by the other peers.
+type Reachability struct {
+	// Status is the reachability status reported by AutoNAT.
+	Status ReachabilityStatus
+	// RelayService tells whether the node acts as a circuit relay for the
+	// peers that are not publicly reachable.
+	RelayService bool
+	// HolePunching tells whether the relayed connections are upgraded to
+	// the direct ones by hole punching.
+	HolePunching bool
+	// RelayAddresses are the circuit addresses through which the node is
+	// reachable when it is not publicly reachable.
+	RelayAddresses []ma.Multiaddr
+	// DirectConnections is the number of the direct connections.
+	DirectConnections int
+	// RelayedConnections is the number of the connections through a relay.
+	RelayedConnections int
 }
 
 // Streamer is able to create a new Stream.
@@ -163,16 +193,26 @@ type Stream interface {
 }
 
 // ProtocolSpec defines a collection of Stream specifications with handlers.
+// Additional versions of the protocol, served side by side with the base
+// Version, are defined by Versions.
 type ProtocolSpec struct {
 	Name          string
 	Version       string
 	StreamSpecs   []StreamSpec
+	Versions      []VersionSpec
 	ConnectIn     func(context.Context, Peer) error
 	ConnectOut    func(context.Context, Peer) error
 	DisconnectIn  func(Peer) error
 	DisconnectOut func(Peer) error
 }
 
+// VersionSpec defines the Stream specifications of an additional version of
+// a protocol.
+type VersionSpec struct {
+	Version     string
+	StreamSpecs []StreamSpec
+}
+
 // StreamSpec defines a Stream handling within the protocol.
 type StreamSpec struct {
 	Name    string
@@ -10,18 +10,22 @@ var ErrUnexpected = errors.New("unexpected request while in light mode")
 
 // WithDisconnectStreams will mutate the given spec and replace the handler with a always erroring one.
 func WithDisconnectStreams(spec ProtocolSpec) {
-	for i := range spec.StreamSpecs {
-		spec.StreamSpecs[i].Handler = func(c context.Context, p Peer, s Stream) error {
-			return NewDisconnectError(ErrUnexpected)
+	for _, v := range spec.SupportedVersions() {
+		for i := range v.StreamSpecs {
+			v.StreamSpecs[i].Handler = func(c context.Context, p Peer, s Stream) error {
+				return NewDisconnectError(ErrUnexpected)
+			}
 		}
 	}
 }
 
 // WithBlocklistStreams will mutate the given spec and replace the handler with a always erroring one.
 func WithBlocklistStreams(dur time.Duration, spec ProtocolSpec) {
-	for i := range spec.StreamSpecs {
-		spec.StreamSpecs[i].Handler = func(c context.Context, p Peer, s Stream) error {
-			return NewBlockPeerError(dur, ErrUnexpected)
+	for _, v := range spec.SupportedVersions() {
+		for i := range v.StreamSpecs {
+			v.StreamSpecs[i].Handler = func(c context.Context, p Peer, s Stream) error {
+				return NewBlockPeerError(dur, ErrUnexpected)
+			}
 		}
 	}
 }
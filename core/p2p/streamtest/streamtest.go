@@ -112,22 +112,34 @@ func (r *Recorder) NewStream(ctx context.Context, addr cluster.Address, h p2p.He
 
 	var handler p2p.HandlerFunc
 	var headler p2p.HeadlerFunc
-	peerHandlers, ok := r.protocolsWithPeers[addr.String()]
-	if !ok {
+	var peerHandlers []p2p.StreamSpec
+	if p, ok := r.protocolsWithPeers[addr.String()]; ok {
+		peerHandlers = p.StreamSpecs
+		for _, v := range p.Versions {
+			if v.Version == protocolVersion {
+				peerHandlers = v.StreamSpecs
+			}
+		}
+	} else {
 		for _, p := range r.protocols {
-			if p.Name == protocolName && p.Version == protocolVersion {
-				peerHandlers = p
+			if p.Name != protocolName {
+				continue
+			}
+			for _, v := range p.SupportedVersions() {
+				if v.Version == protocolVersion {
+					peerHandlers = v.StreamSpecs
+				}
 			}
 		}
 	}
-	for _, s := range peerHandlers.StreamSpecs {
+	for _, s := range peerHandlers {
 		if s.Name == streamName {
 			handler = s.Handler
 			headler = s.Headler
 		}
 	}
 	if handler == nil {
-		return nil, ErrStreamNotSupported
+		return nil, p2p.NewIncompatibleStreamError(ErrStreamNotSupported)
 	}
 	for i := len(r.middlewares) - 1; i >= 0; i-- {
 		handler = r.middlewares[i](handler)
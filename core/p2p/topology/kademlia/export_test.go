@@ -1,5 +1,12 @@
 package kademlia
 
+import (
+	"time"
+
+	"github.com/redesblock/mop/core/cluster"
+	im "github.com/redesblock/mop/core/p2p/topology/kademlia/internal/metrics"
+)
+
 var (
 	TimeToRetry                 = &timeToRetry
 	SaturationPeers             = &saturationPeers
@@ -15,3 +22,7 @@ var (
 )
 
 type PeerFilterFunc = peerFilterFunc
+
+func (k *Kad) RecordPeerLatency(addr cluster.Address, latency time.Duration) {
+	k.collector.Record(addr, im.PeerLatency(latency))
+}
@@ -11,8 +11,8 @@ import (
 
 	"github.com/redesblock/mop/core/cluster"
 	"github.com/redesblock/mop/core/p2p"
+	"github.com/redesblock/mop/core/storer/kv"
 	"github.com/redesblock/mop/core/storer/shed"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 const ewmaSmoothing = 0.1
@@ -222,7 +222,7 @@ func NewCollector(db *shed.DB) (*Collector, error) {
 	c.persistence = &val
 
 	counters := make(map[string]persistentCounters)
-	if err := val.Get(&counters); err != nil && !errors.Is(err, leveldb.ErrNotFound) {
+	if err := val.Get(&counters); err != nil && !errors.Is(err, kv.ErrNotFound) {
 		return nil, err
 	}
 
@@ -10,6 +10,7 @@ import (
 	"net"
 	"os"
 	"runtime/pprof"
+	"sort"
 	"sync"
 	"syscall"
 	"time"
@@ -59,6 +60,7 @@ var (
 	timeToRetry                 = 2 * shortRetry
 	broadcastBinSize            = 4
 	peerPingPollTime            = 10 * time.Second // how often to ping a peer
+	addressBookPruneInterval    = time.Hour        // how often to prune the expired addresses from the address book
 )
 
 var (
@@ -88,6 +90,19 @@ type Options struct {
 	StaticNodes      []cluster.Address
 	ReachabilityFunc peerFilterFunc
 	IgnoreRadius     bool
+	// AddressBookPruneInterval is how often the expired addresses are
+	// pruned from the address book.
+	AddressBookPruneInterval time.Duration
+	// ConnectionsSaturatedFunc reports whether the overall connection
+	// budget of the node is used up, in which case only the neighbours
+	// are connected.
+	ConnectionsSaturatedFunc func() bool
+	// LatencyTradeoff is the number of proximity orders by which a peer may
+	// be farther from an address than the closest peer and still be chosen
+	// by ClosestPeer for its lower latency, provided it is closer to the
+	// address than this node. With zero only the peers in the same proximity
+	// order as the closest peer are chosen by their latency.
+	LatencyTradeoff uint8
 }
 
 // Kad is the Cluster forwarding kademlia implementation.
@@ -126,6 +141,9 @@ type Kad struct {
 	reachability       p2p.ReachabilityStatus
 	peerFilter         peerFilterFunc
 	ignoreStorageDepth bool
+	pruneInterval      time.Duration // how often to prune the address book
+	saturatedConns     func() bool   // whether the overall connection budget is used up
+	latencyTradeoff    uint8         // proximity orders given up for lower latency
 }
 
 // New returns a new Kademlia.
@@ -150,6 +168,9 @@ func New(
 	if o.BitSuffixLength == 0 {
 		o.BitSuffixLength = defaultBitSuffixLength
 	}
+	if o.AddressBookPruneInterval == 0 {
+		o.AddressBookPruneInterval = addressBookPruneInterval
+	}
 
 	imc, err := im.NewCollector(metricsDB)
 	if err != nil {
@@ -182,6 +203,9 @@ func New(
 		peerFilter:         o.ReachabilityFunc,
 		ignoreStorageDepth: o.IgnoreRadius,
 		storageRadius:      cluster.MaxPO,
+		pruneInterval:      o.AddressBookPruneInterval,
+		saturatedConns:     o.ConnectionsSaturatedFunc,
+		latencyTradeoff:    o.LatencyTradeoff,
 	}
 
 	blocklistCallback := func(a cluster.Address) {
@@ -199,6 +223,10 @@ func New(
 		k.peerFilter = k.collector.IsUnreachable
 	}
 
+	if k.saturatedConns == nil {
+		k.saturatedConns = func() bool { return false }
+	}
+
 	if k.bitSuffixLength > 0 {
 		k.commonBinPrefixes = generateCommonBinPrefixes(k.base, k.bitSuffixLength)
 	}
@@ -511,6 +539,21 @@ func (k *Kad) manage() {
 		}
 	}()
 
+	k.wg.Add(1)
+	go func() {
+		defer k.wg.Done()
+		for {
+			select {
+			case <-k.halt:
+				return
+			case <-k.quit:
+				return
+			case <-time.After(k.pruneInterval):
+				k.pruneAddressBook()
+			}
+		}
+	}()
+
 	k.wg.Add(1)
 	go func() {
 		defer k.wg.Done()
@@ -562,7 +605,11 @@ func (k *Kad) manage() {
 			}
 
 			oldDepth := k.NeighborhoodDepth()
-			k.connectBalanced(&wg, balanceChan)
+			if k.saturatedConns() {
+				k.metrics.ConnectionsSaturated.Inc()
+			} else {
+				k.connectBalanced(&wg, balanceChan)
+			}
 			k.connectNeighbours(&wg, neighbourhoodChan)
 			wg.Wait()
 
@@ -724,6 +771,9 @@ func (k *Kad) Start(_ context.Context) error {
 			return
 		default:
 		}
+		// drop the expired addresses before connecting to them
+		k.pruneAddressBook()
+
 		var (
 			start     = time.Now()
 			addresses []cluster.Address
@@ -752,6 +802,30 @@ func (k *Kad) Start(_ context.Context) error {
 	return nil
 }
 
+// pruneAddressBook removes the expired addresses of the peers that are
+// neither connected nor static from the address book and the known peers.
+func (k *Kad) pruneAddressBook() {
+	start := time.Now()
+	pruned, err := k.addressBook.Prune(func(peer cluster.Address) bool {
+		return k.connectedPeers.Exists(peer) || k.staticPeer(peer)
+	})
+	for _, peer := range pruned {
+		k.waitNext.Remove(peer)
+		k.knownPeers.Remove(peer)
+	}
+	k.metrics.AddressBookPrunedPeers.Add(float64(len(pruned)))
+	k.metrics.AddressBookPruneTime.Observe(time.Since(start).Seconds())
+	k.metrics.CurrentlyKnownPeers.Set(float64(k.knownPeers.Length()))
+	if err != nil {
+		k.metrics.AddressBookPruneErrors.Inc()
+		k.logger.Error(err, "address book prune failed")
+		return
+	}
+	if len(pruned) > 0 {
+		k.logger.Debug("pruned expired addresses from address book", "count", len(pruned), "elapsed", time.Since(start))
+	}
+}
+
 func (k *Kad) previouslyConnected() []cluster.Address {
 	loggerV1 := k.logger.V(1).Register()
 
@@ -1245,6 +1319,14 @@ func closestPeerFunc(closest *cluster.Address, addr cluster.Address, spf sanctio
 }
 
 // ClosestPeer returns the closest peer to a given address.
+//
+// The latency breaks the ties among the peers in the same proximity order
+// as the closest peer and, with a latency tradeoff, among the peers at most
+// latencyTradeoff proximity orders farther from the address: the fastest
+// of them is returned instead of the closest one. Only the peers closer to
+// the address than this node are considered, so that the chosen peer is
+// always a step towards the address. The closest peer is returned when no
+// such peer has a measured latency.
 func (k *Kad) ClosestPeer(addr cluster.Address, includeSelf bool, filter topology.Filter, skipPeers ...cluster.Address) (cluster.Address, error) {
 	if k.connectedPeers.Length() == 0 {
 		return cluster.Address{}, topology.ErrNotFound
@@ -1256,6 +1338,27 @@ func (k *Kad) ClosestPeer(addr cluster.Address, includeSelf bool, filter topolog
 		closest = k.base
 	}
 
+	var (
+		// minPO is the lowest proximity order of the peers which may be
+		// chosen for their latency, given the closest peer so far.
+		minPO uint8
+		// fastest holds the fastest peer in each proximity order to the
+		// address, tracked in the same pass as the closest peer.
+		fastest [cluster.MaxPO + 1]struct {
+			peer    cluster.Address
+			latency time.Duration
+		}
+	)
+	updateMinPO := func() {
+		minPO = 0
+		if po := cluster.Proximity(closest.Bytes(), addr.Bytes()); po > k.latencyTradeoff {
+			minPO = po - k.latencyTradeoff
+		}
+	}
+	if !closest.IsZero() {
+		updateMinPO()
+	}
+
 	err := k.EachPeerRev(func(peer cluster.Address, po uint8) (bool, bool, error) {
 
 		for _, a := range skipPeers {
@@ -1266,11 +1369,25 @@ func (k *Kad) ClosestPeer(addr cluster.Address, includeSelf bool, filter topolog
 
 		if closest.IsZero() {
 			closest = peer
-			return false, false, nil
+			updateMinPO()
+		} else if closer, _ := peer.Closer(addr, closest); closer {
+			closest = peer
+			updateMinPO()
 		}
 
-		if closer, _ := peer.Closer(addr, closest); closer {
-			closest = peer
+		peerPO := cluster.Proximity(peer.Bytes(), addr.Bytes())
+		if peerPO < minPO {
+			return false, false, nil
+		}
+		if closer, _ := peer.Closer(addr, k.base); !closer {
+			return false, false, nil
+		}
+		ss := k.collector.Inspect(peer)
+		if ss == nil || ss.LatencyEWMA == 0 {
+			return false, false, nil
+		}
+		if f := &fastest[peerPO]; f.peer.IsZero() || ss.LatencyEWMA < f.latency {
+			f.peer, f.latency = peer, ss.LatencyEWMA
 		}
 		return false, false, nil
 	}, filter)
@@ -1288,7 +1405,17 @@ func (k *Kad) ClosestPeer(addr cluster.Address, includeSelf bool, filter topolog
 		return cluster.Address{}, topology.ErrWantSelf
 	}
 
-	return closest, nil
+	chosen := closest
+	var latency time.Duration
+	for po := int(minPO); po < len(fastest); po++ {
+		if f := fastest[po]; !f.peer.IsZero() && (latency == 0 || f.latency < latency) {
+			chosen, latency = f.peer, f.latency
+		}
+	}
+	if !chosen.Equal(closest) {
+		k.metrics.LatencyRoutedPeers.Inc()
+	}
+	return chosen, nil
 }
 
 // IsWithinDepth returns if an address is within the neighborhood depth of a node.
@@ -1454,6 +1581,28 @@ func (k *Kad) SetStorageRadius(d uint8) {
 	}
 }
 
+// latencyDistribution returns the distribution of the latencies.
+func latencyDistribution(latencies []time.Duration) *topology.LatencyDistribution {
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var sum time.Duration
+	for _, l := range latencies {
+		sum += l
+	}
+	percentile := func(p int) int64 {
+		return latencies[(len(latencies)-1)*p/100].Milliseconds()
+	}
+
+	return &topology.LatencyDistribution{
+		Measured: len(latencies),
+		Min:      latencies[0].Milliseconds(),
+		Mean:     (sum / time.Duration(len(latencies))).Milliseconds(),
+		Median:   percentile(50),
+		P90:      percentile(90),
+		Max:      latencies[len(latencies)-1].Milliseconds(),
+	}
+}
+
 func (k *Kad) Snapshot() *topology.KadParams {
 	var infos []topology.BinInfo
 	for i := int(cluster.MaxPO); i >= 0; i-- {
@@ -1461,8 +1610,12 @@ func (k *Kad) Snapshot() *topology.KadParams {
 	}
 
 	ss := k.collector.Snapshot(time.Now())
+	latencies := make(map[uint8][]time.Duration)
 
 	_ = k.connectedPeers.EachBin(func(addr cluster.Address, po uint8) (bool, bool, error) {
+		if s, ok := ss[addr.ByteString()]; ok && s.LatencyEWMA > 0 {
+			latencies[po] = append(latencies[po], s.LatencyEWMA)
+		}
 		infos[po].BinConnected++
 		infos[po].ConnectedPeers = append(
 			infos[po].ConnectedPeers,
@@ -1474,6 +1627,10 @@ func (k *Kad) Snapshot() *topology.KadParams {
 		return false, false, nil
 	})
 
+	for po, l := range latencies {
+		infos[po].Latency = latencyDistribution(l)
+	}
+
 	// output (k.knownPeers ¬ k.connectedPeers) here to not repeat the peers we already have in the connected peers list
 	_ = k.knownPeers.EachBin(func(addr cluster.Address, po uint8) (bool, bool, error) {
 		infos[po].BinPopulation++
@@ -1248,6 +1248,58 @@ func TestKademlia_SubscribeTopologyChange(t *testing.T) {
 	})
 }
 
+func TestClosestPeerLatency(t *testing.T) {
+	var (
+		chunk = cluster.MustParseHexAddress("7000000000000000000000000000000000000000000000000000000000000000") // 0111
+		peers = []cluster.Address{
+			cluster.MustParseHexAddress("6000000000000000000000000000000000000000000000000000000000000000"), // 0110, closest to chunk
+			cluster.MustParseHexAddress("6800000000000000000000000000000000000000000000000000000000000000"), // 0110 1, po 3 to chunk
+			cluster.MustParseHexAddress("4000000000000000000000000000000000000000000000000000000000000000"), // 0100, po 2 to chunk
+		}
+		latencies = []time.Duration{100 * time.Millisecond, 10 * time.Millisecond, time.Millisecond}
+		far       = cluster.MustParseHexAddress("0000000000000000000000000000000000000000000000000000000000000000") // 0000, po 1 to chunk
+		near      = cluster.MustParseHexAddress("5000000000000000000000000000000000000000000000000000000000000000") // 0101, po 2 to chunk, closer than 0100
+	)
+
+	for _, tc := range []struct {
+		name         string
+		base         cluster.Address
+		tradeoff     uint8
+		latencies    bool
+		expectedPeer int
+	}{
+		{name: "no latencies", base: far, tradeoff: 1, expectedPeer: 0},
+		// the latency breaks the tie between the peers in the same proximity order
+		{name: "no tradeoff", base: far, latencies: true, expectedPeer: 1},
+		{name: "tradeoff", base: far, tradeoff: 1, latencies: true, expectedPeer: 2},
+		{name: "farther than self", base: near, tradeoff: 1, latencies: true, expectedPeer: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var conns int32
+			_, kad, ab, _, signer := newTestKademliaWithAddr(t, tc.base, &conns, nil, kademlia.Options{LatencyTradeoff: tc.tradeoff})
+			if err := kad.Start(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			defer kad.Close()
+
+			for i, p := range peers {
+				connectOne(t, signer, kad, ab, p, nil)
+				if tc.latencies {
+					kad.RecordPeerLatency(p, latencies[i])
+				}
+			}
+
+			peer, err := kad.ClosestPeer(chunk, false, topology.Filter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := peers[tc.expectedPeer]; !peer.Equal(want) {
+				t.Fatalf("got peer %s, want %s", peer, want)
+			}
+		})
+	}
+}
+
 func TestSnapshot(t *testing.T) {
 	var conns = new(int32)
 	sa, kad, ab, _, signer := newTestKademlia(t, conns, nil, kademlia.Options{})
@@ -1277,6 +1329,31 @@ func TestSnapshot(t *testing.T) {
 	}
 }
 
+func TestSnapshotLatency(t *testing.T) {
+	var conns int32
+	base := cluster.MustParseHexAddress("0000000000000000000000000000000000000000000000000000000000000000")
+	_, kad, ab, _, signer := newTestKademliaWithAddr(t, base, &conns, nil, kademlia.Options{})
+	if err := kad.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer kad.Close()
+
+	for i, l := range []time.Duration{time.Millisecond, 10 * time.Millisecond, 100 * time.Millisecond} {
+		peer := cluster.MustParseHexAddress(fmt.Sprintf("4%d00000000000000000000000000000000000000000000000000000000000000", i)) // po 1 to base
+		connectOne(t, signer, kad, ab, peer, nil)
+		kad.RecordPeerLatency(peer, l)
+	}
+
+	snap := kad.Snapshot()
+	if snap.Bins.Bin0.Latency != nil {
+		t.Fatal("expected no latency distribution for empty bin")
+	}
+	want := topology.LatencyDistribution{Measured: 3, Min: 1, Mean: 37, Median: 10, P90: 10, Max: 100}
+	if got := snap.Bins.Bin1.Latency; got == nil || *got != want {
+		t.Fatalf("got latency distribution %+v, want %+v", got, want)
+	}
+}
+
 func getBinPopulation(bins *topology.KadBins, po uint8) uint64 {
 	rv := reflect.ValueOf(bins)
 	bin := fmt.Sprintf("Bin%d", po)
@@ -1785,6 +1862,72 @@ func binSizes(kad *kademlia.Kad) []int {
 	return bins
 }
 
+// TestAddressBookExpiry tests that the expired addresses of the peers which
+// are not connected are periodically pruned from the address book.
+func TestAddressBookExpiry(t *testing.T) {
+	metricsDB, err := shed.NewDB("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := metricsDB.Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	var (
+		conns       int32
+		failedConns int32
+		base        = test.RandomAddress()
+		pk, _       = mopCrypto.GenerateSecp256k1Key()
+		signer      = mopCrypto.NewDefaultSigner(pk)
+		ab          = address.New(mockstate.NewStateStore(), address.WithTTL(100*time.Millisecond))
+		p2ps        = p2pMock(ab, signer, &conns, &failedConns)
+		ppm         = pingpongmock.New(func(_ context.Context, _ cluster.Address, _ ...string) (time.Duration, error) {
+			return 0, nil
+		})
+	)
+	kad, err := kademlia.New(base, ab, mock.NewDiscovery(), p2ps, ppm, metricsDB, log.Noop, kademlia.Options{
+		AddressBookPruneInterval: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2ps.SetPickyNotifier(kad)
+
+	if err := kad.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer kad.Close()
+
+	connected := test.RandomAddressAt(base, 1)
+	connectOne(t, signer, kad, ab, connected, nil)
+
+	stale := test.RandomAddressAt(base, 2)
+	staleAddress, err := mop.NewAddress(signer, nonConnectableAddress, stale, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ab.Put(stale, *staleAddress); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 50; i++ {
+		overlays, err := ab.Overlays()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(overlays) == 1 {
+			if !overlays[0].Equal(connected) {
+				t.Fatalf("got overlay %s, want %s", overlays[0], connected)
+			}
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for the address book to be pruned")
+}
+
 func newTestKademliaWithAddrDiscovery(
 	t *testing.T,
 	base cluster.Address,
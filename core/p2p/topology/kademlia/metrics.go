@@ -23,7 +23,12 @@ type metrics struct {
 	TotalOutboundConnectionFailedAttempts prometheus.Counter
 	TotalBootNodesConnectionAttempts      prometheus.Counter
 	StartAddAddressBookOverlaysTime       prometheus.Histogram
+	AddressBookPrunedPeers                prometheus.Counter
+	AddressBookPruneErrors                prometheus.Counter
+	AddressBookPruneTime                  prometheus.Histogram
+	ConnectionsSaturated                  prometheus.Counter
 	PeerLatencyEWMA                       prometheus.Histogram
+	LatencyRoutedPeers                    prometheus.Counter
 	Flag                                  prometheus.Counter
 	Unflag                                prometheus.Counter
 	Blocklist                             prometheus.Counter
@@ -132,12 +137,42 @@ func newMetrics() metrics {
 			Name:      "start_add_addressbook_overlays_time",
 			Help:      "The time spent adding overlays peers from address on kademlia start.",
 		}),
+		AddressBookPrunedPeers: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "addressbook_pruned_peers_count",
+			Help:      "The number of expired peer addresses pruned from the address book.",
+		}),
+		AddressBookPruneErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "addressbook_prune_errors_count",
+			Help:      "The number of failed address book prunes.",
+		}),
+		AddressBookPruneTime: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "addressbook_prune_time",
+			Help:      "The time spent pruning the address book.",
+		}),
+		ConnectionsSaturated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "connections_saturated_count",
+			Help:      "Number of times the balanced connections were skipped as the connection budget was used up.",
+		}),
 		PeerLatencyEWMA: prometheus.NewHistogram(prometheus.HistogramOpts{
 			Namespace: m.Namespace,
 			Subsystem: subsystem,
 			Name:      "peer_latency_ewma",
 			Help:      "Peer latency EWMA value distribution.",
 		}),
+		LatencyRoutedPeers: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "latency_routed_peers",
+			Help:      "The number of times a faster peer was chosen instead of the closest one.",
+		}),
 		Flag: prometheus.NewCounter(prometheus.CounterOpts{
 			Namespace: m.Namespace,
 			Subsystem: subsystem,
@@ -84,11 +84,23 @@ type MetricSnapshotView struct {
 	Reachability               string  `json:"reachability"`
 }
 
+// LatencyDistribution is the distribution of the latencies, in milliseconds,
+// of the connected peers in a bin.
+type LatencyDistribution struct {
+	Measured int   `json:"measured"`
+	Min      int64 `json:"min"`
+	Mean     int64 `json:"mean"`
+	Median   int64 `json:"median"`
+	P90      int64 `json:"p90"`
+	Max      int64 `json:"max"`
+}
+
 type BinInfo struct {
-	BinPopulation     uint        `json:"population"`
-	BinConnected      uint        `json:"connected"`
-	DisconnectedPeers []*PeerInfo `json:"disconnectedPeers"`
-	ConnectedPeers    []*PeerInfo `json:"connectedPeers"`
+	BinPopulation     uint                 `json:"population"`
+	BinConnected      uint                 `json:"connected"`
+	DisconnectedPeers []*PeerInfo          `json:"disconnectedPeers"`
+	ConnectedPeers    []*PeerInfo          `json:"connectedPeers"`
+	Latency           *LatencyDistribution `json:"latency,omitempty"`
 }
 
 type KadBins struct {
@@ -0,0 +1,70 @@
+package p2p
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/redesblock/mop/core/cluster"
+)
+
+// ErrNoCommonVersion is returned by NewVersionedStream when the peer does
+// not support any version of the protocol which has the requested stream.
+var ErrNoCommonVersion = errors.New("no common protocol version")
+
+// SupportedVersions returns the base and the additional versions of the
+// protocol, ordered from the highest to the lowest version.
+func (p ProtocolSpec) SupportedVersions() []VersionSpec {
+	vs := make([]VersionSpec, 0, len(p.Versions)+1)
+	vs = append(vs, VersionSpec{Version: p.Version, StreamSpecs: p.StreamSpecs})
+	vs = append(vs, p.Versions...)
+	sort.SliceStable(vs, func(i, j int) bool {
+		return compareVersions(vs[i].Version, vs[j].Version) > 0
+	})
+	return vs
+}
+
+// hasStream reports whether the version defines the named stream.
+func (v VersionSpec) hasStream(name string) bool {
+	for _, ss := range v.StreamSpecs {
+		if ss.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// NewVersionedStream creates a new stream in the highest version of the
+// protocol which defines the stream and which is supported by the peer, and
+// returns it together with the negotiated version. Lower versions are tried
+// only when the peer rejects a higher one as incompatible.
+func NewVersionedStream(ctx context.Context, s Streamer, address cluster.Address, h Headers, spec ProtocolSpec, stream string) (Stream, string, error) {
+	for _, v := range spec.SupportedVersions() {
+		if !v.hasStream(stream) {
+			continue
+		}
+		st, err := s.NewStream(ctx, address, h, spec.Name, v.Version, stream)
+		if err != nil {
+			var ise *IncompatibleStreamError
+			if errors.As(err, &ise) {
+				continue
+			}
+			return nil, "", err
+		}
+		return st, v.Version, nil
+	}
+	return nil, "", NewIncompatibleStreamError(ErrNoCommonVersion)
+}
+
+// compareVersions compares two protocol versions by their semantic version,
+// or lexically if any of them is not a valid semantic version.
+func compareVersions(a, b string) int {
+	va, erra := semver.NewVersion(a)
+	vb, errb := semver.NewVersion(b)
+	if erra != nil || errb != nil {
+		return strings.Compare(a, b)
+	}
+	return va.Compare(*vb)
+}
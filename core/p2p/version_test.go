@@ -0,0 +1,97 @@
+package p2p_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/p2p"
+	"github.com/redesblock/mop/core/p2p/streamtest"
+)
+
+func TestSupportedVersions(t *testing.T) {
+	spec := p2p.ProtocolSpec{
+		Name:    "test",
+		Version: "1.2.0",
+		Versions: []p2p.VersionSpec{
+			{Version: "1.10.0"},
+			{Version: "2.0.0"},
+			{Version: "1.0.0"},
+		},
+	}
+
+	want := []string{"2.0.0", "1.10.0", "1.2.0", "1.0.0"}
+	got := spec.SupportedVersions()
+	if len(got) != len(want) {
+		t.Fatalf("got %d versions, want %d", len(got), len(want))
+	}
+	for i, v := range got {
+		if v.Version != want[i] {
+			t.Fatalf("got version %q at %d, want %q", v.Version, i, want[i])
+		}
+	}
+}
+
+func TestNewVersionedStream(t *testing.T) {
+	var called string
+	handler := func(version string) p2p.HandlerFunc {
+		return func(context.Context, p2p.Peer, p2p.Stream) error {
+			called = version
+			return nil
+		}
+	}
+	spec := func(base string, versions ...string) p2p.ProtocolSpec {
+		s := p2p.ProtocolSpec{
+			Name:        "test",
+			Version:     base,
+			StreamSpecs: []p2p.StreamSpec{{Name: "stream", Handler: handler(base)}},
+		}
+		for _, v := range versions {
+			s.Versions = append(s.Versions, p2p.VersionSpec{
+				Version:     v,
+				StreamSpecs: []p2p.StreamSpec{{Name: "stream", Handler: handler(v)}},
+			})
+		}
+		return s
+	}
+
+	for _, tc := range []struct {
+		name        string
+		local, peer p2p.ProtocolSpec
+		want        string
+	}{
+		{name: "same versions", local: spec("1.0.0", "2.0.0"), peer: spec("1.0.0", "2.0.0"), want: "2.0.0"},
+		{name: "older peer", local: spec("1.0.0", "2.0.0"), peer: spec("1.0.0"), want: "1.0.0"},
+		{name: "newer peer", local: spec("1.0.0"), peer: spec("1.0.0", "2.0.0"), want: "1.0.0"},
+		{name: "no common version", local: spec("2.0.0"), peer: spec("1.0.0")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			called = ""
+			recorder := streamtest.New(streamtest.WithProtocols(tc.peer))
+
+			stream, version, err := p2p.NewVersionedStream(context.Background(), recorder, cluster.ZeroAddress, nil, tc.local, "stream")
+			if tc.want == "" {
+				if !errors.Is(err, p2p.ErrNoCommonVersion) {
+					t.Fatalf("got error %v, want %v", err, p2p.ErrNoCommonVersion)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := stream.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if version != tc.want {
+				t.Fatalf("got version %q, want %q", version, tc.want)
+			}
+			if _, err := recorder.Records(cluster.ZeroAddress, "test", tc.want, "stream"); err != nil {
+				t.Fatal(err)
+			}
+			if called != tc.want {
+				t.Fatalf("got handler of version %q called, want %q", called, tc.want)
+			}
+		})
+	}
+}
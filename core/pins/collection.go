@@ -0,0 +1,588 @@
+package pins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redesblock/mop/core/chunk/encryption"
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/storer/storage"
+	"github.com/redesblock/mop/core/traverser"
+)
+
+// loggerName is the tree path name of the logger for this package.
+const loggerName = "pins"
+
+const (
+	collectionPrefix        = "pin-collections/"
+	collectionMemberPrefix  = "pin-collection-members/"
+	collectionRefPrefix     = "pin-collection-refs/"
+	collectionPinnedPrefix  = "pin-collection-pinned/"
+	collectionUnsizedPrefix = "pin-collection-unsized/"
+
+	maxCollectionNameLength = 64
+)
+
+var (
+	// ErrCollectionNotFound is returned for collections that do not exist.
+	ErrCollectionNotFound = errors.New("pin collection not found")
+	// ErrCollectionExists is returned when creating a collection with the
+	// name of an existing one of the same owner.
+	ErrCollectionExists = errors.New("pin collection already exists")
+	// ErrInvalidCollectionName is returned for names that can not be used.
+	ErrInvalidCollectionName = errors.New("invalid pin collection name")
+)
+
+// Collection is a named set of pinned references.
+type Collection struct {
+	Name    string            `json:"name"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Owner   string            `json:"owner,omitempty"`
+	Created time.Time         `json:"created"`
+	// Count is the number of references in the collection.
+	Count uint64 `json:"count"`
+	// Size is the total number of pinned bytes of the sized references.
+	Size uint64 `json:"size"`
+	// Unsized is the number of references which sizes are not computed
+	// yet and are not included in the size.
+	Unsized uint64 `json:"unsized"`
+}
+
+// HasLabels reports whether the collection has all the labels.
+func (c Collection) HasLabels(labels map[string]string) bool {
+	for k, v := range labels {
+		if l, ok := c.Labels[k]; !ok || l != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ID returns the identifier of the collection.
+func (c Collection) ID() string {
+	return CollectionID(c.Owner, c.Name)
+}
+
+// Member is a reference of a collection.
+type Member struct {
+	Reference cluster.Address `json:"reference"`
+	Size      uint64          `json:"size"`
+	Sized     bool            `json:"sized"`
+	Added     time.Time       `json:"added"`
+}
+
+// Collections keeps the named collections of pinned references in the
+// state store. The references are pinned and unpinned with the Interface,
+// the collections only keep their metadata. The sizes of the added
+// references are computed in the background.
+type Collections struct {
+	store     storage.StateStorer
+	getter    storage.Getter
+	traverser traverser.Traverser
+	logger    log.Logger
+	mu        sync.Mutex
+
+	sizeC   chan struct{}
+	quit    chan struct{}
+	stopped chan struct{}
+}
+
+// NewCollections returns new collections of pinned references. The sizes
+// of the references are those of their chunks in the getter.
+func NewCollections(store storage.StateStorer, getter storage.Getter, traverser traverser.Traverser, logger log.Logger) *Collections {
+	c := &Collections{
+		store:     store,
+		getter:    getter,
+		traverser: traverser,
+		logger:    logger.WithName(loggerName).Register(),
+		sizeC:     make(chan struct{}, 1),
+		quit:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	go c.sizeWorker()
+	// size the references left unsized on shutdown
+	c.triggerSizing()
+	return c
+}
+
+// ValidCollectionName checks that the name can be used for a collection.
+func ValidCollectionName(name string) error {
+	if name == "" || len(name) > maxCollectionNameLength || strings.Contains(name, "/") {
+		return ErrInvalidCollectionName
+	}
+	return nil
+}
+
+// CollectionID returns the identifier of the collection of the owner with
+// the name. The names are scoped per owner, so that the collections of
+// different owners can have the same name.
+func CollectionID(owner, name string) string {
+	return owner + "/" + name
+}
+
+// Create creates a new empty collection of the owner.
+func (c *Collections) Create(name, owner string, labels map[string]string) (Collection, error) {
+	if err := ValidCollectionName(name); err != nil {
+		return Collection{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := CollectionID(owner, name)
+	switch _, err := c.get(id); {
+	case err == nil:
+		return Collection{}, ErrCollectionExists
+	case !errors.Is(err, ErrCollectionNotFound):
+		return Collection{}, err
+	}
+
+	col := Collection{
+		Name:    name,
+		Labels:  labels,
+		Owner:   owner,
+		Created: time.Now().UTC(),
+	}
+	if err := c.store.Put(collectionKey(id), col); err != nil {
+		return Collection{}, fmt.Errorf("unable to put collection %q: %w", id, err)
+	}
+	return col, nil
+}
+
+// Get returns the collection with the ID.
+func (c *Collections) Get(id string) (Collection, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.get(id)
+}
+
+// List returns all the collections sorted by name.
+func (c *Collections) List() ([]Collection, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cols := make([]Collection, 0)
+	err := c.store.Iterate(collectionPrefix, func(_, val []byte) (bool, error) {
+		var col Collection
+		if err := json.Unmarshal(val, &col); err != nil {
+			return true, err
+		}
+		cols = append(cols, col)
+		return false, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("iteration failed: %w", err)
+	}
+	sort.Slice(cols, func(i, j int) bool { return cols[i].Name < cols[j].Name })
+	return cols, nil
+}
+
+// Delete deletes the collection and returns its references, which stay
+// pinned.
+func (c *Collections) Delete(id string) ([]cluster.Address, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.get(id); err != nil {
+		return nil, err
+	}
+	members, err := c.members(id)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]cluster.Address, 0, len(members))
+	for _, m := range members {
+		if err := c.removeMember(id, m.Reference); err != nil {
+			return nil, err
+		}
+		refs = append(refs, m.Reference)
+	}
+	if err := c.store.Delete(collectionKey(id)); err != nil {
+		return nil, fmt.Errorf("unable to delete collection %q: %w", id, err)
+	}
+	return refs, nil
+}
+
+// Add adds the pinned reference to the collection and reports whether it
+// was not in the collection before. The pinned argument tells whether the
+// pin was created for the collection, in which case the reference is
+// released once it is in no collection. The size of the reference is
+// computed in the background.
+func (c *Collections) Add(id string, ref cluster.Address, pinned bool) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	col, err := c.get(id)
+	if err != nil {
+		return false, err
+	}
+	switch _, err := c.member(id, ref); {
+	case err == nil:
+		return false, nil
+	case !errors.Is(err, storage.ErrNotFound):
+		return false, err
+	}
+
+	m := Member{
+		Reference: ref,
+		Added:     time.Now().UTC(),
+	}
+	if err := c.store.Put(memberKey(id, ref), m); err != nil {
+		return false, fmt.Errorf("unable to put reference %q of collection %q: %w", ref, id, err)
+	}
+	if err := c.store.Put(refKey(ref, id), id); err != nil {
+		return false, fmt.Errorf("unable to put reference %q of collection %q: %w", ref, id, err)
+	}
+	if err := c.store.Put(unsizedKey(id, ref), true); err != nil {
+		return false, fmt.Errorf("unable to put reference %q of collection %q: %w", ref, id, err)
+	}
+	if pinned {
+		if err := c.store.Put(pinnedKey(ref), true); err != nil {
+			return false, fmt.Errorf("unable to put pin of reference %q: %w", ref, err)
+		}
+	}
+	col.Count++
+	col.Unsized++
+	if err := c.store.Put(collectionKey(id), col); err != nil {
+		return false, fmt.Errorf("unable to put collection %q: %w", id, err)
+	}
+
+	c.triggerSizing()
+	return true, nil
+}
+
+// Remove removes the reference from the collection and reports whether it
+// was in the collection.
+func (c *Collections) Remove(id string, ref cluster.Address) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.get(id); err != nil {
+		return false, err
+	}
+	switch _, err := c.member(id, ref); {
+	case errors.Is(err, storage.ErrNotFound):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	if err := c.removeMember(id, ref); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Members returns the references of the collection sorted by reference.
+func (c *Collections) Members(id string) ([]Member, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.get(id); err != nil {
+		return nil, err
+	}
+	return c.members(id)
+}
+
+// IDs returns the sorted IDs of the collections with the reference.
+func (c *Collections) IDs(ref cluster.Address) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prefix := refKey(ref, "")
+	ids := make([]string, 0)
+	err := c.store.Iterate(prefix, func(key, _ []byte) (bool, error) {
+		if id := strings.TrimPrefix(string(key), prefix); id != string(key) {
+			ids = append(ids, id)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("iteration failed: %w", err)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Release reports whether the pin of the reference was created for a
+// collection and the reference is in no collection anymore, in which case
+// the reference should be unpinned. The pin is released only once.
+func (c *Collections) Release(ref cluster.Address) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	collected := false
+	err := c.store.Iterate(refKey(ref, ""), func(_, _ []byte) (bool, error) {
+		collected = true
+		return true, nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("iteration failed: %w", err)
+	}
+	if collected {
+		return false, nil
+	}
+
+	var pinned bool
+	switch err := c.store.Get(pinnedKey(ref), &pinned); {
+	case errors.Is(err, storage.ErrNotFound):
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("unable to get pin of reference %q: %w", ref, err)
+	}
+	if err := c.store.Delete(pinnedKey(ref)); err != nil {
+		return false, fmt.Errorf("unable to delete pin of reference %q: %w", ref, err)
+	}
+	return true, nil
+}
+
+// KeepPin records that the reference is pinned outside of the collections,
+// so that its pin is not released once it is in no collection.
+func (c *Collections) KeepPin(ref cluster.Address) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.store.Delete(pinnedKey(ref)); err != nil {
+		return fmt.Errorf("unable to delete pin of reference %q: %w", ref, err)
+	}
+	return nil
+}
+
+// Close stops the sizing of the references.
+func (c *Collections) Close() error {
+	close(c.quit)
+	select {
+	case <-c.stopped:
+	case <-time.After(5 * time.Second):
+		return errors.New("stopping pin collections with ongoing worker goroutine")
+	}
+	return nil
+}
+
+func (c *Collections) get(id string) (Collection, error) {
+	var col Collection
+	switch err := c.store.Get(collectionKey(id), &col); {
+	case errors.Is(err, storage.ErrNotFound):
+		return Collection{}, ErrCollectionNotFound
+	case err != nil:
+		return Collection{}, fmt.Errorf("unable to get collection %q: %w", id, err)
+	}
+	return col, nil
+}
+
+func (c *Collections) member(id string, ref cluster.Address) (Member, error) {
+	var m Member
+	err := c.store.Get(memberKey(id, ref), &m)
+	return m, err
+}
+
+func (c *Collections) members(id string) ([]Member, error) {
+	members := make([]Member, 0)
+	err := c.store.Iterate(collectionMemberPrefix+id+"/", func(_, val []byte) (bool, error) {
+		var m Member
+		if err := json.Unmarshal(val, &m); err != nil {
+			return true, err
+		}
+		members = append(members, m)
+		return false, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("iteration failed: %w", err)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Reference.String() < members[j].Reference.String() })
+	return members, nil
+}
+
+func (c *Collections) removeMember(id string, ref cluster.Address) error {
+	m, err := c.member(id, ref)
+	if err != nil {
+		return err
+	}
+	col, err := c.get(id)
+	if err != nil {
+		return err
+	}
+	if err := c.store.Delete(memberKey(id, ref)); err != nil {
+		return fmt.Errorf("unable to delete reference %q of collection %q: %w", ref, id, err)
+	}
+	if err := c.store.Delete(refKey(ref, id)); err != nil {
+		return fmt.Errorf("unable to delete reference %q of collection %q: %w", ref, id, err)
+	}
+	col.Count--
+	if m.Sized {
+		col.Size -= m.Size
+	} else {
+		if err := c.store.Delete(unsizedKey(id, ref)); err != nil {
+			return fmt.Errorf("unable to delete reference %q of collection %q: %w", ref, id, err)
+		}
+		col.Unsized--
+	}
+	if err := c.store.Put(collectionKey(id), col); err != nil {
+		return fmt.Errorf("unable to put collection %q: %w", id, err)
+	}
+	return nil
+}
+
+func (c *Collections) triggerSizing() {
+	select {
+	case c.sizeC <- struct{}{}:
+	default:
+	}
+}
+
+// sizeWorker computes the sizes of the added references.
+func (c *Collections) sizeWorker() {
+	defer close(c.stopped)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-c.quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		select {
+		case <-c.quit:
+			return
+		case <-c.sizeC:
+		}
+		if err := c.sizeMembers(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			c.logger.Error(err, "pin collections: sizing failed")
+		}
+	}
+}
+
+// sizeMembers computes the sizes of the unsized references. The references
+// which can not be sized are left unsized until the next sizing.
+func (c *Collections) sizeMembers(ctx context.Context) error {
+	type unsized struct {
+		id  string
+		ref cluster.Address
+	}
+
+	c.mu.Lock()
+	var pending []unsized
+	err := c.store.Iterate(collectionUnsizedPrefix, func(key, _ []byte) (bool, error) {
+		k := strings.TrimPrefix(string(key), collectionUnsizedPrefix)
+		i := strings.LastIndex(k, "/")
+		if i < 0 {
+			return false, nil
+		}
+		ref, err := cluster.ParseHexAddress(k[i+1:])
+		if err != nil {
+			return true, err
+		}
+		pending = append(pending, unsized{id: k[:i], ref: ref})
+		return false, nil
+	})
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("iteration failed: %w", err)
+	}
+
+	for _, p := range pending {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// the size is taken without holding the lock as the traversal of
+		// large references takes long
+		size, err := c.size(ctx, p.ref)
+		if err != nil {
+			c.logger.Debug("pin collections: size reference failed", "collection", p.id, "reference", p.ref, "error", err)
+			continue
+		}
+		if err := c.setSize(p.id, p.ref, size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setSize records the size of the reference of the collection unless it
+// was removed in the meantime.
+func (c *Collections) setSize(id string, ref cluster.Address, size uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var unsized bool
+	switch err := c.store.Get(unsizedKey(id, ref), &unsized); {
+	case errors.Is(err, storage.ErrNotFound):
+		return nil
+	case err != nil:
+		return fmt.Errorf("unable to get reference %q of collection %q: %w", ref, id, err)
+	}
+	m, err := c.member(id, ref)
+	if err != nil {
+		return fmt.Errorf("unable to get reference %q of collection %q: %w", ref, id, err)
+	}
+	col, err := c.get(id)
+	if err != nil {
+		return err
+	}
+
+	m.Size = size
+	m.Sized = true
+	if err := c.store.Put(memberKey(id, ref), m); err != nil {
+		return fmt.Errorf("unable to put reference %q of collection %q: %w", ref, id, err)
+	}
+	if err := c.store.Delete(unsizedKey(id, ref)); err != nil {
+		return fmt.Errorf("unable to delete reference %q of collection %q: %w", ref, id, err)
+	}
+	col.Size += size
+	col.Unsized--
+	if err := c.store.Put(collectionKey(id), col); err != nil {
+		return fmt.Errorf("unable to put collection %q: %w", id, err)
+	}
+	return nil
+}
+
+// size returns the number of bytes of the chunks of the reference.
+func (c *Collections) size(ctx context.Context, ref cluster.Address) (uint64, error) {
+	var size uint64
+	err := c.traverser.Traverse(ctx, ref, func(leaf cluster.Address) error {
+		if len(leaf.Bytes()) == encryption.ReferenceSize {
+			leaf = cluster.NewAddress(leaf.Bytes()[:cluster.HashSize])
+		}
+		ch, err := c.getter.Get(ctx, storage.ModeGetLookup, leaf)
+		if err != nil {
+			return fmt.Errorf("unable to get chunk %q of %q: %w", leaf, ref, err)
+		}
+		size += uint64(len(ch.Data()))
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("traverser of %q failed: %w", ref, err)
+	}
+	return size, nil
+}
+
+func collectionKey(id string) string {
+	return collectionPrefix + id
+}
+
+func memberKey(id string, ref cluster.Address) string {
+	return collectionMemberPrefix + id + "/" + ref.String()
+}
+
+func refKey(ref cluster.Address, id string) string {
+	return collectionRefPrefix + ref.String() + "/" + id
+}
+
+func pinnedKey(ref cluster.Address) string {
+	return collectionPinnedPrefix + ref.String()
+}
+
+func unsizedKey(id string, ref cluster.Address) string {
+	return collectionUnsizedPrefix + id + "/" + ref.String()
+}
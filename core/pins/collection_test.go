@@ -0,0 +1,197 @@
+package pins_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/file/pipeline/builder"
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/pins"
+	statestorem "github.com/redesblock/mop/core/storer/statestore/mock"
+	"github.com/redesblock/mop/core/storer/storage"
+	storagem "github.com/redesblock/mop/core/storer/storage/mock"
+	"github.com/redesblock/mop/core/traverser"
+)
+
+func TestCollections(t *testing.T) {
+	var (
+		ctx         = context.Background()
+		storerMock  = storagem.NewStorer()
+		collections = pins.NewCollections(
+			statestorem.NewStateStore(),
+			storerMock,
+			traverser.New(storerMock),
+			log.Noop,
+		)
+		refs  = make([]cluster.Address, 2)
+		sizes = make([]uint64, 2)
+	)
+	for i, content := range []string{"Hello, Mop!", "Hello again, Mop!"} {
+		pipe := builder.NewPipelineBuilder(ctx, storerMock, storage.ModePutUpload, false)
+		ref, err := builder.FeedPipeline(ctx, pipe, strings.NewReader(content), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		refs[i] = ref
+		// the content fits a single chunk with the span
+		sizes[i] = uint64(len(content) + cluster.SpanSize)
+	}
+	size := sizes[0] + sizes[1]
+	t.Cleanup(func() {
+		if err := collections.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	for _, name := range []string{"", "a/b"} {
+		if _, err := collections.Create(name, "", nil); !errors.Is(err, pins.ErrInvalidCollectionName) {
+			t.Fatalf("Create(%q): got error %v, want %v", name, err, pins.ErrInvalidCollectionName)
+		}
+	}
+
+	labels := map[string]string{"team": "ops"}
+	if _, err := collections.Create("backups", "a", labels); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := collections.Create("site", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := collections.Create("site", "", nil); !errors.Is(err, pins.ErrCollectionExists) {
+		t.Fatalf("Create(...): got error %v, want %v", err, pins.ErrCollectionExists)
+	}
+	// the names are scoped per owner
+	other, err := collections.Create("site", "a", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backups, site := pins.CollectionID("a", "backups"), pins.CollectionID("", "site")
+	if other.ID() == site {
+		t.Fatalf("got ID %q of the collection of another owner", other.ID())
+	}
+
+	for _, add := range []struct {
+		name   string
+		ref    cluster.Address
+		pinned bool
+		want   bool
+	}{
+		{backups, refs[0], true, true},
+		{backups, refs[1], false, true},
+		{backups, refs[1], false, false},
+		{site, refs[1], false, true},
+	} {
+		added, err := collections.Add(add.name, add.ref, add.pinned)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if added != add.want {
+			t.Fatalf("Add(%q, %s): got %v, want %v", add.name, add.ref, added, add.want)
+		}
+	}
+	if _, err := collections.Add("missing", refs[0], false); !errors.Is(err, pins.ErrCollectionNotFound) {
+		t.Fatalf("Add(...): got error %v, want %v", err, pins.ErrCollectionNotFound)
+	}
+
+	col := waitSized(t, collections, backups)
+	if col.Count != 2 || col.Size != size || col.Owner != "a" || !col.HasLabels(labels) {
+		t.Fatalf("got collection %+v, want 2 references of %d bytes", col, size)
+	}
+
+	ids, err := collections.IDs(refs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{site, backups}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("got collections %v, want %v", ids, want)
+	}
+
+	removed, err := collections.Remove(backups, refs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !removed {
+		t.Fatal("reference of the collection not removed")
+	}
+	// only the pins created for the collections are released
+	for i, want := range []bool{true, false} {
+		release, err := collections.Release(refs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if release != want {
+			t.Fatalf("Release(%s): got %v, want %v", refs[i], release, want)
+		}
+	}
+	if release, err := collections.Release(refs[0]); err != nil || release {
+		t.Fatalf("Release(%s): got %v (error %v), want a released pin", refs[0], release, err)
+	}
+	members, err := collections.Members(backups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 1 || !members[0].Reference.Equal(refs[1]) || !members[0].Sized {
+		t.Fatalf("got members %v, want %s", members, refs[1])
+	}
+	if col, err = collections.Get(backups); err != nil {
+		t.Fatal(err)
+	}
+	if col.Count != 1 || col.Size != sizes[1] {
+		t.Fatalf("got collection %+v after removal", col)
+	}
+
+	deleted, err := collections.Delete(backups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 || !deleted[0].Equal(refs[1]) {
+		t.Fatalf("got deleted references %v, want %s", deleted, refs[1])
+	}
+	if _, err := collections.Get(backups); !errors.Is(err, pins.ErrCollectionNotFound) {
+		t.Fatalf("Get(...): got error %v, want %v", err, pins.ErrCollectionNotFound)
+	}
+	cols, err := collections.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cols) != 2 || cols[0].ID() != site || cols[1].ID() != other.ID() {
+		t.Fatalf("got collections %v, want site", cols)
+	}
+
+	// pins kept outside of the collections are not released
+	if _, err := collections.Add(site, refs[0], true); err != nil {
+		t.Fatal(err)
+	}
+	if err := collections.KeepPin(refs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := collections.Remove(site, refs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if release, err := collections.Release(refs[0]); err != nil || release {
+		t.Fatalf("Release(%s): got %v (error %v), want a kept pin", refs[0], release, err)
+	}
+}
+
+// waitSized waits for the sizes of the references of the collection.
+func waitSized(t *testing.T, collections *pins.Collections, id string) pins.Collection {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		col, err := collections.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if col.Unsized == 0 {
+			return col
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d unsized references of collection %q", col.Unsized, id)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
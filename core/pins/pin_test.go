@@ -27,7 +27,7 @@ func TestPinningService(t *testing.T) {
 	)
 
 	pipe := builder.NewPipelineBuilder(ctx, storerMock, storage.ModePutUpload, false)
-	ref, err := builder.FeedPipeline(ctx, pipe, strings.NewReader(content))
+	ref, err := builder.FeedPipeline(ctx, pipe, strings.NewReader(content), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
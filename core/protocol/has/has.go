@@ -0,0 +1,186 @@
+// Package has exposes the lightweight has protocol which asks
+// a peer whether it stores chunks, without transferring their data.
+package has
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/p2p"
+	"github.com/redesblock/mop/core/p2p/protobuf"
+	"github.com/redesblock/mop/core/protocol/has/pb"
+	"github.com/redesblock/mop/core/storer/storage"
+	"github.com/redesblock/mop/core/util/bitvector"
+)
+
+// loggerName is the tree path name of the logger for this package.
+const loggerName = "has"
+
+const (
+	protocolName    = "has"
+	protocolVersion = "1.0.0"
+	streamName      = "has"
+)
+
+const (
+	// MaxAddresses is the maximum number of chunk addresses in a single request.
+	MaxAddresses = 128
+
+	messageTimeout = 10 * time.Second // timeout for reading and writing a single message
+)
+
+var (
+	// ErrTooManyAddresses is returned when a request holds more than MaxAddresses addresses.
+	ErrTooManyAddresses = errors.New("has: too many addresses")
+	// ErrNoAddresses is returned when a request holds no addresses.
+	ErrNoAddresses = errors.New("has: no addresses")
+)
+
+// Interface is the has protocol client.
+type Interface interface {
+	// Has asks the peer whether it stores the chunks and returns the answer
+	// for each address in the same order.
+	Has(ctx context.Context, peer cluster.Address, addrs ...cluster.Address) ([]bool, error)
+}
+
+// Service implements the has protocol.
+type Service struct {
+	streamer p2p.Streamer
+	storer   storage.Hasser
+	logger   log.Logger
+	metrics  metrics
+}
+
+// New returns a new has protocol service which answers the requests from
+// the chunks in the storer.
+func New(streamer p2p.Streamer, storer storage.Hasser, logger log.Logger) *Service {
+	return &Service{
+		streamer: streamer,
+		storer:   storer,
+		logger:   logger.WithName(loggerName).Register(),
+		metrics:  newMetrics(),
+	}
+}
+
+func (s *Service) Protocol() p2p.ProtocolSpec {
+	return p2p.ProtocolSpec{
+		Name:    protocolName,
+		Version: protocolVersion,
+		StreamSpecs: []p2p.StreamSpec{
+			{
+				Name:    streamName,
+				Handler: s.handler,
+			},
+		},
+	}
+}
+
+// Has implements the Interface.
+func (s *Service) Has(ctx context.Context, peer cluster.Address, addrs ...cluster.Address) (has []bool, err error) {
+	if len(addrs) == 0 {
+		return nil, ErrNoAddresses
+	}
+	if len(addrs) > MaxAddresses {
+		return nil, ErrTooManyAddresses
+	}
+
+	stream, err := s.streamer.NewStream(ctx, peer, nil, protocolName, protocolVersion, streamName)
+	if err != nil {
+		return nil, fmt.Errorf("new stream: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = stream.Reset()
+		} else {
+			go stream.FullClose()
+		}
+	}()
+
+	s.metrics.RequestsSent.Inc()
+
+	req := pb.Request{Addresses: make([][]byte, 0, len(addrs))}
+	for _, a := range addrs {
+		req.Addresses = append(req.Addresses, a.Bytes())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, messageTimeout)
+	defer cancel()
+
+	w, r := protobuf.NewWriterAndReader(stream)
+	if err := w.WriteMsgWithContext(ctx, &req); err != nil {
+		return nil, fmt.Errorf("write request: %w", err)
+	}
+
+	var resp pb.Response
+	if err := r.ReadMsgWithContext(ctx, &resp); err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	bv, err := bitvector.NewFromBytes(resp.Bitvector, len(addrs))
+	if err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+
+	has = make([]bool, len(addrs))
+	for i := range has {
+		has[i] = bv.Get(i)
+	}
+	return has, nil
+}
+
+func (s *Service) handler(ctx context.Context, p p2p.Peer, stream p2p.Stream) (err error) {
+	defer func() {
+		if err != nil {
+			_ = stream.Reset()
+		} else {
+			_ = stream.FullClose()
+		}
+	}()
+
+	s.metrics.RequestsReceived.Inc()
+
+	ctx, cancel := context.WithTimeout(ctx, messageTimeout)
+	defer cancel()
+
+	w, r := protobuf.NewWriterAndReader(stream)
+	var req pb.Request
+	if err := r.ReadMsgWithContext(ctx, &req); err != nil {
+		return fmt.Errorf("read request: %w", err)
+	}
+	if len(req.Addresses) == 0 {
+		return ErrNoAddresses
+	}
+	if len(req.Addresses) > MaxAddresses {
+		s.logger.Debug("has request with too many addresses", "peer_address", p.Address, "count", len(req.Addresses))
+		return ErrTooManyAddresses
+	}
+
+	addrs := make([]cluster.Address, 0, len(req.Addresses))
+	for _, a := range req.Addresses {
+		addrs = append(addrs, cluster.NewAddress(a))
+	}
+
+	has, err := s.storer.HasMulti(ctx, addrs...)
+	if err != nil {
+		return fmt.Errorf("has multi: %w", err)
+	}
+
+	bv, err := bitvector.New(len(addrs))
+	if err != nil {
+		return fmt.Errorf("new bitvector: %w", err)
+	}
+	for i, ok := range has {
+		if ok {
+			bv.Set(i)
+		}
+	}
+
+	if err := w.WriteMsgWithContext(ctx, &pb.Response{Bitvector: bv.Bytes()}); err != nil {
+		return fmt.Errorf("write response: %w", err)
+	}
+	return nil
+}
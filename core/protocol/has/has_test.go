@@ -0,0 +1,76 @@
+package has_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/cluster/test"
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/p2p/streamtest"
+	"github.com/redesblock/mop/core/protocol/has"
+	"github.com/redesblock/mop/core/storer/storage"
+	"github.com/redesblock/mop/core/storer/storage/mock"
+	testingc "github.com/redesblock/mop/core/storer/storage/testing"
+)
+
+func TestHas(t *testing.T) {
+	logger := log.Noop
+
+	storer := mock.NewStorer()
+	var (
+		addrs []cluster.Address
+		want  []bool
+	)
+	for i := 0; i < 10; i++ {
+		ch := testingc.GenerateTestRandomChunk()
+		stored := i%3 == 0
+		if stored {
+			if _, err := storer.Put(context.Background(), storage.ModePutUpload, ch); err != nil {
+				t.Fatal(err)
+			}
+		}
+		addrs = append(addrs, ch.Address())
+		want = append(want, stored)
+	}
+
+	server := has.New(nil, storer, logger)
+	recorder := streamtest.New(streamtest.WithProtocols(server.Protocol()))
+	client := has.New(recorder, nil, logger)
+
+	peer := test.RandomAddress()
+	got, err := client.Has(context.Background(), peer, addrs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	records, err := recorder.Records(peer, "has", "1.0.0", "has")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(records); l != 1 {
+		t.Fatalf("got %d records, want %d", l, 1)
+	}
+}
+
+func TestHasLimits(t *testing.T) {
+	client := has.New(streamtest.New(), nil, log.Noop)
+	peer := test.RandomAddress()
+
+	if _, err := client.Has(context.Background(), peer); !errors.Is(err, has.ErrNoAddresses) {
+		t.Fatalf("got error %v, want %v", err, has.ErrNoAddresses)
+	}
+
+	addrs := make([]cluster.Address, has.MaxAddresses+1)
+	for i := range addrs {
+		addrs[i] = test.RandomAddress()
+	}
+	if _, err := client.Has(context.Background(), peer, addrs...); !errors.Is(err, has.ErrTooManyAddresses) {
+		t.Fatalf("got error %v, want %v", err, has.ErrTooManyAddresses)
+	}
+}
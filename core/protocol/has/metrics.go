@@ -0,0 +1,37 @@
+package has
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	m "github.com/redesblock/mop/core/metrics"
+)
+
+type metrics struct {
+	// all metrics fields must be exported
+	// to be able to return them by Metrics()
+	// using reflection
+	RequestsSent     prometheus.Counter
+	RequestsReceived prometheus.Counter
+}
+
+func newMetrics() metrics {
+	subsystem := "has"
+
+	return metrics{
+		RequestsSent: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "requests_sent_count",
+			Help:      "Number of has requests sent.",
+		}),
+		RequestsReceived: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "requests_received_count",
+			Help:      "Number of has requests received.",
+		}),
+	}
+}
+
+func (s *Service) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(s.metrics)
+}
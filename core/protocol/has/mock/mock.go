@@ -0,0 +1,19 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/redesblock/mop/core/cluster"
+)
+
+type Service struct {
+	hasFunc func(ctx context.Context, peer cluster.Address, addrs ...cluster.Address) ([]bool, error)
+}
+
+func New(hasFunc func(ctx context.Context, peer cluster.Address, addrs ...cluster.Address) ([]bool, error)) *Service {
+	return &Service{hasFunc: hasFunc}
+}
+
+func (s *Service) Has(ctx context.Context, peer cluster.Address, addrs ...cluster.Address) ([]bool, error) {
+	return s.hasFunc(ctx, peer, addrs...)
+}
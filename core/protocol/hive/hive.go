@@ -7,6 +7,7 @@
 package hive
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
 	"errors"
@@ -207,10 +208,12 @@ func (s *Service) sendPeers(ctx context.Context, peer cluster.Address, peers []c
 		}
 
 		peersRequest.Peers = append(peersRequest.Peers, &pb.MopAddress{
-			Overlay:     addr.Overlay.Bytes(),
-			Underlay:    addr.Underlay.Bytes(),
-			Signature:   addr.Signature,
-			Transaction: addr.Transaction,
+			Overlay:         addr.Overlay.Bytes(),
+			Underlay:        addr.Underlay.Bytes(),
+			Signature:       addr.Signature,
+			Transaction:     addr.Transaction,
+			Timestamp:       addr.Timestamp,
+			RecordSignature: addr.RecordSignature,
 		})
 	}
 
@@ -308,6 +311,25 @@ func (s *Service) checkAndAddPeers(ctx context.Context, peers pb.Peers) {
 		overlay := cluster.NewAddress(p.Overlay)
 		cacheOverlay := overlay.ByteString()[:cachePrefix]
 
+		// known peer with a signed record, keep the newer record
+		if len(p.RecordSignature) > 0 {
+			if known, err := s.addressBook.Get(overlay); err == nil {
+				_ = s.lru.Add(cacheOverlay, nil)
+				if err := s.sem.Acquire(ctx, 1); err != nil {
+					return
+				}
+				wg.Add(1)
+				go func(known *mop.Address, p *pb.MopAddress) {
+					defer func() {
+						s.sem.Release(1)
+						wg.Done()
+					}()
+					s.replaceRecord(ctx, known, p)
+				}(known, p)
+				continue
+			}
+		}
+
 		// cached peer, skip
 		if _, ok := s.lru.Get(cacheOverlay); ok {
 			continue
@@ -343,6 +365,14 @@ func (s *Service) checkAndAddPeers(ctx context.Context, peers pb.Peers) {
 				return
 			}
 
+			if len(newPeer.RecordSignature) > 0 {
+				if _, err := s.parseRecord(newPeer); err != nil {
+					s.metrics.InvalidRecords.Inc()
+					s.logger.Debug("invalid peer address record", "peer_address", hex.EncodeToString(newPeer.Overlay), "error", err)
+					return
+				}
+			}
+
 			ctx, cancel := context.WithTimeout(ctx, pingTimeout)
 			defer cancel()
 
@@ -360,10 +390,12 @@ func (s *Service) checkAndAddPeers(ctx context.Context, peers pb.Peers) {
 			s.metrics.ReachablePeers.Inc()
 
 			mopAddress := mop.Address{
-				Overlay:     cluster.NewAddress(newPeer.Overlay),
-				Underlay:    multiUnderlay,
-				Signature:   newPeer.Signature,
-				Transaction: newPeer.Transaction,
+				Overlay:         cluster.NewAddress(newPeer.Overlay),
+				Underlay:        multiUnderlay,
+				Signature:       newPeer.Signature,
+				Transaction:     newPeer.Transaction,
+				Timestamp:       newPeer.Timestamp,
+				RecordSignature: newPeer.RecordSignature,
 			}
 
 			err = s.addressBook.Put(mopAddress.Overlay, mopAddress)
@@ -384,3 +416,59 @@ func (s *Service) checkAndAddPeers(ctx context.Context, peers pb.Peers) {
 		s.addPeersHandler(peersToAdd...)
 	}
 }
+
+// parseRecord returns the address of the peer if its signed record is valid.
+func (s *Service) parseRecord(p *pb.MopAddress) (*mop.Address, error) {
+	addr, err := mop.ParseAddress(p.Underlay, p.Overlay, p.Signature, p.Transaction, false, s.networkID)
+	if err != nil {
+		return nil, err
+	}
+	addr.Timestamp = p.Timestamp
+	addr.RecordSignature = p.RecordSignature
+	if err := addr.VerifyRecord(s.networkID); err != nil {
+		return nil, err
+	}
+	return addr, nil
+}
+
+// replaceRecord stores the advertised address of a known peer if its signed
+// record is newer than the known one, is signed by the same owner and its
+// underlay is reachable. Otherwise the known record is kept.
+func (s *Service) replaceRecord(ctx context.Context, known *mop.Address, p *pb.MopAddress) {
+	if p.Timestamp <= known.Timestamp {
+		return
+	}
+
+	addr, err := s.parseRecord(p)
+	if err != nil {
+		s.metrics.InvalidRecords.Inc()
+		s.logger.Debug("invalid peer address record", "peer_address", known.Overlay, "error", err)
+		return
+	}
+
+	knownAddr, err := mop.ParseAddress(known.Underlay.Bytes(), known.Overlay.Bytes(), known.Signature, known.Transaction, false, s.networkID)
+	if err != nil || !bytes.Equal(knownAddr.BSCAddress, addr.BSCAddress) {
+		s.metrics.InvalidRecords.Inc()
+		s.logger.Debug("peer address record signed by a different owner", "peer_address", known.Overlay)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := s.streamer.Ping(ctx, addr.Underlay); err != nil {
+		s.metrics.PingFailureTime.Observe(time.Since(start).Seconds())
+		s.metrics.UnreachablePeers.Inc()
+		s.logger.Debug("unreachable peer underlay in address record", "peer_address", known.Overlay, "underlay", addr.Underlay)
+		return
+	}
+	s.metrics.PingTime.Observe(time.Since(start).Seconds())
+
+	if err := s.addressBook.Put(addr.Overlay, *addr); err != nil {
+		s.metrics.StorePeerErr.Inc()
+		s.logger.Warning("skipping peer address record", "peer_address", known.Overlay, "error", err)
+		return
+	}
+	s.metrics.RecordsReplaced.Inc()
+}
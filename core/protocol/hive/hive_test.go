@@ -282,6 +282,104 @@ func TestBroadcastPeers(t *testing.T) {
 	}
 }
 
+func TestBroadcastPeersRecords(t *testing.T) {
+	logger := log.Noop
+	networkID := uint64(1)
+
+	newRecord := func(t *testing.T, signer crypto.Signer, overlay cluster.Address, underlay string, ts time.Time) mop.Address {
+		t.Helper()
+		u, err := ma.NewMultiaddr(underlay)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mopAddress, err := mop.NewAddress(signer, u, overlay, networkID, tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := mopAddress.SignRecord(signer, networkID, ts); err != nil {
+			t.Fatal(err)
+		}
+		return *mopAddress
+	}
+
+	pk, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := crypto.NewDefaultSigner(pk)
+	overlay, err := crypto.NewOverlayAddress(pk.PublicKey, networkID, block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPK, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		now    = time.Now()
+		known  = newRecord(t, signer, overlay, "/ip4/127.0.0.1/udp/1", now)
+		newer  = newRecord(t, signer, overlay, "/ip4/127.0.0.1/udp/2", now.Add(time.Minute))
+		older  = newRecord(t, signer, overlay, "/ip4/127.0.0.1/udp/3", now.Add(-time.Minute))
+		forged = newRecord(t, crypto.NewDefaultSigner(otherPK), overlay, "/ip4/127.0.0.1/udp/4", now.Add(time.Hour))
+		// unreachable is a newer record with an underlay which fails the ping
+		unreachable = newRecord(t, signer, overlay, "/ip4/127.0.0.1/udp/5", now.Add(time.Hour))
+	)
+
+	testCases := map[string]struct {
+		advertised mop.Address
+		want       mop.Address
+	}{
+		"newer record replaces":      {advertised: newer, want: newer},
+		"older record ignored":       {advertised: older, want: known},
+		"forged record ignored":      {advertised: forged, want: known},
+		"unreachable record ignored": {advertised: unreachable, want: known},
+	}
+	pingErr := func(addr ma.Multiaddr) (time.Duration, error) {
+		if addr.Equal(unreachable.Underlay) {
+			return 0, errors.New("unreachable")
+		}
+		return 0, nil
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			addressbookclean := ab.New(mock.NewStateStore())
+			if err := addressbookclean.Put(overlay, known); err != nil {
+				t.Fatal(err)
+			}
+			addressbook := ab.New(mock.NewStateStore())
+			if err := addressbook.Put(overlay, tc.advertised); err != nil {
+				t.Fatal(err)
+			}
+
+			server, _ := hive.New(streamtest.New(streamtest.WithPingErr(pingErr)), addressbookclean, networkID, false, true, logger)
+			recorder := streamtest.New(streamtest.WithProtocols(server.Protocol()))
+			client, _ := hive.New(recorder, addressbook, networkID, false, true, logger)
+
+			addresee := test.RandomAddress()
+			if err := client.BroadcastPeers(context.Background(), addresee, overlay); err != nil {
+				t.Fatal(err)
+			}
+			// wait for the handler to hand over the peers before waiting for their processing
+			if _, err := recorder.Records(addresee, "hive", "1.0.0", "peers"); err != nil {
+				t.Fatal(err)
+			}
+			if err := server.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := addressbookclean.Get(overlay)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Underlay.Equal(tc.want.Underlay) || got.Timestamp != tc.want.Timestamp {
+				t.Fatalf("got address %s, want %s", got.ShortString(), tc.want.ShortString())
+			}
+		})
+	}
+}
+
 func expectOverlaysEventually(t *testing.T, exporter ab.Interface, wantOverlays []cluster.Address) {
 	var (
 		overlays []cluster.Address
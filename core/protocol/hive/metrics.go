@@ -21,6 +21,9 @@ type metrics struct {
 	PeerUnderlayErr     prometheus.Counter
 	StorePeerErr        prometheus.Counter
 	ReachablePeers      prometheus.Counter
+
+	RecordsReplaced prometheus.Counter
+	InvalidRecords  prometheus.Counter
 }
 
 func newMetrics() metrics {
@@ -99,6 +102,18 @@ func newMetrics() metrics {
 			Name:      "reachable_peers_count",
 			Help:      "Number of peers that are reachable.",
 		}),
+		RecordsReplaced: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "records_replaced_count",
+			Help:      "Number of known peer addresses replaced by newer signed records.",
+		}),
+		InvalidRecords: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "invalid_records_count",
+			Help:      "Number of peer address records with invalid signatures.",
+		}),
 	}
 }
 
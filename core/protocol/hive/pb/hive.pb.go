@@ -67,10 +67,12 @@ func (m *Peers) GetPeers() []*MopAddress {
 }
 
 type MopAddress struct {
-	Underlay    []byte `protobuf:"bytes,1,opt,name=Underlay,proto3" json:"Underlay,omitempty"`
-	Signature   []byte `protobuf:"bytes,2,opt,name=Signature,proto3" json:"Signature,omitempty"`
-	Overlay     []byte `protobuf:"bytes,3,opt,name=Overlay,proto3" json:"Overlay,omitempty"`
-	Transaction []byte `protobuf:"bytes,4,opt,name=Transaction,proto3" json:"Transaction,omitempty"`
+	Underlay        []byte `protobuf:"bytes,1,opt,name=Underlay,proto3" json:"Underlay,omitempty"`
+	Signature       []byte `protobuf:"bytes,2,opt,name=Signature,proto3" json:"Signature,omitempty"`
+	Overlay         []byte `protobuf:"bytes,3,opt,name=Overlay,proto3" json:"Overlay,omitempty"`
+	Transaction     []byte `protobuf:"bytes,4,opt,name=Transaction,proto3" json:"Transaction,omitempty"`
+	Timestamp       int64  `protobuf:"varint,5,opt,name=Timestamp,proto3" json:"Timestamp,omitempty"`
+	RecordSignature []byte `protobuf:"bytes,6,opt,name=RecordSignature,proto3" json:"RecordSignature,omitempty"`
 }
 
 func (m *MopAddress) Reset()         { *m = MopAddress{} }
@@ -134,6 +136,20 @@ func (m *MopAddress) GetTransaction() []byte {
 	return nil
 }
 
+func (m *MopAddress) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *MopAddress) GetRecordSignature() []byte {
+	if m != nil {
+		return m.RecordSignature
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Peers)(nil), "hive.Peers")
 	proto.RegisterType((*MopAddress)(nil), "hive.MopAddress")
@@ -142,20 +158,22 @@ func init() {
 func init() { proto.RegisterFile("hive.proto", fileDescriptor_d635d1ead41ba02c) }
 
 var fileDescriptor_d635d1ead41ba02c = []byte{
-	// 196 bytes of a gzipped FileDescriptorProto
+	// 234 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0xca, 0xc8, 0x2c, 0x4b,
 	0xd5, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x01, 0xb1, 0x95, 0xf4, 0xb9, 0x58, 0x03, 0x52,
 	0x53, 0x8b, 0x8a, 0x85, 0xd4, 0xb8, 0x58, 0x0b, 0x40, 0x0c, 0x09, 0x46, 0x05, 0x66, 0x0d, 0x6e,
 	0x23, 0x01, 0x3d, 0xb0, 0x52, 0xdf, 0xfc, 0x02, 0xc7, 0x94, 0x94, 0xa2, 0xd4, 0xe2, 0xe2, 0x20,
-	0x88, 0xb4, 0x52, 0x13, 0x23, 0x17, 0x17, 0x42, 0x54, 0x48, 0x8a, 0x8b, 0x23, 0x34, 0x2f, 0x25,
-	0xb5, 0x28, 0x27, 0xb1, 0x52, 0x82, 0x51, 0x81, 0x51, 0x83, 0x27, 0x08, 0xce, 0x17, 0x92, 0xe1,
-	0xe2, 0x0c, 0xce, 0x4c, 0xcf, 0x4b, 0x2c, 0x29, 0x2d, 0x4a, 0x95, 0x60, 0x02, 0x4b, 0x22, 0x04,
-	0x84, 0x24, 0xb8, 0xd8, 0xfd, 0xcb, 0x20, 0x1a, 0x99, 0xc1, 0x72, 0x30, 0xae, 0x90, 0x02, 0x17,
-	0x77, 0x48, 0x51, 0x62, 0x5e, 0x71, 0x62, 0x72, 0x49, 0x66, 0x7e, 0x9e, 0x04, 0x0b, 0x58, 0x16,
-	0x59, 0xc8, 0x49, 0xe6, 0xc4, 0x23, 0x39, 0xc6, 0x0b, 0x8f, 0xe4, 0x18, 0x1f, 0x3c, 0x92, 0x63,
-	0x9c, 0xf0, 0x58, 0x8e, 0xe1, 0xc2, 0x63, 0x39, 0x86, 0x1b, 0x8f, 0xe5, 0x18, 0xa2, 0x98, 0x0a,
-	0x92, 0x92, 0xd8, 0xc0, 0x1e, 0x34, 0x06, 0x04, 0x00, 0x00, 0xff, 0xff, 0x4d, 0xb6, 0x62, 0x8e,
-	0xee, 0x00, 0x00, 0x00,
+	0x88, 0xb4, 0xd2, 0x29, 0x46, 0x2e, 0x2e, 0x84, 0xa8, 0x90, 0x14, 0x17, 0x47, 0x68, 0x5e, 0x4a,
+	0x6a, 0x51, 0x4e, 0x62, 0xa5, 0x04, 0xa3, 0x02, 0xa3, 0x06, 0x4f, 0x10, 0x9c, 0x2f, 0x24, 0xc3,
+	0xc5, 0x19, 0x9c, 0x99, 0x9e, 0x97, 0x58, 0x52, 0x5a, 0x94, 0x2a, 0xc1, 0x04, 0x96, 0x44, 0x08,
+	0x08, 0x49, 0x70, 0xb1, 0xfb, 0x97, 0x41, 0x34, 0x32, 0x83, 0xe5, 0x60, 0x5c, 0x21, 0x05, 0x2e,
+	0xee, 0x90, 0xa2, 0xc4, 0xbc, 0xe2, 0xc4, 0xe4, 0x92, 0xcc, 0xfc, 0x3c, 0x09, 0x16, 0xb0, 0x2c,
+	0xb2, 0x10, 0xc8, 0xe4, 0x90, 0xcc, 0xdc, 0xd4, 0xe2, 0x92, 0xc4, 0xdc, 0x02, 0x09, 0x56, 0x05,
+	0x46, 0x0d, 0xe6, 0x20, 0x84, 0x80, 0x90, 0x06, 0x17, 0x7f, 0x50, 0x6a, 0x72, 0x7e, 0x51, 0x0a,
+	0xc2, 0x76, 0x36, 0xb0, 0x19, 0xe8, 0xc2, 0x4e, 0x32, 0x27, 0x1e, 0xc9, 0x31, 0x5e, 0x78, 0x24,
+	0xc7, 0xf8, 0xe0, 0x91, 0x1c, 0xe3, 0x84, 0xc7, 0x72, 0x0c, 0x17, 0x1e, 0xcb, 0x31, 0xdc, 0x78,
+	0x2c, 0xc7, 0x10, 0xc5, 0x54, 0x90, 0x94, 0xc4, 0x06, 0x0e, 0x28, 0x63, 0x40, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0x8e, 0xa9, 0xb0, 0x9b, 0x36, 0x01, 0x00, 0x00,
 }
 
 func (m *Peers) Marshal() (dAtA []byte, err error) {
@@ -215,6 +233,18 @@ func (m *MopAddress) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.RecordSignature) > 0 {
+		i -= len(m.RecordSignature)
+		copy(dAtA[i:], m.RecordSignature)
+		i = encodeVarintHive(dAtA, i, uint64(len(m.RecordSignature)))
+		i--
+		dAtA[i] = 0x32
+	}
+	if m.Timestamp != 0 {
+		i = encodeVarintHive(dAtA, i, uint64(m.Timestamp))
+		i--
+		dAtA[i] = 0x28
+	}
 	if len(m.Transaction) > 0 {
 		i -= len(m.Transaction)
 		copy(dAtA[i:], m.Transaction)
@@ -294,6 +324,13 @@ func (m *MopAddress) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovHive(uint64(l))
 	}
+	if m.Timestamp != 0 {
+		n += 1 + sovHive(uint64(m.Timestamp))
+	}
+	l = len(m.RecordSignature)
+	if l > 0 {
+		n += 1 + l + sovHive(uint64(l))
+	}
 	return n
 }
 
@@ -552,6 +589,59 @@ func (m *MopAddress) Unmarshal(dAtA []byte) error {
 				m.Transaction = []byte{}
 			}
 			iNdEx = postIndex
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Timestamp", wireType)
+			}
+			m.Timestamp = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowHive
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Timestamp |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RecordSignature", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowHive
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthHive
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthHive
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.RecordSignature = append(m.RecordSignature[:0], dAtA[iNdEx:postIndex]...)
+			if m.RecordSignature == nil {
+				m.RecordSignature = []byte{}
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipHive(dAtA[iNdEx:])
@@ -1 +1,9 @@
 package pullsync
+
+import "github.com/redesblock/mop/core/cluster"
+
+// IsLegacyPeer reports whether the peer is known not to support
+// reconciliation.
+func (s *Syncer) IsLegacyPeer(peer cluster.Address) bool {
+	return s.isLegacyPeer(peer)
+}
@@ -11,6 +11,11 @@ type metrics struct {
 	Delivered     prometheus.Counter // number of chunk deliveries
 	DbOps         prometheus.Counter // number of db ops
 	DuplicateRuid prometheus.Counter //number of duplicate RUID requests we got
+
+	Reconciled         prometheus.Counter // number of intervals synced by reconciliation
+	ReconcileFallbacks prometheus.Counter // number of reconciliations aborted in favour of an offer
+	ReconcileMatched   prometheus.Counter // number of chunks skipped by matching fingerprints
+	ReconcileRanges    prometheus.Counter // number of ranges exchanged in reconciliations
 }
 
 func newMetrics() metrics {
@@ -47,6 +52,30 @@ func newMetrics() metrics {
 			Name:      "duplicate_ruids",
 			Help:      "Total duplicate RUIDs.",
 		}),
+		Reconciled: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "reconciled_intervals",
+			Help:      "Total intervals synced by set reconciliation.",
+		}),
+		ReconcileFallbacks: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "reconcile_fallbacks",
+			Help:      "Total reconciliations aborted in favour of a full offer.",
+		}),
+		ReconcileMatched: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "reconcile_matched_chunks",
+			Help:      "Total chunks skipped by matching range fingerprints.",
+		}),
+		ReconcileRanges: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "reconcile_ranges",
+			Help:      "Total ranges exchanged in set reconciliations.",
+		}),
 	}
 }
 
@@ -406,6 +406,194 @@ func (m *Delivery) GetStamp() []byte {
 	return nil
 }
 
+type Range struct {
+	Lower       []byte `protobuf:"bytes,1,opt,name=Lower,proto3" json:"Lower,omitempty"`
+	Upper       []byte `protobuf:"bytes,2,opt,name=Upper,proto3" json:"Upper,omitempty"`
+	Fingerprint []byte `protobuf:"bytes,3,opt,name=Fingerprint,proto3" json:"Fingerprint,omitempty"`
+	Count       uint32 `protobuf:"varint,4,opt,name=Count,proto3" json:"Count,omitempty"`
+	Hashes      []byte `protobuf:"bytes,5,opt,name=Hashes,proto3" json:"Hashes,omitempty"`
+}
+
+func (m *Range) Reset()         { *m = Range{} }
+func (m *Range) String() string { return proto.CompactTextString(m) }
+func (*Range) ProtoMessage()    {}
+func (*Range) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d1dee042cf9c065c, []int{8}
+}
+func (m *Range) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Range) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Range.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Range) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Range.Merge(m, src)
+}
+func (m *Range) XXX_Size() int {
+	return m.Size()
+}
+func (m *Range) XXX_DiscardUnknown() {
+	xxx_messageInfo_Range.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Range proto.InternalMessageInfo
+
+func (m *Range) GetLower() []byte {
+	if m != nil {
+		return m.Lower
+	}
+	return nil
+}
+
+func (m *Range) GetUpper() []byte {
+	if m != nil {
+		return m.Upper
+	}
+	return nil
+}
+
+func (m *Range) GetFingerprint() []byte {
+	if m != nil {
+		return m.Fingerprint
+	}
+	return nil
+}
+
+func (m *Range) GetCount() uint32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *Range) GetHashes() []byte {
+	if m != nil {
+		return m.Hashes
+	}
+	return nil
+}
+
+type Sketch struct {
+	Topmost uint64   `protobuf:"varint,1,opt,name=Topmost,proto3" json:"Topmost,omitempty"`
+	Count   uint32   `protobuf:"varint,2,opt,name=Count,proto3" json:"Count,omitempty"`
+	Ranges  []*Range `protobuf:"bytes,3,rep,name=Ranges,proto3" json:"Ranges,omitempty"`
+}
+
+func (m *Sketch) Reset()         { *m = Sketch{} }
+func (m *Sketch) String() string { return proto.CompactTextString(m) }
+func (*Sketch) ProtoMessage()    {}
+func (*Sketch) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d1dee042cf9c065c, []int{9}
+}
+func (m *Sketch) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Sketch) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Sketch.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Sketch) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Sketch.Merge(m, src)
+}
+func (m *Sketch) XXX_Size() int {
+	return m.Size()
+}
+func (m *Sketch) XXX_DiscardUnknown() {
+	xxx_messageInfo_Sketch.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Sketch proto.InternalMessageInfo
+
+func (m *Sketch) GetTopmost() uint64 {
+	if m != nil {
+		return m.Topmost
+	}
+	return 0
+}
+
+func (m *Sketch) GetCount() uint32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *Sketch) GetRanges() []*Range {
+	if m != nil {
+		return m.Ranges
+	}
+	return nil
+}
+
+type Split struct {
+	Ranges []*Range `protobuf:"bytes,1,rep,name=Ranges,proto3" json:"Ranges,omitempty"`
+	Abort  bool     `protobuf:"varint,2,opt,name=Abort,proto3" json:"Abort,omitempty"`
+}
+
+func (m *Split) Reset()         { *m = Split{} }
+func (m *Split) String() string { return proto.CompactTextString(m) }
+func (*Split) ProtoMessage()    {}
+func (*Split) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d1dee042cf9c065c, []int{10}
+}
+func (m *Split) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Split) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Split.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Split) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Split.Merge(m, src)
+}
+func (m *Split) XXX_Size() int {
+	return m.Size()
+}
+func (m *Split) XXX_DiscardUnknown() {
+	xxx_messageInfo_Split.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Split proto.InternalMessageInfo
+
+func (m *Split) GetRanges() []*Range {
+	if m != nil {
+		return m.Ranges
+	}
+	return nil
+}
+
+func (m *Split) GetAbort() bool {
+	if m != nil {
+		return m.Abort
+	}
+	return false
+}
+
 func init() {
 	proto.RegisterType((*Syn)(nil), "pullsync.Syn")
 	proto.RegisterType((*Ack)(nil), "pullsync.Ack")
@@ -415,32 +603,42 @@ func init() {
 	proto.RegisterType((*Offer)(nil), "pullsync.Offer")
 	proto.RegisterType((*Want)(nil), "pullsync.Want")
 	proto.RegisterType((*Delivery)(nil), "pullsync.Delivery")
+	proto.RegisterType((*Range)(nil), "pullsync.Range")
+	proto.RegisterType((*Sketch)(nil), "pullsync.Sketch")
+	proto.RegisterType((*Split)(nil), "pullsync.Split")
 }
 
 func init() { proto.RegisterFile("pullsync.proto", fileDescriptor_d1dee042cf9c065c) }
 
 var fileDescriptor_d1dee042cf9c065c = []byte{
-	// 307 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x91, 0xcf, 0x4a, 0x03, 0x31,
-	0x10, 0xc6, 0x9b, 0xfd, 0x53, 0xeb, 0x50, 0x8b, 0x04, 0x91, 0x45, 0x4a, 0x2c, 0xc1, 0x43, 0x4f,
-	0x5e, 0x3c, 0x79, 0xb3, 0x7f, 0x50, 0x4f, 0x0a, 0x69, 0x51, 0xf0, 0x96, 0x6e, 0x53, 0x5d, 0xdc,
-	0x26, 0x4b, 0x92, 0x15, 0xf6, 0x2d, 0x7c, 0x2c, 0x8f, 0x3d, 0x7a, 0x94, 0xdd, 0x17, 0x91, 0x4d,
-	0x77, 0xf1, 0xe2, 0x29, 0xdf, 0x6f, 0x26, 0x33, 0xdf, 0x07, 0x03, 0x83, 0x2c, 0x4f, 0x53, 0x53,
-	0xc8, 0xf8, 0x32, 0xd3, 0xca, 0x2a, 0xdc, 0x6b, 0x99, 0x86, 0xe0, 0x2f, 0x0a, 0x49, 0xcf, 0xc1,
-	0x9f, 0xc4, 0xef, 0x38, 0x82, 0x83, 0x59, 0xae, 0x8d, 0xd2, 0x26, 0x42, 0x23, 0x7f, 0x1c, 0xb0,
-	0x16, 0xe9, 0x19, 0x04, 0x2c, 0x4f, 0xd6, 0x18, 0xef, 0xdf, 0x08, 0x8d, 0xd0, 0xf8, 0x88, 0x39,
-	0x4d, 0x87, 0xd0, 0x9d, 0x71, 0x19, 0x8b, 0xf4, 0xdf, 0xee, 0x0d, 0xf4, 0xee, 0x84, 0x65, 0x5c,
-	0xbe, 0x0a, 0x7c, 0x0c, 0xfe, 0x34, 0x91, 0xae, 0x1d, 0xb2, 0x5a, 0xd6, 0x13, 0xb7, 0x5a, 0x6d,
-	0x23, 0x6f, 0x84, 0xc6, 0x01, 0x73, 0x1a, 0x0f, 0xc0, 0x5b, 0xaa, 0xc8, 0x77, 0x15, 0x6f, 0xa9,
-	0xe8, 0x35, 0x84, 0x8f, 0x9b, 0x8d, 0xd0, 0x75, 0xbc, 0xa5, 0xca, 0xb6, 0xca, 0x58, 0xb7, 0x22,
-	0x60, 0x2d, 0xe2, 0x53, 0xe8, 0xde, 0x73, 0xf3, 0x26, 0x8c, 0x5b, 0xd4, 0x67, 0x0d, 0xd1, 0x0b,
-	0x08, 0x9e, 0xb9, 0xb4, 0x78, 0x08, 0x87, 0xd3, 0xc4, 0x3e, 0x89, 0xd8, 0x2a, 0xed, 0x66, 0xfb,
-	0xec, 0xaf, 0x40, 0x1f, 0xa0, 0x37, 0x17, 0x69, 0xf2, 0x21, 0x74, 0x51, 0x7b, 0x4c, 0xd6, 0x6b,
-	0x2d, 0x8c, 0x69, 0xfe, 0xb5, 0x58, 0x47, 0x9d, 0x73, 0xcb, 0x1b, 0x07, 0xa7, 0xf1, 0x09, 0x84,
-	0x0b, 0xcb, 0xb7, 0x99, 0x4b, 0xdb, 0x67, 0x7b, 0x98, 0x0e, 0xbf, 0x4a, 0x82, 0x76, 0x25, 0x41,
-	0x3f, 0x25, 0x41, 0x9f, 0x15, 0xe9, 0xec, 0x2a, 0xd2, 0xf9, 0xae, 0x48, 0xe7, 0xc5, 0xcb, 0x56,
-	0xab, 0xae, 0xbb, 0xc1, 0xd5, 0x6f, 0x00, 0x00, 0x00, 0xff, 0xff, 0xf7, 0xd1, 0x30, 0xa1, 0x95,
-	0x01, 0x00, 0x00,
+	// 422 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x92, 0x4f, 0x8b, 0x13, 0x31,
+	0x18, 0xc6, 0x9b, 0xf9, 0x67, 0x7d, 0xb7, 0xae, 0x12, 0x16, 0x19, 0xa4, 0x8c, 0x43, 0x10, 0xec,
+	0x69, 0x0f, 0x7a, 0xf2, 0x66, 0xdb, 0xa5, 0x7a, 0x10, 0x85, 0xb4, 0x2a, 0x78, 0x4b, 0xa7, 0xd9,
+	0xdd, 0x61, 0xa7, 0x49, 0x48, 0x52, 0xa5, 0x67, 0xbf, 0x80, 0x1f, 0xcb, 0xe3, 0x1e, 0x3d, 0x4a,
+	0xfb, 0x45, 0x24, 0xc9, 0xcc, 0x5a, 0x41, 0x7a, 0x9a, 0xf7, 0xf7, 0x66, 0x9e, 0xe7, 0x79, 0xdf,
+	0x10, 0x38, 0x55, 0x9b, 0xa6, 0x31, 0x5b, 0x51, 0x9d, 0x2b, 0x2d, 0xad, 0xc4, 0xfd, 0x8e, 0x49,
+	0x0a, 0xf1, 0x7c, 0x2b, 0xc8, 0x53, 0x88, 0xc7, 0xd5, 0x0d, 0xce, 0xe1, 0xde, 0x74, 0xa3, 0x8d,
+	0xd4, 0x26, 0x47, 0x65, 0x3c, 0x4a, 0x68, 0x87, 0xe4, 0x09, 0x24, 0x74, 0x53, 0xaf, 0x30, 0x0e,
+	0xdf, 0x1c, 0x95, 0x68, 0xf4, 0x80, 0xfa, 0x9a, 0x0c, 0x21, 0x9b, 0x32, 0x51, 0xf1, 0xe6, 0xbf,
+	0xa7, 0xaf, 0xa1, 0xff, 0x86, 0x5b, 0xca, 0xc4, 0x15, 0xc7, 0x8f, 0x20, 0x9e, 0xd4, 0xc2, 0x1f,
+	0xa7, 0xd4, 0x95, 0x4e, 0x31, 0xd3, 0x72, 0x9d, 0x47, 0x25, 0x1a, 0x25, 0xd4, 0xd7, 0xf8, 0x14,
+	0xa2, 0x85, 0xcc, 0x63, 0xdf, 0x89, 0x16, 0x92, 0xbc, 0x82, 0xf4, 0xc3, 0xe5, 0x25, 0xd7, 0x6e,
+	0xbc, 0x85, 0x54, 0x6b, 0x69, 0xac, 0xb7, 0x48, 0x68, 0x87, 0xf8, 0x31, 0x64, 0x6f, 0x99, 0xb9,
+	0xe6, 0xc6, 0x1b, 0x0d, 0x68, 0x4b, 0xe4, 0x19, 0x24, 0x9f, 0x99, 0xb0, 0x78, 0x08, 0xf7, 0x27,
+	0xb5, 0xfd, 0xc4, 0x2b, 0x2b, 0xb5, 0xd7, 0x0e, 0xe8, 0xdf, 0x06, 0x79, 0x0f, 0xfd, 0x0b, 0xde,
+	0xd4, 0x5f, 0xb9, 0xde, 0xba, 0x8c, 0xf1, 0x6a, 0xa5, 0xb9, 0x31, 0xed, 0x7f, 0x1d, 0xba, 0x51,
+	0x2f, 0x98, 0x65, 0x6d, 0x82, 0xaf, 0xf1, 0x19, 0xa4, 0x73, 0xcb, 0xd6, 0xca, 0x4f, 0x3b, 0xa0,
+	0x01, 0xc8, 0x77, 0x04, 0x69, 0x58, 0xf8, 0x0c, 0xd2, 0x77, 0xf2, 0x1b, 0xef, 0x32, 0x03, 0xb8,
+	0xee, 0x47, 0xa5, 0xb8, 0x6e, 0xad, 0x02, 0xe0, 0x12, 0x4e, 0x66, 0xb5, 0xb8, 0xe2, 0x5a, 0xe9,
+	0x5a, 0xd8, 0xd6, 0xf1, 0xb0, 0xe5, 0x74, 0x53, 0xb9, 0x11, 0x36, 0x4f, 0xfc, 0xfd, 0x06, 0x38,
+	0xd8, 0x3d, 0xfd, 0x67, 0x77, 0x06, 0xd9, 0xfc, 0x86, 0xdb, 0xea, 0xfa, 0xc8, 0xbd, 0xdd, 0x39,
+	0x46, 0x87, 0x8e, 0xcf, 0x21, 0xf3, 0xe3, 0x9b, 0x3c, 0x2e, 0xe3, 0xd1, 0xc9, 0x8b, 0x87, 0xe7,
+	0x77, 0xef, 0xc7, 0xf7, 0x69, 0x7b, 0x4c, 0x66, 0x90, 0xce, 0x55, 0x53, 0x1f, 0x2a, 0xd0, 0x51,
+	0x85, 0x0b, 0x1c, 0x2f, 0xa5, 0x0e, 0x81, 0x7d, 0x1a, 0x60, 0x32, 0xfc, 0xb9, 0x2b, 0xd0, 0xed,
+	0xae, 0x40, 0xbf, 0x77, 0x05, 0xfa, 0xb1, 0x2f, 0x7a, 0xb7, 0xfb, 0xa2, 0xf7, 0x6b, 0x5f, 0xf4,
+	0xbe, 0x44, 0x6a, 0xb9, 0xcc, 0xfc, 0xa3, 0x7d, 0xf9, 0x27, 0x00, 0x00, 0xff, 0xff, 0xfb, 0xc9,
+	0xba, 0x05, 0xc6, 0x02, 0x00, 0x00,
 }
 
 func (m *Syn) Marshal() (dAtA []byte, err error) {
@@ -710,6 +908,156 @@ func (m *Delivery) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
+func (m *Range) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Range) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Range) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Hashes) > 0 {
+		i -= len(m.Hashes)
+		copy(dAtA[i:], m.Hashes)
+		i = encodeVarintPullsync(dAtA, i, uint64(len(m.Hashes)))
+		i--
+		dAtA[i] = 0x2a
+	}
+	if m.Count != 0 {
+		i = encodeVarintPullsync(dAtA, i, uint64(m.Count))
+		i--
+		dAtA[i] = 0x20
+	}
+	if len(m.Fingerprint) > 0 {
+		i -= len(m.Fingerprint)
+		copy(dAtA[i:], m.Fingerprint)
+		i = encodeVarintPullsync(dAtA, i, uint64(len(m.Fingerprint)))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if len(m.Upper) > 0 {
+		i -= len(m.Upper)
+		copy(dAtA[i:], m.Upper)
+		i = encodeVarintPullsync(dAtA, i, uint64(len(m.Upper)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.Lower) > 0 {
+		i -= len(m.Lower)
+		copy(dAtA[i:], m.Lower)
+		i = encodeVarintPullsync(dAtA, i, uint64(len(m.Lower)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Sketch) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Sketch) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Sketch) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Ranges) > 0 {
+		for iNdEx := len(m.Ranges) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Ranges[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPullsync(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x1a
+		}
+	}
+	if m.Count != 0 {
+		i = encodeVarintPullsync(dAtA, i, uint64(m.Count))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.Topmost != 0 {
+		i = encodeVarintPullsync(dAtA, i, uint64(m.Topmost))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Split) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Split) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Split) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Abort {
+		i--
+		if m.Abort {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Ranges) > 0 {
+		for iNdEx := len(m.Ranges) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Ranges[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPullsync(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
 func encodeVarintPullsync(dAtA []byte, offset int, v uint64) int {
 	offset -= sovPullsync(v)
 	base := offset
@@ -838,19 +1186,86 @@ func (m *Delivery) Size() (n int) {
 	return n
 }
 
-func sovPullsync(x uint64) (n int) {
-	return (math_bits.Len64(x|1) + 6) / 7
+func (m *Range) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Lower)
+	if l > 0 {
+		n += 1 + l + sovPullsync(uint64(l))
+	}
+	l = len(m.Upper)
+	if l > 0 {
+		n += 1 + l + sovPullsync(uint64(l))
+	}
+	l = len(m.Fingerprint)
+	if l > 0 {
+		n += 1 + l + sovPullsync(uint64(l))
+	}
+	if m.Count != 0 {
+		n += 1 + sovPullsync(uint64(m.Count))
+	}
+	l = len(m.Hashes)
+	if l > 0 {
+		n += 1 + l + sovPullsync(uint64(l))
+	}
+	return n
 }
-func sozPullsync(x uint64) (n int) {
-	return sovPullsync(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+
+func (m *Sketch) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Topmost != 0 {
+		n += 1 + sovPullsync(uint64(m.Topmost))
+	}
+	if m.Count != 0 {
+		n += 1 + sovPullsync(uint64(m.Count))
+	}
+	if len(m.Ranges) > 0 {
+		for _, e := range m.Ranges {
+			l = e.Size()
+			n += 1 + l + sovPullsync(uint64(l))
+		}
+	}
+	return n
 }
-func (m *Syn) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
+
+func (m *Split) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Ranges) > 0 {
+		for _, e := range m.Ranges {
+			l = e.Size()
+			n += 1 + l + sovPullsync(uint64(l))
+		}
+	}
+	if m.Abort {
+		n += 2
+	}
+	return n
+}
+
+func sovPullsync(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozPullsync(x uint64) (n int) {
+	return sovPullsync(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *Syn) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
 			if shift >= 64 {
 				return ErrIntOverflowPullsync
 			}
@@ -1604,6 +2019,437 @@ func (m *Delivery) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
+func (m *Range) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPullsync
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Range: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Range: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Lower", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPullsync
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthPullsync
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPullsync
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Lower = append(m.Lower[:0], dAtA[iNdEx:postIndex]...)
+			if m.Lower == nil {
+				m.Lower = []byte{}
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Upper", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPullsync
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthPullsync
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPullsync
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Upper = append(m.Upper[:0], dAtA[iNdEx:postIndex]...)
+			if m.Upper == nil {
+				m.Upper = []byte{}
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Fingerprint", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPullsync
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthPullsync
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPullsync
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Fingerprint = append(m.Fingerprint[:0], dAtA[iNdEx:postIndex]...)
+			if m.Fingerprint == nil {
+				m.Fingerprint = []byte{}
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Count", wireType)
+			}
+			m.Count = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPullsync
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Count |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Hashes", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPullsync
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthPullsync
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPullsync
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Hashes = append(m.Hashes[:0], dAtA[iNdEx:postIndex]...)
+			if m.Hashes == nil {
+				m.Hashes = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPullsync(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthPullsync
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Sketch) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPullsync
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Sketch: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Sketch: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Topmost", wireType)
+			}
+			m.Topmost = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPullsync
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Topmost |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Count", wireType)
+			}
+			m.Count = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPullsync
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Count |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ranges", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPullsync
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPullsync
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPullsync
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Ranges = append(m.Ranges, &Range{})
+			if err := m.Ranges[len(m.Ranges)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPullsync(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthPullsync
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Split) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPullsync
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Split: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Split: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ranges", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPullsync
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPullsync
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPullsync
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Ranges = append(m.Ranges, &Range{})
+			if err := m.Ranges[len(m.Ranges)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Abort", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPullsync
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Abort = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPullsync(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthPullsync
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
 func skipPullsync(dAtA []byte) (n int, err error) {
 	l := len(dAtA)
 	iNdEx := 0
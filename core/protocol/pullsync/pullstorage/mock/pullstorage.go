@@ -96,6 +96,21 @@ func (s *PullStorage) IntervalChunks(_ context.Context, bin uint8, from, to uint
 	return r.chunks, r.topmost, r.err
 }
 
+// BinAddresses returns the addresses of the chunks of the mock storage. The
+// bin is ignored as the mock is not aware of the base address.
+func (s *PullStorage) BinAddresses(_ context.Context, _ uint8, limit int) (addrs []cluster.Address, err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for _, c := range s.chunks {
+		if len(addrs) == limit {
+			break
+		}
+		addrs = append(addrs, c.Address())
+	}
+	return addrs, nil
+}
+
 func (s *PullStorage) Cursors(ctx context.Context) (curs []uint64, err error) {
 	return s.cursors, s.cursorsErr
 }
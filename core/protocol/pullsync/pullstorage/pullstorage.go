@@ -26,6 +26,8 @@ var (
 type Storer interface {
 	// IntervalChunks collects chunk for a requested interval.
 	IntervalChunks(ctx context.Context, bin uint8, from, to uint64, limit int) (chunks []cluster.Address, topmost uint64, err error)
+	// BinAddresses collects the addresses of at most limit chunks in the bin.
+	BinAddresses(ctx context.Context, bin uint8, limit int) ([]cluster.Address, error)
 	// Cursors gets the last BinID for every bin in the local storage
 	Cursors(ctx context.Context) ([]uint64, error)
 	// Get chunks.
@@ -142,6 +144,38 @@ func (s *PullStorer) IntervalChunks(ctx context.Context, bin uint8, from, to uin
 	return r.chs, r.topmost, nil
 }
 
+// BinAddresses collects the addresses of at most limit chunks in the bin.
+// Unlike IntervalChunks it does not wait for new chunks, only the chunks
+// present at the time of the call are collected.
+func (s *PullStorer) BinAddresses(ctx context.Context, bin uint8, limit int) ([]cluster.Address, error) {
+	last, err := s.Storer.LastPullSubscriptionBinID(bin)
+	if err != nil {
+		return nil, err
+	}
+	if last == 0 {
+		return nil, nil
+	}
+
+	ch, dbClosed, stop := s.SubscribePull(ctx, bin, 0, last)
+	defer stop()
+
+	var addrs []cluster.Address
+	for len(addrs) < limit {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return addrs, nil
+			}
+			addrs = append(addrs, v.Address)
+		case <-dbClosed:
+			return nil, ErrDbClosed
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return addrs, nil
+}
+
 // Cursors gets the last BinID for every bin in the local storage
 func (s *PullStorer) Cursors(ctx context.Context) (curs []uint64, err error) {
 	curs = make([]uint64, cluster.MaxBins)
@@ -36,6 +36,9 @@ const (
 	cursorStreamName = "cursors"
 	cancelStreamName = "cancel"
 
+	protocolVersionReconcile = "2.0.0"
+	reconcileStreamName      = "reconcile"
+
 	rateWindowSize = 5 * time.Minute // rate tracker window size
 )
 
@@ -69,6 +72,7 @@ type Interface interface {
 }
 
 type Syncer struct {
+	base       cluster.Address
 	streamer   p2p.Streamer
 	metrics    metrics
 	logger     log.Logger
@@ -82,22 +86,29 @@ type Syncer struct {
 	ruidMtx sync.Mutex
 	ruidCtx map[string]map[uint32]func()
 
+	// legacyPeers are the connected peers which do not support the
+	// reconcile stream.
+	legacyMtx   sync.Mutex
+	legacyPeers map[string]struct{}
+
 	Interface
 	io.Closer
 }
 
-func New(streamer p2p.Streamer, storage pullstorage.Storer, unwrap func(cluster.Chunk), validStamp voucher.ValidStampFn, logger log.Logger) *Syncer {
+func New(base cluster.Address, streamer p2p.Streamer, storage pullstorage.Storer, unwrap func(cluster.Chunk), validStamp voucher.ValidStampFn, logger log.Logger) *Syncer {
 	return &Syncer{
-		streamer:   streamer,
-		storage:    storage,
-		metrics:    newMetrics(),
-		unwrap:     unwrap,
-		validStamp: validStamp,
-		logger:     logger.WithName(loggerName).Register(),
-		ruidCtx:    make(map[string]map[uint32]func()),
-		wg:         sync.WaitGroup{},
-		quit:       make(chan struct{}),
-		rate:       rate.New(rateWindowSize),
+		base:        base,
+		streamer:    streamer,
+		storage:     storage,
+		metrics:     newMetrics(),
+		unwrap:      unwrap,
+		validStamp:  validStamp,
+		logger:      logger.WithName(loggerName).Register(),
+		ruidCtx:     make(map[string]map[uint32]func()),
+		legacyPeers: make(map[string]struct{}),
+		wg:          sync.WaitGroup{},
+		quit:        make(chan struct{}),
+		rate:        rate.New(rateWindowSize),
 	}
 }
 
@@ -119,19 +130,66 @@ func (s *Syncer) Protocol() p2p.ProtocolSpec {
 				Handler: s.cancelHandler,
 			},
 		},
+		// historical intervals are synced by set reconciliation over a
+		// separate protocol version, so that peers which only support the
+		// offer based syncing keep working
+		Versions: []p2p.VersionSpec{
+			{
+				Version: protocolVersionReconcile,
+				StreamSpecs: []p2p.StreamSpec{
+					{
+						Name:    reconcileStreamName,
+						Handler: s.reconcileHandler,
+					},
+				},
+			},
+		},
+		DisconnectIn:  s.disconnect,
+		DisconnectOut: s.disconnect,
 	}
 }
 
+// disconnect forgets whether the peer supports reconciliation, as it may
+// be upgraded when it reconnects.
+func (s *Syncer) disconnect(peer p2p.Peer) error {
+	s.legacyMtx.Lock()
+	defer s.legacyMtx.Unlock()
+	delete(s.legacyPeers, peer.Address.ByteString())
+	return nil
+}
+
+// newStream creates a new stream in the highest version of the protocol
+// which defines the stream and which is supported by the peer.
+func (s *Syncer) newStream(ctx context.Context, peer cluster.Address, stream string) (p2p.Stream, error) {
+	st, _, err := p2p.NewVersionedStream(ctx, s.streamer, peer, nil, s.Protocol(), stream)
+	return st, err
+}
+
 // SyncInterval syncs a requested interval from the given peer.
 // It returns the BinID of highest chunk that was synced from the given interval.
 // If the requested interval is too large, the downstream peer has the liberty to
 // provide less chunks than requested.
+// Historical intervals are synced by reconciling the chunk sets if the peer
+// supports it, otherwise the addresses of all chunks in the interval are
+// offered.
 func (s *Syncer) SyncInterval(ctx context.Context, peer cluster.Address, bin uint8, from, to uint64) (topmost uint64, ruid uint32, err error) {
+	if to != MaxCursor && !s.isLegacyPeer(peer) {
+		topmost, ruid, err = s.syncIntervalReconcile(ctx, peer, bin, from, to)
+		if !errors.Is(err, errReconcileFallback) {
+			return topmost, ruid, err
+		}
+	}
+	return s.syncIntervalOffer(ctx, peer, bin, from, to)
+}
+
+// syncIntervalOffer syncs the interval by receiving the offer of the
+// addresses of all chunks in the interval.
+func (s *Syncer) syncIntervalOffer(ctx context.Context, peer cluster.Address, bin uint8, from, to uint64) (topmost uint64, ruid uint32, err error) {
 	isLiveSync := to == MaxCursor
 	loggerV2 := s.logger.V(2).Register()
 
 	var ru pb.Ruid
-	stream, err := s.streamer.NewStream(ctx, peer, nil, protocolName, protocolVersion, streamName)
+	stream, err := s.newStream(ctx, peer, streamName)
 	if err != nil {
 		return 0, 0, fmt.Errorf("new stream: %w", err)
 	}
@@ -144,13 +202,10 @@ func (s *Syncer) SyncInterval(ctx context.Context, peer cluster.Address, bin uin
 		}
 	}()
 
-	b := make([]byte, 4)
-	_, err = rand.Read(b)
+	ru.Ruid, err = newRuid()
 	if err != nil {
-		return 0, 0, fmt.Errorf("crypto rand: %w", err)
+		return 0, 0, err
 	}
-
-	ru.Ruid = binary.BigEndian.Uint32(b)
 	loggerV2.Debug("syncing peer", "peer_address", peer, "ruid", ru.Ruid, "bin", bin, "from", from, "to", to)
 
 	w, r := protobuf.NewWriterAndReader(stream)
@@ -179,29 +234,39 @@ func (s *Syncer) SyncInterval(ctx context.Context, peer cluster.Address, bin uin
 		return offer.Topmost, ru.Ruid, nil
 	}
 
+	if err = s.receiveOffered(ctx, w, r, offer.Hashes, isLiveSync); err != nil {
+		return 0, ru.Ruid, err
+	}
+
+	return offer.Topmost, ru.Ruid, nil
+}
+
+// receiveOffered sends the want list for the offered hashes to the peer and
+// stores the delivered chunks.
+func (s *Syncer) receiveOffered(ctx context.Context, w protobuf.Writer, r protobuf.Reader, hashes []byte, isLiveSync bool) (err error) {
 	var (
-		bvLen      = len(offer.Hashes) / cluster.HashSize
+		bvLen      = len(hashes) / cluster.HashSize
 		wantChunks = make(map[string]struct{})
 		ctr        = 0
 	)
 
 	bv, err := bitvector.New(bvLen)
 	if err != nil {
-		return 0, ru.Ruid, fmt.Errorf("new bitvector: %w", err)
+		return fmt.Errorf("new bitvector: %w", err)
 	}
 
-	for i := 0; i < len(offer.Hashes); i += cluster.HashSize {
-		a := cluster.NewAddress(offer.Hashes[i : i+cluster.HashSize])
+	for i := 0; i < len(hashes); i += cluster.HashSize {
+		a := cluster.NewAddress(hashes[i : i+cluster.HashSize])
 		if a.Equal(cluster.ZeroAddress) {
 			// i'd like to have this around to see we don't see any of these in the logs
 			s.logger.Error(nil, "syncer got a zero address hash on offer")
-			return 0, ru.Ruid, fmt.Errorf("zero address on offer")
+			return fmt.Errorf("zero address on offer")
 		}
 		s.metrics.Offered.Inc()
 		s.metrics.DbOps.Inc()
 		have, err := s.storage.Has(ctx, a)
 		if err != nil {
-			return 0, ru.Ruid, fmt.Errorf("storage has: %w", err)
+			return fmt.Errorf("storage has: %w", err)
 		}
 		if !have {
 			wantChunks[a.ByteString()] = struct{}{}
@@ -213,7 +278,7 @@ func (s *Syncer) SyncInterval(ctx context.Context, peer cluster.Address, bin uin
 
 	wantMsg := &pb.Want{BitVector: bv.Bytes()}
 	if err = w.WriteMsgWithContext(ctx, wantMsg); err != nil {
-		return 0, ru.Ruid, fmt.Errorf("write want: %w", err)
+		return fmt.Errorf("write want: %w", err)
 	}
 
 	// if ctr is zero, it means we don't want any chunk in the batch
@@ -233,10 +298,10 @@ func (s *Syncer) SyncInterval(ctx context.Context, peer cluster.Address, bin uin
 		}
 
 		addr := cluster.NewAddress(delivery.Address)
-		if _, ok := wantChunks[addr.String()]; !ok {
+		if _, ok := wantChunks[addr.ByteString()]; !ok {
 			// this is fatal for the entire batch, return the
 			// error and don't write the partial batch.
-			return 0, ru.Ruid, ErrUnsolicitedChunk
+			return ErrUnsolicitedChunk
 		}
 
 		delete(wantChunks, addr.ByteString())
@@ -253,31 +318,41 @@ func (s *Syncer) SyncInterval(ctx context.Context, peer cluster.Address, bin uin
 		} else if !soc.Valid(chunk) {
 			// this is fatal for the entire batch, return the
 			// error and don't write the partial batch.
-			return 0, ru.Ruid, cluster.ErrInvalidChunk
+			return cluster.ErrInvalidChunk
 		}
 		chunksToPut = append(chunksToPut, chunk)
+		// store large deliveries in batches of the offer page size
+		if len(chunksToPut) == maxPage {
+			if err := s.putChunks(ctx, chunksToPut, isLiveSync); err != nil {
+				return err
+			}
+			chunksToPut = nil
+		}
 	}
 	if len(chunksToPut) > 0 {
-		if !isLiveSync {
-			s.rate.Add(len(chunksToPut))
-		}
-		s.metrics.DbOps.Inc()
-		ctx, cancel := context.WithTimeout(ctx, storagePutTimeout)
-		defer cancel()
-		if ierr := s.storage.Put(ctx, storage.ModePutSync, chunksToPut...); ierr != nil {
+		if ierr := s.putChunks(ctx, chunksToPut, isLiveSync); ierr != nil {
 			if err != nil {
-				ierr = fmt.Errorf(", chainsync err: %w", err)
+				ierr = fmt.Errorf("%v, chainsync err: %w", ierr, err)
 			}
-			return 0, ru.Ruid, fmt.Errorf("delivery put: %w", ierr)
+			return ierr
 		}
 	}
 	// there might have been an error in the for loop above,
 	// return it if it indeed happened
-	if err != nil {
-		return 0, ru.Ruid, err
-	}
+	return err
+}
 
-	return offer.Topmost, ru.Ruid, nil
+func (s *Syncer) putChunks(ctx context.Context, chs []cluster.Chunk, isLiveSync bool) error {
+	if !isLiveSync {
+		s.rate.Add(len(chs))
+	}
+	s.metrics.DbOps.Inc()
+	ctx, cancel := context.WithTimeout(ctx, storagePutTimeout)
+	defer cancel()
+	if err := s.storage.Put(ctx, storage.ModePutSync, chs...); err != nil {
+		return fmt.Errorf("delivery put: %w", err)
+	}
+	return nil
 }
 
 // handler handles an incoming request to chainsync an interval
@@ -297,34 +372,8 @@ func (s *Syncer) handler(ctx context.Context, p p2p.Peer, stream p2p.Stream) (er
 		return fmt.Errorf("send ruid: %w", err)
 	}
 	loggerV2.Debug("peer pulling", "peer_address", p.Address, "ruid", ru.Ruid)
-	ctx, cancel := context.WithCancel(ctx)
-
-	s.ruidMtx.Lock()
-	if _, ok := s.ruidCtx[p.Address.ByteString()]; !ok {
-		s.ruidCtx[p.Address.ByteString()] = make(map[uint32]func())
-	}
-	if c, ok := s.ruidCtx[p.Address.ByteString()][ru.Ruid]; ok {
-		s.metrics.DuplicateRuid.Inc()
-		c()
-	}
-	s.ruidCtx[p.Address.ByteString()][ru.Ruid] = cancel
-	s.ruidMtx.Unlock()
-	cc := make(chan struct{})
-	defer close(cc)
-	go func() {
-		select {
-		case <-s.quit:
-		case <-ctx.Done():
-		case <-cc:
-		}
-		cancel()
-		s.ruidMtx.Lock()
-		delete(s.ruidCtx[p.Address.ByteString()], ru.Ruid)
-		if len(s.ruidCtx[p.Address.ByteString()]) == 0 {
-			delete(s.ruidCtx, p.Address.ByteString())
-		}
-		s.ruidMtx.Unlock()
-	}()
+	ctx, done := s.registerRuid(ctx, p.Address, ru.Ruid)
+	defer done()
 
 	select {
 	case <-s.quit:
@@ -361,6 +410,47 @@ func (s *Syncer) handler(ctx context.Context, p p2p.Peer, stream p2p.Stream) (er
 		return nil
 	}
 
+	return s.deliverWanted(ctx, w, r, offer)
+}
+
+// registerRuid registers the cancellation of the returned context under the
+// ruid of the peer, so the request can be cancelled by the peer. The returned
+// function must be called once the request is served.
+func (s *Syncer) registerRuid(ctx context.Context, peer cluster.Address, ruid uint32) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	s.ruidMtx.Lock()
+	if _, ok := s.ruidCtx[peer.ByteString()]; !ok {
+		s.ruidCtx[peer.ByteString()] = make(map[uint32]func())
+	}
+	if c, ok := s.ruidCtx[peer.ByteString()][ruid]; ok {
+		s.metrics.DuplicateRuid.Inc()
+		c()
+	}
+	s.ruidCtx[peer.ByteString()][ruid] = cancel
+	s.ruidMtx.Unlock()
+	cc := make(chan struct{})
+	go func() {
+		select {
+		case <-s.quit:
+		case <-ctx.Done():
+		case <-cc:
+		}
+		cancel()
+		s.ruidMtx.Lock()
+		delete(s.ruidCtx[peer.ByteString()], ruid)
+		if len(s.ruidCtx[peer.ByteString()]) == 0 {
+			delete(s.ruidCtx, peer.ByteString())
+		}
+		s.ruidMtx.Unlock()
+	}()
+
+	return ctx, func() { close(cc) }
+}
+
+// deliverWanted reads the want of the peer for the sent offer and delivers
+// the wanted chunks.
+func (s *Syncer) deliverWanted(ctx context.Context, w protobuf.Writer, r protobuf.Reader, offer *pb.Offer) error {
 	var want pb.Want
 	if err := r.ReadMsgWithContext(ctx, &want); err != nil {
 		return fmt.Errorf("read want: %w", err)
@@ -424,7 +514,7 @@ func (s *Syncer) processWant(ctx context.Context, o *pb.Offer, w *pb.Want) ([]cl
 func (s *Syncer) GetCursors(ctx context.Context, peer cluster.Address) (retr []uint64, err error) {
 	loggerV2 := s.logger.V(2).Register()
 
-	stream, err := s.streamer.NewStream(ctx, peer, nil, protocolName, protocolVersion, cursorStreamName)
+	stream, err := s.newStream(ctx, peer, cursorStreamName)
 	if err != nil {
 		return nil, fmt.Errorf("new stream: %w", err)
 	}
@@ -490,7 +580,7 @@ func (s *Syncer) cursorHandler(ctx context.Context, p p2p.Peer, stream p2p.Strea
 func (s *Syncer) CancelRuid(ctx context.Context, peer cluster.Address, ruid uint32) (err error) {
 	loggerV2 := s.logger.V(2).Register()
 
-	stream, err := s.streamer.NewStream(ctx, peer, nil, protocolName, protocolVersion, cancelStreamName)
+	stream, err := s.newStream(ctx, peer, cancelStreamName)
 	if err != nil {
 		return fmt.Errorf("new stream: %w", err)
 	}
@@ -551,6 +641,15 @@ func (s *Syncer) cancelHandler(ctx context.Context, p p2p.Peer, stream p2p.Strea
 	return nil
 }
 
+// newRuid returns a new random request id.
+func newRuid() (uint32, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return 0, fmt.Errorf("crypto rand: %w", err)
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
 func (s *Syncer) Rate() float64 {
 	return s.rate.Rate()
 }
@@ -47,7 +47,7 @@ func TestIncoming_WantEmptyInterval(t *testing.T) {
 	var (
 		mockTopmost        = uint64(5)
 		ps, _              = newPullSync(nil, mock.WithIntervalsResp([]cluster.Address{}, mockTopmost, nil))
-		recorder           = streamtest.New(streamtest.WithProtocols(ps.Protocol()))
+		recorder           = streamtest.New(streamtest.WithProtocols(offerProtocol(ps)))
 		psClient, clientDb = newPullSync(recorder)
 	)
 
@@ -69,7 +69,7 @@ func TestIncoming_WantNone(t *testing.T) {
 	var (
 		mockTopmost        = uint64(5)
 		ps, _              = newPullSync(nil, mock.WithIntervalsResp(addrs, mockTopmost, nil), mock.WithChunks(chunks...))
-		recorder           = streamtest.New(streamtest.WithProtocols(ps.Protocol()))
+		recorder           = streamtest.New(streamtest.WithProtocols(offerProtocol(ps)))
 		psClient, clientDb = newPullSync(recorder, mock.WithChunks(chunks...))
 	)
 
@@ -90,7 +90,7 @@ func TestIncoming_WantOne(t *testing.T) {
 	var (
 		mockTopmost        = uint64(5)
 		ps, _              = newPullSync(nil, mock.WithIntervalsResp(addrs, mockTopmost, nil), mock.WithChunks(chunks...))
-		recorder           = streamtest.New(streamtest.WithProtocols(ps.Protocol()))
+		recorder           = streamtest.New(streamtest.WithProtocols(offerProtocol(ps)))
 		psClient, clientDb = newPullSync(recorder, mock.WithChunks(someChunks(1, 2, 3, 4)...))
 	)
 
@@ -114,7 +114,7 @@ func TestIncoming_WantAll(t *testing.T) {
 	var (
 		mockTopmost        = uint64(5)
 		ps, _              = newPullSync(nil, mock.WithIntervalsResp(addrs, mockTopmost, nil), mock.WithChunks(chunks...))
-		recorder           = streamtest.New(streamtest.WithProtocols(ps.Protocol()))
+		recorder           = streamtest.New(streamtest.WithProtocols(offerProtocol(ps)))
 		psClient, clientDb = newPullSync(recorder)
 	)
 
@@ -143,7 +143,7 @@ func TestIncoming_UnsolicitedChunk(t *testing.T) {
 	var (
 		mockTopmost = uint64(5)
 		ps, _       = newPullSync(nil, mock.WithIntervalsResp(addrs, mockTopmost, nil), mock.WithChunks(chunks...), mock.WithEvilChunk(addrs[4], evil))
-		recorder    = streamtest.New(streamtest.WithProtocols(ps.Protocol()))
+		recorder    = streamtest.New(streamtest.WithProtocols(offerProtocol(ps)))
 		psClient, _ = newPullSync(recorder)
 	)
 
@@ -212,5 +212,13 @@ func newPullSync(s p2p.Streamer, o ...mock.Option) (*pullsync.Syncer, *mock.Pull
 	logger := log.Noop
 	unwrap := func(cluster.Chunk) {}
 	validStamp := func(ch cluster.Chunk, _ []byte) (cluster.Chunk, error) { return ch, nil }
-	return pullsync.New(s, storage, unwrap, validStamp, logger), storage
+	return pullsync.New(cluster.ZeroAddress, s, storage, unwrap, validStamp, logger), storage
+}
+
+// offerProtocol returns the protocol specification of a peer which does not
+// support the reconcile stream, so that intervals are synced by offers.
+func offerProtocol(ps *pullsync.Syncer) p2p.ProtocolSpec {
+	spec := ps.Protocol()
+	spec.Versions = nil
+	return spec
 }
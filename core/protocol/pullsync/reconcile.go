@@ -0,0 +1,425 @@
+package pullsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/p2p"
+	"github.com/redesblock/mop/core/p2p/protobuf"
+	"github.com/redesblock/mop/core/protocol/pullsync/pb"
+	"golang.org/x/crypto/sha3"
+)
+
+// Set reconciliation of historical intervals.
+//
+// The upstream peer sorts the addresses of the chunks in the requested
+// interval of the bin and sends a sketch of the set: the set is split into
+// ranges of the address space, small ranges are sent with the addresses of
+// their chunks and large ranges with their fingerprint and size only. At
+// most maxReconcileSet chunks are reconciled at once, the topmost bin ID of
+// the sketch ends the page of the interval which is synced. The downstream
+// peer can only select its chunks by address, not by the bin IDs of the
+// upstream peer, so it compares the fingerprints with the ones of all the
+// chunks it stores in the same bin and ranges and requests the split of the
+// ranges which do not match. Once all differing ranges are resolved into
+// addresses, the downstream peer wants the chunks it does not have, as with
+// an offer. Intervals which make up most of the bin and whose chunks the
+// downstream peer already stores are synced by exchanging a few
+// fingerprints instead of all the addresses.
+
+const (
+	// maxReconcileSet is the maximal number of chunks of an interval
+	// which are reconciled at once.
+	maxReconcileSet = 1 << 16
+	// reconcileBranching is the number of ranges a range is split into.
+	reconcileBranching = 16
+	// reconcileHashesThreshold is the number of chunks up to which the
+	// addresses of the chunks in a range are sent instead of its fingerprint.
+	reconcileHashesThreshold = 32
+	// maxReconcileRounds is the maximal number of splits of a reconciliation.
+	maxReconcileRounds = 8
+	// maxSplitRanges is the maximal number of ranges of a split request.
+	maxSplitRanges = maxReconcileSet / reconcileHashesThreshold
+	// fingerprintSize is the size of the fingerprint of a range.
+	fingerprintSize = 16
+)
+
+var (
+	// errReconcileFallback denotes that the interval is to be synced by offer.
+	errReconcileFallback = errors.New("reconciliation not applicable")
+	// ErrInvalidSketch is returned when the sketch of a peer is malformed.
+	ErrInvalidSketch = errors.New("invalid sketch")
+)
+
+// syncIntervalReconcile syncs a historical interval by set reconciliation.
+// It returns errReconcileFallback if the interval should be synced by
+// offer instead.
+func (s *Syncer) syncIntervalReconcile(ctx context.Context, peer cluster.Address, bin uint8, from, to uint64) (topmost uint64, ruid uint32, err error) {
+	loggerV2 := s.logger.V(2).Register()
+
+	stream, err := s.newStream(ctx, peer, reconcileStreamName)
+	if err != nil {
+		var ise *p2p.IncompatibleStreamError
+		if errors.As(err, &ise) {
+			s.setLegacyPeer(peer)
+			return 0, 0, errReconcileFallback
+		}
+		return 0, 0, fmt.Errorf("new stream: %w", err)
+	}
+	defer func() {
+		if err != nil && !errors.Is(err, errReconcileFallback) {
+			_ = stream.Reset()
+			loggerV2.Debug("error syncing peer", "peer_address", peer, "error", err)
+		} else {
+			go stream.FullClose()
+		}
+	}()
+
+	ruid, err = newRuid()
+	if err != nil {
+		return 0, 0, err
+	}
+	loggerV2.Debug("reconciling peer", "peer_address", peer, "ruid", ruid, "bin", bin, "from", from, "to", to)
+
+	w, r := protobuf.NewWriterAndReader(stream)
+	if err = w.WriteMsgWithContext(ctx, &pb.Ruid{Ruid: ruid}); err != nil {
+		return 0, ruid, fmt.Errorf("write ruid: %w", err)
+	}
+	rangeMsg := &pb.GetRange{Bin: int32(bin), From: from, To: to}
+	if err = w.WriteMsgWithContext(ctx, rangeMsg); err != nil {
+		return 0, ruid, fmt.Errorf("write get range: %w", err)
+	}
+
+	var sketch pb.Sketch
+	if err = r.ReadMsgWithContext(ctx, &sketch); err != nil {
+		return 0, ruid, fmt.Errorf("read sketch: %w", err)
+	}
+	topmost = sketch.Topmost
+
+	if sketch.Count > maxReconcileSet {
+		return 0, ruid, ErrInvalidSketch
+	}
+	// empty bin, nothing to reconcile.
+	if len(sketch.Ranges) == 0 {
+		return topmost, ruid, nil
+	}
+
+	// the ranges with local chunks outside of the interval do not match, so
+	// reconciliation only pays off if the interval makes up most of the
+	// local chunks of the bin and most of its chunks are present.
+	have, err := s.reconcileCandidates(ctx, peer, bin, 2*int(sketch.Count))
+	if err != nil && !errors.Is(err, errReconcileFallback) {
+		return 0, ruid, err
+	}
+	if err != nil || len(have)*2 < int(sketch.Count) {
+		s.metrics.ReconcileFallbacks.Inc()
+		if err = w.WriteMsgWithContext(ctx, &pb.Split{Abort: true}); err != nil {
+			return 0, ruid, fmt.Errorf("write split: %w", err)
+		}
+		return 0, ruid, errReconcileFallback
+	}
+
+	var offered []byte
+	for round := 0; ; round++ {
+		var split pb.Split
+		for _, rng := range sketch.Ranges {
+			s.metrics.ReconcileRanges.Inc()
+			if len(rng.Fingerprint) == 0 {
+				if len(rng.Hashes)%cluster.HashSize != 0 || len(rng.Hashes)/cluster.HashSize != int(rng.Count) {
+					return 0, ruid, ErrInvalidSketch
+				}
+				offered = append(offered, rng.Hashes...)
+				continue
+			}
+			items := rangeItems(have, rng.Lower, rng.Upper)
+			if len(items) == int(rng.Count) && bytes.Equal(fingerprint(items), rng.Fingerprint) {
+				s.metrics.ReconcileMatched.Add(float64(rng.Count))
+				continue
+			}
+			split.Ranges = append(split.Ranges, &pb.Range{Lower: rng.Lower, Upper: rng.Upper})
+		}
+		if len(offered) > maxReconcileSet*cluster.HashSize {
+			return 0, ruid, ErrInvalidSketch
+		}
+
+		// an empty split concludes the reconciliation.
+		if err = w.WriteMsgWithContext(ctx, &split); err != nil {
+			return 0, ruid, fmt.Errorf("write split: %w", err)
+		}
+		if len(split.Ranges) == 0 {
+			break
+		}
+		if round == maxReconcileRounds {
+			return 0, ruid, ErrInvalidSketch
+		}
+
+		sketch = pb.Sketch{}
+		if err = r.ReadMsgWithContext(ctx, &sketch); err != nil {
+			return 0, ruid, fmt.Errorf("read sketch: %w", err)
+		}
+	}
+	s.metrics.Reconciled.Inc()
+
+	if len(offered) == 0 {
+		return topmost, ruid, nil
+	}
+
+	if err = s.receiveOffered(ctx, w, r, offered, false); err != nil {
+		return 0, ruid, err
+	}
+
+	return topmost, ruid, nil
+}
+
+// reconcileCandidates returns the sorted addresses of all the locally
+// stored chunks which fall into the given bin of the peer. A partial set
+// would not match the fingerprints of the peer, so errReconcileFallback is
+// returned if there are more than limit chunks.
+func (s *Syncer) reconcileCandidates(ctx context.Context, peer cluster.Address, bin uint8, limit int) ([]cluster.Address, error) {
+	// the chunks in bin of the peer are stored in the bins of the base
+	// determined by the proximity of the base and the peer.
+	po := cluster.Proximity(s.base.Bytes(), peer.Bytes())
+	var bins []uint8
+	switch {
+	case bin < po:
+		bins = []uint8{bin}
+	case bin == po:
+		for b := po + 1; b <= cluster.MaxPO; b++ {
+			bins = append(bins, b)
+		}
+	default:
+		bins = []uint8{po}
+	}
+
+	var (
+		seen  = make(map[string]struct{})
+		addrs []cluster.Address
+	)
+	for _, b := range bins {
+		s.metrics.DbOps.Inc()
+		want := limit - len(addrs) + 1
+		bas, err := s.storage.BinAddresses(ctx, b, want)
+		if err != nil {
+			return nil, fmt.Errorf("bin addresses: %w", err)
+		}
+		if len(bas) == want {
+			return nil, errReconcileFallback
+		}
+		for _, a := range bas {
+			if cluster.Proximity(a.Bytes(), peer.Bytes()) != bin {
+				continue
+			}
+			if _, ok := seen[a.ByteString()]; ok {
+				continue
+			}
+			seen[a.ByteString()] = struct{}{}
+			addrs = append(addrs, a)
+		}
+	}
+	sortAddresses(addrs)
+	return addrs, nil
+}
+
+// reconcileHandler handles an incoming request to reconcile an interval.
+func (s *Syncer) reconcileHandler(ctx context.Context, p p2p.Peer, stream p2p.Stream) (err error) {
+	loggerV2 := s.logger.V(2).Register()
+
+	r := protobuf.NewReader(stream)
+	defer func() {
+		if err != nil {
+			_ = stream.Reset()
+		} else {
+			_ = stream.FullClose()
+		}
+	}()
+	var ru pb.Ruid
+	if err := r.ReadMsgWithContext(ctx, &ru); err != nil {
+		return fmt.Errorf("read ruid: %w", err)
+	}
+	loggerV2.Debug("peer reconciling", "peer_address", p.Address, "ruid", ru.Ruid)
+	ctx, done := s.registerRuid(ctx, p.Address, ru.Ruid)
+	defer done()
+
+	select {
+	case <-s.quit:
+		return nil
+	default:
+	}
+
+	s.wg.Add(1)
+	defer s.wg.Done()
+
+	var rn pb.GetRange
+	if err := r.ReadMsgWithContext(ctx, &rn); err != nil {
+		return fmt.Errorf("read get range: %w", err)
+	}
+
+	// the set covers the page of the interval up to the topmost bin ID,
+	// see the description of the reconciliation.
+	s.metrics.DbOps.Inc()
+	addrs, top, err := s.storage.IntervalChunks(ctx, uint8(rn.Bin), rn.From, rn.To, maxReconcileSet)
+	if err != nil {
+		return fmt.Errorf("interval chunks: %w", err)
+	}
+	sortAddresses(addrs)
+
+	w, r := protobuf.NewWriterAndReader(stream)
+
+	var sent []byte
+	sketch := &pb.Sketch{Topmost: top, Count: uint32(len(addrs))}
+	if len(addrs) > 0 {
+		sketch.Ranges, sent = sketchRanges(addrs, nil, nil, sent)
+	}
+	if err := w.WriteMsgWithContext(ctx, sketch); err != nil {
+		return fmt.Errorf("write sketch: %w", err)
+	}
+	if len(addrs) == 0 {
+		return nil
+	}
+
+	for round := 0; ; round++ {
+		var split pb.Split
+		if err := r.ReadMsgWithContext(ctx, &split); err != nil {
+			return fmt.Errorf("read split: %w", err)
+		}
+		if split.Abort {
+			return nil
+		}
+		if len(split.Ranges) == 0 {
+			break
+		}
+		if round == maxReconcileRounds || len(split.Ranges) > maxSplitRanges {
+			return fmt.Errorf("split of %d ranges in round %d: %w", len(split.Ranges), round, ErrInvalidSketch)
+		}
+
+		sketch = &pb.Sketch{Topmost: top, Count: uint32(len(addrs))}
+		for _, rng := range split.Ranges {
+			var ranges []*pb.Range
+			ranges, sent = sketchRanges(rangeItems(addrs, rng.Lower, rng.Upper), rng.Lower, rng.Upper, sent)
+			sketch.Ranges = append(sketch.Ranges, ranges...)
+		}
+		if err := w.WriteMsgWithContext(ctx, sketch); err != nil {
+			return fmt.Errorf("write sketch: %w", err)
+		}
+	}
+
+	if len(sent) == 0 {
+		return nil
+	}
+
+	return s.deliverWanted(ctx, w, r, &pb.Offer{Topmost: top, Hashes: sent})
+}
+
+// sketchRanges returns the ranges of the sorted items within the bounds,
+// along with sent extended by the addresses sent in the ranges. The
+// addresses are sent for small sets, larger sets are split into ranges
+// described by their fingerprints.
+func sketchRanges(items []cluster.Address, lower, upper, sent []byte) ([]*pb.Range, []byte) {
+	if len(items) <= reconcileHashesThreshold {
+		rng := hashesRange(items, lower, upper)
+		return []*pb.Range{rng}, append(sent, rng.Hashes...)
+	}
+
+	size := (len(items) + reconcileBranching - 1) / reconcileBranching
+	ranges := make([]*pb.Range, 0, reconcileBranching)
+	for i := 0; i < len(items); i += size {
+		end := i + size
+		if end > len(items) {
+			end = len(items)
+		}
+		lo, up := lower, upper
+		if i > 0 {
+			lo = items[i].Bytes()
+		}
+		if end < len(items) {
+			up = items[end].Bytes()
+		}
+
+		part := items[i:end]
+		ranges = append(ranges, &pb.Range{
+			Lower:       lo,
+			Upper:       up,
+			Count:       uint32(len(part)),
+			Fingerprint: fingerprint(part),
+		})
+	}
+	return ranges, sent
+}
+
+func hashesRange(items []cluster.Address, lower, upper []byte) *pb.Range {
+	hashes := make([]byte, 0, len(items)*cluster.HashSize)
+	for _, a := range items {
+		hashes = append(hashes, a.Bytes()...)
+	}
+	return &pb.Range{Lower: lower, Upper: upper, Count: uint32(len(items)), Hashes: hashes}
+}
+
+// rangeItems returns the sorted items in the range [lower, upper). An empty
+// upper bound denotes the end of the address space.
+func rangeItems(items []cluster.Address, lower, upper []byte) []cluster.Address {
+	i := sort.Search(len(items), func(i int) bool {
+		return bytes.Compare(items[i].Bytes(), lower) >= 0
+	})
+	j := len(items)
+	if len(upper) > 0 {
+		j = sort.Search(len(items), func(i int) bool {
+			return bytes.Compare(items[i].Bytes(), upper) >= 0
+		})
+	}
+	if j < i {
+		return nil
+	}
+	return items[i:j]
+}
+
+// fingerprint returns the fingerprint of a set of addresses: the truncated
+// hash of the sum of the addresses modulo 2^256 and the size of the set.
+// The sum makes the fingerprint independent of the order of the addresses.
+func fingerprint(addrs []cluster.Address) []byte {
+	var sum [cluster.HashSize]byte
+	for _, a := range addrs {
+		b := a.Bytes()
+		var carry uint16
+		for i := len(sum) - 1; i >= 0; i-- {
+			v := uint16(sum[i]) + carry
+			if j := len(b) - len(sum) + i; j >= 0 {
+				v += uint16(b[j])
+			}
+			sum[i] = byte(v)
+			carry = v >> 8
+		}
+	}
+	count := make([]byte, 8)
+	binary.BigEndian.PutUint64(count, uint64(len(addrs)))
+
+	h := sha3.NewLegacyKeccak256()
+	_, _ = h.Write(sum[:])
+	_, _ = h.Write(count)
+	return h.Sum(nil)[:fingerprintSize]
+}
+
+func sortAddresses(addrs []cluster.Address) {
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i].Bytes(), addrs[j].Bytes()) < 0
+	})
+}
+
+// isLegacyPeer reports whether the peer is known not to support
+// reconciliation.
+func (s *Syncer) isLegacyPeer(peer cluster.Address) bool {
+	s.legacyMtx.Lock()
+	defer s.legacyMtx.Unlock()
+	_, ok := s.legacyPeers[peer.ByteString()]
+	return ok
+}
+
+func (s *Syncer) setLegacyPeer(peer cluster.Address) {
+	s.legacyMtx.Lock()
+	defer s.legacyMtx.Unlock()
+	s.legacyPeers[peer.ByteString()] = struct{}{}
+}
@@ -0,0 +1,206 @@
+package pullsync_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/p2p"
+	"github.com/redesblock/mop/core/p2p/streamtest"
+	"github.com/redesblock/mop/core/protocol/pullsync/pullstorage/mock"
+	testingc "github.com/redesblock/mop/core/storer/storage/testing"
+)
+
+// peer is the address of the upstream peer in the reconciliation tests.
+var peer = cluster.NewAddress(make([]byte, cluster.HashSize))
+
+// binChunks returns n random chunks which fall into bin 0 of the peer.
+func binChunks(n int) ([]cluster.Chunk, []cluster.Address) {
+	chs := make([]cluster.Chunk, 0, n)
+	addrs := make([]cluster.Address, 0, n)
+	for len(chs) < n {
+		ch := testingc.GenerateTestRandomChunk()
+		if cluster.Proximity(ch.Address().Bytes(), peer.Bytes()) != 0 {
+			continue
+		}
+		chs = append(chs, ch)
+		addrs = append(addrs, ch.Address())
+	}
+	return chs, addrs
+}
+
+func TestReconcile(t *testing.T) {
+	mockTopmost := uint64(200)
+
+	t.Run("in sync", func(t *testing.T) {
+		var (
+			chs, addrs         = binChunks(200)
+			ps, _              = newPullSync(nil, mock.WithIntervalsResp(addrs, mockTopmost, nil), mock.WithChunks(chs...))
+			recorder           = streamtest.New(streamtest.WithProtocols(ps.Protocol()))
+			psClient, clientDb = newPullSync(recorder, mock.WithChunks(chs...))
+		)
+
+		topmost, _, err := psClient.SyncInterval(context.Background(), peer, 0, 0, mockTopmost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if topmost != mockTopmost {
+			t.Fatalf("got topmost %d but want %d", topmost, mockTopmost)
+		}
+		if clientDb.PutCalls() > 0 {
+			t.Fatal("too many puts")
+		}
+
+		records, err := recorder.Records(peer, "pullsync", "2.0.0", "reconcile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l := len(records[0].Out()); l >= len(addrs)*cluster.HashSize/2 {
+			t.Fatalf("got %d bytes of sketches for %d chunks", l, len(addrs))
+		}
+	})
+
+	t.Run("missing chunks", func(t *testing.T) {
+		var (
+			chs, addrs         = binChunks(200)
+			ps, _              = newPullSync(nil, mock.WithIntervalsResp(addrs, mockTopmost, nil), mock.WithChunks(chs...))
+			recorder           = streamtest.New(streamtest.WithProtocols(ps.Protocol()))
+			psClient, clientDb = newPullSync(recorder, mock.WithChunks(chs[10:]...))
+		)
+
+		topmost, _, err := psClient.SyncInterval(context.Background(), peer, 0, 0, mockTopmost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if topmost != mockTopmost {
+			t.Fatalf("got topmost %d but want %d", topmost, mockTopmost)
+		}
+		haveChunks(t, clientDb, addrs...)
+		if p := clientDb.PutCalls(); p != 1 {
+			t.Fatalf("want %d puts but got %d", 1, p)
+		}
+		if _, err := recorder.Records(peer, "pullsync", "1.1.0", "pullsync"); !errors.Is(err, streamtest.ErrRecordsNotFound) {
+			t.Fatalf("got error %v, want %v", err, streamtest.ErrRecordsNotFound)
+		}
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		var (
+			chs, addrs = binChunks(20)
+			ps, _      = newPullSync(nil,
+				mock.WithIntervalsResp(addrs, mockTopmost, nil),
+				mock.WithIntervalsResp(addrs, mockTopmost, nil),
+				mock.WithChunks(chs...),
+			)
+			recorder           = streamtest.New(streamtest.WithProtocols(ps.Protocol()))
+			psClient, clientDb = newPullSync(recorder)
+		)
+
+		topmost, _, err := psClient.SyncInterval(context.Background(), peer, 0, 0, mockTopmost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if topmost != mockTopmost {
+			t.Fatalf("got topmost %d but want %d", topmost, mockTopmost)
+		}
+		haveChunks(t, clientDb, addrs...)
+		if _, err := recorder.Records(peer, "pullsync", "1.1.0", "pullsync"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("legacy peer", func(t *testing.T) {
+		var (
+			chs, addrs = binChunks(20)
+			ps, _      = newPullSync(nil,
+				mock.WithIntervalsResp(addrs, mockTopmost, nil),
+				mock.WithChunks(chs...),
+			)
+			legacy = ps.Protocol()
+		)
+		// the peer only supports the 1.x versions without reconciliation
+		legacy.Versions = nil
+		var (
+			recorder           = streamtest.New(streamtest.WithProtocols(legacy))
+			psClient, clientDb = newPullSync(recorder)
+		)
+
+		topmost, _, err := psClient.SyncInterval(context.Background(), peer, 0, 0, mockTopmost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if topmost != mockTopmost {
+			t.Fatalf("got topmost %d but want %d", topmost, mockTopmost)
+		}
+		haveChunks(t, clientDb, addrs...)
+		if _, err := recorder.Records(peer, "pullsync", "2.0.0", "reconcile"); !errors.Is(err, streamtest.ErrRecordsNotFound) {
+			t.Fatalf("got error %v, want %v", err, streamtest.ErrRecordsNotFound)
+		}
+		if _, err := recorder.Records(peer, "pullsync", "1.1.0", "pullsync"); err != nil {
+			t.Fatal(err)
+		}
+		if !psClient.IsLegacyPeer(peer) {
+			t.Fatal("peer is not legacy")
+		}
+
+		// the peer may be upgraded when it reconnects
+		if err := psClient.Protocol().DisconnectIn(p2p.Peer{Address: peer}); err != nil {
+			t.Fatal(err)
+		}
+		if psClient.IsLegacyPeer(peer) {
+			t.Fatal("peer is still legacy after disconnect")
+		}
+	})
+
+	t.Run("paged interval", func(t *testing.T) {
+		var (
+			chs, addrs = binChunks(200)
+			ps, _      = newPullSync(nil,
+				mock.WithIntervalsResp(addrs[:100], mockTopmost/2, nil),
+				mock.WithChunks(chs...),
+			)
+			recorder           = streamtest.New(streamtest.WithProtocols(ps.Protocol()))
+			psClient, clientDb = newPullSync(recorder, mock.WithChunks(chs...))
+		)
+
+		topmost, _, err := psClient.SyncInterval(context.Background(), peer, 0, 0, mockTopmost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if topmost != mockTopmost/2 {
+			t.Fatalf("got topmost %d but want %d", topmost, mockTopmost/2)
+		}
+		if clientDb.PutCalls() > 0 {
+			t.Fatal("too many puts")
+		}
+		if _, err := recorder.Records(peer, "pullsync", "1.1.0", "pullsync"); !errors.Is(err, streamtest.ErrRecordsNotFound) {
+			t.Fatalf("got error %v, want %v", err, streamtest.ErrRecordsNotFound)
+		}
+	})
+
+	t.Run("interval of a larger bin", func(t *testing.T) {
+		var (
+			chs, addrs = binChunks(100)
+			ps, _      = newPullSync(nil,
+				mock.WithIntervalsResp(addrs[:20], mockTopmost, nil),
+				mock.WithIntervalsResp(addrs[:20], mockTopmost, nil),
+				mock.WithChunks(chs...),
+			)
+			recorder           = streamtest.New(streamtest.WithProtocols(ps.Protocol()))
+			psClient, clientDb = newPullSync(recorder, mock.WithChunks(chs[10:]...))
+		)
+
+		topmost, _, err := psClient.SyncInterval(context.Background(), peer, 0, 0, mockTopmost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if topmost != mockTopmost {
+			t.Fatalf("got topmost %d but want %d", topmost, mockTopmost)
+		}
+		haveChunks(t, clientDb, addrs[:20]...)
+		if _, err := recorder.Records(peer, "pullsync", "1.1.0", "pullsync"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -69,6 +69,8 @@ type Receipt struct {
 	Address   cluster.Address
 	Signature []byte
 	Nonce     []byte
+	// Price is the amount credited to the peer the chunk was pushed to.
+	Price uint64
 }
 
 type PushSync struct {
@@ -96,6 +98,7 @@ type receiptResult struct {
 	pushTime time.Time
 	peer     cluster.Address
 	receipt  *pb.Receipt
+	price    uint64
 	pushed   bool
 	err      error
 }
@@ -263,7 +266,7 @@ func (ps *PushSync) handler(ctx context.Context, p p2p.Peer, stream p2p.Stream)
 		}
 	}()
 
-	receipt, err := ps.pushToClosest(ctx, chunk, false, p.Address)
+	receipt, _, err := ps.pushToClosest(ctx, chunk, false, p.Address)
 	if err != nil {
 		if errors.Is(err, topology.ErrWantSelf) {
 			storerNode = true
@@ -325,7 +328,7 @@ func (ps *PushSync) handler(ctx context.Context, p p2p.Peer, stream p2p.Stream)
 // the validity of the receipt.
 func (ps *PushSync) PushChunkToClosest(ctx context.Context, ch cluster.Chunk) (*Receipt, error) {
 	ps.metrics.TotalOutgoing.Inc()
-	r, err := ps.pushToClosest(ctx, ch, true, cluster.ZeroAddress)
+	r, price, err := ps.pushToClosest(ctx, ch, true, cluster.ZeroAddress)
 	if err != nil {
 		ps.metrics.TotalOutgoingErrors.Inc()
 		return nil, err
@@ -334,10 +337,13 @@ func (ps *PushSync) PushChunkToClosest(ctx context.Context, ch cluster.Chunk) (*
 		Address:   cluster.NewAddress(r.Address),
 		Signature: r.Signature,
 		Nonce:     r.Nonce,
+		Price:     price,
 	}, nil
 }
 
-func (ps *PushSync) pushToClosest(ctx context.Context, ch cluster.Chunk, origin bool, originAddr cluster.Address) (*pb.Receipt, error) {
+// pushToClosest pushes the chunk to the closest peer and returns its receipt
+// along with the price credited to the peer.
+func (ps *PushSync) pushToClosest(ctx context.Context, ch cluster.Chunk, origin bool, originAddr cluster.Address) (*pb.Receipt, uint64, error) {
 	span, logger, ctx := ps.tracer.StartSpanFromContext(ctx, "push-closest", ps.logger, opentracing.Tag{Key: "address", Value: ch.Address().String()})
 	defer span.Finish()
 	defer ps.skipList.PruneExpired()
@@ -405,7 +411,7 @@ func (ps *PushSync) pushToClosest(ctx context.Context, ch cluster.Chunk, origin
 	for {
 		select {
 		case <-ctx.Done():
-			return nil, ErrNoPush
+			return nil, 0, ErrNoPush
 		case <-timer.C:
 
 			allowedRetries--
@@ -413,12 +419,12 @@ func (ps *PushSync) pushToClosest(ctx context.Context, ch cluster.Chunk, origin
 
 			peer, retry, err := nextPeer()
 			if err != nil {
-				return nil, err
+				return nil, 0, err
 			}
 
 			if retry {
 				if allowedRetries <= 0 {
-					return nil, ErrNoPush
+					return nil, 0, ErrNoPush
 				}
 				timer.Reset(waitRefresh)
 				continue
@@ -460,7 +466,7 @@ func (ps *PushSync) pushToClosest(ctx context.Context, ch cluster.Chunk, origin
 			}
 
 			if result.err == nil {
-				return result.receipt, nil
+				return result.receipt, result.price, nil
 			}
 
 			ps.metrics.TotalFailedSendAttempts.Inc()
@@ -472,7 +478,7 @@ func (ps *PushSync) pushToClosest(ctx context.Context, ch cluster.Chunk, origin
 			}
 
 			if allowedRetries <= 0 || allowedPushes <= 0 {
-				return nil, ErrNoPush
+				return nil, 0, ErrNoPush
 			}
 
 			// retry immediately
@@ -497,22 +503,23 @@ func (ps *PushSync) measurePushPeer(t time.Time, err error, origin bool) {
 func (ps *PushSync) pushPeer(ctx context.Context, resultChan chan<- receiptResult, doneChan <-chan struct{}, peer cluster.Address, ch cluster.Chunk, origin bool) {
 
 	var (
-		err     error
-		receipt pb.Receipt
-		pushed  bool
-		now     = time.Now()
+		err          error
+		receipt      pb.Receipt
+		receiptPrice uint64
+		pushed       bool
+		now          = time.Now()
 	)
 
 	defer func() {
 		select {
-		case resultChan <- receiptResult{pushTime: now, peer: peer, err: err, pushed: pushed, receipt: &receipt}:
+		case resultChan <- receiptResult{pushTime: now, peer: peer, err: err, pushed: pushed, receipt: &receipt, price: receiptPrice}:
 		case <-doneChan:
 			ps.metrics.DuplicateReceipt.Inc()
 		}
 	}()
 
 	// compute the price we pay for this receipt and reserve it for the rest of this function
-	receiptPrice := ps.pricer.PeerPrice(peer, ch.Address())
+	receiptPrice = ps.pricer.PeerPrice(peer, ch.Address())
 
 	creditCtx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
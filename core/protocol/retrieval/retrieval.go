@@ -23,6 +23,7 @@ import (
 	"github.com/redesblock/mop/core/skiper"
 	"github.com/redesblock/mop/core/storer/storage"
 	"github.com/redesblock/mop/core/tracer"
+	"github.com/redesblock/mop/core/usage"
 	"resenje.org/singleflight"
 )
 
@@ -151,6 +152,8 @@ func (s *Service) RetrieveChunk(ctx context.Context, addr, sourcePeerAddr cluste
 				// create a new context without cancelation but
 				// set the tracer span to the new context from the context of the first caller
 				ctx := tracer.WithContext(context.Background(), tracer.FromContext(topCtx))
+				// and the usage meter to attribute the retrieval cost to the request
+				ctx = usage.WithContext(ctx, usage.FromContext(topCtx))
 
 				// get the tracer span
 				span, _, ctx := s.tracer.StartSpanFromContext(ctx, "retrieve-chunk", s.logger, opentracing.Tag{Key: "address", Value: addr.String()})
@@ -339,6 +342,7 @@ func (s *Service) retrieveChunk(ctx context.Context, addr cluster.Address, sp *s
 		return nil, peer, true, err
 	}
 	s.metrics.ChunkPrice.Observe(float64(chunkPrice))
+	usage.AddCost(ctx, chunkPrice)
 	return chunk, peer, true, err
 }
 
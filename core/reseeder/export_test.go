@@ -0,0 +1,7 @@
+package reseeder
+
+import "context"
+
+func (s *Service) Round(ctx context.Context) error {
+	return s.round(ctx)
+}
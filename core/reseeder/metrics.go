@@ -0,0 +1,79 @@
+package reseeder
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	m "github.com/redesblock/mop/core/metrics"
+)
+
+type metrics struct {
+	// all metrics fields must be exported
+	// to be able to return them by Metrics()
+	// using reflection
+	Rounds          prometheus.Counter
+	RootsChecked    prometheus.Counter
+	ChunksChecked   prometheus.Counter
+	UnderReplicated prometheus.Counter
+	ChunksPushed    prometheus.Counter
+	PushErrors      prometheus.Counter
+	Spent           prometheus.Counter
+	BudgetExhausted prometheus.Counter
+}
+
+func newMetrics() metrics {
+	subsystem := "reseeder"
+
+	return metrics{
+		Rounds: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "rounds_count",
+			Help:      "Number of repair rounds.",
+		}),
+		RootsChecked: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "roots_checked_count",
+			Help:      "Number of pinned roots checked.",
+		}),
+		ChunksChecked: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "chunks_checked_count",
+			Help:      "Number of chunks of pinned roots checked.",
+		}),
+		UnderReplicated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "under_replicated_count",
+			Help:      "Number of chunks found with too few replicas.",
+		}),
+		ChunksPushed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "chunks_pushed_count",
+			Help:      "Number of chunks pushed again.",
+		}),
+		PushErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "push_errors_count",
+			Help:      "Number of chunks which failed to be pushed again.",
+		}),
+		Spent: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "spent_total",
+			Help:      "Amount spent on pushing chunks in bookkeeper units.",
+		}),
+		BudgetExhausted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "budget_exhausted_count",
+			Help:      "Number of repair rounds stopped by the exhausted budget.",
+		}),
+	}
+}
+
+func (s *Service) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(s.metrics)
+}
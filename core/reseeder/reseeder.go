@@ -0,0 +1,308 @@
+// Package reseeder provides the background repair of pinned content. It
+// periodically samples the pinned roots, asks the peers of the neighbourhood
+// of every chunk whether they store it and pushes again the chunks with too
+// few replicas.
+package reseeder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/redesblock/mop/core/availability"
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/p2p/topology"
+	"github.com/redesblock/mop/core/pricer"
+	"github.com/redesblock/mop/core/protocol/has"
+	"github.com/redesblock/mop/core/protocol/pushsync"
+	"github.com/redesblock/mop/core/storer/storage"
+	"github.com/redesblock/mop/core/traverser"
+	"golang.org/x/time/rate"
+)
+
+// loggerName is the tree path name of the logger for this package.
+const loggerName = "reseeder"
+
+const (
+	// DefaultInterval is the default time between two repair rounds.
+	DefaultInterval = time.Hour
+	// DefaultSampleSize is the default number of pinned roots checked in a round.
+	DefaultSampleSize = 4
+	// DefaultRate is the default number of chunks pushed per second.
+	DefaultRate = 10
+)
+
+// errBudgetExhausted is returned when pushing the chunk would exceed the
+// budget of the round.
+var errBudgetExhausted = errors.New("budget exhausted")
+
+// Pinner lists the pinned roots.
+type Pinner interface {
+	Pins() ([]cluster.Address, error)
+}
+
+// Topology is the topology needed to find the peers closest to the chunks
+// and the depth within which they store them.
+type Topology interface {
+	topology.EachPeerer
+	topology.ClosestPeerer
+	topology.NeighborhoodDepther
+}
+
+// Options configure the reseeder.
+type Options struct {
+	// Interval is the time between two repair rounds.
+	Interval time.Duration
+	// SampleSize is the number of pinned roots checked in a round.
+	SampleSize int
+	// Peers is the number of the closest peers asked for each chunk.
+	Peers int
+	// Replicas is the number of replicas below which a chunk is pushed again.
+	Replicas int
+	// Rate is the maximal number of chunks pushed per second.
+	Rate float64
+	// Budget is the maximal amount in bookkeeper units spent on pushing
+	// chunks in a round, where zero means no limit.
+	Budget uint64
+}
+
+// Service is the background repair of pinned content.
+type Service struct {
+	storer     storage.Storer
+	traverser  traverser.Traverser
+	pinner     Pinner
+	has        has.Interface
+	topology   Topology
+	pushSyncer pushsync.PushSyncer
+	pricer     pricer.Interface
+	logger     log.Logger
+	metrics    metrics
+	limiter    *rate.Limiter
+	sampleSize int
+	peers      int
+	replicas   int
+	budget     uint64
+	quit       chan struct{}
+	stopped    chan struct{}
+}
+
+// New constructs a new reseeder and starts its repair rounds.
+func New(
+	storer storage.Storer,
+	pinner Pinner,
+	h has.Interface,
+	t Topology,
+	p pushsync.PushSyncer,
+	pr pricer.Interface,
+	logger log.Logger,
+	o Options,
+) *Service {
+	if o.Interval <= 0 {
+		o.Interval = DefaultInterval
+	}
+	if o.SampleSize <= 0 {
+		o.SampleSize = DefaultSampleSize
+	}
+	if o.Peers <= 0 {
+		o.Peers = availability.DefaultPeers
+	}
+	if o.Replicas <= 0 {
+		o.Replicas = availability.DefaultReplicas
+	}
+	if o.Rate <= 0 {
+		o.Rate = DefaultRate
+	}
+
+	s := &Service{
+		storer:     storer,
+		traverser:  traverser.New(storer),
+		pinner:     pinner,
+		has:        h,
+		topology:   t,
+		pushSyncer: p,
+		pricer:     pr,
+		logger:     logger.WithName(loggerName).Register(),
+		metrics:    newMetrics(),
+		limiter:    rate.NewLimiter(rate.Limit(o.Rate), 1),
+		sampleSize: o.SampleSize,
+		peers:      o.Peers,
+		replicas:   o.Replicas,
+		budget:     o.Budget,
+		quit:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+
+	go s.manage(o.Interval)
+
+	return s
+}
+
+func (s *Service) manage(interval time.Duration) {
+	defer close(s.stopped)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.quit
+		cancel()
+	}()
+
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-time.After(interval):
+		}
+
+		if err := s.round(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.logger.Debug("repair round failed", "error", err)
+			s.logger.Error(nil, "repair round failed")
+		}
+	}
+}
+
+// round repairs a random sample of the pinned roots within the budget.
+func (s *Service) round(ctx context.Context) error {
+	s.metrics.Rounds.Inc()
+
+	roots, err := s.pinner.Pins()
+	if err != nil {
+		return fmt.Errorf("list pins: %w", err)
+	}
+	rand.Shuffle(len(roots), func(i, j int) {
+		roots[i], roots[j] = roots[j], roots[i]
+	})
+	if len(roots) > s.sampleSize {
+		roots = roots[:s.sampleSize]
+	}
+
+	budget := s.budget
+	for _, root := range roots {
+		pushed, err := s.repair(ctx, root, &budget)
+		if pushed > 0 {
+			s.logger.Info("reseeded pinned content", "root_address", root, "chunks", pushed)
+		}
+		if errors.Is(err, errBudgetExhausted) {
+			s.metrics.BudgetExhausted.Inc()
+			s.logger.Debug("repair round budget exhausted", "root_address", root)
+			return nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			s.logger.Debug("repair failed", "root_address", root, "error", err)
+			continue
+		}
+		s.metrics.RootsChecked.Inc()
+	}
+	return nil
+}
+
+// repair pushes the chunks of the content on the given root address which
+// have less replicas than expected, and returns how many chunks were pushed.
+// The price of the pushed chunks is subtracted from the remaining budget.
+func (s *Service) repair(ctx context.Context, root cluster.Address, budget *uint64) (int, error) {
+	var statuses []availability.ChunkStatus
+	seen := make(map[string]struct{})
+	iterFn := func(addr cluster.Address) error {
+		if _, ok := seen[addr.ByteString()]; !ok {
+			seen[addr.ByteString()] = struct{}{}
+			statuses = append(statuses, availability.ChunkStatus{Address: addr})
+		}
+		return nil
+	}
+	if err := s.traverser.Traverse(ctx, root, iterFn); err != nil {
+		return 0, fmt.Errorf("traverse %s: %w", root, err)
+	}
+	s.metrics.ChunksChecked.Add(float64(len(statuses)))
+
+	depth := s.topology.NeighborhoodDepth()
+	if err := availability.CountReplicas(ctx, s.has, s.topology, s.logger, s.peers, depth, statuses); err != nil {
+		return 0, err
+	}
+
+	pushed := 0
+	for _, st := range statuses {
+		// without any response the replication of the chunk is unknown
+		if st.Responded == 0 || st.Replicas >= s.replicas {
+			continue
+		}
+		s.metrics.UnderReplicated.Inc()
+
+		err := s.push(ctx, st.Address, budget)
+		if errors.Is(err, errBudgetExhausted) {
+			return pushed, err
+		}
+		// the node is the closest to the chunk and replicates it to the
+		// neighbourhood by itself, nothing was pushed.
+		if errors.Is(err, topology.ErrWantSelf) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return pushed, ctx.Err()
+			}
+			s.metrics.PushErrors.Inc()
+			s.logger.Debug("push chunk failed", "chunk_address", st.Address, "error", err)
+			continue
+		}
+		pushed++
+	}
+	return pushed, nil
+}
+
+// push pushes the local chunk to the closest peer if its expected price fits
+// into the budget and the rate limit allows it. The budget is charged the
+// price credited to the peer the chunk was pushed to, and is not charged if
+// the chunk was not pushed to any peer.
+func (s *Service) push(ctx context.Context, addr cluster.Address, budget *uint64) error {
+	if s.budget > 0 {
+		peer, err := s.topology.ClosestPeer(addr, false, topology.Filter{Reachable: true})
+		if err != nil {
+			return fmt.Errorf("closest peer: %w", err)
+		}
+		if s.pricer.PeerPrice(peer, addr) > *budget {
+			return errBudgetExhausted
+		}
+	}
+
+	if err := s.limiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	ch, err := s.storer.Get(ctx, storage.ModeGetSync, addr)
+	if err != nil {
+		return fmt.Errorf("get chunk: %w", err)
+	}
+	receipt, err := s.pushSyncer.PushChunkToClosest(ctx, ch)
+	if err != nil {
+		return err
+	}
+	if s.budget > 0 {
+		if receipt.Price > *budget {
+			*budget = 0
+		} else {
+			*budget -= receipt.Price
+		}
+	}
+	s.metrics.ChunksPushed.Inc()
+	s.metrics.Spent.Add(float64(receipt.Price))
+	return nil
+}
+
+// Close stops the repair rounds.
+func (s *Service) Close() error {
+	close(s.quit)
+	select {
+	case <-s.stopped:
+		return nil
+	case <-time.After(5 * time.Second):
+		return errors.New("stopping reseeder with ongoing worker goroutine")
+	}
+}
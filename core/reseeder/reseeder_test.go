@@ -0,0 +1,179 @@
+package reseeder_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/cluster/test"
+	"github.com/redesblock/mop/core/file/pipeline/builder"
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/p2p/topology"
+	topmock "github.com/redesblock/mop/core/p2p/topology/mock"
+	pinsmock "github.com/redesblock/mop/core/pins/mock"
+	pricermock "github.com/redesblock/mop/core/pricer/mock"
+	hasmock "github.com/redesblock/mop/core/protocol/has/mock"
+	"github.com/redesblock/mop/core/protocol/pushsync"
+	pushmock "github.com/redesblock/mop/core/protocol/pushsync/mock"
+	"github.com/redesblock/mop/core/reseeder"
+	"github.com/redesblock/mop/core/storer/storage"
+	"github.com/redesblock/mop/core/storer/storage/mock"
+)
+
+func TestRound(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		data   = make([]byte, 20*cluster.ChunkSize)
+		store  = mock.NewStorer()
+		pinner = pinsmock.NewServiceMock()
+		holder = test.RandomAddress()
+		other  = test.RandomAddress()
+	)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+
+	pipe := builder.NewPipelineBuilder(ctx, store, storage.ModePutUpload, false)
+	root, err := builder.FeedPipeline(ctx, pipe, bytes.NewReader(data), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pinner.CreatePin(ctx, root, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// the holder stores every other chunk of the content
+	var (
+		mu       sync.Mutex
+		index    int
+		replicas = make(map[string]bool)
+	)
+	hasServer := hasmock.New(func(_ context.Context, peer cluster.Address, addrs ...cluster.Address) ([]bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		res := make([]bool, len(addrs))
+		for i, a := range addrs {
+			if !peer.Equal(holder) {
+				continue
+			}
+			has, ok := replicas[a.String()]
+			if !ok {
+				has = index%2 == 0
+				replicas[a.String()] = has
+				index++
+			}
+			res[i] = has
+		}
+		return res, nil
+	})
+
+	newReseeder := func(t *testing.T, budget uint64, pushErr error, depth uint8, peers ...cluster.Address) (*reseeder.Service, func() []cluster.Address) {
+		t.Helper()
+
+		var (
+			pmu    sync.Mutex
+			pushed []cluster.Address
+		)
+		pusher := pushmock.New(func(_ context.Context, ch cluster.Chunk) (*pushsync.Receipt, error) {
+			pmu.Lock()
+			defer pmu.Unlock()
+			pushed = append(pushed, ch.Address())
+			if pushErr != nil {
+				return nil, pushErr
+			}
+			return &pushsync.Receipt{Address: ch.Address(), Price: 10}, nil
+		})
+		topologyDriver := topmock.NewTopologyDriver(topmock.WithPeers(peers...), topmock.WithNeighborhoodDepth(depth))
+		s := reseeder.New(store, pinner, hasServer, topologyDriver, pusher, pricermock.NewMockService(10, 10), log.Noop, reseeder.Options{
+			Interval: time.Hour,
+			Replicas: 1,
+			Rate:     1000,
+			Budget:   budget,
+		})
+		t.Cleanup(func() {
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+		})
+		return s, func() []cluster.Address {
+			pmu.Lock()
+			defer pmu.Unlock()
+			return pushed
+		}
+	}
+
+	t.Run("repair", func(t *testing.T) {
+		s, pushed := newReseeder(t, 0, nil, 0, holder, other)
+		if err := s.Round(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		var want int
+		for _, has := range replicas {
+			if !has {
+				want++
+			}
+		}
+		got := pushed()
+		if len(got) != want {
+			t.Fatalf("got %d pushed chunks, want %d", len(got), want)
+		}
+		for _, a := range got {
+			if replicas[a.String()] {
+				t.Fatalf("pushed chunk %s which has enough replicas", a)
+			}
+		}
+	})
+
+	t.Run("budget", func(t *testing.T) {
+		s, pushed := newReseeder(t, 35, nil, 0, holder, other)
+		if err := s.Round(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if got := len(pushed()); got != 3 {
+			t.Fatalf("got %d pushed chunks, want %d", got, 3)
+		}
+	})
+
+	t.Run("no peers", func(t *testing.T) {
+		s, pushed := newReseeder(t, 0, nil, 0)
+		if err := s.Round(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if got := len(pushed()); got != 0 {
+			t.Fatalf("got %d pushed chunks, want %d", got, 0)
+		}
+	})
+
+	t.Run("out of depth", func(t *testing.T) {
+		// the replicas of the holder outside of the depth of the chunks do
+		// not count, so every chunk is pushed.
+		s, pushed := newReseeder(t, 0, nil, cluster.MaxPO, holder, other)
+		if err := s.Round(ctx); err != nil {
+			t.Fatal(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if got, want := len(pushed()), len(replicas); got != want {
+			t.Fatalf("got %d pushed chunks, want %d", got, want)
+		}
+	})
+
+	t.Run("want self", func(t *testing.T) {
+		// chunks not pushed to any peer are not charged to the budget, so
+		// the budget of three pushes does not limit the attempts.
+		s, pushed := newReseeder(t, 35, topology.ErrWantSelf, 0, holder, other)
+		if err := s.Round(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if got := len(pushed()); got <= 3 {
+			t.Fatalf("got %d push attempts, want more than %d", got, 3)
+		}
+	})
+}
@@ -0,0 +1,204 @@
+// Package dnslink implements a name resolver which looks up the reference of
+// a domain name in its DNSLink TXT records.
+//
+// A domain is resolved from the "dnslink=/mop/<reference>" TXT record of the
+// "_dnslink" subdomain of the name, or of the name itself if the subdomain
+// has no such record.
+package dnslink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/resolver"
+)
+
+// loggerName is the tree path name of the logger for this package.
+const loggerName = "dnslink"
+
+const (
+	// DefaultTTL is the default time a resolved name is cached for.
+	DefaultTTL = 5 * time.Minute
+	// DefaultTimeout is the default timeout of the DNS lookup.
+	DefaultTimeout = 10 * time.Second
+
+	subdomainPrefix = "_dnslink."
+	recordPrefix    = "dnslink="
+	namespacePrefix = "/mop/"
+)
+
+// Address is the cluster mop address.
+type Address = cluster.Address
+
+// Ensure Resolver implements the resolver.Interface interface.
+var _ resolver.Interface = (*Resolver)(nil)
+
+// Client looks up the DNS TXT records of a domain name. It is implemented by
+// *net.Resolver.
+type Client interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+type cacheEntry struct {
+	address Address
+	expires time.Time
+}
+
+// Resolver resolves domain names using DNSLink TXT records.
+type Resolver struct {
+	client  Client
+	ttl     time.Duration
+	timeout time.Duration
+	logger  log.Logger
+	now     func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+// Option is a function that applies an option to a Resolver.
+type Option func(*Resolver)
+
+// New returns a new Resolver. The system DNS resolver is used unless a
+// client is set with the WithClient option.
+func New(opts ...Option) *Resolver {
+	r := &Resolver{
+		client:  net.DefaultResolver,
+		ttl:     DefaultTTL,
+		timeout: DefaultTimeout,
+		logger:  log.Noop,
+		now:     time.Now,
+		cache:   make(map[string]cacheEntry),
+	}
+
+	for _, o := range opts {
+		o(r)
+	}
+
+	return r
+}
+
+// WithClient sets the DNS client used for the TXT record lookups.
+func WithClient(c Client) Option {
+	return func(r *Resolver) {
+		r.client = c
+	}
+}
+
+// WithTTL sets the time a resolved name is cached for. A zero TTL disables
+// caching.
+func WithTTL(ttl time.Duration) Option {
+	return func(r *Resolver) {
+		r.ttl = ttl
+	}
+}
+
+// WithTimeout sets the timeout of the DNS lookups of a single resolution.
+func WithTimeout(timeout time.Duration) Option {
+	return func(r *Resolver) {
+		r.timeout = timeout
+	}
+}
+
+// WithLogger sets the logger used by the Resolver.
+func WithLogger(logger log.Logger) Option {
+	return func(r *Resolver) {
+		r.logger = logger.WithName(loggerName).Register()
+	}
+}
+
+// Resolve implements the resolver.Interface interface.
+func (r *Resolver) Resolve(name string) (Address, error) {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+
+	r.mu.Lock()
+	e, ok := r.cache[name]
+	r.mu.Unlock()
+	if ok && r.now().Before(e.expires) {
+		return e.address, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	addr, err := r.lookup(ctx, subdomainPrefix+name)
+	if errors.Is(err, resolver.ErrNotFound) {
+		addr, err = r.lookup(ctx, name)
+	}
+	if err != nil {
+		r.logger.Debug("resolve failed", "name", name, "error", err)
+		return cluster.ZeroAddress, err
+	}
+
+	if r.ttl > 0 {
+		r.mu.Lock()
+		r.cache[name] = cacheEntry{address: addr, expires: r.now().Add(r.ttl)}
+		r.mu.Unlock()
+	}
+
+	return addr, nil
+}
+
+// Close implements the resolver.Interface interface.
+func (r *Resolver) Close() error {
+	return nil
+}
+
+func (r *Resolver) lookup(ctx context.Context, name string) (Address, error) {
+	records, err := r.client.LookupTXT(ctx, name)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return cluster.ZeroAddress, fmt.Errorf("name %s: %w", name, resolver.ErrNotFound)
+		}
+		return cluster.ZeroAddress, fmt.Errorf("lookup %s: %v: %w", name, err, resolver.ErrServiceNotAvailable)
+	}
+
+	// The records of other namespaces, like /ipfs/, are skipped. An error
+	// is returned only if none of the records is a valid mop record.
+	var recordErr error
+	for _, record := range records {
+		if !strings.HasPrefix(record, recordPrefix) {
+			continue
+		}
+		addr, err := ParseRecord(record)
+		if err != nil {
+			if recordErr == nil {
+				recordErr = err
+			}
+			continue
+		}
+		return addr, nil
+	}
+	if recordErr != nil {
+		return cluster.ZeroAddress, recordErr
+	}
+
+	return cluster.ZeroAddress, fmt.Errorf("name %s: %w", name, resolver.ErrNotFound)
+}
+
+// ParseRecord parses the reference of the "dnslink=/mop/<reference>" TXT
+// record. Any path following the reference is ignored.
+func ParseRecord(record string) (Address, error) {
+	value := strings.TrimPrefix(strings.TrimSpace(record), recordPrefix)
+	if !strings.HasPrefix(value, namespacePrefix) {
+		return cluster.ZeroAddress, fmt.Errorf("record %q: %w", record, resolver.ErrParse)
+	}
+	value = strings.TrimPrefix(value, namespacePrefix)
+	if i := strings.Index(value, "/"); i >= 0 {
+		value = value[:i]
+	}
+
+	addr, err := cluster.ParseHexAddress(value)
+	if err != nil {
+		return cluster.ZeroAddress, fmt.Errorf("record %q: %v: %w", record, err, resolver.ErrInvalidContentHash)
+	}
+	return addr, nil
+}
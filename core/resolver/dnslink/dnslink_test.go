@@ -0,0 +1,154 @@
+package dnslink_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/resolver"
+	"github.com/redesblock/mop/core/resolver/dnslink"
+)
+
+// dnsServer is an in-process DNS server answering TXT queries from a map.
+type dnsServer struct {
+	mu      sync.Mutex
+	records map[string][]string
+}
+
+func (d *dnsServer) set(name string, txt ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.records[dns.Fqdn(name)] = txt
+}
+
+func (d *dnsServer) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	m := new(dns.Msg)
+	m.SetReply(req)
+	m.Authoritative = true
+	for _, q := range req.Question {
+		txt, ok := d.records[strings.ToLower(q.Name)]
+		if !ok {
+			m.Rcode = dns.RcodeNameError
+			continue
+		}
+		if q.Qtype != dns.TypeTXT {
+			continue
+		}
+		for _, v := range txt {
+			m.Answer = append(m.Answer, &dns.TXT{
+				Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60},
+				Txt: []string{v},
+			})
+		}
+	}
+	_ = w.WriteMsg(m)
+}
+
+func newClient(t *testing.T) (*dnsServer, dnslink.Client) {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &dnsServer{records: make(map[string][]string)}
+	srv := &dns.Server{PacketConn: pc, Handler: d}
+	go func() { _ = srv.ActivateAndServe() }()
+	t.Cleanup(func() { _ = srv.Shutdown() })
+
+	return d, &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "udp", pc.LocalAddr().String())
+		},
+	}
+}
+
+func TestResolve(t *testing.T) {
+	var (
+		ref1 = cluster.MustParseHexAddress("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+		ref2 = cluster.MustParseHexAddress("bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+		now  = time.Now()
+	)
+	server, client := newClient(t)
+	server.set("_dnslink.docs.example.org", "v=spf1 -all", "dnslink=/mop/"+ref1.String())
+	server.set("example.org", "dnslink=/mop/"+ref2.String()+"/index.html")
+	server.set("bad.example.org", "dnslink=/ipfs/QmHash")
+	server.set("mixed.example.org", "dnslink=/ipfs/QmHash", "dnslink=/mop/invalid", "dnslink=/mop/"+ref1.String())
+
+	r := dnslink.New(
+		dnslink.WithClient(client),
+		dnslink.WithTTL(time.Minute),
+		dnslink.WithNowFunc(func() time.Time { return now }),
+	)
+
+	testCases := []struct {
+		name    string
+		want    cluster.Address
+		wantErr error
+	}{
+		{name: "docs.example.org", want: ref1},
+		{name: "Example.org.", want: ref2},
+		{name: "missing.example.org", wantErr: resolver.ErrNotFound},
+		{name: "bad.example.org", wantErr: resolver.ErrParse},
+		{name: "mixed.example.org", want: ref1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := r.Resolve(tc.name)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tc.wantErr)
+			}
+			if !got.Equal(tc.want) {
+				t.Fatalf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("cache", func(t *testing.T) {
+		server.set("_dnslink.docs.example.org", "dnslink=/mop/"+ref2.String())
+
+		got, err := r.Resolve("docs.example.org")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(ref1) {
+			t.Fatalf("got %s, want cached %s", got, ref1)
+		}
+
+		now = now.Add(2 * time.Minute)
+		got, err = r.Resolve("docs.example.org")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(ref2) {
+			t.Fatalf("got %s, want %s", got, ref2)
+		}
+	})
+}
+
+func TestParseRecord(t *testing.T) {
+	ref := cluster.MustParseHexAddress("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+
+	got, err := dnslink.ParseRecord("dnslink=/mop/" + ref.String() + "/path/to/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(ref) {
+		t.Fatalf("got %s, want %s", got, ref)
+	}
+
+	if _, err := dnslink.ParseRecord("dnslink=/mop/zz"); !errors.Is(err, resolver.ErrInvalidContentHash) {
+		t.Fatalf("got error %v, want %v", err, resolver.ErrInvalidContentHash)
+	}
+}
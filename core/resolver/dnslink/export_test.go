@@ -0,0 +1,11 @@
+package dnslink
+
+import "time"
+
+// WithNowFunc sets the function returning the current time used for the
+// cache expiry.
+func WithNowFunc(now func() time.Time) Option {
+	return func(r *Resolver) {
+		r.now = now
+	}
+}
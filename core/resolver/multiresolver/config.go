@@ -2,7 +2,9 @@ package multiresolver
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -12,12 +14,36 @@ import (
 // https://en.wikipedia.org/wiki/Domain_Name_System#cite_note-rfc1034-1
 const maxTLDLength = 63
 
+// RegistryEndpoint is the endpoint which selects the local name registry
+// resolver. The address of the connection string is the registry owner and
+// the record cache TTL can be set with the ttl query parameter, eg.
+// "tld:0x314159265dD8dbb310642f98f50C066173C1259b@registry?ttl=10m".
+const RegistryEndpoint = "registry"
+
+// DNSLinkEndpoint is the endpoint which selects the DNSLink TXT record
+// resolver. The record cache TTL can be set with the ttl query parameter,
+// eg. "dnslink?ttl=1m".
+const DNSLinkEndpoint = "dnslink"
+
 // ConnectionConfig contains the TLD, endpoint and contract address used to
 // establish to a resolver.
 type ConnectionConfig struct {
 	TLD      string
 	Address  string
 	Endpoint string
+	// TTL is the time the names resolved by the local name registry or the
+	// DNSLink resolver are cached for. A zero TTL uses the resolver default.
+	TTL time.Duration
+}
+
+// IsRegistry returns true if the config selects the local name registry.
+func (c ConnectionConfig) IsRegistry() bool {
+	return c.Endpoint == RegistryEndpoint
+}
+
+// IsDNSLink returns true if the config selects the DNSLink resolver.
+func (c ConnectionConfig) IsDNSLink() bool {
+	return c.Endpoint == DNSLinkEndpoint
 }
 
 // ParseConnectionString will try to parse a connection string used to connect
@@ -56,10 +82,34 @@ func parseConnectionString(cs string) (ConnectionConfig, error) {
 		endpoint = endpoint[i+1:]
 	}
 
+	var ttl time.Duration
+	isEndpoint := func(name string) bool {
+		return endpoint == name || strings.HasPrefix(endpoint, name+"?")
+	}
+	if isEndpoint(RegistryEndpoint) || isEndpoint(DNSLinkEndpoint) {
+		if isEndpoint(RegistryEndpoint) && addr == "" {
+			return ConnectionConfig{}, fmt.Errorf("registry %s: %w", cs, ErrRegistryOwnerMissing)
+		}
+		if i := strings.Index(endpoint, "?"); i > 0 {
+			q, err := url.ParseQuery(endpoint[i+1:])
+			if err != nil {
+				return ConnectionConfig{}, fmt.Errorf("resolver %s: %w", cs, err)
+			}
+			if v := q.Get("ttl"); v != "" {
+				ttl, err = time.ParseDuration(v)
+				if err != nil || ttl < 0 {
+					return ConnectionConfig{}, fmt.Errorf("resolver ttl %s: %w", v, ErrInvalidTTL)
+				}
+			}
+			endpoint = endpoint[:i]
+		}
+	}
+
 	return ConnectionConfig{
 		Endpoint: endpoint,
 		Address:  addr,
 		TLD:      tld,
+		TTL:      ttl,
 	}, nil
 }
 
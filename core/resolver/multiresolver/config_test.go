@@ -3,6 +3,7 @@ package multiresolver_test
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/redesblock/mop/core/resolver/multiresolver"
 )
@@ -101,6 +102,58 @@ func TestParseConnectionStrings(t *testing.T) {
 				},
 			},
 		},
+		{
+			desc: "registry with ttl",
+			conStrings: []string{
+				"team:0x314159265dD8dbb310642f98f50C066173C1259b@registry?ttl=10m",
+				"0x314159265dD8dbb310642f98f50C066173C1259b@registry",
+			},
+			wantCfg: []multiresolver.ConnectionConfig{
+				{
+					TLD:      "team",
+					Address:  "0x314159265dD8dbb310642f98f50C066173C1259b",
+					Endpoint: multiresolver.RegistryEndpoint,
+					TTL:      10 * time.Minute,
+				},
+				{
+					TLD:      "",
+					Address:  "0x314159265dD8dbb310642f98f50C066173C1259b",
+					Endpoint: multiresolver.RegistryEndpoint,
+				},
+			},
+		},
+		{
+			desc: "dnslink",
+			conStrings: []string{
+				"org:dnslink?ttl=30s",
+				"dnslink",
+			},
+			wantCfg: []multiresolver.ConnectionConfig{
+				{
+					TLD:      "org",
+					Endpoint: multiresolver.DNSLinkEndpoint,
+					TTL:      30 * time.Second,
+				},
+				{
+					TLD:      "",
+					Endpoint: multiresolver.DNSLinkEndpoint,
+				},
+			},
+		},
+		{
+			desc: "registry without owner",
+			conStrings: []string{
+				"team:registry",
+			},
+			wantErr: multiresolver.ErrRegistryOwnerMissing,
+		},
+		{
+			desc: "registry with invalid ttl",
+			conStrings: []string{
+				"team:0x314159265dD8dbb310642f98f50C066173C1259b@registry?ttl=soon",
+			},
+			wantErr: multiresolver.ErrInvalidTTL,
+		},
 		{
 			desc: "mixed with error",
 			conStrings: []string{
@@ -133,6 +186,9 @@ func TestParseConnectionStrings(t *testing.T) {
 				if got.Endpoint != want.Endpoint {
 					t.Errorf("got %q, want %q", got.Endpoint, want.Endpoint)
 				}
+				if got.TTL != want.TTL {
+					t.Errorf("got %v, want %v", got.TTL, want.TTL)
+				}
 			}
 		})
 	}
@@ -5,12 +5,17 @@ import (
 	"fmt"
 	"path"
 	"strings"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/hashicorp/go-multierror"
 	"github.com/redesblock/mop/core/log"
 	"github.com/redesblock/mop/core/resolver"
 	"github.com/redesblock/mop/core/resolver/cidv1"
 	"github.com/redesblock/mop/core/resolver/client/ens"
+	"github.com/redesblock/mop/core/resolver/dnslink"
+	"github.com/redesblock/mop/core/resolver/registry"
+	"github.com/redesblock/mop/core/storer/storage"
 )
 
 // loggerName is the tree path name of the logger for this package.
@@ -31,6 +36,11 @@ var (
 	ErrResolverChainFailed = errors.New("resolver chain failed")
 	// ErrCloseFailed denotes that closing the multiresolver failed.
 	ErrCloseFailed = errors.New("close failed")
+	// ErrRegistryOwnerMissing denotes a local name registry connection
+	// string without the registry owner address.
+	ErrRegistryOwnerMissing = errors.New("registry owner address missing")
+	// ErrInvalidTTL denotes an invalid resolver cache TTL.
+	ErrInvalidTTL = errors.New("invalid TTL")
 )
 
 type resolverMap map[string][]resolver.Interface
@@ -40,6 +50,8 @@ type MultiResolver struct {
 	resolvers resolverMap
 	logger    log.Logger
 	cfgs      []ConnectionConfig
+	getter    storage.Getter
+	dnsClient dnslink.Client
 	// ForceDefault will force all names to be resolved by the default
 	// resolution chain, regadless of their TLD.
 	ForceDefault bool
@@ -72,10 +84,21 @@ func NewMultiResolver(opts ...Option) *MultiResolver {
 
 	// Attempt to conect to each resolver using the connection string.
 	for _, c := range mr.cfgs {
+		// Resolve looks up the resolver chains by the name extension, which
+		// includes the leading dot.
+		tld := c.TLD
+		if tld != "" {
+			tld = "." + tld
+		}
 
-		// NOTE: if we want to create a specific client based on the TLD
-		// we can do it here.
-		mr.connectENSClient(c.TLD, c.Address, c.Endpoint)
+		switch {
+		case c.IsRegistry():
+			mr.connectRegistry(tld, c.Address, c.TTL)
+		case c.IsDNSLink():
+			mr.connectDNSLink(tld, c.TTL)
+		default:
+			mr.connectENSClient(tld, c.Address, c.Endpoint)
+		}
 	}
 
 	return mr
@@ -88,6 +111,22 @@ func WithConnectionConfigs(cfgs []ConnectionConfig) Option {
 	}
 }
 
+// WithChunkGetter will set the chunk getter used by the local name registry
+// resolvers to look up the registry records.
+func WithChunkGetter(getter storage.Getter) Option {
+	return func(mr *MultiResolver) {
+		mr.getter = getter
+	}
+}
+
+// WithDNSClient will set the DNS client used by the DNSLink resolvers instead
+// of the system resolver.
+func WithDNSClient(c dnslink.Client) Option {
+	return func(mr *MultiResolver) {
+		mr.dnsClient = c
+	}
+}
+
 // WithLogger will set the logger used by the MultiResolver.
 func WithLogger(logger log.Logger) Option {
 	return func(mr *MultiResolver) {
@@ -206,3 +245,31 @@ func (mr *MultiResolver) connectENSClient(tld, address, endpoint string) {
 		mr.PushResolver(tld, ensCl)
 	}
 }
+
+func (mr *MultiResolver) connectRegistry(tld, owner string, ttl time.Duration) {
+	log := mr.logger
+
+	if mr.getter == nil {
+		log.Error(nil, "local name registry requires a chunk getter", "tld", tld, "owner", owner)
+		return
+	}
+
+	opts := []registry.Option{registry.WithLogger(log)}
+	if ttl > 0 {
+		opts = append(opts, registry.WithTTL(ttl))
+	}
+	mr.PushResolver(tld, registry.New(mr.getter, common.HexToAddress(owner), opts...))
+	log.Info("local name registry", "tld", tld, "owner", owner)
+}
+
+func (mr *MultiResolver) connectDNSLink(tld string, ttl time.Duration) {
+	opts := []dnslink.Option{dnslink.WithLogger(mr.logger)}
+	if mr.dnsClient != nil {
+		opts = append(opts, dnslink.WithClient(mr.dnsClient))
+	}
+	if ttl > 0 {
+		opts = append(opts, dnslink.WithTTL(ttl))
+	}
+	mr.PushResolver(tld, dnslink.New(opts...))
+	mr.logger.Info("dnslink resolver", "tld", tld)
+}
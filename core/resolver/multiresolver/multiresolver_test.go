@@ -11,6 +11,7 @@ import (
 	"github.com/redesblock/mop/core/resolver"
 	"github.com/redesblock/mop/core/resolver/mock"
 	"github.com/redesblock/mop/core/resolver/multiresolver"
+	storemock "github.com/redesblock/mop/core/storer/storage/mock"
 )
 
 type Address = cluster.Address
@@ -255,3 +256,29 @@ func TestResolve(t *testing.T) {
 		}
 	})
 }
+
+func TestConnectLocalResolvers(t *testing.T) {
+	mr := multiresolver.NewMultiResolver(
+		multiresolver.WithConnectionConfigs([]multiresolver.ConnectionConfig{
+			{
+				TLD:      "org",
+				Endpoint: multiresolver.DNSLinkEndpoint,
+			},
+			{
+				TLD:      "team",
+				Address:  "0x314159265dD8dbb310642f98f50C066173C1259b",
+				Endpoint: multiresolver.RegistryEndpoint,
+			},
+		}),
+		multiresolver.WithChunkGetter(storemock.NewStorer()),
+	)
+
+	for _, tld := range []string{".org", ".team"} {
+		if got := mr.ChainCount(tld); got != 1 {
+			t.Errorf("tld %s: got %d resolvers, want 1", tld, got)
+		}
+	}
+	if got := mr.ChainCount(""); got != 0 {
+		t.Errorf("default chain: got %d resolvers, want 0", got)
+	}
+}
@@ -0,0 +1,208 @@
+// Package registry implements a name resolver backed by a local name
+// registry. The records of the registry are feed updates signed by the
+// registry owner, stored as single owner chunks in the cluster, so names can
+// be published and updated without an on chain transaction.
+//
+// The record of a name is the sequence feed with the keccak256 hash of the
+// lower cased name as the topic and the registry owner as the owner. The
+// payload of the latest feed update is the reference the name resolves to.
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/redesblock/mop/core/chunk/soc"
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/crypto"
+	"github.com/redesblock/mop/core/feeds"
+	"github.com/redesblock/mop/core/feeds/sequence"
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/resolver"
+	"github.com/redesblock/mop/core/storer/storage"
+)
+
+// loggerName is the tree path name of the logger for this package.
+const loggerName = "registry"
+
+const (
+	// DefaultTTL is the default time a resolved name is cached for.
+	DefaultTTL = 5 * time.Minute
+	// DefaultTimeout is the default timeout of the record lookup.
+	DefaultTimeout = 30 * time.Second
+)
+
+// Address is the cluster mop address.
+type Address = cluster.Address
+
+// Ensure Resolver implements the resolver.Interface interface.
+var _ resolver.Interface = (*Resolver)(nil)
+
+// ErrInvalidRecord denotes that the record of the name is not a valid
+// record signed by the registry owner.
+var ErrInvalidRecord = errors.New("invalid registry record")
+
+type cacheEntry struct {
+	address Address
+	expires time.Time
+}
+
+// Resolver resolves names using the records published by the registry owner.
+type Resolver struct {
+	getter  storage.Getter
+	owner   common.Address
+	ttl     time.Duration
+	timeout time.Duration
+	logger  log.Logger
+	now     func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+// Option is a function that applies an option to a Resolver.
+type Option func(*Resolver)
+
+// New returns a new Resolver which looks up the records of the registry
+// owned by owner through the given chunk getter.
+func New(getter storage.Getter, owner common.Address, opts ...Option) *Resolver {
+	r := &Resolver{
+		getter:  getter,
+		owner:   owner,
+		ttl:     DefaultTTL,
+		timeout: DefaultTimeout,
+		logger:  log.Noop,
+		now:     time.Now,
+		cache:   make(map[string]cacheEntry),
+	}
+
+	for _, o := range opts {
+		o(r)
+	}
+
+	return r
+}
+
+// WithTTL sets the time a resolved name is cached for. A zero TTL disables
+// caching.
+func WithTTL(ttl time.Duration) Option {
+	return func(r *Resolver) {
+		r.ttl = ttl
+	}
+}
+
+// WithTimeout sets the timeout of the record lookup.
+func WithTimeout(timeout time.Duration) Option {
+	return func(r *Resolver) {
+		r.timeout = timeout
+	}
+}
+
+// WithLogger sets the logger used by the Resolver.
+func WithLogger(logger log.Logger) Option {
+	return func(r *Resolver) {
+		r.logger = logger.WithName(loggerName).Register()
+	}
+}
+
+// Owner returns the address of the registry owner.
+func (r *Resolver) Owner() common.Address {
+	return r.owner
+}
+
+// Resolve implements the resolver.Interface interface.
+func (r *Resolver) Resolve(name string) (Address, error) {
+	name = strings.ToLower(name)
+
+	r.mu.Lock()
+	e, ok := r.cache[name]
+	r.mu.Unlock()
+	if ok && r.now().Before(e.expires) {
+		return e.address, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	addr, err := r.lookup(ctx, name)
+	if err != nil {
+		r.logger.Debug("resolve failed", "name", name, "error", err)
+		return cluster.ZeroAddress, err
+	}
+
+	if r.ttl > 0 {
+		r.mu.Lock()
+		r.cache[name] = cacheEntry{address: addr, expires: r.now().Add(r.ttl)}
+		r.mu.Unlock()
+	}
+
+	return addr, nil
+}
+
+// Close implements the resolver.Interface interface.
+func (r *Resolver) Close() error {
+	return nil
+}
+
+func (r *Resolver) lookup(ctx context.Context, name string) (Address, error) {
+	topic, err := Topic(name)
+	if err != nil {
+		return cluster.ZeroAddress, err
+	}
+
+	lookup := sequence.NewAsyncFinder(r.getter, feeds.New(topic, r.owner))
+	ch, err := feeds.Latest(ctx, lookup, 0)
+	if err != nil {
+		return cluster.ZeroAddress, fmt.Errorf("lookup %s: %v: %w", name, err, resolver.ErrServiceNotAvailable)
+	}
+	if ch == nil {
+		return cluster.ZeroAddress, fmt.Errorf("name %s: %w", name, resolver.ErrNotFound)
+	}
+
+	// The record address is derived from the registry owner, a valid
+	// single owner chunk at that address can only be signed by the owner.
+	if !soc.Valid(ch) {
+		return cluster.ZeroAddress, fmt.Errorf("name %s: %w", name, ErrInvalidRecord)
+	}
+	_, payload, err := feeds.FromChunk(ch)
+	if err != nil {
+		return cluster.ZeroAddress, fmt.Errorf("name %s: %v: %w", name, err, ErrInvalidRecord)
+	}
+	if len(payload) != cluster.HashSize && len(payload) != cluster.HashSize*2 {
+		return cluster.ZeroAddress, fmt.Errorf("name %s: reference length %d: %w", name, len(payload), resolver.ErrInvalidContentHash)
+	}
+
+	return cluster.NewAddress(payload), nil
+}
+
+// Topic returns the feed topic of the record of the given name.
+func Topic(name string) ([]byte, error) {
+	return crypto.LegacyKeccak256([]byte(strings.ToLower(name)))
+}
+
+// Publish stores a new record for the name pointing to the reference. The
+// record is signed by the signer, which has to be the registry owner for the
+// record to be resolved.
+func Publish(ctx context.Context, getter storage.Getter, putter storage.Putter, signer crypto.Signer, name string, ref Address) error {
+	topic, err := Topic(name)
+	if err != nil {
+		return err
+	}
+	p, err := feeds.NewPutter(putter, signer, topic)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	_, _, next, err := sequence.NewFinder(getter, p.Feed).At(ctx, now, 0)
+	if err != nil {
+		return fmt.Errorf("lookup next index: %w", err)
+	}
+
+	return p.Put(ctx, next, now, ref.Bytes())
+}
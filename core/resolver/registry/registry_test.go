@@ -0,0 +1,89 @@
+package registry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/crypto"
+	"github.com/redesblock/mop/core/resolver"
+	"github.com/redesblock/mop/core/resolver/registry"
+	"github.com/redesblock/mop/core/storer/storage/mock"
+)
+
+func newSigner(t *testing.T) crypto.Signer {
+	t.Helper()
+
+	key, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return crypto.NewDefaultSigner(key)
+}
+
+func TestResolve(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		storer = mock.NewStorer()
+		signer = newSigner(t)
+		ref1   = cluster.MustParseHexAddress("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+		ref2   = cluster.MustParseHexAddress("bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+		now    = time.Now()
+	)
+	owner, err := signer.BSCAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := registry.New(storer, owner,
+		registry.WithTTL(time.Minute),
+		registry.WithNowFunc(func() time.Time { return now }),
+	)
+
+	if _, err := r.Resolve("dataset.team"); !errors.Is(err, resolver.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, resolver.ErrNotFound)
+	}
+
+	if err := registry.Publish(ctx, storer, storer, signer, "dataset.team", ref1); err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.Resolve("Dataset.Team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(ref1) {
+		t.Fatalf("got %s, want %s", got, ref1)
+	}
+
+	if err := registry.Publish(ctx, storer, storer, signer, "dataset.team", ref2); err != nil {
+		t.Fatal(err)
+	}
+	got, err = r.Resolve("dataset.team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(ref1) {
+		t.Fatalf("got %s, want cached %s", got, ref1)
+	}
+
+	now = now.Add(2 * time.Minute)
+	got, err = r.Resolve("dataset.team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(ref2) {
+		t.Fatalf("got %s, want %s", got, ref2)
+	}
+
+	t.Run("other owner", func(t *testing.T) {
+		other := newSigner(t)
+		if err := registry.Publish(ctx, storer, storer, other, "other.team", ref1); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Resolve("other.team"); !errors.Is(err, resolver.ErrNotFound) {
+			t.Fatalf("got error %v, want %v", err, resolver.ErrNotFound)
+		}
+	})
+}
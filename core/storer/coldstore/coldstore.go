@@ -0,0 +1,22 @@
+// Package coldstore provides the blob stores of the secondary storage tier
+// to which the localstore moves rarely accessed chunks.
+package coldstore
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound is returned when the blob is not in the store.
+var ErrNotFound = errors.New("coldstore: not found")
+
+// Store is a blob store keyed by strings of lowercase hexadecimal digits.
+type Store interface {
+	// Put stores the blob under the key, replacing an existing one.
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns the blob stored under the key or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes the blob stored under the key. It is not an
+	// error if the blob does not exist.
+	Delete(ctx context.Context, key string) error
+}
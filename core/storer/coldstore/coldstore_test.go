@@ -0,0 +1,145 @@
+package coldstore_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/redesblock/mop/core/storer/coldstore"
+)
+
+func TestDirStore(t *testing.T) {
+	s, err := coldstore.NewDirStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+}
+
+func TestS3Store(t *testing.T) {
+	srv := httptest.NewServer(newS3StandIn("access", "bucket"))
+	t.Cleanup(srv.Close)
+
+	s, err := coldstore.NewS3Store(coldstore.S3Options{
+		Endpoint:  srv.URL,
+		Bucket:    "bucket",
+		AccessKey: "access",
+		SecretKey: "secret",
+		Prefix:    "mop/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+
+	s, err = coldstore.NewS3Store(coldstore.S3Options{
+		Endpoint:  srv.URL,
+		Bucket:    "bucket",
+		AccessKey: "other",
+		SecretKey: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(context.Background(), "00", []byte("data")); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("got error %v, want forbidden", err)
+	}
+
+	if _, err := coldstore.NewS3Store(coldstore.S3Options{Endpoint: "ftp://host", Bucket: "bucket"}); err == nil {
+		t.Fatal("expected invalid endpoint error")
+	}
+}
+
+func testStore(t *testing.T, s coldstore.Store) {
+	t.Helper()
+
+	ctx := context.Background()
+	key, data := "00000000012a", []byte("chunk data")
+
+	if _, err := s.Get(ctx, key); !errors.Is(err, coldstore.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, coldstore.ErrNotFound)
+	}
+	if err := s.Put(ctx, key, data); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("got data %q, want %q", got, data)
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, key); !errors.Is(err, coldstore.ErrNotFound) {
+		t.Fatalf("got error %v after delete, want %v", err, coldstore.ErrNotFound)
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("deleting missing blob: %v", err)
+	}
+}
+
+// s3StandIn is a minimal in-memory S3-compatible object store
+// which checks the shape of the request signatures.
+type s3StandIn struct {
+	accessKey string
+	bucket    string
+	mu        sync.Mutex
+	objects   map[string][]byte
+}
+
+func newS3StandIn(accessKey, bucket string) *s3StandIn {
+	return &s3StandIn{accessKey: accessKey, bucket: bucket, objects: make(map[string][]byte)}
+}
+
+func (s *s3StandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	hash := sha256.Sum256(body)
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential="+s.accessKey+"/") ||
+		!strings.Contains(auth, "SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=") ||
+		r.Header.Get("X-Amz-Date") == "" ||
+		r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(hash[:]) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/"+s.bucket+"/")
+	if name == r.URL.Path {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		s.objects[name] = body
+	case http.MethodGet:
+		data, ok := s.objects[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	case http.MethodDelete:
+		delete(s.objects, name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
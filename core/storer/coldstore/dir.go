@@ -0,0 +1,69 @@
+package coldstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var _ Store = (*DirStore)(nil)
+
+// DirStore stores the blobs as files in a directory, typically
+// on a larger and slower disk than the one of the localstore.
+type DirStore struct {
+	path string
+}
+
+// NewDirStore returns a store keeping the blobs in the directory,
+// which is created if it does not exist.
+func NewDirStore(path string) (*DirStore, error) {
+	if err := os.MkdirAll(path, 0775); err != nil {
+		return nil, fmt.Errorf("create directory: %w", err)
+	}
+	return &DirStore{path: path}, nil
+}
+
+// file returns the path of the blob file, the blobs are spread
+// over subdirectories named after the last two key digits.
+func (s *DirStore) file(key string) string {
+	dir := key
+	if len(key) > 2 {
+		dir = key[len(key)-2:]
+	}
+	return filepath.Join(s.path, dir, key)
+}
+
+// Put implements the Store interface.
+func (s *DirStore) Put(_ context.Context, key string, data []byte) error {
+	file := s.file(key)
+	if err := os.MkdirAll(filepath.Dir(file), 0775); err != nil {
+		return err
+	}
+
+	// write to a temporary file first not to leave partial blobs behind
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// Get implements the Store interface.
+func (s *DirStore) Get(_ context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(s.file(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// Delete implements the Store interface.
+func (s *DirStore) Delete(_ context.Context, key string) error {
+	err := os.Remove(s.file(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,178 @@
+package coldstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var _ Store = (*S3Store)(nil)
+
+// DefaultS3Timeout is the timeout of the requests to the object store
+// if no client is set in the options.
+const DefaultS3Timeout = 30 * time.Second
+
+// S3Options are the options of an S3-compatible object store.
+type S3Options struct {
+	// Endpoint is the URL of the object store, like https://s3.eu-central-1.amazonaws.com.
+	Endpoint string
+	// Region is the region used to sign the requests, us-east-1 if empty.
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// Prefix is prepended to the keys of the objects.
+	Prefix string
+	// Client is the HTTP client of the requests, a client with the
+	// DefaultS3Timeout if nil.
+	Client *http.Client
+}
+
+// S3Store stores the blobs as objects in a bucket of an S3-compatible
+// object store, addressed in path style and signed with AWS Signature V4.
+type S3Store struct {
+	endpoint *url.URL
+	o        S3Options
+}
+
+// NewS3Store returns a store keeping the blobs in the bucket.
+func NewS3Store(o S3Options) (*S3Store, error) {
+	endpoint, err := url.Parse(o.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parse endpoint: %w", err)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf("invalid endpoint %q", o.Endpoint)
+	}
+	if o.Bucket == "" {
+		return nil, errors.New("no bucket")
+	}
+	if o.Region == "" {
+		o.Region = "us-east-1"
+	}
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: DefaultS3Timeout}
+	}
+	return &S3Store{endpoint: endpoint, o: o}, nil
+}
+
+// Put implements the Store interface.
+func (s *S3Store) Put(ctx context.Context, key string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+	return nil
+}
+
+// Get implements the Store interface.
+func (s *S3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(resp.Body)
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, responseError(resp)
+	}
+}
+
+// Delete implements the Store interface.
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return responseError(resp)
+	}
+}
+
+func (s *S3Store) do(ctx context.Context, method, key string, body []byte) (*http.Response, error) {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.o.Bucket + "/" + s.o.Prefix + key
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	s.sign(req, body, time.Now().UTC())
+
+	return s.o.Client.Do(req)
+}
+
+// sign adds the AWS Signature Version 4 authorization header to the request.
+func (s *S3Store) sign(req *http.Request, body []byte, t time.Time) {
+	const algorithm = "AWS4-HMAC-SHA256"
+
+	payloadHash := sha256Hex(body)
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.o.Region + "/s3/aws4_request"
+	stringToSign := algorithm + "\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.o.SecretKey), date)
+	key = hmacSHA256(key, s.o.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s", algorithm, s.o.AccessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func responseError(resp *http.Response) error {
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("object store: %s: %s", resp.Status, bytes.TrimSpace(b))
+}
@@ -0,0 +1,249 @@
+// Package kv provides the ordered key-value store abstraction which the
+// shed and the statestore are built on, together with its goleveldb and
+// pebble backends.
+package kv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// BackendLevelDB is the name of the goleveldb backend.
+	BackendLevelDB = "leveldb"
+	// BackendPebble is the name of the pebble backend.
+	BackendPebble = "pebble"
+
+	// DefaultBackend is the backend of new databases when none is requested.
+	DefaultBackend = BackendLevelDB
+)
+
+// Backends lists the names of all the supported backends.
+var Backends = []string{BackendLevelDB, BackendPebble}
+
+var (
+	// ErrNotFound is returned when the key is not found in the store.
+	ErrNotFound = errors.New("kv: not found")
+	// ErrUnknownBackend is returned when the requested backend is not supported.
+	ErrUnknownBackend = errors.New("kv: unknown backend")
+	// ErrBackendMismatch is returned when the requested backend differs from
+	// the backend of an existing database.
+	ErrBackendMismatch = errors.New("kv: backend mismatch")
+)
+
+// Store is an ordered key-value store.
+type Store interface {
+	// Get returns the value of the key or ErrNotFound.
+	Get(key []byte) ([]byte, error)
+	// Has reports whether the key is in the store.
+	Has(key []byte) (bool, error)
+	// Put sets the value of the key.
+	Put(key, value []byte) error
+	// Delete removes the key. It is not an error if the key does not exist.
+	Delete(key []byte) error
+	// Write applies the batch atomically.
+	Write(batch *Batch) error
+	// NewIterator returns an iterator over the keys in the range,
+	// over all keys if the range is nil.
+	NewIterator(r *Range) Iterator
+	// NewSnapshot returns a consistent read-only view of the store.
+	NewSnapshot() (Snapshot, error)
+	// Compact compacts the keys in the range [start, end), nil bounds are
+	// the first and the last key of the store.
+	Compact(start, end []byte) error
+	// Close releases the resources of the store.
+	Close() error
+}
+
+// Snapshot is a read-only view of a store at the time it was taken.
+type Snapshot interface {
+	Get(key []byte) ([]byte, error)
+	Has(key []byte) (bool, error)
+	Release()
+}
+
+// Iterator iterates over the key-value pairs of a store in key order. Moving
+// back from an exhausted iterator positions it at the last key and moving
+// forward from before the first key positions it at the first one.
+// Key and Value return nil if the iterator is not positioned at a key, the
+// returned slices must not be modified and are only valid until the next move.
+type Iterator interface {
+	First() bool
+	Last() bool
+	Seek(key []byte) bool
+	Next() bool
+	Prev() bool
+	Key() []byte
+	Value() []byte
+	Release()
+	Error() error
+}
+
+// Range is a key range, including Start and excluding Limit.
+// Nil bounds are unbounded.
+type Range struct {
+	Start []byte
+	Limit []byte
+}
+
+// PrefixRange returns the range of the keys which start with the prefix.
+func PrefixRange(prefix []byte) *Range {
+	var limit []byte
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if c := prefix[i]; c < 0xff {
+			limit = make([]byte, i+1)
+			copy(limit, prefix)
+			limit[i] = c + 1
+			break
+		}
+	}
+	return &Range{Start: prefix, Limit: limit}
+}
+
+// Batch is a sequence of writes which is applied to a store atomically.
+// The zero value is an empty batch ready to use.
+type Batch struct {
+	ops []batchOp
+}
+
+type batchOp struct {
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+// Put adds setting the value of the key to the batch.
+func (b *Batch) Put(key, value []byte) {
+	b.ops = append(b.ops, batchOp{
+		key:   append([]byte(nil), key...),
+		value: append([]byte(nil), value...),
+	})
+}
+
+// Delete adds removing the key to the batch.
+func (b *Batch) Delete(key []byte) {
+	b.ops = append(b.ops, batchOp{
+		key:    append([]byte(nil), key...),
+		delete: true,
+	})
+}
+
+// Len returns the number of writes in the batch.
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+// Reset removes all the writes from the batch.
+func (b *Batch) Reset() {
+	b.ops = b.ops[:0]
+}
+
+// Options are the options of the store backends, zero values
+// select the defaults of the backend.
+type Options struct {
+	// Backend is the backend of a new database. It must match the backend of
+	// an existing database if set. The default backend is used if empty.
+	Backend                string
+	BlockCacheCapacity     uint64
+	WriteBufferSize        uint64
+	OpenFilesLimit         uint64
+	DisableSeeksCompaction bool
+}
+
+// Open opens or creates the database in the directory with the backend
+// recorded in the directory or, for new databases, with the backend in
+// the options. The database is kept in memory if the path is empty.
+func Open(path string, o *Options) (Store, error) {
+	if o == nil {
+		o = new(Options)
+	}
+
+	backend := o.Backend
+	if path != "" {
+		existing, err := ReadBackend(path)
+		if err != nil {
+			return nil, err
+		}
+		if existing != "" {
+			if backend != "" && backend != existing {
+				return nil, fmt.Errorf("%w: database in %s uses %s, not %s", ErrBackendMismatch, path, existing, backend)
+			}
+			backend = existing
+		}
+	}
+	if backend == "" {
+		backend = DefaultBackend
+	}
+
+	var (
+		s   Store
+		err error
+	)
+	switch backend {
+	case BackendLevelDB:
+		s, err = openLevelDB(path, o)
+	case BackendPebble:
+		s, err = openPebble(path, o)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownBackend, backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if path != "" {
+		if err := writeBackend(path, backend); err != nil {
+			_ = s.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// ValidBackend reports whether the backend is supported.
+func ValidBackend(backend string) bool {
+	for _, b := range Backends {
+		if b == backend {
+			return true
+		}
+	}
+	return false
+}
+
+// backendFile is the name of the file in the database directory
+// which records the backend of the database.
+const backendFile = "BACKEND"
+
+// ReadBackend returns the backend of the database in the directory or an
+// empty string if there is no database. Databases created before the
+// backend was recorded are goleveldb ones.
+func ReadBackend(path string) (string, error) {
+	b, err := os.ReadFile(filepath.Join(path, backendFile))
+	if err == nil {
+		return strings.TrimSpace(string(b)), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("read backend: %w", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(path, "CURRENT")); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("read backend: %w", err)
+	}
+	return BackendLevelDB, nil
+}
+
+func writeBackend(path, backend string) error {
+	if existing, err := os.ReadFile(filepath.Join(path, backendFile)); err == nil && strings.TrimSpace(string(existing)) == backend {
+		return nil
+	}
+	if err := os.WriteFile(filepath.Join(path, backendFile), []byte(backend+"\n"), 0644); err != nil {
+		return fmt.Errorf("write backend: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package kv_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/redesblock/mop/core/storer/kv"
+)
+
+func newStore(t *testing.T, path, backend string) kv.Store {
+	t.Helper()
+
+	s, err := kv.Open(path, &kv.Options{Backend: backend})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	return s
+}
+
+func TestStore(t *testing.T) {
+	for _, backend := range kv.Backends {
+		t.Run(backend, func(t *testing.T) {
+			s := newStore(t, "", backend)
+
+			key, value := []byte("key"), []byte("value")
+			if _, err := s.Get(key); !errors.Is(err, kv.ErrNotFound) {
+				t.Fatalf("got error %v, want %v", err, kv.ErrNotFound)
+			}
+			if err := s.Put(key, value); err != nil {
+				t.Fatal(err)
+			}
+			got, err := s.Get(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, value) {
+				t.Fatalf("got value %q, want %q", got, value)
+			}
+			if has, err := s.Has(key); err != nil || !has {
+				t.Fatalf("got has %v, error %v, want key", has, err)
+			}
+
+			snapshot, err := s.NewSnapshot()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer snapshot.Release()
+
+			if err := s.Delete(key); err != nil {
+				t.Fatal(err)
+			}
+			if has, err := s.Has(key); err != nil || has {
+				t.Fatalf("got has %v, error %v, want deleted key", has, err)
+			}
+			if got, err := snapshot.Get(key); err != nil || !bytes.Equal(got, value) {
+				t.Fatalf("got snapshot value %q, error %v, want %q", got, err, value)
+			}
+			if _, err := snapshot.Get([]byte("missing")); !errors.Is(err, kv.ErrNotFound) {
+				t.Fatalf("got snapshot error %v, want %v", err, kv.ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestBatch(t *testing.T) {
+	for _, backend := range kv.Backends {
+		t.Run(backend, func(t *testing.T) {
+			s := newStore(t, "", backend)
+
+			if err := s.Put([]byte("a"), []byte("1")); err != nil {
+				t.Fatal(err)
+			}
+
+			batch := new(kv.Batch)
+			key := []byte("b")
+			batch.Put(key, []byte("2"))
+			key[0] = 'c' // the batch must not share the key
+			batch.Put(key, []byte("3"))
+			batch.Delete([]byte("a"))
+			if batch.Len() != 3 {
+				t.Fatalf("got batch length %d, want %d", batch.Len(), 3)
+			}
+			if err := s.Write(batch); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := keys(t, s, nil); got != "b,c," {
+				t.Fatalf("got keys %q, want %q", got, "b,c,")
+			}
+
+			batch.Reset()
+			if batch.Len() != 0 {
+				t.Fatal("batch not reset")
+			}
+		})
+	}
+}
+
+func TestIterator(t *testing.T) {
+	for _, backend := range kv.Backends {
+		t.Run(backend, func(t *testing.T) {
+			s := newStore(t, "", backend)
+
+			for _, k := range []string{"a1", "a2", "b1", "c1"} {
+				if err := s.Put([]byte(k), []byte("v"+k)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := keys(t, s, kv.PrefixRange([]byte("a"))); got != "a1,a2," {
+				t.Fatalf("got prefix keys %q, want %q", got, "a1,a2,")
+			}
+
+			it := s.NewIterator(nil)
+			defer it.Release()
+
+			if !it.Next() || string(it.Key()) != "a1" {
+				t.Fatalf("got key %q moving forward from a new iterator, want %q", it.Key(), "a1")
+			}
+			if !it.Seek([]byte("b")) || string(it.Key()) != "b1" || string(it.Value()) != "vb1" {
+				t.Fatalf("got key %q at seek, want %q", it.Key(), "b1")
+			}
+			if it.Seek([]byte("d")) {
+				t.Fatal("seek past the last key")
+			}
+			if it.Key() != nil {
+				t.Fatalf("got key %q for exhausted iterator", it.Key())
+			}
+			if !it.Prev() || string(it.Key()) != "c1" {
+				t.Fatalf("got key %q moving back from the end, want %q", it.Key(), "c1")
+			}
+			if !it.First() || it.Prev() {
+				t.Fatal("moved before the first key")
+			}
+			if !it.Next() || string(it.Key()) != "a1" {
+				t.Fatalf("got key %q moving forward from the start, want %q", it.Key(), "a1")
+			}
+			if !it.Last() || string(it.Key()) != "c1" {
+				t.Fatalf("got last key %q, want %q", it.Key(), "c1")
+			}
+			if err := it.Error(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestPrefixRange(t *testing.T) {
+	for _, tc := range []struct {
+		prefix []byte
+		limit  []byte
+	}{
+		{prefix: []byte{1, 2}, limit: []byte{1, 3}},
+		{prefix: []byte{1, 0xff}, limit: []byte{2}},
+		{prefix: []byte{0xff}, limit: nil},
+	} {
+		if r := kv.PrefixRange(tc.prefix); !bytes.Equal(r.Start, tc.prefix) || !bytes.Equal(r.Limit, tc.limit) {
+			t.Errorf("prefix %x: got range [%x, %x), want [%x, %x)", tc.prefix, r.Start, r.Limit, tc.prefix, tc.limit)
+		}
+	}
+}
+
+func TestOpenBackend(t *testing.T) {
+	dir := t.TempDir()
+
+	if b, err := kv.ReadBackend(dir); err != nil || b != "" {
+		t.Fatalf("got backend %q, error %v, want none", b, err)
+	}
+
+	s, err := kv.Open(dir, &kv.Options{Backend: kv.BackendPebble})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if b, err := kv.ReadBackend(dir); err != nil || b != kv.BackendPebble {
+		t.Fatalf("got backend %q, error %v, want %q", b, err, kv.BackendPebble)
+	}
+	if _, err := kv.Open(dir, &kv.Options{Backend: kv.BackendLevelDB}); !errors.Is(err, kv.ErrBackendMismatch) {
+		t.Fatalf("got error %v, want %v", err, kv.ErrBackendMismatch)
+	}
+	newStore(t, dir, "")
+
+	if _, err := kv.Open(t.TempDir(), &kv.Options{Backend: "unknown"}); !errors.Is(err, kv.ErrUnknownBackend) {
+		t.Fatalf("got error %v, want %v", err, kv.ErrUnknownBackend)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	other := filepath.Join(dir, "sharky", "shard")
+	if err := os.MkdirAll(filepath.Dir(other), 0775); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(other, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := kv.Open(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const n = 12345
+	for i := 0; i < n; i++ {
+		if err := s.Put([]byte(fmt.Sprintf("key-%05d", i)), []byte(fmt.Sprint(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := kv.Migrate(dir, kv.BackendLevelDB, nil); err == nil {
+		t.Fatal("expected error migrating to the same backend")
+	}
+
+	count, backup, err := kv.Migrate(dir, kv.BackendPebble, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != n {
+		t.Fatalf("got %d migrated keys, want %d", count, n)
+	}
+	if b, err := kv.ReadBackend(dir); err != nil || b != kv.BackendPebble {
+		t.Fatalf("got backend %q, error %v, want %q", b, err, kv.BackendPebble)
+	}
+	if b, err := kv.ReadBackend(backup); err != nil || b != kv.BackendLevelDB {
+		t.Fatalf("got backup backend %q, error %v, want %q", b, err, kv.BackendLevelDB)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Fatalf("other files not kept: %v", err)
+	}
+
+	s = newStore(t, dir, "")
+	for _, i := range []int{0, n / 2, n - 1} {
+		v, err := s.Get([]byte(fmt.Sprintf("key-%05d", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(v) != fmt.Sprint(i) {
+			t.Fatalf("got value %q, want %q", v, fmt.Sprint(i))
+		}
+	}
+}
+
+// keys returns the comma terminated keys in the range.
+func keys(t *testing.T, s kv.Store, r *kv.Range) string {
+	t.Helper()
+
+	it := s.NewIterator(r)
+	defer it.Release()
+
+	var b bytes.Buffer
+	for ok := it.First(); ok; ok = it.Next() {
+		b.Write(it.Key())
+		b.WriteByte(',')
+	}
+	if err := it.Error(); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
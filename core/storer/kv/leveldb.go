@@ -0,0 +1,145 @@
+package kv
+
+import (
+	"errors"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	ldberrors "github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+var _ Store = (*levelDB)(nil)
+
+// levelDB is the goleveldb backend.
+type levelDB struct {
+	db *leveldb.DB
+}
+
+func openLevelDB(path string, o *Options) (Store, error) {
+	var (
+		db  *leveldb.DB
+		err error
+	)
+	if path == "" {
+		db, err = leveldb.Open(storage.NewMemStorage(), nil)
+	} else {
+		db, err = leveldb.OpenFile(path, levelDBOptions(o))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &levelDB{db: db}, nil
+}
+
+func levelDBOptions(o *Options) *opt.Options {
+	return &opt.Options{
+		OpenFilesCacheCapacity: int(o.OpenFilesLimit),
+		BlockCacheCapacity:     int(o.BlockCacheCapacity),
+		WriteBuffer:            int(o.WriteBufferSize),
+		DisableSeeksCompaction: o.DisableSeeksCompaction,
+	}
+}
+
+// IsCorrupted reports whether the error returned by Open is caused by a
+// corrupted database which Recover may be able to repair.
+func IsCorrupted(err error) bool {
+	return ldberrors.IsCorrupted(err)
+}
+
+// Recover opens the corrupted goleveldb database in the directory
+// recovering what it can. Other backends are not supported.
+func Recover(path string, o *Options) (Store, error) {
+	if o == nil {
+		o = new(Options)
+	}
+	backend, err := ReadBackend(path)
+	if err != nil {
+		return nil, err
+	}
+	if backend != BackendLevelDB {
+		return nil, errors.New("kv: recovery is only supported by the leveldb backend")
+	}
+
+	db, err := leveldb.RecoverFile(path, levelDBOptions(o))
+	if err != nil {
+		return nil, err
+	}
+	return &levelDB{db: db}, nil
+}
+
+func (s *levelDB) Get(key []byte) ([]byte, error) {
+	v, err := s.db.Get(key, nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return v, err
+}
+
+func (s *levelDB) Has(key []byte) (bool, error) {
+	return s.db.Has(key, nil)
+}
+
+func (s *levelDB) Put(key, value []byte) error {
+	return s.db.Put(key, value, nil)
+}
+
+func (s *levelDB) Delete(key []byte) error {
+	return s.db.Delete(key, nil)
+}
+
+func (s *levelDB) Write(batch *Batch) error {
+	b := new(leveldb.Batch)
+	for _, op := range batch.ops {
+		if op.delete {
+			b.Delete(op.key)
+		} else {
+			b.Put(op.key, op.value)
+		}
+	}
+	return s.db.Write(b, nil)
+}
+
+func (s *levelDB) NewIterator(r *Range) Iterator {
+	if r == nil {
+		return s.db.NewIterator(nil, nil)
+	}
+	return s.db.NewIterator(&util.Range{Start: r.Start, Limit: r.Limit}, nil)
+}
+
+func (s *levelDB) NewSnapshot() (Snapshot, error) {
+	snapshot, err := s.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return levelDBSnapshot{snapshot: snapshot}, nil
+}
+
+func (s *levelDB) Compact(start, end []byte) error {
+	return s.db.CompactRange(util.Range{Start: start, Limit: end})
+}
+
+func (s *levelDB) Close() error {
+	return s.db.Close()
+}
+
+type levelDBSnapshot struct {
+	snapshot *leveldb.Snapshot
+}
+
+func (s levelDBSnapshot) Get(key []byte) ([]byte, error) {
+	v, err := s.snapshot.Get(key, nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return v, err
+}
+
+func (s levelDBSnapshot) Has(key []byte) (bool, error) {
+	return s.snapshot.Has(key, nil)
+}
+
+func (s levelDBSnapshot) Release() {
+	s.snapshot.Release()
+}
@@ -0,0 +1,139 @@
+package kv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// migrateBatchSize is the number of keys copied in a single batch.
+const migrateBatchSize = 10000
+
+// Migrate converts the database in the directory to the backend by copying
+// all its keys into a new database which then replaces the old one. The old
+// database files are moved to the returned backup directory. Other files and
+// subdirectories of the directory are left untouched.
+func Migrate(path, backend string, o *Options) (count int, backup string, err error) {
+	if !ValidBackend(backend) {
+		return 0, "", fmt.Errorf("%w: %q", ErrUnknownBackend, backend)
+	}
+	from, err := ReadBackend(path)
+	if err != nil {
+		return 0, "", err
+	}
+	if from == "" {
+		return 0, "", fmt.Errorf("no database in %s", path)
+	}
+	if from == backend {
+		return 0, "", fmt.Errorf("database in %s already uses %s", path, backend)
+	}
+
+	var opts Options
+	if o != nil {
+		opts = *o
+	}
+
+	tmp := path + ".migrate"
+	if err := os.RemoveAll(tmp); err != nil {
+		return 0, "", err
+	}
+
+	opts.Backend = from
+	src, err := Open(path, &opts)
+	if err != nil {
+		return 0, "", fmt.Errorf("open %s database: %w", from, err)
+	}
+	opts.Backend = backend
+	dst, err := Open(tmp, &opts)
+	if err != nil {
+		_ = src.Close()
+		return 0, "", fmt.Errorf("open %s database: %w", backend, err)
+	}
+
+	count, err = copyStore(dst, src)
+	if err != nil {
+		_ = src.Close()
+		_ = dst.Close()
+		_ = os.RemoveAll(tmp)
+		return 0, "", fmt.Errorf("copy: %w", err)
+	}
+	if err := src.Close(); err != nil {
+		_ = dst.Close()
+		return 0, "", fmt.Errorf("close %s database: %w", from, err)
+	}
+	if err := dst.Close(); err != nil {
+		return 0, "", fmt.Errorf("close %s database: %w", backend, err)
+	}
+
+	backup = path + "." + from + ".bak"
+	if err := os.RemoveAll(backup); err != nil {
+		return 0, "", err
+	}
+	if err := os.Mkdir(backup, 0775); err != nil {
+		return 0, "", err
+	}
+	if err := moveDatabaseFiles(path, backup); err != nil {
+		return 0, "", fmt.Errorf("back up %s database: %w", from, err)
+	}
+	if err := moveDatabaseFiles(tmp, path); err != nil {
+		return 0, "", fmt.Errorf("move %s database: %w", backend, err)
+	}
+	if err := writeBackend(path, backend); err != nil {
+		return 0, "", err
+	}
+	if err := writeBackend(backup, from); err != nil {
+		return 0, "", err
+	}
+	return count, backup, os.RemoveAll(tmp)
+}
+
+func copyStore(dst, src Store) (count int, err error) {
+	it := src.NewIterator(nil)
+	defer it.Release()
+
+	batch := new(Batch)
+	for ok := it.First(); ok; ok = it.Next() {
+		batch.Put(it.Key(), it.Value())
+		count++
+		if batch.Len() == migrateBatchSize {
+			if err := dst.Write(batch); err != nil {
+				return 0, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return 0, err
+	}
+	if batch.Len() > 0 {
+		if err := dst.Write(batch); err != nil {
+			return 0, err
+		}
+	}
+	return count, nil
+}
+
+// moveDatabaseFiles moves the files of the database in the src directory to
+// the dst directory. Database files are the regular files of the directory
+// except hidden ones and the backend file.
+func moveDatabaseFiles(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") || name == backendFile {
+			continue
+		}
+		if !e.Type().IsRegular() {
+			return errors.New("unexpected file " + filepath.Join(src, name))
+		}
+		if err := os.Rename(filepath.Join(src, name), filepath.Join(dst, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
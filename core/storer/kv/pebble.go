@@ -0,0 +1,228 @@
+package kv
+
+import (
+	"errors"
+	"io"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+)
+
+var _ Store = (*pebbleDB)(nil)
+
+// pebbleDB is the pebble backend. Writes are not synced, like
+// the writes of the goleveldb backend.
+type pebbleDB struct {
+	db *pebble.DB
+}
+
+func openPebble(path string, o *Options) (Store, error) {
+	po := &pebble.Options{
+		MaxOpenFiles: int(o.OpenFilesLimit),
+		MemTableSize: int(o.WriteBufferSize),
+	}
+	if path == "" {
+		po.FS = vfs.NewMem()
+	}
+	if o.BlockCacheCapacity > 0 {
+		cache := pebble.NewCache(int64(o.BlockCacheCapacity))
+		defer cache.Unref()
+		po.Cache = cache
+	}
+
+	db, err := pebble.Open(path, po)
+	if err != nil {
+		return nil, err
+	}
+	return &pebbleDB{db: db}, nil
+}
+
+func (s *pebbleDB) Get(key []byte) ([]byte, error) {
+	return pebbleGet(s.db, key)
+}
+
+func (s *pebbleDB) Has(key []byte) (bool, error) {
+	return pebbleHas(s.db, key)
+}
+
+func (s *pebbleDB) Put(key, value []byte) error {
+	return s.db.Set(key, value, pebble.NoSync)
+}
+
+func (s *pebbleDB) Delete(key []byte) error {
+	return s.db.Delete(key, pebble.NoSync)
+}
+
+func (s *pebbleDB) Write(batch *Batch) error {
+	b := s.db.NewBatch()
+	defer b.Close()
+
+	for _, op := range batch.ops {
+		var err error
+		if op.delete {
+			err = b.Delete(op.key, nil)
+		} else {
+			err = b.Set(op.key, op.value, nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return b.Commit(pebble.NoSync)
+}
+
+func (s *pebbleDB) NewIterator(r *Range) Iterator {
+	o := new(pebble.IterOptions)
+	if r != nil {
+		o.LowerBound, o.UpperBound = r.Start, r.Limit
+	}
+	// a new iterator is positioned before the first key.
+	return &pebbleIterator{it: s.db.NewIter(o), exhausted: -1}
+}
+
+func (s *pebbleDB) NewSnapshot() (Snapshot, error) {
+	return pebbleSnapshot{snapshot: s.db.NewSnapshot()}, nil
+}
+
+func (s *pebbleDB) Compact(start, end []byte) error {
+	if end == nil {
+		// pebble needs an upper bound, use the successor of the last key.
+		it := s.db.NewIter(nil)
+		if it.Last() {
+			end = append(append([]byte(nil), it.Key()...), 0)
+		}
+		if err := it.Close(); err != nil {
+			return err
+		}
+		if end == nil {
+			return nil
+		}
+	}
+	if start == nil {
+		start = []byte{}
+	}
+	return s.db.Compact(start, end)
+}
+
+func (s *pebbleDB) Close() error {
+	return s.db.Close()
+}
+
+type pebbleReader interface {
+	Get(key []byte) ([]byte, io.Closer, error)
+}
+
+func pebbleGet(r pebbleReader, key []byte) ([]byte, error) {
+	v, closer, err := r.Get(key)
+	if err != nil {
+		if errors.Is(err, pebble.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	defer closer.Close()
+
+	return append([]byte(nil), v...), nil
+}
+
+func pebbleHas(r pebbleReader, key []byte) (bool, error) {
+	_, closer, err := r.Get(key)
+	if err != nil {
+		if errors.Is(err, pebble.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, closer.Close()
+}
+
+type pebbleSnapshot struct {
+	snapshot *pebble.Snapshot
+}
+
+func (s pebbleSnapshot) Get(key []byte) ([]byte, error) {
+	return pebbleGet(s.snapshot, key)
+}
+
+func (s pebbleSnapshot) Has(key []byte) (bool, error) {
+	return pebbleHas(s.snapshot, key)
+}
+
+func (s pebbleSnapshot) Release() {
+	_ = s.snapshot.Close()
+}
+
+// pebbleIterator adapts the pebble iterator to the goleveldb iterator
+// semantics of moving back from the end and forward from the start.
+type pebbleIterator struct {
+	it *pebble.Iterator
+	// exhausted is positive if the iterator moved past the last key,
+	// negative if it moved before the first one.
+	exhausted int
+	released  bool
+	err       error
+}
+
+func (i *pebbleIterator) position(ok bool, direction int) bool {
+	if ok {
+		i.exhausted = 0
+	} else {
+		i.exhausted = direction
+	}
+	return ok
+}
+
+func (i *pebbleIterator) First() bool {
+	return i.position(i.it.First(), 0)
+}
+
+func (i *pebbleIterator) Last() bool {
+	return i.position(i.it.Last(), 0)
+}
+
+func (i *pebbleIterator) Seek(key []byte) bool {
+	return i.position(i.it.SeekGE(key), 1)
+}
+
+func (i *pebbleIterator) Next() bool {
+	if i.exhausted < 0 {
+		return i.First()
+	}
+	return i.position(i.it.Next(), 1)
+}
+
+func (i *pebbleIterator) Prev() bool {
+	if i.exhausted > 0 {
+		return i.Last()
+	}
+	return i.position(i.it.Prev(), -1)
+}
+
+func (i *pebbleIterator) Key() []byte {
+	if !i.it.Valid() {
+		return nil
+	}
+	return i.it.Key()
+}
+
+func (i *pebbleIterator) Value() []byte {
+	if !i.it.Valid() {
+		return nil
+	}
+	return i.it.Value()
+}
+
+func (i *pebbleIterator) Release() {
+	if i.released {
+		return
+	}
+	i.released = true
+	i.err = i.it.Close()
+}
+
+func (i *pebbleIterator) Error() error {
+	if i.released {
+		return i.err
+	}
+	return i.it.Error()
+}
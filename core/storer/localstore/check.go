@@ -0,0 +1,549 @@
+package localstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redesblock/mop/core/chunk/cac"
+	"github.com/redesblock/mop/core/chunk/soc"
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/storer/kv"
+	"github.com/redesblock/mop/core/storer/sharky"
+	"github.com/redesblock/mop/core/storer/shed"
+	"github.com/redesblock/mop/core/storer/storage"
+)
+
+// RefetchFunc retrieves a chunk from the network. It is used to restore
+// the pinned chunks removed by a repairing consistency check.
+type RefetchFunc func(ctx context.Context, addr cluster.Address) (cluster.Chunk, error)
+
+// CheckOptions are the options of a consistency check.
+type CheckOptions struct {
+	// Repair removes the invalid chunks and the dangling index entries
+	// and restores the missing index entries.
+	Repair bool
+	// Rate is the maximum number of chunks verified per second,
+	// unlimited if zero.
+	Rate int
+}
+
+// CheckReport is the result of a consistency check.
+type CheckReport struct {
+	// Chunks is the number of verified chunks.
+	Chunks int `json:"chunks"`
+	// Skipped is the number of chunks in the cold store which were
+	// not verified as no cold store is configured.
+	Skipped int `json:"skipped"`
+	// Invalid are the chunks which data does not match their address.
+	Invalid []cluster.Address `json:"invalid"`
+	// Unreadable are the chunks which data can not be read.
+	Unreadable []cluster.Address `json:"unreadable"`
+	// Missing is the number of chunks without an entry, by index name.
+	Missing map[string]int `json:"missing"`
+	// Dangling is the number of entries of chunks which are not
+	// stored, by index name.
+	Dangling map[string]int `json:"dangling"`
+	// Repaired is the number of repaired chunks and index entries.
+	Repaired int `json:"repaired"`
+	// Refetched is the number of removed pinned chunks which were
+	// retrieved again.
+	Refetched int `json:"refetched"`
+}
+
+// Consistent reports whether the check found no problems.
+func (r *CheckReport) Consistent() bool {
+	return len(r.Invalid) == 0 && len(r.Unreadable) == 0 && len(r.Missing) == 0 && len(r.Dangling) == 0
+}
+
+// SetRefetchFunc sets the function used to retrieve again the pinned
+// chunks removed by a repairing consistency check.
+func (db *DB) SetRefetchFunc(f RefetchFunc) {
+	db.refetchMu.Lock()
+	defer db.refetchMu.Unlock()
+	db.refetch = f
+}
+
+// checkBatchSize is the number of chunks read from the retrieval data index
+// at once. The chunks are verified after the iterator is released, so that
+// a throttled check does not hold it open.
+var checkBatchSize = 1000
+
+// Check verifies the data of all chunks against their addresses and the
+// consistency of the indexes with the retrieval data index. With the
+// Repair option the found problems are fixed: invalid chunks are removed,
+// and retrieved again if they were pinned, dangling index entries are
+// deleted and missing ones are restored.
+func (db *DB) Check(ctx context.Context, o CheckOptions) (r *CheckReport, err error) {
+	db.metrics.CheckCounter.Inc()
+	defer func(start time.Time) {
+		if err != nil {
+			db.metrics.CheckErrorCounter.Inc()
+		}
+		totalTimeMetric(db.metrics.TotalTimeCheck, start)
+	}(time.Now())
+
+	r = &CheckReport{
+		Missing:  make(map[string]int),
+		Dangling: make(map[string]int),
+	}
+
+	var (
+		corrupt   []shed.Item
+		missing   = make(map[string][]shed.Item)
+		start     = time.Now()
+		startFrom *shed.Item
+	)
+	for {
+		var items []shed.Item
+		err = db.retrievalDataIndex.Iterate(func(item shed.Item) (stop bool, err error) {
+			items = append(items, item)
+			return len(items) == checkBatchSize, nil
+		}, &shed.IterateOptions{StartFrom: startFrom, SkipStartFromItem: startFrom != nil})
+		if err != nil {
+			return r, fmt.Errorf("iterate retrieval data index: %w", err)
+		}
+
+		for _, item := range items {
+			if err := ctx.Err(); err != nil {
+				return r, err
+			}
+
+			loc, err := sharky.LocationFromBinary(item.Location)
+			if err == nil && loc.Cold && db.cold == nil {
+				r.Skipped++
+				continue
+			}
+
+			r.Chunks++
+			valid := false
+			if err == nil {
+				valid, err = db.checkData(ctx, loc, item.Address)
+			}
+			if err != nil {
+				db.logger.Debug("check: unreadable chunk", "address", cluster.NewAddress(item.Address), "error", err)
+				r.Unreadable = append(r.Unreadable, cluster.NewAddress(item.Address))
+				corrupt = append(corrupt, item)
+			} else if !valid {
+				r.Invalid = append(r.Invalid, cluster.NewAddress(item.Address))
+				corrupt = append(corrupt, item)
+			}
+
+			names, expected, err := db.expectedIndexes(item)
+			if err != nil {
+				return r, err
+			}
+			for _, name := range names {
+				has, err := db.indexByName(name).Has(expected)
+				if err != nil {
+					return r, err
+				}
+				if !has {
+					missing[name] = append(missing[name], item)
+					r.Missing[name]++
+				}
+			}
+
+			if o.Rate > 0 {
+				wait := time.Until(start.Add(time.Duration(r.Chunks) * time.Second / time.Duration(o.Rate)))
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					return r, ctx.Err()
+				}
+			}
+		}
+
+		if len(items) < checkBatchSize {
+			break
+		}
+		startFrom = &shed.Item{Address: items[len(items)-1].Address}
+	}
+
+	dangling := make(map[string][]shed.Item)
+	for name, index := range map[string]shed.Index{
+		"retrievalAccessIndex": db.retrievalAccessIndex,
+		"pushIndex":            db.pushIndex,
+		"pullIndex":            db.pullIndex,
+		"gcIndex":              db.gcIndex,
+		"pinIndex":             db.pinIndex,
+		"voucherChunksIndex":   db.voucherChunksIndex,
+	} {
+		err := index.Iterate(func(item shed.Item) (stop bool, err error) {
+			if err := ctx.Err(); err != nil {
+				return true, err
+			}
+			isDangling, err := db.danglingEntry(name, item)
+			if err != nil {
+				return true, err
+			}
+			if isDangling {
+				dangling[name] = append(dangling[name], item)
+				r.Dangling[name]++
+			}
+			return false, nil
+		}, nil)
+		if err != nil {
+			return r, fmt.Errorf("iterate %s: %w", name, err)
+		}
+	}
+
+	db.metrics.CheckInvalidChunks.Add(float64(len(corrupt)))
+	for _, n := range r.Dangling {
+		db.metrics.CheckDanglingEntries.Add(float64(n))
+	}
+	for _, n := range r.Missing {
+		db.metrics.CheckMissingEntries.Add(float64(n))
+	}
+
+	if !o.Repair {
+		return r, nil
+	}
+
+	shared, err := db.sharedLocations(corrupt)
+	if err != nil {
+		return r, err
+	}
+	for _, item := range corrupt {
+		if err := db.repairChunk(ctx, item, !shared[string(item.Location)], r); err != nil {
+			return r, fmt.Errorf("repair chunk %x: %w", item.Address, err)
+		}
+	}
+	if err := db.repairEntries(dangling, missing, r); err != nil {
+		return r, err
+	}
+	db.metrics.CheckRepaired.Add(float64(r.Repaired))
+	return r, nil
+}
+
+// expectedIndexes returns the names of the indexes which should have an
+// entry of the stored chunk, along with the item completed with its access
+// timestamp. The uploaded chunks are pulled until they are synced, the
+// synced ones are either pinned or accessed and cached for the garbage
+// collection, and the pinned ones within the storage radius of their batch
+// form the reserve and are pulled. A chunk without an access timestamp is
+// taken as accessed when it was stored.
+func (db *DB) expectedIndexes(item shed.Item) ([]string, shed.Item, error) {
+	names := []string{"voucherChunksIndex"}
+
+	pushing, err := db.pushIndex.Has(item)
+	if err != nil {
+		return nil, item, err
+	}
+	if pushing {
+		return append(names, "pullIndex"), item, nil
+	}
+
+	item.AccessTimestamp = item.StoreTimestamp
+	i, err := db.retrievalAccessIndex.Get(item)
+	switch {
+	case err == nil:
+		item.AccessTimestamp = i.AccessTimestamp
+	case !errors.Is(err, kv.ErrNotFound):
+		return nil, item, err
+	}
+
+	pinned, err := db.pinIndex.Has(item)
+	if err != nil {
+		return nil, item, err
+	}
+	if !pinned {
+		return append(names, "retrievalAccessIndex", "gcIndex"), item, nil
+	}
+
+	item.Radius = 0
+	radius, err := db.voucherRadiusIndex.Get(shed.Item{BatchID: item.BatchID})
+	switch {
+	case err == nil:
+		item.Radius = radius.Radius
+	case !errors.Is(err, kv.ErrNotFound):
+		return nil, item, err
+	}
+	if withinRadiusFn(db, item) {
+		names = append(names, "pullIndex")
+	}
+	return names, item, nil
+}
+
+// danglingEntry reports whether the index entry belongs to a chunk which is
+// not stored. The garbage collection index entries are dangling as well if
+// they do not match the access timestamp of the chunk, as the chunk is then
+// collected in a wrong order or not at all.
+func (db *DB) danglingEntry(name string, item shed.Item) (bool, error) {
+	has, err := db.retrievalDataIndex.Has(item)
+	if err != nil {
+		return false, err
+	}
+	if !has {
+		return true, nil
+	}
+	if name != "gcIndex" {
+		return false, nil
+	}
+	i, err := db.retrievalAccessIndex.Get(item)
+	switch {
+	case errors.Is(err, kv.ErrNotFound):
+		return true, nil
+	case err != nil:
+		return false, err
+	}
+	return i.AccessTimestamp != item.AccessTimestamp, nil
+}
+
+// checkData reports whether the stored data of the chunk matches its address.
+func (db *DB) checkData(ctx context.Context, loc sharky.Location, addr []byte) (bool, error) {
+	data := make([]byte, loc.Length)
+	if err := db.readBlob(ctx, loc, data); err != nil {
+		return false, err
+	}
+	ch := cluster.NewChunk(cluster.NewAddress(addr), data)
+	return cac.Valid(ch) || soc.Valid(ch), nil
+}
+
+// sharedLocations returns the locations of the corrupt chunks which are
+// referenced by more than one retrieval data index entry.
+func (db *DB) sharedLocations(corrupt []shed.Item) (map[string]bool, error) {
+	if len(corrupt) == 0 {
+		return nil, nil
+	}
+	refs := make(map[string]int, len(corrupt))
+	for _, item := range corrupt {
+		refs[string(item.Location)] = 0
+	}
+	err := db.retrievalDataIndex.Iterate(func(item shed.Item) (stop bool, err error) {
+		if n, ok := refs[string(item.Location)]; ok {
+			refs[string(item.Location)] = n + 1
+		}
+		return false, nil
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("iterate retrieval data index: %w", err)
+	}
+	shared := make(map[string]bool)
+	for loc, n := range refs {
+		if n > 1 {
+			shared[loc] = true
+		}
+	}
+	return shared, nil
+}
+
+// repairChunk removes the corrupt chunk and retrieves it again if it was
+// pinned. The location of the chunk is released if it is not shared with
+// another chunk.
+func (db *DB) repairChunk(ctx context.Context, item shed.Item, release bool, r *CheckReport) error {
+	addr := cluster.NewAddress(item.Address)
+
+	var pins uint64
+	i, err := db.pinIndex.Get(item)
+	switch {
+	case err == nil:
+		pins = i.PinCounter
+	case !errors.Is(err, kv.ErrNotFound):
+		return err
+	}
+
+	if err := db.removeCorrupt(ctx, item, release); err != nil {
+		return err
+	}
+	r.Repaired++
+
+	db.refetchMu.Lock()
+	refetch := db.refetch
+	db.refetchMu.Unlock()
+	if pins == 0 || refetch == nil {
+		return nil
+	}
+
+	ch, err := refetch(ctx, addr)
+	if err != nil {
+		db.logger.Warning("check: refetch pinned chunk failed", "address", addr, "error", err)
+		return nil
+	}
+	if !cac.Valid(ch) && !soc.Valid(ch) {
+		db.logger.Warning("check: refetched pinned chunk is invalid", "address", addr)
+		return nil
+	}
+	if _, err := db.Put(ctx, storage.ModePutRequestPin, ch); err != nil {
+		return err
+	}
+	for ; pins > 1; pins-- {
+		if err := db.Set(ctx, storage.ModeSetPin, addr); err != nil {
+			return err
+		}
+	}
+	r.Refetched++
+	return nil
+}
+
+// removeCorrupt deletes the index entries of the chunk and releases its
+// location if release is set.
+func (db *DB) removeCorrupt(ctx context.Context, item shed.Item, release bool) error {
+	db.batchMu.Lock()
+	defer db.batchMu.Unlock()
+	if db.gcRunning {
+		db.dirtyAddresses = append(db.dirtyAddresses, cluster.NewAddress(item.Address))
+	}
+
+	batch := new(kv.Batch)
+	gcSizeChange, err := db.setRemove(batch, item, true)
+	if err != nil {
+		return err
+	}
+	if err := db.incGCSizeInBatch(batch, gcSizeChange); err != nil {
+		return err
+	}
+	// free the stamp index so that the chunk can be stored again
+	stamped, err := db.voucherIndexIndex.Get(item)
+	switch {
+	case err == nil:
+		if bytes.Equal(stamped.Address, item.Address) {
+			if err := db.voucherIndexIndex.DeleteInBatch(batch, item); err != nil {
+				return err
+			}
+		}
+	case !errors.Is(err, kv.ErrNotFound):
+		return err
+	}
+	if err := db.shed.WriteBatch(batch); err != nil {
+		return err
+	}
+
+	if !release {
+		return nil
+	}
+	loc, err := sharky.LocationFromBinary(item.Location)
+	if err != nil {
+		// the location of an unreadable chunk may be invalid
+		return nil
+	}
+	if err := db.releaseBlob(ctx, loc); err != nil {
+		db.logger.Warning("check: failed releasing location", "address", cluster.NewAddress(item.Address), "location", loc, "error", err)
+	}
+	return nil
+}
+
+// repairEntries deletes the dangling index entries and restores the missing
+// ones, checking again the chunks as they may have changed in the meantime.
+func (db *DB) repairEntries(dangling, missing map[string][]shed.Item, r *CheckReport) error {
+	db.batchMu.Lock()
+	defer db.batchMu.Unlock()
+
+	batch := new(kv.Batch)
+	var gcSizeChange int64
+	for name, items := range dangling {
+		index := db.indexByName(name)
+		for _, item := range items {
+			isDangling, err := db.danglingEntry(name, item)
+			if err != nil {
+				return err
+			}
+			if !isDangling {
+				continue
+			}
+			if err := index.DeleteInBatch(batch, item); err != nil {
+				return err
+			}
+			if name == "gcIndex" {
+				gcSizeChange--
+			}
+			r.Repaired++
+		}
+	}
+	for name, items := range missing {
+		index := db.indexByName(name)
+		for _, item := range items {
+			i, err := db.retrievalDataIndex.Get(item)
+			if err != nil {
+				if errors.Is(err, kv.ErrNotFound) {
+					continue
+				}
+				return err
+			}
+			names, expected, err := db.expectedIndexes(i)
+			if err != nil {
+				return err
+			}
+			if !containsName(names, name) {
+				continue
+			}
+			has, err := index.Has(expected)
+			if err != nil {
+				return err
+			}
+			if has {
+				continue
+			}
+			if err := index.PutInBatch(batch, expected); err != nil {
+				return err
+			}
+			if name == "gcIndex" {
+				gcSizeChange++
+			}
+			r.Repaired++
+		}
+	}
+	if err := db.incGCSizeInBatch(batch, gcSizeChange); err != nil {
+		return err
+	}
+	return db.shed.WriteBatch(batch)
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (db *DB) indexByName(name string) shed.Index {
+	switch name {
+	case "retrievalAccessIndex":
+		return db.retrievalAccessIndex
+	case "pushIndex":
+		return db.pushIndex
+	case "pullIndex":
+		return db.pullIndex
+	case "gcIndex":
+		return db.gcIndex
+	case "pinIndex":
+		return db.pinIndex
+	case "voucherChunksIndex":
+		return db.voucherChunksIndex
+	}
+	panic("unknown index " + name)
+}
+
+// verifyWorker periodically runs a throttled repairing consistency check.
+func (db *DB) verifyWorker() {
+	defer close(db.verifyWorkerDone)
+
+	if db.verifyInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(db.verifyInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r, err := db.Check(db.ctx, CheckOptions{Repair: true, Rate: db.verifyRate})
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					db.logger.Error(err, "consistency check failed")
+				}
+				continue
+			}
+			if !r.Consistent() {
+				db.logger.Warning("consistency check repaired the database", "chunks", r.Chunks, "invalid", len(r.Invalid), "unreadable", len(r.Unreadable), "missing", r.Missing, "dangling", r.Dangling, "repaired", r.Repaired, "refetched", r.Refetched)
+			}
+		case <-db.close:
+			return
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package localstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/storer/coldstore"
+	"github.com/redesblock/mop/core/storer/kv"
+	"github.com/redesblock/mop/core/storer/sharky"
+	"github.com/redesblock/mop/core/storer/storage"
+)
+
+// TestCheck corrupts a chunk and the indexes and checks that the
+// consistency check finds and repairs the problems.
+func TestCheck(t *testing.T) {
+	db := newTestDB(t, nil)
+	ctx := context.Background()
+
+	chunks := []cluster.Chunk{generateTestRandomChunk(), generateTestRandomChunk(), generateTestRandomChunk()}
+	if _, err := db.Put(ctx, storage.ModePutUpload, chunks...); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set(ctx, storage.ModeSetPin, chunks[0].Address()); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := db.Check(ctx, CheckOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Chunks != len(chunks) || !r.Consistent() {
+		t.Fatalf("got report %+v, want %d consistent chunks", r, len(chunks))
+	}
+
+	// point the pinned chunk to the data of another chunk
+	corrupt, err := db.retrievalDataIndex.Get(addressToItem(chunks[0].Address()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := db.retrievalDataIndex.Get(addressToItem(chunks[1].Address()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupt.Location = other.Location
+	if err := db.retrievalDataIndex.Put(corrupt); err != nil {
+		t.Fatal(err)
+	}
+	// lose the pull index entry of a chunk
+	missing, err := db.retrievalDataIndex.Get(addressToItem(chunks[2].Address()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.pullIndex.Delete(missing); err != nil {
+		t.Fatal(err)
+	}
+	// leave a push index entry of a chunk which is not stored
+	dangling := addressToItem(generateTestRandomChunk().Address())
+	dangling.StoreTimestamp = now()
+	if err := db.pushIndex.Put(dangling); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err = db.Check(ctx, CheckOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Invalid) != 1 || !r.Invalid[0].Equal(chunks[0].Address()) {
+		t.Fatalf("got invalid chunks %v, want %s", r.Invalid, chunks[0].Address())
+	}
+	if r.Missing["pullIndex"] != 1 || r.Dangling["pushIndex"] != 1 {
+		t.Fatalf("got missing %v, dangling %v, want a missing pull index and a dangling push index entry", r.Missing, r.Dangling)
+	}
+	if r.Repaired != 0 {
+		t.Fatalf("got %d repaired without repair", r.Repaired)
+	}
+
+	var refetched []cluster.Address
+	db.SetRefetchFunc(func(_ context.Context, addr cluster.Address) (cluster.Chunk, error) {
+		refetched = append(refetched, addr)
+		return chunks[0], nil
+	})
+
+	r, err = db.Check(ctx, CheckOptions{Repair: true, Rate: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Repaired != 3 || r.Refetched != 1 {
+		t.Fatalf("got %d repaired and %d refetched, want %d and %d", r.Repaired, r.Refetched, 3, 1)
+	}
+	if len(refetched) != 1 || !refetched[0].Equal(chunks[0].Address()) {
+		t.Fatalf("got refetched %v, want %s", refetched, chunks[0].Address())
+	}
+
+	r, err = db.Check(ctx, CheckOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Chunks != len(chunks) || !r.Consistent() {
+		t.Fatalf("got report %+v after repair, want %d consistent chunks", r, len(chunks))
+	}
+	if _, err := db.pinIndex.Get(addressToItem(chunks[0].Address())); err != nil {
+		t.Fatalf("refetched chunk is not pinned: %v", err)
+	}
+	if _, err := db.pushIndex.Get(dangling); !errors.Is(err, kv.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, kv.ErrNotFound)
+	}
+	for _, ch := range chunks {
+		if _, err := db.Get(ctx, storage.ModeGetRequest, ch.Address()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestCheckCache checks that the cached chunks, which are not in the pull
+// index, are consistent and are not added to the pull index by a repair.
+func TestCheckCache(t *testing.T) {
+	db := newTestDB(t, nil)
+	ctx := context.Background()
+
+	ch := generateTestRandomChunk()
+	if _, err := db.Put(ctx, storage.ModePutRequestCache, ch); err != nil {
+		t.Fatal(err)
+	}
+	item, err := db.retrievalDataIndex.Get(addressToItem(ch.Address()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if has, err := db.pullIndex.Has(item); err != nil || has {
+		t.Fatalf("got pull index entry %v (error %v) of a cached chunk", has, err)
+	}
+
+	r, err := db.Check(ctx, CheckOptions{Repair: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Chunks != 1 || !r.Consistent() || r.Repaired != 0 {
+		t.Fatalf("got report %+v, want a consistent cached chunk", r)
+	}
+	if has, err := db.pullIndex.Has(item); err != nil || has {
+		t.Fatalf("got pull index entry %v (error %v) of a cached chunk after repair", has, err)
+	}
+}
+
+// TestCheckRelease checks that the location of a removed corrupt chunk is
+// released when no other chunk uses it.
+func TestCheckRelease(t *testing.T) {
+	cold, err := coldstore.NewDirStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := newTestDB(t, &Options{ColdStore: cold, ColdAfter: time.Hour})
+	ctx := context.Background()
+
+	ch := generateTestRandomChunk()
+	if _, err := db.Put(ctx, storage.ModePutUpload, ch); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set(ctx, storage.ModeSetPin, ch.Address()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the released locations are observed through the deletions from the
+	// cold store
+	defer setNow(func() int64 {
+		return time.Now().Add(2 * time.Hour).UnixNano()
+	})()
+	if moved, err := db.moveToCold(ctx, coldMoveLimit); err != nil || moved != 1 {
+		t.Fatalf("got %d moved chunks (error %v), want %d", moved, err, 1)
+	}
+	item, err := db.retrievalDataIndex.Get(addressToItem(ch.Address()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc, err := sharky.LocationFromBinary(item.Location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cold.Put(ctx, coldKey(loc), make([]byte, loc.Length)); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted := make(chan string, 1)
+	t.Cleanup(setTestHookColdDelete(func(key string) {
+		deleted <- key
+	}))
+
+	r, err := db.Check(ctx, CheckOptions{Repair: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Invalid) != 1 || r.Repaired != 1 {
+		t.Fatalf("got report %+v, want one repaired invalid chunk", r)
+	}
+	select {
+	case key := <-deleted:
+		if key != coldKey(loc) {
+			t.Fatalf("got deleted key %s, want %s", key, coldKey(loc))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("location of the corrupt chunk not released")
+	}
+}
+
+// TestCheckBatches checks that all chunks are verified when they are read
+// from the retrieval data index in more than one batch.
+func TestCheckBatches(t *testing.T) {
+	defer func(size int) { checkBatchSize = size }(checkBatchSize)
+	checkBatchSize = 2
+
+	db := newTestDB(t, nil)
+	ctx := context.Background()
+
+	chunks := make([]cluster.Chunk, 5)
+	for i := range chunks {
+		chunks[i] = generateTestRandomChunk()
+	}
+	if _, err := db.Put(ctx, storage.ModePutUpload, chunks...); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := db.Check(ctx, CheckOptions{Rate: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Chunks != len(chunks) || !r.Consistent() {
+		t.Fatalf("got report %+v, want %d consistent chunks", r, len(chunks))
+	}
+}
+
+// TestCheckGC checks that the missing, stale and dangling garbage collection
+// entries are found and repaired.
+func TestCheckGC(t *testing.T) {
+	db := newTestDB(t, nil)
+	ctx := context.Background()
+
+	chunks := []cluster.Chunk{generateTestRandomChunk(), generateTestRandomChunk()}
+	if _, err := db.Put(ctx, storage.ModePutRequestCache, chunks...); err != nil {
+		t.Fatal(err)
+	}
+	gcSize, err := db.gcSize.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// lose the garbage collection index entry of a chunk
+	lost, err := db.retrievalDataIndex.Get(addressToItem(chunks[0].Address()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	access, err := db.retrievalAccessIndex.Get(lost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lost.AccessTimestamp = access.AccessTimestamp
+	if err := db.gcIndex.Delete(lost); err != nil {
+		t.Fatal(err)
+	}
+	// leave a stale garbage collection index entry of a chunk
+	stale, err := db.retrievalDataIndex.Get(addressToItem(chunks[1].Address()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.AccessTimestamp = 1
+	if err := db.gcIndex.Put(stale); err != nil {
+		t.Fatal(err)
+	}
+	// leave the entries of a chunk which is not stored
+	dangling := addressToItem(generateTestRandomChunk().Address())
+	dangling.AccessTimestamp = now()
+	if err := db.gcIndex.Put(dangling); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.retrievalAccessIndex.Put(dangling); err != nil {
+		t.Fatal(err)
+	}
+	// the size counts the entries as they were written
+	if err := db.gcSize.Put(gcSize + 1); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := db.Check(ctx, CheckOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Missing["gcIndex"] != 1 || r.Dangling["gcIndex"] != 2 || r.Dangling["retrievalAccessIndex"] != 1 {
+		t.Fatalf("got missing %v, dangling %v, want a missing and two dangling gc index entries and a dangling retrieval access index entry", r.Missing, r.Dangling)
+	}
+
+	r, err = db.Check(ctx, CheckOptions{Repair: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Repaired != 4 {
+		t.Fatalf("got %d repaired, want %d", r.Repaired, 4)
+	}
+
+	r, err = db.Check(ctx, CheckOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Chunks != len(chunks) || !r.Consistent() {
+		t.Fatalf("got report %+v after repair, want %d consistent chunks", r, len(chunks))
+	}
+	if has, err := db.gcIndex.Has(lost); err != nil || !has {
+		t.Fatalf("got gc index entry %v (error %v), want restored", has, err)
+	}
+	got, err := db.gcSize.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != gcSize {
+		t.Fatalf("got gc size %d, want %d", got, gcSize)
+	}
+}
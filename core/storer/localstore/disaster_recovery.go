@@ -73,6 +73,10 @@ func recovery(db *DB) (chan locOrErr, error) {
 			if err != nil {
 				return true, fmt.Errorf("location from binary: %w", err)
 			}
+			// cold locations are not kept by sharky
+			if loc.Cold {
+				return false, nil
+			}
 
 			usedLocations <- locOrErr{
 				loc: loc,
@@ -5,9 +5,9 @@ import (
 	"time"
 
 	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/storer/kv"
 	"github.com/redesblock/mop/core/storer/sharky"
 	"github.com/redesblock/mop/core/storer/shed"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 var (
@@ -84,7 +84,7 @@ func (db *DB) collectGarbage() (evicted uint64, done bool, err error) {
 		}
 		totalTimeMetric(db.metrics.TotalTimeCollectGarbage, start)
 	}(time.Now())
-	batch := new(leveldb.Batch)
+	batch := new(kv.Batch)
 	target := db.gcTarget()
 
 	// tell the localstore to start logging dirty addresses
@@ -226,7 +226,7 @@ func (db *DB) collectGarbage() (evicted uint64, done bool, err error) {
 	}
 
 	for _, loc := range locations {
-		err = db.sharky.Release(db.ctx, loc)
+		err = db.releaseBlob(db.ctx, loc)
 		if err != nil {
 			db.logger.Warning("failed releasing sharky location", "location", loc)
 		}
@@ -273,12 +273,12 @@ func (db *DB) triggerReserveEviction() {
 // incGCSizeInBatch changes gcSize field value
 // by change which can be negative. This function
 // must be called under batchMu lock.
-func (db *DB) incGCSizeInBatch(batch *leveldb.Batch, change int64) (err error) {
+func (db *DB) incGCSizeInBatch(batch *kv.Batch, change int64) (err error) {
 	if change == 0 {
 		return nil
 	}
 	gcSize, err := db.gcSize.Get()
-	if err != nil && !errors.Is(err, leveldb.ErrNotFound) {
+	if err != nil && !errors.Is(err, kv.ErrNotFound) {
 		return err
 	}
 
@@ -308,12 +308,12 @@ func (db *DB) incGCSizeInBatch(batch *leveldb.Batch, change int64) (err error) {
 // incReserveSizeInBatch changes reserveSize field value
 // by change which can be negative. This function
 // must be called under batchMu lock.
-func (db *DB) incReserveSizeInBatch(batch *leveldb.Batch, change int64) (err error) {
+func (db *DB) incReserveSizeInBatch(batch *kv.Batch, change int64) (err error) {
 	if change == 0 {
 		return nil
 	}
 	reserveSize, err := db.reserveSize.Get()
-	if err != nil && !errors.Is(err, leveldb.ErrNotFound) {
+	if err != nil && !errors.Is(err, kv.ErrNotFound) {
 		return err
 	}
 
@@ -12,9 +12,9 @@ import (
 	"github.com/redesblock/mop/core/cluster"
 	"github.com/redesblock/mop/core/incentives/voucher"
 	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/storer/kv"
 	"github.com/redesblock/mop/core/storer/shed"
 	"github.com/redesblock/mop/core/storer/storage"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 // TestDB_collectGarbageWorker tests garbage collection runs
@@ -248,7 +248,7 @@ func TestPinGC(t *testing.T) {
 	t.Run("first chunks after pinned chunks should be removed", func(t *testing.T) {
 		for i := pinChunksCount; i < (int(cacheCapacity) - int(gcTarget)); i++ {
 			_, err := db.Get(context.Background(), storage.ModeGetRequest, addrs[i])
-			if !errors.Is(err, leveldb.ErrNotFound) {
+			if !errors.Is(err, kv.ErrNotFound) {
 				t.Fatal(err)
 			}
 		}
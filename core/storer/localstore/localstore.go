@@ -20,12 +20,13 @@ import (
 	"github.com/redesblock/mop/core/incentives/voucher/batchstore"
 	"github.com/redesblock/mop/core/log"
 	"github.com/redesblock/mop/core/pins"
+	"github.com/redesblock/mop/core/storer/coldstore"
+	"github.com/redesblock/mop/core/storer/kv"
 	"github.com/redesblock/mop/core/storer/sharky"
 	"github.com/redesblock/mop/core/storer/shed"
 	"github.com/redesblock/mop/core/storer/storage"
 	"github.com/redesblock/mop/core/tags"
 	"github.com/spf13/afero"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 // loggerName is the tree path name of the logger for this package.
@@ -164,6 +165,9 @@ type DB struct {
 	// are done
 	collectGarbageWorkerDone  chan struct{}
 	reserveEvictionWorkerDone chan struct{}
+	coldTieringWorkerDone     chan struct{}
+	coldDeleteWorkerDone      chan struct{}
+	verifyWorkerDone          chan struct{}
 
 	// wait for all subscriptions to finish before closing
 	// underlaying leveldb to prevent possible panics from
@@ -174,6 +178,21 @@ type DB struct {
 	updateGCItemKeys   map[string]*shed.Item
 	updateGCItemKeysMu sync.Mutex
 
+	// cold is the secondary storage tier of rarely accessed chunks
+	cold      coldstore.Store
+	coldAfter time.Duration
+	coldSeq   shed.Uint64Field
+	// keys of the blobs to be deleted from the cold store
+	coldDeletes       []string
+	coldDeletesMu     sync.Mutex
+	coldDeleteTrigger chan struct{}
+
+	// background consistency checks
+	verifyInterval time.Duration
+	verifyRate     int
+	refetch        RefetchFunc
+	refetchMu      sync.Mutex
+
 	metrics metrics
 
 	logger log.Logger
@@ -203,6 +222,21 @@ type Options struct {
 	// DisableSeeksCompaction toggles the seek driven compactions feature on leveldb
 	// and is passed on to shed.
 	DisableSeeksCompaction bool
+	// Backend is the key-value store backend of a new database and is
+	// passed on to shed. Existing databases keep their backend.
+	Backend string
+	// ColdStore is the secondary tier where rarely accessed reserve and
+	// pinned chunks are moved to. Tiering is disabled if nil.
+	ColdStore coldstore.Store
+	// ColdAfter is the time since the last access after which a chunk
+	// is moved to the ColdStore.
+	ColdAfter time.Duration
+	// VerifyInterval is the time between two background consistency
+	// checks which repair the database. Disabled if zero.
+	VerifyInterval time.Duration
+	// VerifyRate is the maximum number of chunks verified per second
+	// by the background consistency checks, unlimited if zero.
+	VerifyRate int
 
 	// MetricsPrefix defines a prefix for metrics names.
 	MetricsPrefix string
@@ -298,6 +332,14 @@ func New(path string, baseKey []byte, ss storage.StateStorer, o *Options, logger
 		close:                     make(chan struct{}),
 		collectGarbageWorkerDone:  make(chan struct{}),
 		reserveEvictionWorkerDone: make(chan struct{}),
+		coldTieringWorkerDone:     make(chan struct{}),
+		coldDeleteWorkerDone:      make(chan struct{}),
+		coldDeleteTrigger:         make(chan struct{}, 1),
+		verifyWorkerDone:          make(chan struct{}),
+		verifyInterval:            o.VerifyInterval,
+		verifyRate:                o.VerifyRate,
+		cold:                      o.ColdStore,
+		coldAfter:                 o.ColdAfter,
 		metrics:                   newMetrics(),
 		updateGCItemKeys:          map[string]*shed.Item{},
 		logger:                    logger.WithName(loggerName).Register(),
@@ -331,6 +373,7 @@ func New(path string, baseKey []byte, ss storage.StateStorer, o *Options, logger
 		BlockCacheCapacity:     o.BlockCacheCapacity,
 		WriteBufferSize:        o.WriteBufferSize,
 		DisableSeeksCompaction: o.DisableSeeksCompaction,
+		Backend:                o.Backend,
 	}
 
 	if withinRadiusFn == nil {
@@ -375,7 +418,7 @@ func New(path string, baseKey []byte, ss storage.StateStorer, o *Options, logger
 		return nil, err
 	}
 	schemaName, err := db.schemaName.Get()
-	if err != nil && !errors.Is(err, leveldb.ErrNotFound) {
+	if err != nil && !errors.Is(err, kv.ErrNotFound) {
 		return nil, err
 	}
 	if schemaName == "" {
@@ -403,6 +446,12 @@ func New(path string, baseKey []byte, ss storage.StateStorer, o *Options, logger
 		return nil, err
 	}
 
+	// sequence of the blobs moved to the cold store
+	db.coldSeq, err = db.shed.NewUint64Field("cold-seq")
+	if err != nil {
+		return nil, err
+	}
+
 	// Index storing actual chunk address, data and bin id.
 	headerSize := 16 + voucher.StampSize
 	db.retrievalDataIndex, err = db.shed.NewIndex("Address->StoreTimestamp|BinID|BatchID|BatchIndex|Sig|Location", shed.IndexFuncs{
@@ -663,6 +712,9 @@ func New(path string, baseKey []byte, ss storage.StateStorer, o *Options, logger
 	// start garbage collection worker
 	go db.collectGarbageWorker()
 	go db.reserveEvictionWorker()
+	go db.coldTieringWorker()
+	go db.coldDeleteWorker()
+	go db.verifyWorker()
 	return db, nil
 }
 
@@ -687,6 +739,9 @@ func (db *DB) Close() error {
 		// return before closing the shed
 		<-db.collectGarbageWorkerDone
 		<-db.reserveEvictionWorkerDone
+		<-db.coldTieringWorkerDone
+		<-db.coldDeleteWorkerDone
+		<-db.verifyWorkerDone
 		close(done)
 	}()
 
@@ -15,11 +15,11 @@ import (
 	"github.com/redesblock/mop/core/cluster"
 	"github.com/redesblock/mop/core/incentives/voucher"
 	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/storer/kv"
 	"github.com/redesblock/mop/core/storer/sharky"
 	"github.com/redesblock/mop/core/storer/shed"
 	"github.com/redesblock/mop/core/storer/storage"
 	chunktesting "github.com/redesblock/mop/core/storer/storage/testing"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 func init() {
@@ -321,7 +321,7 @@ func newRetrieveIndexesTest(db *DB, chunk cluster.Chunk, storeTimestamp, accessT
 		validateData(t, db, item, chunk.Data())
 
 		// access index should not be set
-		wantErr := leveldb.ErrNotFound
+		wantErr := kv.ErrNotFound
 		_, err = db.retrievalAccessIndex.Get(addressToItem(chunk.Address()))
 		if !errors.Is(err, wantErr) {
 			t.Errorf("got error %v, want %v", err, wantErr)
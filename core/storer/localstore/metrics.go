@@ -54,6 +54,21 @@ type metrics struct {
 	SubscribePushIterationDone    prometheus.Counter
 	SubscribePushIterationFailure prometheus.Counter
 
+	ColdMoveCounter        prometheus.Counter
+	ColdMoveErrorCounter   prometheus.Counter
+	ColdMovedCounter       prometheus.Counter
+	ColdReadCounter        prometheus.Counter
+	ColdDeleteErrorCounter prometheus.Counter
+	TotalTimeColdMove      prometheus.Counter
+
+	CheckCounter         prometheus.Counter
+	CheckErrorCounter    prometheus.Counter
+	CheckInvalidChunks   prometheus.Counter
+	CheckMissingEntries  prometheus.Counter
+	CheckDanglingEntries prometheus.Counter
+	CheckRepaired        prometheus.Counter
+	TotalTimeCheck       prometheus.Counter
+
 	GCSize                  prometheus.Gauge
 	GCStoreTimeStamps       prometheus.Gauge
 	GCStoreAccessTimeStamps prometheus.Gauge
@@ -382,6 +397,84 @@ func newMetrics() metrics {
 			Name:      "evict_reserve_total_time",
 			Help:      "total time spent evicting from reserve",
 		}),
+		ColdMoveCounter: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "cold_move_count",
+			Help:      "number of times the cold tiering worker was invoked",
+		}),
+		ColdMoveErrorCounter: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "cold_move_err_count",
+			Help:      "number of times moving chunks to the cold store got an error",
+		}),
+		ColdMovedCounter: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "cold_moved_count",
+			Help:      "number of chunks moved to the cold store",
+		}),
+		ColdReadCounter: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "cold_read_count",
+			Help:      "number of chunks read from the cold store",
+		}),
+		ColdDeleteErrorCounter: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "cold_delete_err_count",
+			Help:      "number of times deleting a blob from the cold store got an error",
+		}),
+		TotalTimeColdMove: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "cold_move_total_time",
+			Help:      "total time spent moving chunks to the cold store",
+		}),
+		CheckCounter: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "check_count",
+			Help:      "number of consistency checks",
+		}),
+		CheckErrorCounter: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "check_err_count",
+			Help:      "number of times a consistency check got an error",
+		}),
+		CheckInvalidChunks: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "check_invalid_chunks",
+			Help:      "number of invalid or unreadable chunks found by consistency checks",
+		}),
+		CheckMissingEntries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "check_missing_entries",
+			Help:      "number of missing index entries found by consistency checks",
+		}),
+		CheckDanglingEntries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "check_dangling_entries",
+			Help:      "number of dangling index entries found by consistency checks",
+		}),
+		CheckRepaired: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "check_repaired",
+			Help:      "number of chunks and index entries repaired by consistency checks",
+		}),
+		TotalTimeCheck: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "check_total_time",
+			Help:      "total time spent checking the consistency of the database",
+		}),
 	}
 }
 
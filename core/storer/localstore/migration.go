@@ -4,8 +4,8 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/redesblock/mop/core/storer/kv"
 	"github.com/redesblock/mop/core/storer/shed"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 var errMissingCurrentSchema = errors.New("could not find current db schema")
@@ -96,7 +96,7 @@ func getMigrations(currentSchema, targetSchema string, allSchemeMigrations []mig
 func truncateIndex(db *DB, idx shed.Index) (n int, err error) {
 	const maxBatchSize = 10000
 
-	batch := new(leveldb.Batch)
+	batch := new(kv.Batch)
 	if err = idx.Iterate(func(item shed.Item) (stop bool, err error) {
 		if err = idx.DeleteInBatch(batch, item); err != nil {
 			return true, err
@@ -6,8 +6,8 @@ import (
 	"time"
 
 	"github.com/redesblock/mop/core/incentives/voucher"
+	"github.com/redesblock/mop/core/storer/kv"
 	"github.com/redesblock/mop/core/storer/shed"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 // DBSchemaBatchIndex is the mop schema identifier for dead-push.
@@ -17,7 +17,7 @@ const DBSchemaDeadPush = "dead-push"
 func migrateDeadPush(db *DB) error {
 	start := time.Now()
 	db.logger.Debug("removing dangling entries from push index")
-	batch := new(leveldb.Batch)
+	batch := new(kv.Batch)
 	count := 0
 	headerSize := 16 + voucher.StampSize
 	retrievalDataIndex, err := db.shed.NewIndex("Address->StoreTimestamp|BinID|BatchID|BatchIndex|Sig|Data", shed.IndexFuncs{
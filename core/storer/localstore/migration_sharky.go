@@ -8,9 +8,9 @@ import (
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/redesblock/mop/core/incentives/voucher"
+	"github.com/redesblock/mop/core/storer/kv"
 	"github.com/redesblock/mop/core/storer/sharky"
 	"github.com/redesblock/mop/core/storer/shed"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 // DBSchemaSharky is the mop schema identifier for sharky.
@@ -21,7 +21,7 @@ func migrateSharky(db *DB) error {
 	db.logger.Info("starting sharky migration; have patience, this might take a while...")
 	var (
 		start          = time.Now()
-		batch          = new(leveldb.Batch)
+		batch          = new(kv.Batch)
 		batchSize      = 10000
 		batchesCount   = 0
 		headerSize     = 16 + voucher.StampSize
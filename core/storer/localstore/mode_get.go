@@ -7,10 +7,10 @@ import (
 
 	"github.com/redesblock/mop/core/cluster"
 	"github.com/redesblock/mop/core/incentives/voucher"
+	"github.com/redesblock/mop/core/storer/kv"
 	"github.com/redesblock/mop/core/storer/sharky"
 	"github.com/redesblock/mop/core/storer/shed"
 	"github.com/redesblock/mop/core/storer/storage"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 // Get returns a chunk from the database. If the chunk is
@@ -30,7 +30,7 @@ func (db *DB) Get(ctx context.Context, mode storage.ModeGet, addr cluster.Addres
 
 	out, err := db.get(ctx, mode, addr)
 	if err != nil {
-		if errors.Is(err, leveldb.ErrNotFound) {
+		if errors.Is(err, kv.ErrNotFound) {
 			return nil, storage.ErrNotFound
 		}
 		return nil, err
@@ -66,7 +66,7 @@ func (db *DB) get(ctx context.Context, mode storage.ModeGet, addr cluster.Addres
 		}
 
 		out.Data = make([]byte, l.Length)
-		err = db.sharky.Read(ctx, l, out.Data)
+		err = db.readBlob(ctx, l, out.Data)
 		if err != nil {
 			return out, err
 		}
@@ -149,7 +149,7 @@ func (db *DB) updateGC(item shed.Item) (err error) {
 		db.dirtyAddresses = append(db.dirtyAddresses, cluster.NewAddress(item.Address))
 	}
 
-	batch := new(leveldb.Batch)
+	batch := new(kv.Batch)
 
 	// update accessTimeStamp in retrieve, gc
 
@@ -157,7 +157,7 @@ func (db *DB) updateGC(item shed.Item) (err error) {
 	switch {
 	case err == nil:
 		item.AccessTimestamp = i.AccessTimestamp
-	case errors.Is(err, leveldb.ErrNotFound):
+	case errors.Is(err, kv.ErrNotFound):
 		// no chunk accesses
 	default:
 		return err
@@ -182,7 +182,7 @@ func (db *DB) updateGC(item shed.Item) (err error) {
 		if err != nil {
 			return err
 		}
-	} else if !errors.Is(err, leveldb.ErrNotFound) {
+	} else if !errors.Is(err, kv.ErrNotFound) {
 		return err
 	}
 	// if the item is not in the gc we don't
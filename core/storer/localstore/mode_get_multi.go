@@ -9,9 +9,9 @@ import (
 
 	"github.com/redesblock/mop/core/cluster"
 	"github.com/redesblock/mop/core/incentives/voucher"
+	"github.com/redesblock/mop/core/storer/kv"
 	"github.com/redesblock/mop/core/storer/shed"
 	"github.com/redesblock/mop/core/storer/storage"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 // GetMulti returns chunks from the database. If one of the chunks is not found
@@ -32,7 +32,7 @@ func (db *DB) GetMulti(ctx context.Context, mode storage.ModeGet, addrs ...clust
 
 	out, err := db.getMulti(ctx, mode, addrs...)
 	if err != nil {
-		if errors.Is(err, leveldb.ErrNotFound) {
+		if errors.Is(err, kv.ErrNotFound) {
 			return nil, storage.ErrNotFound
 		}
 		return nil, err
@@ -65,7 +65,7 @@ func (db *DB) getMulti(ctx context.Context, mode storage.ModeGet, addrs ...clust
 		}
 
 		out[i].Data = make([]byte, l.Length)
-		err = db.sharky.Read(ctx, l, out[i].Data)
+		err = db.readBlob(ctx, l, out[i].Data)
 		if err != nil {
 			return nil, err
 		}
@@ -8,10 +8,10 @@ import (
 	"time"
 
 	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/storer/kv"
 	"github.com/redesblock/mop/core/storer/sharky"
 	"github.com/redesblock/mop/core/storer/shed"
 	"github.com/redesblock/mop/core/storer/storage"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 var (
@@ -72,7 +72,7 @@ func (db *DB) put(ctx context.Context, mode storage.ModePut, chs ...cluster.Chun
 		}
 	}
 
-	batch := new(leveldb.Batch)
+	batch := new(kv.Batch)
 
 	// variables that provide information for operations
 	// to be done after write batch function successfully executes
@@ -125,7 +125,7 @@ func (db *DB) put(ctx context.Context, mode storage.ModePut, chs ...cluster.Chun
 	defer func() {
 		if retErr != nil {
 			for _, l := range committedLocations {
-				err := db.sharky.Release(context.Background(), l)
+				err := db.releaseBlob(context.Background(), l)
 				if err != nil {
 					db.logger.Warning("failed releasing sharky location on error", "error", err)
 				}
@@ -216,7 +216,7 @@ func (db *DB) put(ctx context.Context, mode storage.ModePut, chs ...cluster.Chun
 	}
 
 	for _, v := range *releaseLocs {
-		err = db.sharky.Release(ctx, v)
+		err = db.releaseBlob(ctx, v)
 		if err != nil {
 			db.logger.Warning("failed releasing sharky location", "location", v)
 		}
@@ -256,7 +256,7 @@ func (db *DB) putSharky(ctx context.Context, item shed.Item) (loc sharky.Locatio
 func (db *DB) putRequest(
 	ctx context.Context,
 	loc *releaseLocations,
-	batch *leveldb.Batch,
+	batch *kv.Batch,
 	binIDs map[uint8]uint64,
 	item shed.Item,
 	forcePin, forceCache bool,
@@ -264,7 +264,7 @@ func (db *DB) putRequest(
 
 	previous, err := db.voucherIndexIndex.Get(item)
 	if err != nil {
-		if !errors.Is(err, leveldb.ErrNotFound) {
+		if !errors.Is(err, kv.ErrNotFound) {
 			return false, 0, 0, err
 		}
 	} else {
@@ -294,7 +294,7 @@ func (db *DB) putRequest(
 
 		radius, err := db.voucherRadiusIndex.Get(item)
 		if err != nil {
-			if !errors.Is(err, leveldb.ErrNotFound) {
+			if !errors.Is(err, kv.ErrNotFound) {
 				return false, 0, 0, err
 			}
 		} else {
@@ -350,7 +350,7 @@ func (db *DB) putRequest(
 // The batch can be written to the database.
 // Provided batch and binID map are updated.
 func (db *DB) putUpload(
-	batch *leveldb.Batch,
+	batch *kv.Batch,
 	loc *releaseLocations,
 	binIDs map[uint8]uint64,
 	item shed.Item,
@@ -358,7 +358,7 @@ func (db *DB) putUpload(
 
 	previous, err := db.voucherIndexIndex.Get(item)
 	if err != nil {
-		if !errors.Is(err, leveldb.ErrNotFound) {
+		if !errors.Is(err, kv.ErrNotFound) {
 			return false, 0, fmt.Errorf("voucher index get: %w", err)
 		}
 	} else {
@@ -421,10 +421,10 @@ func (db *DB) putUpload(
 //
 // The batch can be written to the database.
 // Provided batch and binID map are updated.
-func (db *DB) putSync(batch *leveldb.Batch, loc *releaseLocations, binIDs map[uint8]uint64, item shed.Item) (exists bool, gcSizeChange, reserveSizeChange int64, err error) {
+func (db *DB) putSync(batch *kv.Batch, loc *releaseLocations, binIDs map[uint8]uint64, item shed.Item) (exists bool, gcSizeChange, reserveSizeChange int64, err error) {
 	previous, err := db.voucherIndexIndex.Get(item)
 	if err != nil {
-		if !errors.Is(err, leveldb.ErrNotFound) {
+		if !errors.Is(err, kv.ErrNotFound) {
 			return false, 0, 0, err
 		}
 	} else {
@@ -455,7 +455,7 @@ func (db *DB) putSync(batch *leveldb.Batch, loc *releaseLocations, binIDs map[ui
 
 		radius, err := db.voucherRadiusIndex.Get(item)
 		if err != nil {
-			if !errors.Is(err, leveldb.ErrNotFound) {
+			if !errors.Is(err, kv.ErrNotFound) {
 				return false, 0, 0, err
 			}
 		} else {
@@ -502,7 +502,7 @@ func (db *DB) putSync(batch *leveldb.Batch, loc *releaseLocations, binIDs map[ui
 
 // preserveOrCache is a helper function used to add chunks to either a pinned reserve or gc cache
 // (the retrieval access index and the gc index)
-func (db *DB) preserveOrCache(batch *leveldb.Batch, item shed.Item, forcePin, forceCache bool) (gcSizeChange, reserveSizeChange int64, err error) {
+func (db *DB) preserveOrCache(batch *kv.Batch, item shed.Item, forcePin, forceCache bool) (gcSizeChange, reserveSizeChange int64, err error) {
 	if !forceCache && (withinRadiusFn(db, item) || forcePin) {
 		if !forcePin {
 			reserveSizeChange++
@@ -520,7 +520,7 @@ func (db *DB) preserveOrCache(batch *leveldb.Batch, item shed.Item, forcePin, fo
 		return gcSizeChange, 0, nil
 	}
 	exists, err := db.gcIndex.Has(item)
-	if err != nil && !errors.Is(err, leveldb.ErrNotFound) {
+	if err != nil && !errors.Is(err, kv.ErrNotFound) {
 		return 0, 0, err
 	}
 	if exists {
@@ -13,10 +13,10 @@ import (
 	"github.com/redesblock/mop/core/cluster"
 	"github.com/redesblock/mop/core/incentives/voucher"
 	vouchertesting "github.com/redesblock/mop/core/incentives/voucher/testing"
+	"github.com/redesblock/mop/core/storer/kv"
 	"github.com/redesblock/mop/core/storer/sharky"
 	"github.com/redesblock/mop/core/storer/shed"
 	"github.com/redesblock/mop/core/storer/storage"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 var putModes = []storage.ModePut{
@@ -158,7 +158,7 @@ func TestModePutRequestCache(t *testing.T) {
 
 			for _, ch := range chunks {
 				newRetrieveIndexesTestWithAccess(db, ch, wantTimestamp, wantTimestamp)(t)
-				newPinIndexTest(db, ch, leveldb.ErrNotFound)(t)
+				newPinIndexTest(db, ch, kv.ErrNotFound)(t)
 			}
 
 			newItemsCountTest(db.voucherChunksIndex, tc.count)(t)
@@ -200,7 +200,7 @@ func TestModePutSync(t *testing.T) {
 
 				newRetrieveIndexesTestWithAccess(db, ch, wantTimestamp, wantTimestamp)(t)
 				newPullIndexTest(db, ch, binIDs[po], nil)(t)
-				newPinIndexTest(db, ch, leveldb.ErrNotFound)(t)
+				newPinIndexTest(db, ch, kv.ErrNotFound)(t)
 				newIndexGCSizeTest(db)(t)
 			}
 			newItemsCountTest(db.voucherChunksIndex, tc.count)(t)
@@ -242,7 +242,7 @@ func TestModePutUpload(t *testing.T) {
 				newRetrieveIndexesTest(db, ch, wantTimestamp, 0)(t)
 				newPullIndexTest(db, ch, binIDs[po], nil)(t)
 				newPushIndexTest(db, ch, wantTimestamp, nil)(t)
-				newPinIndexTest(db, ch, leveldb.ErrNotFound)(t)
+				newPinIndexTest(db, ch, kv.ErrNotFound)(t)
 			}
 			newItemsCountTest(db.voucherIndexIndex, tc.count)(t)
 			newIndexGCSizeTest(db)(t)
@@ -321,7 +321,7 @@ func TestModePutSyncUpload_SameIndex(t *testing.T) {
 
 	newItemsCountTest(db.retrievalDataIndex, 1)(t)
 	newPullIndexTest(db, chunks[1], binIDs[db.po(chunks[1].Address())], nil)(t)
-	newPinIndexTest(db, chunks[0], leveldb.ErrNotFound)(t)
+	newPinIndexTest(db, chunks[0], kv.ErrNotFound)(t)
 	newPinIndexTest(db, chunks[1], nil)(t)
 	newItemsCountTest(db.pullIndex, 1)(t)
 	newItemsCountTest(db.voucherIndexIndex, 1)(t)
@@ -8,11 +8,11 @@ import (
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/storer/kv"
 	"github.com/redesblock/mop/core/storer/sharky"
 	"github.com/redesblock/mop/core/storer/shed"
 	"github.com/redesblock/mop/core/storer/storage"
 	"github.com/redesblock/mop/core/tags"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 // Set updates database indexes for
@@ -39,7 +39,7 @@ func (db *DB) set(ctx context.Context, mode storage.ModeSet, addrs ...cluster.Ad
 		db.dirtyAddresses = append(db.dirtyAddresses, addrs...)
 	}
 
-	batch := new(leveldb.Batch)
+	batch := new(kv.Batch)
 	var committedLocations []sharky.Location
 
 	// variables that provide information for operations
@@ -119,7 +119,7 @@ func (db *DB) set(ctx context.Context, mode storage.ModeSet, addrs ...cluster.Ad
 
 	sharkyErr := new(multierror.Error)
 	for _, l := range committedLocations {
-		sharkyErr = multierror.Append(sharkyErr, db.sharky.Release(ctx, l))
+		sharkyErr = multierror.Append(sharkyErr, db.releaseBlob(ctx, l))
 	}
 	if sharkyErr.ErrorOrNil() != nil {
 		return sharkyErr.ErrorOrNil()
@@ -132,11 +132,12 @@ func (db *DB) set(ctx context.Context, mode storage.ModeSet, addrs ...cluster.Ad
 }
 
 // setSync adds the chunk to the garbage collection after syncing by updating indexes
-// - ModeSetSync - the corresponding tag is incremented, then item is removed
-//   from push chainsync index
-// - update to gc index happens given item does not exist in pins index
+//   - ModeSetSync - the corresponding tag is incremented, then item is removed
+//     from push chainsync index
+//   - update to gc index happens given item does not exist in pins index
+//
 // Provided batch is updated.
-func (db *DB) setSync(batch *leveldb.Batch, addr cluster.Address) (gcSizeChange, reserveSizeChange int64, err error) {
+func (db *DB) setSync(batch *kv.Batch, addr cluster.Address) (gcSizeChange, reserveSizeChange int64, err error) {
 	item := addressToItem(addr)
 
 	// need to get access timestamp here as it is not
@@ -145,7 +146,7 @@ func (db *DB) setSync(batch *leveldb.Batch, addr cluster.Address) (gcSizeChange,
 
 	i, err := db.retrievalDataIndex.Get(item)
 	if err != nil {
-		if errors.Is(err, leveldb.ErrNotFound) {
+		if errors.Is(err, kv.ErrNotFound) {
 			// chunk is not found,
 			// no need to update gc index
 			// just delete from the push index
@@ -166,7 +167,7 @@ func (db *DB) setSync(batch *leveldb.Batch, addr cluster.Address) (gcSizeChange,
 
 	i, err = db.pushIndex.Get(item)
 	if err != nil {
-		if errors.Is(err, leveldb.ErrNotFound) {
+		if errors.Is(err, kv.ErrNotFound) {
 			// we handle this error internally, since this is an internal inconsistency of the indices
 			// this error can happen if the chunk is put with ModePutRequest or ModePutSync
 			// but this function is called with ModeSetSync
@@ -196,7 +197,7 @@ func (db *DB) setSync(batch *leveldb.Batch, addr cluster.Address) (gcSizeChange,
 
 	i1, err := db.retrievalAccessIndex.Get(item)
 	if err != nil {
-		if !errors.Is(err, leveldb.ErrNotFound) {
+		if !errors.Is(err, kv.ErrNotFound) {
 			return 0, 0, err
 		}
 		item.AccessTimestamp = now()
@@ -211,15 +212,16 @@ func (db *DB) setSync(batch *leveldb.Batch, addr cluster.Address) (gcSizeChange,
 }
 
 // setRemove removes the chunk by updating indexes:
-//  - delete from retrieve, pull, gc
+//   - delete from retrieve, pull, gc
+//
 // Provided batch is updated.
-func (db *DB) setRemove(batch *leveldb.Batch, item shed.Item, check bool) (gcSizeChange int64, err error) {
+func (db *DB) setRemove(batch *kv.Batch, item shed.Item, check bool) (gcSizeChange int64, err error) {
 	if item.AccessTimestamp == 0 {
 		i, err := db.retrievalAccessIndex.Get(item)
 		switch {
 		case err == nil:
 			item.AccessTimestamp = i.AccessTimestamp
-		case errors.Is(err, leveldb.ErrNotFound):
+		case errors.Is(err, kv.ErrNotFound):
 		default:
 			return 0, err
 		}
@@ -262,7 +264,7 @@ func (db *DB) setRemove(batch *leveldb.Batch, item shed.Item, check bool) (gcSiz
 	if check {
 		_, err := db.gcIndex.Get(item)
 		if err != nil {
-			if !errors.Is(err, leveldb.ErrNotFound) {
+			if !errors.Is(err, kv.ErrNotFound) {
 				return 0, err
 			}
 			return 0, db.pinIndex.DeleteInBatch(batch, item)
@@ -278,7 +280,7 @@ func (db *DB) setRemove(batch *leveldb.Batch, item shed.Item, check bool) (gcSiz
 // setPin increments pins counter for the chunk by updating
 // pins index and sets the chunk to be excluded from garbage collection.
 // Provided batch is updated.
-func (db *DB) setPin(batch *leveldb.Batch, item shed.Item) (gcSizeChange int64, err error) {
+func (db *DB) setPin(batch *kv.Batch, item shed.Item) (gcSizeChange int64, err error) {
 	// Get the existing pins counter of the chunk
 	i, err := db.pinIndex.Get(item)
 
@@ -287,13 +289,13 @@ func (db *DB) setPin(batch *leveldb.Batch, item shed.Item) (gcSizeChange int64,
 	item.PinCounter = i.PinCounter
 
 	if err != nil {
-		if !errors.Is(err, leveldb.ErrNotFound) {
+		if !errors.Is(err, kv.ErrNotFound) {
 			return 0, err
 		}
 		// if this Address is not pinned yet, then
 		i, err := db.retrievalAccessIndex.Get(item)
 		if err != nil {
-			if !errors.Is(err, leveldb.ErrNotFound) {
+			if !errors.Is(err, kv.ErrNotFound) {
 				return 0, err
 			}
 			// not synced yet
@@ -325,7 +327,7 @@ func (db *DB) setPin(batch *leveldb.Batch, item shed.Item) (gcSizeChange int64,
 
 // setUnpin decrements pins counter for the chunk by updating pins index.
 // Provided batch is updated.
-func (db *DB) setUnpin(batch *leveldb.Batch, addr cluster.Address) (gcSizeChange int64, err error) {
+func (db *DB) setUnpin(batch *kv.Batch, addr cluster.Address) (gcSizeChange int64, err error) {
 	item := addressToItem(addr)
 
 	// Get the existing pins counter of the chunk
@@ -361,14 +363,14 @@ func (db *DB) setUnpin(batch *leveldb.Batch, addr cluster.Address) (gcSizeChange
 		// be written to leveldb, removing the item from the pins index, but not moving it to
 		// the gc index because it still exists in the push index.
 		return 0, nil
-	case !errors.Is(err, leveldb.ErrNotFound):
-		// err is not leveldb.ErrNotFound
+	case !errors.Is(err, kv.ErrNotFound):
+		// err is not kv.ErrNotFound
 		return 0, fmt.Errorf("get push index: %w", err)
 	}
 
 	i, err = db.retrievalAccessIndex.Get(item)
 	if err != nil {
-		if !errors.Is(err, leveldb.ErrNotFound) {
+		if !errors.Is(err, kv.ErrNotFound) {
 			return 0, fmt.Errorf("get retrieval access index: %w", err)
 		}
 		item.AccessTimestamp = now()
@@ -5,8 +5,8 @@ import (
 	"testing"
 
 	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/storer/kv"
 	"github.com/redesblock/mop/core/storer/storage"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 // TestModeSetRemove validates ModeSetRemove index values on the provided DB.
@@ -34,7 +34,7 @@ func TestModeSetRemove(t *testing.T) {
 			})
 
 			for _, ch := range chunks {
-				newPullIndexTest(db, ch, 0, leveldb.ErrNotFound)(t)
+				newPullIndexTest(db, ch, 0, kv.ErrNotFound)(t)
 			}
 
 			t.Run("pull index count", newItemsCountTest(db.pullIndex, 0))
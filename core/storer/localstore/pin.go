@@ -4,9 +4,9 @@ import (
 	"errors"
 
 	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/storer/kv"
 	"github.com/redesblock/mop/core/storer/shed"
 	"github.com/redesblock/mop/core/storer/storage"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 // pinCounter returns the pins counter for a given cluster address, provided that the
@@ -17,7 +17,7 @@ func (db *DB) pinCounter(address cluster.Address) (uint64, error) {
 	})
 
 	if err != nil {
-		if errors.Is(err, leveldb.ErrNotFound) {
+		if errors.Is(err, kv.ErrNotFound) {
 			return 0, storage.ErrNotFound
 		}
 		return 0, err
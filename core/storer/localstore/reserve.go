@@ -1,12 +1,14 @@
 package localstore
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 
 	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/incentives/voucher/batchstore"
+	"github.com/redesblock/mop/core/storer/kv"
 	"github.com/redesblock/mop/core/storer/shed"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 // UnreserveBatch atomically unpins chunks of a batch in proximity order upto and including po.
@@ -17,14 +19,14 @@ func (db *DB) UnreserveBatch(id []byte, radius uint8) (evicted uint64, err error
 		item = shed.Item{
 			BatchID: id,
 		}
-		batch             = new(leveldb.Batch)
+		batch             = new(kv.Batch)
 		oldRadius         uint8
 		reserveSizeChange uint64
 	)
 
 	i, err := db.voucherRadiusIndex.Get(item)
 	if err != nil {
-		if !errors.Is(err, leveldb.ErrNotFound) {
+		if !errors.Is(err, kv.ErrNotFound) {
 			return 0, err
 		}
 		oldRadius = 0
@@ -51,7 +53,7 @@ func (db *DB) UnreserveBatch(id []byte, radius uint8) (evicted uint64, err error
 		if err := db.shed.WriteBatch(batch); err != nil {
 			return 0, err
 		}
-		batch = new(leveldb.Batch)
+		batch = new(kv.Batch)
 	}
 
 	if radius != cluster.MaxPO+1 {
@@ -65,7 +67,7 @@ func (db *DB) UnreserveBatch(id []byte, radius uint8) (evicted uint64, err error
 	}
 
 	gcSize, err := db.gcSize.Get()
-	if err != nil && !errors.Is(err, leveldb.ErrNotFound) {
+	if err != nil && !errors.Is(err, kv.ErrNotFound) {
 		return 0, err
 	}
 
@@ -82,7 +84,7 @@ var unpinBatchSize = 10000
 func (db *DB) unpinBatchChunks(id []byte, bin uint8) (uint64, error) {
 	loggerV1 := db.logger.V(1).Register()
 	var (
-		batch                  = new(leveldb.Batch)
+		batch                  = new(kv.Batch)
 		gcSizeChange           int64 // number to add or subtract from gcSize and reserveSize
 		reserveSizeChange      uint64
 		totalReserveSizeChange uint64
@@ -91,7 +93,7 @@ func (db *DB) unpinBatchChunks(id []byte, bin uint8) (uint64, error) {
 		addr := cluster.NewAddress(item.Address)
 		c, err := db.setUnpin(batch, addr)
 		if err != nil {
-			if !errors.Is(err, leveldb.ErrNotFound) {
+			if !errors.Is(err, kv.ErrNotFound) {
 				return false, fmt.Errorf("unpin: %w", err)
 			}
 			// this is possible when we are resyncing chain data after
@@ -143,7 +145,7 @@ func (db *DB) unpinBatchChunks(id []byte, bin uint8) (uint64, error) {
 		if err := db.shed.WriteBatch(batch); err != nil {
 			return 0, err
 		}
-		batch = new(leveldb.Batch)
+		batch = new(kv.Batch)
 		gcSizeChange = 0
 		totalReserveSizeChange += reserveSizeChange
 		reserveSizeChange = 0
@@ -180,6 +182,34 @@ func (db *DB) ComputeReserveSize(startPO uint8) (uint64, error) {
 	return count, err
 }
 
+// ReserveUsage returns the number of stored chunks of every batch by
+// proximity order. The values of the batches are not set.
+func (db *DB) ReserveUsage() ([]*batchstore.BatchUsage, error) {
+	var (
+		usage []*batchstore.BatchUsage
+		last  *batchstore.BatchUsage
+	)
+	err := db.voucherChunksIndex.Iterate(func(item shed.Item) (stop bool, err error) {
+		if last == nil || !bytes.Equal(last.ID, item.BatchID) {
+			last = &batchstore.BatchUsage{ID: item.BatchID}
+			i, err := db.voucherRadiusIndex.Get(item)
+			switch {
+			case err == nil:
+				last.Radius = i.Radius
+			case !errors.Is(err, kv.ErrNotFound):
+				return true, err
+			}
+			usage = append(usage, last)
+		}
+		last.Bins[db.po(cluster.NewAddress(item.Address))]++
+		return false, nil
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
 func generateAddressAt(baseBytes []byte, prox int) []byte {
 
 	addr := make([]byte, 32)
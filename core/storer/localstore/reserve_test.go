@@ -1,6 +1,7 @@
 package localstore
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"sync"
@@ -770,3 +771,56 @@ func TestDB_ReserveGC_BatchedUnreserve(t *testing.T) {
 
 	t.Run("gc size", newIndexGCSizeTest(db))
 }
+
+func TestReserveUsage(t *testing.T) {
+	db := newTestDB(t, nil)
+
+	batchA, batchB := vouchertesting.MustNewID(), vouchertesting.MustNewID()
+	var chs []cluster.Chunk
+	for _, c := range []struct {
+		batch []byte
+		po    int
+		count int
+	}{
+		{batch: batchA, po: 2, count: 3},
+		{batch: batchA, po: 4, count: 1},
+		{batch: batchB, po: 3, count: 2},
+	} {
+		for i := 0; i < c.count; i++ {
+			ch := generateTestRandomChunkAt(cluster.NewAddress(db.baseKey), c.po).WithStamp(vouchertesting.MustNewBatchStamp(c.batch)).WithBatch(0, 3, 2, false)
+			chs = append(chs, ch)
+		}
+	}
+	if _, err := db.Put(context.Background(), storage.ModePutSync, chs...); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.UnreserveBatch(batchA, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	usage, err := db.ReserveUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 2 {
+		t.Fatalf("got usage of %d batches, want %d", len(usage), 2)
+	}
+	for _, u := range usage {
+		var (
+			wantRadius uint8
+			wantBins   [cluster.MaxBins]uint64
+		)
+		switch {
+		case bytes.Equal(u.ID, batchA):
+			wantRadius = 3
+			wantBins[2], wantBins[4] = 3, 1
+		case bytes.Equal(u.ID, batchB):
+			wantBins[3] = 2
+		default:
+			t.Fatalf("got usage of unknown batch %x", u.ID)
+		}
+		if u.Radius != wantRadius || u.Bins != wantBins {
+			t.Fatalf("got batch %x radius %d bins %v, want radius %d bins %v", u.ID, u.Radius, u.Bins, wantRadius, wantBins)
+		}
+	}
+}
@@ -88,7 +88,7 @@ func (db *DB) SubscribePush(ctx context.Context, skipf func([]byte) bool) (c <-c
 						return true, err
 					}
 					itemData := make([]byte, loc.Length)
-					err = db.sharky.Read(ctx, loc, itemData)
+					err = db.readBlob(ctx, loc, itemData)
 					if err != nil {
 						return true, err
 					}
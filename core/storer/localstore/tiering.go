@@ -0,0 +1,257 @@
+package localstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/storer/kv"
+	"github.com/redesblock/mop/core/storer/sharky"
+	"github.com/redesblock/mop/core/storer/shed"
+)
+
+var (
+	// coldMoveInterval is the time between two rounds of moving
+	// rarely accessed chunks to the cold store.
+	coldMoveInterval = 10 * time.Minute
+	// coldMoveLimit is the maximum number of chunks moved in a round.
+	coldMoveLimit = 1000
+	// coldDeleteTimeout is the maximum time of deleting a blob from the
+	// cold store.
+	coldDeleteTimeout = time.Minute
+)
+
+// readBlob reads the chunk data at the location into buf, from sharky
+// or from the cold store.
+func (db *DB) readBlob(ctx context.Context, loc sharky.Location, buf []byte) error {
+	if !loc.Cold {
+		return db.sharky.Read(ctx, loc, buf)
+	}
+	if db.cold == nil {
+		return fmt.Errorf("read cold location %s: no cold store", coldKey(loc))
+	}
+	db.metrics.ColdReadCounter.Inc()
+	data, err := db.cold.Get(ctx, coldKey(loc))
+	if err != nil {
+		return fmt.Errorf("read cold location %s: %w", coldKey(loc), err)
+	}
+	if len(data) != len(buf) {
+		return fmt.Errorf("read cold location %s: got %d bytes, want %d", coldKey(loc), len(data), len(buf))
+	}
+	copy(buf, data)
+	return nil
+}
+
+// releaseBlob frees the location in sharky or schedules the deletion of
+// the blob from the cold store. The blobs are deleted from the cold store
+// by the coldDeleteWorker, so that the callers, which usually hold batchMu,
+// do not wait for the cold store.
+func (db *DB) releaseBlob(ctx context.Context, loc sharky.Location) error {
+	if !loc.Cold {
+		return db.sharky.Release(ctx, loc)
+	}
+	if db.cold == nil {
+		return fmt.Errorf("release cold location %s: no cold store", coldKey(loc))
+	}
+	db.coldDeletesMu.Lock()
+	db.coldDeletes = append(db.coldDeletes, coldKey(loc))
+	db.coldDeletesMu.Unlock()
+
+	select {
+	case db.coldDeleteTrigger <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// coldDeleteWorker deletes the blobs of the released cold locations from
+// the cold store. The blobs which are not deleted when the DB is closed are
+// left in the cold store.
+func (db *DB) coldDeleteWorker() {
+	defer close(db.coldDeleteWorkerDone)
+
+	if db.cold == nil {
+		return
+	}
+
+	for {
+		select {
+		case <-db.coldDeleteTrigger:
+			db.coldDeletesMu.Lock()
+			keys := db.coldDeletes
+			db.coldDeletes = nil
+			db.coldDeletesMu.Unlock()
+
+			for _, key := range keys {
+				ctx, cancel := context.WithTimeout(db.ctx, coldDeleteTimeout)
+				err := db.cold.Delete(ctx, key)
+				cancel()
+				if err != nil {
+					db.metrics.ColdDeleteErrorCounter.Inc()
+					db.logger.Warning("failed deleting cold blob", "key", key, "error", err)
+				}
+				if testHookColdDelete != nil {
+					testHookColdDelete(key)
+				}
+			}
+		case <-db.close:
+			return
+		}
+	}
+}
+
+// coldKey returns the key of the blob in the cold store.
+func coldKey(loc sharky.Location) string {
+	return fmt.Sprintf("%02x%08x", loc.Shard, loc.Slot)
+}
+
+// coldTieringWorker periodically moves the reserve and pinned
+// chunks which were not accessed for a while to the cold store.
+func (db *DB) coldTieringWorker() {
+	defer close(db.coldTieringWorkerDone)
+
+	if db.cold == nil {
+		return
+	}
+
+	ticker := time.NewTicker(coldMoveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			moved, err := db.moveToCold(db.ctx, coldMoveLimit)
+			if err != nil {
+				db.logger.Error(err, "move chunks to cold store failed")
+			}
+			if testHookColdMove != nil {
+				testHookColdMove(moved)
+			}
+		case <-db.close:
+			return
+		}
+	}
+}
+
+// moveToCold moves at most limit chunks which were not accessed since
+// ColdAfter from sharky to the cold store. Chunks of both the reserve and
+// the pinned content are kept in the pin index.
+func (db *DB) moveToCold(ctx context.Context, limit int) (moved int, err error) {
+	db.metrics.ColdMoveCounter.Inc()
+	defer func(start time.Time) {
+		if err != nil {
+			db.metrics.ColdMoveErrorCounter.Inc()
+		}
+		totalTimeMetric(db.metrics.TotalTimeColdMove, start)
+	}(time.Now())
+
+	cutoff := now() - db.coldAfter.Nanoseconds()
+
+	var candidates []shed.Item
+	err = db.pinIndex.Iterate(func(item shed.Item) (stop bool, err error) {
+		i, err := db.retrievalDataIndex.Get(item)
+		if err != nil {
+			if errors.Is(err, kv.ErrNotFound) {
+				return false, nil
+			}
+			return true, err
+		}
+		loc, err := sharky.LocationFromBinary(i.Location)
+		if err != nil {
+			return true, err
+		}
+		if loc.Cold {
+			return false, nil
+		}
+		accessed := i.StoreTimestamp
+		a, err := db.retrievalAccessIndex.Get(item)
+		switch {
+		case err == nil:
+			accessed = a.AccessTimestamp
+		case !errors.Is(err, kv.ErrNotFound):
+			return true, err
+		}
+		if accessed > cutoff {
+			return false, nil
+		}
+		candidates = append(candidates, i)
+		return len(candidates) >= limit, nil
+	}, nil)
+	if err != nil {
+		return 0, fmt.Errorf("iterate pin index: %w", err)
+	}
+
+	for _, item := range candidates {
+		select {
+		case <-ctx.Done():
+			return moved, ctx.Err()
+		default:
+		}
+		ok, err := db.moveItemToCold(ctx, item)
+		if err != nil {
+			return moved, err
+		}
+		if ok {
+			moved++
+		}
+	}
+	db.metrics.ColdMovedCounter.Add(float64(moved))
+	return moved, nil
+}
+
+// moveItemToCold copies the chunk data to the cold store and points the
+// retrieval data index to it, unless the chunk changed in the meantime.
+func (db *DB) moveItemToCold(ctx context.Context, item shed.Item) (bool, error) {
+	hot, err := sharky.LocationFromBinary(item.Location)
+	if err != nil {
+		return false, err
+	}
+	data := make([]byte, hot.Length)
+	if err := db.sharky.Read(ctx, hot, data); err != nil {
+		return false, fmt.Errorf("read chunk %x: %w", item.Address, err)
+	}
+
+	id, err := db.coldSeq.Inc()
+	if err != nil {
+		return false, err
+	}
+	cold := sharky.Location{Shard: uint8(id >> 32), Slot: uint32(id), Length: hot.Length, Cold: true}
+	if err := db.cold.Put(ctx, coldKey(cold), data); err != nil {
+		return false, fmt.Errorf("put chunk %x: %w", item.Address, err)
+	}
+
+	db.batchMu.Lock()
+	defer db.batchMu.Unlock()
+
+	current, err := db.retrievalDataIndex.Get(item)
+	if err != nil && !errors.Is(err, kv.ErrNotFound) {
+		return false, err
+	}
+	if err != nil || string(current.Location) != string(item.Location) {
+		// the chunk was removed or replaced while it was copied
+		return false, db.releaseBlob(ctx, cold)
+	}
+
+	current.Location, err = cold.MarshalBinary()
+	if err != nil {
+		return false, err
+	}
+	batch := new(kv.Batch)
+	if err := db.retrievalDataIndex.PutInBatch(batch, current); err != nil {
+		return false, err
+	}
+	if err := db.shed.WriteBatch(batch); err != nil {
+		return false, err
+	}
+	if db.lru != nil {
+		db.lru.Remove(cluster.NewAddress(item.Address).String())
+	}
+	return true, db.sharky.Release(ctx, hot)
+}
+
+var (
+	testHookColdMove   func(moved int)
+	testHookColdDelete func(key string)
+)
@@ -0,0 +1,173 @@
+package localstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/storer/coldstore"
+	"github.com/redesblock/mop/core/storer/sharky"
+	"github.com/redesblock/mop/core/storer/storage"
+)
+
+// TestColdTiering checks that rarely accessed pinned chunks are moved to
+// the cold store, transparently read from it and deleted from it.
+func TestColdTiering(t *testing.T) {
+	cold, err := coldstore.NewDirStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := newTestDB(t, &Options{ColdStore: cold, ColdAfter: time.Hour})
+	ctx := context.Background()
+
+	pinned, unpinned := generateTestRandomChunk(), generateTestRandomChunk()
+	if _, err := db.Put(ctx, storage.ModePutUpload, pinned, unpinned); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set(ctx, storage.ModeSetPin, pinned.Address()); err != nil {
+		t.Fatal(err)
+	}
+
+	moved, err := db.moveToCold(ctx, coldMoveLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != 0 {
+		t.Fatalf("got %d moved chunks of recently stored chunks, want none", moved)
+	}
+
+	defer setNow(func() int64 {
+		return time.Now().Add(2 * time.Hour).UnixNano()
+	})()
+
+	moved, err = db.moveToCold(ctx, coldMoveLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != 1 {
+		t.Fatalf("got %d moved chunks, want %d", moved, 1)
+	}
+
+	item, err := db.retrievalDataIndex.Get(addressToItem(pinned.Address()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc, err := sharky.LocationFromBinary(item.Location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loc.Cold {
+		t.Fatal("pinned chunk location is not cold")
+	}
+
+	for _, ch := range []cluster.Chunk{pinned, unpinned} {
+		got, err := db.Get(ctx, storage.ModeGetRequest, ch.Address())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Data(), ch.Data()) {
+			t.Fatalf("got chunk %s data %x, want %x", ch.Address(), got.Data(), ch.Data())
+		}
+	}
+
+	deleted := make(chan string, 1)
+	t.Cleanup(setTestHookColdDelete(func(key string) {
+		deleted <- key
+	}))
+
+	if err := db.Set(ctx, storage.ModeSetRemove, pinned.Address()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case key := <-deleted:
+		if key != coldKey(loc) {
+			t.Fatalf("got deleted key %s, want %s", key, coldKey(loc))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cold blob not deleted")
+	}
+	if _, err := cold.Get(ctx, coldKey(loc)); !errors.Is(err, coldstore.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, coldstore.ErrNotFound)
+	}
+}
+
+// TestColdDeleteAsync checks that the writes to the DB do not wait for
+// the blobs to be deleted from the cold store.
+func TestColdDeleteAsync(t *testing.T) {
+	dir, err := coldstore.NewDirStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	unblock := make(chan struct{})
+	cold := &blockingDeleteStore{Store: dir, unblock: unblock}
+	db := newTestDB(t, &Options{ColdStore: cold, ColdAfter: time.Hour})
+	ctx := context.Background()
+
+	chs := []cluster.Chunk{generateTestRandomChunk(), generateTestRandomChunk()}
+	for _, ch := range chs {
+		if _, err := db.Put(ctx, storage.ModePutUpload, ch); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Set(ctx, storage.ModeSetPin, ch.Address()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer setNow(func() int64 {
+		return time.Now().Add(2 * time.Hour).UnixNano()
+	})()
+
+	if _, err := db.moveToCold(ctx, coldMoveLimit); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted := make(chan string, len(chs))
+	t.Cleanup(setTestHookColdDelete(func(key string) {
+		deleted <- key
+	}))
+
+	// the deletes from the cold store are blocked
+	for _, ch := range chs {
+		if err := db.Set(ctx, storage.ModeSetRemove, ch.Address()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Put(ctx, storage.ModePutUpload, generateTestRandomChunk()); err != nil {
+		t.Fatal(err)
+	}
+
+	close(unblock)
+	for range chs {
+		select {
+		case <-deleted:
+		case <-time.After(5 * time.Second):
+			t.Fatal("cold blob not deleted")
+		}
+	}
+}
+
+// blockingDeleteStore is a cold store which blocks the deletes until
+// unblock is closed.
+type blockingDeleteStore struct {
+	coldstore.Store
+	unblock chan struct{}
+}
+
+func (s *blockingDeleteStore) Delete(ctx context.Context, key string) error {
+	select {
+	case <-s.unblock:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return s.Store.Delete(ctx, key)
+}
+
+func setTestHookColdDelete(h func(key string)) (reset func()) {
+	current := testHookColdDelete
+	reset = func() { testHookColdDelete = current }
+	testHookColdDelete = h
+	return reset
+}
@@ -10,26 +10,41 @@ import (
 const LocationSize int = 7
 
 // Location models the location <shard, slot, length> of a chunk
+// Cold locations are not in the store but in a secondary storage tier
+// where shard and slot together identify the blob.
 type Location struct {
 	Shard  uint8
 	Slot   uint32
 	Length uint16
+	Cold   bool
 }
 
 // MarshalBinary returns byte representation of location
+// Cold locations have an additional trailing marker byte.
 func (l *Location) MarshalBinary() ([]byte, error) {
-	b := make([]byte, LocationSize)
+	size := LocationSize
+	if l.Cold {
+		size++
+	}
+	b := make([]byte, size)
 	b[0] = l.Shard
 	binary.LittleEndian.PutUint32(b[1:5], l.Slot)
-	binary.LittleEndian.PutUint16(b[5:], l.Length)
+	binary.LittleEndian.PutUint16(b[5:7], l.Length)
+	if l.Cold {
+		b[LocationSize] = 1
+	}
 	return b, nil
 }
 
 // UnmarshalBinary constructs the location from byte representation
 func (l *Location) UnmarshalBinary(buf []byte) error {
+	if len(buf) < LocationSize {
+		return ErrInvalidLocation
+	}
 	l.Shard = buf[0]
 	l.Slot = binary.LittleEndian.Uint32(buf[1:5])
-	l.Length = binary.LittleEndian.Uint16(buf[5:])
+	l.Length = binary.LittleEndian.Uint16(buf[5:7])
+	l.Cold = len(buf) > LocationSize && buf[LocationSize] == 1
 	return nil
 }
 
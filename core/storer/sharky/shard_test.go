@@ -1,6 +1,7 @@
 package sharky_test
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"testing"
@@ -49,3 +50,35 @@ func TestLocationSerialization(t *testing.T) {
 		})
 	}
 }
+
+func TestColdLocationSerialization(t *testing.T) {
+	l := &sharky.Location{Shard: 2, Slot: 200, Length: 4096, Cold: true}
+
+	buf, err := l.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) != sharky.LocationSize+1 {
+		t.Fatalf("got buffer length %d, want %d", len(buf), sharky.LocationSize+1)
+	}
+
+	l2, err := sharky.LocationFromBinary(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l2 != *l {
+		t.Fatalf("got location %v, want %v", l2, l)
+	}
+
+	hot, err := sharky.LocationFromBinary(buf[:sharky.LocationSize])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hot.Cold {
+		t.Fatal("got cold location from hot location bytes")
+	}
+
+	if _, err := sharky.LocationFromBinary(buf[:3]); !errors.Is(err, sharky.ErrInvalidLocation) {
+		t.Fatalf("got error %v, want %v", err, sharky.ErrInvalidLocation)
+	}
+}
@@ -9,11 +9,7 @@ package shed
 import (
 	"errors"
 
-	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/iterator"
-	"github.com/syndtr/goleveldb/leveldb/opt"
-	"github.com/syndtr/goleveldb/leveldb/storage"
-	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/redesblock/mop/core/storer/kv"
 )
 
 var (
@@ -24,18 +20,21 @@ var (
 )
 
 type Options struct {
+	// Backend is the key-value store backend of a new database,
+	// existing databases keep their backend.
+	Backend                string
 	BlockCacheCapacity     uint64
 	WriteBufferSize        uint64
 	OpenFilesLimit         uint64
 	DisableSeeksCompaction bool
 }
 
-// DB provides abstractions over LevelDB in order to
+// DB provides abstractions over a key-value store in order to
 // implement complex structures using fields and ordered indexes.
 // It provides a schema functionality to store fields and indexes
 // information about naming and types.
 type DB struct {
-	ldb     *leveldb.DB
+	kv      kv.Store
 	metrics metrics
 	quit    chan struct{} // Quit channel to stop the metrics collection before closing the database
 }
@@ -52,39 +51,34 @@ func NewDB(path string, o *Options) (db *DB, err error) {
 			DisableSeeksCompaction: defaultDisableSeeksCompaction,
 		}
 	}
-	var ldb *leveldb.DB
-	if path == "" {
-		ldb, err = leveldb.Open(storage.NewMemStorage(), nil)
-	} else {
-		ldb, err = leveldb.OpenFile(path, &opt.Options{
-			OpenFilesCacheCapacity: int(o.OpenFilesLimit),
-			BlockCacheCapacity:     int(o.BlockCacheCapacity),
-			WriteBuffer:            int(o.WriteBufferSize),
-			DisableSeeksCompaction: o.DisableSeeksCompaction,
-		})
-	}
-
+	store, err := kv.Open(path, &kv.Options{
+		Backend:                o.Backend,
+		OpenFilesLimit:         o.OpenFilesLimit,
+		BlockCacheCapacity:     o.BlockCacheCapacity,
+		WriteBufferSize:        o.WriteBufferSize,
+		DisableSeeksCompaction: o.DisableSeeksCompaction,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return NewDBWrap(ldb)
+	return NewDBWrap(store)
 }
 
-// NewDBWrap returns new DB which uses the given ldb as its underlying storage.
-// The function will panics if the given ldb is nil.
-func NewDBWrap(ldb *leveldb.DB) (db *DB, err error) {
-	if ldb == nil {
-		panic(errors.New("shed: NewDBWrap: nil ldb"))
+// NewDBWrap returns new DB which uses the given store as its underlying storage.
+// The function will panics if the given store is nil.
+func NewDBWrap(store kv.Store) (db *DB, err error) {
+	if store == nil {
+		panic(errors.New("shed: NewDBWrap: nil store"))
 	}
 
 	db = &DB{
-		ldb:     ldb,
+		kv:      store,
 		metrics: newMetrics(),
 	}
 
 	if _, err = db.getSchema(); err != nil {
-		if errors.Is(err, leveldb.ErrNotFound) {
+		if errors.Is(err, kv.ErrNotFound) {
 			// Save schema with initialized default fields.
 			if err = db.putSchema(schema{
 				Fields:  make(map[string]fieldSpec),
@@ -103,9 +97,9 @@ func NewDBWrap(ldb *leveldb.DB) (db *DB, err error) {
 	return db, nil
 }
 
-// Put wraps LevelDB Put method to increment metrics counter.
+// Put wraps the store Put method to increment metrics counter.
 func (db *DB) Put(key, value []byte) (err error) {
-	err = db.ldb.Put(key, value, nil)
+	err = db.kv.Put(key, value)
 	if err != nil {
 		db.metrics.PutFailCounter.Inc()
 		return err
@@ -114,11 +108,11 @@ func (db *DB) Put(key, value []byte) (err error) {
 	return nil
 }
 
-// Get wraps LevelDB Get method to increment metrics counter.
+// Get wraps the store Get method to increment metrics counter.
 func (db *DB) Get(key []byte) (value []byte, err error) {
-	value, err = db.ldb.Get(key, nil)
+	value, err = db.kv.Get(key)
 	if err != nil {
-		if errors.Is(err, leveldb.ErrNotFound) {
+		if errors.Is(err, kv.ErrNotFound) {
 			db.metrics.GetNotFoundCounter.Inc()
 		} else {
 			db.metrics.GetFailCounter.Inc()
@@ -129,9 +123,9 @@ func (db *DB) Get(key []byte) (value []byte, err error) {
 	return value, nil
 }
 
-// Has wraps LevelDB Has method to increment metrics counter.
+// Has wraps the store Has method to increment metrics counter.
 func (db *DB) Has(key []byte) (yes bool, err error) {
-	yes, err = db.ldb.Has(key, nil)
+	yes, err = db.kv.Has(key)
 	if err != nil {
 		db.metrics.HasFailCounter.Inc()
 		return false, err
@@ -140,9 +134,9 @@ func (db *DB) Has(key []byte) (yes bool, err error) {
 	return yes, nil
 }
 
-// Delete wraps LevelDB Delete method to increment metrics counter.
+// Delete wraps the store Delete method to increment metrics counter.
 func (db *DB) Delete(key []byte) (err error) {
-	err = db.ldb.Delete(key, nil)
+	err = db.kv.Delete(key)
 	if err != nil {
 		db.metrics.DeleteFailCounter.Inc()
 		return err
@@ -151,15 +145,15 @@ func (db *DB) Delete(key []byte) (err error) {
 	return nil
 }
 
-// NewIterator wraps LevelDB NewIterator method to increment metrics counter.
-func (db *DB) NewIterator() iterator.Iterator {
+// NewIterator wraps the store NewIterator method to increment metrics counter.
+func (db *DB) NewIterator() kv.Iterator {
 	db.metrics.IteratorCounter.Inc()
-	return db.ldb.NewIterator(nil, nil)
+	return db.kv.NewIterator(nil)
 }
 
-// WriteBatch wraps LevelDB Write method to increment metrics counter.
-func (db *DB) WriteBatch(batch *leveldb.Batch) (err error) {
-	err = db.ldb.Write(batch, nil)
+// WriteBatch wraps the store Write method to increment metrics counter.
+func (db *DB) WriteBatch(batch *kv.Batch) (err error) {
+	err = db.kv.Write(batch)
 	if err != nil {
 		db.metrics.WriteBatchFailCounter.Inc()
 		return err
@@ -169,14 +163,13 @@ func (db *DB) WriteBatch(batch *leveldb.Batch) (err error) {
 }
 
 // Compact triggers a full database compaction on the underlying
-// LevelDB instance. Use with care! This can be very expensive!
+// store. Use with care! This can be very expensive!
 func (db *DB) Compact(start, end []byte) error {
-	r := util.Range{Start: start, Limit: end}
-	return db.ldb.CompactRange(r)
+	return db.kv.Compact(start, end)
 }
 
-// Close closes LevelDB database.
+// Close closes the underlying store.
 func (db *DB) Close() (err error) {
 	close(db.quit)
-	return db.ldb.Close()
+	return db.kv.Close()
 }
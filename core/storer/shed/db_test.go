@@ -2,6 +2,8 @@ package shed
 
 import (
 	"testing"
+
+	"github.com/redesblock/mop/core/storer/kv"
 )
 
 // TestNewDB constructs a new DB
@@ -29,45 +31,50 @@ func TestNewDB(t *testing.T) {
 
 // TestDB_persistence creates one DB, saves a field and closes that DB.
 // Then, it constructs another DB and tries to retrieve the saved value.
+// The second DB is opened without options to use the backend of the first one.
 func TestDB_persistence(t *testing.T) {
-	dir := t.TempDir()
+	for _, backend := range kv.Backends {
+		t.Run(backend, func(t *testing.T) {
+			dir := t.TempDir()
 
-	db, err := NewDB(dir, nil)
-	if err != nil {
-		t.Fatal(err)
-	}
-	stringField, err := db.NewStringField("preserve-me")
-	if err != nil {
-		t.Fatal(err)
-	}
-	want := "persistent value"
-	err = stringField.Put(want)
-	if err != nil {
-		t.Fatal(err)
-	}
-	err = db.Close()
-	if err != nil {
-		t.Fatal(err)
-	}
+			db, err := NewDB(dir, &Options{Backend: backend})
+			if err != nil {
+				t.Fatal(err)
+			}
+			stringField, err := db.NewStringField("preserve-me")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := "persistent value"
+			err = stringField.Put(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = db.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
 
-	db2, err := NewDB(dir, nil)
-	if err != nil {
-		t.Fatal(err)
-	}
-	stringField2, err := db2.NewStringField("preserve-me")
-	if err != nil {
-		t.Fatal(err)
-	}
-	got, err := stringField2.Get()
-	if err != nil {
-		t.Fatal(err)
-	}
-	if got != want {
-		t.Errorf("got string %q, want %q", got, want)
-	}
-	err = db2.Close()
-	if err != nil {
-		t.Fatal(err)
+			db2, err := NewDB(dir, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stringField2, err := db2.NewStringField("preserve-me")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := stringField2.Get()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("got string %q, want %q", got, want)
+			}
+			err = db2.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
 	}
 }
 
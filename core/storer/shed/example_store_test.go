@@ -10,10 +10,10 @@ import (
 	"time"
 
 	"github.com/redesblock/mop/core/cluster"
+	"github.com/redesblock/mop/core/storer/kv"
 	"github.com/redesblock/mop/core/storer/shed"
 	"github.com/redesblock/mop/core/storer/storage"
 	"github.com/redesblock/mop/core/storer/storage/testing"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 // Store holds fields and indexes (including their encoding functions)
@@ -141,14 +141,14 @@ func (s *Store) Put(_ context.Context, ch cluster.Chunk) (err error) {
 // items from them and adding new items as keys of index entries
 // are changed.
 func (s *Store) Get(_ context.Context, addr cluster.Address) (c cluster.Chunk, err error) {
-	batch := new(leveldb.Batch)
+	batch := new(kv.Batch)
 
 	// Get the chunk data and storage timestamp.
 	item, err := s.retrievalIndex.Get(shed.Item{
 		Address: addr.Bytes(),
 	})
 	if err != nil {
-		if errors.Is(err, leveldb.ErrNotFound) {
+		if errors.Is(err, kv.ErrNotFound) {
 			return nil, storage.ErrNotFound
 		}
 		return nil, fmt.Errorf("retrieval index get: %w", err)
@@ -169,7 +169,7 @@ func (s *Store) Get(_ context.Context, addr cluster.Address) (c cluster.Chunk, e
 		if err != nil {
 			return nil, fmt.Errorf("gc index delete in batch: %w", err)
 		}
-	case errors.Is(err, leveldb.ErrNotFound):
+	case errors.Is(err, kv.ErrNotFound):
 		// Access timestamp is not found. Do not do anything.
 		// This is the first get request.
 	default:
@@ -225,7 +225,7 @@ func (s *Store) CollectGarbage() (err error) {
 	for roundCount := 0; roundCount < maxRounds; roundCount++ {
 		var garbageCount int
 		// New batch for a new cg round.
-		trash := new(leveldb.Batch)
+		trash := new(kv.Batch)
 		// Iterate through all index items and break when needed.
 		err = s.gcIndex.Iterate(func(item shed.Item) (stop bool, err error) {
 			// Remove the chunk.
@@ -267,7 +267,7 @@ func (s *Store) CollectGarbage() (err error) {
 // string from a database field.
 func (s *Store) GetSchema() (name string, err error) {
 	name, err = s.schemaName.Get()
-	if errors.Is(err, leveldb.ErrNotFound) {
+	if errors.Is(err, kv.ErrNotFound) {
 		return "", nil
 	}
 	return name, err
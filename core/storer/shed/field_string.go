@@ -4,7 +4,7 @@ import (
 	"errors"
 	"fmt"
 
-	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/redesblock/mop/core/storer/kv"
 )
 
 // StringField is the most simple field implementation
@@ -33,7 +33,7 @@ func (db *DB) NewStringField(name string) (f StringField, err error) {
 func (f StringField) Get() (val string, err error) {
 	b, err := f.db.Get(f.key)
 	if err != nil {
-		if errors.Is(err, leveldb.ErrNotFound) {
+		if errors.Is(err, kv.ErrNotFound) {
 			return "", nil
 		}
 		return "", err
@@ -48,6 +48,6 @@ func (f StringField) Put(val string) (err error) {
 
 // PutInBatch stores a string in a batch that can be
 // saved later in database.
-func (f StringField) PutInBatch(batch *leveldb.Batch, val string) {
+func (f StringField) PutInBatch(batch *kv.Batch, val string) {
 	batch.Put(f.key, []byte(val))
 }
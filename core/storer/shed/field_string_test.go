@@ -3,7 +3,7 @@ package shed
 import (
 	"testing"
 
-	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/redesblock/mop/core/storer/kv"
 )
 
 // TestStringField validates put and get operations
@@ -58,7 +58,7 @@ func TestStringField(t *testing.T) {
 	})
 
 	t.Run("put in batch", func(t *testing.T) {
-		batch := new(leveldb.Batch)
+		batch := new(kv.Batch)
 		want := "simple string batch value"
 		simpleString.PutInBatch(batch, want)
 		err = db.WriteBatch(batch)
@@ -74,7 +74,7 @@ func TestStringField(t *testing.T) {
 		}
 
 		t.Run("overwrite", func(t *testing.T) {
-			batch := new(leveldb.Batch)
+			batch := new(kv.Batch)
 			want := "overwritten string batch value"
 			simpleString.PutInBatch(batch, want)
 			err = db.WriteBatch(batch)
@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 
-	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/redesblock/mop/core/storer/kv"
 )
 
 // StructField is a helper to store complex structure by
@@ -28,7 +28,7 @@ func (db *DB) NewStructField(name string) (f StructField, err error) {
 }
 
 // Get unmarshals data from the database to a provided val.
-// If the data is not found leveldb.ErrNotFound is returned.
+// If the data is not found kv.ErrNotFound is returned.
 func (f StructField) Get(val interface{}) (err error) {
 	b, err := f.db.Get(f.key)
 	if err != nil {
@@ -47,7 +47,7 @@ func (f StructField) Put(val interface{}) (err error) {
 }
 
 // PutInBatch marshals provided val and puts it into the batch.
-func (f StructField) PutInBatch(batch *leveldb.Batch, val interface{}) (err error) {
+func (f StructField) PutInBatch(batch *kv.Batch, val interface{}) (err error) {
 	b, err := json.Marshal(val)
 	if err != nil {
 		return err
@@ -3,7 +3,7 @@ package shed
 import (
 	"testing"
 
-	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/redesblock/mop/core/storer/kv"
 )
 
 // TestStructField validates put and get operations
@@ -23,8 +23,8 @@ func TestStructField(t *testing.T) {
 	t.Run("get empty", func(t *testing.T) {
 		var s complexStructure
 		err := complexField.Get(&s)
-		if err != leveldb.ErrNotFound {
-			t.Fatalf("got error %v, want %v", err, leveldb.ErrNotFound)
+		if err != kv.ErrNotFound {
+			t.Fatalf("got error %v, want %v", err, kv.ErrNotFound)
 		}
 		want := ""
 		if s.A != want {
@@ -69,7 +69,7 @@ func TestStructField(t *testing.T) {
 	})
 
 	t.Run("put in batch", func(t *testing.T) {
-		batch := new(leveldb.Batch)
+		batch := new(kv.Batch)
 		want := complexStructure{
 			A: "simple string batch value",
 		}
@@ -91,7 +91,7 @@ func TestStructField(t *testing.T) {
 		}
 
 		t.Run("overwrite", func(t *testing.T) {
-			batch := new(leveldb.Batch)
+			batch := new(kv.Batch)
 			want := complexStructure{
 				A: "overwritten string batch value",
 			}
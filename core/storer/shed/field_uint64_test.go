@@ -3,7 +3,7 @@ package shed
 import (
 	"testing"
 
-	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/redesblock/mop/core/storer/kv"
 )
 
 // TestUint64Field validates put and get operations
@@ -58,7 +58,7 @@ func TestUint64Field(t *testing.T) {
 	})
 
 	t.Run("put in batch", func(t *testing.T) {
-		batch := new(leveldb.Batch)
+		batch := new(kv.Batch)
 		var want uint64 = 42
 		counter.PutInBatch(batch, want)
 		err = db.WriteBatch(batch)
@@ -74,7 +74,7 @@ func TestUint64Field(t *testing.T) {
 		}
 
 		t.Run("overwrite", func(t *testing.T) {
-			batch := new(leveldb.Batch)
+			batch := new(kv.Batch)
 			var want uint64 = 84
 			counter.PutInBatch(batch, want)
 			err = db.WriteBatch(batch)
@@ -131,7 +131,7 @@ func TestUint64Field_IncInBatch(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	batch := new(leveldb.Batch)
+	batch := new(kv.Batch)
 	var want uint64 = 1
 	got, err := counter.IncInBatch(batch)
 	if err != nil {
@@ -152,7 +152,7 @@ func TestUint64Field_IncInBatch(t *testing.T) {
 		t.Errorf("got uint64 %v, want %v", got, want)
 	}
 
-	batch2 := new(leveldb.Batch)
+	batch2 := new(kv.Batch)
 	want = 2
 	got, err = counter.IncInBatch(batch2)
 	if err != nil {
@@ -220,7 +220,7 @@ func TestUint64Field_DecInBatch(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	batch := new(leveldb.Batch)
+	batch := new(kv.Batch)
 	var want uint64
 	got, err := counter.DecInBatch(batch)
 	if err != nil {
@@ -241,7 +241,7 @@ func TestUint64Field_DecInBatch(t *testing.T) {
 		t.Errorf("got uint64 %v, want %v", got, want)
 	}
 
-	batch2 := new(leveldb.Batch)
+	batch2 := new(kv.Batch)
 	want = 42
 	counter.PutInBatch(batch2, want)
 	err = db.WriteBatch(batch2)
@@ -256,7 +256,7 @@ func TestUint64Field_DecInBatch(t *testing.T) {
 		t.Errorf("got uint64 %v, want %v", got, want)
 	}
 
-	batch3 := new(leveldb.Batch)
+	batch3 := new(kv.Batch)
 	want = 41
 	got, err = counter.DecInBatch(batch3)
 	if err != nil {
@@ -5,8 +5,7 @@ import (
 	"errors"
 	"fmt"
 
-	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/redesblock/mop/core/storer/kv"
 )
 
 // Item holds fields relevant to Cluster Chunk data and metadata.
@@ -183,9 +182,9 @@ func (f Index) Get(keyFields Item) (out Item, err error) {
 // fields. Every item must have all fields needed for encoding the
 // key set. The passed slice items will be changed so that they
 // contain data from the index values. No new slice is allocated.
-// This function uses a single leveldb snapshot.
+// This function uses a single store snapshot.
 func (f Index) Fill(items []Item) (err error) {
-	snapshot, err := f.db.ldb.GetSnapshot()
+	snapshot, err := f.db.kv.NewSnapshot()
 	if err != nil {
 		return fmt.Errorf("get snapshot: %w", err)
 	}
@@ -196,7 +195,7 @@ func (f Index) Fill(items []Item) (err error) {
 		if err != nil {
 			return fmt.Errorf("encode key: %w", err)
 		}
-		value, err := snapshot.Get(key, nil)
+		value, err := snapshot.Get(key)
 		if err != nil {
 			return fmt.Errorf("get value: %w", err)
 		}
@@ -224,7 +223,7 @@ func (f Index) Has(keyFields Item) (bool, error) {
 // there this Item's encoded key is stored in the index for each of them.
 func (f Index) HasMulti(items ...Item) ([]bool, error) {
 	have := make([]bool, len(items))
-	snapshot, err := f.db.ldb.GetSnapshot()
+	snapshot, err := f.db.kv.NewSnapshot()
 	if err != nil {
 		return nil, fmt.Errorf("get snapshot: %w", err)
 	}
@@ -234,7 +233,7 @@ func (f Index) HasMulti(items ...Item) ([]bool, error) {
 		if err != nil {
 			return nil, fmt.Errorf("encode key for address %x: %w", keyFields.Address, err)
 		}
-		have[i], err = snapshot.Has(key, nil)
+		have[i], err = snapshot.Has(key)
 		if err != nil {
 			return nil, fmt.Errorf("has key for address %x: %w", keyFields.Address, err)
 		}
@@ -259,7 +258,7 @@ func (f Index) Put(i Item) (err error) {
 // PutInBatch is the same as Put method, but it just
 // saves the key/value pair to the batch instead
 // directly to the database.
-func (f Index) PutInBatch(batch *leveldb.Batch, i Item) (err error) {
+func (f Index) PutInBatch(batch *kv.Batch, i Item) (err error) {
 	key, err := f.encodeKeyFunc(i)
 	if err != nil {
 		return fmt.Errorf("encode key: %w", err)
@@ -284,7 +283,7 @@ func (f Index) Delete(keyFields Item) (err error) {
 
 // DeleteInBatch is the same as Delete just the operation
 // is performed on the batch instead on the database.
-func (f Index) DeleteInBatch(batch *leveldb.Batch, keyFields Item) (err error) {
+func (f Index) DeleteInBatch(batch *kv.Batch, keyFields Item) (err error) {
 	key, err := f.encodeKeyFunc(keyFields)
 	if err != nil {
 		return fmt.Errorf("encode key: %w", err)
@@ -393,7 +392,7 @@ func (f Index) Iterate(fn IndexIterFunc, options *IterateOptions) (err error) {
 	for ; ok; ok = itSeekerFn() {
 		item, err := f.itemFromIterator(it, prefix)
 		if err != nil {
-			if errors.Is(err, leveldb.ErrNotFound) {
+			if errors.Is(err, kv.ErrNotFound) {
 				break
 			}
 			return fmt.Errorf("get item from iterator: %w", err)
@@ -431,7 +430,7 @@ func bytesIncrement(bytes []byte) []byte {
 
 // First returns the first item in the Index which encoded key starts with a prefix.
 // If the prefix is nil, the first element of the whole index is returned.
-// If Index has no elements, a leveldb.ErrNotFound error is returned.
+// If Index has no elements, a kv.ErrNotFound error is returned.
 func (f Index) First(prefix []byte) (i Item, err error) {
 	it := f.db.NewIterator()
 	defer it.Release()
@@ -444,12 +443,12 @@ func (f Index) First(prefix []byte) (i Item, err error) {
 
 // itemFromIterator returns the Item from the current iterator position.
 // If the complete encoded key does not start with totalPrefix,
-// leveldb.ErrNotFound is returned. Value for totalPrefix must start with
+// kv.ErrNotFound is returned. Value for totalPrefix must start with
 // Index prefix.
-func (f Index) itemFromIterator(it iterator.Iterator, totalPrefix []byte) (i Item, err error) {
+func (f Index) itemFromIterator(it kv.Iterator, totalPrefix []byte) (i Item, err error) {
 	key := it.Key()
 	if !bytes.HasPrefix(key, totalPrefix) {
-		return i, leveldb.ErrNotFound
+		return i, kv.ErrNotFound
 	}
 	// create a copy of key byte slice not to share leveldb underlaying slice array
 	keyItem, err := f.decodeKeyFunc(append([]byte(nil), key...))
@@ -466,7 +465,7 @@ func (f Index) itemFromIterator(it iterator.Iterator, totalPrefix []byte) (i Ite
 
 // Last returns the last item in the Index which encoded key starts with a prefix.
 // If the prefix is nil, the last element of the whole index is returned.
-// If Index has no elements, a leveldb.ErrNotFound error is returned.
+// If Index has no elements, a kv.ErrNotFound error is returned.
 func (f Index) Last(prefix []byte) (i Item, err error) {
 	it := f.db.NewIterator()
 	defer it.Release()
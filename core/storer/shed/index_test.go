@@ -9,7 +9,7 @@ import (
 	"testing"
 	"time"
 
-	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/redesblock/mop/core/storer/kv"
 )
 
 // Index functions for the index that is used in tests in this file.
@@ -90,7 +90,7 @@ func TestIndex(t *testing.T) {
 			StoreTimestamp: time.Now().UTC().UnixNano(),
 		}
 
-		batch := new(leveldb.Batch)
+		batch := new(kv.Batch)
 		err = index.PutInBatch(batch, want)
 		if err != nil {
 			t.Fatal(err)
@@ -114,7 +114,7 @@ func TestIndex(t *testing.T) {
 				StoreTimestamp: time.Now().UTC().UnixNano(),
 			}
 
-			batch := new(leveldb.Batch)
+			batch := new(kv.Batch)
 			err = index.PutInBatch(batch, want)
 			if err != nil {
 				t.Fatal(err)
@@ -136,7 +136,7 @@ func TestIndex(t *testing.T) {
 	t.Run("put in batch twice", func(t *testing.T) {
 		// ensure that the last item of items with the same db keys
 		// is actually saved
-		batch := new(leveldb.Batch)
+		batch := new(kv.Batch)
 		address := []byte("put-in-batch-twice-hash")
 
 		// put the first item
@@ -234,7 +234,7 @@ func TestIndex(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		wantErr := leveldb.ErrNotFound
+		wantErr := kv.ErrNotFound
 		_, err = index.Get(Item{
 			Address: want.Address,
 		})
@@ -262,7 +262,7 @@ func TestIndex(t *testing.T) {
 		}
 		checkItem(t, got, want)
 
-		batch := new(leveldb.Batch)
+		batch := new(kv.Batch)
 		err = index.DeleteInBatch(batch, Item{
 			Address: want.Address,
 		})
@@ -274,7 +274,7 @@ func TestIndex(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		wantErr := leveldb.ErrNotFound
+		wantErr := kv.ErrNotFound
 		_, err = index.Get(Item{
 			Address: want.Address,
 		})
@@ -337,7 +337,7 @@ func TestIndex(t *testing.T) {
 			items = append(items, Item{
 				Address: []byte("put-hash-missing"),
 			})
-			want := leveldb.ErrNotFound
+			want := kv.ErrNotFound
 			err := index.Fill(items)
 			if !errors.Is(err, want) {
 				t.Errorf("got error %v, want %v", err, want)
@@ -378,7 +378,7 @@ func TestIndex_Iterate(t *testing.T) {
 			Data:    []byte("data1"),
 		},
 	}
-	batch := new(leveldb.Batch)
+	batch := new(kv.Batch)
 	for _, i := range items {
 		err = index.PutInBatch(batch, i)
 		if err != nil {
@@ -560,7 +560,7 @@ func TestIndex_IterateReverse(t *testing.T) {
 			Data:    []byte("data1"),
 		},
 	}
-	batch := new(leveldb.Batch)
+	batch := new(kv.Batch)
 	for _, i := range items {
 		err = index.PutInBatch(batch, i)
 		if err != nil {
@@ -773,7 +773,7 @@ func TestIndex_Iterate_withPrefix(t *testing.T) {
 		{Address: []byte("want-hash-09"), Data: []byte("data89")},
 		{Address: []byte("skip-hash-10"), Data: []byte("data90")},
 	}
-	batch := new(leveldb.Batch)
+	batch := new(kv.Batch)
 	for _, i := range allItems {
 		err = index.PutInBatch(batch, i)
 		if err != nil {
@@ -959,7 +959,7 @@ func TestIndex_IterateReverse_withPrefix(t *testing.T) {
 		{Address: []byte("want-hash-09"), Data: []byte("data89")},
 		{Address: []byte("z-skip-hash-10"), Data: []byte("data90")},
 	}
-	batch := new(leveldb.Batch)
+	batch := new(kv.Batch)
 	for _, i := range allItems {
 		err = index.PutInBatch(batch, i)
 		if err != nil {
@@ -1164,7 +1164,7 @@ func TestIndex_count(t *testing.T) {
 			Data:    []byte("data1"),
 		},
 	}
-	batch := new(leveldb.Batch)
+	batch := new(kv.Batch)
 	for _, i := range items {
 		err = index.PutInBatch(batch, i)
 		if err != nil {
@@ -1336,7 +1336,7 @@ func TestIndex_firstAndLast(t *testing.T) {
 		return bytes.Compare(addrs[i], addrs[j]) == -1
 	})
 
-	batch := new(leveldb.Batch)
+	batch := new(kv.Batch)
 	for _, addr := range addrs {
 		err = index.PutInBatch(batch, Item{
 			Address: addr,
@@ -1398,11 +1398,11 @@ func TestIndex_firstAndLast(t *testing.T) {
 		},
 		{
 			prefix: []byte{0, 3},
-			err:    leveldb.ErrNotFound,
+			err:    kv.ErrNotFound,
 		},
 		{
 			prefix: []byte{222},
-			err:    leveldb.ErrNotFound,
+			err:    kv.ErrNotFound,
 		},
 	} {
 		got, err := index.Last(tc.prefix)
@@ -1482,7 +1482,7 @@ func TestIndex_HasMulti(t *testing.T) {
 		Data:    []byte("data0"),
 	}
 
-	batch := new(leveldb.Batch)
+	batch := new(kv.Batch)
 	for _, i := range items {
 		err = index.PutInBatch(batch, i)
 		if err != nil {
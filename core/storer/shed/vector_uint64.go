@@ -5,7 +5,7 @@ import (
 	"errors"
 	"fmt"
 
-	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/redesblock/mop/core/storer/kv"
 )
 
 // Uint64Vector provides a way to have multiple counters in the database.
@@ -34,7 +34,7 @@ func (db *DB) NewUint64Vector(name string) (f Uint64Vector, err error) {
 func (f Uint64Vector) Get(i uint64) (val uint64, err error) {
 	b, err := f.db.Get(f.indexKey(i))
 	if err != nil {
-		if errors.Is(err, leveldb.ErrNotFound) {
+		if errors.Is(err, kv.ErrNotFound) {
 			return 0, nil
 		}
 		return 0, err
@@ -49,7 +49,7 @@ func (f Uint64Vector) Put(i, val uint64) (err error) {
 
 // PutInBatch stores a uint64 value at index i in a batch
 // that can be saved later in the database.
-func (f Uint64Vector) PutInBatch(batch *leveldb.Batch, i, val uint64) {
+func (f Uint64Vector) PutInBatch(batch *kv.Batch, i, val uint64) {
 	batch.Put(f.indexKey(i), encodeUint64(val))
 }
 
@@ -58,7 +58,7 @@ func (f Uint64Vector) PutInBatch(batch *leveldb.Batch, i, val uint64) {
 func (f Uint64Vector) Inc(i uint64) (val uint64, err error) {
 	val, err = f.Get(i)
 	if err != nil {
-		if errors.Is(err, leveldb.ErrNotFound) {
+		if errors.Is(err, kv.ErrNotFound) {
 			val = 0
 		} else {
 			return 0, err
@@ -71,10 +71,10 @@ func (f Uint64Vector) Inc(i uint64) (val uint64, err error) {
 // IncInBatch increments a uint64 value at index i in the batch
 // by retreiving a value from the database, not the same batch.
 // This operation is not goroutine safe.
-func (f Uint64Vector) IncInBatch(batch *leveldb.Batch, i uint64) (val uint64, err error) {
+func (f Uint64Vector) IncInBatch(batch *kv.Batch, i uint64) (val uint64, err error) {
 	val, err = f.Get(i)
 	if err != nil {
-		if errors.Is(err, leveldb.ErrNotFound) {
+		if errors.Is(err, kv.ErrNotFound) {
 			val = 0
 		} else {
 			return 0, err
@@ -91,7 +91,7 @@ func (f Uint64Vector) IncInBatch(batch *leveldb.Batch, i uint64) (val uint64, er
 func (f Uint64Vector) Dec(i uint64) (val uint64, err error) {
 	val, err = f.Get(i)
 	if err != nil {
-		if errors.Is(err, leveldb.ErrNotFound) {
+		if errors.Is(err, kv.ErrNotFound) {
 			val = 0
 		} else {
 			return 0, err
@@ -107,10 +107,10 @@ func (f Uint64Vector) Dec(i uint64) (val uint64, err error) {
 // by retreiving a value from the database, not the same batch.
 // This operation is not goroutine safe.
 // The field is protected from overflow to a negative value.
-func (f Uint64Vector) DecInBatch(batch *leveldb.Batch, i uint64) (val uint64, err error) {
+func (f Uint64Vector) DecInBatch(batch *kv.Batch, i uint64) (val uint64, err error) {
 	val, err = f.Get(i)
 	if err != nil {
-		if errors.Is(err, leveldb.ErrNotFound) {
+		if errors.Is(err, kv.ErrNotFound) {
 			val = 0
 		} else {
 			return 0, err
@@ -3,7 +3,7 @@ package shed
 import (
 	"testing"
 
-	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/redesblock/mop/core/storer/kv"
 )
 
 // TestUint64Vector validates put and get operations
@@ -61,7 +61,7 @@ func TestUint64Vector(t *testing.T) {
 
 	t.Run("put in batch", func(t *testing.T) {
 		for _, index := range []uint64{0, 1, 2, 3, 5, 10} {
-			batch := new(leveldb.Batch)
+			batch := new(kv.Batch)
 			var want uint64 = 43 + index
 			bins.PutInBatch(batch, index, want)
 			err = db.WriteBatch(batch)
@@ -77,7 +77,7 @@ func TestUint64Vector(t *testing.T) {
 			}
 
 			t.Run("overwrite", func(t *testing.T) {
-				batch := new(leveldb.Batch)
+				batch := new(kv.Batch)
 				var want uint64 = 85 + index
 				bins.PutInBatch(batch, index, want)
 				err = db.WriteBatch(batch)
@@ -138,7 +138,7 @@ func TestUint64Vector_IncInBatch(t *testing.T) {
 	}
 
 	for _, index := range []uint64{0, 1, 2, 3, 5, 10} {
-		batch := new(leveldb.Batch)
+		batch := new(kv.Batch)
 		var want uint64 = 1
 		got, err := bins.IncInBatch(batch, index)
 		if err != nil {
@@ -159,7 +159,7 @@ func TestUint64Vector_IncInBatch(t *testing.T) {
 			t.Errorf("got %v uint64 %v, want %v", index, got, want)
 		}
 
-		batch2 := new(leveldb.Batch)
+		batch2 := new(kv.Batch)
 		want = 2
 		got, err = bins.IncInBatch(batch2, index)
 		if err != nil {
@@ -231,7 +231,7 @@ func TestUint64Vector_DecInBatch(t *testing.T) {
 	}
 
 	for _, index := range []uint64{0, 1, 2, 3, 5, 10} {
-		batch := new(leveldb.Batch)
+		batch := new(kv.Batch)
 		var want uint64
 		got, err := bins.DecInBatch(batch, index)
 		if err != nil {
@@ -252,7 +252,7 @@ func TestUint64Vector_DecInBatch(t *testing.T) {
 			t.Errorf("got %v uint64 %v, want %v", index, got, want)
 		}
 
-		batch2 := new(leveldb.Batch)
+		batch2 := new(kv.Batch)
 		want = 42 + index
 		bins.PutInBatch(batch2, index, want)
 		err = db.WriteBatch(batch2)
@@ -267,7 +267,7 @@ func TestUint64Vector_DecInBatch(t *testing.T) {
 			t.Errorf("got %v uint64 %v, want %v", index, got, want)
 		}
 
-		batch3 := new(leveldb.Batch)
+		batch3 := new(kv.Batch)
 		want = 41 + index
 		got, err = bins.DecInBatch(batch3, index)
 		if err != nil {
@@ -7,14 +7,8 @@ import (
 	"fmt"
 
 	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/storer/kv"
 	"github.com/redesblock/mop/core/storer/storage"
-	"github.com/syndtr/goleveldb/leveldb"
-	ldberr "github.com/syndtr/goleveldb/leveldb/errors"
-
-	ldb "github.com/syndtr/goleveldb/leveldb"
-	ldbs "github.com/syndtr/goleveldb/leveldb/storage"
-
-	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 // loggerName is the tree path name of the logger for this package.
@@ -22,20 +16,20 @@ const loggerName = "leveldb"
 
 var _ storage.StateStorer = (*Store)(nil)
 
-// Store uses LevelDB to store values.
+// Store uses a key-value store, LevelDB by default, to store values.
 type Store struct {
-	db     *leveldb.DB
+	db     kv.Store
 	logger log.Logger
 }
 
 func NewInMemoryStateStore(l log.Logger) (*Store, error) {
-	ldb, err := ldb.Open(ldbs.NewMemStorage(), nil)
+	db, err := kv.Open("", nil)
 	if err != nil {
 		return nil, err
 	}
 
 	s := &Store{
-		db:     ldb,
+		db:     db,
 		logger: l.WithName(loggerName).Register(),
 	}
 
@@ -48,16 +42,24 @@ func NewInMemoryStateStore(l log.Logger) (*Store, error) {
 
 // NewStateStore creates a new persistent state storage.
 func NewStateStore(path string, l log.Logger) (*Store, error) {
+	return NewStateStoreWithBackend(path, "", l)
+}
+
+// NewStateStoreWithBackend creates a new persistent state storage which
+// uses the given key-value store backend if the storage does not exist.
+// Existing storage keeps its backend.
+func NewStateStoreWithBackend(path, backend string, l log.Logger) (*Store, error) {
 	l = l.WithName(loggerName).Register()
 
-	db, err := leveldb.OpenFile(path, nil)
+	o := &kv.Options{Backend: backend}
+	db, err := kv.Open(path, o)
 	if err != nil {
-		if !ldberr.IsCorrupted(err) {
+		if !kv.IsCorrupted(err) {
 			return nil, err
 		}
 
 		l.Warning("statestore open failed, attempting recovery", "error", err)
-		db, err = leveldb.RecoverFile(path, nil)
+		db, err = kv.Recover(path, o)
 		if err != nil {
 			return nil, fmt.Errorf("statestore recovery: %w", err)
 		}
@@ -102,9 +104,9 @@ func migrate(s *Store) error {
 // Get retrieves a value of the requested key. If no results are found,
 // storage.ErrNotFound will be returned.
 func (s *Store) Get(key string, i interface{}) error {
-	data, err := s.db.Get([]byte(key), nil)
+	data, err := s.db.Get([]byte(key))
 	if err != nil {
-		if errors.Is(err, leveldb.ErrNotFound) {
+		if errors.Is(err, kv.ErrNotFound) {
 			return storage.ErrNotFound
 		}
 		return err
@@ -130,17 +132,17 @@ func (s *Store) Put(key string, i interface{}) (err error) {
 		return err
 	}
 
-	return s.db.Put([]byte(key), bytes, nil)
+	return s.db.Put([]byte(key), bytes)
 }
 
 // Delete removes entries stored under a specific key.
 func (s *Store) Delete(key string) (err error) {
-	return s.db.Delete([]byte(key), nil)
+	return s.db.Delete([]byte(key))
 }
 
 // Iterate entries that match the supplied prefix.
 func (s *Store) Iterate(prefix string, iterFunc storage.StateIterFunc) (err error) {
-	iter := s.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+	iter := s.db.NewIterator(kv.PrefixRange([]byte(prefix)))
 	defer iter.Release()
 	for iter.Next() {
 		stop, err := iterFunc(append([]byte(nil), iter.Key()...), append([]byte(nil), iter.Value()...))
@@ -155,9 +157,9 @@ func (s *Store) Iterate(prefix string, iterFunc storage.StateIterFunc) (err erro
 }
 
 func (s *Store) getSchemaName() (string, error) {
-	name, err := s.db.Get([]byte(dbSchemaKey), nil)
+	name, err := s.db.Get([]byte(dbSchemaKey))
 	if err != nil {
-		if errors.Is(err, leveldb.ErrNotFound) {
+		if errors.Is(err, kv.ErrNotFound) {
 			return "", storage.ErrNotFound
 		}
 		return "", err
@@ -166,11 +168,11 @@ func (s *Store) getSchemaName() (string, error) {
 }
 
 func (s *Store) putSchemaName(val string) error {
-	return s.db.Put([]byte(dbSchemaKey), []byte(val), nil)
+	return s.db.Put([]byte(dbSchemaKey), []byte(val))
 }
 
 // DB implements StateStorer.DB method.
-func (s *Store) DB() *leveldb.DB {
+func (s *Store) DB() kv.Store {
 	return s.db
 }
 
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/storer/kv"
 	"github.com/redesblock/mop/core/storer/statestore/leveldb"
 	"github.com/redesblock/mop/core/storer/statestore/test"
 	"github.com/redesblock/mop/core/storer/storage"
@@ -36,6 +37,31 @@ func TestPersistentStateStore(t *testing.T) {
 	})
 }
 
+func TestPersistentStateStoreBackend(t *testing.T) {
+	test.Run(t, func(t *testing.T) storage.StateStorer {
+		store, err := leveldb.NewStateStoreWithBackend(t.TempDir(), kv.BackendPebble, log.Noop)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			if err := store.Close(); err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		return store
+	})
+
+	test.RunPersist(t, func(t *testing.T, dir string) storage.StateStorer {
+		store, err := leveldb.NewStateStoreWithBackend(dir, kv.BackendPebble, log.Noop)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return store
+	})
+}
+
 func TestGetSchemaName(t *testing.T) {
 	dir := t.TempDir()
 
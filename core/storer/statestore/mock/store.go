@@ -7,8 +7,8 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/redesblock/mop/core/storer/kv"
 	"github.com/redesblock/mop/core/storer/storage"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 var _ storage.StateStorer = (*store)(nil)
@@ -97,7 +97,7 @@ func (s *store) Iterate(prefix string, iterFunc storage.StateIterFunc) (err erro
 }
 
 // DB implements StateStorer.DB method.
-func (s *store) DB() *leveldb.DB {
+func (s *store) DB() kv.Store {
 	return nil
 }
 
@@ -136,7 +136,15 @@ func (m *MockStorer) Has(ctx context.Context, addr cluster.Address) (yes bool, e
 }
 
 func (m *MockStorer) HasMulti(ctx context.Context, addrs ...cluster.Address) (yes []bool, err error) {
-	panic("not implemented") // TODO: Implement
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	yes = make([]bool, len(addrs))
+	for i, addr := range addrs {
+		if yes[i], err = m.has(ctx, addr); err != nil {
+			return nil, err
+		}
+	}
+	return yes, nil
 }
 
 func (m *MockStorer) Set(ctx context.Context, mode storage.ModeSet, addrs ...cluster.Address) (err error) {
@@ -9,7 +9,7 @@ import (
 	"io"
 
 	"github.com/redesblock/mop/core/cluster"
-	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/redesblock/mop/core/storer/kv"
 )
 
 var (
@@ -171,7 +171,7 @@ type StateStorer interface {
 	Delete(key string) (err error)
 	Iterate(prefix string, iterFunc StateIterFunc) (err error)
 	// DB returns the underlying DB storage.
-	DB() *leveldb.DB
+	DB() kv.Store
 	io.Closer
 }
 
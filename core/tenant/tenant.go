@@ -0,0 +1,228 @@
+// Package tenant provides the namespacing of the API objects per tenant.
+// A tenant is bound to the security tokens issued for its credential on the
+// restricted API and owns the tags, pins and batches it creates, which other
+// tenants can neither list nor use. Requests of administrators are not bound
+// to a tenant and reach all the objects.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/redesblock/mop/core/storer/storage"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Kinds of the owned objects.
+const (
+	KindTag   = "tag"
+	KindPin   = "pin"
+	KindBatch = "batch"
+)
+
+const (
+	keysPrefix        = "tenant-keys/"
+	ownersPrefix      = "tenant-owners/"
+	credentialsPrefix = "tenant-credentials/"
+)
+
+// ErrInvalidName is returned for tenant names that can not be used.
+var ErrInvalidName = errors.New("invalid tenant name")
+
+type tenantKey struct{}
+
+// WithContext returns a context of requests bound to the tenant. The empty
+// tenant is the one of anonymous requests, which only reach the objects
+// without an owner.
+func WithContext(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// FromContext returns the tenant the requests with the context are bound
+// to, and false if they are not bound to any.
+func FromContext(ctx context.Context) (tenant string, ok bool) {
+	tenant, ok = ctx.Value(tenantKey{}).(string)
+	return tenant, ok
+}
+
+// ValidName checks that the name can be used as a tenant.
+func ValidName(name string) error {
+	if name == "" || len(name) > 64 || strings.Contains(name, "/") {
+		return ErrInvalidName
+	}
+	return nil
+}
+
+// Registry keeps the credentials of the tenants and the owners of the
+// objects in the state store.
+type Registry struct {
+	store storage.StateStorer
+	mu    sync.Mutex
+}
+
+// New returns a new registry of the owners of the objects.
+func New(store storage.StateStorer) *Registry {
+	return &Registry{store: store}
+}
+
+// SetCredential sets the password of the tenant, which is kept as a bcrypt
+// hash.
+func (r *Registry) SetCredential(tenant, password string) error {
+	if err := ValidName(tenant); err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return r.store.Put(credentialsPrefix+tenant, hash)
+}
+
+// RemoveCredential removes the password of the tenant, so that no security
+// tokens are issued for it anymore.
+func (r *Registry) RemoveCredential(tenant string) error {
+	return r.store.Delete(credentialsPrefix + tenant)
+}
+
+// Authorize reports whether the password is the credential of the tenant.
+func (r *Registry) Authorize(tenant, password string) (bool, error) {
+	if ValidName(tenant) != nil {
+		return false, nil
+	}
+	var hash []byte
+	switch err := r.store.Get(credentialsPrefix+tenant, &hash); {
+	case errors.Is(err, storage.ErrNotFound):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil, nil
+}
+
+// Add records the tenant as an owner of the object of the kind with the key.
+func (r *Registry) Add(kind, tenant, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.store.Put(keysKey(kind, tenant, key), true); err != nil {
+		return err
+	}
+	return r.store.Put(ownersKey(kind, key, tenant), true)
+}
+
+// Remove removes the tenant from the owners of the object and reports
+// whether the object has no owner left.
+func (r *Registry) Remove(kind, tenant, key string) (last bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.remove(kind, tenant, key); err != nil {
+		return false, err
+	}
+	owners, err := r.owners(kind, key)
+	if err != nil {
+		return false, err
+	}
+	return len(owners) == 0, nil
+}
+
+// RemoveAll removes all the owners of the object.
+func (r *Registry) RemoveAll(kind, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	owners, err := r.owners(kind, key)
+	if err != nil {
+		return err
+	}
+	for _, tenant := range owners {
+		if err := r.remove(kind, tenant, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Owns reports whether the tenant owns the object. The empty tenant owns
+// the objects without an owner.
+func (r *Registry) Owns(kind, tenant, key string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	owners, err := r.owners(kind, key)
+	if err != nil {
+		return false, err
+	}
+	if tenant == "" && len(owners) == 0 {
+		return true, nil
+	}
+	for _, o := range owners {
+		if o == tenant {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Owners returns the sorted owners of the object.
+func (r *Registry) Owners(kind, key string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.owners(kind, key)
+}
+
+// Keys returns the sorted keys of the objects of the kind owned by the tenant.
+func (r *Registry) Keys(kind, tenant string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prefix := keysKey(kind, tenant, "")
+	var keys []string
+	err := r.store.Iterate(prefix, func(k, _ []byte) (bool, error) {
+		key := strings.TrimPrefix(string(k), prefix)
+		if key != string(k) && !strings.Contains(key, "/") {
+			keys = append(keys, key)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (r *Registry) owners(kind, key string) ([]string, error) {
+	prefix := ownersKey(kind, key, "")
+	var owners []string
+	err := r.store.Iterate(prefix, func(k, _ []byte) (bool, error) {
+		if tenant := strings.TrimPrefix(string(k), prefix); tenant != string(k) {
+			owners = append(owners, tenant)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(owners)
+	return owners, nil
+}
+
+func (r *Registry) remove(kind, tenant, key string) error {
+	if err := r.store.Delete(keysKey(kind, tenant, key)); err != nil {
+		return err
+	}
+	return r.store.Delete(ownersKey(kind, key, tenant))
+}
+
+func keysKey(kind, tenant, key string) string {
+	return keysPrefix + kind + "/" + tenant + "/" + key
+}
+
+func ownersKey(kind, key, tenant string) string {
+	return ownersPrefix + kind + "/" + key + "/" + tenant
+}
@@ -0,0 +1,151 @@
+package tenant_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	statestore "github.com/redesblock/mop/core/storer/statestore/mock"
+	"github.com/redesblock/mop/core/tenant"
+)
+
+func TestRegistry(t *testing.T) {
+	r := tenant.New(statestore.NewStateStore())
+
+	for _, o := range []struct{ tenant, key string }{
+		{"a", "1"},
+		{"a", "2"},
+		{"a.b", "3"},
+		{"b", "2"},
+	} {
+		if err := r.Add(tenant.KindTag, o.tenant, o.key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Add(tenant.KindPin, "a", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := r.Keys(tenant.KindTag, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"1", "2"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("got keys %v, want %v", keys, want)
+	}
+
+	owners, err := r.Owners(tenant.KindTag, "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(owners, want) {
+		t.Fatalf("got owners %v, want %v", owners, want)
+	}
+
+	for _, tc := range []struct {
+		tenant, key string
+		want        bool
+	}{
+		{"a", "1", true},
+		{"b", "1", false},
+		{"", "1", false},
+		{"", "4", true},
+	} {
+		owns, err := r.Owns(tenant.KindTag, tc.tenant, tc.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if owns != tc.want {
+			t.Fatalf("tenant %q owns %q: got %v, want %v", tc.tenant, tc.key, owns, tc.want)
+		}
+	}
+
+	last, err := r.Remove(tenant.KindTag, "a", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last {
+		t.Fatal("removed the last owner of an object owned by two tenants")
+	}
+	if last, err = r.Remove(tenant.KindTag, "b", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if !last {
+		t.Fatal("owners left after removing the last one")
+	}
+
+	if err := r.RemoveAll(tenant.KindTag, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if keys, err = r.Keys(tenant.KindTag, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 {
+		t.Fatalf("got keys %v, want none", keys)
+	}
+
+	// objects of other kinds are not affected
+	if keys, err = r.Keys(tenant.KindPin, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"1"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("got keys %v, want %v", keys, want)
+	}
+}
+
+func TestContext(t *testing.T) {
+	if _, ok := tenant.FromContext(context.Background()); ok {
+		t.Fatal("background context bound to a tenant")
+	}
+	got, ok := tenant.FromContext(tenant.WithContext(context.Background(), "a"))
+	if !ok || got != "a" {
+		t.Fatalf("got tenant %q, want %q", got, "a")
+	}
+}
+
+func TestValidName(t *testing.T) {
+	for _, name := range []string{"", "a/b", string(make([]byte, 65))} {
+		if err := tenant.ValidName(name); err == nil {
+			t.Fatalf("name %q is valid", name)
+		}
+	}
+	if err := tenant.ValidName("business-unit.1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	r := tenant.New(statestore.NewStateStore())
+
+	if err := r.SetCredential("a/b", "secret"); err == nil {
+		t.Fatal("expected error for an invalid tenant name")
+	}
+	if err := r.SetCredential("a", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		tenant, password string
+		want             bool
+	}{
+		{"a", "secret", true},
+		{"a", "wrong", false},
+		{"b", "secret", false},
+		{"", "secret", false},
+	} {
+		got, err := r.Authorize(tc.tenant, tc.password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Fatalf("got authorized %v for tenant %q and password %q, want %v", got, tc.tenant, tc.password, tc.want)
+		}
+	}
+
+	if err := r.RemoveCredential("a"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := r.Authorize("a", "secret"); err != nil || got {
+		t.Fatalf("got authorized %v (error %v) after the credential is removed", got, err)
+	}
+}
@@ -0,0 +1,51 @@
+package traffic
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	m "github.com/redesblock/mop/core/metrics"
+)
+
+type metrics struct {
+	// all metrics fields must be exported
+	// to be able to return them by Metrics()
+	// using reflection
+	Reports   prometheus.Counter
+	Delivered prometheus.Counter
+	Rejected  prometheus.Counter
+	Failures  prometheus.Counter
+}
+
+func newMetrics() metrics {
+	subsystem := "traffic"
+
+	return metrics{
+		Reports: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "reports_count",
+			Help:      "Number of spooled traffic reports.",
+		}),
+		Delivered: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "delivered_count",
+			Help:      "Number of delivered traffic reports.",
+		}),
+		Rejected: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "rejected_count",
+			Help:      "Number of traffic reports rejected by the billing service.",
+		}),
+		Failures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "failures_count",
+			Help:      "Number of failed traffic report deliveries.",
+		}),
+	}
+}
+
+func (e *Exporter) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(e.metrics)
+}
@@ -0,0 +1,423 @@
+// Package traffic provides the export of the hourly API traffic reports to
+// a central billing service. The reports are signed with the node key,
+// spooled to the state store until delivered and retried with backoff.
+package traffic
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/redesblock/mop/core/crypto"
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/storer/storage"
+)
+
+// loggerName is the tree path name of the logger for this package.
+const loggerName = "traffic"
+
+const (
+	// DefaultMinBackoff is the default time before the first retry.
+	DefaultMinBackoff = 5 * time.Second
+	// DefaultMaxBackoff is the default maximal time between two retries.
+	DefaultMaxBackoff = 30 * time.Minute
+
+	requestTimeout = 30 * time.Second
+
+	spoolPrefix    = "traffic-report-"
+	rejectedPrefix = "traffic-rejected-"
+)
+
+var (
+	// ErrInvalidSignature is returned when the report is not signed by its address.
+	ErrInvalidSignature = errors.New("invalid signature")
+	// errRejected is returned when the billing service refuses the report.
+	errRejected = errors.New("report rejected")
+)
+
+// Report is the API traffic of a node in an hour by API key.
+type Report struct {
+	Timestamp     int64            `json:"timestamp"`
+	Address       string           `json:"address"`
+	UploadedCnt   map[string]int64 `json:"uploaded_cnt"`
+	Uploaded      map[string]int64 `json:"uploaded"`
+	DownloadedCnt map[string]int64 `json:"downloaded_cnt"`
+	Downloaded    map[string]int64 `json:"downloaded"`
+	Signed        string           `json:"signed"`
+	NATAddr       string           `json:"nat_addr"`
+}
+
+// signedData returns the data of the report which is signed.
+func (r Report) signedData() ([]byte, error) {
+	r.Signed = ""
+	return json.Marshal(r)
+}
+
+// Sign sets the signature of the report by the signer.
+func (r *Report) Sign(signer crypto.Signer) error {
+	data, err := r.signedData()
+	if err != nil {
+		return err
+	}
+	sig, err := signer.Sign(data)
+	if err != nil {
+		return err
+	}
+	r.Signed = hex.EncodeToString(sig)
+	return nil
+}
+
+// Verify checks that the report is signed by the key of its address.
+func (r *Report) Verify() error {
+	sig, err := hex.DecodeString(r.Signed)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	data, err := r.signedData()
+	if err != nil {
+		return err
+	}
+	pub, err := crypto.Recover(sig, data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	addr, err := crypto.NewBSCAddress(*pub)
+	if err != nil {
+		return err
+	}
+	if common.BytesToAddress(addr) != common.HexToAddress(r.Address) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Options configure the exporter.
+type Options struct {
+	// URL is the endpoint of the billing service the reports are posted to.
+	URL string
+	// CAFiles are PEM files of the certificate authorities trusted in
+	// addition to the system ones to verify the billing service.
+	CAFiles []string
+	// MinBackoff is the time before the first retry.
+	MinBackoff time.Duration
+	// MaxBackoff is the maximal time between two retries.
+	MaxBackoff time.Duration
+}
+
+// Status is the delivery status of the exporter.
+type Status struct {
+	// Pending is the number of spooled reports.
+	Pending int `json:"pending"`
+	// Delivered is the number of reports delivered since the start.
+	Delivered uint64 `json:"delivered"`
+	// Rejected is the number of reports refused by the billing service
+	// which are kept in the state store.
+	Rejected int `json:"rejected"`
+	// Failures is the number of consecutive failed delivery attempts.
+	Failures     uint64    `json:"failures"`
+	LastError    string    `json:"lastError,omitempty"`
+	LastAttempt  time.Time `json:"lastAttempt"`
+	LastDelivery time.Time `json:"lastDelivery"`
+	NextAttempt  time.Time `json:"nextAttempt"`
+}
+
+// Exporter signs the traffic reports, spools them to the state store and
+// delivers them to the billing service.
+type Exporter struct {
+	signer  crypto.Signer
+	store   storage.StateStorer
+	client  *http.Client
+	url     string
+	logger  log.Logger
+	metrics metrics
+
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu     sync.Mutex // guards status and the spool keys
+	status Status
+	seq    uint64
+
+	trigger chan struct{}
+	quit    chan struct{}
+	stopped chan struct{}
+}
+
+// New returns a new exporter delivering the reports to the URL of the options.
+func New(signer crypto.Signer, store storage.StateStorer, logger log.Logger, o Options) (*Exporter, error) {
+	client, err := newClient(o.CAFiles)
+	if err != nil {
+		return nil, err
+	}
+	if o.MinBackoff <= 0 {
+		o.MinBackoff = DefaultMinBackoff
+	}
+	if o.MaxBackoff < o.MinBackoff {
+		o.MaxBackoff = DefaultMaxBackoff
+		if o.MaxBackoff < o.MinBackoff {
+			o.MaxBackoff = o.MinBackoff
+		}
+	}
+
+	e := &Exporter{
+		signer:     signer,
+		store:      store,
+		client:     client,
+		url:        o.URL,
+		logger:     logger.WithName(loggerName).Register(),
+		metrics:    newMetrics(),
+		minBackoff: o.MinBackoff,
+		maxBackoff: o.MaxBackoff,
+		trigger:    make(chan struct{}, 1),
+		quit:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+
+	go e.manage()
+
+	return e, nil
+}
+
+// newClient returns an HTTP client verifying the server certificates with
+// the system certificate authorities and the ones in the CA files.
+func newClient(caFiles []string) (*http.Client, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(caFiles) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, f := range caFiles {
+			pem, err := os.ReadFile(f)
+			if err != nil {
+				return nil, fmt.Errorf("read ca file: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates in ca file %s", f)
+			}
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{
+		Timeout:   requestTimeout,
+		Transport: transport,
+	}, nil
+}
+
+// Export signs the report and spools it for the delivery.
+func (e *Exporter) Export(r *Report) error {
+	if err := r.Sign(e.signer); err != nil {
+		return fmt.Errorf("sign report: %w", err)
+	}
+
+	e.mu.Lock()
+	e.seq++
+	key := fmt.Sprintf("%s%016x-%016x-%08x", spoolPrefix, r.Timestamp, time.Now().UnixNano(), e.seq)
+	err := e.store.Put(key, r)
+	e.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("spool report: %w", err)
+	}
+	e.metrics.Reports.Inc()
+
+	select {
+	case e.trigger <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (e *Exporter) manage() {
+	defer close(e.stopped)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-e.quit
+		cancel()
+	}()
+
+	var backoff time.Duration
+	for {
+		err := e.deliver(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			backoff *= 2
+			if backoff < e.minBackoff {
+				backoff = e.minBackoff
+			}
+			if backoff > e.maxBackoff {
+				backoff = e.maxBackoff
+			}
+			e.logger.Debug("deliver traffic reports failed", "error", err, "retry_in", backoff)
+			e.mu.Lock()
+			e.status.NextAttempt = time.Now().Add(backoff)
+			e.mu.Unlock()
+
+			// new reports are spooled and delivered after the backoff
+			select {
+			case <-e.quit:
+				return
+			case <-time.After(backoff):
+			}
+			continue
+		}
+
+		backoff = 0
+		select {
+		case <-e.quit:
+			return
+		case <-e.trigger:
+		}
+	}
+}
+
+// deliver posts the spooled reports in order until a delivery fails.
+func (e *Exporter) deliver(ctx context.Context) error {
+	keys, err := e.keys(spoolPrefix)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		r := new(Report)
+		if err := e.store.Get(key, r); err != nil {
+			return fmt.Errorf("get spooled report: %w", err)
+		}
+
+		err := e.post(ctx, r)
+		e.mu.Lock()
+		e.status.LastAttempt = time.Now()
+		e.mu.Unlock()
+
+		switch {
+		case err == nil:
+			if err := e.store.Delete(key); err != nil {
+				return fmt.Errorf("delete spooled report: %w", err)
+			}
+			e.metrics.Delivered.Inc()
+			e.mu.Lock()
+			e.status.Delivered++
+			e.status.Failures = 0
+			e.status.LastError = ""
+			e.status.LastDelivery = e.status.LastAttempt
+			e.status.NextAttempt = time.Time{}
+			e.mu.Unlock()
+		case errors.Is(err, errRejected):
+			// retrying a refused report would block the ones after it
+			e.logger.Warning("traffic report rejected", "timestamp", r.Timestamp, "error", err)
+			if err := e.store.Put(rejectedPrefix+strings.TrimPrefix(key, spoolPrefix), r); err != nil {
+				return fmt.Errorf("keep rejected report: %w", err)
+			}
+			if err := e.store.Delete(key); err != nil {
+				return fmt.Errorf("delete spooled report: %w", err)
+			}
+			e.metrics.Rejected.Inc()
+			e.mu.Lock()
+			e.status.LastError = err.Error()
+			e.mu.Unlock()
+		default:
+			e.metrics.Failures.Inc()
+			e.mu.Lock()
+			e.status.Failures++
+			e.status.LastError = err.Error()
+			e.mu.Unlock()
+			return err
+		}
+	}
+	return nil
+}
+
+// post sends the report to the billing service.
+func (e *Exporter) post(ctx context.Context, r *Report) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %s", errRejected, resp.Status)
+	}
+	return fmt.Errorf("unexpected response: %s", resp.Status)
+}
+
+// keys returns the sorted state store keys with the prefix.
+func (e *Exporter) keys(prefix string) ([]string, error) {
+	var keys []string
+	err := e.store.Iterate(prefix, func(key, _ []byte) (bool, error) {
+		if !strings.HasPrefix(string(key), prefix) {
+			return true, nil
+		}
+		keys = append(keys, string(key))
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Status returns the delivery status.
+func (e *Exporter) Status() (Status, error) {
+	pending, err := e.keys(spoolPrefix)
+	if err != nil {
+		return Status{}, err
+	}
+	rejected, err := e.keys(rejectedPrefix)
+	if err != nil {
+		return Status{}, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s := e.status
+	s.Pending = len(pending)
+	s.Rejected = len(rejected)
+	return s, nil
+}
+
+// Close stops the delivery. The spooled reports are delivered after a restart.
+func (e *Exporter) Close() error {
+	close(e.quit)
+	select {
+	case <-e.stopped:
+		return nil
+	case <-time.After(5 * time.Second):
+		return errors.New("stopping traffic exporter with ongoing worker goroutine")
+	}
+}
@@ -0,0 +1,184 @@
+package traffic_test
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redesblock/mop/core/crypto"
+	"github.com/redesblock/mop/core/log"
+	statestore "github.com/redesblock/mop/core/storer/statestore/mock"
+	"github.com/redesblock/mop/core/traffic"
+)
+
+func TestExporter(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		reports  []traffic.Report
+		failures = 2
+	)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var report traffic.Report
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if report.Timestamp == 0 {
+			// refused by the billing service
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		reports = append(reports, report)
+	}))
+	defer srv.Close()
+
+	signer, addr := newSigner(t)
+	store := statestore.NewStateStore()
+	e, err := traffic.New(signer, store, log.Noop, traffic.Options{
+		URL:        srv.URL,
+		CAFiles:    []string{writeCA(t, srv)},
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: 20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	for _, ts := range []int64{3600, 0, 7200} {
+		if err := e.Export(&traffic.Report{
+			Timestamp:  ts,
+			Address:    addr,
+			Downloaded: map[string]int64{"key": 100},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var status traffic.Status
+	for i := 0; ; i++ {
+		if status, err = e.Status(); err != nil {
+			t.Fatal(err)
+		}
+		if status.Pending == 0 {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("got %d pending reports, want none", status.Pending)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if status.Delivered != 2 || status.Rejected != 1 || status.Failures != 0 {
+		t.Fatalf("got status %+v, want 2 delivered and 1 rejected report", status)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reports) != 2 || reports[0].Timestamp != 3600 || reports[1].Timestamp != 7200 {
+		t.Fatalf("got reports %+v, want the reports of 3600 and 7200 in order", reports)
+	}
+	for _, r := range reports {
+		if err := r.Verify(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reports[0].Downloaded["key"]++
+	if err := reports[0].Verify(); !errors.Is(err, traffic.ErrInvalidSignature) {
+		t.Fatalf("got error %v, want %v", err, traffic.ErrInvalidSignature)
+	}
+}
+
+// TestExporterUntrusted checks that the reports are not delivered to a
+// server with a certificate of an unknown authority and remain spooled
+// after a restart.
+func TestExporterUntrusted(t *testing.T) {
+	delivered := make(chan struct{}, 1)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case delivered <- struct{}{}:
+		default:
+		}
+	}))
+	defer srv.Close()
+
+	signer, addr := newSigner(t)
+	store := statestore.NewStateStore()
+	o := traffic.Options{URL: srv.URL, MinBackoff: 10 * time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+	e, err := traffic.New(signer, store, log.Noop, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Export(&traffic.Report{Timestamp: 3600, Address: addr}); err != nil {
+		t.Fatal(err)
+	}
+
+	var status traffic.Status
+	for i := 0; status.Failures < 2; i++ {
+		if i == 100 {
+			t.Fatal("delivery did not fail")
+		}
+		time.Sleep(20 * time.Millisecond)
+		if status, err = e.Status(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if status.Pending != 1 || status.LastError == "" || status.NextAttempt.IsZero() {
+		t.Fatalf("got status %+v, want a pending report with an error", status)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	o.CAFiles = []string{writeCA(t, srv)}
+	e, err = traffic.New(signer, store, log.Noop, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	select {
+	case <-delivered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("spooled report not delivered after restart")
+	}
+}
+
+func newSigner(t *testing.T) (crypto.Signer, string) {
+	t.Helper()
+
+	key, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := crypto.NewDefaultSigner(key)
+	addr, err := signer.BSCAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer, addr.String()
+}
+
+func writeCA(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
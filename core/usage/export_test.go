@@ -0,0 +1,9 @@
+package usage
+
+import "time"
+
+// Expire removes the records which ended before the retention at the time now.
+func (s *Service) Expire(now time.Time) error {
+	s.now = func() time.Time { return now }
+	return s.expire()
+}
@@ -0,0 +1,70 @@
+package usage
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+type meterKey struct{}
+
+// Meter collects the usage of a single API request which is not known to
+// the API layer: the retrieval cost paid to the peers and the reference
+// of the uploaded content.
+type Meter struct {
+	cost uint64
+
+	mu        sync.Mutex
+	reference string
+}
+
+// Cost returns the retrieval cost in bookkeeper units.
+func (m *Meter) Cost() uint64 {
+	return atomic.LoadUint64(&m.cost)
+}
+
+// Reference returns the reference of the uploaded content.
+func (m *Meter) Reference() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.reference
+}
+
+// WithMeter returns a new context with a new meter.
+func WithMeter(ctx context.Context) (context.Context, *Meter) {
+	m := new(Meter)
+	return WithContext(ctx, m), m
+}
+
+// WithContext returns a new context with the meter. It is used to pass
+// the meter to contexts which are detached from the request context.
+func WithContext(ctx context.Context, m *Meter) context.Context {
+	if m == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, meterKey{}, m)
+}
+
+// FromContext returns the meter from the context, or nil if the context
+// has no meter.
+func FromContext(ctx context.Context) *Meter {
+	m, _ := ctx.Value(meterKey{}).(*Meter)
+	return m
+}
+
+// AddCost adds the retrieval cost to the meter of the context, if any.
+func AddCost(ctx context.Context, cost uint64) {
+	if m := FromContext(ctx); m != nil {
+		atomic.AddUint64(&m.cost, cost)
+	}
+}
+
+// SetReference sets the reference of the uploaded content to the meter
+// of the context, if any.
+func SetReference(ctx context.Context, reference string) {
+	if m := FromContext(ctx); m != nil {
+		m.mu.Lock()
+		m.reference = reference
+		m.mu.Unlock()
+	}
+}
@@ -0,0 +1,367 @@
+// Package usage provides the accounting of the content served and stored
+// by the API. The served bytes, the retrieval cost in bookkeeper units and
+// the stored bytes are attributed to root references and tenants and kept
+// in hourly records in the state store for the retention period.
+package usage
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/storer/storage"
+)
+
+// loggerName is the tree path name of the logger for this package.
+const loggerName = "usage"
+
+const (
+	// Period is the time span of a usage record.
+	Period = time.Hour
+	// DefaultFlushInterval is the default time between two writes of the
+	// collected usage to the state store.
+	DefaultFlushInterval = time.Minute
+	// DefaultRetention is the default time for which the usage records are
+	// kept in the state store.
+	DefaultRetention = 90 * 24 * time.Hour
+
+	keyPrefix = "usage-"
+)
+
+// Record is the usage of a root reference by a tenant in a period.
+type Record struct {
+	// Time is the start of the period.
+	Time      time.Time `json:"time"`
+	Reference string    `json:"reference"`
+	// Tenant is the tenant of the requests, empty for requests not bound to one.
+	Tenant   string `json:"tenant"`
+	Requests uint64 `json:"requests"`
+	// Served is the number of bytes sent in the responses.
+	Served uint64 `json:"served"`
+	// Stored is the number of bytes uploaded.
+	Stored uint64 `json:"stored"`
+	// Cost is the retrieval cost in bookkeeper units.
+	Cost uint64 `json:"cost"`
+}
+
+func (r *Record) add(o *Record) {
+	r.Requests += o.Requests
+	r.Served += o.Served
+	r.Stored += o.Stored
+	r.Cost += o.Cost
+}
+
+func recordKey(t time.Time, reference, tenant string) string {
+	return fmt.Sprintf("%s%016x-%s-%s", keyPrefix, t.Unix(), reference, tenant)
+}
+
+// recordTime returns the start of the period of the record from its key.
+func recordTime(key string) (time.Time, error) {
+	ts := strings.TrimPrefix(key, keyPrefix)
+	if i := strings.IndexByte(ts, '-'); i >= 0 {
+		ts = ts[:i]
+	}
+	sec, err := strconv.ParseInt(ts, 16, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("usage record key %s: %w", key, err)
+	}
+	return time.Unix(sec, 0).UTC(), nil
+}
+
+// Filter selects the usage records of a query by the start of their period.
+type Filter struct {
+	// From is the inclusive start of the time range, unbounded if zero.
+	From time.Time
+	// To is the exclusive end of the time range, unbounded if zero.
+	To time.Time
+	// Reference selects the records of a reference if not empty.
+	Reference string
+	// Tenant selects the records of a tenant if not empty.
+	Tenant string
+}
+
+func (f Filter) matchTime(t time.Time) bool {
+	switch {
+	case !f.From.IsZero() && t.Before(f.From):
+		return false
+	case !f.To.IsZero() && !t.Before(f.To):
+		return false
+	}
+	return true
+}
+
+func (f Filter) match(r *Record) bool {
+	switch {
+	case !f.matchTime(r.Time):
+		return false
+	case f.Reference != "" && f.Reference != r.Reference:
+		return false
+	case f.Tenant != "" && f.Tenant != r.Tenant:
+		return false
+	}
+	return true
+}
+
+// Service collects the usage records and periodically writes them to the
+// state store, removing the ones older than the retention.
+type Service struct {
+	store     storage.StateStorer
+	logger    log.Logger
+	retention time.Duration
+	now       func() time.Time
+
+	mu      sync.Mutex // guards pending
+	pending map[string]*Record
+
+	storeMu sync.Mutex // serializes the writes to the store
+
+	quit    chan struct{}
+	stopped chan struct{}
+}
+
+// New returns a new usage service writing the collected usage to the
+// state store every flush interval and keeping it for the retention.
+func New(store storage.StateStorer, logger log.Logger, flushInterval, retention time.Duration) *Service {
+	if flushInterval <= 0 {
+		flushInterval = DefaultFlushInterval
+	}
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	s := &Service{
+		store:     store,
+		logger:    logger.WithName(loggerName).Register(),
+		retention: retention,
+		now:       time.Now,
+		pending:   make(map[string]*Record),
+		quit:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+
+	go s.manage(flushInterval)
+
+	return s
+}
+
+func (s *Service) manage(interval time.Duration) {
+	defer close(s.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.Flush(); err != nil {
+			s.logger.Error(err, "flush usage records")
+		}
+		if err := s.expire(); err != nil {
+			s.logger.Error(err, "expire usage records")
+		}
+	}
+}
+
+// Add records a request served at the time t. The reference is the root
+// reference of the content and the tenant is the tenant of the request.
+func (s *Service) Add(t time.Time, reference, tenant string, served, stored, cost uint64) {
+	r := &Record{
+		Time:      t.UTC().Truncate(Period),
+		Reference: reference,
+		Tenant:    tenant,
+		Requests:  1,
+		Served:    served,
+		Stored:    stored,
+		Cost:      cost,
+	}
+	k := recordKey(r.Time, r.Reference, r.Tenant)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p, ok := s.pending[k]; ok {
+		p.add(r)
+		return
+	}
+	s.pending[k] = r
+}
+
+// Flush writes the collected usage to the state store. The pending records
+// are swapped out under the lock, so that the requests are not blocked by
+// the writes. The records which are not written are put back to pending,
+// so that they are not counted twice.
+func (s *Service) Flush() error {
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+
+	s.mu.Lock()
+	batch := s.pending
+	s.pending = make(map[string]*Record)
+	s.mu.Unlock()
+
+	for k, r := range batch {
+		if err := s.write(k, r); err != nil {
+			s.restore(batch)
+			return err
+		}
+		delete(batch, k)
+	}
+	return nil
+}
+
+// write adds the record to the one stored under the key.
+func (s *Service) write(k string, r *Record) error {
+	merged := *r
+	stored := new(Record)
+	switch err := s.store.Get(k, stored); {
+	case err == nil:
+		merged.add(stored)
+	case !errors.Is(err, storage.ErrNotFound):
+		return fmt.Errorf("get usage record %s: %w", k, err)
+	}
+	if err := s.store.Put(k, &merged); err != nil {
+		return fmt.Errorf("put usage record %s: %w", k, err)
+	}
+	return nil
+}
+
+// restore puts the records which are not written back to pending, adding
+// the ones collected in the meantime.
+func (s *Service) restore(batch map[string]*Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, r := range batch {
+		if p, ok := s.pending[k]; ok {
+			r.add(p)
+		}
+		s.pending[k] = r
+	}
+}
+
+// Records returns the usage records selected by the filter, ordered by time.
+func (s *Service) Records(f Filter) ([]Record, error) {
+	if err := s.Flush(); err != nil {
+		return nil, err
+	}
+
+	records := make([]Record, 0)
+	err := s.store.Iterate(keyPrefix, func(key, value []byte) (bool, error) {
+		if !strings.HasPrefix(string(key), keyPrefix) {
+			return true, nil
+		}
+		// the records out of the time range are not decoded
+		t, err := recordTime(string(key))
+		if err != nil {
+			return true, err
+		}
+		if !f.matchTime(t) {
+			return false, nil
+		}
+		var r Record
+		if err := json.Unmarshal(value, &r); err != nil {
+			return true, fmt.Errorf("unmarshal usage record %s: %w", key, err)
+		}
+		if f.match(&r) {
+			records = append(records, r)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if !a.Time.Equal(b.Time) {
+			return a.Time.Before(b.Time)
+		}
+		if a.Reference != b.Reference {
+			return a.Reference < b.Reference
+		}
+		return a.Tenant < b.Tenant
+	})
+	return records, nil
+}
+
+// expire removes the records of the periods which ended before the retention.
+// The keys are ordered by the time of the record, so the iteration stops at
+// the first record which is not expired.
+func (s *Service) expire() error {
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+
+	cutoff := s.now().Add(-s.retention - Period)
+	var expired []string
+	err := s.store.Iterate(keyPrefix, func(key, _ []byte) (bool, error) {
+		if !strings.HasPrefix(string(key), keyPrefix) {
+			return true, nil
+		}
+		t, err := recordTime(string(key))
+		if err != nil {
+			return true, err
+		}
+		if !t.Before(cutoff) {
+			return true, nil
+		}
+		expired = append(expired, string(key))
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range expired {
+		if err := s.store.Delete(k); err != nil {
+			return fmt.Errorf("delete usage record %s: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// Close stops the periodic writes and writes the collected usage.
+func (s *Service) Close() error {
+	close(s.quit)
+	select {
+	case <-s.stopped:
+	case <-time.After(5 * time.Second):
+		return errors.New("stopping usage service with ongoing worker goroutine")
+	}
+	return s.Flush()
+}
+
+// csvHeader is the header of the usage records in CSV format.
+var csvHeader = []string{"time", "reference", "tenant", "requests", "served", "stored", "cost"}
+
+// WriteCSV writes the usage records in CSV format.
+func WriteCSV(w io.Writer, records []Record) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, r := range records {
+		err := cw.Write([]string{
+			r.Time.UTC().Format(time.RFC3339),
+			r.Reference,
+			r.Tenant,
+			strconv.FormatUint(r.Requests, 10),
+			strconv.FormatUint(r.Served, 10),
+			strconv.FormatUint(r.Stored, 10),
+			strconv.FormatUint(r.Cost, 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,202 @@
+package usage_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redesblock/mop/core/log"
+	"github.com/redesblock/mop/core/storer/statestore/leveldb"
+	statestore "github.com/redesblock/mop/core/storer/statestore/mock"
+	"github.com/redesblock/mop/core/storer/storage"
+	"github.com/redesblock/mop/core/usage"
+)
+
+func TestService(t *testing.T) {
+	store := statestore.NewStateStore()
+	s := usage.New(store, log.Noop, time.Hour, 0)
+
+	start := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+	s.Add(start.Add(time.Minute), "aa", "tenant", 100, 0, 5)
+	s.Add(start.Add(2*time.Minute), "aa", "tenant", 50, 0, 3)
+	s.Add(start.Add(3*time.Minute), "bb", "", 0, 4096, 0)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the records are persisted and added to the stored ones
+	s = usage.New(store, log.Noop, time.Hour, 0)
+	defer s.Close()
+	s.Add(start.Add(time.Hour), "aa", "tenant", 10, 0, 1)
+
+	records, err := s.Records(usage.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []usage.Record{
+		{Time: start, Reference: "aa", Tenant: "tenant", Requests: 2, Served: 150, Cost: 8},
+		{Time: start, Reference: "bb", Requests: 1, Stored: 4096},
+		{Time: start.Add(time.Hour), Reference: "aa", Tenant: "tenant", Requests: 1, Served: 10, Cost: 1},
+	}
+	assertRecords(t, records, want)
+
+	for _, tc := range []struct {
+		name   string
+		filter usage.Filter
+		want   []usage.Record
+	}{
+		{
+			name:   "time range",
+			filter: usage.Filter{From: start.Add(time.Hour), To: start.Add(2 * time.Hour)},
+			want:   want[2:],
+		},
+		{
+			name:   "reference",
+			filter: usage.Filter{Reference: "bb"},
+			want:   want[1:2],
+		},
+		{
+			name:   "tenant",
+			filter: usage.Filter{Tenant: "tenant", To: start.Add(time.Hour)},
+			want:   want[:1],
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			records, err := s.Records(tc.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertRecords(t, records, tc.want)
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := usage.WriteCSV(&buf, want[1:2]); err != nil {
+		t.Fatal(err)
+	}
+	wantCSV := "time,reference,tenant,requests,served,stored,cost\n2022-01-01T10:00:00Z,bb,,1,0,4096,0\n"
+	if got := buf.String(); got != wantCSV {
+		t.Fatalf("got csv %q, want %q", got, wantCSV)
+	}
+}
+
+// TestFlushFailure checks that the usage of a failed write is neither lost
+// nor counted twice.
+func TestFlushFailure(t *testing.T) {
+	store := &failingStore{StateStorer: statestore.NewStateStore()}
+	s := usage.New(store, log.Noop, time.Hour, 0)
+	defer s.Close()
+
+	start := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+	s.Add(start, "aa", "", 100, 0, 0)
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	s.Add(start, "aa", "", 10, 0, 0)
+
+	store.fail = true
+	if err := s.Flush(); !errors.Is(err, errPut) {
+		t.Fatalf("got error %v, want %v", err, errPut)
+	}
+	store.fail = false
+	// the usage collected after the failed write is added to the unwritten one
+	s.Add(start, "aa", "", 1, 0, 0)
+
+	records, err := s.Records(usage.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertRecords(t, records, []usage.Record{{Time: start, Reference: "aa", Requests: 3, Served: 111}})
+}
+
+// TestExpire checks that the records older than the retention are removed.
+// The records are iterated in the order of the keys of the state store.
+func TestExpire(t *testing.T) {
+	store, err := leveldb.NewInMemoryStateStore(log.Noop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	s := usage.New(store, log.Noop, time.Hour, 24*time.Hour)
+	defer s.Close()
+
+	start := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+	s.Add(start, "aa", "", 100, 0, 0)
+	s.Add(start, "bb", "", 1, 0, 0)
+	s.Add(start.Add(24*time.Hour), "aa", "", 10, 0, 0)
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Expire(start.Add(25 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	records, err := s.Records(usage.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertRecords(t, records, []usage.Record{
+		{Time: start, Reference: "aa", Requests: 1, Served: 100},
+		{Time: start, Reference: "bb", Requests: 1, Served: 1},
+		{Time: start.Add(24 * time.Hour), Reference: "aa", Requests: 1, Served: 10},
+	})
+
+	if err := s.Expire(start.Add(26 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	records, err = s.Records(usage.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertRecords(t, records, []usage.Record{{Time: start.Add(24 * time.Hour), Reference: "aa", Requests: 1, Served: 10}})
+}
+
+func TestMeter(t *testing.T) {
+	usage.AddCost(context.Background(), 10) // no meter, no panic
+
+	ctx, m := usage.WithMeter(context.Background())
+	usage.AddCost(ctx, 10)
+	usage.AddCost(usage.WithContext(context.Background(), usage.FromContext(ctx)), 5)
+	usage.SetReference(ctx, "aa")
+
+	if got := m.Cost(); got != 15 {
+		t.Fatalf("got cost %d, want %d", got, 15)
+	}
+	if got := m.Reference(); got != "aa" {
+		t.Fatalf("got reference %q, want %q", got, "aa")
+	}
+}
+
+var errPut = errors.New("put failed")
+
+// failingStore fails the writes when fail is set.
+type failingStore struct {
+	storage.StateStorer
+	fail bool
+}
+
+func (s *failingStore) Put(key string, i interface{}) error {
+	if s.fail {
+		return errPut
+	}
+	return s.StateStorer.Put(key, i)
+}
+
+func assertRecords(t *testing.T, got, want []usage.Record) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d records %+v, want %d", len(got), got, len(want))
+	}
+	for i := range got {
+		if !got[i].Time.Equal(want[i].Time) {
+			t.Fatalf("record %d: got time %s, want %s", i, got[i].Time, want[i].Time)
+		}
+		got[i].Time = want[i].Time
+		if got[i] != want[i] {
+			t.Fatalf("record %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}